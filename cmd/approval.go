@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/approval"
+)
+
+// Operations that need a second approval when the config file asks for it,
+// as named in approval files
+const (
+	approvalRestore = "restore"
+	approvalCleanup = "cleanup"
+)
+
+// requireApproval checks the second approval that the config file requires
+// before a destructive operation: a signed --approval-file or a TOTP code,
+// from --approval-code or asked for. backupPath is the backup the operation
+// reads, or "".
+func requireApproval(operation, backupPath string) error {
+	if !cfg.Approval.Required() {
+		return nil
+	}
+
+	if approvalFile != "" {
+		keys, err := approval.ParsePublicKeys(cfg.Approval.PublicKeys)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return withExitCode(exitApprovalRequired, fmt.Errorf("--approval-file needs approval.public_keys in the config file"))
+		}
+		a, err := approval.Load(approvalFile)
+		if err != nil {
+			return err
+		}
+		var sum string
+		if backupPath != "" {
+			if sum, err = approval.HashFile(backupPath); err != nil {
+				return err
+			}
+		}
+		if err := a.Verify(keys, operation, sum, time.Now()); err != nil {
+			return withExitCode(exitApprovalRequired, fmt.Errorf("approval rejected: %w", err))
+		}
+		fmt.Printf("Approved by %s, valid until %s\n", defaultString(a.Approver, "an approver"), a.ExpiresAt.Local().Format(time.RFC3339))
+		fmt.Println()
+		eventData["approval"] = "file"
+		return nil
+	}
+
+	secret := cfg.Approval.Secret()
+	if secret == "" {
+		return withExitCode(exitApprovalRequired, fmt.Errorf("this operation needs a second approval: "+
+			"pass --approval-file with an approval signed by an approver (see 'approve sign')"))
+	}
+	code := approvalCode
+	if code == "" {
+		fmt.Println("This operation needs a second approval.")
+		answer, err := askString("Approval code from the approver's authenticator app", "", nil)
+		if err != nil {
+			return err
+		}
+		code = answer
+	}
+	ok, err := approval.ValidateTOTP(secret, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return withExitCode(exitApprovalRequired, fmt.Errorf("approval rejected: the approval code is wrong or has expired"))
+	}
+	fmt.Println("Approval code accepted")
+	fmt.Println()
+	eventData["approval"] = "totp"
+	return nil
+}
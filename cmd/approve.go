@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/approval"
+)
+
+var (
+	approveKeyOutput string
+	approveKey       string
+	approveCommand   string
+	approveInput     string
+	approveValid     time.Duration
+	approveApprover  string
+	approveOutput    string
+	approveAccount   string
+)
+
+// approveCmd groups the commands used by approvers
+var approveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approve destructive operations (two-person rule)",
+	Long: `Set up and give second approvals for destructive operations.
+
+For families or small organisations where one person manages another's
+contacts, the config file can require a second person to approve every
+operation that deletes contacts from the account: replace restores and
+'cleanup empty' on the live account. The operation then only runs with
+either:
+
+  - an approval file (--approval-file) signed with an approver's key, whose
+    public key is listed in approval.public_keys. Approvals name the
+    operation, expire, and for restores are bound to the exact backup file.
+  - a TOTP code (--approval-code, or asked for) from the approver's
+    authenticator app, whose secret is approval.totp_secret (or the
+    environment variable named by approval.totp_secret_env).
+
+Approval files are the stronger option: the config file only holds public
+keys, so whoever runs the operation cannot approve it themselves. A TOTP
+secret in the config file can be read by anyone who can read that file.
+
+Examples:
+  # Approver: create a key pair and send the public key to the manager
+  google-contacts-backup approve keygen -o approver.key
+
+  # Approver: approve one restore of a specific backup for the next 24 hours
+  google-contacts-backup approve sign --key approver.key --command restore \
+    -i backup.json -o approval.json
+
+  # Manager: run the restore with the approval
+  google-contacts-backup restore -i backup.json --approval-file approval.json
+
+  # Set up TOTP instead: add the secret to the approver's authenticator app
+  google-contacts-backup approve totp`,
+}
+
+// approveKeygenCmd represents the approve keygen command
+var approveKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Create an approver key pair",
+	RunE:  runApproveKeygen,
+}
+
+// approveSignCmd represents the approve sign command
+var approveSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign an approval file for one destructive operation",
+	RunE:  runApproveSign,
+}
+
+// approveTOTPCmd represents the approve totp command
+var approveTOTPCmd = &cobra.Command{
+	Use:   "totp",
+	Short: "Create a TOTP secret for approval codes",
+	RunE:  runApproveTOTP,
+}
+
+func init() {
+	rootCmd.AddCommand(approveCmd)
+	approveCmd.AddCommand(approveKeygenCmd)
+	approveCmd.AddCommand(approveSignCmd)
+	approveCmd.AddCommand(approveTOTPCmd)
+
+	approveKeygenCmd.Flags().StringVarP(&approveKeyOutput, "output", "o", "approval.key",
+		"File to write the private key to")
+
+	approveSignCmd.Flags().StringVar(&approveKey, "key", "",
+		"Private key file created by 'approve keygen' (required)")
+	approveSignCmd.Flags().StringVar(&approveCommand, "command", "",
+		"Operation to approve: "+approvalRestore+" or "+approvalCleanup+" (required)")
+	approveSignCmd.Flags().StringVarP(&approveInput, "input", "i", "",
+		"Backup file the restore may use (default: any backup)")
+	approveSignCmd.Flags().DurationVar(&approveValid, "valid", 24*time.Hour,
+		"How long the approval stays valid")
+	approveSignCmd.Flags().StringVar(&approveApprover, "approver", "",
+		"Your name, shown when the approval is used")
+	approveSignCmd.Flags().StringVarP(&approveOutput, "output", "o", "approval.json",
+		"File to write the approval to")
+	approveSignCmd.MarkFlagRequired("key")
+	approveSignCmd.MarkFlagRequired("command")
+
+	approveTOTPCmd.Flags().StringVar(&approveAccount, "account", "approver",
+		"Account name shown in the authenticator app")
+}
+
+func runApproveKeygen(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(approveKeyOutput); err == nil {
+		return fmt.Errorf("%s already exists; choose another --output", approveKeyOutput)
+	}
+	publicKey, privateKey, err := approval.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(approveKeyOutput, []byte(privateKey+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write approval key: %w", err)
+	}
+
+	fmt.Printf("Private key written to %s. Keep it to yourself.\n", approveKeyOutput)
+	fmt.Println()
+	fmt.Println("Public key, to add to approval.public_keys in the config file:")
+	fmt.Printf("  %s\n", publicKey)
+	return nil
+}
+
+func runApproveSign(cmd *cobra.Command, args []string) error {
+	if approveCommand != approvalRestore && approveCommand != approvalCleanup {
+		return fmt.Errorf("invalid command %q: must be %s or %s", approveCommand, approvalRestore, approvalCleanup)
+	}
+	if approveInput != "" && approveCommand != approvalRestore {
+		return fmt.Errorf("--input only applies to restore approvals")
+	}
+	if approveValid <= 0 {
+		return fmt.Errorf("invalid --valid %s: must be positive", approveValid)
+	}
+
+	key, err := approval.LoadPrivateKey(approveKey)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	a := &approval.Approval{
+		Command:   approveCommand,
+		Approver:  approveApprover,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(approveValid),
+	}
+	if approveInput != "" {
+		if a.BackupSHA256, err = approval.HashFile(approveInput); err != nil {
+			return err
+		}
+	}
+	a.Sign(key)
+	if err := a.Save(approveOutput); err != nil {
+		return err
+	}
+
+	target := "any backup"
+	if approveInput != "" {
+		target = approveInput
+	}
+	if approveCommand == approvalCleanup {
+		target = "the live account"
+	}
+	fmt.Printf("Approved %s of %s until %s\n", approveCommand, target, a.ExpiresAt.Local().Format(time.RFC3339))
+	fmt.Printf("Approval written to %s\n", approveOutput)
+	return nil
+}
+
+func runApproveTOTP(cmd *cobra.Command, args []string) error {
+	secret, err := approval.GenerateTOTPSecret()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Add this secret to the approver's authenticator app, either by typing it")
+	fmt.Println("in or by turning the otpauth URL into a QR code:")
+	fmt.Println()
+	fmt.Printf("  Secret: %s\n", secret)
+	fmt.Printf("  URL:    %s\n", approval.TOTPURI(secret, approveAccount))
+	fmt.Println()
+	fmt.Println("Then set approval.totp_secret in the config file to the secret (or store")
+	fmt.Println("it in an environment variable named by approval.totp_secret_env).")
+	return nil
+}
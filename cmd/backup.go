@@ -3,21 +3,55 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"filippo.io/age"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
 
-	"github.com/mheap/google-contacts-backup/internal/auth"
 	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/encryption"
+	"github.com/mheap/google-contacts-backup/internal/i18n"
 	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/state"
+	"github.com/mheap/google-contacts-backup/internal/storage"
 )
 
 var (
 	outputFile   string
 	outputFormat string
+	groupMembers bool
+	csvLocale    string
+	notesPlain   bool
+	csvSources   bool
+
+	onlyDomains    []string
+	excludeDomains []string
+	backupFilter   string
+	backupModified string
+
+	backupFields        []string
+	backupExcludeFields []string
+
+	photoBytes           bool
+	includeOtherContacts bool
+	profilePhotoFallback bool
+	profilePhotoBytes    bool
+
+	backupRecipients []string
+	backupPassphrase bool
+
+	backupAssignUUIDs  bool
+	backupCompress     string
+	backupResume       bool
+	backupStableOutput bool
+	backupUpload       string
 )
 
 // backupCmd represents the backup command
@@ -29,12 +63,70 @@ var backupCmd = &cobra.Command{
 Supported formats:
   - json: Full backup including all contact data and groups (default)
   - csv:  Google-compatible CSV that can be imported via Google Contacts web UI
+  - dir:  The full backup as a directory with one JSON file per contact, for
+          keeping the history of your contacts in git
 
 The backup includes:
   - All contact fields (names, emails, phones, addresses, etc.)
-  - Contact photos (as URLs - note: URLs may expire, JSON only)
+  - Contact photos (as URLs - note: URLs may expire, JSON only; with
+    --photo-bytes the images themselves, so restore can re-upload them)
   - Contact groups/labels
+  - Member lists of each user group (JSON only)
   - Custom fields
+  - Notes with their content type (plain text or HTML)
+  - With --include-other-contacts, the "Other contacts" Gmail saved
+    automatically (JSON only; they are not restored)
+
+With --recipient (or "encryption.recipients" in the config file), the file is
+encrypted with age before it is written. Recipients can be age public keys,
+files listing them, or plugin recipients such as age1yubikey1... or
+age1tpm1..., which need the matching age-plugin-NAME program on $PATH and
+make decryption require the hardware token. Commands that read backups
+decrypt them with --identity.
+
+With --compress gzip or --compress zstd (or "backup.compress" in the config
+file), the file is compressed, which shrinks JSON backups several times over
+since they mostly repeat the same field names. The default file name gets a
+.gz or .zst suffix. Commands that read backups detect compressed files
+automatically. Compressed files are encrypted after compression.
+
+With --assign-uuids (or "backup.assign_uuids" in the config file), every
+contact without one is given a random UUID the first time it is backed up.
+The UUID is stored in the contact's clientData in your Google account, so it
+is part of every later backup, survives restores, is used as the vCard UID
+of exports and lets merge restores find contacts that were recreated under a
+new resource name. It gives other tools a durable identifier for each contact.
+
+With --fields, only the listed person fields are read from Google and saved,
+and with --exclude-fields every field but the listed ones, for example to
+keep notes (biographies) or custom data (clientData) out of the backup.
+Fields are given by their People API names or as phones, emails, addresses,
+birthdays, notes, orgs, websites, im, nicknames, relations or custom;
+metadata is always read. The backup records the fields it leaves out, and a
+replace restore of it warns that those fields are lost.
+
+With --stable-output (or "backup.stable_output" in the config file), contacts,
+other contacts and groups are sorted by resource name, and member lists and
+memberships by group, so two backups of an unchanged account are byte for
+byte identical and the difference between two backups is a readable diff.
+The backup's creation time becomes the time of the latest change to any of
+its contacts or groups, which is what makes it repeatable.
+
+Contacts on the "ignore" list of the config file (by email address, resource
+name or label) are left out of the backup.
+
+With --passphrase, the file is instead encrypted with a key derived from a
+passphrase (age's scrypt mode), so it can be decrypted without a key file.
+The passphrase is asked for twice on the terminal, or read from the
+CONTACTS_BACKUP_PASSPHRASE environment variable for unattended runs.
+Commands that read backups ask for it again, or read the same variable. A
+passphrase cannot be combined with recipients.
+
+Every page of contacts is recorded in a temporary file as it arrives
+(google-contacts-backup-partial.jsonl in the temp directory, readable only by
+you), which is deleted once the backup is saved. If a long backup dies in
+the middle of fetching contacts, run it again with --resume to continue from
+the last page received instead of starting over.
 
 Examples:
   # Backup to a timestamped JSON file (default)
@@ -47,18 +139,114 @@ Examples:
   google-contacts-backup backup --format csv
   google-contacts-backup backup -f csv -o my-contacts.csv
 
+  # One JSON file per contact, to commit to git
+  google-contacts-backup backup --format dir -o contacts/
+
+  # CSV with French headers for a French-language Google account
+  google-contacts-backup backup -f csv --csv-locale fr
+
+  # Download contact photos into the backup so restore brings them back
+  google-contacts-backup backup --photo-bytes
+
+  # Also keep the people Gmail saved automatically
+  google-contacts-backup backup --include-other-contacts
+
+  # Keep Google profile photos for contacts without a photo of their own
+  google-contacts-backup backup --profile-photo-fallback --profile-photo-bytes
+
+  # Export only work contacts when leaving a job
+  google-contacts-backup backup --only-domain example.com -o work-contacts.json
+
+  # Strip work contacts from a personal backup
+  google-contacts-backup backup --exclude-domain example.com
+
+  # Back up only family contacts that have a phone number
+  google-contacts-backup backup --filter 'label="Family" && has(phone)'
+
+  # Back up only the contacts edited this week
+  google-contacts-backup backup --modified-since 7d -o this-week.json
+
+  # Keep notes and custom data out of the backup
+  google-contacts-backup backup --exclude-fields notes,clientData
+
+  # Encrypt the backup so that only a YubiKey can decrypt it
+  google-contacts-backup backup --recipient age1yubikey1q...
+
+  # Compress a large backup with zstd
+  google-contacts-backup backup --compress zstd
+
+  # Identical output while nothing changes, for diffing or deduplicating storage
+  google-contacts-backup backup --stable-output -o contacts.json
+
+  # Give every contact a stable UUID that survives restores
+  google-contacts-backup backup --assign-uuids
+
+  # Encrypt the backup with a passphrase before storing it in a cloud drive
+  google-contacts-backup backup --passphrase -o ~/Dropbox/contacts.json.age
+
+  # Continue a backup that was interrupted while fetching contacts
+  google-contacts-backup backup --resume
+
+  # Upload the backup to an S3 bucket once it is written
+  google-contacts-backup backup --upload s3://my-bucket/contacts/
+
+  # Or to Google Cloud Storage, with Application Default Credentials
+  google-contacts-backup backup --upload gs://my-bucket/contacts/
+
   # Use a specific credentials file
   google-contacts-backup backup -c ~/my-credentials.json -o backup.json`,
-	RunE: runBackup,
+	RunE: withEvents("backup", runBackup),
 }
 
 func init() {
 	rootCmd.AddCommand(backupCmd)
 
 	backupCmd.Flags().StringVarP(&outputFile, "output", "o", "",
-		"Output file path for the backup (default: contacts-TIMESTAMP.json or .csv)")
+		"Output file path for the backup, or directory for --format dir (default: contacts-TIMESTAMP.json or .csv in backup.directory)")
 	backupCmd.Flags().StringVarP(&outputFormat, "format", "f", "json",
-		"Output format: json (full backup) or csv (Google-compatible)")
+		"Output format: json (full backup), csv (Google-compatible) or dir (one JSON file per contact)")
+	backupCmd.Flags().BoolVar(&groupMembers, "group-members", true,
+		"Fetch the member list of each user group (one extra request per group)")
+	backupCmd.Flags().BoolVar(&notesPlain, "notes-plaintext", false,
+		"Convert HTML notes to plain text in CSV output (JSON always keeps the original)")
+	backupCmd.Flags().BoolVar(&includeOtherContacts, "include-other-contacts", false,
+		"Also back up \"Other contacts\" saved automatically by Gmail (JSON only)")
+	backupCmd.Flags().BoolVar(&photoBytes, "photo-bytes", false,
+		"Download contact photos into the backup so restore can re-upload them (JSON only)")
+	backupCmd.Flags().BoolVar(&profilePhotoFallback, "profile-photo-fallback", false,
+		"Record the Google profile photo of contacts with no contact photo (JSON only)")
+	backupCmd.Flags().BoolVar(&profilePhotoBytes, "profile-photo-bytes", false,
+		"Also download fallback profile photos into the backup (implies --profile-photo-fallback)")
+	backupCmd.Flags().StringVar(&csvLocale, "csv-locale", "en",
+		"Language of CSV headers: "+strings.Join(models.CSVLocales(), ", "))
+	backupCmd.Flags().BoolVar(&csvSources, "csv-sources", false,
+		"Add a column after each CSV field naming its source: CONTACT, PROFILE or DOMAIN_PROFILE (JSON always records it)")
+	backupCmd.Flags().StringSliceVar(&onlyDomains, "only-domain", nil,
+		"Only keep contacts with an email address in this domain or its subdomains (repeatable)")
+	backupCmd.Flags().StringSliceVar(&excludeDomains, "exclude-domain", nil,
+		"Drop contacts with an email address in this domain or its subdomains (repeatable)")
+	backupCmd.Flags().StringVar(&backupFilter, "filter", "",
+		"Only keep contacts matching this filter expression (see 'help filters')")
+	backupCmd.Flags().StringVar(&backupModified, "modified-since", "",
+		"Only keep contacts updated since this date (YYYY-MM-DD), RFC 3339 time or period (e.g. 7d)")
+	backupCmd.Flags().StringSliceVar(&backupFields, "fields", nil,
+		"Only back up these person fields, e.g. names,emails,phones (default: all)")
+	backupCmd.Flags().StringSliceVar(&backupExcludeFields, "exclude-fields", nil,
+		"Leave these person fields out of the backup, e.g. notes,clientData")
+	backupCmd.Flags().StringSliceVar(&backupRecipients, "recipient", nil,
+		"Encrypt the backup to this age recipient, plugin recipient or recipients file (repeatable)")
+	backupCmd.Flags().StringVar(&backupCompress, "compress", "",
+		"Compress the backup: gzip, zstd or none (overrides backup.compress)")
+	backupCmd.Flags().BoolVar(&backupAssignUUIDs, "assign-uuids", false,
+		"Give contacts without one a stable UUID, stored in their clientData in Google (overrides backup.assign_uuids)")
+	backupCmd.Flags().BoolVar(&backupStableOutput, "stable-output", false,
+		"Sort the backup canonically so unchanged accounts give identical files (overrides backup.stable_output)")
+	backupCmd.Flags().BoolVar(&backupResume, "resume", false,
+		"Continue an interrupted backup from the contacts it already fetched")
+	backupCmd.Flags().BoolVar(&backupPassphrase, "passphrase", false,
+		"Encrypt the backup with a passphrase (asked for, or read from $CONTACTS_BACKUP_PASSPHRASE)")
+	backupCmd.Flags().StringVar(&backupUpload, "upload", "",
+		"Upload the backup to s3://bucket/prefix or gs://bucket/prefix after writing it (overrides backup.upload)")
 }
 
 // getDefaultOutputFile returns the default output filename based on format
@@ -67,6 +255,8 @@ func getDefaultOutputFile(format string) string {
 	switch strings.ToLower(format) {
 	case "csv":
 		return fmt.Sprintf("contacts-%s.csv", timestamp)
+	case "dir":
+		return fmt.Sprintf("contacts-%s", timestamp)
 	default:
 		return fmt.Sprintf("contacts-%s.json", timestamp)
 	}
@@ -77,40 +267,109 @@ func runBackup(cmd *cobra.Command, args []string) error {
 
 	// Validate format
 	format := strings.ToLower(outputFormat)
-	if format != "json" && format != "csv" {
-		return fmt.Errorf("invalid format %q: must be 'json' or 'csv'", outputFormat)
+	if format != "json" && format != "csv" && format != "dir" {
+		return fmt.Errorf("invalid format %q: must be 'json', 'csv' or 'dir'", outputFormat)
+	}
+	// The dir format holds everything a JSON backup does
+	fullBackup := format == "json" || format == "dir"
+	if includeOtherContacts && !fullBackup {
+		return fmt.Errorf("--include-other-contacts needs the json or dir format: CSV backups cannot hold other contacts")
 	}
 
-	// Set default output file if not specified
-	if outputFile == "" {
-		outputFile = getDefaultOutputFile(format)
+	if !models.IsCSVLocale(csvLocale) {
+		return fmt.Errorf("invalid CSV locale %q: must be one of %s", csvLocale, strings.Join(models.CSVLocales(), ", "))
 	}
 
-	// Check if credentials file exists
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("credentials file not found: %s\n\nRun 'google-contacts-backup auth' first, or see 'google-contacts-backup --help' for setup instructions", credentialsFile)
+	expr, err := withModifiedSince(backupFilter, backupModified)
+	if err != nil {
+		return err
+	}
+	selection, err := parseFilter(expr)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("Authenticating with Google...")
+	readFields, leftOut, err := parseBackupFields(backupFields, backupExcludeFields)
+	if err != nil {
+		return err
+	}
 
-	// Authenticate
-	authenticator := auth.NewAuthenticator(credentialsFile)
-	httpClient, err := authenticator.GetClient(ctx)
+	recipientSpecs := backupRecipients
+	if !cmd.Flags().Changed("recipient") && !backupPassphrase {
+		recipientSpecs = cfg.Encryption.Recipients
+	}
+	recipients, err := encryption.ParseRecipients(recipientSpecs)
 	if err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+		return err
+	}
+	if backupPassphrase {
+		if len(recipients) > 0 {
+			return fmt.Errorf("--passphrase cannot be combined with --recipient")
+		}
+		passphrase, err := encryption.ReadPassphrase("Passphrase to encrypt the backup with: ", true)
+		if err != nil {
+			return err
+		}
+		recipient, err := encryption.PassphraseRecipient(passphrase)
+		if err != nil {
+			return err
+		}
+		recipients = []age.Recipient{recipient}
 	}
 
-	fmt.Println("Authentication successful!")
-	fmt.Println()
+	compressSetting := cfg.Backup.Compress
+	if cmd.Flags().Changed("compress") {
+		compressSetting = backupCompress
+	}
+	compression, err := models.ParseCompression(compressSetting)
+	if err != nil {
+		return err
+	}
 
-	// Create contacts client
-	client, err := contacts.NewClient(ctx, httpClient)
+	if format == "dir" && (len(recipients) > 0 || compression != models.CompressionNone) {
+		return fmt.Errorf("the dir format cannot be encrypted or compressed: use the json format, or keep the directory in an encrypted repository")
+	}
+
+	upload, uploadMode, err := backupUploadLocation(cmd, format)
 	if err != nil {
-		return fmt.Errorf("failed to create contacts client: %w", err)
+		return err
+	}
+	var uploadBackend storage.Backend
+	if upload != nil {
+		uploadBackend, err = openUploadBackend(ctx, *upload, cfg.Upload.RetainDays)
+		if err != nil {
+			return err
+		}
+		defer uploadBackend.Close()
+	}
+
+	// Set default output file if not specified
+	if outputFile == "" {
+		outputFile = getDefaultOutputFile(format) + models.CompressionExtension(compression)
+		if cfg.Backup.Directory != "" {
+			outputFile = filepath.Join(cfg.Backup.Directory, outputFile)
+		}
+		if len(recipients) > 0 {
+			outputFile += ".age"
+		}
 	}
 
+	fmt.Println("Authenticating with Google...")
+
+	// Authenticate and create contacts client
+	client, err := newContactsClient(ctx, contacts.WithPersonFields(readFields))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
 	// Create backup file
 	backup := models.NewBackupFile()
+	if len(leftOut) > 0 {
+		backup.AddTransform(models.TransformFields, describeLeftOutFields(readFields, leftOut), 0)
+	}
 
 	// Fetch contact groups
 	fmt.Println("Fetching contact groups...")
@@ -125,6 +384,22 @@ func runBackup(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Found %d contact groups\n", len(groups))
 	fmt.Println()
 
+	if groupMembers && fullBackup {
+		fmt.Println("Fetching group member lists...")
+		members, err := client.GetGroupMembers(ctx, groups, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch group members: %w", err)
+		}
+		backup.GroupMembers = members
+		fmt.Printf("Fetched members of %d user groups\n", len(members))
+		fmt.Println()
+	}
+
+	listing, err := startContactListing()
+	if err != nil {
+		return err
+	}
+
 	// Fetch contacts with progress bar
 	fmt.Println("Fetching contacts...")
 
@@ -142,10 +417,12 @@ func runBackup(cmd *cobra.Command, args []string) error {
 	)
 
 	var totalKnown bool
-	contactsList, err := client.ListContacts(ctx, func(current, total int) {
+	var reportedTotal int
+	contactsList, err := listing.fetch(ctx, client, func(current, total int) {
 		if !totalKnown && total > 0 {
 			bar.ChangeMax(total)
 			totalKnown = true
+			reportedTotal = total
 		}
 		bar.Set(current)
 	})
@@ -157,16 +434,115 @@ func runBackup(cmd *cobra.Command, args []string) error {
 	bar.Finish()
 	fmt.Println() // New line after progress bar
 
+	if totalKnown && reportedTotal != len(contactsList) {
+		warnf("the API reported %d contacts but %d were downloaded", reportedTotal, len(contactsList))
+	}
+
 	for _, contact := range contactsList {
 		backup.AddContact(contact)
 	}
+	applyIgnoreList(backup)
+
+	assignUUIDs := cfg.Backup.AssignUUIDs
+	if cmd.Flags().Changed("assign-uuids") {
+		assignUUIDs = backupAssignUUIDs
+	}
+	if assignUUIDs && readOnlyMode() {
+		// Assigning UUIDs writes to the account
+		warnf("read-only mode: contacts without a UUID are backed up without one")
+		assignUUIDs = false
+	}
+	if assignUUIDs && slices.Contains(leftOut, "clientData") {
+		// UUIDs live in clientData: without it, every contact would look
+		// like it has none, and writing one would wipe its custom data
+		warnf("clientData is not backed up, so no UUIDs are assigned")
+		assignUUIDs = false
+	}
+
+	if includeOtherContacts && fullBackup {
+		fmt.Println("Fetching other contacts...")
+		otherContacts, err := client.ListOtherContacts(ctx, nil)
+		if contacts.IsInsufficientScope(err) {
+			return withExitCode(exitAuthFailure, fmt.Errorf("the saved token cannot read other contacts, as it predates that permission: "+
+				"delete ~/.google-contacts-backup/token.json and run 'google-contacts-backup auth' again"))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch other contacts: %w", err)
+		}
+		kept, ignored := cfg.Ignore.Split(otherContacts, nil)
+		backup.OtherContacts = kept
+		fmt.Printf("Found %d other contacts\n", len(backup.OtherContacts))
+		if len(ignored) > 0 {
+			fmt.Printf("Ignoring %d other contacts on the ignore list\n", len(ignored))
+			eventData["ignored_other_contacts"] = len(ignored)
+		}
+	}
+
+	if len(onlyDomains) > 0 || len(excludeDomains) > 0 {
+		filterByDomain(backup)
+	}
+
+	if selection != nil {
+		removed := selection.Apply(backup)
+		backup.AddTransform(models.TransformFilter, selection.String(), removed)
+		fmt.Printf("Filter kept %d contacts and dropped %d\n", len(backup.Contacts), removed)
+	}
+
+	// Only contacts that stay in the backup get a UUID written to the account
+	if assignUUIDs {
+		backup.Contacts, err = assignContactUUIDs(ctx, client, backup.Contacts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if photoBytes && fullBackup {
+		if err := addContactPhotos(ctx, client, backup); err != nil {
+			return err
+		}
+	}
+
+	if (profilePhotoFallback || profilePhotoBytes) && fullBackup {
+		if err := addFallbackPhotos(ctx, client, backup); err != nil {
+			return err
+		}
+	}
+
+	stableOutput := cfg.Backup.StableOutput
+	if cmd.Flags().Changed("stable-output") {
+		stableOutput = backupStableOutput
+	}
+	if stableOutput {
+		backup.Canonicalize()
+	}
 
 	// Save backup to file
 	fmt.Printf("\nSaving backup to %s...\n", outputFile)
 
-	switch format {
-	case "csv":
-		if err := backup.SaveToCSV(outputFile); err != nil {
+	csvOptions := models.CSVOptions{Locale: csvLocale, PlainTextNotes: notesPlain, Sources: csvSources}
+	writeBackup := func(w io.Writer) error {
+		return models.WriteCompressed(w, compression, func(w io.Writer) error {
+			if format == "csv" {
+				return backup.WriteCSV(w, csvOptions)
+			}
+			return backup.WriteJSON(w)
+		})
+	}
+	switch {
+	case len(recipients) > 0:
+		if err := saveEncrypted(outputFile, recipients, writeBackup); err != nil {
+			return fmt.Errorf("failed to save backup: %w", err)
+		}
+	case compression != models.CompressionNone:
+		if err := saveFile(outputFile, writeBackup); err != nil {
+			return fmt.Errorf("failed to save backup: %w", err)
+		}
+	case format == "dir":
+		if err := backup.SaveToDir(outputFile); err != nil {
+			return fmt.Errorf("failed to save backup: %w", err)
+		}
+	case format == "csv":
+		if err := backup.SaveToCSV(outputFile, csvOptions); err != nil {
 			return fmt.Errorf("failed to save backup: %w", err)
 		}
 	default:
@@ -175,22 +551,335 @@ func runBackup(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	listing.finish()
+
+	var uploaded string
+	if upload != nil {
+		fmt.Println()
+		uploaded, err = uploadFile(ctx, uploadBackend, outputFile, *upload, cfg.Upload.RetainDays, uploadMode)
+		if err != nil {
+			return fmt.Errorf("backup saved to %s, but the upload failed: %w", outputFile, err)
+		}
+		eventData["upload"] = uploaded
+	}
+
+	eventData["file"] = outputFile
+	eventData["format"] = format
+	eventData["contacts"] = backup.ContactCount
+	eventData["groups"] = backup.GroupCount
+	eventData["encrypted"] = len(recipients) > 0
+	eventData["compression"] = compression
+	if includeOtherContacts && fullBackup {
+		eventData["other_contacts"] = len(backup.OtherContacts)
+	}
+
+	// Filtered backups hold part of the address book, so their size says
+	// nothing about its trend
+	if selection == nil && len(onlyDomains) == 0 && len(excludeDomains) == 0 {
+		recordBackupSize(state.Size{
+			At:            backup.CreatedAt,
+			Contacts:      backup.ContactCount,
+			OtherContacts: len(backup.OtherContacts),
+			Groups:        backup.GroupCount,
+		})
+	}
+
 	// Print summary
 	fmt.Println()
-	fmt.Println("Backup completed successfully!")
+	fmt.Println(i18n.T("backup.completed"))
 	fmt.Println()
-	fmt.Printf("  Format:   %s\n", strings.ToUpper(format))
-	fmt.Printf("  Contacts: %d\n", backup.ContactCount)
-	fmt.Printf("  Groups:   %d\n", backup.GroupCount)
-	fmt.Printf("  File:     %s\n", outputFile)
+	fmt.Println(i18n.T("backup.summary.format", strings.ToUpper(format)))
+	fmt.Println(i18n.T("backup.summary.contacts", backup.ContactCount))
+	fmt.Println(i18n.T("backup.summary.groups", backup.GroupCount))
+	fmt.Println(i18n.T("backup.summary.file", outputFile))
+	if uploaded != "" {
+		fmt.Printf("  Uploaded to: %s\n", uploaded)
+	}
+	if linked := models.LinkedContacts(backup.Contacts); len(linked) > 0 {
+		fmt.Printf("  Linked to Google profiles: %d\n", len(linked))
+	}
+	if len(backup.OtherContacts) > 0 {
+		fmt.Printf("  Other contacts: %d\n", len(backup.OtherContacts))
+	}
+	if compression != models.CompressionNone {
+		fmt.Printf("  Compressed with %s\n", compression)
+	}
+	switch {
+	case backupPassphrase:
+		fmt.Println("  Encrypted with a passphrase")
+	case len(recipients) > 0:
+		fmt.Printf("  Encrypted to %d recipients\n", len(recipients))
+	}
 	fmt.Println()
 
-	if format == "json" {
+	if fullBackup && !photoBytes {
 		fmt.Println("Note: Contact photos are stored as URLs which may expire over time.")
-	} else {
+		fmt.Println("      Use --photo-bytes to keep the images so restore can re-upload them.")
+	} else if format == "csv" {
 		fmt.Println("Note: CSV format can be imported directly via Google Contacts web UI.")
 		fmt.Println("      Contact photos and some metadata are not included in CSV format.")
 	}
 
 	return nil
 }
+
+// backupUploadLocation returns where --upload or backup.upload sends the
+// backup, or nil if it stays local, and the object lock mode from the
+// upload section of the config.
+func backupUploadLocation(cmd *cobra.Command, format string) (*storage.Location, string, error) {
+	destination := cfg.Backup.Upload
+	if cmd.Flags().Changed("upload") {
+		destination = backupUpload
+	}
+	if destination == "" {
+		return nil, "", nil
+	}
+	if format == "dir" {
+		return nil, "", fmt.Errorf("--upload needs a single backup file: use the json or csv format, or upload the directory with your storage provider's tools")
+	}
+	if cfg.Upload.RetainDays < 0 {
+		return nil, "", fmt.Errorf("invalid upload.retain_days %d: must be a positive number of days", cfg.Upload.RetainDays)
+	}
+	loc, err := storage.ParseLocation(destination)
+	if err != nil {
+		return nil, "", err
+	}
+	mode, err := storage.ParseRetentionMode(defaultString(cfg.Upload.RetentionMode, "compliance"))
+	if err != nil {
+		return nil, "", err
+	}
+	return &loc, mode, nil
+}
+
+// parseBackupFields resolves --fields and --exclude-fields to the person
+// fields to read, or nil to read every field, and the fields left out.
+func parseBackupFields(only, exclude []string) ([]string, []string, error) {
+	if len(only) == 0 && len(exclude) == 0 {
+		return nil, nil, nil
+	}
+
+	all := contacts.PersonFields()
+	resolve := func(flag string, names []string) (map[string]bool, error) {
+		fields := make(map[string]bool)
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			field, ok := models.ResolveFieldName(name)
+			if !ok && slices.Contains(all, name) {
+				field, ok = name, true
+			}
+			if !ok || !slices.Contains(all, field) {
+				return nil, fmt.Errorf("unknown field %q in --%s: use one of %s", name, flag, strings.Join(all, ", "))
+			}
+			if field == "metadata" {
+				return nil, fmt.Errorf("metadata is always backed up and cannot be given in --%s", flag)
+			}
+			fields[field] = true
+		}
+		return fields, nil
+	}
+	onlyFields, err := resolve("fields", only)
+	if err != nil {
+		return nil, nil, err
+	}
+	excluded, err := resolve("exclude-fields", exclude)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var read, leftOut []string
+	for _, field := range all {
+		switch {
+		case field == "metadata":
+			read = append(read, field)
+		case (len(onlyFields) == 0 || onlyFields[field]) && !excluded[field]:
+			read = append(read, field)
+		default:
+			leftOut = append(leftOut, field)
+		}
+	}
+	if len(read) == 1 {
+		return nil, nil, fmt.Errorf("--fields and --exclude-fields leave no field to back up")
+	}
+	return read, leftOut, nil
+}
+
+// describeLeftOutFields describes the fields a backup leaves out for its
+// transforms, by listing them or, if shorter, the fields it keeps.
+func describeLeftOutFields(read, leftOut []string) string {
+	kept := slices.DeleteFunc(slices.Clone(read), func(field string) bool { return field == "metadata" })
+	if len(leftOut) <= len(kept) {
+		return strings.Join(leftOut, ", ")
+	}
+	return "other than " + strings.Join(kept, ", ")
+}
+
+// filterByDomain applies --only-domain and --exclude-domain to the backup.
+func filterByDomain(backup *models.BackupFile) {
+	var removed int
+	if len(onlyDomains) > 0 {
+		only := len(backup.RemoveContacts(func(p *people.Person) bool {
+			return !models.HasEmailDomain(p, onlyDomains)
+		}))
+		backup.AddTransform(models.TransformDomain, "only "+strings.Join(onlyDomains, ", "), only)
+		removed += only
+	}
+	if len(excludeDomains) > 0 {
+		excluded := len(backup.RemoveContacts(func(p *people.Person) bool {
+			return models.HasEmailDomain(p, excludeDomains)
+		}))
+		backup.AddTransform(models.TransformDomain, "excluding "+strings.Join(excludeDomains, ", "), excluded)
+		removed += excluded
+	}
+	fmt.Printf("Domain filter kept %d contacts and dropped %d\n", len(backup.Contacts), removed)
+}
+
+// addContactPhotos downloads the photo of every contact that has one of its
+// own into the backup.
+func addContactPhotos(ctx context.Context, client *contacts.Client, backup *models.BackupFile) error {
+	var withPhoto []*people.Person
+	for _, contact := range backup.Contacts {
+		if models.ContactPhoto(contact) != nil {
+			withPhoto = append(withPhoto, contact)
+		}
+	}
+
+	if len(withPhoto) == 0 {
+		fmt.Println("No contacts have a contact photo to download")
+		return nil
+	}
+
+	fmt.Printf("\nDownloading %d contact photos...\n", len(withPhoto))
+	bar := progressbar.NewOptions(len(withPhoto),
+		progressbar.OptionSetDescription("Downloading photos"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+
+	photos := make(map[string]*models.Photo)
+	for _, contact := range withPhoto {
+		url := models.ContactPhoto(contact).Url
+		data, contentType, err := client.DownloadPhoto(ctx, url)
+		if err != nil {
+			warnf("failed to download contact photo for %s: %v", contact.ResourceName, err)
+		} else {
+			photos[contact.ResourceName] = &models.Photo{URL: url, ContentType: contentType, Data: data}
+		}
+		bar.Add(1)
+	}
+	bar.Finish()
+	fmt.Println()
+
+	backup.Photos = photos
+	fmt.Printf("Downloaded %d contact photos\n", len(photos))
+	return nil
+}
+
+// addFallbackPhotos records the Google profile photo of every contact that
+// has no contact photo, downloading the image if --profile-photo-bytes is set.
+func addFallbackPhotos(ctx context.Context, client *contacts.Client, backup *models.BackupFile) error {
+	fallbacks := make(map[string]*models.FallbackPhoto)
+	for _, contact := range backup.Contacts {
+		if photo := models.ProfilePhotoFallback(contact); photo != nil {
+			fallbacks[contact.ResourceName] = &models.FallbackPhoto{Source: "PROFILE", URL: photo.Url}
+		}
+	}
+
+	if len(fallbacks) == 0 {
+		fmt.Println("No contacts need a profile photo fallback")
+		return nil
+	}
+
+	if profilePhotoBytes {
+		fmt.Printf("\nDownloading %d profile photos...\n", len(fallbacks))
+		bar := progressbar.NewOptions(len(fallbacks),
+			progressbar.OptionSetDescription("Downloading photos"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+
+		for resourceName, fallback := range fallbacks {
+			data, contentType, err := client.DownloadPhoto(ctx, fallback.URL)
+			if err != nil {
+				warnf("failed to download profile photo for %s: %v", resourceName, err)
+			} else {
+				fallback.Data = data
+				fallback.ContentType = contentType
+			}
+			bar.Add(1)
+		}
+		bar.Finish()
+		fmt.Println()
+	}
+
+	backup.FallbackPhotos = fallbacks
+	fmt.Printf("Recorded %d profile photo fallbacks\n", len(fallbacks))
+	return nil
+}
+
+// assignContactUUIDs gives the contacts without a UUID a new one and stores
+// it in their clientData in Google. Returns the contacts with the updated
+// ones replaced by their new versions. Contacts that could not be updated
+// are backed up without a UUID, so they get one on the next run.
+func assignContactUUIDs(ctx context.Context, client *contacts.Client, contactsList []*people.Person) ([]*people.Person, error) {
+	missing := models.AssignUUIDs(contactsList)
+	if len(missing) == 0 {
+		return contactsList, nil
+	}
+
+	fmt.Printf("Assigning UUIDs to %d contacts...\n", len(missing))
+	bar := progressbar.NewOptions(len(missing),
+		progressbar.OptionSetDescription("Updating contacts"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	updated, err := client.UpdateContacts(ctx, missing, []string{"clientData"}, func(done, total int) {
+		bar.Set(done)
+	})
+	bar.Finish()
+	fmt.Println()
+
+	byName := make(map[string]*people.Person, len(updated))
+	for _, contact := range updated {
+		byName[contact.ResourceName] = contact
+	}
+	result := make([]*people.Person, len(contactsList))
+	for i, contact := range contactsList {
+		if newer, ok := byName[contact.ResourceName]; ok {
+			result[i] = newer
+		} else {
+			result[i] = contact
+		}
+	}
+	failed := 0
+	for _, contact := range missing {
+		if _, ok := byName[contact.ResourceName]; !ok {
+			models.RemoveClientData(contact, models.UUIDKey, "")
+			failed++
+		}
+	}
+
+	if err != nil && len(updated) == 0 {
+		return nil, fmt.Errorf("failed to assign UUIDs: %w", err)
+	}
+	if failed > 0 {
+		warnf("%d contacts could not be given a UUID; they are backed up without one", failed)
+	}
+	fmt.Printf("Assigned UUIDs to %d contacts\n", len(updated))
+	fmt.Println()
+	return result, nil
+}
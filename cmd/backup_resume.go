@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/state"
+)
+
+// contactListing fetches the contacts of a backup page by page, recording
+// every page in the partial listing file so that an interrupted backup can
+// be continued with --resume.
+type contactListing struct {
+	path    string
+	partial *state.PartialListing
+
+	// contacts, nextPageToken, complete and total are the progress so far
+	contacts      []*people.Person
+	nextPageToken string
+	complete      bool
+	total         int
+}
+
+// startContactListing prepares the listing of a backup: with --resume it
+// picks up the contacts of an interrupted backup, and otherwise it starts
+// over.
+func startContactListing() (*contactListing, error) {
+	l := &contactListing{path: state.DefaultPartialListingPath()}
+	startedAt := time.Now()
+
+	progress, err := state.ReadPartialListing(l.path)
+	if err != nil {
+		if backupResume {
+			return nil, err
+		}
+		warnf("%v", err)
+	}
+	switch {
+	case backupResume && progress != nil:
+		fmt.Printf("Resuming the backup started at %s: %d contacts were already fetched\n",
+			progress.StartedAt.Local().Format(time.RFC3339), len(progress.Contacts))
+		startedAt = progress.StartedAt
+		l.contacts = progress.Contacts
+		l.nextPageToken = progress.NextPageToken
+		l.complete = progress.Complete
+		l.total = progress.Total
+	case backupResume:
+		fmt.Println("There is no interrupted backup to resume; fetching every contact.")
+	case progress != nil:
+		fmt.Println("Note: an interrupted backup can be continued with --resume; this backup starts over.")
+	}
+
+	partial, err := state.CreatePartialListing(l.path, startedAt)
+	if err != nil {
+		warnf("%v; this backup cannot be resumed if it is interrupted", err)
+		return l, nil
+	}
+	l.partial = partial
+	if len(l.contacts) > 0 || l.complete {
+		l.record(l.contacts, l.nextPageToken)
+	}
+	return l, nil
+}
+
+// fetch lists the contacts that were not fetched yet and returns every
+// contact of the account. progressFn is called as by ListContacts.
+func (l *contactListing) fetch(ctx context.Context, client *contacts.Client, progressFn func(current, total int)) ([]*people.Person, error) {
+	defer l.close()
+
+	if len(l.contacts) > 0 {
+		progressFn(len(l.contacts), l.total)
+	}
+	if l.complete {
+		return l.contacts, nil
+	}
+
+	err := client.ListContactPages(ctx, l.nextPageToken, func(page contacts.ContactPage) error {
+		if l.total == 0 && page.TotalPeople > 0 {
+			l.total = page.TotalPeople
+		}
+		l.contacts = append(l.contacts, page.Contacts...)
+		l.record(page.Contacts, page.NextPageToken)
+		progressFn(len(l.contacts), l.total)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l.contacts, nil
+}
+
+// record appends a page to the partial listing. Write errors are warnings:
+// the backup goes on, but cannot be resumed.
+func (l *contactListing) record(page []*people.Person, nextPageToken string) {
+	if l.partial == nil {
+		return
+	}
+	if err := l.partial.AddPage(page, nextPageToken, l.total); err != nil {
+		warnf("%v; this backup cannot be resumed if it is interrupted", err)
+		l.close()
+	}
+}
+
+// close closes the partial listing file.
+func (l *contactListing) close() {
+	if l.partial != nil {
+		l.partial.Close()
+		l.partial = nil
+	}
+}
+
+// finish deletes the partial listing once the backup was saved.
+func (l *contactListing) finish() {
+	if err := state.RemovePartialListing(l.path); err != nil {
+		warnf("%v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/synthetic"
+)
+
+var (
+	benchInput      string
+	benchContacts   int
+	benchIterations int
+)
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure exporter throughput and memory",
+	Long: `Measure how fast each exporter serializes a backup and how much memory it allocates.
+
+The backup is read from --input, or generated with synthetic data when no
+input is given. Output is written to a discarding writer, so disk speed does
+not affect the results. Each exporter runs --iterations times and the fastest
+run is reported alongside the average allocation.
+
+Examples:
+  # Benchmark against 20,000 synthetic contacts
+  google-contacts-backup bench --contacts 20000
+
+  # Benchmark against a real backup
+  google-contacts-backup bench -i my-contacts.json --iterations 5`,
+	RunE: runBench,
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	markLocal(benchCmd, nil)
+
+	benchCmd.Flags().StringVarP(&benchInput, "input", "i", "",
+		"Backup file to benchmark against (default: generate synthetic data)")
+	benchCmd.Flags().IntVar(&benchContacts, "contacts", 5000,
+		"Number of synthetic contacts to generate when no input is given")
+	benchCmd.Flags().IntVar(&benchIterations, "iterations", 3,
+		"Number of runs per exporter")
+}
+
+// benchExporter is a named function writing a backup in one format
+type benchExporter struct {
+	name  string
+	write func(b *models.BackupFile, w io.Writer) error
+}
+
+// benchExporters lists every exporter measured by the bench command
+var benchExporters = []benchExporter{
+	{"json", func(b *models.BackupFile, w io.Writer) error { return b.WriteJSON(w) }},
+	{"csv", func(b *models.BackupFile, w io.Writer) error { return b.WriteCSV(w, models.CSVOptions{}) }},
+	{"vcard", func(b *models.BackupFile, w io.Writer) error { return b.WriteVCard(w, models.VCardOptions{}) }},
+	{"html", func(b *models.BackupFile, w io.Writer) error { return b.WriteHTML(w, models.HTMLOptions{}) }},
+}
+
+// countingWriter discards data while counting the bytes written
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// benchResult holds the measurements of one exporter
+type benchResult struct {
+	best       time.Duration
+	bytes      int64
+	allocBytes uint64
+	allocs     uint64
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	if benchIterations < 1 {
+		return fmt.Errorf("--iterations must be at least 1")
+	}
+
+	var backup *models.BackupFile
+	if benchInput != "" {
+		fmt.Printf("Loading backup file: %s\n", benchInput)
+		loaded, err := loadBackup(benchInput)
+		if err != nil {
+			return fmt.Errorf("failed to load backup: %w", err)
+		}
+		backup = loaded
+	} else {
+		fmt.Printf("Generating %d synthetic contacts...\n", benchContacts)
+		backup = synthetic.Generate(synthetic.Options{Contacts: benchContacts, Groups: 20, Seed: 1})
+	}
+	fmt.Println()
+
+	fmt.Printf("%-8s %12s %12s %14s %14s %12s %12s\n", "FORMAT", "BEST", "OUTPUT", "THROUGHPUT", "CONTACTS/S", "ALLOC/RUN", "ALLOCS/RUN")
+	for _, exporter := range benchExporters {
+		result, err := measureExporter(backup, exporter)
+		if err != nil {
+			return fmt.Errorf("%s exporter failed: %w", exporter.name, err)
+		}
+
+		seconds := result.best.Seconds()
+		fmt.Printf("%-8s %12s %12s %12.1f/s %14.0f %12s %12d\n",
+			exporter.name,
+			result.best.Round(time.Microsecond),
+			formatBytes(uint64(result.bytes)),
+			float64(result.bytes)/seconds/(1<<20),
+			float64(len(backup.Contacts))/seconds,
+			formatBytes(result.allocBytes),
+			result.allocs,
+		)
+	}
+	fmt.Println()
+	fmt.Println("Throughput is in MiB of output per second; ALLOC/RUN and ALLOCS/RUN are the average heap bytes and objects allocated per run.")
+
+	return nil
+}
+
+// measureExporter runs an exporter benchIterations times.
+func measureExporter(backup *models.BackupFile, exporter benchExporter) (*benchResult, error) {
+	result := &benchResult{}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	for i := 0; i < benchIterations; i++ {
+		writer := &countingWriter{}
+		start := time.Now()
+		if err := exporter.write(backup, writer); err != nil {
+			return nil, err
+		}
+		elapsed := time.Since(start)
+
+		if i == 0 || elapsed < result.best {
+			result.best = elapsed
+		}
+		result.bytes = writer.n
+	}
+
+	runtime.ReadMemStats(&after)
+	result.allocBytes = (after.TotalAlloc - before.TotalAlloc) / uint64(benchIterations)
+	result.allocs = (after.Mallocs - before.Mallocs) / uint64(benchIterations)
+
+	return result, nil
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
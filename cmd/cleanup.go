@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	cleanupInput   string
+	cleanupOutput  string
+	cleanupDryRun  bool
+	cleanupConfirm bool
+)
+
+// cleanupCmd groups commands that remove unwanted contacts
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Find and remove unwanted contacts",
+}
+
+// cleanupEmptyCmd represents the cleanup empty command
+var cleanupEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Delete contacts with no name, email or phone number",
+	Long: `Find contacts that have no name, no email address and no phone number, and
+delete them from your Google account or exclude them from a backup file.
+
+Such contacts are common artifacts of phone syncs. They may still hold other
+data, such as an address or notes, so every match is listed with the fields
+it does have before anything is removed.
+
+Without --input, the live account is cleaned and matching contacts are
+deleted permanently. With --input, matching contacts are removed from the
+backup file, which is written to --output (default: overwrite the input) in
+the format it was read in, compressed and encrypted the same way.
+Deleting from the live account needs --approval-file or --approval-code if
+the config file requires a second approval (see 'approve').
+
+Examples:
+  # List empty contacts in the live account without deleting anything
+  google-contacts-backup cleanup empty --dry-run
+
+  # Delete them after confirming
+  google-contacts-backup cleanup empty
+
+  # Exclude them from a backup, writing a new file
+  google-contacts-backup cleanup empty -i backup.json -o cleaned.json`,
+	RunE: runCleanupEmpty,
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+	cleanupCmd.AddCommand(cleanupEmptyCmd)
+
+	cleanupEmptyCmd.Flags().StringVarP(&cleanupInput, "input", "i", "",
+		"Backup file to clean instead of the live account")
+	cleanupEmptyCmd.Flags().StringVarP(&cleanupOutput, "output", "o", "",
+		"Output file path when cleaning a backup (default: overwrite the input file)")
+	cleanupEmptyCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false,
+		"List empty contacts without removing them")
+	cleanupEmptyCmd.Flags().BoolVar(&cleanupConfirm, "confirm", false,
+		"Skip confirmation prompt")
+}
+
+// printEmptyContacts lists contacts with the fields they still hold.
+func printEmptyContacts(contacts []*people.Person) {
+	fmt.Printf("Found %d empty contacts:\n", len(contacts))
+	for _, contact := range contacts {
+		fields := models.PresentFields(contact)
+		if len(fields) == 0 {
+			fmt.Printf("  %s (no data)\n", contact.ResourceName)
+		} else {
+			fmt.Printf("  %s (has: %s)\n", contact.ResourceName, strings.Join(fields, ", "))
+		}
+	}
+	fmt.Println()
+}
+
+// confirmCleanup asks the user to confirm removing n contacts.
+func confirmCleanup(n int, target string) (bool, error) {
+	return askConfirmation(i18n.T("cleanup.confirm", n, target))
+}
+
+func runCleanupEmpty(cmd *cobra.Command, args []string) error {
+	if cleanupInput != "" {
+		return cleanupEmptyBackup()
+	}
+	if cleanupOutput != "" {
+		return fmt.Errorf("--output can only be used with --input")
+	}
+	return cleanupEmptyLive()
+}
+
+// cleanupEmptyBackup removes empty contacts from a backup file.
+func cleanupEmptyBackup() error {
+	if cleanupOutput == "" {
+		cleanupOutput = cleanupInput
+	}
+
+	fmt.Printf("Loading backup file: %s\n", cleanupInput)
+	backup, stored, err := loadStoredBackup(cleanupInput, inputFormat(cleanupInput))
+	if err != nil {
+		return fmt.Errorf("failed to load backup: %w", err)
+	}
+	fmt.Println()
+
+	var empty []*people.Person
+	for _, contact := range backup.Contacts {
+		if models.IsEmptyContact(contact) {
+			empty = append(empty, contact)
+		}
+	}
+	if len(empty) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no empty contacts found"))
+	}
+
+	printEmptyContacts(empty)
+	if cleanupDryRun {
+		fmt.Println("Dry run: backup left unchanged.")
+		return nil
+	}
+
+	if !cleanupConfirm {
+		ok, err := confirmCleanup(len(empty), cleanupOutput)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println(i18n.T("cleanup.cancelled"))
+			return nil
+		}
+	}
+
+	removed := backup.RemoveContacts(models.IsEmptyContact)
+
+	fmt.Printf("Saving backup to %s...\n", cleanupOutput)
+	if err := saveStoredBackup(backup, cleanupOutput, stored); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println(i18n.T("cleanup.completed"))
+	fmt.Println()
+	fmt.Printf("  Removed:   %d\n", len(removed))
+	fmt.Printf("  Remaining: %d\n", len(backup.Contacts))
+	fmt.Printf("  File:      %s\n", cleanupOutput)
+
+	return nil
+}
+
+// cleanupEmptyLive deletes empty contacts from the Google account.
+func cleanupEmptyLive() error {
+	ctx := context.Background()
+
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	fmt.Println("Fetching contacts...")
+	fetchBar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Fetching contacts"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+
+	allContacts, err := client.ListContacts(ctx, func(current, total int) {
+		fetchBar.ChangeMax(total)
+		fetchBar.Set(current)
+	})
+	fetchBar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+	fmt.Println()
+
+	var empty []*people.Person
+	var resourceNames []string
+	for _, contact := range allContacts {
+		if models.IsEmptyContact(contact) && contact.ResourceName != "" {
+			empty = append(empty, contact)
+			resourceNames = append(resourceNames, contact.ResourceName)
+		}
+	}
+	if len(empty) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no empty contacts found"))
+	}
+
+	printEmptyContacts(empty)
+	if cleanupDryRun {
+		fmt.Println("Dry run: no contacts were deleted.")
+		return nil
+	}
+
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	if err := requireApproval(approvalCleanup, ""); err != nil {
+		return err
+	}
+
+	if !cleanupConfirm {
+		fmt.Println(i18n.T("cleanup.delete_warning"))
+		ok, err := confirmCleanup(len(empty), i18n.T("cleanup.target_account"))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println(i18n.T("cleanup.cancelled"))
+			return nil
+		}
+		fmt.Println()
+	}
+
+	deleteBar := progressbar.NewOptions(len(resourceNames),
+		progressbar.OptionSetDescription("Deleting contacts"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+
+	err = client.DeleteContacts(ctx, resourceNames, func(deleted, total int) {
+		deleteBar.Set(deleted)
+	})
+	deleteBar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to delete contacts: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println(i18n.T("cleanup.completed"))
+	fmt.Println()
+	fmt.Printf("  Deleted: %d\n", len(resourceNames))
+
+	return nil
+}
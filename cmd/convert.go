@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	convertInput      string
+	convertOutput     string
+	convertFrom       string
+	convertTo         string
+	convertCSVLocale  string
+	convertNotesPlain bool
+	convertCSVSources bool
+	convertCharset    string
+	convertDedupe     bool
+	convertMergeInto  string
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert contacts between file formats, offline",
+	Long: `Convert contacts from one file format to another. Only local files are
+read and written: no Google account, credentials or network access is needed.
+
+Input formats: ` + strings.Join(models.ConvertInputFormats(), ", ") + `, dir
+Output formats: ` + strings.Join(models.ConvertOutputFormats(), ", ") + `, dir
+
+The formats are taken from the file extensions (.json, .csv, .vcf, .html);
+use --from and --to for other names or for vcf21 and the CRM and phone vendor
+CSVs. dir is a backup directory, as written by backup --format dir, and is
+used for directories and output paths ending in a slash. CSV input may be written by this tool in any --csv-locale or exported
+from Google Contacts; vCard input may be version 2.1, 3.0 or 4.0. Labels are
+carried over as contact groups (CSV Labels, vCard CATEGORIES). The columns
+of CSV input are listed with the contact field each is read into, and --map
+reads other columns as known ones, as for restore.
+
+Unlike export, convert writes every contact as it is: the ignore list of the
+config file and filters do not apply.
+
+With --dedupe, contacts of the input that look like the same person (the same
+email address, phone number or name) are merged into one, keeping every email address, phone number, address and
+label of each. With --merge-into, the input is merged into an existing
+backup instead: labels are matched by name, contacts that are already in the
+backup get the details they lack from their duplicates in the input, and the
+rest are added. Contacts of the existing backup are never merged with each
+other. The merged contacts are listed.
+
+Examples:
+  # Turn a backup into vCards
+  google-contacts-backup convert -i backup.json -o contacts.vcf
+
+  # Turn a Google Contacts CSV export into a JSON backup, e.g. to restore it
+  google-contacts-backup convert -i contacts.csv -o contacts.json
+
+  # Read the columns of a CRM export as the matching contact fields
+  google-contacts-backup convert -i crm.csv -o contacts.json --map columns.json
+
+  # Turn a phone's vCard export into a JSON backup
+  google-contacts-backup convert -i phone.vcf -o phone.json
+
+  # Merge a phone's vCard export into a backup, without duplicates
+  google-contacts-backup convert -i phone.vcf --merge-into backup.json -o merged.json
+
+  # Write vCard 2.1 for an old phone
+  google-contacts-backup convert -i backup.json -o phone.vcf --to vcf21`,
+	Args: cobra.NoArgs,
+	RunE: withEvents("convert", runConvert),
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	markLocal(convertCmd, nil)
+
+	convertCmd.Flags().StringVarP(&convertInput, "input", "i", "",
+		"Input file (required)")
+	convertCmd.MarkFlagRequired("input")
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "",
+		"Output file (required)")
+	convertCmd.MarkFlagRequired("output")
+	convertCmd.Flags().StringVar(&convertFrom, "from", "",
+		"Input format: "+strings.Join(models.ConvertInputFormats(), ", ")+", dir (default: from the input file extension)")
+	addCSVMapFlag(convertCmd)
+	convertCmd.Flags().StringVar(&convertTo, "to", "",
+		"Output format: "+strings.Join(models.ConvertOutputFormats(), ", ")+", dir (default: from the output file extension)")
+	convertCmd.Flags().StringVar(&convertCSVLocale, "csv-locale", "en",
+		"Language of CSV headers: "+strings.Join(models.CSVLocales(), ", "))
+	convertCmd.Flags().BoolVar(&convertNotesPlain, "notes-plaintext", false,
+		"Convert HTML notes to plain text in CSV and vCard output")
+	convertCmd.Flags().BoolVar(&convertCSVSources, "csv-sources", false,
+		"Add a column after each CSV field naming its source: CONTACT, PROFILE or DOMAIN_PROFILE")
+	convertCmd.Flags().StringVar(&convertCharset, "charset", "utf-8",
+		"Character set of vcf21, CRM and phone vendor CSV output: "+strings.Join(models.Charsets(), ", "))
+	convertCmd.Flags().BoolVar(&convertDedupe, "dedupe", false,
+		"Merge contacts of the input that are duplicates of each other")
+	convertCmd.Flags().StringVar(&convertMergeInto, "merge-into", "",
+		"Backup to merge the input into, merging duplicates (implies --dedupe)")
+}
+
+// outputFormatOf returns the format of an output file by its extension, dir
+// for a directory, or "" if the extension does not name one.
+func outputFormatOf(path string) string {
+	if info, err := os.Stat(path); strings.HasSuffix(path, "/") || err == nil && info.IsDir() {
+		return "dir"
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	case ".vcf":
+		return "vcf"
+	case ".html", ".htm":
+		return "html"
+	}
+	return ""
+}
+
+func runConvert(cmd *cobra.Command, args []string) error {
+	from := strings.ToLower(convertFrom)
+	if from == "" {
+		from = inputFormat(convertInput)
+	}
+	to := strings.ToLower(convertTo)
+	if to == "" {
+		if to = outputFormatOf(convertOutput); to == "" {
+			return fmt.Errorf("cannot tell the output format from %s: use --to", convertOutput)
+		}
+	}
+	if err := checkCSVMap(from); err != nil {
+		return err
+	}
+	csvPreview = true
+	if !models.IsCSVLocale(convertCSVLocale) {
+		return fmt.Errorf("invalid CSV locale %q: must be one of %s", convertCSVLocale, strings.Join(models.CSVLocales(), ", "))
+	}
+	if !models.IsCharset(convertCharset) {
+		return fmt.Errorf("invalid charset %q: must be one of %s", convertCharset, strings.Join(models.Charsets(), ", "))
+	}
+
+	fmt.Printf("Reading %s (%s)\n", convertInput, from)
+	backup, err := loadBackupAs(convertInput, from)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", convertInput, err)
+	}
+
+	if convertDedupe || convertMergeInto != "" {
+		base := models.NewBackupFile()
+		if convertMergeInto != "" {
+			fmt.Printf("Reading %s\n", convertMergeInto)
+			if base, err = loadBackup(convertMergeInto); err != nil {
+				return fmt.Errorf("failed to read %s: %w", convertMergeInto, err)
+			}
+		}
+		existing := len(base.Contacts)
+		merges := mergeImport(base, backup)
+		printImportMerges(merges)
+		fmt.Printf("Merged %d duplicate contacts, added %d new contacts\n", len(merges), len(base.Contacts)-existing)
+		eventData["merged"] = len(merges)
+		backup = base
+	}
+
+	opts := models.ConvertOptions{
+		CSV:      models.CSVOptions{Locale: convertCSVLocale, PlainTextNotes: convertNotesPlain, Sources: convertCSVSources},
+		VCard:    models.VCardOptions{PlainTextNotes: convertNotesPlain, Charset: convertCharset},
+		Template: models.TemplateCSVOptions{Charset: convertCharset},
+	}
+	if to == "vcf21" {
+		// vCard 2.1 cannot mark notes as HTML
+		opts.VCard.PlainTextNotes = true
+	}
+	if to == "dir" {
+		err = backup.SaveToDir(convertOutput)
+	} else {
+		err = saveFile(convertOutput, func(w io.Writer) error {
+			return backup.WriteFormat(w, to, opts)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	eventData["file"] = convertOutput
+	eventData["from"] = from
+	eventData["to"] = to
+	eventData["contacts"] = len(backup.Contacts)
+
+	fmt.Printf("Wrote %d contacts and %d groups to %s (%s)\n", len(backup.Contacts), len(backup.GetUserGroups()), convertOutput, to)
+	return nil
+}
+
+// printImportMerges lists the imported contacts that were merged into
+// others.
+func printImportMerges(merges []importMerge) {
+	for _, merge := range merges {
+		fmt.Printf("  %s (%s) → %s (%s)\n", models.DisplayName(merge.merged), merge.merged.ResourceName, models.DisplayName(merge.into), merge.into.ResourceName)
+	}
+}
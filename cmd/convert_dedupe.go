@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/dedupe"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// importMerge is an imported contact merged into another contact
+type importMerge struct {
+	// into is the contact that was kept
+	into *people.Person
+
+	// merged is the imported contact whose details were added to into
+	merged *people.Person
+}
+
+// mergeImport adds the contacts and groups of imported to base, merging
+// duplicates instead of adding them twice. Imported groups are matched to
+// the groups of base by name (system groups by resource name) and the
+// others are added; contacts and groups whose placeholder resource names
+// clash with those of base are renamed.
+//
+// Duplicates are found with dedupe.Find across both backups. Every
+// imported contact of a cluster is merged into the cluster's first contact
+// of base or, for clusters without one, into the cluster's first imported
+// contact. Contacts of base are never merged with each other: the existing
+// backup is only added to.
+func mergeImport(base, imported *models.BackupFile) []importMerge {
+	groupMap := mergeImportGroups(base, imported)
+
+	taken := make(map[string]bool, len(base.Contacts)+len(imported.Contacts))
+	for _, contact := range base.Contacts {
+		taken[contact.ResourceName] = true
+	}
+	// importedNames holds the resource names the imported contacts had in
+	// imported, which its group members and photos refer to
+	importedNames := make([]string, len(imported.Contacts))
+	for i, contact := range imported.Contacts {
+		importedNames[i] = contact.ResourceName
+		contact.ResourceName = uniqueResourceName(contact.ResourceName, taken)
+		taken[contact.ResourceName] = true
+		for _, membership := range contact.Memberships {
+			if m := membership.ContactGroupMembership; m != nil && groupMap[m.ContactGroupResourceName] != "" {
+				m.ContactGroupResourceName = groupMap[m.ContactGroupResourceName]
+			}
+		}
+	}
+
+	all := append(append([]*people.Person{}, base.Contacts...), imported.Contacts...)
+	isBase := make(map[*people.Person]bool, len(base.Contacts))
+	for _, contact := range base.Contacts {
+		isBase[contact] = true
+	}
+
+	var merges []importMerge
+	mergedInto := make(map[*people.Person]*people.Person)
+	for _, cluster := range dedupe.Find(all, dedupe.Options{}) {
+		into := cluster.Contacts[0]
+		for _, contact := range cluster.Contacts {
+			if isBase[contact] {
+				into = contact
+				break
+			}
+		}
+		for _, contact := range cluster.Contacts {
+			if contact == into || isBase[contact] {
+				continue
+			}
+			dedupe.Merge(into, contact)
+			mergedInto[contact] = into
+			merges = append(merges, importMerge{into: into, merged: contact})
+		}
+	}
+
+	// nameMap maps the resource names of imported contacts in imported to
+	// those of the contacts they became or were merged into
+	nameMap := make(map[string]string, len(imported.Contacts))
+	for i, contact := range imported.Contacts {
+		into := mergedInto[contact]
+		if into == nil {
+			base.AddContact(contact)
+			into = contact
+		}
+		old := importedNames[i]
+		nameMap[old] = into.ResourceName
+		if photo := imported.Photos[old]; photo != nil && base.Photos[into.ResourceName] == nil {
+			if base.Photos == nil {
+				base.Photos = make(map[string]*models.Photo)
+			}
+			base.Photos[into.ResourceName] = photo
+		}
+		if photo := imported.FallbackPhotos[old]; photo != nil && base.FallbackPhotos[into.ResourceName] == nil {
+			if base.FallbackPhotos == nil {
+				base.FallbackPhotos = make(map[string]*models.FallbackPhoto)
+			}
+			base.FallbackPhotos[into.ResourceName] = photo
+		}
+	}
+
+	for group, members := range imported.GroupMembers {
+		if base.GroupMembers == nil {
+			base.GroupMembers = make(map[string][]string)
+		}
+		group = groupMap[group]
+		for _, member := range members {
+			if resourceName := nameMap[member]; resourceName != "" {
+				base.GroupMembers[group] = appendUnique(base.GroupMembers[group], resourceName)
+			}
+		}
+	}
+
+	return merges
+}
+
+// mergeImportGroups adds the groups of imported that base lacks and returns
+// a map from the resource names of imported groups to those in base.
+func mergeImportGroups(base, imported *models.BackupFile) map[string]string {
+	taken := make(map[string]bool, len(base.Groups))
+	byName := make(map[string]string)
+	for _, group := range base.Groups {
+		taken[group.ResourceName] = true
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			byName[group.Name] = group.ResourceName
+		}
+	}
+
+	groupMap := make(map[string]string, len(imported.Groups))
+	for _, group := range imported.Groups {
+		switch {
+		case group.GroupType != "USER_CONTACT_GROUP" && taken[group.ResourceName]:
+			groupMap[group.ResourceName] = group.ResourceName
+		case byName[group.Name] != "" && group.GroupType == "USER_CONTACT_GROUP":
+			groupMap[group.ResourceName] = byName[group.Name]
+		default:
+			old := group.ResourceName
+			group.ResourceName = uniqueResourceName(old, taken)
+			taken[group.ResourceName] = true
+			if group.GroupType == "USER_CONTACT_GROUP" {
+				byName[group.Name] = group.ResourceName
+			}
+			groupMap[old] = group.ResourceName
+			base.AddGroup(group)
+		}
+	}
+	return groupMap
+}
+
+// uniqueResourceName returns resourceName, or resourceName with the first
+// free numeric suffix if it is already taken.
+func uniqueResourceName(resourceName string, taken map[string]bool) string {
+	if !taken[resourceName] {
+		return resourceName
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", resourceName, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// appendUnique appends value to values unless it is already there.
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var countJSON bool
+
+// countCmd represents the count command
+var countCmd = &cobra.Command{
+	Use:   "count",
+	Short: "Report the live contact and group counts",
+	Long: `Quickly report how many contacts and contact groups are in your Google account.
+
+Unlike backup, this command requests a single page with minimal fields, so it
+finishes in a couple of API calls regardless of the size of the account. It is
+intended for monitoring scripts that only need freshness/size signals.
+
+Examples:
+  # Print the counts
+  google-contacts-backup count
+
+  # Print the counts as JSON for scripts
+  google-contacts-backup count --json`,
+	RunE: runCount,
+}
+
+func init() {
+	rootCmd.AddCommand(countCmd)
+
+	countCmd.Flags().BoolVar(&countJSON, "json", false,
+		"Print the counts as JSON")
+}
+
+// countResult is the machine-readable output of the count command
+type countResult struct {
+	CheckedAt  time.Time `json:"checked_at"`
+	Contacts   int       `json:"contacts"`
+	Groups     int       `json:"groups"`
+	UserGroups int       `json:"user_groups"`
+}
+
+func runCount(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	contactCount, err := client.CountContacts(ctx)
+	if err != nil {
+		return err
+	}
+
+	groups, err := client.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch contact groups: %w", err)
+	}
+
+	result := countResult{
+		CheckedAt: time.Now().UTC(),
+		Contacts:  contactCount,
+		Groups:    len(groups),
+	}
+	for _, group := range groups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			result.UserGroups++
+		}
+	}
+
+	if countJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	fmt.Printf("  Contacts:    %d\n", result.Contacts)
+	fmt.Printf("  Groups:      %d\n", result.Groups)
+	fmt.Printf("  User groups: %d\n", result.UserGroups)
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	// csvMapFile is the --map file of the commands that import CSV
+	csvMapFile string
+
+	// csvPreview makes loading a CSV file print how its columns are read
+	csvPreview bool
+)
+
+// addCSVMapFlag adds --map to a command that imports CSV files.
+func addCSVMapFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&csvMapFile, "map", "",
+		`JSON file mapping CSV headers to the columns to import them as, e.g. {"E-Mail privat": "Email 1 - Value"} ("" skips a column)`)
+}
+
+// checkCSVMap fails if --map is given for an input that is not CSV.
+func checkCSVMap(format string) error {
+	if csvMapFile != "" && format != "csv" {
+		return fmt.Errorf("--map only applies to CSV input")
+	}
+	return nil
+}
+
+// readCSVImport reads a CSV file with the --map overrides. For commands that
+// import CSV, it prints how every column is read and warns about columns
+// whose values are dropped, before the contacts are used.
+func readCSVImport(r io.Reader, path string) (*models.BackupFile, error) {
+	var overrides models.CSVMap
+	if csvMapFile != "" {
+		var err error
+		if overrides, err = models.LoadCSVMap(csvMapFile); err != nil {
+			return nil, err
+		}
+	}
+
+	backup, mapping, err := models.ReadCSVMapped(r, overrides)
+	if err != nil || !csvPreview {
+		return backup, err
+	}
+	printCSVMapping(path, mapping)
+	return backup, nil
+}
+
+// printCSVMapping prints the contact field every column of a CSV file is
+// read into, and warns about the columns that are dropped.
+func printCSVMapping(path string, mapping *models.CSVMapping) {
+	width := 0
+	for _, column := range mapping.Columns {
+		width = max(width, utf8.RuneCountInString(column.Header))
+	}
+
+	fmt.Printf("Columns of %s:\n", path)
+	for _, column := range mapping.Columns {
+		target := column.Field
+		switch {
+		case target != "" && column.Override:
+			target += " (--map)"
+		case column.Override:
+			target = "skipped (--map)"
+		case target == "":
+			target = "not imported"
+		}
+		fmt.Printf("  %-*s → %s\n", width, column.Header, target)
+	}
+	fmt.Println()
+
+	for _, column := range mapping.Dropped() {
+		warnf("column %q is not imported, dropping its values in %d row(s): map it with --map, or map it to \"\" to skip it", column.Header, column.Values)
+	}
+	for _, header := range mapping.UnusedOverrides {
+		warnf("--map maps column %q, which %s does not have", header, path)
+	}
+}
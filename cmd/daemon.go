@@ -0,0 +1,401 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/daemon"
+	"github.com/mheap/google-contacts-backup/internal/state"
+	"github.com/mheap/google-contacts-backup/internal/trend"
+)
+
+var (
+	daemonSchedule string
+	daemonPrint    bool
+	daemonRunNow   bool
+
+	daemonWindowsService bool
+
+	// daemonEvents writes to the Windows event log while 'daemon run' runs
+	// as a Windows service, and is nil otherwise
+	daemonEvents daemon.Logger
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Schedule unattended backups with the system scheduler",
+	Long: `Install, remove or inspect the system scheduler entry that runs
+'google-contacts-backup backup' on the configured schedule.
+
+The scheduler depends on the operating system:
+  Linux     a systemd user service and timer in ~/.config/systemd/user
+  macOS     a launchd agent in ~/Library/LaunchAgents, logging to
+            ~/Library/Logs/google-contacts-backup.log
+  Windows   a Scheduled Task named google-contacts-backup
+
+The schedule comes from backup.schedule in the config file (set by 'init')
+or --schedule: hourly, daily (at 02:00) or weekly (Sundays at 02:00). Backups
+are written to backup.directory. The --config and --credentials paths in
+effect at install time are passed on to the scheduled backup.
+
+On Linux, run 'loginctl enable-linger' once so the timer also runs while you
+are logged out.
+
+On Windows, --windows-service installs a service named google-contacts-backup
+instead of a Scheduled Task. The service starts with Windows, runs backups
+whether or not anyone is logged in, shows up in the Services console and
+writes each backup's outcome to the Application event log. It runs as
+LocalSystem with your profile directory, so it uses your config file,
+credentials and token. Installing, removing and inspecting the service needs
+an administrator prompt; pass --windows-service to 'daemon uninstall' and
+'daemon status' as well.
+
+'daemon run' is the scheduler for containers and long-lived pods, where no
+system scheduler is available: it stays in the foreground and starts a
+backup at each scheduled time until it receives SIGTERM or Ctrl+C. Combine
+it with --serve-health for Kubernetes liveness probes and monitoring.
+
+Examples:
+  # Run backups on the schedule chosen in 'init'
+  google-contacts-backup daemon install
+
+  # Run backups every hour
+  google-contacts-backup daemon install --schedule hourly
+
+  # Show the files that would be installed, without installing them
+  google-contacts-backup daemon install --print
+
+  # Check when the next backup runs
+  google-contacts-backup daemon status
+
+  # Stop scheduled backups
+  google-contacts-backup daemon uninstall
+
+  # Run backups from a Windows service, from an administrator prompt
+  google-contacts-backup daemon install --windows-service
+
+  # Run as a long-lived pod, backing up now and then every day
+  google-contacts-backup daemon run --schedule daily --now --serve-health :8080`,
+}
+
+// daemonInstallCmd represents the daemon install command
+var daemonInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the scheduler entry for unattended backups",
+	RunE:  runDaemonInstall,
+}
+
+// daemonUninstallCmd represents the daemon uninstall command
+var daemonUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the scheduler entry",
+	RunE:  runDaemonUninstall,
+}
+
+// daemonStatusCmd represents the daemon status command
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the scheduler entry and when it runs next",
+	RunE:  runDaemonStatus,
+}
+
+// daemonRunCmd represents the daemon run command
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run backups on the schedule in the foreground, for containers",
+	RunE:  runDaemonRun,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonRunCmd)
+	daemonCmd.AddCommand(daemonInstallCmd)
+	daemonCmd.AddCommand(daemonUninstallCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+
+	daemonInstallCmd.Flags().StringVar(&daemonSchedule, "schedule", "",
+		"How often to back up: "+strings.Join(daemon.Schedules, ", ")+" (default: backup.schedule from the config file)")
+	daemonInstallCmd.Flags().BoolVar(&daemonPrint, "print", false,
+		"Print the files that would be installed instead of installing them")
+	for _, c := range []*cobra.Command{daemonInstallCmd, daemonUninstallCmd, daemonStatusCmd} {
+		c.Flags().BoolVar(&daemonWindowsService, "windows-service", false,
+			"Use a Windows service instead of a Scheduled Task (Windows only)")
+	}
+
+	daemonRunCmd.Flags().StringVar(&daemonSchedule, "schedule", "",
+		"How often to back up: "+strings.Join(daemon.Schedules, ", ")+" (default: backup.schedule from the config file)")
+	daemonRunCmd.Flags().BoolVar(&daemonRunNow, "now", false,
+		"Also back up immediately on start")
+}
+
+// newScheduler returns the scheduler of this system, or the Windows service
+// with --windows-service.
+func newScheduler() (daemon.Scheduler, error) {
+	if daemonWindowsService {
+		return daemon.NewService()
+	}
+	return daemon.New(runtime.GOOS)
+}
+
+// daemonRunCommand returns the command line of 'daemon run' on a schedule,
+// with the same paths as backupCommand.
+func daemonRunCommand(schedule string) []string {
+	backup := backupCommand()
+	command := []string{backup[0], "daemon", "run", "--schedule", schedule}
+	return append(command, backup[2:]...)
+}
+
+// resolveSchedule returns --schedule, or the configured schedule.
+func resolveSchedule() (string, error) {
+	schedule := daemonSchedule
+	if schedule == "" {
+		schedule = cfg.Backup.Schedule
+	}
+	if schedule == "" {
+		return "", fmt.Errorf("no schedule configured: run 'google-contacts-backup init' or pass --schedule")
+	}
+	if !slices.Contains(daemon.Schedules, schedule) {
+		return "", fmt.Errorf("invalid schedule %q: must be one of %s", schedule, strings.Join(daemon.Schedules, ", "))
+	}
+	return schedule, nil
+}
+
+func runDaemonInstall(cmd *cobra.Command, args []string) error {
+	schedule, err := resolveSchedule()
+	if err != nil {
+		return err
+	}
+
+	scheduler, err := newScheduler()
+	if err != nil {
+		return err
+	}
+	job := daemon.Job{Command: backupCommand(), Schedule: schedule}
+	if daemonWindowsService {
+		// The service runs the scheduler itself rather than single backups
+		job.Command = daemonRunCommand(schedule)
+	}
+
+	if daemonPrint {
+		files, err := scheduler.Files(job)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Printf("A %s runs: %s\n", scheduler.Kind(), strings.Join(job.Command, " "))
+		}
+		for _, file := range files {
+			fmt.Printf("# %s\n%s\n", file.Path, file.Content)
+		}
+		return nil
+	}
+
+	if err := checkCredentials(); err != nil {
+		return err
+	}
+	if cfg.Backup.Directory == "" {
+		warnf("backup.directory is not set; scheduled backups are written to the scheduler's working directory")
+	}
+
+	fmt.Printf("Installing %s...\n", scheduler.Kind())
+	if err := scheduler.Install(job); err != nil {
+		return fmt.Errorf("failed to install %s: %w", scheduler.Kind(), err)
+	}
+
+	fmt.Println()
+	fmt.Println("Scheduled backups installed successfully!")
+	fmt.Println()
+	fmt.Printf("  Schedule: %s\n", schedule)
+	fmt.Printf("  Command:  %s\n", strings.Join(job.Command, " "))
+	if cfg.Backup.Directory != "" {
+		fmt.Printf("  Backups:  %s\n", cfg.Backup.Directory)
+	}
+	fmt.Println()
+	fmt.Println("Run 'google-contacts-backup daemon status' to see when the next backup runs.")
+
+	return nil
+}
+
+func runDaemonUninstall(cmd *cobra.Command, args []string) error {
+	scheduler, err := newScheduler()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removing %s...\n", scheduler.Kind())
+	if err := scheduler.Uninstall(); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", scheduler.Kind(), err)
+	}
+	fmt.Println("Scheduled backups removed.")
+
+	return nil
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	scheduler, err := newScheduler()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Scheduler: %s\n", scheduler.Kind())
+	fmt.Printf("Schedule:  %s\n", defaultString(cfg.Backup.Schedule, "none configured"))
+	fmt.Println()
+	return scheduler.Status(os.Stdout)
+}
+
+func runDaemonRun(cmd *cobra.Command, args []string) error {
+	schedule, err := resolveSchedule()
+	if err != nil {
+		return err
+	}
+	if err := checkCredentials(); err != nil {
+		return err
+	}
+
+	inService, err := daemon.InService()
+	if err != nil {
+		return fmt.Errorf("failed to detect the Windows service manager: %w", err)
+	}
+	if inService {
+		return daemon.RunService(func(ctx context.Context, log daemon.Logger) error {
+			daemonEvents = log
+			log.Info(daemon.EventStarted, fmt.Sprintf("Running backups %s", schedule))
+			runSchedule(ctx, schedule)
+			return nil
+		})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Running backups %s; stop with Ctrl+C or SIGTERM.\n", schedule)
+	runSchedule(ctx, schedule)
+	return nil
+}
+
+// runSchedule runs a backup at each scheduled time, and first with --now,
+// until ctx is cancelled.
+func runSchedule(ctx context.Context, schedule string) {
+	if daemonRunNow {
+		runScheduledBackup(ctx)
+	}
+
+	for ctx.Err() == nil {
+		next := daemon.Next(schedule, time.Now())
+		if healthServer != nil {
+			healthServer.SetNextRun(next)
+		}
+		fmt.Printf("Next backup at %s\n", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+			runScheduledBackup(ctx)
+		}
+	}
+
+	fmt.Println("Stopping scheduled backups.")
+}
+
+// runScheduledBackup runs one backup in a child process, so every backup
+// starts from a clean slate, and reports its outcome to the health
+// endpoints. Failures are printed and do not stop the daemon.
+func runScheduledBackup(ctx context.Context) {
+	command := backupCommand()
+	fmt.Printf("\n[%s] Starting backup\n", time.Now().Format(time.RFC3339))
+	if healthServer != nil {
+		healthServer.SetRunning(true)
+	}
+
+	child := exec.CommandContext(ctx, command[0], command[1:]...)
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	// A service has no console, so keep the output for the event log
+	var output bytes.Buffer
+	if daemonEvents != nil {
+		daemonEvents.Info(daemon.EventBackupStarted, "Starting backup")
+		child.Stdout = &output
+		child.Stderr = &output
+	}
+	// Let an interrupted backup finish writing before it is killed
+	child.Cancel = func() error {
+		return child.Process.Signal(os.Interrupt)
+	}
+	child.WaitDelay = time.Minute
+
+	if err := child.Run(); err != nil {
+		fmt.Printf("[%s] Backup failed: %v\n", time.Now().Format(time.RFC3339), err)
+		if daemonEvents != nil {
+			daemonEvents.Error(daemon.EventBackupFailed, fmt.Sprintf("Backup failed: %v\n\n%s", err, lastLines(output.String(), 20)))
+		}
+	} else {
+		fmt.Printf("[%s] Backup finished\n", time.Now().Format(time.RFC3339))
+		if daemonEvents != nil {
+			daemonEvents.Info(daemon.EventBackupDone, fmt.Sprintf("Backup finished\n\n%s", lastLines(output.String(), 20)))
+		}
+	}
+
+	if healthServer == nil {
+		return
+	}
+	// The backup records its own run in the state store
+	run, sizes, err := lastBackupRun()
+	if err != nil {
+		fmt.Printf("Warning: failed to read the backup's run: %v\n", err)
+		if daemonEvents != nil {
+			daemonEvents.Warning(daemon.EventWarning, fmt.Sprintf("Failed to read the backup's run: %v", err))
+		}
+	}
+	if sizes != nil {
+		healthServer.SetTrend(sizes)
+	}
+	if run == nil {
+		healthServer.SetRunning(false)
+		return
+	}
+	healthServer.RecordRun(run)
+}
+
+// lastBackupRun reads the most recent backup run and the trend of the
+// recorded address book sizes from the state store
+func lastBackupRun() (*state.Run, *trend.Trend, error) {
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer store.Close()
+	run, err := store.LastRun("backup")
+	return run, loadTrend(store), err
+}
+
+// lastLines returns the last n non-empty lines of the output of a command,
+// dropping progress bar redraws.
+func lastLines(output string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if i := strings.LastIndex(line, "\r"); i >= 0 {
+			line = line[i+1:]
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/dedupe"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	dedupeInput     string
+	dedupeWrite     string
+	dedupeThreshold float64
+	dedupeJSON      bool
+)
+
+// dedupeCmd represents the dedupe command
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find duplicate contacts in a backup or the live account",
+	Long: `Find contacts that look like the same person and print them in clusters,
+each with the matches that joined it and how confident they are.
+
+Contacts are compared on their email addresses (by the inbox they deliver
+to, as in 'report aliases'), phone numbers (by their last ten digits) and
+names, after normalization: accents, case and punctuation are ignored, a
+nickname matches the full given name (Bob and Robert) and an initial
+matches a given name. A shared email address or phone number, or the same
+full name, is enough on its own; weaker signals such as a nickname need a
+second one. --threshold (between 0 and 1, default 0.8) raises or lowers the
+confidence two contacts need. Duplicates that only match through others
+(A shares an email with B, B a phone with C) end up in the same cluster.
+
+Without --input, the contacts of the live account are scanned; nothing is
+changed in Google either way. With --write, the contacts of each cluster
+are merged into its first contact, which gains every email address, phone
+number, address, label and other detail of the others, and the result is
+written to a new backup file. Check the clusters first, then restore the
+merged backup (for example with --mode replace) to clean up the account.
+
+Exits with code 6 if no duplicates are found.
+
+Examples:
+  # List the duplicates in the live account
+  google-contacts-backup dedupe
+
+  # List the duplicates in a backup
+  google-contacts-backup dedupe -i my-contacts.json
+
+  # Only report the most certain matches
+  google-contacts-backup dedupe -i my-contacts.json --threshold 0.9
+
+  # Write a backup with the duplicates merged
+  google-contacts-backup dedupe -i my-contacts.json --write merged.json
+
+  # Machine-readable clusters for scripts
+  google-contacts-backup dedupe -i my-contacts.json --json`,
+	Args: cobra.NoArgs,
+	RunE: withEvents("dedupe", runDedupe),
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+	markLocal(dedupeCmd, func() bool { return dedupeInput != "" })
+
+	dedupeCmd.Flags().StringVarP(&dedupeInput, "input", "i", "",
+		"Backup file to scan instead of the live account")
+	dedupeCmd.Flags().StringVar(&dedupeWrite, "write", "",
+		"Write a backup with the duplicates merged to this file")
+	dedupeCmd.Flags().Float64Var(&dedupeThreshold, "threshold", dedupe.DefaultThreshold,
+		"Confidence from 0 to 1 two contacts need to be reported as duplicates")
+	dedupeCmd.Flags().BoolVar(&dedupeJSON, "json", false,
+		"Print the clusters as JSON")
+}
+
+// dedupeCluster is the machine-readable form of a dedupe.Cluster
+type dedupeCluster struct {
+	Contacts []aliasReportee `json:"contacts"`
+	Matches  []dedupeMatch   `json:"matches"`
+}
+
+// dedupeMatch is the machine-readable form of a dedupe.Pair
+type dedupeMatch struct {
+	A          string   `json:"a"`
+	B          string   `json:"b"`
+	Confidence float64  `json:"confidence"`
+	Reasons    []string `json:"reasons"`
+}
+
+func runDedupe(cmd *cobra.Command, args []string) error {
+	if dedupeThreshold <= 0 || dedupeThreshold > 1 {
+		return fmt.Errorf("invalid --threshold %g: must be between 0 and 1", dedupeThreshold)
+	}
+
+	backup, err := loadDedupeSource()
+	if err != nil {
+		return err
+	}
+
+	clusters := dedupe.Find(backup.Contacts, dedupe.Options{Threshold: dedupeThreshold})
+	duplicates := 0
+	for _, cluster := range clusters {
+		duplicates += len(cluster.Contacts) - 1
+	}
+	eventData["contacts"] = len(backup.Contacts)
+	eventData["clusters"] = len(clusters)
+	eventData["duplicates"] = duplicates
+
+	if dedupeJSON {
+		report := make([]dedupeCluster, 0, len(clusters))
+		for _, cluster := range clusters {
+			entry := dedupeCluster{}
+			for _, contact := range cluster.Contacts {
+				entry.Contacts = append(entry.Contacts, aliasReportee{
+					ResourceName: contact.ResourceName,
+					Name:         models.DisplayName(contact),
+				})
+			}
+			for _, pair := range cluster.Pairs {
+				entry.Matches = append(entry.Matches, dedupeMatch{
+					A:          pair.A.ResourceName,
+					B:          pair.B.ResourceName,
+					Confidence: pair.Confidence,
+					Reasons:    pair.Reasons,
+				})
+			}
+			report = append(report, entry)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printDedupeClusters(clusters, len(backup.Contacts))
+	}
+
+	if len(clusters) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no duplicate contacts found"))
+	}
+
+	if dedupeWrite != "" {
+		merged := mergeClusters(backup, clusters)
+		if err := backup.SaveToFile(dedupeWrite); err != nil {
+			return fmt.Errorf("failed to save backup: %w", err)
+		}
+		eventData["file"] = dedupeWrite
+		eventData["merged"] = merged
+		if !dedupeJSON {
+			fmt.Printf("Merged %d duplicates into %d contacts; wrote %d contacts to %s\n",
+				merged, len(clusters), len(backup.Contacts), dedupeWrite)
+		}
+	}
+
+	return nil
+}
+
+// loadDedupeSource returns the backup given with --input, or else a backup
+// of the contacts and groups of the live account.
+func loadDedupeSource() (*models.BackupFile, error) {
+	if dedupeInput != "" {
+		backup, err := loadBackup(dedupeInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load backup: %w", err)
+		}
+		return backup, nil
+	}
+
+	ctx := context.Background()
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var live []*people.Person
+	var groups []*people.ContactGroup
+	if dedupeJSON {
+		// Keep progress out of the JSON
+		if live, err = client.ListContacts(ctx, nil); err != nil {
+			return nil, fmt.Errorf("failed to fetch contacts: %w", err)
+		}
+		if groups, err = client.ListGroups(ctx); err != nil {
+			return nil, fmt.Errorf("failed to fetch groups: %w", err)
+		}
+	} else if live, groups, err = fetchLiveAccount(ctx, client); err != nil {
+		return nil, err
+	}
+
+	backup := models.NewBackupFile()
+	for _, group := range groups {
+		backup.AddGroup(group)
+	}
+	for _, contact := range live {
+		backup.AddContact(contact)
+	}
+	return backup, nil
+}
+
+// printDedupeClusters lists the clusters with the matches that joined them.
+func printDedupeClusters(clusters []dedupe.Cluster, total int) {
+	if len(clusters) == 0 {
+		fmt.Printf("No duplicates found in %d contacts.\n", total)
+		return
+	}
+
+	clustered := 0
+	for _, cluster := range clusters {
+		clustered += len(cluster.Contacts)
+	}
+	fmt.Printf("Found %d clusters of duplicates, %d of %d contacts:\n", len(clusters), clustered, total)
+	fmt.Println()
+	for i, cluster := range clusters {
+		fmt.Printf("Cluster %d (%d contacts)\n", i+1, len(cluster.Contacts))
+		for _, contact := range cluster.Contacts {
+			fmt.Printf("  %s (%s)\n", models.DisplayName(contact), contact.ResourceName)
+		}
+		for _, pair := range cluster.Pairs {
+			fmt.Printf("    %s ~ %s: %.0f%%, %s\n", pair.A.ResourceName, pair.B.ResourceName,
+				100*pair.Confidence, strings.Join(pair.Reasons, ", "))
+		}
+		fmt.Println()
+	}
+}
+
+// mergeClusters merges the contacts of each cluster into its first contact
+// and removes the others from the backup, moving their group memberships and
+// photos to the contact they were merged into. It returns the number of
+// contacts removed.
+func mergeClusters(backup *models.BackupFile, clusters []dedupe.Cluster) int {
+	mergedInto := make(map[string]string)
+	removed := make(map[*people.Person]bool)
+	for _, cluster := range clusters {
+		into := cluster.Contacts[0]
+		for _, contact := range cluster.Contacts[1:] {
+			dedupe.Merge(into, contact)
+			removed[contact] = true
+			if contact.ResourceName == "" {
+				continue
+			}
+			mergedInto[contact.ResourceName] = into.ResourceName
+			if photo := backup.Photos[contact.ResourceName]; photo != nil && backup.Photos[into.ResourceName] == nil {
+				backup.Photos[into.ResourceName] = photo
+			}
+			if photo := backup.FallbackPhotos[contact.ResourceName]; photo != nil && backup.FallbackPhotos[into.ResourceName] == nil {
+				backup.FallbackPhotos[into.ResourceName] = photo
+			}
+		}
+	}
+
+	for group, members := range backup.GroupMembers {
+		var kept []string
+		for _, member := range members {
+			if into, ok := mergedInto[member]; ok {
+				member = into
+			}
+			kept = appendUnique(kept, member)
+		}
+		backup.GroupMembers[group] = kept
+	}
+
+	return len(backup.RemoveContacts(func(contact *people.Person) bool { return removed[contact] }))
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/diff"
+)
+
+var (
+	diffJSON   bool
+	diffFormat string
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <older backup> <newer backup>",
+	Short: "Compare two backup files",
+	Long: `Compare two JSON backups of the same account and report the contacts and
+contact groups that were added, removed or changed between them, for example
+to see what changed between weekly backups. Nothing is sent to Google.
+
+Contacts are matched by resource name and compared by content, ignoring
+etags and metadata. Changed contacts are listed with the fields that differ
+(People API names such as emailAddresses); label changes show up as
+memberships. Contacts that were linked to or unlinked from a Google profile
+or another person ("linked people" in the contact's metadata) are listed as
+relinked, and each backup's number of linked contacts is shown. Groups are matched by resource name and reported as changed when
+they were renamed or gained or lost members.
+
+With --format json (or --json), the differences are printed as a JSON object
+with "from", "to", "contacts" and "groups" keys for scripts.
+
+With --format csv, the contact differences are printed as CSV for review in
+a spreadsheet, e.g. before approving a restore: one row per added, removed
+or changed contact, with the old and new values of each field in adjacent
+columns ("Phones (old)", "Phones (new)"). Changed contacts only fill in the
+fields that differ. Fields with several values list them separated by "; ".
+
+Examples:
+  # See what changed between two weekly backups
+  google-contacts-backup diff contacts-20240601-020000.json contacts-20240608-020000.json
+
+  # Machine-readable output
+  google-contacts-backup diff last-week.json today.json --json | jq '.contacts.changed[].name'
+
+  # Review the changes in a spreadsheet
+  google-contacts-backup diff last-week.json today.json --format csv > changes.csv`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	markLocal(diffCmd, nil)
+
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text",
+		"Output format: text, json or csv (one row per changed contact, old and new values side by side)")
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false,
+		"Print the differences as JSON (same as --format json)")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	format := diffFormat
+	if diffJSON {
+		if cmd.Flags().Changed("format") && format != "json" {
+			return fmt.Errorf("--json cannot be combined with --format %s", format)
+		}
+		format = "json"
+	}
+	switch format {
+	case "text", "json", "csv":
+	default:
+		return fmt.Errorf("invalid --format %q: must be text, json or csv", format)
+	}
+
+	from, err := loadBackup(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", args[0], err)
+	}
+	to, err := loadBackup(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", args[1], err)
+	}
+
+	result, err := diff.Backups(from, to)
+	if err != nil {
+		return err
+	}
+	result.From.File = args[0]
+	result.To.File = args[1]
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case "csv":
+		return diff.WriteCSV(os.Stdout, result, from, to)
+	}
+
+	printDiffSummary(result)
+	return nil
+}
+
+// printDiffSummary prints the differences between two backups for humans.
+func printDiffSummary(result *diff.Result) {
+	fmt.Println("Comparing backups:")
+	for _, side := range []struct {
+		label   string
+		summary diff.Summary
+	}{{"From", result.From}, {"To", result.To}} {
+		fmt.Printf("  %-5s %s (%s, %d contacts, %d linked to profiles, %d groups)\n", side.label+":", side.summary.File,
+			side.summary.CreatedAt.Local().Format(time.RFC3339), side.summary.Contacts, side.summary.Linked, side.summary.Groups)
+	}
+	fmt.Println()
+
+	if result.Empty() {
+		fmt.Println("The backups hold the same contacts and groups.")
+		return
+	}
+
+	contacts, groups := result.Contacts, result.Groups
+	fmt.Printf("Contacts: %d added, %d removed, %d changed, %d relinked\n",
+		len(contacts.Added), len(contacts.Removed), len(contacts.Changed), len(contacts.Relinked))
+	fmt.Printf("Groups:   %d added, %d removed, %d changed\n", len(groups.Added), len(groups.Removed), len(groups.Changed))
+	fmt.Println()
+
+	printContactList("Added contacts", contacts.Added)
+	printContactList("Removed contacts", contacts.Removed)
+	if len(contacts.Changed) > 0 {
+		fmt.Printf("Changed contacts (%d):\n", len(contacts.Changed))
+		for _, change := range contacts.Changed {
+			fmt.Printf("  %s (%s): %s\n", change.Name, change.Key, strings.Join(change.Fields, ", "))
+		}
+		fmt.Println()
+	}
+
+	if len(contacts.Relinked) > 0 {
+		fmt.Printf("Relinked contacts (%d):\n", len(contacts.Relinked))
+		for _, change := range contacts.Relinked {
+			var parts []string
+			if len(change.Linked) > 0 {
+				parts = append(parts, "linked to "+strings.Join(change.Linked, ", "))
+			}
+			if len(change.Unlinked) > 0 {
+				parts = append(parts, "unlinked from "+strings.Join(change.Unlinked, ", "))
+			}
+			fmt.Printf("  %s (%s): %s\n", change.Name, change.Key, strings.Join(parts, "; "))
+		}
+		fmt.Println()
+	}
+
+	printGroupList("Added groups", groups.Added)
+	printGroupList("Removed groups", groups.Removed)
+	if len(groups.Changed) > 0 {
+		fmt.Printf("Changed groups (%d):\n", len(groups.Changed))
+		for _, change := range groups.Changed {
+			var parts []string
+			if change.OldName != change.NewName {
+				parts = append(parts, fmt.Sprintf("renamed from %q", change.OldName))
+			}
+			if change.OldMembers != change.NewMembers {
+				parts = append(parts, fmt.Sprintf("%d -> %d members", change.OldMembers, change.NewMembers))
+			}
+			fmt.Printf("  %s (%s): %s\n", change.NewName, change.ResourceName, strings.Join(parts, ", "))
+		}
+		fmt.Println()
+	}
+}
+
+// printContactList prints a titled list of contacts, if there are any.
+func printContactList(title string, contacts []diff.Contact) {
+	if len(contacts) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(contacts))
+	for _, contact := range contacts {
+		fmt.Printf("  %s (%s)\n", contact.Name, contact.Key)
+	}
+	fmt.Println()
+}
+
+// printGroupList prints a titled list of groups, if there are any.
+func printGroupList(title string, groups []diff.Group) {
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(groups))
+	for _, group := range groups {
+		fmt.Printf("  %s (%s, %d members)\n", group.Name, group.ResourceName, group.Members)
+	}
+	fmt.Println()
+}
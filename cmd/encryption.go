@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+
+	"github.com/mheap/google-contacts-backup/internal/encryption"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// loadBackup loads a JSON backup, decrypting it with --identity (or the
+// identities from the config file) if it is encrypted, or with a passphrase
+// if it was encrypted with one. Compressed backups are decompressed; those
+// that are not encrypted are streamed rather than read into memory first.
+// Files named .csv or .vcf (optionally followed by .age) are read as
+// Google-compatible CSV or vCard instead.
+func loadBackup(path string) (*models.BackupFile, error) {
+	return loadBackupAs(path, inputFormat(path))
+}
+
+// inputFormat returns the format of an input file by its name: csv or vcf
+// for .csv and .vcf files, dir for a backup directory, otherwise json. A
+// trailing .age is ignored.
+func inputFormat(path string) string {
+	if models.IsBackupDir(path) {
+		return "dir"
+	}
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(path, ".age"))) {
+	case ".csv":
+		return "csv"
+	case ".vcf":
+		return "vcf"
+	}
+	return "json"
+}
+
+// loadBackupAs loads a file in the given format (see
+// models.ConvertInputFormats) or a backup directory (format "dir"),
+// decrypting files as loadBackup does.
+func loadBackupAs(path, format string) (*models.BackupFile, error) {
+	backup, _, err := loadStoredBackup(path, format)
+	return backup, err
+}
+
+// storedBackup is how a backup file was stored, so a command that changes
+// it can write it back the same way.
+type storedBackup struct {
+	// format is the format of the file, as for loadBackupAs
+	format string
+
+	// compression is the compression of a JSON backup
+	compression string
+
+	// recipients are the recipients to encrypt the file to again, if it
+	// was encrypted
+	recipients []age.Recipient
+}
+
+// loadStoredBackup loads a file as loadBackupAs does, and also returns how
+// it was stored. A file encrypted with a passphrase is encrypted with the
+// same passphrase again; a file encrypted to recipients is encrypted to
+// encryption.recipients from the config file, or else to the recipients of
+// the identities that decrypt it.
+func loadStoredBackup(path, format string) (*models.BackupFile, storedBackup, error) {
+	stored := storedBackup{format: format, compression: models.CompressionNone}
+	if format == "dir" {
+		backup, err := models.LoadDir(path)
+		return backup, stored, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, stored, fmt.Errorf("failed to read backup file: %w", err)
+	}
+	defer file.Close()
+
+	read := func(r *bufio.Reader) (*models.BackupFile, storedBackup, error) {
+		if format == "csv" {
+			backup, err := readCSVImport(r, path)
+			return backup, stored, err
+		}
+		if format == "json" {
+			head, _ := r.Peek(8)
+			stored.compression = models.DetectCompression(head)
+		}
+		backup, err := models.ReadFormat(r, format)
+		return backup, stored, err
+	}
+
+	r := bufio.NewReader(file)
+	if head, _ := r.Peek(64); !encryption.IsEncrypted(head) {
+		return read(r)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, stored, fmt.Errorf("failed to read backup file: %w", err)
+	}
+
+	switch {
+	case encryption.IsPassphraseEncrypted(data):
+		passphrase, err := encryption.ReadPassphrase(fmt.Sprintf("Passphrase for %s: ", path), false)
+		if err != nil {
+			return nil, stored, err
+		}
+		data, err = encryption.DecryptPassphrase(data, passphrase)
+		if err != nil {
+			return nil, stored, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		recipient, err := encryption.PassphraseRecipient(passphrase)
+		if err != nil {
+			return nil, stored, err
+		}
+		stored.recipients = []age.Recipient{recipient}
+	default:
+		paths := identityFiles
+		if len(paths) == 0 {
+			paths = cfg.Encryption.Identities
+		}
+		if len(paths) == 0 {
+			return nil, stored, fmt.Errorf("%s is encrypted: use --identity or set encryption.identities in the config file", path)
+		}
+		identities, err := encryption.LoadIdentities(paths)
+		if err != nil {
+			return nil, stored, err
+		}
+		data, err = encryption.Decrypt(data, identities)
+		if err != nil {
+			return nil, stored, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		stored.recipients, err = encryption.ParseRecipients(cfg.Encryption.Recipients)
+		if err != nil {
+			return nil, stored, err
+		}
+		if len(stored.recipients) == 0 {
+			stored.recipients = encryption.IdentityRecipients(identities)
+		}
+	}
+
+	return read(bufio.NewReader(bytes.NewReader(data)))
+}
+
+// saveStoredBackup writes a backup to path the way a file loaded with
+// loadStoredBackup was stored: in the same format, compressed and encrypted
+// the same way.
+func saveStoredBackup(backup *models.BackupFile, path string, stored storedBackup) error {
+	if stored.format == "dir" {
+		return backup.SaveToDir(path)
+	}
+
+	write := func(w io.Writer) error {
+		return models.WriteCompressed(w, stored.compression, func(w io.Writer) error {
+			return backup.WriteFormat(w, stored.format, models.ConvertOptions{})
+		})
+	}
+	if len(stored.recipients) > 0 {
+		return saveEncrypted(path, stored.recipients, write)
+	}
+	return saveFile(path, write)
+}
+
+// saveEncrypted writes an export to path, encrypted to the recipients, so the
+// plaintext never touches the disk.
+func saveEncrypted(path string, recipients []age.Recipient, write func(io.Writer) error) error {
+	return saveFile(path, func(w io.Writer) error {
+		enc, err := encryption.Encrypt(w, recipients)
+		if err != nil {
+			return err
+		}
+		if err := write(enc); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("failed to encrypt backup file: %w", err)
+		}
+		return nil
+	})
+}
+
+// saveFile creates or truncates path and calls write with it.
+func saveFile(path string, write func(io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/notify"
+	"github.com/mheap/google-contacts-backup/internal/state"
+)
+
+// eventData collects details about the current run for webhook notifications
+// and the run history in the state store
+var eventData = map[string]any{}
+
+// withEvents wraps a command so that its outcome is recorded as the command's
+// last run in the state store, and "<name>.completed" or "<name>.failed" is
+// sent to the configured webhooks when it finishes.
+func withEvents(name string, run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		start := time.Now()
+		if healthServer != nil {
+			healthServer.SetRunning(true)
+		}
+		err := run(cmd, args)
+
+		eventType := name + ".completed"
+		eventData["duration_seconds"] = time.Since(start).Seconds()
+		if err != nil {
+			eventType = name + ".failed"
+			eventData["error"] = err.Error()
+			eventData["exit_code"] = exitCodeFor(err)
+		}
+
+		recordRun(name, start, err)
+
+		// Local commands stay off the network, webhooks included
+		if len(cfg.Webhooks) == 0 || offlineCommand != "" {
+			return err
+		}
+
+		event := notify.NewEvent(eventType, eventData)
+		for _, deliveryErr := range notify.Send(context.Background(), cfg.Webhooks, event) {
+			warnf("failed to deliver webhook: %v", deliveryErr)
+		}
+
+		return err
+	}
+}
+
+// recordRun stores the outcome of a command as its last run.
+func recordRun(name string, start time.Time, err error) {
+	store, openErr := state.Open(stateFile)
+	if openErr != nil {
+		warnf("failed to record run: %v", openErr)
+		return
+	}
+	defer store.Close()
+
+	run := &state.Run{
+		Command:    name,
+		StartedAt:  start.UTC(),
+		FinishedAt: time.Now().UTC(),
+		Details:    eventData,
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if recordErr := store.RecordRun(run); recordErr != nil {
+		warnf("failed to record run: %v", recordErr)
+	}
+	reportRun(run)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+)
+
+// Exit codes returned by the CLI, so wrappers such as cron jobs and CI
+// pipelines can react to specific failures.
+const (
+	exitOK                   = 0
+	exitFailure              = 1
+	exitAuthFailure          = 2
+	exitQuotaExceeded        = 3
+	exitPartialFailure       = 4
+	exitVerificationMismatch = 5
+	exitNothingToDo          = 6
+	exitApprovalRequired     = 7
+)
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode wraps err so that the process exits with the given code.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCodeFor maps an error returned by a command to a process exit code.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+
+	switch {
+	case contacts.IsQuotaError(err):
+		return exitQuotaExceeded
+	case contacts.IsAuthError(err):
+		return exitAuthFailure
+	default:
+		return exitFailure
+	}
+}
+
+// warningCount is the number of warnings reported by the current command.
+var warningCount int
+
+// warnf prints a warning to stderr, keeping it out of machine-readable
+// output, and records it so --strict can fail the command.
+func warnf(format string, args ...any) {
+	warningCount++
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// failOnWarnings turns recorded warnings into a partial failure in --strict mode.
+func failOnWarnings(cmd *cobra.Command, args []string) error {
+	if strictMode && warningCount > 0 {
+		return withExitCode(exitPartialFailure,
+			fmt.Errorf("%d warning(s) reported and --strict is set", warningCount))
+	}
+	return nil
+}
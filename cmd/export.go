@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/evidence"
+	"github.com/mheap/google-contacts-backup/internal/integrity"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	exportInput      string
+	exportOutput     string
+	exportFormatName string
+	exportSince      string
+	exportFilter     string
+	exportModified   string
+	exportCSVLocale  string
+	exportNotesPlain bool
+	exportCSVSources bool
+	exportCharset    string
+	exportMinimal    bool
+	exportNameLength int
+	exportPhoneLen   int
+	exportKeys       bool
+	exportEvidence   bool
+	exportSigningKey string
+	exportTSA        string
+)
+
+// exportFormat is an output format of the export command
+type exportFormat struct {
+	name        string
+	extension   string
+	description string
+	write       func(b *models.BackupFile, w io.Writer) error
+}
+
+// exportFormats lists the formats accepted by export --format
+var exportFormats = []exportFormat{
+	{"json", ".json", "full backup, restorable with this tool", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteJSON(w)
+	}},
+	{"csv", ".csv", "Google-compatible CSV", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteCSV(w, models.CSVOptions{Locale: exportCSVLocale, PlainTextNotes: exportNotesPlain, Sources: exportCSVSources})
+	}},
+	{"vcf", ".vcf", "vCard 3.0, for phones and mail clients", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteVCard(w, models.VCardOptions{PlainTextNotes: exportNotesPlain, Keys: exportKeys})
+	}},
+	{"html", ".html", "printable HTML page", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteHTML(w, models.HTMLOptions{})
+	}},
+	{"vcf21", ".vcf", "vCard 2.1, for feature phones and car head units", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteVCard(w, models.VCardOptions{PlainTextNotes: true, Version: "2.1", Charset: exportCharset})
+	}},
+	{"hubspot", ".csv", "HubSpot contact import CSV", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteTemplateCSV(w, "hubspot", models.TemplateCSVOptions{Charset: exportCharset})
+	}},
+	{"salesforce", ".csv", "Salesforce Data Import Wizard contact CSV", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteTemplateCSV(w, "salesforce", models.TemplateCSVOptions{Charset: exportCharset})
+	}},
+	{"nokia", ".csv", "Nokia PC Suite contacts CSV", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteTemplateCSV(w, "nokia", models.TemplateCSVOptions{Charset: exportCharset})
+	}},
+	{"samsung", ".csv", "Samsung Kies contacts CSV", func(b *models.BackupFile, w io.Writer) error {
+		return b.WriteTemplateCSV(w, "samsung", models.TemplateCSVOptions{Charset: exportCharset})
+	}},
+}
+
+// lookupExportFormat returns the export format with the given name.
+func lookupExportFormat(name string) (exportFormat, error) {
+	names := make([]string, 0, len(exportFormats))
+	for _, format := range exportFormats {
+		if format.name == strings.ToLower(name) {
+			return format, nil
+		}
+		names = append(names, format.name)
+	}
+	return exportFormat{}, fmt.Errorf("invalid format %q: must be one of %s", name, strings.Join(names, ", "))
+}
+
+// exportFormatHelp describes each export format, one per line.
+func exportFormatHelp() string {
+	lines := make([]string, 0, len(exportFormats))
+	for _, format := range exportFormats {
+		lines = append(lines, fmt.Sprintf("  - %-12s %s", format.name+":", format.description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Convert a backup file to another format",
+	Long: `Convert a JSON backup into another format, without contacting Google.
+
+Supported formats:
+` + exportFormatHelp() + `
+
+Older phones and car head units that choke on modern vCards can usually read
+vcf21 or their vendor's CSV. Use --charset to write those formats in a legacy
+character set; characters it cannot represent become "?".
+
+With --minimal, each contact is reduced to its name and primary phone number,
+cut to --max-name-length and --max-phone-length characters, for SIM cards and
+devices that can only hold basic entries. Contacts without a phone number are
+skipped, and phone numbers keep only the characters a phone can dial.
+--minimal works with the csv (a two-column Name,Phone file) and vcf21 formats.
+
+With --keys, vcf exports also carry the PGP keys and S/MIME certificates
+that mail clients store in custom fields or client data (e.g. a "PGP Key"
+field), as vCard KEY properties, so encrypted mail keeps working after moving
+contacts to another client. ASCII-armored keys, base64 keys and key URLs are
+written; fingerprints are not keys and stay in their custom fields.
+
+With --since-backup, only contacts added or changed since an older backup are
+exported, for feeding incremental updates into downstream systems such as a
+CRM. Changes are found by comparing the content hashes of the two backups
+(see 'verify'); contacts deleted since the older backup are counted but not
+exported.
+
+With --modified-since, only contacts Google last updated on or after a date,
+or within a period such as 7d, are exported. It uses the update time each
+backup records per contact (metadata.sources.updateTime), so it needs no
+older backup but does not count deletions. Contacts without a recorded update
+time are left out.
+
+With --evidence, the export is packaged with its provenance for disputes,
+audits and compliance requests: a zip archive holding the contacts as a full
+JSON backup and in --format, the backup's manifest of contact hashes, a
+SHA256SUMS file and an attestation. The attestation records the tool and its
+version, the time, the Google account the tool is signed in to (looked up
+with the People API, so --evidence needs the credentials), the SHA-256 of
+the source backup and the checksum of every exported file. It is signed with
+an ed25519 key created by 'approve keygen' (--signing-key) and timestamped by
+an RFC 3161 timestamp authority (--tsa, default ` + evidence.DefaultTSA + `),
+so anyone can check with sha256sum and openssl that the archive is unchanged
+and existed at that time; the README.txt inside explains how. --tsa none
+skips the timestamp.
+
+Examples:
+  # Export a backup as vCards
+  google-contacts-backup export -i my-contacts.json -f vcf -o contacts.vcf
+
+  # Keep mail encryption keys when moving to another mail client
+  google-contacts-backup export -i my-contacts.json -f vcf --keys -o contacts.vcf
+
+  # Export only what changed since last week's backup
+  google-contacts-backup export -i today.json --since-backup last-week.json -f csv -o delta.csv
+
+  # Export the contacts edited in the last 30 days
+  google-contacts-backup export -i today.json --modified-since 30d -f vcf -o recent.vcf
+
+  # Seed a CRM with everyone at work
+  google-contacts-backup export -i my-contacts.json -f hubspot --filter 'label="Work"' -o hubspot.csv
+
+  # Load contacts onto an old Nokia that expects Windows-1252
+  google-contacts-backup export -i my-contacts.json -f vcf21 --charset windows-1252 -o phone.vcf
+
+  # Fit contacts onto a SIM card with 14-character names
+  google-contacts-backup export -i my-contacts.json -f vcf21 --minimal --max-name-length 14 -o sim.vcf
+
+  # Export with signed, timestamped provenance for a legal hold
+  google-contacts-backup export -i my-contacts.json --evidence --signing-key evidence.key \
+    -o contacts-evidence.zip
+
+  # Export one label as a printable page
+  google-contacts-backup export -i my-contacts.json -f html --filter 'label="Family"'`,
+	RunE: withEvents("export", runExport),
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportInput, "input", "i", "",
+		"Backup file to export (required)")
+	exportCmd.MarkFlagRequired("input")
+
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "",
+		"Output file path (default: export-TIMESTAMP with the format's extension)")
+	exportCmd.Flags().StringVarP(&exportFormatName, "format", "f", "csv",
+		"Export format: json, csv, vcf, html, vcf21, hubspot, salesforce, nokia or samsung")
+	exportCmd.Flags().StringVar(&exportSince, "since-backup", "",
+		"Only export contacts added or changed since this older backup")
+	exportCmd.Flags().StringVar(&exportFilter, "filter", "",
+		"Only export contacts matching this filter expression (see 'help filters')")
+	exportCmd.Flags().StringVar(&exportModified, "modified-since", "",
+		"Only export contacts updated since this date (YYYY-MM-DD), RFC 3339 time or period (e.g. 7d)")
+	exportCmd.Flags().StringVar(&exportCSVLocale, "csv-locale", "en",
+		"Language of CSV headers: "+strings.Join(models.CSVLocales(), ", "))
+	exportCmd.Flags().BoolVar(&exportNotesPlain, "notes-plaintext", false,
+		"Convert HTML notes to plain text in CSV and vCard output")
+	exportCmd.Flags().BoolVar(&exportCSVSources, "csv-sources", false,
+		"Add a column after each CSV field naming its source: CONTACT, PROFILE or DOMAIN_PROFILE")
+	exportCmd.Flags().StringVar(&exportCharset, "charset", "utf-8",
+		"Character set of vcf21, CRM and phone vendor CSV output: "+strings.Join(models.Charsets(), ", "))
+	exportCmd.Flags().BoolVar(&exportKeys, "keys", false,
+		"Write PGP keys and S/MIME certificates from custom fields as vCard KEY properties (vcf only)")
+	exportCmd.Flags().BoolVar(&exportMinimal, "minimal", false,
+		"Export only the name and primary phone number of each contact (csv and vcf21 only)")
+	exportCmd.Flags().IntVar(&exportNameLength, "max-name-length", 20,
+		"With --minimal, cut names to this many characters (0 for no limit)")
+	exportCmd.Flags().IntVar(&exportPhoneLen, "max-phone-length", 20,
+		"With --minimal, cut phone numbers to this many characters (0 for no limit)")
+	exportCmd.Flags().BoolVar(&exportEvidence, "evidence", false,
+		"Write a zip with the export, manifest, checksums and a signed, timestamped attestation")
+	exportCmd.Flags().StringVar(&exportSigningKey, "signing-key", "",
+		"With --evidence, private key created by 'approve keygen' to sign the attestation")
+	exportCmd.Flags().StringVar(&exportTSA, "tsa", evidence.DefaultTSA,
+		"With --evidence, URL of the RFC 3161 timestamp authority, or none")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	format, err := lookupExportFormat(exportFormatName)
+	if err != nil {
+		return err
+	}
+	if !models.IsCSVLocale(exportCSVLocale) {
+		return fmt.Errorf("invalid CSV locale %q: must be one of %s", exportCSVLocale, strings.Join(models.CSVLocales(), ", "))
+	}
+	if !models.IsCharset(exportCharset) {
+		return fmt.Errorf("invalid charset %q: must be one of %s", exportCharset, strings.Join(models.Charsets(), ", "))
+	}
+	if exportKeys && format.name != "vcf" {
+		return fmt.Errorf("--keys only works with the vcf format")
+	}
+	if exportMinimal {
+		switch format.name {
+		case "csv":
+			format.write = func(b *models.BackupFile, w io.Writer) error {
+				return b.WriteTemplateCSV(w, "minimal", models.TemplateCSVOptions{Charset: exportCharset})
+			}
+		case "vcf21":
+		default:
+			return fmt.Errorf("--minimal only works with the csv and vcf21 formats")
+		}
+		if exportNameLength < 0 || exportPhoneLen < 0 {
+			return fmt.Errorf("--max-name-length and --max-phone-length must not be negative")
+		}
+	}
+	if exportEvidence && exportSigningKey == "" {
+		return fmt.Errorf("--evidence needs --signing-key; create one with 'approve keygen'")
+	}
+	if !exportEvidence && (exportSigningKey != "" || cmd.Flags().Changed("tsa")) {
+		return fmt.Errorf("--signing-key and --tsa only apply to --evidence")
+	}
+	expr, err := withModifiedSince(exportFilter, exportModified)
+	if err != nil {
+		return err
+	}
+	selection, err := parseFilter(expr)
+	if err != nil {
+		return err
+	}
+
+	if exportOutput == "" {
+		if exportEvidence {
+			exportOutput = fmt.Sprintf("evidence-%s.zip", time.Now().Format("20060102-150405"))
+		} else {
+			exportOutput = fmt.Sprintf("export-%s%s", time.Now().Format("20060102-150405"), format.extension)
+		}
+	}
+
+	fmt.Printf("Loading backup file: %s\n", exportInput)
+	backup, err := loadBackup(exportInput)
+	if err != nil {
+		return fmt.Errorf("failed to load backup: %w", err)
+	}
+	applyIgnoreList(backup)
+
+	if exportSince != "" {
+		fmt.Printf("Loading older backup file: %s\n", exportSince)
+		older, err := loadBackup(exportSince)
+		if err != nil {
+			return fmt.Errorf("failed to load backup: %w", err)
+		}
+		if err := keepChangedSince(backup, older); err != nil {
+			return err
+		}
+	}
+
+	if selection != nil {
+		removed := selection.Apply(backup)
+		backup.AddTransform(models.TransformFilter, selection.String(), removed)
+		fmt.Printf("Filter kept %d contacts and dropped %d\n", len(backup.Contacts), removed)
+	}
+
+	if exportMinimal {
+		minimal := backup.Minimal(models.MinimalOptions{
+			MaxNameLength:  exportNameLength,
+			MaxPhoneLength: exportPhoneLen,
+		})
+		fmt.Printf("Minimal export: %d contacts have a phone number, %d without one skipped\n",
+			len(minimal.Contacts), len(backup.Contacts)-len(minimal.Contacts))
+		backup = minimal
+	}
+
+	if len(backup.Contacts) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no contacts to export"))
+	}
+
+	if exportEvidence {
+		return runExportEvidence(backup, format)
+	}
+
+	fmt.Printf("\nExporting to %s...\n", exportOutput)
+	file, err := os.OpenFile(exportOutput, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := format.write(backup, file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to export contacts: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	eventData["file"] = exportOutput
+	eventData["format"] = format.name
+	eventData["contacts"] = len(backup.Contacts)
+	eventData["minimal"] = exportMinimal
+
+	fmt.Println()
+	fmt.Println("Export completed successfully!")
+	fmt.Println()
+	fmt.Printf("  Format:   %s\n", format.name)
+	fmt.Printf("  Contacts: %d\n", len(backup.Contacts))
+	fmt.Printf("  File:     %s\n", exportOutput)
+
+	return nil
+}
+
+// keepChangedSince removes the contacts that are unchanged since the older
+// backup, leaving those added or changed.
+func keepChangedSince(backup, older *models.BackupFile) error {
+	current, err := backup.BuildManifest()
+	if err != nil {
+		return err
+	}
+	previous, err := older.BuildManifest()
+	if err != nil {
+		return err
+	}
+
+	diff := integrity.Compare(previous.Contacts, current.Contacts)
+	keep := make(map[string]bool, len(diff.Added)+len(diff.Changed))
+	for _, key := range append(diff.Added, diff.Changed...) {
+		keep[key] = true
+	}
+
+	index := make(map[*people.Person]int, len(backup.Contacts))
+	for i, contact := range backup.Contacts {
+		index[contact] = i
+	}
+	removed := backup.RemoveContacts(func(p *people.Person) bool {
+		return !keep[integrity.Key(p, index[p])]
+	})
+	backup.AddTransform(models.TransformSinceBackup, older.CreatedAt.Format(time.RFC3339), len(removed))
+
+	fmt.Printf("Since %s: %d added, %d changed, %d deleted (deleted contacts are not exported)\n",
+		older.CreatedAt.Format(time.RFC3339), len(diff.Added), len(diff.Changed), len(diff.Removed))
+	return nil
+}
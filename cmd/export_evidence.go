@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/approval"
+	"github.com/mheap/google-contacts-backup/internal/evidence"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// runExportEvidence finishes an export --evidence run.
+func runExportEvidence(backup *models.BackupFile, format exportFormat) error {
+	fmt.Printf("\nWriting evidence archive %s...\n", exportOutput)
+	sealed, err := writeEvidence(backup, format)
+	if err != nil {
+		return err
+	}
+
+	eventData["file"] = exportOutput
+	eventData["format"] = format.name
+	eventData["contacts"] = len(backup.Contacts)
+	eventData["evidence"] = true
+	eventData["timestamped"] = sealed.Timestamp != nil
+
+	a := sealed.Attestation
+	fmt.Println()
+	fmt.Println("Evidence archive written successfully!")
+	fmt.Println()
+	fmt.Printf("  Format:    %s\n", format.name)
+	fmt.Printf("  Contacts:  %d\n", a.Source.Contacts)
+	fmt.Printf("  Account:   %s\n", defaultString(a.Account.Email, a.Account.ResourceName))
+	if sealed.Timestamp != nil {
+		fmt.Printf("  Timestamp: %s (%s)\n", sealed.Timestamp.Time.Local().Format(time.RFC3339), sealed.Timestamp.TSA)
+	}
+	fmt.Printf("  Key:       %s\n", a.PublicKey)
+	fmt.Printf("  File:      %s\n", exportOutput)
+	return nil
+}
+
+// writeEvidence writes the backup, in full and exported in format, to an
+// evidence archive at exportOutput, along with its manifest and an
+// attestation naming the signed-in account, signed with --signing-key and
+// timestamped by --tsa.
+func writeEvidence(backup *models.BackupFile, format exportFormat) (*evidence.Evidence, error) {
+	key, err := approval.LoadPrivateKey(exportSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	tsa := exportTSA
+	if strings.EqualFold(tsa, "none") {
+		tsa = ""
+		warnf("--tsa none: the evidence is not timestamped, so only the local clock dates it")
+	}
+
+	sourceHash, err := approval.HashFile(exportInput)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := backup.BuildManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Identifying the signed-in Google account...")
+	me, err := client.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	account := evidence.Account{ResourceName: me.ResourceName}
+	if len(me.EmailAddresses) > 0 {
+		account.Email = me.EmailAddresses[0].Value
+	}
+	if len(me.Names) > 0 {
+		account.Name = me.Names[0].DisplayName
+	}
+
+	var files []evidence.File
+	add := func(name string, write func(*models.BackupFile, io.Writer) error) error {
+		var b bytes.Buffer
+		if err := write(backup, &b); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		files = append(files, evidence.File{Name: name, Data: b.Bytes()})
+		return nil
+	}
+	// The full backup always goes in, so nothing is lost to the format
+	if err := add("contacts.json", (*models.BackupFile).WriteJSON); err != nil {
+		return nil, err
+	}
+	if format.name != "json" {
+		if err := add("contacts-"+format.name+format.extension, format.write); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	files = append(files, evidence.File{Name: "manifest.json", Data: append(encoded, '\n')})
+
+	host, _ := os.Hostname()
+	attestation := &evidence.Attestation{
+		Tool:      "google-contacts-backup",
+		Version:   Version,
+		CreatedAt: time.Now(),
+		Host:      host,
+		Account:   account,
+		Source: evidence.Source{
+			File:         filepath.Base(exportInput),
+			SHA256:       sourceHash,
+			CreatedAt:    backup.CreatedAt,
+			Contacts:     len(backup.Contacts),
+			Groups:       len(backup.Groups),
+			ManifestRoot: manifest.Root,
+		},
+	}
+	if tsa != "" {
+		fmt.Printf("Timestamping with %s...\n", tsa)
+	}
+	sealed, err := evidence.Seal(ctx, files, attestation, key, approval.EncodePublicKey(key), tsa)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveFile(exportOutput, sealed.WriteZip); err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/filter"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// filtersCmd is a help topic describing filter expressions
+var filtersCmd = &cobra.Command{
+	Use:   "filters",
+	Short: "Filter expressions for selecting contacts",
+	Long: `Commands that select contacts (backup, export, restore, share, tag) accept a --filter
+expression, for example:
+
+  label="Work" && has(phone) && updated>2024-01-01
+
+Expressions combine comparisons and has(FIELD) checks with && (and), || (or)
+and ! (not), grouped with parentheses. A comparison is FIELD OP VALUE, where
+VALUE is a quoted string or a bare word and OP is one of:
+
+  =, ==   equals (ignoring case; for domain, subdomains match too)
+  !=      does not equal
+  ~       contains (ignoring case)
+  >, >=   after (dates only)
+  <, <=   before (dates only)
+
+A field with several values, such as email, matches if any value does; !=
+matches if none does. Dates are YYYY-MM-DD (a whole day) or RFC 3339.
+
+Fields:
+  ` + strings.Join(filter.FieldHelp(), "\n  ") + `
+
+Examples:
+  label="Family" || label="Friends"
+  domain=example.com && !has(phone)
+  tag.source=conference2024
+  name~"smith" && updated>=2024-06-01`,
+}
+
+func init() {
+	rootCmd.AddCommand(filtersCmd)
+}
+
+// parseFilter parses a --filter flag value. An empty expression yields a nil
+// filter, which callers treat as selecting everything.
+func parseFilter(expr string) (*filter.Filter, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return filter.Parse(expr)
+}
+
+// applyIgnoreList removes the contacts on the config file's ignore list from
+// a backup, before anything else selects from it, and returns how many were
+// removed.
+func applyIgnoreList(backup *models.BackupFile) int {
+	removed := cfg.Ignore.Apply(backup)
+	if len(removed) > 0 {
+		fmt.Printf("Ignoring %d contacts on the ignore list\n", len(removed))
+		eventData["ignored"] = len(removed)
+		backup.AddTransform(models.TransformIgnoreList, "", len(removed))
+	}
+	return len(removed)
+}
+
+// withModifiedSince adds the --modified-since condition to a --filter
+// expression. since is a date (YYYY-MM-DD), an RFC 3339 time or a period
+// before now such as 7d or 36h.
+func withModifiedSince(expr, since string) (string, error) {
+	if since == "" {
+		return expr, nil
+	}
+
+	if _, err := time.Parse("2006-01-02", since); err != nil {
+		if _, err := time.Parse(time.RFC3339, since); err != nil {
+			period, err := parsePeriod(since)
+			if err != nil {
+				return "", fmt.Errorf("invalid --modified-since %q: use YYYY-MM-DD, RFC 3339 or a period such as 7d", since)
+			}
+			since = time.Now().Add(-period).UTC().Format(time.RFC3339)
+		}
+	}
+
+	condition := fmt.Sprintf("updated>=%q", since)
+	if expr == "" {
+		return condition, nil
+	}
+	return "(" + expr + ") && " + condition, nil
+}
+
+// matchOperators maps the operators of --match queries to filter operators.
+// Longer operators come first, so "is not" is not read as "is".
+var matchOperators = []struct{ word, op string }{
+	{"does not contain", "!~"},
+	{"contains", "~"},
+	{"is not", "!="},
+	{"is", "="},
+	{"equals", "="},
+}
+
+// parseMatch turns a --match query such as "name contains Smith" into a
+// filter expression. Queries are FIELD OPERATOR VALUE, where the operator is
+// contains, does not contain, is (or equals) or is not, and VALUE is the rest
+// of the query, quoted or not.
+func parseMatch(query string) (string, error) {
+	words := strings.Fields(query)
+	for i := 1; i < len(words); i++ {
+		rest := strings.ToLower(strings.Join(words[i:], " "))
+		for _, operator := range matchOperators {
+			if !strings.HasPrefix(rest, operator.word+" ") {
+				continue
+			}
+			field := strings.Join(words[:i], " ")
+			value := strings.Join(words[i+len(strings.Fields(operator.word)):], " ")
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			if operator.op == "!~" {
+				return fmt.Sprintf("!(%s~%q)", field, value), nil
+			}
+			return fmt.Sprintf("%s%s%q", field, operator.op, value), nil
+		}
+	}
+	return "", fmt.Errorf("invalid --match %q: use FIELD contains VALUE, FIELD is VALUE or FIELD is not VALUE (see 'help filters' for fields)", query)
+}
+
+// withSelection adds the labels of --group and the query of --match to a
+// --filter expression. A contact is selected if it carries any of the labels
+// and matches the query and the expression.
+func withSelection(expr string, groups []string, match string) (string, error) {
+	var conditions []string
+	if expr != "" {
+		conditions = append(conditions, "("+expr+")")
+	}
+	if len(groups) > 0 {
+		labels := make([]string, 0, len(groups))
+		for _, group := range groups {
+			labels = append(labels, fmt.Sprintf("label=%q", group))
+		}
+		conditions = append(conditions, "("+strings.Join(labels, " || ")+")")
+	}
+	if match != "" {
+		condition, err := parseMatch(match)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, "("+condition+")")
+	}
+	if len(conditions) == 1 && expr != "" {
+		return expr, nil
+	}
+	return strings.Join(conditions, " && "), nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/synthetic"
+)
+
+var (
+	generateContacts int
+	generateGroups   int
+	generateOutput   string
+	generateSeed     uint64
+)
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a synthetic backup file with fake contacts",
+	Long: `Generate a backup file filled with realistic but entirely fake contacts and groups.
+
+The output uses the normal JSON backup format, so it can be used to load-test
+restores against a test account or to benchmark exporters without touching
+real personal data. Email addresses use the reserved example.* domains and
+phone numbers use ranges reserved for fiction.
+
+The same --seed always produces the same file.
+
+Examples:
+  # Generate 5000 contacts in 20 groups
+  google-contacts-backup generate --contacts 5000 --groups 20 -o synthetic.json
+
+  # Reproduce a previous file exactly
+  google-contacts-backup generate --contacts 5000 --seed 42 -o synthetic.json`,
+	RunE: runGenerate,
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+	markLocal(generateCmd, nil)
+
+	generateCmd.Flags().IntVar(&generateContacts, "contacts", 1000,
+		"Number of contacts to generate")
+	generateCmd.Flags().IntVar(&generateGroups, "groups", 10,
+		"Number of user contact groups to generate")
+	generateCmd.Flags().StringVarP(&generateOutput, "output", "o", "",
+		"Output file path (default: synthetic-TIMESTAMP.json)")
+	generateCmd.Flags().Uint64Var(&generateSeed, "seed", 0,
+		"Random seed for reproducible output (default: random)")
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	if generateContacts < 0 || generateGroups < 0 {
+		return fmt.Errorf("--contacts and --groups must not be negative")
+	}
+
+	if generateOutput == "" {
+		generateOutput = fmt.Sprintf("synthetic-%s.json", time.Now().Format("20060102-150405"))
+	}
+
+	if !cmd.Flags().Changed("seed") {
+		generateSeed = uint64(time.Now().UnixNano())
+	}
+
+	fmt.Printf("Generating %d contacts in %d groups (seed %d)...\n", generateContacts, generateGroups, generateSeed)
+	backup := synthetic.Generate(synthetic.Options{
+		Contacts: generateContacts,
+		Groups:   generateGroups,
+		Seed:     generateSeed,
+	})
+
+	if err := backup.SaveToFile(generateOutput); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Synthetic backup generated!")
+	fmt.Println()
+	fmt.Printf("  Contacts: %d\n", backup.ContactCount)
+	fmt.Printf("  Groups:   %d\n", len(backup.GetUserGroups()))
+	fmt.Printf("  Seed:     %d\n", generateSeed)
+	fmt.Printf("  File:     %s\n", generateOutput)
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	groupsExportInput  string
+	groupsExportOutput string
+	groupsImportInput  string
+	groupsImportDryRun bool
+)
+
+// groupsCmd groups the groups subcommands
+var groupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "Edit label memberships in a spreadsheet",
+	Long: `Export the label memberships of a backup as a contacts × labels matrix,
+edit it in a spreadsheet, and apply the changes to your Google account.
+
+Examples:
+  # Write the matrix of a backup
+  google-contacts-backup groups export -i my-contacts.json -o labels.csv
+
+  # Preview the changes made in the spreadsheet
+  google-contacts-backup groups import -i labels.csv --dry-run
+
+  # Apply them
+  google-contacts-backup groups import -i labels.csv`,
+}
+
+// groupsExportCmd represents the groups export command
+var groupsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the label memberships of a backup as a CSV matrix",
+	Long: `Write the label memberships of a backup as CSV: one row per contact, with
+its resource name and name, and one column per label holding "x" where the
+contact carries the label. Nothing is sent to Google.`,
+	Args: cobra.NoArgs,
+	RunE: runGroupsExport,
+}
+
+// groupsImportCmd represents the groups import command
+var groupsImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Apply the label memberships of a CSV matrix to the account",
+	Long: `Apply the label memberships of a matrix written by 'groups export' to your
+Google account. Contacts are matched by resource name.
+
+For every label column, contacts marked with x (or 1, yes, true) are added to
+the label and contacts with an empty cell (or 0, no, false) are removed from
+it. Labels without a column and contacts without a row are left alone, and so
+are contacts that no longer exist in the account. A column for a label the
+account does not have creates the label, if any contact is marked in it.
+
+Memberships are changed in batches of up to 1000 contacts per label; the
+contacts themselves are not updated.`,
+	Args: cobra.NoArgs,
+	RunE: runGroupsImport,
+}
+
+func init() {
+	rootCmd.AddCommand(groupsCmd)
+	groupsCmd.AddCommand(groupsExportCmd)
+	groupsCmd.AddCommand(groupsImportCmd)
+	markLocal(groupsExportCmd, nil)
+
+	groupsExportCmd.Flags().StringVarP(&groupsExportInput, "input", "i", "",
+		"Backup file to export the memberships of (required)")
+	groupsExportCmd.MarkFlagRequired("input")
+	groupsExportCmd.Flags().StringVarP(&groupsExportOutput, "output", "o", "",
+		"Output CSV file (default: standard output)")
+
+	groupsImportCmd.Flags().StringVarP(&groupsImportInput, "input", "i", "",
+		"Membership matrix CSV file (required)")
+	groupsImportCmd.MarkFlagRequired("input")
+	groupsImportCmd.Flags().BoolVar(&groupsImportDryRun, "dry-run", false,
+		"List the memberships that would change without changing them")
+}
+
+func runGroupsExport(cmd *cobra.Command, args []string) error {
+	backup, err := loadBackup(groupsExportInput)
+	if err != nil {
+		return err
+	}
+	matrix := backup.MembershipMatrix()
+
+	if groupsExportOutput == "" {
+		return matrix.WriteCSV(os.Stdout)
+	}
+	if err := saveFile(groupsExportOutput, func(w io.Writer) error { return matrix.WriteCSV(w) }); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d contacts and %d labels to %s\n", len(matrix.Rows), len(matrix.Labels), groupsExportOutput)
+	return nil
+}
+
+// membershipChange is the contacts to add to and remove from a label
+type membershipChange struct {
+	label  string
+	add    []string
+	remove []string
+}
+
+func runGroupsImport(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	file, err := os.Open(groupsImportInput)
+	if err != nil {
+		return fmt.Errorf("failed to read membership matrix: %w", err)
+	}
+	matrix, err := models.ReadMembershipMatrix(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+	live, liveGroups, err := fetchLiveAccount(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	liveContacts := make(map[string]*people.Person, len(live))
+	for _, contact := range live {
+		liveContacts[contact.ResourceName] = contact
+	}
+	labelGroups := make(map[string]string)
+	for resourceName, name := range groupNamesOf(liveGroups) {
+		labelGroups[name] = resourceName
+	}
+
+	changes, missing := planMembershipChanges(matrix, liveContacts, labelGroups)
+	if len(missing) > 0 {
+		warnf("%d contacts in the matrix are not in the account and are skipped", len(missing))
+		for _, row := range missing {
+			fmt.Printf("  %s (%s)\n", row.Name, row.ResourceName)
+		}
+		fmt.Println()
+	}
+
+	added, removed := 0, 0
+	var newLabels []*people.ContactGroup
+	for _, change := range changes {
+		added += len(change.add)
+		removed += len(change.remove)
+		if labelGroups[change.label] == "" {
+			newLabels = append(newLabels, &people.ContactGroup{
+				ResourceName: "contactGroups/" + change.label,
+				Name:         change.label,
+				GroupType:    "USER_CONTACT_GROUP",
+			})
+		}
+		fmt.Printf("%s: add %d, remove %d\n", change.label, len(change.add), len(change.remove))
+	}
+	if len(changes) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("the account already matches the matrix"))
+	}
+	fmt.Println()
+
+	if groupsImportDryRun {
+		if len(newLabels) > 0 {
+			printGroupNames("Would create", newLabels)
+		}
+		for _, change := range changes {
+			for _, resourceName := range change.add {
+				fmt.Printf("  + %s: %s (%s)\n", change.label, models.DisplayName(liveContacts[resourceName]), resourceName)
+			}
+			for _, resourceName := range change.remove {
+				fmt.Printf("  - %s: %s (%s)\n", change.label, models.DisplayName(liveContacts[resourceName]), resourceName)
+			}
+		}
+		fmt.Println()
+		fmt.Println("Dry run: no memberships were changed.")
+		return nil
+	}
+
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	if len(newLabels) > 0 {
+		groupMap, _, err := matchGroups(ctx, client, newLabels, liveGroups)
+		if err != nil {
+			return err
+		}
+		for _, group := range newLabels {
+			labelGroups[group.Name] = groupMap[group.ResourceName]
+		}
+		fmt.Printf("Created %d labels\n", len(newLabels))
+	}
+
+	bar := progressbar.NewOptions(added+removed,
+		progressbar.OptionSetDescription("Updating labels"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	done := 0
+	for _, change := range changes {
+		err := client.ModifyGroupMembers(ctx, labelGroups[change.label], change.add, change.remove, func(modified, total int) {
+			bar.Set(done + modified)
+		})
+		if err != nil {
+			bar.Finish()
+			fmt.Println()
+			return fmt.Errorf("failed to update label %s: %w", change.label, err)
+		}
+		done += len(change.add) + len(change.remove)
+	}
+	bar.Finish()
+	fmt.Println()
+
+	fmt.Println()
+	fmt.Printf("Added %d and removed %d label memberships across %d labels\n", added, removed, len(changes))
+	return nil
+}
+
+// planMembershipChanges compares the matrix with the live account and returns
+// the changes per label, in column order, and the rows of contacts that are
+// not in the account. labelGroups maps live label names to resource names.
+func planMembershipChanges(matrix *models.MembershipMatrix, liveContacts map[string]*people.Person, labelGroups map[string]string) ([]membershipChange, []models.MembershipRow) {
+	var missing []models.MembershipRow
+	for _, row := range matrix.Rows {
+		if liveContacts[row.ResourceName] == nil {
+			missing = append(missing, row)
+		}
+	}
+
+	var changes []membershipChange
+	for _, label := range matrix.Labels {
+		change := membershipChange{label: label}
+		group := labelGroups[label]
+		for _, row := range matrix.Rows {
+			contact := liveContacts[row.ResourceName]
+			if contact == nil {
+				continue
+			}
+			member := group != "" && hasGroupMembership(contact, group)
+			switch {
+			case row.Labels[label] && !member:
+				change.add = append(change.add, row.ResourceName)
+			case !row.Labels[label] && member:
+				change.remove = append(change.remove, row.ResourceName)
+			}
+		}
+		if len(change.add) > 0 || len(change.remove) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes, missing
+}
+
+// hasGroupMembership reports whether the contact is a member of the group.
+func hasGroupMembership(contact *people.Person, group string) bool {
+	for _, membership := range contact.Memberships {
+		if membership.ContactGroupMembership != nil && membership.ContactGroupMembership.ContactGroupResourceName == group {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+
+	"github.com/mheap/google-contacts-backup/internal/health"
+	"github.com/mheap/google-contacts-backup/internal/state"
+	"github.com/mheap/google-contacts-backup/internal/trend"
+)
+
+var (
+	// serveHealth is the address of the health endpoints, if any
+	serveHealth string
+
+	// healthServer serves --serve-health while the command runs, or is nil
+	healthServer *health.Server
+)
+
+// startHealthServer serves /healthz, /last-run and /metrics on the --serve-health
+// address, reporting on runs of the given command.
+func startHealthServer(command string) error {
+	if serveHealth == "" {
+		return nil
+	}
+
+	var lastRun *state.Run
+	var sizes *trend.Trend
+	store, err := state.Open(stateFile)
+	if err != nil {
+		warnf("failed to read the last run: %v", err)
+	} else {
+		lastRun, err = store.LastRun(command)
+		if err != nil {
+			warnf("failed to read the last run: %v", err)
+		}
+		sizes = loadTrend(store)
+		store.Close()
+	}
+
+	healthServer = health.New(command, lastRun)
+	healthServer.SetTrend(sizes)
+	if err := healthServer.Start(serveHealth); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Serving /healthz, /last-run and /metrics on %s\n", serveHealth)
+
+	return nil
+}
+
+// reportRun passes a finished run to the health endpoints, if they are served.
+func reportRun(run *state.Run) {
+	if healthServer == nil {
+		return
+	}
+	// Details is the live eventData map, which webhooks still add to
+	copied := *run
+	copied.Details = maps.Clone(run.Details)
+	healthServer.RecordRun(&copied)
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/auth"
+	"github.com/mheap/google-contacts-backup/internal/config"
+	"github.com/mheap/google-contacts-backup/internal/daemon"
+	"github.com/mheap/google-contacts-backup/internal/state"
+	"github.com/mheap/google-contacts-backup/internal/storage"
+)
+
+// scheduleCrontab maps backup schedules to crontab time fields
+var scheduleCrontab = map[string]string{
+	"hourly": "0 * * * *",
+	"daily":  "0 2 * * *",
+	"weekly": "0 2 * * 0",
+}
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up credentials, authentication and backup settings interactively",
+	Long: `Walk through first-time setup step by step and write the config file at the end.
+
+The wizard:
+  1. Installs the OAuth credentials file downloaded from Google Cloud Console
+  2. Authenticates with Google (the same as 'auth')
+  3. Chooses a directory for backups, and optionally an S3 or Google Cloud
+     Storage destination for 'upload'
+  4. Chooses how often backups should run, for 'daemon install' or cron
+  5. Chooses how long uploaded S3 backups are locked against deletion
+
+Press Enter to keep the value shown in brackets. Running init again edits
+the existing settings; keys the wizard does not ask about are kept.
+
+Examples:
+  # Set everything up
+  google-contacts-backup init
+
+  # Write the settings somewhere other than the default config file
+  google-contacts-backup init --config ./config.json`,
+	RunE: runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	settings := *cfg
+
+	fmt.Println("Welcome to google-contacts-backup!")
+	fmt.Printf("This wizard writes your settings to %s.\n", configFile)
+	fmt.Println()
+
+	// Step 1: OAuth credentials
+	fmt.Println("Step 1/5: Google API credentials")
+	if err := auth.NewAuthenticator(credentialsFile).ValidateCredentials(); err == nil {
+		fmt.Printf("Using credentials file: %s\n", credentialsFile)
+	} else {
+		fmt.Println("Download OAuth credentials from Google Cloud Console:")
+		fmt.Println("  1. Go to https://console.cloud.google.com/")
+		fmt.Println("  2. Create or select a project")
+		fmt.Println("  3. Enable the People API")
+		fmt.Println("  4. Create OAuth 2.0 credentials (Desktop application)")
+		fmt.Println("  5. Download the credentials JSON file")
+		fmt.Println()
+		source, err := askString("Path to the downloaded credentials file", "", func(path string) error {
+			return auth.NewAuthenticator(expandHome(path)).ValidateCredentials()
+		})
+		if err != nil {
+			return err
+		}
+		if err := installCredentials(expandHome(source), credentialsFile); err != nil {
+			return err
+		}
+		fmt.Printf("Saved credentials to %s\n", credentialsFile)
+	}
+	fmt.Println()
+
+	// Step 2: authentication
+	fmt.Println("Step 2/5: Authentication")
+	ok, err := askConfirmation("Authenticate with Google now? (yes/no): ")
+	if err != nil {
+		return err
+	}
+	if ok {
+		if _, err := auth.NewAuthenticator(credentialsFile).GetClient(ctx); err != nil {
+			return withExitCode(exitAuthFailure, fmt.Errorf("authentication failed: %w", err))
+		}
+		fmt.Println("Authentication successful!")
+	} else {
+		fmt.Println("Skipped; run 'google-contacts-backup auth' later.")
+	}
+	fmt.Println()
+
+	// Step 3: where backups go
+	fmt.Println("Step 3/5: Backup location")
+	directory := settings.Backup.Directory
+	if directory == "" {
+		directory = expandHome("~/contacts-backups")
+	}
+	directory, err = askString("Directory for backups", directory, nil)
+	if err != nil {
+		return err
+	}
+	settings.Backup.Directory = expandHome(directory)
+	if err := os.MkdirAll(settings.Backup.Directory, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	destination, err := askString("Upload destination, s3://bucket/prefix or gs://bucket/prefix (\"none\" for none)",
+		defaultString(settings.Upload.Destination, "none"), func(value string) error {
+			if value == "none" {
+				return nil
+			}
+			_, err := storage.ParseLocation(value)
+			return err
+		})
+	if err != nil {
+		return err
+	}
+	if destination == "none" {
+		destination = ""
+	}
+	settings.Upload.Destination = destination
+	fmt.Println()
+
+	// Step 4: schedule
+	fmt.Println("Step 4/5: Schedule")
+	schedule, err := askString("How often should backups run? ("+strings.Join(daemon.Schedules, ", ")+" or none)",
+		defaultString(settings.Backup.Schedule, "daily"), func(value string) error {
+			if value != "none" && !slices.Contains(daemon.Schedules, value) {
+				return fmt.Errorf("must be one of %s or none", strings.Join(daemon.Schedules, ", "))
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	if schedule == "none" {
+		schedule = ""
+	}
+	settings.Backup.Schedule = schedule
+	fmt.Println()
+
+	// Step 5: retention
+	fmt.Println("Step 5/5: Retention")
+	switch {
+	case strings.HasPrefix(destination, "s3://"):
+		days, err := askString("Lock uploaded backups against deletion for how many days? (0 for no lock)",
+			strconv.Itoa(settings.Upload.RetainDays), func(value string) error {
+				if n, err := strconv.Atoi(value); err != nil || n < 0 {
+					return fmt.Errorf("must be a whole number of days, 0 or more")
+				}
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+		settings.Upload.RetainDays, _ = strconv.Atoi(days)
+	case destination != "":
+		fmt.Println("Object lock is only available for S3 destinations; skipped.")
+	default:
+		fmt.Println("No upload destination; skipped.")
+	}
+	fmt.Println()
+
+	if err := settings.Save(configFile); err != nil {
+		return err
+	}
+	cfg = &settings
+
+	fmt.Println("Setup completed successfully!")
+	fmt.Println()
+	fmt.Printf("  Config:      %s\n", configFile)
+	fmt.Printf("  Backups:     %s\n", settings.Backup.Directory)
+	fmt.Printf("  Upload:      %s\n", defaultString(settings.Upload.Destination, "none"))
+	fmt.Printf("  Schedule:    %s\n", defaultString(settings.Backup.Schedule, "none"))
+	if settings.Upload.RetainDays > 0 {
+		fmt.Printf("  Retention:   %d days\n", settings.Upload.RetainDays)
+	}
+	fmt.Println()
+
+	if settings.Backup.Schedule != "" {
+		fmt.Printf("To run backups %s, install the scheduler entry for this system:\n", settings.Backup.Schedule)
+		fmt.Println("  google-contacts-backup daemon install")
+		fmt.Println("or add this line to your crontab (crontab -e):")
+		fmt.Printf("  %s %s\n", scheduleCrontab[settings.Backup.Schedule], strings.Join(backupCommand(), " "))
+		fmt.Println()
+	}
+	fmt.Println("You can now run:")
+	fmt.Println("  google-contacts-backup backup    # to backup your contacts")
+
+	return nil
+}
+
+// installCredentials copies a downloaded credentials file into place.
+func installCredentials(source, target string) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(target, data, 0600); err != nil {
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return nil
+}
+
+// backupCommand returns the command a scheduler should run to take a
+// backup with the current settings.
+func backupCommand() []string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "google-contacts-backup"
+	}
+	command := []string{exe, "backup"}
+	if configFile != config.DefaultPath() {
+		command = append(command, "--config", configFile)
+	}
+	if credentialsFile != getDefaultCredentialsPath() {
+		command = append(command, "--credentials", credentialsFile)
+	}
+	if stateFile != state.DefaultPath() {
+		command = append(command, "--state-file", stateFile)
+	}
+	return command
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// defaultString returns value, or fallback if value is empty.
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	inspectInput string
+	inspectJSON  bool
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Summarize a backup file and check its structure",
+	Long: `Print what a backup holds without restoring it or reaching for jq: its format
+version, creation date and tag, the number of contacts, other contacts, groups
+and photos, every group with its number of members, and for each contact
+field how many contacts have it. Nothing is sent to Google.
+
+The backup's structure is checked too: the contact and group counts must
+agree with its contents, every contact and group must have a distinct
+resource name, memberships, member lists and photos must refer to contacts
+and groups in the backup, and the contacts must match the manifest. Problems
+are listed and exit with code 5. Use verify for a full check including the
+People API's limits.
+
+Encrypted (.age) and compressed backups are read like any other; so are CSV
+and vCard files, as restore reads them.
+
+Examples:
+  # Summarize a backup
+  google-contacts-backup inspect -i my-contacts.json
+
+  # Machine-readable output for scripts
+  google-contacts-backup inspect -i my-contacts.json --json`,
+	Args: cobra.NoArgs,
+	RunE: withEvents("inspect", runInspect),
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	markLocal(inspectCmd, nil)
+
+	inspectCmd.Flags().StringVarP(&inspectInput, "input", "i", "",
+		"Backup file to inspect (required)")
+	inspectCmd.MarkFlagRequired("input")
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false,
+		"Print the summary as JSON")
+}
+
+// inspectResult is the machine-readable output of the inspect command
+type inspectResult struct {
+	File          string                `json:"file"`
+	Version       string                `json:"version"`
+	CreatedAt     time.Time             `json:"created_at"`
+	Tag           string                `json:"tag,omitempty"`
+	Transforms    int                   `json:"transforms"`
+	Contacts      int                   `json:"contacts"`
+	OtherContacts int                   `json:"other_contacts"`
+	Groups        int                   `json:"groups"`
+	UserGroups    int                   `json:"user_groups"`
+	Photos        int                   `json:"photos"`
+	Manifest      bool                  `json:"manifest"`
+	GroupMembers  []models.GroupSummary `json:"group_members"`
+	FieldCoverage map[string]int        `json:"field_coverage"`
+	Problems      []string              `json:"problems"`
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	backup, err := loadBackup(inspectInput)
+	if err != nil {
+		return fmt.Errorf("failed to load backup: %w", err)
+	}
+
+	result := inspectResult{
+		File:          inspectInput,
+		Version:       backup.Version,
+		CreatedAt:     backup.CreatedAt,
+		Tag:           backup.Tag,
+		Transforms:    len(backup.Transforms),
+		Contacts:      len(backup.Contacts),
+		OtherContacts: len(backup.OtherContacts),
+		Groups:        len(backup.Groups),
+		UserGroups:    len(backup.GetUserGroups()),
+		Photos:        len(backup.Photos),
+		Manifest:      backup.Manifest != nil,
+		GroupMembers:  backup.GroupSummaries(),
+		FieldCoverage: backup.FieldCoverage(),
+		Problems:      backup.StructureProblems(),
+	}
+	if result.Problems == nil {
+		result.Problems = []string{}
+	}
+
+	eventData["file"] = inspectInput
+	eventData["contacts"] = result.Contacts
+	eventData["problems"] = len(result.Problems)
+
+	if inspectJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		printInspectResult(result)
+	}
+
+	if len(result.Problems) > 0 {
+		return withExitCode(exitVerificationMismatch, fmt.Errorf("the backup has %d structural problems", len(result.Problems)))
+	}
+	return nil
+}
+
+// printInspectResult prints the summary for people.
+func printInspectResult(result inspectResult) {
+	fmt.Printf("  File:           %s\n", result.File)
+	fmt.Printf("  Version:        %s\n", defaultString(result.Version, "(none)"))
+	fmt.Printf("  Created:        %s\n", result.CreatedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	if result.Tag != "" {
+		fmt.Printf("  Tag:            %s\n", result.Tag)
+	}
+	if result.Transforms > 0 {
+		fmt.Printf("  Transforms:     %d (the backup is partial)\n", result.Transforms)
+	}
+	fmt.Printf("  Contacts:       %d\n", result.Contacts)
+	if result.OtherContacts > 0 {
+		fmt.Printf("  Other contacts: %d\n", result.OtherContacts)
+	}
+	fmt.Printf("  Groups:         %d (%d user)\n", result.Groups, result.UserGroups)
+	fmt.Printf("  Photos:         %d\n", result.Photos)
+	if result.Manifest {
+		fmt.Println("  Manifest:       yes")
+	} else {
+		fmt.Println("  Manifest:       no")
+	}
+
+	if len(result.GroupMembers) > 0 {
+		fmt.Println()
+		fmt.Println("Groups:")
+		for _, group := range result.GroupMembers {
+			name := group.Name
+			if group.GroupType != "USER_CONTACT_GROUP" {
+				name += " (system)"
+			}
+			fmt.Printf("  %-40s %6d\n", name, group.Members)
+		}
+	}
+
+	if len(result.FieldCoverage) > 0 {
+		fields := make([]string, 0, len(result.FieldCoverage))
+		for field := range result.FieldCoverage {
+			fields = append(fields, field)
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			if result.FieldCoverage[fields[i]] != result.FieldCoverage[fields[j]] {
+				return result.FieldCoverage[fields[i]] > result.FieldCoverage[fields[j]]
+			}
+			return fields[i] < fields[j]
+		})
+		fmt.Println()
+		fmt.Println("Field coverage:")
+		for _, field := range fields {
+			count := result.FieldCoverage[field]
+			fmt.Printf("  %-20s %6d  %5.1f%%\n", field, count, 100*float64(count)/float64(result.Contacts))
+		}
+	}
+
+	fmt.Println()
+	if len(result.Problems) == 0 {
+		fmt.Println("Structure OK.")
+		return
+	}
+	fmt.Println("Structural problems:")
+	for _, problem := range result.Problems {
+		fmt.Printf("  %s\n", problem)
+	}
+	fmt.Println()
+}
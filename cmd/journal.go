@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/journal"
+)
+
+// runJournal is the audit journal of the current run, if one was requested
+var runJournal *journal.Journal
+
+// openJournal starts the audit journal at path for command and records the
+// start of the run. An empty path disables the journal.
+func openJournal(path, command string, details map[string]any) error {
+	if path == "" {
+		return nil
+	}
+	j, err := journal.Open(expandHome(path), command)
+	if err != nil {
+		return err
+	}
+	runJournal = j
+	writeJournal(journal.Record{Event: journal.EventStart, Details: details})
+	return nil
+}
+
+// closeJournal records how the run ended and closes the journal.
+func closeJournal(start time.Time, err error) {
+	if runJournal == nil {
+		return
+	}
+	record := journal.Record{
+		Event:      journal.EventFinish,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	writeJournal(record)
+	runJournal.Close()
+	runJournal = nil
+}
+
+// journalStep records that a phase of the run completed.
+func journalStep(step string, details map[string]any) {
+	writeJournal(journal.Record{Event: journal.EventStep, Step: step, Details: details})
+}
+
+// journalBatch records a create or update batch reported by the contacts client.
+func journalBatch(batch contacts.Batch) {
+	record := journal.Record{
+		Event:      journal.EventBatch,
+		Operation:  batch.Operation,
+		Batch:      &batch.Index,
+		Contacts:   batch.Contacts,
+		Created:    batch.Created,
+		DurationMS: batch.Duration.Milliseconds(),
+	}
+	if batch.Err != nil {
+		record.Error = batch.Err.Error()
+	}
+	writeJournal(record)
+}
+
+// journalOptions returns the contacts client options that feed the journal.
+func journalOptions() []contacts.Option {
+	if runJournal == nil {
+		return nil
+	}
+	return []contacts.Option{contacts.WithBatchHandler(journalBatch)}
+}
+
+// writeJournal appends record to the journal, if one is open. Write errors
+// are warnings: the run itself goes on.
+func writeJournal(record journal.Record) {
+	if runJournal == nil {
+		return
+	}
+	if err := runJournal.Write(record); err != nil {
+		warnf("%v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+// errOffline is returned for any network request made by a command that
+// works on local files alone
+var errOffline = errors.New("network access is disabled for commands that work on local files")
+
+// localCommands are the commands that work on backup files alone, each with
+// a check of whether the flags given keep it local, e.g. verify without
+// --against-live. They are filled in by markLocal from the commands' init.
+var localCommands = map[*cobra.Command]func() bool{}
+
+// offlineCommand is the name of the running command if it works on local
+// files alone; no People API client can be created and every HTTP request
+// fails while it is set.
+var offlineCommand string
+
+// markLocal marks a command as working on local files alone, whenever local
+// reports true; a nil local means always.
+func markLocal(cmd *cobra.Command, local func() bool) {
+	if local == nil {
+		local = func() bool { return true }
+	}
+	localCommands[cmd] = local
+}
+
+// enterOffline cuts off the network for a local command, so that it works the
+// same on an air-gapped machine holding the backups: the command cannot
+// create a People API client or refresh the OAuth token, webhooks are not
+// sent, and any other HTTP request through the default transport fails.
+//
+// Local commands never build a client: newContactsClient refuses through
+// checkOnline before reading the token. Replacing the default transport as
+// well catches requests made without a client, such as by a library, and
+// affects nothing else, since a process runs exactly one command and the
+// transport is replaced before the command starts.
+func enterOffline(cmd *cobra.Command) {
+	local, ok := localCommands[cmd]
+	if !ok || !local() {
+		return
+	}
+	offlineCommand = cmd.CommandPath()
+	http.DefaultTransport = offlineTransport{}
+	http.DefaultClient = &http.Client{Transport: offlineTransport{}}
+}
+
+// checkOnline refuses to reach Google from a local command.
+func checkOnline() error {
+	if offlineCommand == "" {
+		return nil
+	}
+	return fmt.Errorf("%s works on local files and never connects to Google: %w", offlineCommand, errOffline)
+}
+
+// offlineTransport fails every request.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Redacted(), errOffline)
+}
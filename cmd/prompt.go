@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+)
+
+// stdin is shared by all prompts, so answers piped in by scripts are not
+// lost to a reader's buffer between prompts
+var stdin = bufio.NewReader(os.Stdin)
+
+// readAnswer reads one line from stdin, without the line ending. A last
+// line without a newline is accepted; running out of input is an error.
+func readAnswer() (string, error) {
+	response, err := stdin.ReadString('\n')
+	if err != nil && (response == "" || !errors.Is(err, io.EOF)) {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return strings.TrimSpace(response), nil
+}
+
+// askConfirmation prints a prompt and reports whether the user agreed, in
+// the language selected with --lang or in English.
+func askConfirmation(prompt string) (bool, error) {
+	fmt.Print(prompt)
+
+	response, err := readAnswer()
+	if err != nil {
+		return false, err
+	}
+
+	return i18n.IsYes(response), nil
+}
+
+// askString prints a prompt showing the default answer and returns the
+// user's answer, or the default if the answer is empty. The answer is asked
+// again until validate accepts it.
+func askString(prompt, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			fmt.Printf("%s [%s]: ", prompt, defaultValue)
+		} else {
+			fmt.Printf("%s: ", prompt)
+		}
+
+		response, err := readAnswer()
+		if err != nil {
+			return "", err
+		}
+		if response == "" {
+			response = defaultValue
+		}
+		if validate == nil {
+			return response, nil
+		}
+		if err := validate(response); err != nil {
+			fmt.Printf("  %v\n", err)
+			continue
+		}
+		return response, nil
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+	"github.com/mheap/google-contacts-backup/internal/prune"
+)
+
+var (
+	pruneDir         string
+	pruneKeepLast    int
+	pruneKeepDaily   int
+	pruneKeepWeekly  int
+	pruneKeepMonthly int
+	pruneDryRun      bool
+	pruneConfirm     bool
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old backups according to a retention policy",
+	Long: `Delete old backups from a directory, keeping the ones a retention policy
+selects, so scheduled backups don't fill up the disk.
+
+Only backups with the backup command's default names are considered, such as
+contacts-20240601-020000.json, contacts-20240601-020000.csv and their
+compressed (.gz, .zst) and encrypted (.age) variants, and dir-format backups
+in directories such as contacts-20240601-020000, which are deleted with
+everything in them; the time a backup was taken is read from its name.
+Other files in the directory are never touched.
+
+Each rule keeps the newest backup of that many periods, counting back from
+the newest backup and skipping periods without one:
+  --keep-last     the newest backups, whenever they were taken
+  --keep-daily    one backup per day
+  --keep-weekly   one backup per ISO week (Monday to Sunday)
+  --keep-monthly  one backup per calendar month
+A backup is kept if any rule keeps it, and everything else is deleted. Files
+taken at the same time, such as the .json and .csv backups of one run, count
+as one backup and are kept or deleted together. The directory defaults to backup.directory, and the rules default to the "prune"
+section of the config file, so a scheduled job can simply run
+'prune --confirm' after each backup.
+
+Examples:
+  # Keep a week of daily backups and a month of weekly ones
+  google-contacts-backup prune --dir ~/backups --keep-daily 7 --keep-weekly 4
+
+  # See what would be deleted without deleting anything
+  google-contacts-backup prune --dir ~/backups --keep-daily 7 --dry-run
+
+  # Use the policy and directory from the config file, without prompting
+  google-contacts-backup prune --confirm`,
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	markLocal(pruneCmd, nil)
+
+	pruneCmd.Flags().StringVar(&pruneDir, "dir", "",
+		"Directory holding the backups (default: backup.directory)")
+	pruneCmd.Flags().IntVar(&pruneKeepLast, "keep-last", 0,
+		"Keep the newest N backups (overrides prune.keep_last)")
+	pruneCmd.Flags().IntVar(&pruneKeepDaily, "keep-daily", 0,
+		"Keep one backup for each of the last N days with backups (overrides prune.keep_daily)")
+	pruneCmd.Flags().IntVar(&pruneKeepWeekly, "keep-weekly", 0,
+		"Keep one backup for each of the last N weeks with backups (overrides prune.keep_weekly)")
+	pruneCmd.Flags().IntVar(&pruneKeepMonthly, "keep-monthly", 0,
+		"Keep one backup for each of the last N months with backups (overrides prune.keep_monthly)")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false,
+		"List the backups that would be deleted without deleting them")
+	pruneCmd.Flags().BoolVar(&pruneConfirm, "confirm", false,
+		"Skip confirmation prompt")
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	dir := pruneDir
+	if dir == "" {
+		dir = cfg.Backup.Directory
+	}
+	if dir == "" {
+		return fmt.Errorf("no backup directory: use --dir or set backup.directory in the config file")
+	}
+	dir = expandHome(dir)
+
+	policy := prune.Policy{
+		Last:    cfg.Prune.KeepLast,
+		Daily:   cfg.Prune.KeepDaily,
+		Weekly:  cfg.Prune.KeepWeekly,
+		Monthly: cfg.Prune.KeepMonthly,
+	}
+	if cmd.Flags().Changed("keep-last") {
+		policy.Last = pruneKeepLast
+	}
+	if cmd.Flags().Changed("keep-daily") {
+		policy.Daily = pruneKeepDaily
+	}
+	if cmd.Flags().Changed("keep-weekly") {
+		policy.Weekly = pruneKeepWeekly
+	}
+	if cmd.Flags().Changed("keep-monthly") {
+		policy.Monthly = pruneKeepMonthly
+	}
+	if policy.Empty() {
+		return fmt.Errorf("no retention policy: use --keep-last, --keep-daily, --keep-weekly or --keep-monthly, or set them in the prune section of the config file")
+	}
+
+	backups, err := prune.Scan(dir)
+	if err != nil {
+		return err
+	}
+	keep, remove := prune.Plan(backups, policy)
+
+	fmt.Printf("Found %d backups in %s\n", len(backups), dir)
+	fmt.Println()
+	if len(keep) > 0 {
+		fmt.Printf("Keeping %d backups:\n", len(keep))
+		for _, backup := range keep {
+			fmt.Printf("  %s  %s (%s)\n", backup.Time.Format("2006-01-02 15:04"),
+				filepath.Base(backup.Path), strings.Join(backup.Reasons, ", "))
+		}
+		fmt.Println()
+	}
+	if len(remove) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no backups to delete"))
+	}
+	fmt.Printf("Deleting %d backups:\n", len(remove))
+	for _, backup := range remove {
+		fmt.Printf("  %s  %s\n", backup.Time.Format("2006-01-02 15:04"), filepath.Base(backup.Path))
+	}
+	fmt.Println()
+
+	if pruneDryRun {
+		fmt.Println("Dry run: no backups were deleted.")
+		return nil
+	}
+
+	if !pruneConfirm {
+		ok, err := askConfirmation(i18n.T("prune.confirm", len(remove), dir))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println(i18n.T("prune.cancelled"))
+			return nil
+		}
+	}
+
+	deleted := 0
+	for _, backup := range remove {
+		del := os.Remove
+		if backup.Dir {
+			del = os.RemoveAll
+		}
+		if err := del(backup.Path); err != nil {
+			warnf("failed to delete %s: %v", backup.Path, err)
+			continue
+		}
+		deleted++
+	}
+
+	fmt.Printf("Deleted %d of %d backups, kept %d.\n", deleted, len(remove), len(keep))
+	if deleted < len(remove) {
+		return withExitCode(exitPartialFailure, fmt.Errorf("%d backups could not be deleted", len(remove)-deleted))
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/reconcile"
+)
+
+var (
+	recoverDeletedSince string
+	recoverDir          string
+	recoverDryRun       bool
+	recoverConfirm      bool
+)
+
+// recoverCmd represents the recover command
+var recoverCmd = &cobra.Command{
+	Use:   "recover [backup files...]",
+	Short: "Re-create contacts deleted recently, using older backups",
+	Long: `Find the contacts that were deleted from your Google account recently and
+re-create them from your backups. Nothing is deleted or changed: contacts
+that still exist are left alone, only the missing ones are created.
+
+The backups compared are the JSON backups in backup.directory (or --dir), or
+the files given as arguments. For --deleted-since 7d, recover uses the newest
+backup taken at least 7 days ago, which holds every contact that existed
+then, plus every backup taken since, which also hold contacts created and
+deleted within the 7 days. Contacts in any of them that no longer exist in
+the account (matched by resource name, then by external ID) are listed and,
+after confirmation, re-created with their labels and, for backups taken with
+--photo-bytes, their photos. A contact found in several backups is restored
+as it was in the newest one.
+
+Durations are Go durations (36h, 90m) or a number of days or weeks (7d, 2w).
+If every backup is newer than the start of the period, contacts deleted
+before the oldest backup cannot be found; recover says so.
+
+Examples:
+  # Show what was deleted in the last week, without re-creating anything
+  google-contacts-backup recover --deleted-since 7d --dry-run
+
+  # Re-create contacts deleted in the last 3 days (will prompt)
+  google-contacts-backup recover --deleted-since 3d
+
+  # Compare specific backup files instead of backup.directory
+  google-contacts-backup recover --deleted-since 2w backups/*.json`,
+	RunE: withEvents("recover", runRecover),
+}
+
+func init() {
+	rootCmd.AddCommand(recoverCmd)
+
+	recoverCmd.Flags().StringVar(&recoverDeletedSince, "deleted-since", "7d",
+		"Find contacts deleted within this period, e.g. 7d, 2w or 36h")
+	recoverCmd.Flags().StringVar(&recoverDir, "dir", "",
+		"Directory of backups to compare (default: backup.directory from the config file)")
+	recoverCmd.Flags().BoolVar(&recoverDryRun, "dry-run", false,
+		"List deleted contacts without re-creating them")
+	recoverCmd.Flags().BoolVar(&recoverConfirm, "confirm", false,
+		"Skip confirmation prompt")
+}
+
+// parsePeriod parses a duration such as "36h", "7d" or "2w".
+func parsePeriod(period string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(period, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(period, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	var d time.Duration
+	if unit > 0 {
+		n, err := strconv.Atoi(period[:len(period)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid period %q: expected e.g. 7d, 2w or 36h", period)
+		}
+		d = time.Duration(n) * unit
+	} else {
+		var err error
+		if d, err = time.ParseDuration(period); err != nil {
+			return 0, fmt.Errorf("invalid period %q: expected e.g. 7d, 2w or 36h", period)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid period %q: must be positive", period)
+	}
+	return d, nil
+}
+
+// findBackupFiles returns the JSON backups in dir, including encrypted ones.
+func findBackupFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		if !entry.IsDir() && (strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.age")) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+func runRecover(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	period, err := parsePeriod(recoverDeletedSince)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-period)
+
+	paths := args
+	if len(paths) == 0 {
+		dir := recoverDir
+		if dir == "" {
+			dir = cfg.Backup.Directory
+		}
+		if dir == "" {
+			return fmt.Errorf("no backups to compare: pass backup files, --dir, or set backup.directory in the config file")
+		}
+		paths, err = findBackupFiles(dir)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no JSON backups found in %s", dir)
+		}
+	}
+
+	fmt.Printf("Loading %d backup files...\n", len(paths))
+	var backups []*models.BackupFile
+	for _, path := range paths {
+		backup, err := loadBackup(path)
+		if err != nil {
+			warnf("skipping %s: %v", path, err)
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("none of the %d files could be loaded as a backup", len(paths))
+	}
+	fmt.Println()
+
+	if err := checkCredentials(); err != nil {
+		return err
+	}
+
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	fmt.Println("Fetching current contacts...")
+	fetchBar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Downloading"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	var totalKnown bool
+	live, err := client.ListContacts(ctx, func(current, total int) {
+		if !totalKnown && total > 0 {
+			fetchBar.ChangeMax(total)
+			totalKnown = true
+		}
+		fetchBar.Set(current)
+	})
+	fetchBar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+	fmt.Printf("Found %d contacts\n", len(live))
+	fmt.Println()
+
+	recovery := reconcile.Deleted(backups, live, cutoff)
+	oldest := recovery.Backups[0].CreatedAt
+	fmt.Printf("Compared %d backups taken since %s\n", len(recovery.Backups), oldest.Local().Format(time.RFC3339))
+	if !recovery.Complete {
+		warnf("the oldest backup is newer than %s ago; contacts deleted before %s cannot be found",
+			recoverDeletedSince, oldest.Local().Format(time.RFC3339))
+	}
+
+	deleted := recovery.Backup.Contacts
+	eventData["backups"] = len(recovery.Backups)
+	eventData["deleted"] = len(deleted)
+	if len(deleted) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no contacts were deleted in the last %s", recoverDeletedSince))
+	}
+
+	fmt.Println()
+	fmt.Printf("Found %d deleted contacts:\n", len(deleted))
+	for _, contact := range deleted {
+		fmt.Printf("  %s (last backed up %s)\n", models.DisplayName(contact),
+			recovery.LastSeen[contact.ResourceName].Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Println()
+
+	if recoverDryRun {
+		fmt.Println("Dry run: no contacts were re-created.")
+		return nil
+	}
+
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	if !recoverConfirm {
+		ok, err := askConfirmation(fmt.Sprintf("Re-create these %d contacts? (yes/no): ", len(deleted)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Recover cancelled.")
+			eventData["cancelled"] = true
+			return nil
+		}
+		fmt.Println()
+	}
+
+	liveGroups, err := client.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	groupMap, createdGroups, err := matchGroups(ctx, client, recovery.Backup.Groups, liveGroups)
+	if err != nil {
+		return err
+	}
+	if createdGroups > 0 {
+		fmt.Printf("Created %d missing contact groups\n", createdGroups)
+	}
+
+	fmt.Println("Re-creating contacts...")
+	createBar := progressbar.NewOptions(len(deleted),
+		progressbar.OptionSetDescription("Creating contacts"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	created, err := client.CreateContacts(ctx, deleted, groupMap, func(created, total int) {
+		createBar.Set(created)
+	})
+	createBar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to create contacts: %w", err)
+	}
+
+	restoredPhotos, err := restorePhotos(ctx, client, recovery.Backup, created)
+	if err != nil {
+		return err
+	}
+
+	eventData["recovered"] = len(deleted)
+	eventData["photos"] = restoredPhotos
+
+	fmt.Println()
+	fmt.Println("Recover completed successfully!")
+	fmt.Println()
+	fmt.Printf("  Contacts re-created: %d\n", len(deleted))
+	if createdGroups > 0 {
+		fmt.Printf("  Groups created:      %d\n", createdGroups)
+	}
+	if restoredPhotos > 0 {
+		fmt.Printf("  Photos restored:     %d\n", restoredPhotos)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	refreshInput         string
+	refreshOutput        string
+	refreshResources     []string
+	refreshResourcesFile string
+)
+
+// refreshCmd represents the refresh command
+var refreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Re-fetch specific contacts into an existing backup",
+	Long: `Re-fetch a list of contacts by resource name and patch them into an existing backup.
+
+This is useful when only a handful of contacts need updating (for example
+those that failed verification) and a full backup would be wasteful.
+Contacts already present in the backup are replaced; contacts that are not
+in the backup yet are added. Resource names that no longer exist in the
+account are reported and left untouched in the backup.
+
+The backup is written back in the format it was read in, compressed and
+encrypted the same way.
+
+Examples:
+  # Refresh two contacts in place
+  google-contacts-backup refresh -i backup.json --resource people/c123 --resource people/c456
+
+  # Refresh contacts listed in a file (one resource name per line)
+  google-contacts-backup refresh -i backup.json --resources-file failed.txt -o refreshed.json`,
+	RunE: runRefresh,
+}
+
+func init() {
+	rootCmd.AddCommand(refreshCmd)
+
+	refreshCmd.Flags().StringVarP(&refreshInput, "input", "i", "",
+		"Backup file to patch (required)")
+	refreshCmd.MarkFlagRequired("input")
+
+	refreshCmd.Flags().StringVarP(&refreshOutput, "output", "o", "",
+		"Output file path (default: overwrite the input file)")
+	refreshCmd.Flags().StringSliceVar(&refreshResources, "resource", nil,
+		"Resource name to refresh, e.g. people/c123 (repeatable)")
+	refreshCmd.Flags().StringVar(&refreshResourcesFile, "resources-file", "",
+		"File containing resource names to refresh, one per line")
+}
+
+// readResourceNames reads non-empty, non-comment lines from a file.
+func readResourceNames(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open resources file: %w", err)
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read resources file: %w", err)
+	}
+
+	return names, nil
+}
+
+func runRefresh(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	resourceNames := append([]string{}, refreshResources...)
+	if refreshResourcesFile != "" {
+		fromFile, err := readResourceNames(refreshResourcesFile)
+		if err != nil {
+			return err
+		}
+		resourceNames = append(resourceNames, fromFile...)
+	}
+	if len(resourceNames) == 0 {
+		return fmt.Errorf("no resource names given: use --resource or --resources-file")
+	}
+
+	if refreshOutput == "" {
+		refreshOutput = refreshInput
+	}
+
+	fmt.Printf("Loading backup file: %s\n", refreshInput)
+	backup, stored, err := loadStoredBackup(refreshInput, inputFormat(refreshInput))
+	if err != nil {
+		return fmt.Errorf("failed to load backup: %w", err)
+	}
+
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	fmt.Printf("Fetching %d contacts...\n", len(resourceNames))
+	bar := progressbar.NewOptions(len(resourceNames),
+		progressbar.OptionSetDescription("Fetching"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+
+	fetched, missing, err := client.GetContacts(ctx, resourceNames, func(current, total int) {
+		bar.Set(current)
+	})
+	bar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+
+	if len(fetched) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("none of the %d requested contacts could be fetched; backup left unchanged", len(missing)))
+	}
+
+	var replaced, added int
+	for _, contact := range fetched {
+		if backup.ReplaceContact(contact) {
+			replaced++
+		} else {
+			added++
+		}
+	}
+
+	fmt.Printf("\nSaving backup to %s...\n", refreshOutput)
+	if err := saveStoredBackup(backup, refreshOutput, stored); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Refresh completed successfully!")
+	fmt.Println()
+	fmt.Printf("  Replaced: %d\n", replaced)
+	fmt.Printf("  Added:    %d\n", added)
+	fmt.Printf("  Missing:  %d\n", len(missing))
+	fmt.Printf("  File:     %s\n", refreshOutput)
+
+	if len(missing) > 0 {
+		fmt.Println()
+		fmt.Println("The following contacts could not be fetched and were left unchanged:")
+		for _, name := range missing {
+			fmt.Printf("  %s\n", name)
+		}
+		warningCount += len(missing)
+	}
+
+	return nil
+}
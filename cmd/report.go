@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/dedupe"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	reportAliasesInput string
+	reportAliasesJSON  bool
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Print reports about the contacts in a backup",
+	Long: `Print reports about the contacts in a backup or a history of backups.
+Reports only read backup files and the state store; nothing is sent to
+Google.
+
+Examples:
+  # Find email addresses that are spellings of the same inbox
+  google-contacts-backup report aliases -i my-contacts.json
+
+  # List the contacts added, removed and changed in the last 90 days
+  google-contacts-backup report changes --since 90d
+
+  # Show how the size of the address book changed from backup to backup
+  google-contacts-backup report trend`,
+}
+
+// reportAliasesCmd represents the report aliases command
+var reportAliasesCmd = &cobra.Command{
+	Use:   "aliases",
+	Short: "Find email addresses that deliver to the same inbox",
+	Long: `Find email addresses in a backup that look different but deliver to the
+same inbox.
+
+Addresses are canonicalized the way the mail providers treat them: they are
+lower-cased, +tags are dropped for providers that support plus addressing
+(Gmail, Outlook, iCloud, Fastmail, Proton) and, for Gmail, dots in the part
+before the @ are ignored and googlemail.com is treated as gmail.com. So
+jane.doe+news@gmail.com, JaneDoe@googlemail.com and janedoe@gmail.com are the
+same inbox. Every canonical address reached through more than one spelling
+is listed with the spellings and the contacts using them. Aliases shared by
+several contacts are marked as possible duplicates.
+
+With --json, the aliases are printed as a JSON array for scripts.
+
+Examples:
+  # List the aliases in a backup
+  google-contacts-backup report aliases -i my-contacts.json
+
+  # Only the aliases shared by several contacts
+  google-contacts-backup report aliases -i my-contacts.json --json | jq '.[] | select(.contacts | length > 1)'`,
+	Args: cobra.NoArgs,
+	RunE: runReportAliases,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportAliasesCmd)
+	markLocal(reportAliasesCmd, nil)
+
+	reportAliasesCmd.Flags().StringVarP(&reportAliasesInput, "input", "i", "",
+		"Backup file to report on (required)")
+	reportAliasesCmd.MarkFlagRequired("input")
+	reportAliasesCmd.Flags().BoolVar(&reportAliasesJSON, "json", false,
+		"Print the aliases as JSON")
+}
+
+// aliasReport is the machine-readable form of a dedupe.Alias
+type aliasReport struct {
+	Canonical string          `json:"canonical"`
+	Addresses []string        `json:"addresses"`
+	Contacts  []aliasReportee `json:"contacts"`
+}
+
+// aliasReportee identifies a contact using an alias
+type aliasReportee struct {
+	ResourceName string `json:"resource_name"`
+	Name         string `json:"name"`
+}
+
+func runReportAliases(cmd *cobra.Command, args []string) error {
+	backup, err := loadBackup(reportAliasesInput)
+	if err != nil {
+		return err
+	}
+
+	aliases := dedupe.Aliases(backup.Contacts)
+
+	if reportAliasesJSON {
+		report := make([]aliasReport, 0, len(aliases))
+		for _, alias := range aliases {
+			entry := aliasReport{Canonical: alias.Canonical, Addresses: alias.Addresses}
+			for _, contact := range alias.Contacts {
+				entry.Contacts = append(entry.Contacts, aliasReportee{
+					ResourceName: contact.ResourceName,
+					Name:         models.DisplayName(contact),
+				})
+			}
+			report = append(report, entry)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	if len(aliases) == 0 {
+		fmt.Printf("No email aliases found in %d contacts.\n", len(backup.Contacts))
+		return nil
+	}
+
+	shared := 0
+	for _, alias := range aliases {
+		if len(alias.Contacts) > 1 {
+			shared++
+		}
+	}
+	fmt.Printf("Found %d inboxes reached through more than one address (%d shared by several contacts):\n",
+		len(aliases), shared)
+	fmt.Println()
+	for _, alias := range aliases {
+		heading := alias.Canonical
+		if len(alias.Contacts) > 1 {
+			heading += " (possible duplicates)"
+		}
+		fmt.Println(heading)
+		fmt.Printf("  Addresses: %s\n", strings.Join(alias.Addresses, ", "))
+		var names []string
+		for _, contact := range alias.Contacts {
+			names = append(names, fmt.Sprintf("%s (%s)", models.DisplayName(contact), contact.ResourceName))
+		}
+		fmt.Printf("  Contacts:  %s\n", strings.Join(names, ", "))
+		fmt.Println()
+	}
+
+	return nil
+}
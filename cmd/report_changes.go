@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/diff"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	reportChangesSince   string
+	reportChangesDir     string
+	reportChangesFormat  string
+	reportChangesOutput  string
+	reportChangesFrom    string
+	reportChangesTo      []string
+	reportChangesSubject string
+)
+
+// reportChangesCmd represents the report changes command
+var reportChangesCmd = &cobra.Command{
+	Use:   "changes [backup files...]",
+	Short: "Digest of the contacts added, removed and changed over a period",
+	Long: `Turn the history of your backups into a changelog of your address book:
+every contact added, removed or changed over a period, with the date of the
+change, and the labels that were added, removed, renamed or gained or lost
+members.
+
+The backups compared are the JSON backups in backup.directory (or --dir), or
+the files given as arguments. For --since 90d, the newest backup taken at
+least 90 days ago is compared with the next one, that one with the next, and
+so on up to the newest backup. Contacts are matched by resource name, as in
+'diff'. A change is dated by the backup that first shows it, so it happened
+between that backup and the one before. Changed contacts are listed with
+every field that changed during the period and how many backups show a
+change; contacts that were added and removed again within the period are
+listed separately. If every backup is newer than the start of the period,
+the digest says that earlier changes are missing.
+
+Durations are Go durations (36h, 90m) or a number of days or weeks (90d,
+12w).
+
+--format selects the output:
+  text:  plain text (default)
+  html:  a standalone HTML page
+  email: an email message with a text and an HTML part, addressed with --to
+         and --from, to pipe into sendmail -t or save and send otherwise
+  json:  the digest as JSON for scripts
+
+Examples:
+  # What changed in the last 90 days
+  google-contacts-backup report changes --since 90d
+
+  # A quarterly changelog as a web page
+  google-contacts-backup report changes --since 90d --format html -o changes.html
+
+  # Mail yourself a monthly digest, e.g. from cron
+  google-contacts-backup report changes --since 30d --format email \
+    --from backups@example.com --to me@example.com | sendmail -t
+
+  # Compare specific backup files instead of backup.directory
+  google-contacts-backup report changes --since 2w backups/*.json`,
+	RunE: runReportChanges,
+}
+
+func init() {
+	reportCmd.AddCommand(reportChangesCmd)
+	markLocal(reportChangesCmd, nil)
+
+	reportChangesCmd.Flags().StringVar(&reportChangesSince, "since", "30d",
+		"Report the changes within this period, e.g. 90d, 12w or 36h")
+	reportChangesCmd.Flags().StringVar(&reportChangesDir, "dir", "",
+		"Directory of backups to compare (default: backup.directory from the config file)")
+	reportChangesCmd.Flags().StringVar(&reportChangesFormat, "format", "text",
+		"Output format: text, html, email or json")
+	reportChangesCmd.Flags().StringVarP(&reportChangesOutput, "output", "o", "",
+		"Write the digest to this file instead of standard output")
+	reportChangesCmd.Flags().StringVar(&reportChangesFrom, "from", "",
+		"Sender of the email (--format email)")
+	reportChangesCmd.Flags().StringSliceVar(&reportChangesTo, "to", nil,
+		"Recipient of the email (--format email, repeatable)")
+	reportChangesCmd.Flags().StringVar(&reportChangesSubject, "subject", "",
+		"Subject of the email (default: the digest's title and summary)")
+}
+
+func runReportChanges(cmd *cobra.Command, args []string) error {
+	switch reportChangesFormat {
+	case "text", "html", "json":
+		if reportChangesFrom != "" || len(reportChangesTo) > 0 || reportChangesSubject != "" {
+			return fmt.Errorf("--from, --to and --subject only apply to --format email")
+		}
+	case "email":
+		if len(reportChangesTo) == 0 {
+			return fmt.Errorf("--format email needs at least one --to address")
+		}
+	default:
+		return fmt.Errorf("invalid --format %q: must be text, html, email or json", reportChangesFormat)
+	}
+
+	period, err := parsePeriod(reportChangesSince)
+	if err != nil {
+		return err
+	}
+
+	paths := args
+	if len(paths) == 0 {
+		dir := defaultString(reportChangesDir, cfg.Backup.Directory)
+		if dir == "" {
+			return fmt.Errorf("no backups to compare: pass backup files, --dir, or set backup.directory in the config file")
+		}
+		paths, err = findBackupFiles(dir)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no JSON backups found in %s", dir)
+		}
+	}
+
+	var backups []*models.BackupFile
+	for _, path := range paths {
+		backup, err := loadBackup(path)
+		if err != nil {
+			// Warnings go to standard error, so they stay out of the digest
+			warningCount++
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", path, err)
+			continue
+		}
+		backups = append(backups, backup)
+	}
+
+	digest, err := diff.History(backups, time.Now().Add(-period))
+	if err != nil {
+		return err
+	}
+
+	write := func(w io.Writer) error {
+		switch reportChangesFormat {
+		case "html":
+			return diff.WriteDigestHTML(w, digest)
+		case "email":
+			return diff.WriteDigestEmail(w, digest, diff.DigestEmailOptions{
+				From:    reportChangesFrom,
+				To:      reportChangesTo,
+				Subject: reportChangesSubject,
+			})
+		case "json":
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(digest)
+		}
+		return diff.WriteDigestText(w, digest)
+	}
+	if reportChangesOutput == "" {
+		return write(os.Stdout)
+	}
+	if err := saveFile(reportChangesOutput, write); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the changes of %d backups to %s\n", digest.Backups, reportChangesOutput)
+	return nil
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/state"
+	"github.com/mheap/google-contacts-backup/internal/trend"
+)
+
+var (
+	reportTrendSince  string
+	reportTrendDir    string
+	reportTrendFormat string
+	reportTrendOutput string
+)
+
+// reportTrendCmd represents the report trend command
+var reportTrendCmd = &cobra.Command{
+	Use:   "trend [backup files...]",
+	Short: "Growth and shrinkage of the address book over time",
+	Long: `Show how many contacts and groups each backup held, how that changed from
+one backup to the next, and flag unusual changes: a sudden drop, such as a
+phone or sync client deleting contacts, or an explosion, such as a sync loop
+creating duplicates.
+
+Every full backup (one without --filter, --modified-since or domain filters)
+records its counts in the state store, and warns when they changed unusually
+since the previous backup. The report reads those counts. To include backups
+taken before, pass backup files or --dir; their counts are read from the
+files instead.
+
+A change is unusual if the address book lost more than 10% of its contacts
+or gained more than 25%, and at least 10 contacts; set trend.max_drop_percent,
+trend.max_growth_percent and trend.min_change in the config file to change
+that.
+
+--format selects the output:
+  text:       a table of the backups (default)
+  json:       every backup with its change, for scripts
+  prometheus: gauges for the newest backup in the Prometheus text format;
+              write them with -o to a node_exporter textfile collector
+              directory. --serve-health also serves them on /metrics.
+
+Examples:
+  # The size of the address book over the last 90 days
+  google-contacts-backup report trend --since 90d
+
+  # The same from the backups on disk, e.g. before any were recorded
+  google-contacts-backup report trend --dir backups
+
+  # Export gauges for node_exporter after each backup
+  google-contacts-backup report trend --format prometheus \
+    -o /var/lib/node_exporter/textfile/contacts.prom`,
+	RunE: runReportTrend,
+}
+
+func init() {
+	reportCmd.AddCommand(reportTrendCmd)
+	markLocal(reportTrendCmd, nil)
+
+	reportTrendCmd.Flags().StringVar(&reportTrendSince, "since", "",
+		"Only show backups within this period, e.g. 90d, 12w or 36h (default: all)")
+	reportTrendCmd.Flags().StringVar(&reportTrendDir, "dir", "",
+		"Read the counts from the backups in this directory instead of the state store")
+	reportTrendCmd.Flags().StringVar(&reportTrendFormat, "format", "text",
+		"Output format: text, json or prometheus")
+	reportTrendCmd.Flags().StringVarP(&reportTrendOutput, "output", "o", "",
+		"Write the report to this file instead of standard output")
+}
+
+func runReportTrend(cmd *cobra.Command, args []string) error {
+	switch reportTrendFormat {
+	case "text", "json", "prometheus":
+	default:
+		return fmt.Errorf("invalid --format %q: must be text, json or prometheus", reportTrendFormat)
+	}
+	var since time.Time
+	if reportTrendSince != "" {
+		period, err := parsePeriod(reportTrendSince)
+		if err != nil {
+			return err
+		}
+		since = time.Now().Add(-period)
+	}
+
+	var sizes []state.Size
+	var err error
+	if len(args) > 0 || reportTrendDir != "" {
+		sizes, err = backupSizes(args, reportTrendDir)
+	} else {
+		sizes, err = recordedSizes()
+	}
+	if err != nil {
+		return err
+	}
+	t := analyzeSizes(sizes, since)
+
+	write := func(w io.Writer) error {
+		switch reportTrendFormat {
+		case "json":
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(t)
+		case "prometheus":
+			return trend.WritePrometheus(w, t)
+		}
+		return trend.WriteText(w, t)
+	}
+	if reportTrendOutput == "" {
+		return write(os.Stdout)
+	}
+	if err := saveFile(reportTrendOutput, write); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the sizes of %d backups to %s\n", len(t.Points), reportTrendOutput)
+	return nil
+}
+
+// trendOptions returns the thresholds for unusual changes from the config
+// file.
+func trendOptions() trend.Options {
+	return trend.Options{
+		MaxDrop:   cfg.Trend.MaxDropPercent / 100,
+		MaxGrowth: cfg.Trend.MaxGrowthPercent / 100,
+		MinChange: cfg.Trend.MinChange,
+	}
+}
+
+// analyzeSizes returns the trend of the sizes taken after since, each
+// compared with the one before it.
+func analyzeSizes(sizes []state.Size, since time.Time) *trend.Trend {
+	var previous *state.Size
+	first := 0
+	for first < len(sizes) && sizes[first].At.Before(since) {
+		previous = &sizes[first]
+		first++
+	}
+	return trend.Analyze(sizes[first:], previous, trendOptions())
+}
+
+// recordedSizes returns the sizes recorded in the state store.
+func recordedSizes() ([]state.Size, error) {
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return store.Sizes()
+}
+
+// backupSizes reads the sizes of the given backup files, or of the backups
+// in dir, sorted by the time they were taken.
+func backupSizes(paths []string, dir string) ([]state.Size, error) {
+	if len(paths) == 0 {
+		var err error
+		if paths, err = findBackupFiles(dir); err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no JSON backups found in %s", dir)
+		}
+	}
+
+	var sizes []state.Size
+	for _, path := range paths {
+		backup, err := loadBackup(path)
+		if err != nil {
+			// Warnings go to standard error, so they stay out of the report
+			warningCount++
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", path, err)
+			continue
+		}
+		sizes = append(sizes, state.Size{
+			At:            backup.CreatedAt,
+			Contacts:      len(backup.Contacts),
+			OtherContacts: len(backup.OtherContacts),
+			Groups:        backup.GroupCount,
+		})
+	}
+	slices.SortStableFunc(sizes, func(a, b state.Size) int { return a.At.Compare(b.At) })
+	return sizes, nil
+}
+
+// recordBackupSize records the size of the address book at a full backup
+// in the state store, and warns if it changed unusually since the previous
+// backup.
+func recordBackupSize(size state.Size) {
+	store, err := state.Open(stateFile)
+	if err != nil {
+		warnf("failed to record the address book size: %v", err)
+		return
+	}
+	defer store.Close()
+
+	if err := store.RecordSize(size); err != nil {
+		warnf("%v", err)
+		return
+	}
+	sizes, err := store.Sizes()
+	if err != nil {
+		warnf("%v", err)
+		return
+	}
+	t := analyzeSizes(sizes, time.Time{})
+	if healthServer != nil {
+		healthServer.SetTrend(t)
+	}
+
+	last := t.Last()
+	eventData["contacts_change"] = last.Change
+	if last.Alert != "" {
+		eventData["size_alert"] = last.Alert
+		warnf("the address book %s since the previous backup; check for a sync client deleting contacts or creating duplicates", last.Describe())
+	}
+}
+
+// loadTrend reads the trend of the recorded sizes for the health endpoints.
+func loadTrend(store *state.Store) *trend.Trend {
+	sizes, err := store.Sizes()
+	if err != nil {
+		warnf("%v", err)
+	}
+	return analyzeSizes(sizes, time.Time{})
+}
@@ -1,43 +1,238 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"maps"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
 
-	"github.com/mheap/google-contacts-backup/internal/auth"
+	"github.com/mheap/google-contacts-backup/internal/carddav"
 	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/i18n"
 	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/reconcile"
 )
 
 var (
-	inputFile   string
-	skipConfirm bool
+	inputFile          string
+	skipConfirm        bool
+	printOrder         bool
+	trickleRate        string
+	targetURL          string
+	restoreFilter      string
+	restoreModified    string
+	restoreMode        string
+	restoreJournal     string
+	restoreDryRun      bool
+	restoreSimulate    bool
+	restoreSkipCheck   bool
+	restoreReuseGroups bool
+	restoreFieldsOnly  []string
+	restoreGroups      []string
+	restoreMatch       string
+	restoreRange       string
+	restoreReplace     bool
+	restoreSnapshot    bool
+	restoreResume      bool
+	restoreNoSafety    bool
+	restoreUndo        bool
+	restoreDataLoss    bool
 )
 
+// Restore modes accepted by --mode
+const (
+	restoreModeReplace = "replace"
+	restoreModeMerge   = "merge"
+
+	// restoreModeFields is used for --fields-only, not accepted by --mode
+	restoreModeFields = "fields"
+
+	// restoreModeSelection is used for --group and --match with --replace,
+	// not accepted by --mode
+	restoreModeSelection = "selection"
+)
+
+// restoreTarget is the destination a backup is restored to. The Google
+// People API client and the CardDAV client both implement it.
+type restoreTarget interface {
+	DeleteAllContacts(ctx context.Context, progressFn func(deleted, total int)) error
+	DeleteUserGroups(ctx context.Context, progressFn func(deleted, total int)) ([]string, error)
+	CreateGroups(ctx context.Context, groups []*people.ContactGroup, progressFn func(created, total int)) (map[string]string, error)
+	CreateContacts(ctx context.Context, contacts []*people.Person, groupMap map[string]string, progressFn func(created, total int)) (map[string]string, error)
+}
+
 // restoreCmd represents the restore command
 var restoreCmd = &cobra.Command{
 	Use:   "restore",
-	Short: "Restore Google Contacts from a JSON backup file",
+	Short: "Restore Google Contacts from a JSON or CSV backup file",
 	Long: `Restore your Google Contacts from a previously created backup file.
 
-WARNING: This operation is DESTRUCTIVE! It will:
+WARNING: The default mode, replace, is DESTRUCTIVE! It will:
   1. DELETE ALL existing contacts in your Google account
   2. DELETE ALL user-created contact groups (labels)
   3. Recreate contact groups from the backup
   4. Recreate all contacts from the backup
 
 System groups (My Contacts, Starred, etc.) are preserved but their
-membership is reset.
+membership is reset. Groups are created four at a time; a group whose name
+already exists, for example one that could not be deleted in step 2, is
+reused instead of failing the restore.
+
+Restore order is deterministic: groups are started sorted by name, then
+contacts are created sorted by their original resource name in batches of
+200. Repeated restores from the same backup therefore issue the same batches,
+and a failure is reported at the same batch index. Use --print-order to list
+the order before anything is changed.
+
+With --target, the backup is restored to a CardDAV address book instead of
+Google. Every card in that address book is deleted first, then each contact
+is uploaded as a vCard with its labels as categories. The password is read
+from $CARDDAV_PASSWORD (or the URL); use carddav+http:// for servers on
+localhost without TLS.
+
+With --filter, only the contacts matching the expression are recreated (see
+'google-contacts-backup help filters'). Existing contacts are still deleted
+first, so filter a backup of the whole account with care. --modified-since
+selects the contacts last updated on or after a date, or within a period such
+as 7d, according to the update time recorded in the backup.
+
+With --mode merge, nothing is deleted. Contacts from the backup are matched
+with existing contacts by resource name, then by external ID. Matched
+contacts are updated: fields present in the backup overwrite the current
+values, fields missing from it are kept, and so are frozen fields from the
+config file. Unmatched contacts are created. Labels are matched by name and
+created if missing, and existing label memberships are kept. Use merge to
+re-add a handful of lost contacts without touching the rest of the account.
+
+Contact photos are re-uploaded when the backup was taken with --photo-bytes:
+to every created contact and, in merge mode, to matched contacts that have
+no photo of their own. Older backups only hold photo URLs, which cannot be
+restored.
+
+An input file named .csv is read as CSV: either a CSV written by this tool
+(in any --csv-locale) or one exported from Google Contacts. A file named .vcf
+is read as vCards. Their labels are recreated as contact groups. CSV and
+vCard hold fewer fields than a JSON backup and no photos, so prefer JSON
+backups where you have them. A directory written by backup --format dir is
+read like the JSON backup it holds.
+
+Before anything is created, the columns of a CSV file are listed with the
+contact field each is read into, with a warning for every column whose values
+are dropped. --map names a JSON file that reads other columns as known ones,
+e.g. {"E-Mail privat": "Email 1 - Value", "Fax": ""}; "" skips a column.
+
+With --journal (or restore.journal in the config file), every step and every
+batch of created or updated contacts is appended to a JSON Lines file as it
+happens: the batch index, the backup resource names sent, the resource names
+Google assigned, the duration and any error. Each line is flushed to disk, so
+if the restore crashes or is killed, the journal shows exactly how far it got.
+Batches are only recorded when restoring to Google.
+
+A replace restore to Google keeps a checkpoint in the state store, updated
+after every step and every batch of created contacts. If the restore fails or
+is killed, run the same command with --resume: completed steps are skipped,
+groups that already exist are reused and only the contacts that were not
+created yet are created. The checkpoint is deleted once the restore
+completes.
+
+After a replace restore to Google, the account is fetched again and compared
+with the backup. If every restored contact matches, a fresh backup of the
+account tagged "post-restore" is saved next to the journal (or in
+backup.directory, or the current directory) as the new known-good baseline,
+named e.g. contacts-20240601-020000-post-restore.json. prune never deletes
+these. Use --snapshot=false to skip the check and the snapshot.
+
+Contacts linked to Google profiles or other people ("linked people") lose
+these links in a replace restore, as the People API cannot recreate them;
+the affected contacts are listed before you confirm.
+
+If the config file requires a second approval (see 'approve'), a replace
+restore only runs with --approval-file or --approval-code.
+
+Set restore.confirm_phrase in the config file, e.g. "DELETE {count}
+CONTACTS", to have a replace restore ask for that phrase instead of yes, with
+{count} replaced by the number of contacts it deletes. With
+restore.max_unattended_delete, --confirm alone is not enough for a replace
+restore that deletes more contacts than that: --i-understand-data-loss must
+be given as well. Both count the contacts in the account (or the --target
+address book) before asking.
+
+Contacts on the "ignore" list of the config file are never restored. A
+replace restore to Google does not delete them from the account either, nor
+the labels on the list.
+
+To recover a single label or a few contacts, --group restores only the
+contacts carrying one of the given labels (repeat it for several), and
+--match only those matching a query such as "name contains Smith" (FIELD
+contains, does not contain, is or is not VALUE; see 'help filters' for the
+fields). Both can be combined with each other and with --filter. Only the
+selected contacts and the labels they carry are restored, as in merge mode:
+nothing is deleted. With --replace, the contacts of the account that match
+the selection are deleted first and the selected contacts are recreated from
+the backup; other contacts and labels are left alone.
+
+To split an enormous restore across several days or accounts, --range
+restores only a slice of the backup's contacts by their position in the
+restore order (sorted by resource name, as --print-order lists them), given
+as START:END and counted from 0 with END excluded: 0:2000 is the first 2000
+contacts, 2000:4000 the next 2000 and 4000: the rest. A range is restored in
+merge mode, so later chunks do not delete the contacts of earlier ones and a
+chunk can simply be run again; only the labels its contacts carry are
+created. Give --mode replace with the first chunk to empty the account first.
+
+With --fields-only, only the listed fields are written, and only onto
+existing contacts: contacts from the backup are matched as in merge mode, and
+each matched contact is updated with an update mask of just those fields, so
+everything else about it stays as it is. The fields are set to their values
+in the backup, which clears a field the backup has no values for. Nothing is
+created or deleted, and backup contacts with no match are skipped. Fields are
+given by their People API names or as phones, emails, addresses, birthdays,
+notes, orgs, websites, im, nicknames, relations or custom. Use it to repair
+fields damaged by a bad sync, e.g. phone numbers that were wiped.
+
+With --reuse-existing-groups, groups in the account that have the same name
+as a group in the backup are not deleted in step 2. Memberships from the
+backup are restored into them, and only the missing groups are created, so
+labels keep their identity instead of being replaced by new groups.
+
+With --dry-run, the backup is loaded and the current state of the account
+(or address book) is fetched, then every contact and group that would be
+deleted, created or updated is listed, without asking for confirmation and
+without sending a single change. In merge mode, updated contacts are listed
+with the fields that would change.
+
+Before changing anything, restore backs up the account to
+pre-restore-<timestamp>.json in the backup directory of the config file (or
+the current directory), compressed and encrypted like other backups, and
+prints its path in the summary. If the safety backup fails, the restore is
+aborted. Use --no-safety-backup to skip it, e.g. when restoring to an empty
+account; restores to a CardDAV server never take one.
+
+--undo reverts a botched restore: it finds the most recent safety backup in
+that directory and restores it in replace mode, without -i. The undo takes a
+safety backup of its own first, so running --undo again reverts the undo.
 
-It is STRONGLY recommended to create a fresh backup before restoring:
-  google-contacts-backup backup -o pre-restore-backup.json
+Before anything is changed, every contact to restore is validated against
+the limits and formats the People API enforces, and the restore stops if
+any would be refused, listing each problem with its location (such as
+emailAddresses[1].value) and the batch the contact is in. Use
+--skip-validation to send the contacts anyway. --dry-run lists the problems
+as warnings.
+
+With --simulate, the live account is fetched read-only into an in-memory fake
+of the People API, and the whole restore runs against that copy, with the
+limits and validation of Google Contacts: field lengths, the number of values
+per contact, contact size, dates and labels. Every contact the API would
+reject is listed with the reason, and the command exits with code 5 if there
+is any. The account itself is never changed, nothing is asked and no safety
+backup, journal or checkpoint is written; --trickle is ignored.
 
 Examples:
   # Restore from a backup file (will prompt for confirmation)
@@ -47,35 +242,349 @@ Examples:
   google-contacts-backup restore -i my-contacts.json --confirm
 
   # Use a specific credentials file
-  google-contacts-backup restore -c ~/creds.json -i backup.json`,
-	RunE: runRestore,
+  google-contacts-backup restore -c ~/creds.json -i backup.json
+
+  # Keep labels that already exist instead of recreating them
+  google-contacts-backup restore -i my-contacts.json --reuse-existing-groups
+
+  # Preview exactly what a restore would delete and create
+  google-contacts-backup restore -i my-contacts.json --dry-run
+
+  # Check that Google would accept every contact before restoring
+  google-contacts-backup restore -i my-contacts.json --simulate
+
+  # Show the order in which groups and contact batches will be created
+  google-contacts-backup restore -i my-contacts.json --print-order
+
+  # Create contacts slowly, one per second, after a temporary write ban
+  google-contacts-backup restore -i my-contacts.json --trickle 1/s
+
+  # Move contacts to a CardDAV server such as Nextcloud or Fastmail
+  CARDDAV_PASSWORD=secret google-contacts-backup restore -i backup.json \
+    --target carddav://me@dav.example.com/remote.php/dav/addressbooks/users/me/contacts/
+
+  # Re-add lost contacts without deleting anything
+  google-contacts-backup restore -i backup.json --mode merge --filter 'name~"Smith"'
+
+  # Put back phone numbers and emails wiped by a bad sync, nothing else
+  google-contacts-backup restore -i backup.json --fields-only phones,emails
+
+  # Recover the contacts labelled "Family" without deleting anything
+  google-contacts-backup restore -i backup.json --group Family
+
+  # Replace the contacts named Smith with their versions in the backup
+  google-contacts-backup restore -i backup.json --match "name contains Smith" --replace
+
+  # Restore a huge backup over three days, 25000 contacts a day
+  google-contacts-backup restore -i backup.json --range 0:25000 --mode replace
+  google-contacts-backup restore -i backup.json --range 25000:50000
+  google-contacts-backup restore -i backup.json --range 50000:
+
+  # Keep an audit trail of every batch
+  google-contacts-backup restore -i backup.json --journal restore-journal.jsonl
+
+  # Revert the last restore
+  google-contacts-backup restore --undo
+
+  # Restore into an empty account without the automatic safety backup
+  google-contacts-backup restore -i backup.json --no-safety-backup
+
+  # Continue a restore that was interrupted
+  google-contacts-backup restore -i backup.json --resume
+
+  # Scripted wipe of a large account, with restore.max_unattended_delete set
+  google-contacts-backup restore -i backup.json --confirm --i-understand-data-loss
+
+  # Stay under a shared project quota (all phases share the budget)
+  google-contacts-backup restore -i backup.json --max-requests-per-minute 60`,
+	RunE: withEvents("restore", runRestore),
 }
 
 func init() {
 	rootCmd.AddCommand(restoreCmd)
 
 	restoreCmd.Flags().StringVarP(&inputFile, "input", "i", "",
-		"Input backup file path (required)")
-	restoreCmd.MarkFlagRequired("input")
+		"Input backup file path, JSON, .csv or .vcf, or a backup directory (required unless --undo is given)")
+	addCSVMapFlag(restoreCmd)
 
 	restoreCmd.Flags().BoolVar(&skipConfirm, "confirm", false,
 		"Skip confirmation prompt (use with caution!)")
+	restoreCmd.Flags().BoolVar(&restoreDataLoss, "i-understand-data-loss", false,
+		"With --confirm, allow a replace restore to delete more contacts than restore.max_unattended_delete")
+	restoreCmd.Flags().BoolVar(&printOrder, "print-order", false,
+		"Print the group and contact batch order before restoring")
+	restoreCmd.Flags().StringVar(&trickleRate, "trickle", "",
+		"Create contacts one at a time at this rate, e.g. 1/s, 30/m, 500/h")
+	restoreCmd.Flags().StringVar(&targetURL, "target", "",
+		"Restore to this CardDAV address book (carddav://user@host/path/) instead of Google")
+	restoreCmd.Flags().StringVar(&restoreFilter, "filter", "",
+		"Only restore contacts matching this filter expression (see 'help filters')")
+	restoreCmd.Flags().StringVar(&restoreModified, "modified-since", "",
+		"Only restore contacts updated since this date (YYYY-MM-DD), RFC 3339 time or period (e.g. 7d)")
+	restoreCmd.Flags().StringVar(&restoreMode, "mode", restoreModeReplace,
+		"Restore mode: replace (delete everything first) or merge (update matching contacts, create the rest)")
+	restoreCmd.Flags().StringArrayVar(&restoreGroups, "group", nil,
+		"Only restore contacts with this label, without deleting anything (repeatable)")
+	restoreCmd.Flags().StringVar(&restoreMatch, "match", "",
+		`Only restore contacts matching this query, e.g. "name contains Smith", without deleting anything`)
+	restoreCmd.Flags().StringVar(&restoreRange, "range", "",
+		"Only restore these contacts by position after sorting, e.g. 2000:4000, without deleting anything")
+	restoreCmd.Flags().BoolVar(&restoreReplace, "replace", false,
+		"With --group or --match, delete the matching contacts of the account before restoring them")
+	restoreCmd.Flags().StringSliceVar(&restoreFieldsOnly, "fields-only", nil,
+		"Only write these fields onto matching existing contacts, e.g. phones,emails")
+	restoreCmd.Flags().BoolVar(&restoreReuseGroups, "reuse-existing-groups", false,
+		"Keep existing groups named as in the backup and restore memberships into them")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false,
+		"Show what would be deleted, created and updated without changing anything")
+	restoreCmd.Flags().BoolVar(&restoreSkipCheck, "skip-validation", false,
+		"Restore even if contacts fail validation against the People API's limits")
+	restoreCmd.Flags().BoolVar(&restoreSimulate, "simulate", false,
+		"Run the restore against an in-memory copy of the account and report contacts Google would reject")
+	restoreCmd.Flags().BoolVar(&restoreResume, "resume", false,
+		"Continue an interrupted replace restore from its checkpoint")
+	restoreCmd.Flags().BoolVar(&restoreUndo, "undo", false,
+		"Revert the last restore by restoring the most recent pre-restore safety backup")
+	restoreCmd.Flags().BoolVar(&restoreNoSafety, "no-safety-backup", false,
+		"Do not back up the account automatically before restoring")
+	restoreCmd.Flags().BoolVar(&restoreSnapshot, "snapshot", true,
+		"After a replace restore, verify the account and save a post-restore backup of it")
+	restoreCmd.Flags().StringVar(&restoreJournal, "journal", "",
+		"Append each step and batch to this JSON Lines audit file (default: restore.journal from the config file)")
 }
 
-func runRestore(cmd *cobra.Command, args []string) error {
+// parseTrickleRate parses a rate such as "1/s", "30/m" or "500/h" into the
+// interval between two requests.
+func parseTrickleRate(rate string) (time.Duration, error) {
+	count, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid rate %q: expected N/s, N/m or N/h", rate)
+	}
+
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %q: count must be a positive number", rate)
+	}
+
+	var per time.Duration
+	switch strings.ToLower(unit) {
+	case "s", "sec", "second":
+		per = time.Second
+	case "m", "min", "minute":
+		per = time.Minute
+	case "h", "hour":
+		per = time.Hour
+	default:
+		return 0, fmt.Errorf("invalid rate %q: unit must be s, m or h", rate)
+	}
+
+	return time.Duration(float64(per) / n), nil
+}
+
+// printRestoreOrder lists groups and contact batches in the order they will be created.
+func printRestoreOrder(backup *models.BackupFile, batchSize int) {
+	fmt.Println("Restore order:")
+	for i, group := range backup.GetUserGroups() {
+		fmt.Printf("  group %d: %s\n", i+1, group.Name)
+	}
+	for i, contact := range backup.Contacts {
+		if i%batchSize == 0 {
+			fmt.Printf("  batch %d:\n", i/batchSize)
+		}
+		fmt.Printf("    %d. %s (%s)\n", i+1, models.DisplayName(contact), contact.ResourceName)
+	}
+	fmt.Println()
+}
+
+// openRestoreTarget returns the CardDAV client for --target, or authenticates
+// with Google when no target is given.
+func openRestoreTarget(ctx context.Context, trickleInterval time.Duration) (restoreTarget, error) {
+	if targetURL != "" {
+		client, err := carddav.NewClient(targetURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Restoring to CardDAV address book: %s\n", client)
+		fmt.Println()
+		return client, nil
+	}
+
+	fmt.Println("Authenticating with Google...")
+
+	// Authenticate and create contacts client
+	opts := append(journalOptions(), checkpointOptions()...)
+	client, err := newContactsClient(ctx, append(opts, contacts.WithTrickle(trickleInterval))...)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	return client, nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) (err error) {
 	ctx := context.Background()
+	start := time.Now()
+
+	if restoreUndo {
+		if err := prepareUndo(cmd); err != nil {
+			return err
+		}
+	}
+	if inputFile == "" {
+		return fmt.Errorf("--input is required (or use --undo to revert the last restore)")
+	}
 
 	// Check if input file exists
 	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
 		return fmt.Errorf("backup file not found: %s", inputFile)
 	}
+	if err := checkCSVMap(inputFormat(inputFile)); err != nil {
+		return err
+	}
+	csvPreview = true
+
+	if restoreDataLoss && !skipConfirm {
+		return fmt.Errorf("--i-understand-data-loss only applies with --confirm")
+	}
+
+	if targetURL != "" && !carddav.IsTarget(targetURL) {
+		return fmt.Errorf("unsupported target %q: must start with carddav:// or carddav+http://", targetURL)
+	}
+	if targetURL != "" && trickleRate != "" {
+		return fmt.Errorf("--trickle is only supported when restoring to Google")
+	}
+	if restoreMode != restoreModeReplace && restoreMode != restoreModeMerge {
+		return fmt.Errorf("invalid mode %q: must be replace or merge", restoreMode)
+	}
+	if targetURL != "" && restoreMode == restoreModeMerge {
+		return fmt.Errorf("--mode merge is only supported when restoring to Google")
+	}
+	if targetURL != "" && restoreReuseGroups {
+		return fmt.Errorf("--reuse-existing-groups is only supported when restoring to Google")
+	}
+	if len(restoreFieldsOnly) > 0 {
+		switch {
+		case targetURL != "":
+			return fmt.Errorf("--fields-only is only supported when restoring to Google")
+		case cmd.Flags().Changed("mode"):
+			return fmt.Errorf("--fields-only cannot be combined with --mode")
+		case restoreReuseGroups:
+			return fmt.Errorf("--fields-only cannot be combined with --reuse-existing-groups")
+		}
+		fields, err := parseFieldsOnly(restoreFieldsOnly)
+		if err != nil {
+			return err
+		}
+		restoreFieldsOnly = fields
+		restoreMode = restoreModeFields
+	}
+	selective := len(restoreGroups) > 0 || restoreMatch != ""
+	switch {
+	case restoreReplace && !selective:
+		return fmt.Errorf("--replace only applies with --group or --match; a restore replaces everything by default")
+	case selective && targetURL != "":
+		return fmt.Errorf("--group and --match are only supported when restoring to Google")
+	case selective && restoreMode == restoreModeFields:
+		// The selection narrows the contacts whose fields are written
+		if restoreReplace {
+			return fmt.Errorf("--replace cannot be combined with --fields-only")
+		}
+	case selective && cmd.Flags().Changed("mode"):
+		return fmt.Errorf("--group and --match cannot be combined with --mode; use --replace to replace the selected contacts")
+	case selective && restoreReplace:
+		restoreMode = restoreModeSelection
+	case selective:
+		restoreMode = restoreModeMerge
+	}
+	var contactRng *contactRange
+	if restoreRange != "" {
+		r, err := parseContactRange(restoreRange)
+		if err != nil {
+			return err
+		}
+		switch {
+		case targetURL != "":
+			return fmt.Errorf("--range is only supported when restoring to Google")
+		case restoreMode == restoreModeSelection:
+			return fmt.Errorf("--range cannot be combined with --replace")
+		case !cmd.Flags().Changed("mode") && restoreMode == restoreModeReplace:
+			// Later chunks must not delete the contacts of earlier ones
+			restoreMode = restoreModeMerge
+		}
+		contactRng = &r
+	}
+	if restoreResume {
+		switch {
+		case targetURL != "":
+			return fmt.Errorf("--resume is only supported when restoring to Google")
+		case restoreMode != restoreModeReplace:
+			return fmt.Errorf("--resume only applies to replace restores; merge restores can simply be run again")
+		case restoreDryRun:
+			return fmt.Errorf("--resume cannot be combined with --dry-run")
+		}
+	}
+	if restoreSimulate {
+		switch {
+		case targetURL != "":
+			return fmt.Errorf("--simulate is only supported when restoring to Google")
+		case restoreDryRun:
+			return fmt.Errorf("--simulate cannot be combined with --dry-run")
+		case restoreResume:
+			return fmt.Errorf("--simulate cannot be combined with --resume")
+		}
+	}
+
+	var trickleInterval time.Duration
+	if trickleRate != "" && !restoreSimulate {
+		interval, err := parseTrickleRate(trickleRate)
+		if err != nil {
+			return err
+		}
+		trickleInterval = interval
+	}
+
+	expr, err := withModifiedSince(restoreFilter, restoreModified)
+	if err != nil {
+		return err
+	}
+	expr, err = withSelection(expr, restoreGroups, restoreMatch)
+	if err != nil {
+		return err
+	}
+	selection, err := parseFilter(expr)
+	if err != nil {
+		return err
+	}
 
 	// Load and validate backup file
 	fmt.Printf("Loading backup file: %s\n", inputFile)
-	backup, err := models.LoadBackupFile(inputFile)
+	backup, err := loadBackup(inputFile)
 	if err != nil {
 		return fmt.Errorf("failed to load backup: %w", err)
 	}
+	backup.SortForRestore()
+	addedMemberships := backup.ApplyGroupMembers()
+	backupContacts := len(backup.Contacts)
+	var rangeFirst, rangeLast int
+	if contactRng != nil {
+		rangeFirst, rangeLast = contactRng.apply(backup)
+	}
+	// Transforms recorded when the backup was created, before the ignore
+	// list adds its own
+	transforms := backup.Transforms
+	applyIgnoreList(backup)
+
+	var filtered, unusedGroups int
+	if selection != nil {
+		filtered = selection.Apply(backup)
+	}
+	if selective || contactRng != nil {
+		// Only the labels of the selected contacts are restored
+		unusedGroups = backup.RemoveUnusedGroups()
+	}
 
 	fmt.Println()
 	fmt.Println("Backup file information:")
@@ -83,130 +592,379 @@ func runRestore(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Created:    %s\n", backup.CreatedAt.Format(time.RFC3339))
 	fmt.Printf("  Contacts:   %d\n", backup.ContactCount)
 	fmt.Printf("  Groups:     %d\n", backup.GroupCount)
+	if len(transforms) > 0 {
+		fmt.Println("  Partial:    yes, filtered when it was created")
+		eventData["partial"] = true
+	}
 	fmt.Println()
 
+	if contactRng != nil {
+		if rangeFirst == 0 {
+			return withExitCode(exitNothingToDo, fmt.Errorf("the range %s is past the end of the backup, which has %d contacts", contactRng, backupContacts))
+		}
+		fmt.Printf("Range %s selected contacts %d-%d of %d\n", contactRng, rangeFirst, rangeLast, backupContacts)
+		if selection == nil && unusedGroups > 0 {
+			fmt.Printf("Skipping %d contact groups none of them belongs to\n", unusedGroups)
+		}
+		fmt.Println()
+		eventData["range"] = contactRng.String()
+	}
+
+	if addedMemberships > 0 {
+		fmt.Printf("Recovered %d group memberships from the backup's group member lists\n", addedMemberships)
+		fmt.Println()
+	}
+
+	if selection != nil {
+		fmt.Printf("Filter selected %d contacts and skipped %d\n", len(backup.Contacts), filtered)
+		if unusedGroups > 0 {
+			fmt.Printf("Skipping %d contact groups none of them belongs to\n", unusedGroups)
+		}
+		fmt.Println()
+		if len(backup.Contacts) == 0 {
+			return withExitCode(exitNothingToDo, fmt.Errorf("no contacts match the filter"))
+		}
+	}
+
+	batchSize := contacts.BatchCreateSize
+	if trickleInterval > 0 {
+		batchSize = 1
+		eta := time.Duration(len(backup.Contacts)) * trickleInterval
+		fmt.Printf("Trickle mode: creating one contact every %s (about %s for %d contacts)\n",
+			trickleInterval, eta.Round(time.Second), len(backup.Contacts))
+		fmt.Println()
+	}
+
+	if printOrder {
+		printRestoreOrder(backup, batchSize)
+	}
+
+	// A simulation finds the same problems against the fake People API
+	if !restoreSkipCheck && !restoreSimulate {
+		if err := validateContacts(backup.Contacts, batchSize); err != nil {
+			if !restoreDryRun {
+				return fmt.Errorf("%w; nothing was changed (use --skip-validation to restore anyway)", err)
+			}
+			warnf("%v", err)
+			fmt.Println()
+		}
+	}
+
 	// Check if credentials file exists
-	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
-		return fmt.Errorf("credentials file not found: %s\n\nRun 'google-contacts-backup auth' first, or see 'google-contacts-backup --help' for setup instructions", credentialsFile)
+	if targetURL == "" {
+		if err := checkCredentials(); err != nil {
+			return err
+		}
+	}
+
+	// Replace restores to Google keep a checkpoint, so they can be resumed
+	var checkpoint *restoreCheckpoint
+	if targetURL == "" && restoreMode == restoreModeReplace && !restoreDryRun && !restoreSimulate {
+		checkpoint, err = prepareCheckpoint(backup)
+		if err != nil {
+			return err
+		}
+	}
+
+	if restoreMode == restoreModeReplace && !restoreResume {
+		printPartialWarning(transforms)
+	}
+	if (restoreMode == restoreModeReplace && !restoreResume) || restoreMode == restoreModeSelection {
+		printLinkedWarning(backup)
+	}
+
+	// Restores to Google back up the account before changing anything
+	safetyBackup := targetURL == "" && !restoreNoSafety && !restoreSimulate
+
+	if targetURL == "" && !restoreSimulate && !restoreResume {
+		forecast := forecastRestore(backup, restoreMode, batchSize, trickleInterval, safetyBackup && !restoreDryRun)
+		printRestoreForecast(forecast, trickleInterval)
+	}
+
+	if restoreDryRun {
+		eventData["file"] = inputFile
+		eventData["mode"] = restoreMode
+		eventData["dry_run"] = true
+		return runRestoreDryRun(ctx, backup, selection, batchSize)
+	}
+
+	if restoreSimulate {
+		eventData["file"] = inputFile
+		eventData["mode"] = restoreMode
+		if err := startSimulation(ctx); err != nil {
+			return err
+		}
+		defer func() { err = finishSimulation(err) }()
+	} else if err := checkWritable(); err != nil {
+		return err
+	}
+
+	if (restoreMode == restoreModeReplace || restoreMode == restoreModeSelection) && !restoreSimulate {
+		if err := requireApproval(approvalRestore, inputFile); err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.FrozenFields) > 0 && restoreMode != restoreModeFields {
+		if restoreMode == restoreModeMerge {
+			fmt.Printf("Frozen fields (%s) keep their current values.\n", strings.Join(cfg.FrozenFields, ", "))
+		} else {
+			fmt.Printf("Note: frozen fields (%s) only protect merge restores and syncs.\n", strings.Join(cfg.FrozenFields, ", "))
+			fmt.Println("      A full restore deletes every contact, including those fields.")
+		}
+		fmt.Println()
+	}
+
+	// A replace restore deletes every contact, unless a resumed one did so
+	// before the interruption
+	wipes := restoreMode == restoreModeReplace && !restoreSimulate &&
+		!(restoreResume && checkpoint != nil && checkpoint.DeletedContacts)
+	wipeCount := 0
+	if wipes && wipeGuards() {
+		if wipeCount, err = countWipedContacts(ctx); err != nil {
+			return fmt.Errorf("failed to count the contacts the restore deletes: %w", err)
+		}
+		eventData["wiped_contacts"] = wipeCount
+		if skipConfirm {
+			if err := checkUnattendedWipe(wipeCount); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Confirm with user unless --confirm flag is set
-	if !skipConfirm {
-		fmt.Println("WARNING: This will DELETE ALL existing contacts and groups!")
-		fmt.Println("It is recommended to create a backup first:")
-		fmt.Println("  google-contacts-backup backup -o pre-restore-backup.json")
+	if !skipConfirm && !restoreSimulate {
+		if targetURL != "" {
+			fmt.Printf("Target: %s\n", targetURL)
+		}
+		switch {
+		case restoreResume:
+			fmt.Println(i18n.T("restore.resume_warning"))
+		case restoreMode == restoreModeFields:
+			fmt.Println(i18n.T("restore.fields_warning", strings.Join(restoreFieldsOnly, ", ")))
+		case restoreMode == restoreModeSelection:
+			fmt.Println(i18n.T("restore.selection_warning"))
+		case restoreMode == restoreModeMerge:
+			fmt.Println(i18n.T("restore.merge_warning"))
+		default:
+			fmt.Println(i18n.T("restore.warning"))
+		}
+		if safetyBackup {
+			fmt.Println(i18n.T("restore.safety_backup_note"))
+		} else if !restoreResume {
+			fmt.Println(i18n.T("restore.recommend_backup"))
+			fmt.Println("  google-contacts-backup backup -o pre-restore-backup.json")
+		}
 		fmt.Println()
-		fmt.Print("Are you sure you want to continue? (yes/no): ")
 
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
+		var ok bool
+		if wipes && wipeGuards() {
+			ok, err = askConfirmPhrase(wipeCount)
+		} else {
+			ok, err = askConfirmation(i18n.T("confirm.continue"))
+		}
 		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+			return err
 		}
-
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "yes" && response != "y" {
-			fmt.Println("Restore cancelled.")
+		if !ok {
+			fmt.Println(i18n.T("restore.cancelled"))
+			eventData["cancelled"] = true
 			return nil
 		}
 		fmt.Println()
 	}
 
-	fmt.Println("Authenticating with Google...")
-
-	// Authenticate
-	authenticator := auth.NewAuthenticator(credentialsFile)
-	httpClient, err := authenticator.GetClient(ctx)
+	eventData["mode"] = restoreMode
+	journalPath := defaultString(restoreJournal, cfg.Restore.Journal)
+	if restoreSimulate {
+		journalPath = ""
+	}
+	journalData := map[string]any{
+		"file":       inputFile,
+		"mode":       restoreMode,
+		"target":     defaultString(targetURL, "google"),
+		"contacts":   len(backup.Contacts),
+		"groups":     len(backup.GetUserGroups()),
+		"batch_size": batchSize,
+	}
+	if restoreMode == restoreModeFields {
+		journalData["fields"] = restoreFieldsOnly
+	}
+	if selection != nil {
+		journalData["selection"] = selection.String()
+	}
+	if contactRng != nil {
+		journalData["range"] = contactRng.String()
+	}
+	err = openJournal(journalPath, "restore", journalData)
 	if err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+		return err
 	}
+	defer func() { closeJournal(start, err) }()
 
-	fmt.Println("Authentication successful!")
-	fmt.Println()
-
-	// Create contacts client
-	client, err := contacts.NewClient(ctx, httpClient)
-	if err != nil {
-		return fmt.Errorf("failed to create contacts client: %w", err)
+	if safetyBackup {
+		if err := takeSafetyBackup(ctx, checkpoint); err != nil {
+			return fmt.Errorf("%w (nothing was changed; use --no-safety-backup to restore without one)", err)
+		}
 	}
 
-	// Step 1: Delete all existing contacts
-	fmt.Println("Step 1/4: Deleting existing contacts...")
-	deleteContactsBar := progressbar.NewOptions(-1,
-		progressbar.OptionSetDescription("Deleting contacts"),
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionThrottle(100*time.Millisecond),
-		progressbar.OptionSpinnerType(14),
-		progressbar.OptionFullWidth(),
-		progressbar.OptionSetRenderBlankState(true),
-	)
+	switch restoreMode {
+	case restoreModeMerge:
+		return runMergeRestore(ctx, backup, trickleInterval)
+	case restoreModeFields:
+		return runFieldsRestore(ctx, backup, restoreFieldsOnly)
+	case restoreModeSelection:
+		return runSelectionRestore(ctx, backup, selection, trickleInterval)
+	}
 
-	var deleteTotal int
-	err = client.DeleteAllContacts(ctx, func(deleted, total int) {
-		if deleteTotal == 0 && total > 0 {
-			deleteContactsBar.ChangeMax(total)
-			deleteTotal = total
+	runCheckpoint = checkpoint
+	if checkpoint != nil && !restoreResume {
+		saveCheckpoint()
+	}
+	defer func() {
+		if err != nil && runCheckpoint != nil {
+			fmt.Println()
+			fmt.Printf("The restore was interrupted. Continue it with:\n  google-contacts-backup restore -i %s --resume\n", inputFile)
 		}
-		deleteContactsBar.Set(deleted)
-	})
-	deleteContactsBar.Finish()
-	fmt.Println()
+	}()
 
+	client, err := openRestoreTarget(ctx, trickleInterval)
 	if err != nil {
-		return fmt.Errorf("failed to delete contacts: %w", err)
+		return err
 	}
 
-	if deleteTotal > 0 {
-		fmt.Printf("Deleted %d contacts\n", deleteTotal)
+	// Step 1: Delete all existing contacts
+	resuming := restoreResume && runCheckpoint != nil
+	if resuming && runCheckpoint.DeletedContacts {
+		fmt.Println("Step 1/4: Existing contacts were deleted before the interruption")
+		fmt.Println()
 	} else {
-		fmt.Println("No existing contacts to delete")
-	}
-	fmt.Println()
+		fmt.Println("Step 1/4: Deleting existing contacts...")
+		deleteContactsBar := progressbar.NewOptions(-1,
+			progressbar.OptionSetDescription("Deleting contacts"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionSpinnerType(14),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
 
-	// Step 2: Delete user-created groups
-	fmt.Println("Step 2/4: Deleting existing contact groups...")
-	deleteGroupsBar := progressbar.NewOptions(-1,
-		progressbar.OptionSetDescription("Deleting groups"),
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionThrottle(100*time.Millisecond),
-		progressbar.OptionSpinnerType(14),
-		progressbar.OptionFullWidth(),
-		progressbar.OptionSetRenderBlankState(true),
-	)
+		var deleteTotal, keptIgnored int
+		deleteContactsProgress := func(deleted, total int) {
+			if deleteTotal == 0 && total > 0 {
+				deleteContactsBar.ChangeMax(total)
+				deleteTotal = total
+			}
+			deleteContactsBar.Set(deleted)
+		}
+		if google, ok := client.(*contacts.Client); ok && !cfg.Ignore.Empty() {
+			keptIgnored, err = deleteUnignoredContacts(ctx, google, deleteContactsProgress)
+		} else {
+			err = client.DeleteAllContacts(ctx, deleteContactsProgress)
+		}
+		deleteContactsBar.Finish()
+		fmt.Println()
 
-	var deleteGroupTotal int
-	err = client.DeleteUserGroups(ctx, func(deleted, total int) {
-		if deleteGroupTotal == 0 && total > 0 {
-			deleteGroupsBar.ChangeMax(total)
-			deleteGroupTotal = total
+		if err != nil {
+			return fmt.Errorf("failed to delete contacts: %w", err)
 		}
-		deleteGroupsBar.Set(deleted)
-	})
-	deleteGroupsBar.Finish()
-	fmt.Println()
 
-	if err != nil {
-		return fmt.Errorf("failed to delete groups: %w", err)
+		journalStep("delete_contacts", map[string]any{"deleted": deleteTotal, "ignored": keptIgnored})
+
+		if deleteTotal > 0 {
+			fmt.Printf("Deleted %d contacts\n", deleteTotal)
+		} else {
+			fmt.Println("No existing contacts to delete")
+		}
+		if keptIgnored > 0 {
+			fmt.Printf("Kept %d contacts on the ignore list\n", keptIgnored)
+		}
+		fmt.Println()
+		checkpointStep(func(c *restoreCheckpoint) { c.DeletedContacts = true })
 	}
 
-	if deleteGroupTotal > 0 {
-		fmt.Printf("Deleted %d groups\n", deleteGroupTotal)
+	// Step 2: Delete user-created groups
+	var keptGroups []*people.ContactGroup
+	if resuming && runCheckpoint.DeletedGroups {
+		fmt.Println("Step 2/4: Existing contact groups were deleted before the interruption")
+		fmt.Println()
 	} else {
-		fmt.Println("No user-created groups to delete")
+		fmt.Println("Step 2/4: Deleting existing contact groups...")
+		deleteGroupsBar := progressbar.NewOptions(-1,
+			progressbar.OptionSetDescription("Deleting groups"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionSpinnerType(14),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+
+		var deleteGroupTotal int
+		deleteProgress := func(deleted, total int) {
+			if deleteGroupTotal == 0 && total > 0 {
+				deleteGroupsBar.ChangeMax(total)
+				deleteGroupTotal = total
+			}
+			deleteGroupsBar.Set(deleted)
+		}
+		var skippedGroups []string
+		if google, ok := client.(*contacts.Client); ok && (restoreReuseGroups || len(cfg.Ignore.Labels) > 0) {
+			keptGroups, skippedGroups, err = deleteUnusedGroups(ctx, google, backup, restoreReuseGroups, deleteProgress)
+		} else {
+			skippedGroups, err = client.DeleteUserGroups(ctx, deleteProgress)
+		}
+		deleteGroupsBar.Finish()
+		fmt.Println()
+
+		if err != nil {
+			return fmt.Errorf("failed to delete groups: %w", err)
+		}
+
+		journalStep("delete_groups", map[string]any{"deleted": deleteGroupTotal - len(skippedGroups), "skipped": skippedGroups})
+
+		if deleteGroupTotal > 0 {
+			fmt.Printf("Deleted %d groups\n", deleteGroupTotal-len(skippedGroups))
+		} else {
+			fmt.Println("No user-created groups to delete")
+		}
+		if len(keptGroups) > 0 {
+			fmt.Printf("Kept %d groups named as in the backup, to reuse\n", len(keptGroups))
+		}
+		fmt.Println()
+		checkpointStep(func(c *restoreCheckpoint) { c.DeletedGroups = true })
 	}
-	fmt.Println()
 
 	// Step 3: Recreate contact groups
 	userGroups := backup.GetUserGroups()
 	groupMap := make(map[string]string)
 
-	if len(userGroups) > 0 {
+	// Groups created before an interruption are reused rather than created
+	// twice
+	reuseGroups := restoreReuseGroups
+	if resuming && !runCheckpoint.GroupsCreated && len(userGroups) > 0 {
+		keptGroups, err = listUserGroups(ctx, client.(*contacts.Client))
+		if err != nil {
+			return err
+		}
+		reuseGroups = true
+	}
+
+	if resuming && runCheckpoint.GroupsCreated {
+		groupMap = runCheckpoint.Groups
+		fmt.Printf("Step 3/4: %d contact groups were created before the interruption\n", len(groupMap))
+	} else if len(userGroups) > 0 {
+		groupsToCreate := userGroups
+		var sameName map[string][]string
+		if reuseGroups {
+			groupMap, groupsToCreate, sameName = planGroups(userGroups, keptGroups)
+		}
+
 		fmt.Println("Step 3/4: Creating contact groups...")
-		createGroupsBar := progressbar.NewOptions(len(userGroups),
+		createGroupsBar := progressbar.NewOptions(len(groupsToCreate),
 			progressbar.OptionSetDescription("Creating groups"),
 			progressbar.OptionSetWriter(os.Stderr),
 			progressbar.OptionShowCount(),
@@ -216,7 +974,7 @@ func runRestore(cmd *cobra.Command, args []string) error {
 			progressbar.OptionSetRenderBlankState(true),
 		)
 
-		groupMap, err = client.CreateGroups(ctx, userGroups, func(created, total int) {
+		createdGroups, err := client.CreateGroups(ctx, groupsToCreate, func(created, total int) {
 			createGroupsBar.Set(created)
 		})
 		createGroupsBar.Finish()
@@ -225,17 +983,37 @@ func runRestore(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to create groups: %w", err)
 		}
+		for _, group := range groupsToCreate {
+			if created, ok := createdGroups[group.ResourceName]; ok {
+				groupMap[group.ResourceName] = created
+				for _, name := range sameName[group.Name] {
+					groupMap[name] = created
+				}
+			}
+		}
+		journalStep("create_groups", map[string]any{"groups": groupMap})
+		checkpointStep(func(c *restoreCheckpoint) { c.GroupsCreated, c.Groups = true, groupMap })
 
-		fmt.Printf("Created %d groups\n", len(groupMap))
+		if reuseGroups {
+			fmt.Printf("Created %d groups, reused %d existing groups\n", len(createdGroups), len(userGroups)-len(groupsToCreate))
+		} else {
+			fmt.Printf("Created %d groups\n", len(groupMap))
+		}
 	} else {
 		fmt.Println("Step 3/4: No user-created groups to restore")
+		checkpointStep(func(c *restoreCheckpoint) { c.GroupsCreated = true })
 	}
 	fmt.Println()
 
 	// Step 4: Recreate contacts
-	if len(backup.Contacts) > 0 {
+	var restoredPhotos int
+	toCreate := backup.Contacts
+	if resuming {
+		toCreate = runCheckpoint.remainingContacts(backup)
+	}
+	if len(toCreate) > 0 {
 		fmt.Println("Step 4/4: Creating contacts...")
-		createContactsBar := progressbar.NewOptions(len(backup.Contacts),
+		createContactsBar := progressbar.NewOptions(len(toCreate),
 			progressbar.OptionSetDescription("Creating contacts"),
 			progressbar.OptionSetWriter(os.Stderr),
 			progressbar.OptionShowCount(),
@@ -245,7 +1023,7 @@ func runRestore(cmd *cobra.Command, args []string) error {
 			progressbar.OptionSetRenderBlankState(true),
 		)
 
-		err = client.CreateContacts(ctx, backup.Contacts, groupMap, func(created, total int) {
+		created, err := client.CreateContacts(ctx, toCreate, groupMap, func(created, total int) {
 			createContactsBar.Set(created)
 		})
 		createContactsBar.Finish()
@@ -255,20 +1033,438 @@ func runRestore(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to create contacts: %w", err)
 		}
 
-		fmt.Printf("Created %d contacts\n", len(backup.Contacts))
+		if resuming {
+			fmt.Printf("Created %d contacts, %d were created before the interruption\n", len(toCreate), len(backup.Contacts)-len(toCreate))
+			for name, createdName := range runCheckpoint.Created {
+				created[name] = createdName
+			}
+		} else {
+			fmt.Printf("Created %d contacts\n", len(backup.Contacts))
+		}
+		journalStep("create_contacts", map[string]any{"created": len(toCreate)})
+
+		if google, ok := client.(*contacts.Client); ok {
+			restoredPhotos, err = restorePhotos(ctx, google, backup, created)
+			if err != nil {
+				return err
+			}
+		}
+	} else if resuming && len(backup.Contacts) > 0 {
+		fmt.Println("Step 4/4: All contacts were created before the interruption")
 	} else {
 		fmt.Println("Step 4/4: No contacts to restore")
 	}
+	clearCheckpoint()
+
+	eventData["file"] = inputFile
+	if targetURL != "" {
+		eventData["target"] = "carddav"
+	}
+	eventData["contacts"] = len(backup.Contacts)
+	eventData["groups"] = len(groupMap)
+	eventData["photos"] = restoredPhotos
 
 	// Print summary
 	fmt.Println()
-	fmt.Println("Restore completed successfully!")
+	fmt.Println(i18n.T("restore.completed"))
+	fmt.Println()
+	fmt.Println(i18n.T("restore.summary.contacts", len(backup.Contacts)))
+	fmt.Println(i18n.T("restore.summary.groups", len(groupMap)))
+	if restoredPhotos > 0 {
+		fmt.Println(i18n.T("restore.summary.photos", restoredPhotos))
+	}
+	printSafetyBackupSummary()
+	if targetURL == "" && len(backup.Photos) == 0 {
+		fmt.Println()
+		fmt.Println(i18n.T("restore.photos_note"))
+	}
+
+	if google, ok := client.(*contacts.Client); ok && restoreSnapshot && !restoreSimulate {
+		fmt.Println()
+		path, err := takePostRestoreSnapshot(ctx, google, backup, journalPath)
+		if err != nil {
+			// The restore itself succeeded
+			warnf("%v", err)
+		}
+		if path != "" {
+			eventData["snapshot"] = path
+		}
+	}
+
+	return nil
+}
+
+// runMergeRestore upserts the backup's contacts and labels into the live
+// Google account without deleting anything.
+func runMergeRestore(ctx context.Context, backup *models.BackupFile, trickleInterval time.Duration) error {
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx, append(journalOptions(), contacts.WithTrickle(trickleInterval))...)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	// Step 1: Fetch the live account to match against
+	fmt.Println("Step 1/4: Fetching existing contacts and groups...")
+	fetchBar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Downloading"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	var totalKnown bool
+	live, err := client.ListContacts(ctx, func(current, total int) {
+		if !totalKnown && total > 0 {
+			fetchBar.ChangeMax(total)
+			totalKnown = true
+		}
+		fetchBar.Set(current)
+	})
+	fetchBar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+	liveGroups, err := client.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	fmt.Printf("Found %d existing contacts and %d groups\n", len(live), len(liveGroups))
+	fmt.Println()
+
+	// Step 2: Match labels by name, creating the missing ones
+	fmt.Println("Step 2/4: Matching contact groups...")
+	groupMap, createdGroups, err := matchGroups(ctx, client, backup.GetUserGroups(), liveGroups)
+	if err != nil {
+		return err
+	}
+	journalStep("match_groups", map[string]any{"groups": groupMap, "created": createdGroups})
+	if createdGroups > 0 {
+		fmt.Printf("Created %d groups, reused the others\n", createdGroups)
+	} else {
+		fmt.Printf("All %d contact groups already exist\n", len(groupMap))
+	}
+	fmt.Println()
+
+	// Step 3: Update matched contacts that differ from the backup
+	plan := planMerge(backup, live, groupMap)
+	changed, missing, unchanged, photoTargets := plan.changed, plan.missing, plan.unchanged, plan.photoTargets
+
+	if len(changed) > 0 {
+		fmt.Println("Step 3/4: Updating existing contacts...")
+		updateBar := progressbar.NewOptions(len(changed),
+			progressbar.OptionSetDescription("Updating contacts"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+		_, err := client.UpdateContacts(ctx, changed, contacts.WritableFields, func(updated, total int) {
+			updateBar.Set(updated)
+		})
+		updateBar.Finish()
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("failed to update contacts: %w", err)
+		}
+		journalStep("update_contacts", map[string]any{"updated": len(changed)})
+		fmt.Printf("Updated %d contacts, %d already up to date\n", len(changed), unchanged)
+	} else {
+		fmt.Printf("Step 3/4: %d matching contacts already up to date\n", unchanged)
+	}
+	fmt.Println()
+
+	// Step 4: Create the contacts the account is missing
+	if len(missing) > 0 {
+		fmt.Println("Step 4/4: Creating missing contacts...")
+		createBar := progressbar.NewOptions(len(missing),
+			progressbar.OptionSetDescription("Creating contacts"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+		created, err := client.CreateContacts(ctx, missing, groupMap, func(created, total int) {
+			createBar.Set(created)
+		})
+		createBar.Finish()
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("failed to create contacts: %w", err)
+		}
+		fmt.Printf("Created %d contacts\n", len(missing))
+		journalStep("create_contacts", map[string]any{"created": len(missing)})
+		maps.Copy(photoTargets, created)
+	} else {
+		fmt.Println("Step 4/4: No missing contacts to create")
+	}
+
+	restoredPhotos, err := restorePhotos(ctx, client, backup, photoTargets)
+	if err != nil {
+		return err
+	}
+
+	eventData["file"] = inputFile
+	eventData["contacts"] = len(backup.Contacts)
+	eventData["created"] = len(missing)
+	eventData["updated"] = len(changed)
+	eventData["unchanged"] = unchanged
+	eventData["photos"] = restoredPhotos
+
 	fmt.Println()
-	fmt.Printf("  Contacts restored: %d\n", len(backup.Contacts))
-	fmt.Printf("  Groups restored:   %d\n", len(groupMap))
+	fmt.Println(i18n.T("restore.completed"))
 	fmt.Println()
-	fmt.Println("Note: Contact photos were not restored (API limitation).")
-	fmt.Println("Photo URLs in the backup may have expired.")
+	fmt.Println(i18n.T("restore.summary.created", len(missing)))
+	fmt.Println(i18n.T("restore.summary.updated", len(changed)))
+	fmt.Println(i18n.T("restore.summary.unchanged", unchanged))
+	if restoredPhotos > 0 {
+		fmt.Println(i18n.T("restore.summary.photos", restoredPhotos))
+	}
+	printSafetyBackupSummary()
 
 	return nil
 }
+
+// matchGroups maps backup user groups to the live groups with the same name,
+// creating the groups the account is missing. Backup groups that share a
+// name map to the same live group. Returns the map from backup to live
+// resource names and the number of groups created.
+func matchGroups(ctx context.Context, client *contacts.Client, groups, liveGroups []*people.ContactGroup) (map[string]string, int, error) {
+	groupMap, missing, sameName := planGroups(groups, liveGroups)
+	if len(missing) == 0 {
+		return groupMap, 0, nil
+	}
+
+	created, err := client.CreateGroups(ctx, missing, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create groups: %w", err)
+	}
+	for _, group := range missing {
+		if live, ok := created[group.ResourceName]; ok {
+			for _, name := range sameName[group.Name] {
+				groupMap[name] = live
+			}
+		}
+	}
+
+	return groupMap, len(created), nil
+}
+
+// printLinkedWarning lists the backup contacts linked to Google profiles or
+// other people, whose links a replace restore breaks. It is not counted as a
+// warning for --strict, since it is shown before the user confirms.
+func printLinkedWarning(backup *models.BackupFile) {
+	linked := models.LinkedContacts(backup.Contacts)
+	if len(linked) == 0 {
+		return
+	}
+	eventData["linked"] = len(linked)
+	fmt.Printf("Warning: %d contacts are linked to Google profiles or other people. Restoring\n", len(linked))
+	fmt.Println("recreates them as new contacts, which breaks these links; Google may link")
+	fmt.Println("some of them again later. Affected contacts:")
+	for _, contact := range linked {
+		fmt.Printf("  %s (%s): %s\n", models.DisplayName(contact), contact.ResourceName, strings.Join(models.LinkedProfiles(contact), ", "))
+	}
+	fmt.Println()
+}
+
+// printPartialWarning lists the transforms applied to a backup when it was
+// created. A replace restore of a filtered backup deletes every contact the
+// filters left out.
+func printPartialWarning(transforms []models.Transform) {
+	if len(transforms) == 0 {
+		return
+	}
+	fmt.Println("Warning: this backup is partial. It was filtered when it was created:")
+	for _, transform := range transforms {
+		fmt.Printf("  %s\n", transform)
+	}
+	fmt.Println("Restoring it in replace mode deletes every contact and field the filters left out.")
+	fmt.Println("Use --mode merge to add these contacts without deleting any.")
+	fmt.Println()
+}
+
+// deleteUnignoredContacts deletes every contact in the account except those on
+// the config file's ignore list, and returns how many were kept.
+func deleteUnignoredContacts(ctx context.Context, client *contacts.Client, progressFn func(deleted, total int)) (int, error) {
+	live, err := client.ListContacts(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	liveGroups, err := client.ListGroups(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	kept, ignored := cfg.Ignore.Split(live, groupNamesOf(liveGroups))
+	resourceNames := make([]string, 0, len(kept))
+	for _, contact := range kept {
+		resourceNames = append(resourceNames, contact.ResourceName)
+	}
+	return len(ignored), client.DeleteContacts(ctx, resourceNames, progressFn)
+}
+
+// groupNamesOf maps the resource names of user groups to their names.
+func groupNamesOf(groups []*people.ContactGroup) map[string]string {
+	names := make(map[string]string)
+	for _, group := range groups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			names[group.ResourceName] = group.Name
+		}
+	}
+	return names
+}
+
+// deleteUnusedGroups deletes the live user groups except those on the ignore
+// list and, with reuse, those whose names a backup group has, which are kept
+// to reuse. Returns the kept groups to reuse and the names of groups that
+// could not be deleted.
+func deleteUnusedGroups(ctx context.Context, client *contacts.Client, backup *models.BackupFile, reuse bool, progressFn func(deleted, total int)) ([]*people.ContactGroup, []string, error) {
+	liveGroups, err := client.ListGroups(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make(map[string]bool)
+	if reuse {
+		for _, name := range backup.UserGroupNames() {
+			names[name] = true
+		}
+	}
+	var kept, unused []*people.ContactGroup
+	for _, group := range liveGroups {
+		if group.GroupType != "USER_CONTACT_GROUP" {
+			continue
+		}
+		switch {
+		case names[group.Name]:
+			kept = append(kept, group)
+		case cfg.Ignore.HasLabel(group.Name):
+			// Deleting it would drop the label from the ignored contacts
+		default:
+			unused = append(unused, group)
+		}
+	}
+
+	skipped, err := client.DeleteGroups(ctx, unused, progressFn)
+	return kept, skipped, err
+}
+
+// planGroups maps backup user groups to the live groups with the same name.
+// It returns that map, one backup group per name the account is missing,
+// and the backup resource names sharing each missing name.
+func planGroups(groups, liveGroups []*people.ContactGroup) (map[string]string, []*people.ContactGroup, map[string][]string) {
+	groupMap := make(map[string]string)
+	liveGroupsByName := make(map[string]string)
+	for _, group := range liveGroups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			liveGroupsByName[group.Name] = group.ResourceName
+		}
+	}
+
+	var missing []*people.ContactGroup
+	sameName := make(map[string][]string)
+	for _, group := range groups {
+		if existing, ok := liveGroupsByName[group.Name]; ok {
+			groupMap[group.ResourceName] = existing
+			continue
+		}
+		if _, ok := sameName[group.Name]; !ok {
+			missing = append(missing, group)
+		}
+		sameName[group.Name] = append(sameName[group.Name], group.ResourceName)
+	}
+
+	return groupMap, missing, sameName
+}
+
+// mergePlan is what a merge restore changes in the live account.
+type mergePlan struct {
+	// changed are the merged versions of matched contacts that differ
+	changed []*people.Person
+
+	// missing are backup contacts to create
+	missing []*people.Person
+
+	// unchanged counts matched contacts that are already up to date
+	unchanged int
+
+	// photoTargets maps backup resource names to the live contacts that
+	// receive the backup's photo: matched contacts without a photo of
+	// their own. Created contacts are added once they exist.
+	photoTargets map[string]string
+}
+
+// planMerge matches the backup's contacts with the live ones and works out
+// which to update and which to create. groupMap maps backup group resource
+// names to live ones.
+func planMerge(backup *models.BackupFile, live []*people.Person, groupMap map[string]string) *mergePlan {
+	matches := reconcile.Match(backup.Contacts, live)
+	plan := &mergePlan{photoTargets: make(map[string]string)}
+	for _, contact := range backup.Contacts {
+		existing, ok := matches[contact]
+		if !ok {
+			plan.missing = append(plan.missing, contact)
+			continue
+		}
+		if models.ContactPhoto(existing) == nil {
+			plan.photoTargets[contact.ResourceName] = existing.ResourceName
+		}
+		merged := reconcile.Merge(existing, contact, groupMap, cfg.FrozenFields)
+		if reconcile.Changed(existing, merged) {
+			plan.changed = append(plan.changed, merged)
+		}
+	}
+	plan.unchanged = len(matches) - len(plan.changed)
+	return plan
+}
+
+// restorePhotos uploads the photos embedded in the backup (see backup
+// --photo-bytes) to the live contacts they were restored to. targets maps
+// backup resource names to live ones. Returns the number of photos uploaded.
+func restorePhotos(ctx context.Context, client *contacts.Client, backup *models.BackupFile, targets map[string]string) (int, error) {
+	photos := make(map[string][]byte)
+	for old, live := range targets {
+		if photo := backup.Photos[old]; photo != nil && len(photo.Data) > 0 {
+			photos[live] = photo.Data
+		}
+	}
+	if len(photos) == 0 {
+		return 0, nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Restoring %d contact photos...\n", len(photos))
+	bar := progressbar.NewOptions(len(photos),
+		progressbar.OptionSetDescription("Uploading photos"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	skipped, err := client.RestorePhotos(ctx, photos, func(done, total int) {
+		bar.Set(done)
+	})
+	bar.Finish()
+	fmt.Println()
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore photos: %w", err)
+	}
+
+	journalStep("restore_photos", map[string]any{"restored": len(photos) - len(skipped), "skipped": skipped})
+	fmt.Printf("Restored %d contact photos\n", len(photos)-len(skipped))
+
+	return len(photos) - len(skipped), nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mheap/google-contacts-backup/internal/carddav"
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+)
+
+// confirmPhraseCount is replaced by the number of contacts to delete in
+// restore.confirm_phrase
+const confirmPhraseCount = "{count}"
+
+// wipeGuards reports whether a replace restore that deletes every contact
+// needs the live contact count before it asks for confirmation: to fill in
+// restore.confirm_phrase, or to check restore.max_unattended_delete when
+// --confirm skips the prompt.
+func wipeGuards() bool {
+	if skipConfirm {
+		return cfg.Restore.MaxUnattendedDelete > 0 && !restoreDataLoss
+	}
+	return strings.TrimSpace(cfg.Restore.ConfirmPhrase) != ""
+}
+
+// countWipedContacts returns the number of contacts in the Google account,
+// or the CardDAV address book given with --target, which a replace restore
+// deletes.
+func countWipedContacts(ctx context.Context) (int, error) {
+	if targetURL != "" {
+		client, err := carddav.NewClient(targetURL, nil)
+		if err != nil {
+			return 0, err
+		}
+		return client.CountCards(ctx)
+	}
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return client.CountContacts(ctx)
+}
+
+// checkUnattendedWipe refuses a replace restore run with --confirm that
+// deletes more than restore.max_unattended_delete contacts, unless
+// --i-understand-data-loss is given too.
+func checkUnattendedWipe(count int) error {
+	limit := cfg.Restore.MaxUnattendedDelete
+	if limit <= 0 || count <= limit || restoreDataLoss {
+		return nil
+	}
+	return fmt.Errorf("the restore would delete %d contacts, more than restore.max_unattended_delete (%d) allows with --confirm alone; "+
+		"check the backup and the target, then add --i-understand-data-loss", count, limit)
+}
+
+// askConfirmPhrase asks the user to type restore.confirm_phrase, with the
+// number of contacts to delete filled in, and reports whether they typed it
+// exactly.
+func askConfirmPhrase(count int) (bool, error) {
+	phrase := strings.ReplaceAll(strings.TrimSpace(cfg.Restore.ConfirmPhrase), confirmPhraseCount, strconv.Itoa(count))
+	fmt.Print(i18n.T("restore.confirm_phrase", phrase))
+	response, err := readAnswer()
+	if err != nil {
+		return false, err
+	}
+	return response == phrase, nil
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/carddav"
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/filter"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// runRestoreDryRun reads the current state of the target and prints what a
+// restore of backup would delete, create and update, without changing
+// anything. selection is the filter the backup was narrowed with, if any, and
+// batchSize the number of contacts created per request.
+func runRestoreDryRun(ctx context.Context, backup *models.BackupFile, selection *filter.Filter, batchSize int) error {
+	client, err := openRestoreTarget(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Dry run: nothing will be deleted, created or updated.")
+	fmt.Println()
+
+	if dav, ok := client.(*carddav.Client); ok {
+		cards, err := dav.CountCards(ctx)
+		if err != nil {
+			return err
+		}
+		eventData["would_delete"] = cards
+		eventData["would_create"] = len(backup.Contacts)
+
+		fmt.Printf("Would delete all %d cards in the address book\n", cards)
+		fmt.Println()
+		printWouldCreate(backup.Contacts, 1)
+		fmt.Println("Dry run complete: no changes were made.")
+		return nil
+	}
+
+	google := client.(*contacts.Client)
+	live, liveGroups, err := fetchLiveAccount(ctx, google)
+	if err != nil {
+		return err
+	}
+
+	switch restoreMode {
+	case restoreModeMerge:
+		return printMergeDryRun(backup, live, liveGroups)
+	case restoreModeFields:
+		return printFieldsDryRun(backup, live, restoreFieldsOnly)
+	case restoreModeSelection:
+		return printSelectionDryRun(backup, live, liveGroups, selection, batchSize)
+	}
+
+	userGroups := backup.GetUserGroups()
+	backupGroupNames := make(map[string]bool)
+	for _, group := range userGroups {
+		backupGroupNames[group.Name] = true
+	}
+	var deleteGroups, keptGroups []*people.ContactGroup
+	for _, group := range liveGroups {
+		if group.GroupType != "USER_CONTACT_GROUP" {
+			continue
+		}
+		switch {
+		case restoreReuseGroups && backupGroupNames[group.Name]:
+			keptGroups = append(keptGroups, group)
+		case cfg.Ignore.HasLabel(group.Name):
+		default:
+			deleteGroups = append(deleteGroups, group)
+		}
+	}
+	if restoreReuseGroups {
+		_, userGroups, _ = planGroups(userGroups, keptGroups)
+	}
+	photos := countPhotos(backup, backup.Contacts)
+	live, ignored := cfg.Ignore.Split(live, groupNamesOf(liveGroups))
+
+	eventData["would_delete"] = len(live)
+	eventData["would_delete_groups"] = len(deleteGroups)
+	eventData["would_create_groups"] = len(userGroups)
+	eventData["would_create"] = len(backup.Contacts)
+	eventData["would_restore_photos"] = photos
+
+	fmt.Printf("Would delete %d contacts:\n", len(live))
+	for _, contact := range live {
+		fmt.Printf("  %s (%s)\n", models.DisplayName(contact), contact.ResourceName)
+	}
+	fmt.Println()
+	if len(ignored) > 0 {
+		fmt.Printf("Would keep %d contacts on the ignore list\n", len(ignored))
+		fmt.Println()
+	}
+	printGroupNames("Would delete", deleteGroups)
+	if restoreReuseGroups {
+		printGroupNames("Would reuse", keptGroups)
+	}
+	printGroupNames("Would create", userGroups)
+	printWouldCreate(backup.Contacts, batchSize)
+	if photos > 0 {
+		fmt.Printf("Would restore %d contact photos\n", photos)
+		fmt.Println()
+	}
+
+	fmt.Println("Dry run complete: no changes were made.")
+	return nil
+}
+
+// printMergeDryRun prints the groups and contacts a merge restore would
+// create and the contacts it would update, with the fields that change.
+func printMergeDryRun(backup *models.BackupFile, live []*people.Person, liveGroups []*people.ContactGroup) error {
+	groupMap, missingGroups, sameName := planGroups(backup.GetUserGroups(), liveGroups)
+	// Groups that would be created get placeholder resource names, so
+	// memberships of the new groups count as changes
+	for _, group := range missingGroups {
+		for _, name := range sameName[group.Name] {
+			groupMap[name] = "new:" + group.Name
+		}
+	}
+
+	plan := planMerge(backup, live, groupMap)
+	liveByName := make(map[string]*people.Person, len(live))
+	for _, contact := range live {
+		liveByName[contact.ResourceName] = contact
+	}
+	photos := 0
+	for old := range plan.photoTargets {
+		if photo := backup.Photos[old]; photo != nil && len(photo.Data) > 0 {
+			photos++
+		}
+	}
+	photos += countPhotos(backup, plan.missing)
+
+	eventData["would_create_groups"] = len(missingGroups)
+	eventData["would_update"] = len(plan.changed)
+	eventData["unchanged"] = plan.unchanged
+	eventData["would_create"] = len(plan.missing)
+	eventData["would_restore_photos"] = photos
+
+	printGroupNames("Would create", missingGroups)
+
+	fmt.Printf("Would update %d contacts (%d already up to date):\n", len(plan.changed), plan.unchanged)
+	for _, merged := range plan.changed {
+		existing := liveByName[merged.ResourceName]
+		var fields []string
+		for _, field := range models.DiffFields(existing, merged) {
+			if slices.Contains(contacts.WritableFields, field) {
+				fields = append(fields, field)
+			}
+		}
+		fmt.Printf("  %s (%s): %s\n", models.DisplayName(existing), existing.ResourceName, strings.Join(fields, ", "))
+	}
+	fmt.Println()
+
+	fmt.Printf("Would create %d contacts:\n", len(plan.missing))
+	for _, contact := range plan.missing {
+		fmt.Printf("  %s (%s)\n", models.DisplayName(contact), contact.ResourceName)
+	}
+	fmt.Println()
+	if photos > 0 {
+		fmt.Printf("Would restore %d contact photos\n", photos)
+		fmt.Println()
+	}
+
+	fmt.Println("Dry run complete: no changes were made.")
+	return nil
+}
+
+// fetchLiveAccount downloads the contacts and contact groups of the live account.
+func fetchLiveAccount(ctx context.Context, client *contacts.Client) ([]*people.Person, []*people.ContactGroup, error) {
+	fmt.Println("Fetching current contacts and groups...")
+	fetchBar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Downloading"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	var totalKnown bool
+	live, err := client.ListContacts(ctx, func(current, total int) {
+		if !totalKnown && total > 0 {
+			fetchBar.ChangeMax(total)
+			totalKnown = true
+		}
+		fetchBar.Set(current)
+	})
+	fetchBar.Finish()
+	fmt.Println()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+	liveGroups, err := client.ListGroups(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	fmt.Printf("Found %d existing contacts and %d groups\n", len(live), len(liveGroups))
+	fmt.Println()
+
+	return live, liveGroups, nil
+}
+
+// printWouldCreate lists the contacts a restore would create, by batch.
+func printWouldCreate(toCreate []*people.Person, batchSize int) {
+	fmt.Printf("Would create %d contacts:\n", len(toCreate))
+	for i, contact := range toCreate {
+		if batchSize > 1 && i%batchSize == 0 {
+			fmt.Printf("  batch %d:\n", i/batchSize)
+		}
+		fmt.Printf("    %d. %s (%s)\n", i+1, models.DisplayName(contact), contact.ResourceName)
+	}
+	fmt.Println()
+}
+
+// printGroupNames lists user groups under a heading such as "Would delete".
+func printGroupNames(action string, groups []*people.ContactGroup) {
+	fmt.Printf("%s %d contact groups:\n", action, len(groups))
+	for _, group := range groups {
+		fmt.Printf("  %s\n", group.Name)
+	}
+	fmt.Println()
+}
+
+// countPhotos counts the embedded photos of the given backup contacts.
+func countPhotos(backup *models.BackupFile, selected []*people.Person) int {
+	count := 0
+	for _, contact := range selected {
+		if photo := backup.Photos[contact.ResourceName]; photo != nil && len(photo.Data) > 0 {
+			count++
+		}
+	}
+	return count
+}
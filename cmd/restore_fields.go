@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/reconcile"
+)
+
+// parseFieldsOnly resolves the --fields-only list to API field names. Only
+// writable fields can be restored; labels are rejected, as memberships refer
+// to the backup's groups, and so are frozen fields.
+func parseFieldsOnly(names []string) ([]string, error) {
+	var fields []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field, ok := models.ResolveFieldName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q in --fields-only", name)
+		}
+		if !slices.Contains(contacts.WritableFields, field) {
+			return nil, fmt.Errorf("field %q is read-only and cannot be restored", name)
+		}
+		if field == "memberships" {
+			return nil, fmt.Errorf("labels cannot be restored with --fields-only; use --mode merge instead")
+		}
+		if slices.Contains(cfg.FrozenFields, field) {
+			return nil, fmt.Errorf("field %q is frozen in the config file", field)
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("--fields-only needs at least one field")
+	}
+	return fields, nil
+}
+
+// fieldsPlan is what a --fields-only restore changes in the live account.
+type fieldsPlan struct {
+	// changed are the matched contacts with the selected fields taken
+	// from the backup, where that changes them
+	changed []*people.Person
+
+	// unchanged counts matched contacts whose fields already match
+	unchanged int
+
+	// unmatched are backup contacts with no live counterpart; they are
+	// skipped, as a fields-only restore creates nothing
+	unmatched []*people.Person
+}
+
+// planFieldsRestore matches the backup's contacts with the live ones and
+// works out which to update.
+func planFieldsRestore(backup *models.BackupFile, live []*people.Person, fields []string) *fieldsPlan {
+	matches := reconcile.Match(backup.Contacts, live)
+	plan := &fieldsPlan{}
+	for _, contact := range backup.Contacts {
+		existing, ok := matches[contact]
+		if !ok {
+			plan.unmatched = append(plan.unmatched, contact)
+			continue
+		}
+		merged := reconcile.MergeFields(existing, contact, fields)
+		if reconcile.Changed(existing, merged) {
+			plan.changed = append(plan.changed, merged)
+		}
+	}
+	plan.unchanged = len(matches) - len(plan.changed)
+	return plan
+}
+
+// runFieldsRestore writes the selected fields of the backup's contacts onto
+// the matching live contacts, with an update mask of only those fields.
+func runFieldsRestore(ctx context.Context, backup *models.BackupFile, fields []string) error {
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx, journalOptions()...)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	live, _, err := fetchLiveAccount(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	plan := planFieldsRestore(backup, live, fields)
+	if len(plan.unmatched) > 0 {
+		fmt.Printf("Skipping %d backup contacts that do not exist in the account\n", len(plan.unmatched))
+		fmt.Println()
+	}
+
+	if len(plan.changed) > 0 {
+		fmt.Printf("Updating %s on %d contacts...\n", strings.Join(fields, ", "), len(plan.changed))
+		updateBar := progressbar.NewOptions(len(plan.changed),
+			progressbar.OptionSetDescription("Updating contacts"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+		_, err := client.UpdateContacts(ctx, plan.changed, fields, func(updated, total int) {
+			updateBar.Set(updated)
+		})
+		updateBar.Finish()
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("failed to update contacts: %w", err)
+		}
+		journalStep("update_contacts", map[string]any{"updated": len(plan.changed), "fields": fields})
+		fmt.Printf("Updated %d contacts, %d already up to date\n", len(plan.changed), plan.unchanged)
+	} else {
+		fmt.Printf("All %d matching contacts already up to date\n", plan.unchanged)
+	}
+
+	eventData["file"] = inputFile
+	eventData["fields"] = fields
+	eventData["contacts"] = len(backup.Contacts)
+	eventData["updated"] = len(plan.changed)
+	eventData["unchanged"] = plan.unchanged
+	eventData["unmatched"] = len(plan.unmatched)
+
+	fmt.Println()
+	fmt.Println(i18n.T("restore.completed"))
+	fmt.Println()
+	fmt.Println(i18n.T("restore.summary.updated", len(plan.changed)))
+	fmt.Println(i18n.T("restore.summary.unchanged", plan.unchanged))
+	printSafetyBackupSummary()
+
+	return nil
+}
+
+// printFieldsDryRun prints the contacts a --fields-only restore would update,
+// with the fields that change, and the backup contacts it would skip.
+func printFieldsDryRun(backup *models.BackupFile, live []*people.Person, fields []string) error {
+	plan := planFieldsRestore(backup, live, fields)
+	liveByName := make(map[string]*people.Person, len(live))
+	for _, contact := range live {
+		liveByName[contact.ResourceName] = contact
+	}
+
+	eventData["fields"] = fields
+	eventData["would_update"] = len(plan.changed)
+	eventData["unchanged"] = plan.unchanged
+	eventData["unmatched"] = len(plan.unmatched)
+
+	fmt.Printf("Would update %d contacts (%d already up to date):\n", len(plan.changed), plan.unchanged)
+	for _, merged := range plan.changed {
+		existing := liveByName[merged.ResourceName]
+		fmt.Printf("  %s (%s): %s\n", models.DisplayName(existing), existing.ResourceName, strings.Join(models.DiffFields(existing, merged), ", "))
+	}
+	fmt.Println()
+
+	fmt.Printf("Would skip %d backup contacts that do not exist in the account:\n", len(plan.unmatched))
+	for _, contact := range plan.unmatched {
+		fmt.Printf("  %s (%s)\n", models.DisplayName(contact), contact.ResourceName)
+	}
+	fmt.Println()
+
+	fmt.Println("Dry run complete: no changes were made.")
+	return nil
+}
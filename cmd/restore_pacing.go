@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/state"
+)
+
+// People API page and batch sizes the forecast assumes, as used by the
+// contacts client
+const (
+	forecastPageSize   = 1000
+	forecastDeleteSize = 500
+	forecastUpdateSize = 200
+)
+
+// restoreForecast is the People API usage a restore is expected to need.
+type restoreForecast struct {
+	reads, writes int
+
+	// duration is how long the requests take at the configured rate
+	duration time.Duration
+}
+
+// forecastRestore estimates the requests a restore of backup in mode sends,
+// assuming the account holds about as many contacts as the backup (a
+// replace restore deletes them first), and how long they take at the
+// configured request rate or trickle interval. Every contact is assumed to
+// need a write; a merge restore that finds contacts unchanged sends fewer.
+func forecastRestore(backup *models.BackupFile, mode string, batchSize int, trickle time.Duration, safetyBackup bool) restoreForecast {
+	n := len(backup.Contacts)
+	groups := len(backup.GetUserGroups())
+	pages := ceilDiv(n, forecastPageSize)
+	creates := ceilDiv(n, batchSize)
+
+	// Listing the account's contacts and groups
+	var f restoreForecast
+	f.reads = pages + 1
+	switch mode {
+	case restoreModeReplace:
+		f.writes = ceilDiv(n, forecastDeleteSize) + 2*groups + creates + len(backup.Photos)
+		// The account is fetched again to check the result
+		f.reads += pages + 1 + groups
+	case restoreModeSelection:
+		f.writes = ceilDiv(n, forecastDeleteSize) + groups + creates + len(backup.Photos)
+	case restoreModeMerge:
+		f.writes = groups + creates + 1 + len(backup.Photos)
+	case restoreModeFields:
+		f.writes = ceilDiv(n, forecastUpdateSize)
+	}
+	if safetyBackup {
+		f.reads += pages + 1 + groups
+	}
+
+	rate := contacts.RequestRate(rateLimit, maxRequestsPerMinute)
+	f.duration = time.Duration(float64(f.reads+f.writes) / rate * float64(time.Second))
+	if trickled := time.Duration(n) * trickle; trickled > f.duration {
+		f.duration = trickled
+	}
+	return f
+}
+
+// printRestoreForecast prints the expected API usage and duration of a
+// restore, and how it compares with what is left of the daily write quota
+// (restore.daily_write_quota) after the requests recorded today.
+func printRestoreForecast(f restoreForecast, trickle time.Duration) {
+	fmt.Println("Expected People API usage:")
+	fmt.Printf("  Write requests: about %d\n", f.writes)
+	fmt.Printf("  Read requests:  about %d\n", f.reads)
+	if trickle > 0 {
+		fmt.Printf("  Duration:       about %s, trickling one contact every %s\n", roundDuration(f.duration), trickle)
+	} else {
+		fmt.Printf("  Duration:       about %s at %g requests/s\n", roundDuration(f.duration), contacts.RequestRate(rateLimit, maxRequestsPerMinute))
+	}
+
+	reset := contacts.NextQuotaReset().Local().Format("15:04 MST")
+	used, err := todaysUsage()
+	if err != nil {
+		fmt.Printf("  Used today:     unknown (%v)\n", err)
+	} else {
+		fmt.Printf("  Used today:     %d writes, %d reads (the quota resets at %s)\n", used.Writes, used.Reads, reset)
+	}
+
+	quota := cfg.Restore.DailyWriteQuota
+	switch {
+	case quota <= 0:
+		fmt.Println("  Daily quota:    unknown (set restore.daily_write_quota in the config file to check)")
+	case err != nil:
+		fmt.Printf("  Daily quota:    %d writes\n", quota)
+	case used.Writes+f.writes <= quota:
+		fmt.Printf("  Daily quota:    %d of %d writes after the restore\n", used.Writes+f.writes, quota)
+	default:
+		fmt.Printf("  Daily quota:    %d writes, %d left today\n", quota, max(quota-used.Writes, 0))
+		fmt.Println()
+		warnf("the restore needs about %d write requests but only %d are left of today's quota: "+
+			"split it with --range, --group or --filter, or wait until the quota resets at %s", f.writes, max(quota-used.Writes, 0), reset)
+	}
+	fmt.Println()
+
+	eventData["forecast_writes"] = f.writes
+	eventData["forecast_reads"] = f.reads
+}
+
+// todaysUsage returns the People API requests recorded in the state store
+// for the current quota day.
+func todaysUsage() (state.Usage, error) {
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return state.Usage{}, err
+	}
+	defer store.Close()
+	return store.DailyUsage(contacts.QuotaDay(time.Now()))
+}
+
+// roundDuration rounds d for display: to seconds below an hour, to minutes
+// above.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Hour {
+		return d.Round(time.Second)
+	}
+	return d.Round(time.Minute)
+}
+
+// ceilDiv returns a / b rounded up.
+func ceilDiv(a, b int) int {
+	if b <= 0 {
+		return a
+	}
+	return (a + b - 1) / b
+}
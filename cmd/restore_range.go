@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// contactRange is a slice of a backup's contacts given with --range, by
+// their positions after sorting for the restore
+type contactRange struct {
+	// start is the position of the first contact, counted from 0
+	start int
+
+	// end is the position after the last contact, or -1 for the end of
+	// the backup
+	end int
+}
+
+// parseContactRange parses START:END, where either side may be left out:
+// "2000:4000" is the 2001st to the 4000th contact, ":2000" the first 2000
+// and "4000:" everything from the 4001st on.
+func parseContactRange(s string) (contactRange, error) {
+	from, to, ok := strings.Cut(s, ":")
+	if !ok {
+		return contactRange{}, fmt.Errorf("invalid range %q: expected START:END, e.g. 2000:4000", s)
+	}
+	r := contactRange{end: -1}
+	if from != "" {
+		n, err := strconv.Atoi(from)
+		if err != nil || n < 0 {
+			return contactRange{}, fmt.Errorf("invalid range %q: start must be a number of contacts", s)
+		}
+		r.start = n
+	}
+	if to != "" {
+		n, err := strconv.Atoi(to)
+		if err != nil || n < 0 {
+			return contactRange{}, fmt.Errorf("invalid range %q: end must be a number of contacts", s)
+		}
+		if n <= r.start {
+			return contactRange{}, fmt.Errorf("invalid range %q: end must be after start", s)
+		}
+		r.end = n
+	}
+	return r, nil
+}
+
+// String returns the range as given on the command line.
+func (r contactRange) String() string {
+	if r.end < 0 {
+		return fmt.Sprintf("%d:", r.start)
+	}
+	return fmt.Sprintf("%d:%d", r.start, r.end)
+}
+
+// apply removes the backup's contacts outside the range, along with their
+// group member entries and photos, and returns the positions of the first
+// and last contact kept, counted from 1 as in restore batches. The backup
+// must be sorted for the restore first. Both are 0 if the range is past the
+// end of the backup.
+func (r contactRange) apply(backup *models.BackupFile) (first, last int) {
+	start, end := r.start, r.end
+	if end < 0 || end > len(backup.Contacts) {
+		end = len(backup.Contacts)
+	}
+	if start >= end {
+		backup.RemoveContacts(func(*people.Person) bool { return true })
+		return 0, 0
+	}
+
+	keep := make(map[*people.Person]bool, end-start)
+	for _, contact := range backup.Contacts[start:end] {
+		keep[contact] = true
+	}
+	backup.RemoveContacts(func(contact *people.Person) bool { return !keep[contact] })
+	return start + 1, end
+}
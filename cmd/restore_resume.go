@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/state"
+)
+
+// restoreCheckpointName is the name of the replace restore checkpoint in the
+// state store
+const restoreCheckpointName = "restore"
+
+// restoreCheckpoint records how far a replace restore to Google got. It is
+// saved to the state store after every step and every batch of created
+// contacts, so that restore --resume can continue an interrupted restore
+// instead of deleting everything again.
+type restoreCheckpoint struct {
+	// File, BackupCreatedAt and Contacts identify the backup being restored
+	File            string    `json:"file"`
+	BackupCreatedAt time.Time `json:"backup_created_at"`
+	Contacts        int       `json:"contacts"`
+
+	// Range is the --range of the restore, if any
+	Range string `json:"range,omitempty"`
+
+	StartedAt time.Time `json:"started_at"`
+
+	// SafetyBackup is the safety backup taken before the restore started
+	SafetyBackup string `json:"safety_backup,omitempty"`
+
+	DeletedContacts bool `json:"deleted_contacts"`
+	DeletedGroups   bool `json:"deleted_groups"`
+
+	// GroupsCreated is set once step 3 completed; Groups then maps backup
+	// group resource names to the groups they were restored as
+	GroupsCreated bool              `json:"groups_created"`
+	Groups        map[string]string `json:"groups,omitempty"`
+
+	// Created maps the resource names of the backup contacts created so far
+	// to the resource names Google assigned
+	Created map[string]string `json:"created,omitempty"`
+}
+
+// runCheckpoint is the checkpoint of the current restore, if it keeps one
+var runCheckpoint *restoreCheckpoint
+
+// newRestoreCheckpoint starts a checkpoint for a replace restore of backup
+// from path.
+func newRestoreCheckpoint(path string, backup *models.BackupFile) *restoreCheckpoint {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return &restoreCheckpoint{
+		File:            path,
+		BackupCreatedAt: backup.CreatedAt,
+		Contacts:        len(backup.Contacts),
+		Range:           restoreRange,
+		StartedAt:       time.Now().UTC(),
+		Created:         make(map[string]string),
+	}
+}
+
+// loadRestoreCheckpoint returns the checkpoint of an interrupted restore, or
+// nil if there is none.
+func loadRestoreCheckpoint() (*restoreCheckpoint, error) {
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	var checkpoint restoreCheckpoint
+	found, err := store.LoadCheckpoint(restoreCheckpointName, &checkpoint)
+	if err != nil || !found {
+		return nil, err
+	}
+	if checkpoint.Created == nil {
+		checkpoint.Created = make(map[string]string)
+	}
+	return &checkpoint, nil
+}
+
+// prepareCheckpoint returns the checkpoint a replace restore of backup keeps:
+// with --resume the saved one, which must be of the same backup, and else a
+// new one, which replaces the saved one once the restore starts.
+func prepareCheckpoint(backup *models.BackupFile) (*restoreCheckpoint, error) {
+	current := newRestoreCheckpoint(inputFile, backup)
+	previous, err := loadRestoreCheckpoint()
+	if err != nil {
+		if restoreResume {
+			return nil, err
+		}
+		warnf("%v; this restore cannot be resumed if it is interrupted", err)
+		return nil, nil
+	}
+
+	switch {
+	case !restoreResume:
+		if previous != nil {
+			fmt.Printf("Note: the interrupted restore of %s can be continued with --resume; this restore starts over.\n", previous.File)
+			fmt.Println()
+		}
+		return current, nil
+	case previous == nil:
+		return nil, withExitCode(exitNothingToDo, fmt.Errorf("there is no interrupted restore to resume"))
+	case !previous.matches(current):
+		return nil, fmt.Errorf("the interrupted restore was of %s with %d contacts: resume it with the same backup and filters",
+			previous.File, previous.Contacts)
+	}
+	printResumeStatus(previous)
+	return previous, nil
+}
+
+// matches reports whether the checkpoint was written by a restore of the
+// same backup, with the same filters applied.
+func (c *restoreCheckpoint) matches(other *restoreCheckpoint) bool {
+	return c.File == other.File && c.BackupCreatedAt.Equal(other.BackupCreatedAt) && c.Contacts == other.Contacts &&
+		c.Range == other.Range
+}
+
+// checkpointStep applies update to the checkpoint of the current restore, if
+// any, and saves it.
+func checkpointStep(update func(c *restoreCheckpoint)) {
+	if runCheckpoint == nil {
+		return
+	}
+	update(runCheckpoint)
+	saveCheckpoint()
+}
+
+// checkpointBatch records the contacts of a successfully created batch.
+func checkpointBatch(batch contacts.Batch) {
+	if batch.Operation != "create" || batch.Err != nil || len(batch.Created) != len(batch.Contacts) {
+		return
+	}
+	checkpointStep(func(c *restoreCheckpoint) {
+		for i, name := range batch.Contacts {
+			c.Created[name] = batch.Created[i]
+		}
+	})
+}
+
+// checkpointOptions returns the contacts client options that feed the
+// checkpoint.
+func checkpointOptions() []contacts.Option {
+	if runCheckpoint == nil {
+		return nil
+	}
+	return []contacts.Option{contacts.WithBatchHandler(checkpointBatch)}
+}
+
+// saveCheckpoint writes the checkpoint to the state store. Errors are
+// warnings: the restore goes on, but may not be resumable.
+func saveCheckpoint() {
+	store, err := state.Open(stateFile)
+	if err == nil {
+		err = store.SaveCheckpoint(restoreCheckpointName, runCheckpoint)
+		store.Close()
+	}
+	if err != nil {
+		warnf("failed to save restore checkpoint: %v", err)
+	}
+}
+
+// clearCheckpoint deletes the checkpoint once the restore completed.
+func clearCheckpoint() {
+	if runCheckpoint == nil {
+		return
+	}
+	runCheckpoint = nil
+	store, err := state.Open(stateFile)
+	if err == nil {
+		err = store.DeleteCheckpoint(restoreCheckpointName)
+		store.Close()
+	}
+	if err != nil {
+		warnf("failed to delete restore checkpoint: %v", err)
+	}
+}
+
+// remainingContacts returns the backup contacts the checkpoint has not
+// recorded as created.
+func (c *restoreCheckpoint) remainingContacts(backup *models.BackupFile) []*people.Person {
+	remaining := make([]*people.Person, 0, len(backup.Contacts))
+	for _, contact := range backup.Contacts {
+		if _, ok := c.Created[contact.ResourceName]; !ok {
+			remaining = append(remaining, contact)
+		}
+	}
+	return remaining
+}
+
+// listUserGroups returns the user contact groups of the live account.
+func listUserGroups(ctx context.Context, client *contacts.Client) ([]*people.ContactGroup, error) {
+	groups, err := client.ListGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	var userGroups []*people.ContactGroup
+	for _, group := range groups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			userGroups = append(userGroups, group)
+		}
+	}
+	return userGroups, nil
+}
+
+// printResumeStatus describes how far the interrupted restore got.
+func printResumeStatus(c *restoreCheckpoint) {
+	fmt.Printf("Resuming the restore of %s started at %s:\n", c.File, c.StartedAt.Local().Format(time.RFC3339))
+	done := func(ok bool) string {
+		if ok {
+			return "done"
+		}
+		return "to do"
+	}
+	fmt.Printf("  Delete contacts: %s\n", done(c.DeletedContacts))
+	fmt.Printf("  Delete groups:   %s\n", done(c.DeletedGroups))
+	fmt.Printf("  Create groups:   %s\n", done(c.GroupsCreated))
+	fmt.Printf("  Create contacts: %d of %d created\n", len(c.Created), c.Contacts)
+	fmt.Println()
+}
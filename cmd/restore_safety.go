@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// safetyBackupPath is the safety backup of the current restore, if it took
+// one
+var safetyBackupPath string
+
+// takeSafetyBackup saves a full backup of the live Google account before a
+// restore changes anything, as pre-restore-<timestamp>.json in the backup
+// directory of the config file, or else in the current directory. A resumed
+// restore keeps the safety backup taken before it was interrupted, as the
+// account has already been changed since.
+func takeSafetyBackup(ctx context.Context, checkpoint *restoreCheckpoint) error {
+	if restoreResume && checkpoint != nil {
+		if checkpoint.SafetyBackup != "" {
+			safetyBackupPath = checkpoint.SafetyBackup
+			eventData["safety_backup"] = safetyBackupPath
+			fmt.Printf("The safety backup taken before the interruption is %s\n", safetyBackupPath)
+			fmt.Println()
+		}
+		return nil
+	}
+
+	fmt.Println("Taking a safety backup of the account...")
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+	live, liveGroups, err := fetchLiveAccount(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to take safety backup: %w", err)
+	}
+	backup, err := accountBackup(ctx, client, live, liveGroups, models.TagPreRestore)
+	if err != nil {
+		return fmt.Errorf("failed to take safety backup: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.json", models.TagPreRestore, time.Now().Format("20060102-150405"))
+	path, err := saveAccountBackup(backup, safetyBackupDirectory(), name)
+	if err != nil {
+		return fmt.Errorf("failed to save safety backup: %w", err)
+	}
+
+	safetyBackupPath = path
+	eventData["safety_backup"] = path
+	journalStep("safety_backup", map[string]any{"file": path, "contacts": backup.ContactCount})
+	if checkpoint != nil {
+		checkpoint.SafetyBackup = path
+	}
+	fmt.Printf("Saved safety backup of %d contacts to %s\n", backup.ContactCount, path)
+	fmt.Println()
+	return nil
+}
+
+// safetyBackupDirectory returns where safety backups are saved: in the
+// backup directory of the config file, or else in the current directory.
+func safetyBackupDirectory() string {
+	if cfg.Backup.Directory != "" {
+		return cfg.Backup.Directory
+	}
+	return "."
+}
+
+// latestSafetyBackup returns the path of the most recent safety backup in
+// dir. Their names start with a timestamp, so the last name is the latest.
+func latestSafetyBackup(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var latest string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, models.TagPreRestore+"-") || !strings.Contains(name, ".json") {
+			continue
+		}
+		if name > latest {
+			latest = name
+		}
+	}
+	if latest == "" {
+		return "", withExitCode(exitNothingToDo, fmt.Errorf("no safety backup (%s-*.json) found in %s", models.TagPreRestore, dir))
+	}
+	return filepath.Join(dir, latest), nil
+}
+
+// prepareUndo checks the flags of restore --undo and sets the input file to
+// the most recent safety backup. Undoing restores the whole account as it
+// was, so the flags that restore part of a backup or restore elsewhere are
+// rejected.
+func prepareUndo(cmd *cobra.Command) error {
+	for _, flag := range []string{"input", "target", "mode", "fields-only", "filter", "modified-since", "group", "match", "replace", "resume"} {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--undo cannot be combined with --%s", flag)
+		}
+	}
+
+	path, err := latestSafetyBackup(safetyBackupDirectory())
+	if err != nil {
+		return err
+	}
+	inputFile = path
+	eventData["undo"] = true
+	fmt.Printf("Undoing the last restore with its safety backup: %s\n", path)
+	fmt.Println()
+	return nil
+}
+
+// printSafetyBackupSummary adds the safety backup to the summary of a
+// restore.
+func printSafetyBackupSummary() {
+	if safetyBackupPath != "" {
+		fmt.Println(i18n.T("restore.summary.safety_backup", safetyBackupPath))
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/filter"
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// selectedLiveContacts returns the live contacts a --replace restore of a
+// selection deletes: those matching the selection, except the contacts on
+// the ignore list.
+func selectedLiveContacts(live []*people.Person, liveGroups []*people.ContactGroup, selection *filter.Filter) []*people.Person {
+	groupNames := groupNamesOf(liveGroups)
+	kept, _ := cfg.Ignore.Split(live, groupNames)
+	var selected []*people.Person
+	for _, contact := range kept {
+		if selection.Match(contact, groupNames) {
+			selected = append(selected, contact)
+		}
+	}
+	return selected
+}
+
+// runSelectionRestore replaces the contacts of the live account that match
+// the selection (--group, --match, --filter) with the selected contacts of
+// the backup. Other contacts and all labels are kept; labels are matched by
+// name and created if missing.
+func runSelectionRestore(ctx context.Context, backup *models.BackupFile, selection *filter.Filter, trickleInterval time.Duration) error {
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx, append(journalOptions(), contacts.WithTrickle(trickleInterval))...)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	// Step 1: Delete the live contacts that match the selection
+	live, liveGroups, err := fetchLiveAccount(ctx, client)
+	if err != nil {
+		return err
+	}
+	toDelete := selectedLiveContacts(live, liveGroups, selection)
+	if len(toDelete) > 0 {
+		fmt.Println("Step 1/3: Deleting the selected contacts...")
+		deleteBar := progressbar.NewOptions(len(toDelete),
+			progressbar.OptionSetDescription("Deleting contacts"),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+		resourceNames := make([]string, 0, len(toDelete))
+		for _, contact := range toDelete {
+			resourceNames = append(resourceNames, contact.ResourceName)
+		}
+		err := client.DeleteContacts(ctx, resourceNames, func(deleted, total int) {
+			deleteBar.Set(deleted)
+		})
+		deleteBar.Finish()
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("failed to delete contacts: %w", err)
+		}
+		journalStep("delete_contacts", map[string]any{"deleted": len(toDelete)})
+		fmt.Printf("Deleted %d contacts, kept %d others\n", len(toDelete), len(live)-len(toDelete))
+	} else {
+		fmt.Println("Step 1/3: No contacts in the account match the selection")
+	}
+	fmt.Println()
+
+	// Step 2: Match labels by name, creating the missing ones
+	fmt.Println("Step 2/3: Matching contact groups...")
+	groupMap, createdGroups, err := matchGroups(ctx, client, backup.GetUserGroups(), liveGroups)
+	if err != nil {
+		return err
+	}
+	journalStep("match_groups", map[string]any{"groups": groupMap, "created": createdGroups})
+	if createdGroups > 0 {
+		fmt.Printf("Created %d groups, reused the others\n", createdGroups)
+	} else {
+		fmt.Printf("All %d contact groups already exist\n", len(groupMap))
+	}
+	fmt.Println()
+
+	// Step 3: Create the selected contacts from the backup
+	fmt.Println("Step 3/3: Creating contacts...")
+	createBar := progressbar.NewOptions(len(backup.Contacts),
+		progressbar.OptionSetDescription("Creating contacts"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	created, err := client.CreateContacts(ctx, backup.Contacts, groupMap, func(created, total int) {
+		createBar.Set(created)
+	})
+	createBar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to create contacts: %w", err)
+	}
+	fmt.Printf("Created %d contacts\n", len(backup.Contacts))
+	journalStep("create_contacts", map[string]any{"created": len(backup.Contacts)})
+
+	restoredPhotos, err := restorePhotos(ctx, client, backup, created)
+	if err != nil {
+		return err
+	}
+
+	eventData["file"] = inputFile
+	eventData["deleted"] = len(toDelete)
+	eventData["contacts"] = len(backup.Contacts)
+	eventData["photos"] = restoredPhotos
+
+	fmt.Println()
+	fmt.Println(i18n.T("restore.completed"))
+	fmt.Println()
+	fmt.Println(i18n.T("restore.summary.contacts", len(backup.Contacts)))
+	if createdGroups > 0 {
+		fmt.Println(i18n.T("restore.summary.groups", createdGroups))
+	}
+	if restoredPhotos > 0 {
+		fmt.Println(i18n.T("restore.summary.photos", restoredPhotos))
+	}
+	printSafetyBackupSummary()
+
+	return nil
+}
+
+// printSelectionDryRun prints the contacts a --replace restore of a
+// selection would delete and create, and the groups it would create.
+func printSelectionDryRun(backup *models.BackupFile, live []*people.Person, liveGroups []*people.ContactGroup, selection *filter.Filter, batchSize int) error {
+	toDelete := selectedLiveContacts(live, liveGroups, selection)
+	_, missingGroups, _ := planGroups(backup.GetUserGroups(), liveGroups)
+	photos := countPhotos(backup, backup.Contacts)
+
+	eventData["would_delete"] = len(toDelete)
+	eventData["would_create_groups"] = len(missingGroups)
+	eventData["would_create"] = len(backup.Contacts)
+	eventData["would_restore_photos"] = photos
+
+	fmt.Printf("Would delete %d contacts (%d others are kept):\n", len(toDelete), len(live)-len(toDelete))
+	for _, contact := range toDelete {
+		fmt.Printf("  %s (%s)\n", models.DisplayName(contact), contact.ResourceName)
+	}
+	fmt.Println()
+	printGroupNames("Would create", missingGroups)
+	printWouldCreate(backup.Contacts, batchSize)
+	if photos > 0 {
+		fmt.Printf("Would restore %d contact photos\n", photos)
+		fmt.Println()
+	}
+
+	fmt.Println("Dry run complete: no changes were made.")
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/fakepeople"
+)
+
+// simulatedAccount is the fake People API a --simulate restore runs against.
+// While it is set, newContactsClient connects to it instead of Google.
+var simulatedAccount *fakepeople.Server
+
+// startSimulation fetches the live account read-only and seeds an in-memory
+// fake of the People API with it, so that the rest of the restore runs
+// against the copy.
+func startSimulation(ctx context.Context) error {
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx, contacts.WithReadOnly())
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	live, liveGroups, err := fetchLiveAccount(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	server := fakepeople.New()
+	server.Seed(live, liveGroups)
+	server.CollectRejections()
+	simulatedAccount = server
+
+	fmt.Println("Simulation: restoring into an in-memory copy of the account.")
+	fmt.Println("Nothing will be deleted, created or updated in the account itself.")
+	fmt.Println()
+	return nil
+}
+
+// newSimulatedClient returns a client of the simulated account. Requests are
+// served in-process, so the client does not wait between them.
+func newSimulatedClient(ctx context.Context, opts ...contacts.Option) (*contacts.Client, error) {
+	return contacts.NewClient(ctx, simulatedAccount.HTTPClient(), append(opts, contacts.WithoutPacing())...)
+}
+
+// finishSimulation reports the contacts the simulated account refused. err
+// is the error the simulated restore returned, if any.
+func finishSimulation(err error) error {
+	if err != nil {
+		return fmt.Errorf("simulated restore failed: %w", err)
+	}
+
+	rejections := simulatedAccount.Rejections()
+	eventData["simulate"] = true
+	eventData["rejected"] = len(rejections)
+
+	fmt.Println()
+	if len(rejections) == 0 {
+		fmt.Println("Simulation passed: the People API would accept every contact.")
+		fmt.Println("Nothing was changed in the account.")
+		return nil
+	}
+
+	fmt.Printf("The People API would reject %d contacts:\n", len(rejections))
+	for _, rejection := range rejections {
+		fmt.Printf("  %s batch %d, contact %d: %s: %s\n",
+			rejection.Operation, rejection.Batch, rejection.Index+1, rejection.Name, rejection.Reason)
+	}
+	fmt.Println()
+	fmt.Println("A real restore would fail at the first batch holding one of them.")
+	fmt.Println("Nothing was changed in the account.")
+
+	return withExitCode(exitVerificationMismatch,
+		fmt.Errorf("%d contacts would be rejected by the People API", len(rejections)))
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/encryption"
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/reconcile"
+)
+
+// takePostRestoreSnapshot checks that the live account matches the restored
+// backup and, if it does, saves a fresh backup of the account tagged
+// "post-restore" next to the journal, as the new known-good baseline. It
+// returns the path of the snapshot, or "" if the account did not match.
+// restored is the backup as it was restored, after filters and the ignore
+// list.
+func takePostRestoreSnapshot(ctx context.Context, client *contacts.Client, restored *models.BackupFile, journalPath string) (string, error) {
+	fmt.Println("Verifying the restore...")
+	live, liveGroups, err := fetchLiveAccount(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	comparison := reconcile.Compare(restored, live, liveGroups)
+	if len(comparison.Missing) > 0 || len(comparison.Mismatches) > 0 {
+		warnf("%d restored contacts differ from the backup and %d are missing; no post-restore snapshot was taken "+
+			"(run 'verify -i <backup> --against-live' for details)", len(comparison.Mismatches), len(comparison.Missing))
+		return "", nil
+	}
+	fmt.Printf("All %d restored contacts match the backup\n", comparison.Matched)
+
+	snapshot, err := accountBackup(ctx, client, live, liveGroups, models.TagPostRestore)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("contacts-%s-%s.json", time.Now().Format("20060102-150405"), models.TagPostRestore)
+	path, err := saveAccountBackup(snapshot, snapshotDirectory(journalPath), name)
+	if err != nil {
+		return "", fmt.Errorf("failed to save post-restore snapshot: %w", err)
+	}
+
+	journalStep("snapshot", map[string]any{"file": path, "contacts": snapshot.ContactCount})
+	fmt.Printf("Saved post-restore snapshot of %d contacts to %s\n", snapshot.ContactCount, path)
+	return path, nil
+}
+
+// accountBackup builds a backup of the live account from its contacts and
+// groups, tagged with tag. Contacts on the ignore list are left out, as
+// backup leaves them out.
+func accountBackup(ctx context.Context, client *contacts.Client, live []*people.Person, liveGroups []*people.ContactGroup, tag string) (*models.BackupFile, error) {
+	backup := models.NewBackupFile()
+	backup.Tag = tag
+	for _, group := range liveGroups {
+		backup.AddGroup(group)
+	}
+	for _, contact := range live {
+		backup.AddContact(contact)
+	}
+	if removed := cfg.Ignore.Apply(backup); len(removed) > 0 {
+		backup.AddTransform(models.TransformIgnoreList, "", len(removed))
+	}
+	var err error
+	if backup.GroupMembers, err = client.GetGroupMembers(ctx, liveGroups, nil); err != nil {
+		return nil, fmt.Errorf("failed to fetch group members: %w", err)
+	}
+	return backup, nil
+}
+
+// saveAccountBackup saves backup as name in dir, compressed and encrypted as
+// the config file sets for backups, and returns its path. The compression
+// and .age extensions are appended to name.
+func saveAccountBackup(backup *models.BackupFile, dir, name string) (string, error) {
+	compression, err := models.ParseCompression(cfg.Backup.Compress)
+	if err != nil {
+		return "", err
+	}
+	recipients, err := encryption.ParseRecipients(cfg.Encryption.Recipients)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name) + models.CompressionExtension(compression)
+	if len(recipients) > 0 {
+		path += ".age"
+	}
+
+	write := func(w io.Writer) error {
+		return models.WriteCompressed(w, compression, backup.WriteJSON)
+	}
+	if len(recipients) > 0 {
+		err = saveEncrypted(path, recipients, write)
+	} else {
+		err = saveFile(path, write)
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// snapshotDirectory returns where post-restore snapshots are saved: next to
+// the journal, or in the backup directory of the config file, or else in the
+// current directory.
+func snapshotDirectory(journalPath string) string {
+	switch {
+	case journalPath != "":
+		return filepath.Dir(expandHome(journalPath))
+	case cfg.Backup.Directory != "":
+		return cfg.Backup.Directory
+	default:
+		return "."
+	}
+}
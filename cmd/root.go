@@ -2,11 +2,22 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/auth"
+	"github.com/mheap/google-contacts-backup/internal/config"
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+	"github.com/mheap/google-contacts-backup/internal/reconcile"
+	"github.com/mheap/google-contacts-backup/internal/state"
 )
 
 var (
@@ -15,21 +26,190 @@ var (
 
 	// credentialsFile is the path to the OAuth credentials file
 	credentialsFile string
+
+	// maxRequestsPerMinute caps People API requests across all phases of a command
+	maxRequestsPerMinute int
+
+	// rateLimit is the People API request rate in requests per second
+	rateLimit float64
+
+	// apiLimiter paces the requests of every contacts client the command
+	// creates, so they share one budget; created with the first client
+	apiLimiter *contacts.AdaptiveLimiter
+
+	// configFile is the path to the settings file
+	configFile string
+
+	// cfg holds the settings loaded from configFile before any command runs
+	cfg = &config.Config{}
+
+	// stateFile is the path to the state database
+	stateFile string
+
+	// strictMode turns warnings into a partial-failure exit code
+	strictMode bool
+
+	// identityFiles are age identity files used to decrypt encrypted backups
+	identityFiles []string
+
+	// language selects the language of prompts and summaries
+	language string
+
+	// approvalFile and approvalCode approve destructive operations when the
+	// config file requires a second approval
+	approvalFile string
+	approvalCode string
+
+	// readOnly makes the contacts client refuse every change to the account
+	readOnly bool
+
+	// apiUsage counts the People API requests of every client the command
+	// creates, recorded in the state store when it exits
+	apiUsage contacts.Usage
 )
 
 // getDefaultCredentialsPath returns the default path for credentials.json
 // using XDG_CONFIG_HOME if set, otherwise ~/.config
 func getDefaultCredentialsPath() string {
-	configDir := os.Getenv("XDG_CONFIG_HOME")
-	if configDir == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			// Fallback to current directory if we can't get home
-			return "credentials.json"
-		}
-		configDir = filepath.Join(homeDir, ".config")
-	}
-	return filepath.Join(configDir, "google-contacts-backup", "credentials.json")
+	return filepath.Join(config.Dir(), "credentials.json")
+}
+
+// prepareRun cuts off the network for local commands, loads the settings
+// file and starts the health endpoints.
+func prepareRun(cmd *cobra.Command, args []string) error {
+	enterOffline(cmd)
+	if err := loadConfig(cmd, args); err != nil {
+		return err
+	}
+
+	// The daemon reports on the backups it runs
+	command := cmd.Name()
+	if cmd == daemonRunCmd {
+		command = "backup"
+	}
+	return startHealthServer(command)
+}
+
+// loadConfig loads and validates the settings file.
+func loadConfig(cmd *cobra.Command, args []string) error {
+	loaded, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+	if err := reconcile.ValidateFields(loaded.FrozenFields); err != nil {
+		return fmt.Errorf("invalid frozen_fields in %s: %w", configFile, err)
+	}
+	cfg = loaded
+
+	// --lang wins over the config file, which wins over the environment
+	lang := language
+	if lang == "" {
+		lang = cfg.Language
+	}
+	if lang == "" {
+		lang = i18n.Detect()
+	}
+	if err := i18n.SetLanguage(lang); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkCredentials returns an error if the credentials file does not exist.
+func checkCredentials() error {
+	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
+		return withExitCode(exitAuthFailure, fmt.Errorf("credentials file not found: %s\n\nRun 'google-contacts-backup auth' first, or see 'google-contacts-backup --help' for setup instructions", credentialsFile))
+	}
+	return nil
+}
+
+// newContactsClient authenticates with Google and returns a People API client
+// configured from the global flags plus any extra options. It prints nothing so that commands with
+// machine-readable output can use it. During restore --simulate it connects to
+// the simulated account instead.
+func newContactsClient(ctx context.Context, opts ...contacts.Option) (*contacts.Client, error) {
+	if err := checkOnline(); err != nil {
+		return nil, err
+	}
+	// Problems the client skips over are warnings of the command
+	opts = append([]contacts.Option{contacts.WithWarningHandler(func(msg string) {
+		warnf("%s", msg)
+	})}, opts...)
+	if simulatedAccount != nil {
+		return newSimulatedClient(ctx, opts...)
+	}
+
+	if rateLimit < 0 {
+		return nil, fmt.Errorf("invalid --rate-limit %g: must be a positive number of requests per second", rateLimit)
+	}
+	if rateLimit > 0 && maxRequestsPerMinute > 0 {
+		return nil, fmt.Errorf("--rate-limit and --max-requests-per-minute cannot be combined")
+	}
+
+	if err := checkCredentials(); err != nil {
+		return nil, err
+	}
+
+	authenticator := auth.NewAuthenticator(credentialsFile)
+	httpClient, err := authenticator.GetClient(ctx)
+	if err != nil {
+		return nil, withExitCode(exitAuthFailure, fmt.Errorf("authentication failed: %w", err))
+	}
+
+	if apiLimiter == nil {
+		apiLimiter = contacts.NewAdaptiveLimiter(contacts.RequestRate(rateLimit, maxRequestsPerMinute), 1)
+	}
+
+	opts = append([]contacts.Option{
+		contacts.WithRateLimiter(apiLimiter),
+		contacts.WithRetryHandler(printRetry),
+		contacts.WithUsage(&apiUsage),
+	}, opts...)
+	if readOnlyMode() {
+		opts = append(opts, contacts.WithReadOnly())
+	}
+	client, err := contacts.NewClient(ctx, httpClient, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contacts client: %w", err)
+	}
+
+	return client, nil
+}
+
+// readOnlyMode reports whether changes to the account are disabled, by
+// --read-only or by read_only in the config file.
+func readOnlyMode() bool {
+	return readOnly || cfg.ReadOnly
+}
+
+// checkWritable fails in read-only mode, so that commands that change the
+// account stop before asking for confirmation. The contacts client refuses
+// every change in read-only mode regardless.
+func checkWritable() error {
+	if !readOnlyMode() {
+		return nil
+	}
+	source := "--read-only"
+	if !readOnly {
+		source = "read_only in " + configFile
+	}
+	return fmt.Errorf("%w (disabled by %s)", contacts.ErrReadOnly, source)
+}
+
+// printRetry tells the user that a request is paused by a rate limit.
+func printRetry(attempt int, delay time.Duration, err error) {
+	fmt.Fprintf(os.Stderr, "\nRate limited by the People API; retrying in %s (attempt %d)\n", delay, attempt+1)
+}
+
+// printQuotaHint explains when a daily quota resets, if err is a daily quota error.
+func printQuotaHint(err error) {
+	var qe *contacts.QuotaError
+	if !errors.As(err, &qe) || !qe.Daily {
+		return
+	}
+	wait := time.Until(qe.ResetAt).Round(time.Minute)
+	fmt.Fprintf(os.Stderr, "\nThe daily People API quota resets at %s (in about %s).\n", qe.ResetAt.Local().Format(time.Kitchen+" MST"), wait)
+	fmt.Fprintln(os.Stderr, "Retrying before then will fail; run the command again after the reset.")
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -65,15 +245,48 @@ Examples:
   google-contacts-backup restore -i my-contacts.json
 
 Note: The restore command will DELETE ALL existing contacts before restoring.
-Always create a fresh backup before restoring!`,
-	Version: Version,
+Always create a fresh backup before restoring!
+
+Exit codes:
+  0  success
+  1  general failure
+  2  authentication failure
+  3  People API quota or rate limit exceeded
+  4  partial failure (some items failed, or warnings with --strict)
+  5  verification mismatch
+  6  nothing to do
+  7  approval missing or rejected`,
+	Version:            Version,
+	PersistentPreRunE:  prepareRun,
+	PersistentPostRunE: failOnWarnings,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	recordUsage()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		printQuotaHint(err)
+		os.Exit(exitCodeFor(err))
+	}
+}
+
+// recordUsage adds the People API requests the command sent to today's
+// usage in the state store, which restore forecasts quota use from.
+func recordUsage() {
+	reads, writes := apiUsage.Reads(), apiUsage.Writes()
+	if reads == 0 && writes == 0 {
+		return
+	}
+	store, err := state.Open(stateFile)
+	if err != nil {
+		warnf("failed to record API usage: %v", err)
+		return
+	}
+	defer store.Close()
+	if err := store.AddUsage(contacts.QuotaDay(time.Now()), reads, writes); err != nil {
+		warnf("%v", err)
 	}
 }
 
@@ -82,4 +295,26 @@ func init() {
 	defaultCreds := getDefaultCredentialsPath()
 	rootCmd.PersistentFlags().StringVarP(&credentialsFile, "credentials", "c", defaultCreds,
 		"Path to the OAuth credentials JSON file from Google Cloud Console")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", config.DefaultPath(),
+		"Path to the settings file")
+	rootCmd.PersistentFlags().StringVar(&stateFile, "state-file", state.DefaultPath(),
+		"Path to the state database (sync tokens, checkpoints, run history)")
+	rootCmd.PersistentFlags().BoolVar(&strictMode, "strict", false,
+		"Treat warnings (skipped groups, count mismatches) as failures")
+	rootCmd.PersistentFlags().IntVar(&maxRequestsPerMinute, "max-requests-per-minute", 0,
+		"Maximum People API requests per minute, shared across all phases (0 = default pacing)")
+	rootCmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", 0,
+		"People API requests per second, lowered automatically on rate limit errors (0 = default of 10)")
+	rootCmd.PersistentFlags().StringSliceVar(&identityFiles, "identity", nil,
+		"Age identity file for decrypting encrypted backups, including plugin identities (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&language, "lang", "",
+		"Language of prompts and summaries: "+strings.Join(i18n.Languages(), ", ")+" (default: from LC_ALL, LC_MESSAGES or LANG)")
+	rootCmd.PersistentFlags().StringVar(&approvalFile, "approval-file", "",
+		"Signed approval file for a destructive operation, if the config file requires approval")
+	rootCmd.PersistentFlags().StringVar(&approvalCode, "approval-code", "",
+		"TOTP code from the approver for a destructive operation, if the config file requires approval")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false,
+		"Refuse every change to contacts and groups; only reading commands work")
+	rootCmd.PersistentFlags().StringVar(&serveHealth, "serve-health", "",
+		"Serve /healthz and /last-run JSON and /metrics on this address (e.g. :8080) while the command runs")
 }
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/search"
+)
+
+var (
+	searchInput string
+	searchJSON  bool
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search QUERY",
+	Short: "Find contacts by name, email, phone or notes",
+	Long: `Find the contacts whose names, nicknames, email addresses, phone numbers or
+notes contain QUERY, for example to check whether a contact made it into a
+backup.
+
+Case, accents and punctuation are ignored, so "jose" finds "José" and
+"smith" finds "jane.smith@example.com". A query that looks like a phone
+number is also compared with the digits of phone numbers, so "555 0123"
+finds "+1 (555) 012-3456".
+
+With --input, the contacts of the backup are searched. Without it, the live
+account is searched with Google's contact search, which matches the start
+of words in names, email addresses, phone numbers and organizations, not
+notes, and returns at most 30 contacts; the fields QUERY was found in are
+then worked out as for a backup.
+
+Exits with code 6 if no contacts match.
+
+Examples:
+  # Is Jane Smith in the backup?
+  google-contacts-backup search -i my-contacts.json "jane smith"
+
+  # Search the live account
+  google-contacts-backup search smith
+
+  # Search phone numbers
+  google-contacts-backup search -i my-contacts.json "555 0123"
+
+  # Machine-readable matches for scripts
+  google-contacts-backup search -i my-contacts.json smith --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: withEvents("search", runSearch),
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	markLocal(searchCmd, func() bool { return searchInput != "" })
+
+	searchCmd.Flags().StringVarP(&searchInput, "input", "i", "",
+		"Backup file to search instead of the live account")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false,
+		"Print the matches as JSON")
+}
+
+// searchResult is the machine-readable form of a search.Match
+type searchResult struct {
+	ResourceName string   `json:"resource_name"`
+	Name         string   `json:"name"`
+	Emails       []string `json:"emails"`
+	Phones       []string `json:"phones"`
+	Matched      []string `json:"matched"`
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	query := search.Parse(args[0])
+	if query.Empty() {
+		return fmt.Errorf("invalid query %q: must contain letters or digits", args[0])
+	}
+
+	var contacts []*people.Person
+	if searchInput != "" {
+		backup, err := loadBackup(searchInput)
+		if err != nil {
+			return fmt.Errorf("failed to load backup: %w", err)
+		}
+		contacts = backup.Contacts
+	} else {
+		ctx := context.Background()
+		client, err := newContactsClient(ctx)
+		if err != nil {
+			return err
+		}
+		if contacts, err = client.SearchContacts(ctx, args[0]); err != nil {
+			return err
+		}
+	}
+
+	var matches []search.Match
+	if searchInput != "" {
+		matches = search.Contacts(contacts, query)
+	} else {
+		// Google's search also matches organizations, which are not searched
+		// here; list its results even if no field is found for them
+		for _, contact := range contacts {
+			matches = append(matches, search.Match{Contact: contact, Fields: query.Fields(contact)})
+		}
+	}
+	eventData["source"] = defaultString(searchInput, "live")
+	eventData["contacts"] = len(contacts)
+	eventData["matches"] = len(matches)
+
+	if searchJSON {
+		results := make([]searchResult, 0, len(matches))
+		for _, match := range matches {
+			results = append(results, searchResult{
+				ResourceName: match.Contact.ResourceName,
+				Name:         models.DisplayName(match.Contact),
+				Emails:       emailValues(match.Contact),
+				Phones:       phoneValues(match.Contact),
+				Matched:      match.Fields,
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printSearchMatches(matches, args[0])
+	}
+
+	if len(matches) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no contacts match %q", args[0]))
+	}
+	return nil
+}
+
+// printSearchMatches lists the matches as a table.
+func printSearchMatches(matches []search.Match, query string) {
+	if len(matches) == 0 {
+		fmt.Printf("No contacts match %q.\n", query)
+		return
+	}
+
+	rows := [][]string{{"Name", "Email", "Phone", "Matched", "Resource"}}
+	for _, match := range matches {
+		matched := strings.Join(match.Fields, ", ")
+		if matched == "" {
+			matched = "-"
+		}
+		rows = append(rows, []string{
+			models.DisplayName(match.Contact),
+			strings.Join(emailValues(match.Contact), ", "),
+			strings.Join(phoneValues(match.Contact), ", "),
+			matched,
+			match.Contact.ResourceName,
+		})
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+
+	fmt.Printf("Found %d contacts matching %q:\n\n", len(matches), query)
+	for _, row := range rows {
+		line := "  "
+		for i, cell := range row {
+			if i == len(row)-1 {
+				line += cell
+				break
+			}
+			line += cell + strings.Repeat(" ", widths[i]-len([]rune(cell))+2)
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// emailValues returns the email addresses of a contact.
+func emailValues(contact *people.Person) []string {
+	var values []string
+	for _, email := range contact.EmailAddresses {
+		values = append(values, email.Value)
+	}
+	return values
+}
+
+// phoneValues returns the phone numbers of a contact.
+func phoneValues(contact *people.Person) []string {
+	var values []string
+	for _, phone := range contact.PhoneNumbers {
+		values = append(values, phone.Value)
+	}
+	return values
+}
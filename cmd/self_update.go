@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/update"
+)
+
+var (
+	// releaseKey is the base64 Ed25519 public key the checksums of releases
+	// are signed with, set at build time
+	releaseKey = ""
+
+	selfUpdateCheck bool
+)
+
+// selfUpdateCmd represents the self-update command
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update to the latest release",
+	Long: `Check GitHub for the latest release and, if it is newer than this one,
+download it for this platform and replace the running binary with it.
+
+The release's checksums.txt must carry a valid signature from the release
+signing key built into this binary, and the downloaded archive must match
+its checksum; otherwise nothing is replaced. The new binary is written next
+to the old one and renamed over it, so an interrupted update leaves the old
+binary in place.
+
+With --check, only report whether a newer release exists. The command exits
+with code 6 if this is already the latest release, so scripts can run
+'self-update --check' to decide whether to update.
+
+Installations managed by Homebrew are left to 'brew upgrade'. Set
+GITHUB_TOKEN if GitHub rate limits requests from a shared address.
+
+Examples:
+  # Is there a newer release?
+  google-contacts-backup self-update --check
+
+  # Update
+  google-contacts-backup self-update`,
+	Args: cobra.NoArgs,
+	RunE: runSelfUpdate,
+}
+
+func init() {
+	rootCmd.AddCommand(selfUpdateCmd)
+
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false,
+		"Only check whether a newer release exists")
+}
+
+func runSelfUpdate(cmd *cobra.Command, args []string) error {
+	if Version == "dev" {
+		return fmt.Errorf("this is a development build; self-update only updates release builds, so install a release from https://github.com/%s/releases", update.DefaultRepository)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the running executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if strings.Contains(filepath.ToSlash(exe), "/Cellar/") {
+		return fmt.Errorf("%s is managed by Homebrew; update it with 'brew upgrade google-contacts-backup'", exe)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	client := &update.Client{
+		APIURL: os.Getenv("GITHUB_API_URL"),
+		Token:  os.Getenv("GITHUB_TOKEN"),
+	}
+	release, err := client.Latest(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current version: %s\n", Version)
+	fmt.Printf("Latest release:  %s (%s)\n", release.Version(), release.PublishedAt.Local().Format(time.DateOnly))
+	if !update.Newer(release.Version(), Version) {
+		return withExitCode(exitNothingToDo, fmt.Errorf("%s is the latest release", Version))
+	}
+	if selfUpdateCheck {
+		fmt.Printf("\n%s is available: %s\n", release.Version(), release.URL)
+		fmt.Println("Run 'google-contacts-backup self-update' to install it.")
+		return nil
+	}
+
+	binary, err := downloadRelease(ctx, client, release)
+	if err != nil {
+		return err
+	}
+	if err := update.Replace(exe, binary); err != nil {
+		return err
+	}
+	fmt.Printf("\nUpdated %s from %s to %s.\n", exe, Version, release.Version())
+	return nil
+}
+
+// downloadRelease downloads the release archive for this platform, checks it
+// against the release's signed checksums and returns the binary in it.
+func downloadRelease(ctx context.Context, client *update.Client, release *update.Release) ([]byte, error) {
+	if releaseKey == "" {
+		return nil, fmt.Errorf("this build has no release signing key to verify the download with; download %s manually", release.URL)
+	}
+	key, err := update.ParsePublicKey(releaseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	name := update.ArchiveName(release.Version(), runtime.GOOS, runtime.GOARCH)
+	archive, ok := release.Asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no build for %s/%s (%s)", release.Version(), runtime.GOOS, runtime.GOARCH, name)
+	}
+	checksumsAsset, ok := release.Asset(update.ChecksumsFile)
+	if !ok {
+		return nil, fmt.Errorf("release %s: %w", release.Version(), update.ErrUnsigned)
+	}
+	signatureAsset, ok := release.Asset(update.SignatureFile)
+	if !ok {
+		return nil, fmt.Errorf("release %s: %w", release.Version(), update.ErrUnsigned)
+	}
+
+	checksums, err := client.Download(ctx, checksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := client.Download(ctx, signatureAsset)
+	if err != nil {
+		return nil, err
+	}
+	want, err := update.VerifyChecksums(checksums, signature, key, name)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("\nVerified the signature of %s\n", update.ChecksumsFile)
+
+	fmt.Printf("Downloading %s (%.1f MB)\n", name, float64(archive.Size)/(1<<20))
+	data, err := client.Download(ctx, archive)
+	if err != nil {
+		return nil, err
+	}
+	if err := update.CheckDigest(data, name, want); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Verified the checksum of %s\n", name)
+
+	return update.ExtractBinary(data, name, update.BinaryName())
+}
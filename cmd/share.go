@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/storage"
+)
+
+// maxShareExpiry is the longest validity S3 and GCS allow for presigned URLs
+const maxShareExpiry = 7 * 24 * time.Hour
+
+var (
+	shareInput     string
+	shareLabels    []string
+	shareResources []string
+	shareFormat    string
+	shareTo        string
+	shareExpires   time.Duration
+	shareFilter    string
+)
+
+// shareCmd represents the share command
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Share selected contacts through an expiring download link",
+	Long: `Export selected contacts from a backup and upload them to cloud storage,
+printing a pre-signed download link that expires after a set time.
+
+Contacts are selected by label (--label) and/or resource name (--resource),
+and can be narrowed further with a --filter expression, or selected by the
+filter alone (see 'google-contacts-backup help filters'). They are exported as a vCard file (vcf), which phones and mail clients can
+import directly, or as a printable HTML page (html). The file is uploaded
+under a random name to the destination, which is an s3://bucket/prefix or
+gs://bucket/prefix URL given with --to or the "share.destination" config key.
+
+Credentials for the destination come from the environment: the usual AWS_*
+variables or shared config for S3, and Application Default Credentials for
+GCS (signing GCS links requires a service account). Links can be valid for
+at most 7 days.
+
+Examples:
+  # Share everyone labelled "Family" as a vCard file for 24 hours
+  google-contacts-backup share -i backup.json --label Family --to s3://my-bucket/shared
+
+  # Share two contacts as an HTML page for 2 hours
+  google-contacts-backup share -i backup.json --resource people/c123 --resource people/c456 \
+    --format html --expires 2h --to gs://my-bucket
+
+  # Share everyone at example.com who has a phone number
+  google-contacts-backup share -i backup.json --filter 'domain=example.com && has(phone)' \
+    --to s3://my-bucket/shared`,
+	RunE: runShare,
+}
+
+func init() {
+	rootCmd.AddCommand(shareCmd)
+
+	shareCmd.Flags().StringVarP(&shareInput, "input", "i", "",
+		"Backup file to share contacts from (required)")
+	shareCmd.MarkFlagRequired("input")
+
+	shareCmd.Flags().StringSliceVar(&shareLabels, "label", nil,
+		"Share contacts with this label (repeatable)")
+	shareCmd.Flags().StringSliceVar(&shareResources, "resource", nil,
+		"Share the contact with this resource name, e.g. people/c123 (repeatable)")
+	shareCmd.Flags().StringVar(&shareFilter, "filter", "",
+		"Share contacts matching this filter expression (see 'help filters')")
+	shareCmd.Flags().StringVarP(&shareFormat, "format", "f", "vcf",
+		"Export format: vcf or html")
+	shareCmd.Flags().StringVar(&shareTo, "to", "",
+		"Destination URL, s3://bucket/prefix or gs://bucket/prefix (default: share.destination from config)")
+	shareCmd.Flags().DurationVar(&shareExpires, "expires", 24*time.Hour,
+		"How long the link stays valid, at most 168h (overrides share.expires from config)")
+}
+
+func runShare(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if len(shareLabels) == 0 && len(shareResources) == 0 && shareFilter == "" {
+		return fmt.Errorf("no contacts selected: use --label, --resource or --filter")
+	}
+	selection, err := parseFilter(shareFilter)
+	if err != nil {
+		return err
+	}
+
+	format := strings.ToLower(shareFormat)
+	var contentType, extension string
+	switch format {
+	case "vcf":
+		contentType, extension = "text/vcard; charset=utf-8", ".vcf"
+	case "html":
+		contentType, extension = "text/html; charset=utf-8", ".html"
+	default:
+		return fmt.Errorf("invalid format %q: must be 'vcf' or 'html'", shareFormat)
+	}
+
+	destination := shareTo
+	if destination == "" {
+		destination = cfg.Share.Destination
+	}
+	if destination == "" {
+		return fmt.Errorf("no destination: use --to or set share.destination in the config file")
+	}
+	loc, err := storage.ParseLocation(destination)
+	if err != nil {
+		return err
+	}
+
+	expires := shareExpires
+	if !cmd.Flags().Changed("expires") && cfg.Share.Expires != "" {
+		expires, err = time.ParseDuration(cfg.Share.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid share.expires in config file: %w", err)
+		}
+	}
+	if expires <= 0 || expires > maxShareExpiry {
+		return fmt.Errorf("invalid expiry %s: must be between 1s and %s", expires, maxShareExpiry)
+	}
+
+	fmt.Printf("Loading backup file: %s\n", shareInput)
+	backup, err := loadBackup(shareInput)
+	if err != nil {
+		return fmt.Errorf("failed to load backup: %w", err)
+	}
+	applyIgnoreList(backup)
+
+	selected := backup
+	if len(shareLabels) > 0 || len(shareResources) > 0 {
+		selected = backup.Select(shareLabels, shareResources)
+	}
+	if selection != nil {
+		selection.Apply(selected)
+	}
+	if len(selected.Contacts) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no contacts match the selection"))
+	}
+	fmt.Printf("Selected %d contacts\n", len(selected.Contacts))
+
+	var buf bytes.Buffer
+	switch format {
+	case "vcf":
+		err = selected.WriteVCard(&buf, models.VCardOptions{PlainTextNotes: true})
+	case "html":
+		err = selected.WriteHTML(&buf, models.HTMLOptions{Title: strings.Join(shareLabels, ", ")})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export contacts: %w", err)
+	}
+
+	// A random name keeps shared files from being guessed or overwritten
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("failed to generate file name: %w", err)
+	}
+	key := loc.Key("contacts-" + hex.EncodeToString(suffix) + extension)
+
+	backend, err := storage.Open(ctx, loc)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	fmt.Printf("Uploading to %s://%s/%s...\n", loc.Scheme, loc.Bucket, key)
+	if err := backend.Upload(ctx, key, &buf, contentType); err != nil {
+		return err
+	}
+
+	link, err := backend.PresignGet(ctx, key, expires)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("Share link created!")
+	fmt.Println()
+	fmt.Printf("  Contacts: %d\n", len(selected.Contacts))
+	fmt.Printf("  Format:   %s\n", format)
+	fmt.Printf("  Expires:  %s\n", time.Now().Add(expires).Format(time.RFC3339))
+	fmt.Println()
+	fmt.Println(link)
+
+	return nil
+}
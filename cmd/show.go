@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	showInput    string
+	showEmail    string
+	showResource string
+	showJSON     bool
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show every detail of one contact",
+	Long: `Show one contact from a backup or the live account, found by email address
+or resource name, with every field it holds: names, email addresses, phone
+numbers, addresses, dates, notes, custom fields, the labels (groups) it
+belongs to and anything else, each value with its type.
+
+--email matches an email address of the contact, ignoring case; if several
+contacts share it, each is shown. --resource gives the contact's resource
+name, as listed by 'search', 'dedupe' or 'verify'. --json prints the contact
+as stored instead, with every field and its metadata.
+
+Without --input, the contact is read from the live account; an email
+address is looked up with Google's contact search. Nothing is changed.
+
+Exits with code 6 if no contact is found.
+
+Examples:
+  # Show a contact in a backup
+  google-contacts-backup show -i my-contacts.json --email john@example.com
+
+  # Show a contact in the live account
+  google-contacts-backup show --resource people/c1234567890
+
+  # The contact exactly as stored, for scripts
+  google-contacts-backup show -i my-contacts.json --email john@example.com --json`,
+	Args: cobra.NoArgs,
+	RunE: withEvents("show", runShow),
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+	markLocal(showCmd, func() bool { return showInput != "" })
+
+	showCmd.Flags().StringVarP(&showInput, "input", "i", "",
+		"Backup file to read the contact from instead of the live account")
+	showCmd.Flags().StringVar(&showEmail, "email", "",
+		"Show the contacts with this email address")
+	showCmd.Flags().StringVar(&showResource, "resource", "",
+		"Show the contact with this resource name, e.g. people/c123")
+	showCmd.Flags().BoolVar(&showJSON, "json", false,
+		"Print the contacts as stored, as JSON")
+}
+
+// showFieldOrder is the order fields are shown in; the others follow in
+// alphabetical order
+var showFieldOrder = []string{
+	"names", "nicknames", "emailAddresses", "phoneNumbers", "organizations", "occupations",
+	"addresses", "birthdays", "events", "relations", "urls", "imClients", "biographies",
+	"userDefined", "memberships",
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	if (showEmail == "") == (showResource == "") {
+		return fmt.Errorf("give either --email or --resource")
+	}
+
+	var contacts []*people.Person
+	var groupNames map[string]string
+	var backup *models.BackupFile
+	if showInput != "" {
+		var err error
+		if backup, err = loadBackup(showInput); err != nil {
+			return fmt.Errorf("failed to load backup: %w", err)
+		}
+		backup.ApplyGroupMembers()
+		contacts = backup.Contacts
+		groupNames = showGroupNames(backup.Groups)
+	} else {
+		var err error
+		if contacts, groupNames, err = fetchShowContacts(); err != nil {
+			return err
+		}
+	}
+
+	var found []*people.Person
+	for _, contact := range contacts {
+		if showResource != "" && contact.ResourceName == showResource ||
+			showEmail != "" && hasEmail(contact, showEmail) {
+			found = append(found, contact)
+		}
+	}
+	eventData["source"] = defaultString(showInput, "live")
+	eventData["found"] = len(found)
+
+	if len(found) == 0 {
+		if showEmail != "" {
+			return withExitCode(exitNothingToDo, fmt.Errorf("no contact has the email address %s", showEmail))
+		}
+		return withExitCode(exitNothingToDo, fmt.Errorf("no contact has the resource name %s", showResource))
+	}
+
+	if showJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if showResource != "" {
+			return encoder.Encode(found[0])
+		}
+		return encoder.Encode(found)
+	}
+
+	if len(found) > 1 {
+		fmt.Printf("%d contacts have the email address %s.\n\n", len(found), showEmail)
+	}
+	for i, contact := range found {
+		if i > 0 {
+			fmt.Println()
+		}
+		printContact(contact, groupNames, backup)
+	}
+	return nil
+}
+
+// fetchShowContacts returns the live contacts that may match --email or
+// --resource, and the names of the account's groups.
+func fetchShowContacts() ([]*people.Person, map[string]string, error) {
+	ctx := context.Background()
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var contacts []*people.Person
+	if showResource != "" {
+		if contacts, _, err = client.GetContacts(ctx, []string{showResource}, nil); err != nil {
+			return nil, nil, err
+		}
+	} else if contacts, err = client.SearchContacts(ctx, showEmail); err != nil {
+		return nil, nil, err
+	}
+
+	groups, err := client.ListGroups(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return contacts, showGroupNames(groups), nil
+}
+
+// showGroupNames maps group resource names to the names shown for them,
+// including system groups such as "My Contacts".
+func showGroupNames(groups []*people.ContactGroup) map[string]string {
+	names := make(map[string]string, len(groups))
+	for _, group := range groups {
+		names[group.ResourceName] = defaultString(group.FormattedName, group.Name)
+	}
+	return names
+}
+
+// hasEmail reports whether the contact has the email address, ignoring case
+// and surrounding spaces.
+func hasEmail(contact *people.Person, address string) bool {
+	address = strings.TrimSpace(address)
+	for _, email := range contact.EmailAddresses {
+		if strings.EqualFold(strings.TrimSpace(email.Value), address) {
+			return true
+		}
+	}
+	return false
+}
+
+// printContact prints every field of a contact, one value per line, with the
+// photos a backup holds for it.
+func printContact(contact *people.Person, groupNames map[string]string, backup *models.BackupFile) {
+	fmt.Println(models.DisplayName(contact))
+	printContactLine("Resource", []string{contact.ResourceName})
+	if updated := models.UpdateTime(contact); !updated.IsZero() {
+		printContactLine("Updated", []string{updated.Local().Format(time.DateTime)})
+	}
+	if linked := models.LinkedProfiles(contact); len(linked) > 0 {
+		printContactLine("Linked to", linked)
+	}
+	fmt.Println()
+
+	present := models.PresentFields(contact)
+	if len(contact.Memberships) > 0 {
+		present = append(present, "memberships")
+	}
+	fields := slices.DeleteFunc(slices.Clone(showFieldOrder), func(field string) bool {
+		return !slices.Contains(present, field)
+	})
+	for _, field := range present {
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	for _, field := range fields {
+		printContactLine(models.FieldLabel(field), models.FieldValues(contact, field, groupNames))
+	}
+	bySource := models.FieldsBySource(contact)
+	for _, source := range slices.Sorted(maps.Keys(bySource)) {
+		labels := make([]string, len(bySource[source]))
+		for i, field := range bySource[source] {
+			labels[i] = models.FieldLabel(field)
+		}
+		printContactLine(sourceLabel(source), []string{strings.Join(labels, ", ")})
+	}
+
+	if backup == nil || contact.ResourceName == "" {
+		return
+	}
+	if photo := backup.Photos[contact.ResourceName]; photo != nil && len(photo.Data) > 0 {
+		printContactLine("Saved photo", []string{fmt.Sprintf("%s, %d bytes", defaultString(photo.ContentType, "image"), len(photo.Data))})
+	}
+	if photo := backup.FallbackPhotos[contact.ResourceName]; photo != nil {
+		printContactLine("Profile photo", []string{photo.URL})
+	}
+}
+
+// sourceLabel describes where values of a source type come from.
+func sourceLabel(source string) string {
+	switch source {
+	case "PROFILE":
+		return "From profile"
+	case "DOMAIN_PROFILE":
+		return "From directory"
+	case "DOMAIN_CONTACT":
+		return "Domain contact"
+	default:
+		return "From " + strings.ToLower(strings.ReplaceAll(source, "_", " "))
+	}
+}
+
+// printContactLine prints a label and its values, one per line, aligned
+// after the label. Values spanning lines, such as notes, stay aligned.
+func printContactLine(label string, values []string) {
+	const width = 15
+	for i, value := range values {
+		prefix := ""
+		if i == 0 {
+			prefix = label + ":"
+		}
+		lines := strings.Split(strings.TrimRight(value, "\n"), "\n")
+		for j, line := range lines {
+			if j > 0 {
+				prefix = ""
+			}
+			fmt.Println(strings.TrimRight(fmt.Sprintf("  %-*s %s", width, prefix, line), " "))
+		}
+	}
+}
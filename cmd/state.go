@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/i18n"
+	"github.com/mheap/google-contacts-backup/internal/state"
+)
+
+var (
+	stateResetSections []string
+	stateResetConfirm  bool
+)
+
+// stateCmd represents the state command
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Inspect or reset the persistent state store",
+	Long: `Inspect or reset the state that google-contacts-backup keeps between runs.
+
+The state database lives next to the config file (override with --state-file)
+and holds:
+  sync_tokens   People API sync tokens for incremental syncs
+  checkpoints   progress of interrupted operations, used to resume them
+  mappings      tables mapping resource names, e.g. backup to restored contacts
+  runs          when each command last ran and how it ended
+  usage         People API requests sent today, for the restore quota forecast
+  sizes         contact and group counts of each backup, for 'report trend'
+
+Examples:
+  # Show what is stored
+  google-contacts-backup state show
+
+  # Forget all checkpoints so the next run starts from scratch
+  google-contacts-backup state reset --section checkpoints
+
+  # Delete everything without prompting
+  google-contacts-backup state reset --confirm`,
+}
+
+// stateShowCmd represents the state show command
+var stateShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the contents of the state store",
+	RunE:  runStateShow,
+}
+
+// stateResetCmd represents the state reset command
+var stateResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete sections of the state store",
+	RunE:  runStateReset,
+}
+
+func init() {
+	rootCmd.AddCommand(stateCmd)
+	stateCmd.AddCommand(stateShowCmd)
+	stateCmd.AddCommand(stateResetCmd)
+	markLocal(stateShowCmd, nil)
+	markLocal(stateResetCmd, nil)
+
+	stateResetCmd.Flags().StringSliceVar(&stateResetSections, "section", nil,
+		"Section to reset: "+strings.Join(state.Sections(), ", ")+" (repeatable, default: all)")
+	stateResetCmd.Flags().BoolVar(&stateResetConfirm, "confirm", false,
+		"Skip confirmation prompt")
+}
+
+func runStateShow(cmd *cobra.Command, args []string) error {
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	summaries, err := store.Summarize()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("State file: %s\n", stateFile)
+	for _, summary := range summaries {
+		fmt.Println()
+		fmt.Printf("%s (%d)\n", summary.Section, len(summary.Keys))
+		if summary.Section == state.SectionSizes && len(summary.Keys) > 0 {
+			// One per backup, too many to list
+			fmt.Printf("  %s to %s; see 'report trend'\n", summary.Keys[0], summary.Keys[len(summary.Keys)-1])
+			continue
+		}
+		for _, key := range summary.Keys {
+			switch summary.Section {
+			case state.SectionMappings:
+				fmt.Printf("  %s: %d entries\n", key, summary.Counts[key])
+			case state.SectionRuns:
+				run, err := store.LastRun(key)
+				if err != nil {
+					return err
+				}
+				outcome := "ok"
+				if !run.Succeeded() {
+					outcome = "failed: " + run.Error
+				}
+				fmt.Printf("  %-10s %s (%s) %s\n", key, run.FinishedAt.Local().Format(time.RFC3339),
+					run.FinishedAt.Sub(run.StartedAt).Round(time.Second), outcome)
+			case state.SectionUsage:
+				usage, err := store.DailyUsage(key)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("  %s: %d reads, %d writes\n", key, usage.Reads, usage.Writes)
+			default:
+				fmt.Printf("  %s\n", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+func runStateReset(cmd *cobra.Command, args []string) error {
+	for _, section := range stateResetSections {
+		if !state.IsSection(section) {
+			return fmt.Errorf("invalid section %q: must be one of %s", section, strings.Join(state.Sections(), ", "))
+		}
+	}
+
+	sections := stateResetSections
+	if len(sections) == 0 {
+		sections = state.Sections()
+	}
+
+	if !stateResetConfirm {
+		fmt.Println(i18n.T("state.reset_warning", strings.Join(sections, ", ")))
+		ok, err := askConfirmation(i18n.T("confirm.continue"))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println(i18n.T("state.cancelled"))
+			return nil
+		}
+	}
+
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.Reset(sections...); err != nil {
+		return err
+	}
+
+	fmt.Printf("Reset %s\n", strings.Join(sections, ", "))
+	return nil
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+var (
+	tagFilter string
+	tagAll    bool
+	tagDryRun bool
+)
+
+// tagCmd groups the tag subcommands
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Add or remove key=value tags on contacts",
+	Long: `Add or remove key=value tags on contacts in your Google account.
+
+Tags are stored in each contact's clientData, a field Google keeps for
+applications. They are invisible in the Google Contacts UI but are included
+in JSON backups and restored with them, which makes them a scriptable
+metadata layer (for example source=conference2024).
+
+Contacts are selected with a --filter expression (see
+'google-contacts-backup help filters'), or --all to tag every contact.
+
+Examples:
+  # Tag everyone labelled "Conference" with the event they came from
+  google-contacts-backup tag add source=conference2024 --filter 'label="Conference"'
+
+  # Preview which contacts would be untagged
+  google-contacts-backup tag remove source --filter 'domain=example.com' --dry-run
+
+  # Remove a tag only where it has a specific value
+  google-contacts-backup tag remove source=conference2024 --all`,
+}
+
+// tagAddCmd represents the tag add command
+var tagAddCmd = &cobra.Command{
+	Use:   "add KEY=VALUE",
+	Short: "Set a tag on the matching contacts",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTagAdd,
+}
+
+// tagRemoveCmd represents the tag remove command
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove KEY[=VALUE]",
+	Short: "Remove a tag from the matching contacts",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTagRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+
+	tagCmd.PersistentFlags().StringVar(&tagFilter, "filter", "",
+		"Select contacts matching this filter expression, e.g. 'label=\"Work\" && has(phone)'")
+	tagCmd.PersistentFlags().BoolVar(&tagAll, "all", false,
+		"Select every contact")
+	tagCmd.PersistentFlags().BoolVar(&tagDryRun, "dry-run", false,
+		"List the contacts that would change without updating them")
+}
+
+func runTagAdd(cmd *cobra.Command, args []string) error {
+	key, value, ok := strings.Cut(args[0], "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid tag %q: expected KEY=VALUE", args[0])
+	}
+	return updateTags(fmt.Sprintf("Tagging with %s=%s", key, value), func(p *people.Person) bool {
+		return models.SetClientData(p, key, value)
+	})
+}
+
+func runTagRemove(cmd *cobra.Command, args []string) error {
+	key, value, _ := strings.Cut(args[0], "=")
+	if key == "" {
+		return fmt.Errorf("invalid tag %q: expected KEY or KEY=VALUE", args[0])
+	}
+	return updateTags("Removing tag "+args[0], func(p *people.Person) bool {
+		return models.RemoveClientData(p, key, value)
+	})
+}
+
+// updateTags applies change to every selected contact and writes back the
+// contacts it changed.
+func updateTags(description string, change func(*people.Person) bool) error {
+	ctx := context.Background()
+
+	if tagFilter == "" && !tagAll {
+		return fmt.Errorf("no contacts selected: use --filter or --all")
+	}
+	selection, err := parseFilter(tagFilter)
+	if err != nil {
+		return err
+	}
+
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	groups, err := client.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch contact groups: %w", err)
+	}
+	groupNames := make(map[string]string, len(groups))
+	for _, group := range groups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			groupNames[group.ResourceName] = group.Name
+		}
+	}
+
+	fmt.Println("Fetching contacts...")
+	allContacts, err := client.ListContacts(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+
+	var changed []*people.Person
+	matched := 0
+	for _, contact := range allContacts {
+		if selection != nil && !selection.Match(contact, groupNames) {
+			continue
+		}
+		matched++
+		if change(contact) {
+			changed = append(changed, contact)
+		}
+	}
+
+	fmt.Printf("%d contacts match, %d need changing\n", matched, len(changed))
+	if len(changed) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no contacts to update"))
+	}
+
+	if tagDryRun {
+		fmt.Println()
+		for _, contact := range changed {
+			fmt.Printf("  %s (%s)\n", models.DisplayName(contact), contact.ResourceName)
+		}
+		fmt.Println()
+		fmt.Println("Dry run: no contacts were updated.")
+		return nil
+	}
+
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("%s...\n", description)
+	bar := progressbar.NewOptions(len(changed),
+		progressbar.OptionSetDescription("Updating contacts"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+
+	updated, err := client.UpdateContacts(ctx, changed, []string{"clientData"}, func(done, total int) {
+		bar.Set(done)
+	})
+	bar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to update contacts: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("Updated %d contacts\n", len(updated))
+	if len(updated) < len(changed) {
+		warnf("%d contacts were not updated", len(changed)-len(updated))
+	}
+
+	return nil
+}
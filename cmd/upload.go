@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mheap/google-contacts-backup/internal/storage"
+)
+
+var (
+	uploadTo            string
+	uploadRetainDays    int
+	uploadRetentionMode string
+)
+
+// uploadCmd represents the upload command
+var uploadCmd = &cobra.Command{
+	Use:   "upload FILE",
+	Short: "Upload a backup file to cloud storage, optionally write-once",
+	Long: `Upload a backup file to an s3://bucket/prefix or gs://bucket/prefix destination,
+given with --to or the "upload.destination" config key. The file keeps its
+name under the destination prefix.
+
+With --retain-days, the backup is stored with an S3 object lock so that it
+cannot be deleted or overwritten until the retention period ends, even by
+someone holding the same credentials. The lock is read back after the upload
+and the command fails with exit code 5 if it is missing or shorter than
+requested. The bucket must have object lock enabled. Backblaze B2 and other
+S3-compatible stores are supported by setting AWS_ENDPOINT_URL.
+
+Retention modes:
+  - compliance: nobody, including the account root, can remove the lock or
+    delete the object before it expires (default)
+  - governance: users with the s3:BypassGovernanceRetention permission can
+    remove the lock
+
+Credentials come from the environment: the usual AWS_* variables or shared
+config for S3, and Application Default Credentials for GCS.
+
+Examples:
+  # Upload a backup
+  google-contacts-backup upload contacts-20240101-120000.json --to gs://my-bucket/backups
+
+  # Keep the backup immutable for 90 days
+  google-contacts-backup upload backup.json --to s3://my-bucket/contacts --retain-days 90
+
+  # Upload to Backblaze B2 with a governance lock
+  AWS_ENDPOINT_URL=https://s3.us-west-004.backblazeb2.com \
+    google-contacts-backup upload backup.json --to s3://my-bucket --retain-days 30 --retention-mode governance`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUpload,
+}
+
+func init() {
+	rootCmd.AddCommand(uploadCmd)
+
+	uploadCmd.Flags().StringVar(&uploadTo, "to", "",
+		"Destination URL, s3://bucket/prefix or gs://bucket/prefix (default: upload.destination from config)")
+	uploadCmd.Flags().IntVar(&uploadRetainDays, "retain-days", 0,
+		"Lock the uploaded backup against deletion for this many days (S3 only; overrides upload.retain_days)")
+	uploadCmd.Flags().StringVar(&uploadRetentionMode, "retention-mode", "compliance",
+		"Object lock mode: compliance or governance (overrides upload.retention_mode)")
+}
+
+func runUpload(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	file := args[0]
+
+	destination := uploadTo
+	if destination == "" {
+		destination = cfg.Upload.Destination
+	}
+	if destination == "" {
+		return fmt.Errorf("no destination: use --to or set upload.destination in the config file")
+	}
+	loc, err := storage.ParseLocation(destination)
+	if err != nil {
+		return err
+	}
+
+	retainDays := uploadRetainDays
+	if !cmd.Flags().Changed("retain-days") {
+		retainDays = cfg.Upload.RetainDays
+	}
+	if retainDays < 0 {
+		return fmt.Errorf("invalid retention %d: must be a positive number of days", retainDays)
+	}
+	modeFlag := uploadRetentionMode
+	if !cmd.Flags().Changed("retention-mode") && cfg.Upload.RetentionMode != "" {
+		modeFlag = cfg.Upload.RetentionMode
+	}
+	mode, err := storage.ParseRetentionMode(modeFlag)
+	if err != nil {
+		return err
+	}
+
+	backend, err := openUploadBackend(ctx, loc, retainDays)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	object, err := uploadFile(ctx, backend, file, loc, retainDays, mode)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println("Upload completed successfully!")
+	fmt.Println()
+	fmt.Printf("  Object: %s\n", object)
+	return nil
+}
+
+// openUploadBackend opens the backend of a location, checking that it can
+// lock objects if retainDays is set, so missing credentials or an
+// unsupported lock are reported before anything is uploaded.
+func openUploadBackend(ctx context.Context, loc storage.Location, retainDays int) (storage.Backend, error) {
+	backend, err := storage.Open(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := backend.(storage.Retainer); retainDays > 0 && !ok {
+		backend.Close()
+		return nil, fmt.Errorf("object lock is not supported for %s:// destinations: drop the retention period, or give the bucket a retention policy instead", loc.Scheme)
+	}
+	return backend, nil
+}
+
+// uploadFile uploads a backup file under its name to a location opened with
+// openUploadBackend and, if retainDays is set, locks it for that many days
+// in the given object lock mode and checks the lock. It returns the URL of
+// the uploaded object.
+func uploadFile(ctx context.Context, backend storage.Backend, file string, loc storage.Location, retainDays int, mode string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer f.Close()
+
+	key := loc.Key(filepath.Base(file))
+	object := fmt.Sprintf("%s://%s/%s", loc.Scheme, loc.Bucket, key)
+	contentType := uploadContentType(file)
+	fmt.Printf("Uploading %s to %s...\n", file, object)
+
+	if retainDays == 0 {
+		return object, backend.Upload(ctx, key, f, contentType)
+	}
+
+	retainer, ok := backend.(storage.Retainer)
+	if !ok {
+		return "", fmt.Errorf("object lock is not supported for %s:// destinations", loc.Scheme)
+	}
+
+	want := storage.Retention{
+		Mode:  mode,
+		Until: time.Now().Add(time.Duration(retainDays) * 24 * time.Hour).Truncate(time.Second),
+	}
+	if err := retainer.UploadRetained(ctx, key, f, contentType, want); err != nil {
+		return "", err
+	}
+
+	fmt.Println("Verifying object lock...")
+	got, err := retainer.Retention(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if !got.Covers(want) {
+		if got.Mode == "" {
+			return "", withExitCode(exitVerificationMismatch,
+				fmt.Errorf("uploaded backup is not locked: check that object lock is enabled on the bucket"))
+		}
+		return "", withExitCode(exitVerificationMismatch,
+			fmt.Errorf("uploaded backup is locked in %s mode until %s, expected %s mode until %s",
+				strings.ToLower(got.Mode), got.Until.Format(time.RFC3339),
+				strings.ToLower(want.Mode), want.Until.Format(time.RFC3339)))
+	}
+
+	fmt.Printf("Locked in %s mode until %s\n", strings.ToLower(got.Mode), got.Until.Format(time.RFC3339))
+	return object, nil
+}
+
+// uploadContentType returns the content type of a backup file from its extension.
+func uploadContentType(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return "application/json"
+	case ".csv":
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/validate"
+)
+
+// validateContacts checks contacts against the constraints of the People API
+// and lists every problem with the contact's position and, if batchSize is
+// positive, the restore batch it would be sent in. Returns an error with exit
+// code 5 if any contact would be refused.
+func validateContacts(contacts []*people.Person, batchSize int) error {
+	invalid := 0
+	for i, contact := range contacts {
+		problems := validate.Contact(contact)
+		if len(problems) == 0 {
+			continue
+		}
+		if invalid == 0 {
+			fmt.Println("Contacts the People API would refuse:")
+		}
+		invalid++
+
+		location := fmt.Sprintf("#%d", i+1)
+		if batchSize > 0 {
+			location += fmt.Sprintf(", batch %d", i/batchSize)
+		}
+		fmt.Printf("  %s (%s, %s)\n", models.DisplayName(contact), defaultString(contact.ResourceName, "no resource name"), location)
+		for _, problem := range problems {
+			fmt.Printf("    %s\n", problem)
+		}
+	}
+	eventData["invalid"] = invalid
+	if invalid == 0 {
+		return nil
+	}
+	fmt.Println()
+	return withExitCode(exitVerificationMismatch, fmt.Errorf("%d contacts fail validation against the People API's limits", invalid))
+}
@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/integrity"
+	"github.com/mheap/google-contacts-backup/internal/models"
+	"github.com/mheap/google-contacts-backup/internal/policy"
+	"github.com/mheap/google-contacts-backup/internal/reconcile"
+)
+
+var (
+	verifyInput        string
+	verifyAgainstLive  bool
+	verifySkipPolicies bool
+	verifySkipValidate bool
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check a backup's integrity, or compare it with the live account",
+	Long: `Check a JSON backup against the manifest stored inside it, and optionally
+against the live Google account.
+
+Every JSON backup carries a manifest: a content hash of each contact,
+arranged as a Merkle tree with a single root hash. verify recomputes the
+hashes from the backup's contents and reports any contact whose data no
+longer matches, which catches corruption and tampering.
+
+With --against-live, the live account is downloaded and hashed the same way.
+If the roots match, the account is unchanged since the backup. Otherwise the
+backup is compared with the account field by field, which is how to gain
+confidence after a restore: each backup contact is matched with a live
+contact by resource name, external ID or, for restored contacts that got a
+new resource name, by name, email addresses and phone numbers. Matched
+contacts whose writable fields or labels differ are listed with those
+fields, followed by backup contacts missing from the account and contacts
+only in the account. Read-only fields such as photos and metadata, which a
+restore cannot bring back, are not compared.
+
+Content policies in the config file ("verify" key) are enforced too, so
+automated pipelines catch data regressions such as a sudden rise in contacts
+without names. Each policy names a check and the number of contacts allowed
+to fail it (default 0); min_contacts fails backups that are suspiciously
+small. Available checks:
+` + policyCheckHelp() + `
+
+Every contact is also validated against the limits and formats the People
+API enforces (value lengths, number of values, contact size, email
+addresses, dates and note content types), and each problem is listed with
+its location in the contact, such as emailAddresses[1].value. A contact
+that fails validation would fail its whole batch in a restore.
+
+Differences, policy violations and invalid contacts exit with code 5.
+
+Examples:
+  # Check a backup for corruption
+  google-contacts-backup verify -i my-contacts.json
+
+  # Check integrity only, ignoring the configured policies
+  google-contacts-backup verify -i my-contacts.json --skip-policies
+
+  # See which contacts changed since the backup was taken, or check a restore
+  google-contacts-backup verify -i my-contacts.json --against-live`,
+	RunE: withEvents("verify", runVerify),
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	markLocal(verifyCmd, func() bool { return !verifyAgainstLive })
+
+	verifyCmd.Flags().StringVarP(&verifyInput, "input", "i", "",
+		"Backup file to verify (required)")
+	verifyCmd.MarkFlagRequired("input")
+
+	verifyCmd.Flags().BoolVar(&verifyAgainstLive, "against-live", false,
+		"Compare the backup field by field with the contacts in the live account")
+	verifyCmd.Flags().BoolVar(&verifySkipPolicies, "skip-policies", false,
+		"Do not enforce the content policies from the config file")
+	verifyCmd.Flags().BoolVar(&verifySkipValidate, "skip-validation", false,
+		"Do not validate contacts against the People API's limits")
+}
+
+// policyCheckHelp describes each policy check, one per line.
+func policyCheckHelp() string {
+	lines := make([]string, 0, len(policy.Checks()))
+	for _, name := range policy.Checks() {
+		lines = append(lines, fmt.Sprintf("  - %-22s %s", name+":", policy.Describe(name)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	fmt.Printf("Loading backup file: %s\n", verifyInput)
+	backup, err := loadBackup(verifyInput)
+	if err != nil {
+		return fmt.Errorf("failed to load backup: %w", err)
+	}
+
+	current, err := backup.BuildManifest()
+	if err != nil {
+		return err
+	}
+
+	eventData["file"] = verifyInput
+	eventData["contacts"] = len(backup.Contacts)
+
+	fmt.Println()
+	if backup.Manifest == nil {
+		fmt.Println("The backup has no manifest (it was created by an older version);")
+		fmt.Println("hashes are computed from its contents.")
+	} else {
+		corrupted := integrity.Compare(backup.Manifest.Contacts, current.Contacts)
+		if current.Root != backup.Manifest.Root || !corrupted.Empty() {
+			byKey := contactsByKey(backup.Contacts)
+			fmt.Println("The backup does not match its manifest:")
+			printDiff(corrupted, byKey, byKey)
+			eventData["corrupted"] = len(corrupted.Changed) + len(corrupted.Removed) + len(corrupted.Added)
+			return withExitCode(exitVerificationMismatch, fmt.Errorf("backup contents do not match the manifest"))
+		}
+		fmt.Printf("Manifest OK: %d contacts, root %s\n", len(current.Contacts), current.Root)
+	}
+
+	var policyErr error
+	if !verifySkipPolicies {
+		policyErr = checkPolicies(backup)
+	}
+	if !verifySkipValidate {
+		fmt.Println()
+		if err := validateContacts(backup.Contacts, 0); err != nil && policyErr == nil {
+			policyErr = err
+		} else if err == nil {
+			fmt.Println("Every contact passes validation against the People API's limits.")
+		}
+	}
+
+	if !verifyAgainstLive {
+		return policyErr
+	}
+
+	fmt.Println()
+	fmt.Println("Authenticating with Google...")
+	client, err := newContactsClient(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Authentication successful!")
+	fmt.Println()
+
+	fmt.Println("Fetching contacts...")
+	bar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Downloading"),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+	var totalKnown bool
+	live, err := client.ListContacts(ctx, func(current, total int) {
+		if !totalKnown && total > 0 {
+			bar.ChangeMax(total)
+			totalKnown = true
+		}
+		bar.Set(current)
+	})
+	bar.Finish()
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+
+	liveHashes, err := integrity.Hashes(live)
+	if err != nil {
+		return err
+	}
+	liveRoot := integrity.Root(liveHashes)
+
+	fmt.Println()
+	fmt.Printf("  Backup: %d contacts, root %s\n", len(current.Contacts), current.Root)
+	fmt.Printf("  Live:   %d contacts, root %s\n", len(liveHashes), liveRoot)
+	fmt.Println()
+
+	if integrity.Compare(current.Contacts, liveHashes).Empty() {
+		eventData["changed"], eventData["deleted"], eventData["added"] = 0, 0, 0
+		fmt.Println("The live account matches the backup.")
+		return policyErr
+	}
+
+	liveGroups, err := client.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	backup.ApplyGroupMembers()
+	comparison := reconcile.Compare(backup, live, liveGroups)
+	eventData["changed"] = len(comparison.Mismatches)
+	eventData["deleted"] = len(comparison.Missing)
+	eventData["added"] = len(comparison.Extra)
+
+	fmt.Printf("Matched %d of %d backup contacts in the live account.\n", comparison.Matched, len(backup.Contacts))
+	fmt.Println()
+	if len(comparison.Mismatches) == 0 && len(comparison.Missing) == 0 && len(comparison.Extra) == 0 {
+		fmt.Println("The live account matches the backup in every writable field.")
+		return policyErr
+	}
+
+	printComparison(comparison)
+	return withExitCode(exitVerificationMismatch,
+		fmt.Errorf("%d contacts differ, %d are missing from the live account and %d are only in the live account",
+			len(comparison.Mismatches), len(comparison.Missing), len(comparison.Extra)))
+}
+
+// printComparison lists the mismatched, missing and extra contacts of a
+// field-by-field comparison with their last update time.
+func printComparison(comparison *reconcile.Comparison) {
+	if len(comparison.Mismatches) > 0 {
+		fmt.Printf("Mismatched (%d):\n", len(comparison.Mismatches))
+		for _, mismatch := range comparison.Mismatches {
+			name := mismatch.Backup.ResourceName
+			if mismatch.Live.ResourceName != name {
+				name += " -> " + mismatch.Live.ResourceName
+			}
+			fmt.Printf("  %s (%s): %s\n", models.DisplayName(mismatch.Backup), name, strings.Join(mismatch.Fields, ", "))
+		}
+		fmt.Println()
+	}
+	for _, section := range []struct {
+		title    string
+		contacts []*people.Person
+	}{
+		{"Missing from the live account", comparison.Missing},
+		{"Only in the live account", comparison.Extra},
+	} {
+		if len(section.contacts) == 0 {
+			continue
+		}
+		fmt.Printf("%s (%d):\n", section.title, len(section.contacts))
+		for _, contact := range section.contacts {
+			line := fmt.Sprintf("  %s (%s)", models.DisplayName(contact), contact.ResourceName)
+			if updated := models.UpdateTime(contact); !updated.IsZero() {
+				line += ", updated " + updated.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+}
+
+// checkPolicies enforces the content policies from the config file,
+// printing a line per policy and the contacts that break failing ones.
+func checkPolicies(backup *models.BackupFile) error {
+	rules := cfg.Verify.Policies
+	if len(rules) == 0 && cfg.Verify.MinContacts == 0 {
+		return nil
+	}
+	results, err := policy.Evaluate(backup.Contacts, rules)
+	if err != nil {
+		return fmt.Errorf("invalid verify policies in config file: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Content policies:")
+	checked, failed := len(results), 0
+	if cfg.Verify.MinContacts > 0 {
+		checked++
+		status := "OK"
+		if len(backup.Contacts) < cfg.Verify.MinContacts {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("  %-4s  min_contacts: %d contacts (min %d)\n", status, len(backup.Contacts), cfg.Verify.MinContacts)
+	}
+	for _, result := range results {
+		status := "OK"
+		if !result.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("  %-4s  %s: %d contacts (max %d)\n", status, result.Check, len(result.Violations), result.Max)
+		if !result.Passed() {
+			for _, contact := range result.Violations {
+				fmt.Printf("          %s (%s)\n", models.DisplayName(contact), contact.ResourceName)
+			}
+		}
+	}
+
+	eventData["policy_failures"] = failed
+	if failed > 0 {
+		return withExitCode(exitVerificationMismatch, fmt.Errorf("%d of %d content policies failed", failed, checked))
+	}
+	return nil
+}
+
+// contactsByKey maps the hash keys of contacts to the contacts.
+func contactsByKey(contacts []*people.Person) map[string]*people.Person {
+	byKey := make(map[string]*people.Person, len(contacts))
+	for i, contact := range contacts {
+		byKey[integrity.Key(contact, i)] = contact
+	}
+	return byKey
+}
+
+// printDiff lists the contacts in a diff with their last update time,
+// naming removed contacts from the first set and changed or added contacts
+// from the second.
+func printDiff(diff integrity.Diff, from, to map[string]*people.Person) {
+	sections := []struct {
+		title    string
+		keys     []string
+		contacts map[string]*people.Person
+	}{
+		{"Changed", diff.Changed, to},
+		{"Deleted", diff.Removed, from},
+		{"Added", diff.Added, to},
+	}
+	for _, section := range sections {
+		if len(section.keys) == 0 {
+			continue
+		}
+		fmt.Printf("%s (%d):\n", section.title, len(section.keys))
+		for _, key := range section.keys {
+			contact := section.contacts[key]
+			line := fmt.Sprintf("  %s (%s)", models.DisplayName(contact), key)
+			if updated := models.UpdateTime(contact); !updated.IsZero() {
+				line += ", updated " + updated.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,211 @@
+// Package approval implements the two-person rule for destructive
+// operations: an approver signs an approval file with their ed25519 key, or
+// reads out a TOTP code from their authenticator app, and the operation only
+// runs if the approval checks out.
+package approval
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// publicKeyPrefix and privateKeyPrefix start encoded keys
+	publicKeyPrefix  = "ed25519:"
+	privateKeyPrefix = "ed25519-private:"
+
+	// signedContext is prepended to the signed payload, so a signature made
+	// for anything else is never accepted
+	signedContext = "google-contacts-backup approval v1\n"
+)
+
+// Approval allows one destructive operation until it expires.
+type Approval struct {
+	// Command is the operation approved, e.g. "restore"
+	Command string `json:"command"`
+
+	// BackupSHA256 is the hex SHA-256 of the backup file the operation
+	// reads, for restores. Empty approves any backup.
+	BackupSHA256 string `json:"backup_sha256,omitempty"`
+
+	// Approver names who signed the approval
+	Approver string `json:"approver,omitempty"`
+
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Signature is the ed25519 signature of the fields above
+	Signature []byte `json:"signature"`
+}
+
+// payload returns the bytes that are signed.
+func (a *Approval) payload() []byte {
+	var b strings.Builder
+	b.WriteString(signedContext)
+	fmt.Fprintf(&b, "command=%s\n", a.Command)
+	fmt.Fprintf(&b, "backup_sha256=%s\n", a.BackupSHA256)
+	fmt.Fprintf(&b, "approver=%s\n", a.Approver)
+	fmt.Fprintf(&b, "issued_at=%s\n", a.IssuedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "expires_at=%s\n", a.ExpiresAt.UTC().Format(time.RFC3339))
+	return []byte(b.String())
+}
+
+// Sign signs the approval with key.
+func (a *Approval) Sign(key ed25519.PrivateKey) {
+	a.IssuedAt = a.IssuedAt.UTC().Truncate(time.Second)
+	a.ExpiresAt = a.ExpiresAt.UTC().Truncate(time.Second)
+	a.Signature = ed25519.Sign(key, a.payload())
+}
+
+// Verify checks that the approval is signed by one of keys, covers command
+// and, if it names a backup, the backup with hash backupSHA256, and has not
+// expired at now.
+func (a *Approval) Verify(keys []ed25519.PublicKey, command, backupSHA256 string, now time.Time) error {
+	signed := false
+	for _, key := range keys {
+		if ed25519.Verify(key, a.payload(), a.Signature) {
+			signed = true
+			break
+		}
+	}
+	switch {
+	case !signed:
+		return errors.New("the approval is not signed by any of the approval.public_keys")
+	case a.Command != command:
+		return fmt.Errorf("the approval is for %q, not %q", a.Command, command)
+	case a.BackupSHA256 != "" && a.BackupSHA256 != backupSHA256:
+		return errors.New("the approval is for a different backup file")
+	case now.After(a.ExpiresAt):
+		return fmt.Errorf("the approval expired at %s", a.ExpiresAt.Local().Format(time.RFC3339))
+	case now.Before(a.IssuedAt.Add(-5 * time.Minute)):
+		return errors.New("the approval was issued in the future; check the clocks")
+	}
+	return nil
+}
+
+// Load reads an approval file.
+func Load(path string) (*Approval, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approval file: %w", err)
+	}
+	var a Approval
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse approval file %s: %w", path, err)
+	}
+	return &a, nil
+}
+
+// Save writes the approval to path.
+func (a *Approval) Save(path string) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal approval: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write approval file: %w", err)
+	}
+	return nil
+}
+
+// HashFile returns the hex SHA-256 of a file. A directory, such as a backup
+// written in the dir format, is hashed over the relative path and contents
+// of every file in it, in path order.
+func HashFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	h := sha256.New()
+	if !info.IsDir() {
+		if err := hashInto(h, path); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		return hashInto(h, file)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashInto writes the contents of the file at path to h.
+func hashInto(h io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// GenerateKey creates an approver key pair and returns it encoded.
+func GenerateKey() (publicKey, privateKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return publicKeyPrefix + base64.StdEncoding.EncodeToString(pub),
+		privateKeyPrefix + base64.StdEncoding.EncodeToString(priv.Seed()), nil
+}
+
+// EncodePublicKey returns the public half of key in the form printed by
+// GenerateKey.
+func EncodePublicKey(key ed25519.PrivateKey) string {
+	return publicKeyPrefix + base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// ParsePublicKeys decodes public keys in the form printed by GenerateKey.
+func ParsePublicKeys(encoded []string) ([]ed25519.PublicKey, error) {
+	keys := make([]ed25519.PublicKey, 0, len(encoded))
+	for _, s := range encoded {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), publicKeyPrefix))
+		if !strings.HasPrefix(strings.TrimSpace(s), publicKeyPrefix) || err != nil || len(data) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid approval public key %q: must be %s followed by a base64 key", s, publicKeyPrefix)
+		}
+		keys = append(keys, ed25519.PublicKey(data))
+	}
+	return keys, nil
+}
+
+// LoadPrivateKey reads a private key file written by 'approve keygen'.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approval key: %w", err)
+	}
+	s := strings.TrimSpace(string(data))
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, privateKeyPrefix))
+	if !strings.HasPrefix(s, privateKeyPrefix) || err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s is not an approval key", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -0,0 +1,175 @@
+package approval
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) (ed25519.PrivateKey, ed25519.PublicKey) {
+	t.Helper()
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "approver.key")
+	if err := os.WriteFile(path, []byte(priv+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadPrivateKey(path)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey: %v", err)
+	}
+	keys, err := ParsePublicKeys([]string{pub})
+	if err != nil {
+		t.Fatalf("ParsePublicKeys: %v", err)
+	}
+	if EncodePublicKey(key) != pub {
+		t.Error("EncodePublicKey does not match the generated public key")
+	}
+	return key, keys[0]
+}
+
+func TestVerify(t *testing.T) {
+	key, pub := newKey(t)
+	_, otherPub := newKey(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sign := func(change func(a *Approval)) *Approval {
+		a := &Approval{
+			Command:      "restore",
+			BackupSHA256: "abc123",
+			Approver:     "alice",
+			IssuedAt:     now,
+			ExpiresAt:    now.Add(time.Hour),
+		}
+		if change != nil {
+			change(a)
+		}
+		a.Sign(key)
+		return a
+	}
+
+	tests := []struct {
+		name     string
+		approval *Approval
+		keys     []ed25519.PublicKey
+		command  string
+		backup   string
+		now      time.Time
+		wantErr  string
+	}{
+		{"valid", sign(nil), []ed25519.PublicKey{otherPub, pub}, "restore", "abc123", now, ""},
+		{"any backup", sign(func(a *Approval) { a.BackupSHA256 = "" }), []ed25519.PublicKey{pub}, "restore", "def456", now, ""},
+		{"wrong key", sign(nil), []ed25519.PublicKey{otherPub}, "restore", "abc123", now, "not signed"},
+		{"no keys", sign(nil), nil, "restore", "abc123", now, "not signed"},
+		{"other command", sign(nil), []ed25519.PublicKey{pub}, "cleanup", "abc123", now, `not "cleanup"`},
+		{"other backup", sign(nil), []ed25519.PublicKey{pub}, "restore", "def456", now, "different backup"},
+		{"expired", sign(nil), []ed25519.PublicKey{pub}, "restore", "abc123", now.Add(2 * time.Hour), "expired"},
+		{"issued in the future", sign(nil), []ed25519.PublicKey{pub}, "restore", "abc123", now.Add(-time.Hour), "future"},
+		{"within clock skew", sign(nil), []ed25519.PublicKey{pub}, "restore", "abc123", now.Add(-time.Minute), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.approval.Verify(tt.keys, tt.command, tt.backup, tt.now)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Verify: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("Verify succeeded, want error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("Verify: %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	key, pub := newKey(t)
+	now := time.Now()
+
+	tamper := map[string]func(a *Approval){
+		"command":    func(a *Approval) { a.Command = "cleanup" },
+		"backup":     func(a *Approval) { a.BackupSHA256 = "" },
+		"approver":   func(a *Approval) { a.Approver = "mallory" },
+		"expires_at": func(a *Approval) { a.ExpiresAt = a.ExpiresAt.Add(24 * time.Hour) },
+	}
+	for field, change := range tamper {
+		t.Run(field, func(t *testing.T) {
+			a := &Approval{Command: "restore", BackupSHA256: "abc123", Approver: "alice", IssuedAt: now, ExpiresAt: now.Add(time.Hour)}
+			a.Sign(key)
+			change(a)
+			if err := a.Verify([]ed25519.PublicKey{pub}, a.Command, a.BackupSHA256, now); err == nil || !strings.Contains(err.Error(), "not signed") {
+				t.Errorf("Verify after changing %s: %v, want a signature error", field, err)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	key, pub := newKey(t)
+	now := time.Now()
+	a := &Approval{Command: "restore", Approver: "alice", IssuedAt: now, ExpiresAt: now.Add(time.Hour)}
+	a.Sign(key)
+
+	path := filepath.Join(t.TempDir(), "approval.json")
+	if err := a.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := loaded.Verify([]ed25519.PublicKey{pub}, "restore", "", now); err != nil {
+		t.Errorf("Verify after Load: %v", err)
+	}
+}
+
+func TestParsePublicKeysInvalid(t *testing.T) {
+	pub, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, encoded := range []string{
+		strings.TrimPrefix(pub, publicKeyPrefix),
+		publicKeyPrefix + "not base64!",
+		pub[:len(pub)-8],
+	} {
+		if _, err := ParsePublicKeys([]string{encoded}); err == nil {
+			t.Errorf("ParsePublicKeys(%q) succeeded", encoded)
+		}
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "backup.json")
+	if err := os.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := HashFile(file)
+	if err != nil {
+		t.Fatalf("HashFile: %v", err)
+	}
+	if want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+		t.Errorf("HashFile = %s, want %s", got, want)
+	}
+
+	before, err := HashFile(dir)
+	if err != nil {
+		t.Fatalf("HashFile(dir): %v", err)
+	}
+	if err := os.WriteFile(file, []byte("hello!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	after, err := HashFile(dir)
+	if err != nil {
+		t.Fatalf("HashFile(dir): %v", err)
+	}
+	if before == after {
+		t.Error("HashFile(dir) did not change when a file in it changed")
+	}
+}
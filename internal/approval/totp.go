@@ -0,0 +1,88 @@
+package approval
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// totpPeriod and totpDigits are the RFC 6238 defaults that every
+	// authenticator app supports
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+
+	// totpSkew is how many periods before and after the current one are
+	// accepted, for clocks that are slightly off
+	totpSkew = 1
+)
+
+// totpEncoding is unpadded base32, as used by authenticator apps
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random base32 TOTP secret.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI returns the otpauth:// URI that authenticator apps import, usually
+// as a QR code.
+func TOTPURI(secret, account string) string {
+	label := url.PathEscape("google-contacts-backup:" + account)
+	return fmt.Sprintf("otpauth://totp/%s?secret=%s&issuer=google-contacts-backup&digits=%d&period=%d",
+		label, secret, totpDigits, int(totpPeriod/time.Second))
+}
+
+// ValidateTOTP reports whether code is the code for secret at now, or at
+// the period just before or after it.
+func ValidateTOTP(secret, code string, now time.Time) (bool, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return false, err
+	}
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	counter := now.Unix() / int64(totpPeriod/time.Second)
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		if hmac.Equal([]byte(totpCode(key, uint64(counter+int64(skew)))), []byte(code)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// decodeTOTPSecret decodes a base32 secret, ignoring case, spaces and padding.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	key, err := totpEncoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid TOTP secret: must be base32")
+	}
+	return key, nil
+}
+
+// totpCode computes the HOTP code (RFC 4226) for a counter.
+func totpCode(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for range totpDigits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
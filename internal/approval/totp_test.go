@@ -0,0 +1,76 @@
+package approval
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	// The RFC 6238 SHA-1 test vectors, truncated to 6 digits
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, v := range vectors {
+		ok, err := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("ValidateTOTP: %v", err)
+		}
+		if !ok {
+			t.Errorf("code %s rejected at %d", v.code, v.unix)
+		}
+	}
+
+	now := time.Unix(1111111109, 0)
+	for _, tt := range []struct {
+		name string
+		code string
+		at   time.Time
+		want bool
+	}{
+		{"spaces and lowercase secret", " 081 804 ", now, true},
+		{"one period late", "081804", now.Add(30 * time.Second), true},
+		{"two periods late", "081804", now.Add(90 * time.Second), false},
+		{"wrong code", "123456", now, false},
+	} {
+		ok, err := ValidateTOTP(strings.ToLower(rfc6238Secret), tt.code, tt.at)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ok != tt.want {
+			t.Errorf("%s: ValidateTOTP = %v, want %v", tt.name, ok, tt.want)
+		}
+	}
+
+	if _, err := ValidateTOTP("not base32!", "123456", now); err == nil {
+		t.Error("ValidateTOTP accepted an invalid secret")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		t.Fatalf("decodeTOTPSecret: %v", err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret is %d bytes, want 20", len(key))
+	}
+	if uri := TOTPURI(secret, "alice@example.com"); !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("TOTPURI = %s, missing the secret", uri)
+	}
+}
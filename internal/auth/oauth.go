@@ -38,6 +38,13 @@ func NewAuthenticator(credentialsFile string) *Authenticator {
 	}
 }
 
+// ValidateCredentials returns an error if the credentials file cannot be
+// read or does not hold OAuth client credentials.
+func (a *Authenticator) ValidateCredentials() error {
+	_, err := a.loadCredentials()
+	return err
+}
+
 // GetClient returns an authenticated HTTP client for Google APIs.
 func (a *Authenticator) GetClient(ctx context.Context) (*http.Client, error) {
 	// Load credentials
@@ -123,7 +130,7 @@ func (a *Authenticator) loadCredentials() (*oauth2.Config, error) {
 	config := &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
-		Scopes:       []string{people.ContactsScope},
+		Scopes:       []string{people.ContactsScope, people.ContactsOtherReadonlyScope},
 		Endpoint:     google.Endpoint,
 	}
 
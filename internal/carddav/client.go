@@ -0,0 +1,259 @@
+// Package carddav writes contacts to a CardDAV address book, so that backups
+// can be restored to servers other than Google.
+package carddav
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// PasswordEnv is the environment variable read for the CardDAV password when
+// the target URL does not contain one
+const PasswordEnv = "CARDDAV_PASSWORD"
+
+// propfindBody asks for the resource type of each member of the address book
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/></d:prop></d:propfind>`
+
+// Client writes contacts to a single CardDAV address book collection.
+type Client struct {
+	httpClient *http.Client
+	collection *url.URL
+	username   string
+	password   string
+}
+
+// IsTarget reports whether a restore target URL refers to a CardDAV server.
+func IsTarget(target string) bool {
+	return strings.HasPrefix(target, "carddav://") || strings.HasPrefix(target, "carddav+http://")
+}
+
+// NewClient creates a client for an address book URL of the form
+// carddav://user@host/path/to/addressbook/. The carddav scheme uses HTTPS;
+// carddav+http uses plain HTTP and is meant for servers on localhost. The
+// password is taken from the URL or, preferably, from $CARDDAV_PASSWORD.
+func NewClient(target string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CardDAV target: %w", err)
+	}
+
+	switch u.Scheme {
+	case "carddav":
+		u.Scheme = "https"
+	case "carddav+http":
+		u.Scheme = "http"
+	default:
+		return nil, fmt.Errorf("invalid CardDAV target %q: must start with carddav:// or carddav+http://", target)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid CardDAV target %q: missing host", target)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	c := &Client{httpClient: httpClient, password: os.Getenv(PasswordEnv)}
+	if u.User != nil {
+		c.username = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			c.password = password
+		}
+		u.User = nil
+	}
+	c.collection = u
+
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	return c, nil
+}
+
+// String returns the address book URL without credentials.
+func (c *Client) String() string {
+	return c.collection.String()
+}
+
+// do sends a request relative to the address book and checks the status code.
+func (c *Client) do(ctx context.Context, method, ref string, body []byte, header http.Header) (*http.Response, error) {
+	target, err := c.collection.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CardDAV path %q: %w", ref, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, target.Path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s failed: %s", method, target.Path, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// multistatus is the subset of a WebDAV PROPFIND response the client reads
+type multistatus struct {
+	Responses []struct {
+		Href       string    `xml:"DAV: href"`
+		Collection *struct{} `xml:"DAV: propstat>prop>resourcetype>collection"`
+	} `xml:"DAV: response"`
+}
+
+// listCards returns the hrefs of every card in the address book.
+func (c *Client) listCards(ctx context.Context) ([]string, error) {
+	resp, err := c.do(ctx, "PROPFIND", "", []byte(propfindBody), http.Header{
+		"Depth":        {"1"},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list address book: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to parse address book listing: %w", err)
+	}
+
+	hrefs := make([]string, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		// The collection itself and any sub-collections are not cards
+		if r.Collection != nil {
+			continue
+		}
+		hrefs = append(hrefs, r.Href)
+	}
+
+	return hrefs, nil
+}
+
+// CountCards returns the number of cards in the address book.
+func (c *Client) CountCards(ctx context.Context) (int, error) {
+	hrefs, err := c.listCards(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(hrefs), nil
+}
+
+// DeleteAllContacts deletes every card in the address book.
+// The progressFn callback is called with (deleted, total) after each card.
+func (c *Client) DeleteAllContacts(ctx context.Context, progressFn func(deleted, total int)) error {
+	hrefs, err := c.listCards(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, href := range hrefs {
+		resp, err := c.do(ctx, http.MethodDelete, href, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete card: %w", err)
+		}
+		resp.Body.Close()
+
+		if progressFn != nil {
+			progressFn(i+1, len(hrefs))
+		}
+	}
+
+	return nil
+}
+
+// DeleteUserGroups does nothing: CardDAV has no separate group objects, labels
+// are stored as CATEGORIES on each card and go away with the cards.
+func (c *Client) DeleteUserGroups(ctx context.Context, progressFn func(deleted, total int)) ([]string, error) {
+	return nil, nil
+}
+
+// CreateGroups records the names of the backup's groups so CreateContacts can
+// write them as CATEGORIES. Returns a map of group resource names to names.
+func (c *Client) CreateGroups(ctx context.Context, groups []*people.ContactGroup, progressFn func(created, total int)) (map[string]string, error) {
+	groupMap := make(map[string]string, len(groups))
+	for i, group := range groups {
+		groupMap[group.ResourceName] = group.Name
+		if progressFn != nil {
+			progressFn(i+1, len(groups))
+		}
+	}
+	return groupMap, nil
+}
+
+// CreateContacts uploads each contact as a vCard, labelled with the group
+// names from groupMap (as returned by CreateGroups).
+// Returns a map of original resource names to the hrefs of the new cards.
+// The progressFn callback is called with (created, total) after each card.
+func (c *Client) CreateContacts(ctx context.Context, contacts []*people.Person, groupMap map[string]string, progressFn func(created, total int)) (map[string]string, error) {
+	created := make(map[string]string, len(contacts))
+
+	for i, contact := range contacts {
+		name, err := randomName()
+		if err != nil {
+			return created, err
+		}
+
+		// CardDAV servers require a UID; contacts without a resource name get
+		// the card's name instead
+		card := *contact
+		if card.ResourceName == "" {
+			card.ResourceName = name
+		}
+		body := models.ContactVCard(&card, groupMap, models.VCardOptions{PlainTextNotes: true, Keys: true})
+
+		href := name + ".vcf"
+		resp, err := c.do(ctx, http.MethodPut, href, []byte(body), http.Header{
+			"Content-Type":  {"text/vcard; charset=utf-8"},
+			"If-None-Match": {"*"},
+		})
+		if err != nil {
+			return created, fmt.Errorf("failed to create contact %d (%s): %w", i, models.DisplayName(contact), err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if contact.ResourceName != "" {
+			created[contact.ResourceName] = href
+		}
+
+		if progressFn != nil {
+			progressFn(i+1, len(contacts))
+		}
+	}
+
+	return created, nil
+}
+
+// randomName returns a random card name that will not collide with existing cards
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate card name: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
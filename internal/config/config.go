@@ -0,0 +1,292 @@
+// Package config loads user settings for google-contacts-backup.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mheap/google-contacts-backup/internal/ignore"
+	"github.com/mheap/google-contacts-backup/internal/policy"
+)
+
+const (
+	// appDir is the directory name used under the user's config directory
+	appDir = "google-contacts-backup"
+	// configFile is the filename of the settings file
+	configFile = "config.json"
+)
+
+// Config holds user settings loaded from the config file.
+type Config struct {
+	// Language of prompts and summaries (e.g. "de"), unless --lang is given
+	Language string `json:"language,omitempty"`
+
+	// ReadOnly disables every change to contacts and groups, as --read-only
+	// does, e.g. on a machine that only takes backups
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// FrozenFields lists person fields (People API names such as "biographies")
+	// that merge restores and syncs must never overwrite or delete
+	FrozenFields []string `json:"frozen_fields,omitempty"`
+
+	// Ignore lists contacts that are never backed up, exported or restored
+	Ignore ignore.List `json:"ignore,omitzero"`
+
+	// Webhooks are notified when backups and restores finish
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+
+	// Backup configures where and how often backups are taken
+	Backup Backup `json:"backup,omitzero"`
+
+	// Restore configures the restore command
+	Restore Restore `json:"restore,omitzero"`
+
+	// Prune configures which backups the prune command keeps
+	Prune Prune `json:"prune,omitzero"`
+
+	// Share configures where the share command uploads exports
+	Share Share `json:"share,omitzero"`
+
+	// Upload configures where the upload command stores backups
+	Upload Upload `json:"upload,omitzero"`
+
+	// Encryption configures how backups are encrypted and decrypted
+	Encryption Encryption `json:"encryption,omitzero"`
+
+	// Verify configures the content policies enforced by verify
+	Verify Verify `json:"verify,omitzero"`
+
+	// Trend configures which changes in the size of the address book
+	// backups and 'report trend' flag as unusual
+	Trend Trend `json:"trend,omitzero"`
+
+	// Approval requires a second person to approve operations that delete
+	// contacts from the account
+	Approval Approval `json:"approval,omitzero"`
+}
+
+// Approval configures the two-person rule for destructive operations. If
+// any key or secret is set, replace restores and 'cleanup empty' on the live
+// account only run with an approval file signed by one of the keys or with
+// a valid TOTP code.
+type Approval struct {
+	// PublicKeys are the approvers' public keys ("ed25519:..."), as printed
+	// by 'approve keygen'
+	PublicKeys []string `json:"public_keys,omitempty"`
+
+	// TOTPSecret is the base32 secret shared with the approver's
+	// authenticator app. TOTPSecretEnv names an environment variable
+	// holding the secret instead, to keep it out of the file.
+	TOTPSecret    string `json:"totp_secret,omitempty"`
+	TOTPSecretEnv string `json:"totp_secret_env,omitempty"`
+}
+
+// Required reports whether destructive operations need an approval.
+func (a Approval) Required() bool {
+	return len(a.PublicKeys) > 0 || a.TOTPSecret != "" || a.TOTPSecretEnv != ""
+}
+
+// Secret returns the TOTP secret, resolving TOTPSecretEnv if set.
+func (a Approval) Secret() string {
+	if a.TOTPSecretEnv != "" {
+		return os.Getenv(a.TOTPSecretEnv)
+	}
+	return a.TOTPSecret
+}
+
+// Verify holds data quality rules that backups must satisfy.
+type Verify struct {
+	// MinContacts fails verification if a backup holds fewer contacts,
+	// catching truncated or empty backups
+	MinContacts int `json:"min_contacts,omitempty"`
+
+	// Policies limit how many contacts may fail each content check
+	Policies []policy.Rule `json:"policies,omitempty"`
+}
+
+// Trend holds the thresholds for unusual changes in the number of contacts
+// from one backup to the next. Zero means the default.
+type Trend struct {
+	// MaxDropPercent is the share of contacts the address book may lose
+	// (default 10)
+	MaxDropPercent float64 `json:"max_drop_percent,omitempty"`
+
+	// MaxGrowthPercent is the share of contacts it may gain (default 25)
+	MaxGrowthPercent float64 `json:"max_growth_percent,omitempty"`
+
+	// MinChange is the smallest change in contacts flagged (default 10)
+	MinChange int `json:"min_change,omitempty"`
+}
+
+// Encryption holds default age keys for encrypted backups.
+type Encryption struct {
+	// Recipients encrypt every backup unless --recipient is given. Each is
+	// an age public key, a plugin recipient (e.g. age1yubikey1...) or the
+	// path of a recipients file.
+	Recipients []string `json:"recipients,omitempty"`
+
+	// Identities are identity files used to decrypt backups unless
+	// --identity is given
+	Identities []string `json:"identities,omitempty"`
+}
+
+// Backup holds defaults for the backup command.
+type Backup struct {
+	// Directory receives backups written under their default file name
+	Directory string `json:"directory,omitempty"`
+
+	// Schedule is how often 'daemon install' runs backups: "hourly",
+	// "daily" or "weekly". Empty means backups are only taken by hand.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Compress is "gzip" or "zstd" to compress backups, or empty for none
+	Compress string `json:"compress,omitempty"`
+
+	// AssignUUIDs gives contacts a stable UUID in their clientData the
+	// first time they are backed up
+	AssignUUIDs bool `json:"assign_uuids,omitempty"`
+
+	// StableOutput writes backups in a canonical order, so backups of an
+	// unchanged account are identical
+	StableOutput bool `json:"stable_output,omitempty"`
+
+	// Upload is an s3://bucket/prefix or gs://bucket/prefix URL every
+	// backup is uploaded to after it is written, locked as configured in
+	// the upload section. Empty keeps backups local.
+	Upload string `json:"upload,omitempty"`
+}
+
+// Restore holds defaults for the restore command.
+type Restore struct {
+	// Journal is a file every restore appends its progress to, batch by
+	// batch, unless --journal is given
+	Journal string `json:"journal,omitempty"`
+
+	// DailyWriteQuota is the number of People API write requests the Google
+	// Cloud project may send per day, if known. Restores warn before they
+	// would exceed what is left of it today. Zero means unknown.
+	DailyWriteQuota int `json:"daily_write_quota,omitempty"`
+
+	// ConfirmPhrase is what a replace restore asks to be typed to confirm,
+	// instead of yes. {count} stands for the number of contacts it deletes,
+	// e.g. "DELETE {count} CONTACTS".
+	ConfirmPhrase string `json:"confirm_phrase,omitempty"`
+
+	// MaxUnattendedDelete is the most contacts a replace restore deletes
+	// with --confirm alone; above it, --i-understand-data-loss is needed
+	// as well. Zero means no limit.
+	MaxUnattendedDelete int `json:"max_unattended_delete,omitempty"`
+}
+
+// Prune holds the retention policy of the prune command. Each rule keeps
+// the newest backup of that many periods; zero disables the rule.
+type Prune struct {
+	KeepLast    int `json:"keep_last,omitempty"`
+	KeepDaily   int `json:"keep_daily,omitempty"`
+	KeepWeekly  int `json:"keep_weekly,omitempty"`
+	KeepMonthly int `json:"keep_monthly,omitempty"`
+}
+
+// Share holds defaults for the share command.
+type Share struct {
+	// Destination is an s3://bucket/prefix or gs://bucket/prefix URL
+	Destination string `json:"destination,omitempty"`
+
+	// Expires is how long share links stay valid, as a Go duration (e.g. "24h")
+	Expires string `json:"expires,omitempty"`
+}
+
+// Upload holds defaults for the upload command.
+type Upload struct {
+	// Destination is an s3://bucket/prefix or gs://bucket/prefix URL
+	Destination string `json:"destination,omitempty"`
+
+	// RetainDays locks uploaded backups for this many days (S3 and
+	// S3-compatible stores with object lock enabled). Zero disables it.
+	RetainDays int `json:"retain_days,omitempty"`
+
+	// RetentionMode is "governance" or "compliance" (the default)
+	RetentionMode string `json:"retention_mode,omitempty"`
+}
+
+// Webhook is an HTTP endpoint that receives event notifications.
+type Webhook struct {
+	// URL receives a POST with the JSON event payload
+	URL string `json:"url"`
+
+	// Secret signs payloads with HMAC-SHA256. SecretEnv names an environment
+	// variable holding the secret instead, to keep it out of the file.
+	Secret    string `json:"secret,omitempty"`
+	SecretEnv string `json:"secret_env,omitempty"`
+
+	// Events limits delivery to these event types (e.g. "backup.completed").
+	// Empty means all events.
+	Events []string `json:"events,omitempty"`
+}
+
+// SigningSecret returns the webhook's secret, resolving SecretEnv if set.
+func (w Webhook) SigningSecret() string {
+	if w.SecretEnv != "" {
+		return os.Getenv(w.SecretEnv)
+	}
+	return w.Secret
+}
+
+// Dir returns the configuration directory, using XDG_CONFIG_HOME if set,
+// otherwise ~/.config.
+func Dir() string {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			// Fallback to current directory if we can't get home
+			return "."
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configDir, appDir)
+}
+
+// DefaultPath returns the default path of the config file.
+func DefaultPath() string {
+	return filepath.Join(Dir(), configFile)
+}
+
+// Load reads the config file at path. A missing file is not an error and
+// results in an empty configuration.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Save writes the config to path, creating the directory if needed.
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
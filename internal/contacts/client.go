@@ -3,18 +3,28 @@ package contacts
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/people/v1"
 )
 
 const (
 	// personFields is the list of fields to request for each contact
-	personFields = "names,emailAddresses,phoneNumbers,addresses,organizations,birthdays,biographies,urls,photos,userDefined,events,relations,memberships,nicknames,occupations,genders,imClients,interests,sipAddresses,calendarUrls,externalIds,locales,locations,miscKeywords,clientData"
+	personFields = "names,emailAddresses,phoneNumbers,addresses,organizations,birthdays,biographies,urls,photos,userDefined,events,relations,memberships,nicknames,occupations,genders,imClients,interests,sipAddresses,calendarUrls,externalIds,locales,locations,miscKeywords,clientData,metadata"
+
+	// otherContactFields is the list of fields "Other contacts" support
+	otherContactFields = "names,emailAddresses,phoneNumbers,photos,metadata"
 
 	// maxPageSize is the maximum number of contacts per page
 	maxPageSize = 1000
@@ -22,38 +32,383 @@ const (
 	// batchDeleteSize is the maximum number of contacts to delete in one batch
 	batchDeleteSize = 500
 
-	// batchCreateSize is the maximum number of contacts to create in one batch
-	batchCreateSize = 200
+	// BatchCreateSize is the maximum number of contacts to create in one batch
+	BatchCreateSize = 200
+
+	// batchGetSize is the maximum number of contacts to fetch in one batch
+	batchGetSize = 200
+
+	// batchUpdateSize is the maximum number of contacts to update in one batch
+	batchUpdateSize = 200
+
+	// batchMemberSize is the maximum number of contacts to add to or remove
+	// from a contact group in one request
+	batchMemberSize = 1000
+
+	// groupCreateConcurrency is the number of contact groups created at once
+	groupCreateConcurrency = 4
+
+	// defaultRequestsPerSecond is the default request rate, well below the
+	// People API's per-minute limits
+	defaultRequestsPerSecond = 10
 
-	// rateLimitDelay is the delay between API calls to avoid rate limiting
-	rateLimitDelay = 100 * time.Millisecond
+	// maxPhotoSize is the largest photo DownloadPhoto accepts
+	maxPhotoSize = 10 << 20
+
+	// maxAttempts is how often a request is tried when rate limited
+	maxAttempts = 6
+
+	// initialBackoff and maxBackoff bound the delay between rate-limited attempts
+	initialBackoff = 2 * time.Second
+	maxBackoff     = time.Minute
 )
 
+// WritableFields lists the person fields the People API accepts when creating
+// or updating a contact. All other fields are read-only and cannot be restored.
+var WritableFields = []string{
+	"addresses", "biographies", "birthdays", "calendarUrls", "clientData",
+	"emailAddresses", "events", "externalIds", "genders", "imClients",
+	"interests", "locales", "locations", "memberships", "miscKeywords",
+	"names", "nicknames", "occupations", "organizations", "phoneNumbers",
+	"relations", "sipAddresses", "urls", "userDefined",
+}
+
 // Client wraps the Google People API service.
+//
+// A Client is safe for concurrent use by multiple goroutines. Its settings are
+// fixed when it is created, and the only state it changes afterwards is that
+// of its rate limiter, which every list, create, delete and photo request
+// goes through: running operations in parallel does not raise the request
+// rate above the configured limit. Operations never modify the contacts and
+// groups passed to them.
 type Client struct {
-	service *people.Service
+	service    *people.Service
+	httpClient *http.Client
+
+	// limiter paces every request the client sends
+	limiter RateLimiter
+
+	// createBatchSize is the number of contacts created per request
+	createBatchSize int
+
+	// createLimiter additionally paces contact creation requests, if set
+	createLimiter RateLimiter
+
+	// onRetry is called before a rate-limited request is retried
+	onRetry func(attempt int, delay time.Duration, err error)
+
+	// onBatch is called after every create or update batch
+	onBatch func(batch Batch)
+
+	// onWarning is called with problems an operation skips over
+	onWarning func(msg string)
+
+	// readOnly makes every operation that changes the account fail
+	readOnly bool
+
+	// readMask is the person fields read when listing or fetching contacts
+	readMask string
+}
+
+// Batch describes one create or update request sent by CreateContacts or
+// UpdateContacts, successful or not.
+type Batch struct {
+	// Operation is "create" or "update"
+	Operation string
+
+	// Index is the zero-based batch index within the operation
+	Index int
+
+	// Contacts are the resource names the contacts had in the input, in
+	// batch order. Contacts without one are reported as empty strings.
+	Contacts []string
+
+	// Created are the resource names of the contacts created by the batch
+	Created []string
+
+	// Duration is how long the request took, including retries
+	Duration time.Duration
+
+	// Err is the error that stopped the batch, or nil
+	Err error
+}
+
+// Option configures optional Client behaviour.
+type Option func(*Client)
+
+// WithMaxRequestsPerMinute caps the number of API requests the client issues
+// per minute across all its operations. Values <= 0, or above the default
+// rate, leave the default pacing. To cap several clients together, share one
+// limiter between them with WithRateLimiter.
+func WithMaxRequestsPerMinute(n int) Option {
+	return func(c *Client) {
+		if n <= 0 || n >= defaultRequestsPerSecond*60 {
+			return
+		}
+		c.limiter = NewAdaptiveLimiter(float64(n)/60, 1)
+	}
+}
+
+// WithRateLimit sets the client's request rate, in requests per second,
+// replacing the default of 10. The client still slows down when the API
+// reports rate limit errors. Values <= 0 leave the default rate.
+func WithRateLimit(perSecond float64) Option {
+	return func(c *Client) {
+		if perSecond <= 0 {
+			return
+		}
+		c.limiter = NewAdaptiveLimiter(perSecond, 1)
+	}
+}
+
+// RequestRate returns the request rate, in requests per second, of a client
+// created with WithRateLimit(perSecond) and WithMaxRequestsPerMinute(perMinute),
+// before any slowdown caused by rate limit errors.
+func RequestRate(perSecond float64, perMinute int) float64 {
+	switch {
+	case perMinute > 0 && perMinute < defaultRequestsPerSecond*60:
+		return float64(perMinute) / 60
+	case perSecond > 0:
+		return perSecond
+	}
+	return defaultRequestsPerSecond
+}
+
+// WithRateLimiter replaces the client's rate limiter. The limiter may be
+// shared with other clients to give them a common budget.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithTrickle makes CreateContacts create one contact per request, waiting
+// interval between requests. Other operations keep their normal pacing.
+func WithTrickle(interval time.Duration) Option {
+	return func(c *Client) {
+		if interval <= 0 {
+			return
+		}
+		c.createBatchSize = 1
+		c.createLimiter = NewTokenBucket(float64(time.Second)/float64(interval), 1)
+	}
+}
+
+// WithRetryHandler registers fn to be called before a request that hit a
+// per-minute rate limit is retried, so callers can tell the user why the
+// operation is paused.
+func WithRetryHandler(fn func(attempt int, delay time.Duration, err error)) Option {
+	return func(c *Client) {
+		c.onRetry = fn
+	}
+}
+
+// WithWarningHandler registers fn to be called with a message for every
+// problem an operation skips over instead of failing, such as a photo or
+// group that could not be restored or deleted. Without a handler, these are
+// only reported through the operation's return values.
+func WithWarningHandler(fn func(msg string)) Option {
+	return func(c *Client) {
+		c.onWarning = fn
+	}
+}
+
+// warn reports a skipped problem to the warning handler, if any
+func (c *Client) warn(format string, args ...any) {
+	if c.onWarning != nil {
+		c.onWarning(fmt.Sprintf(format, args...))
+	}
+}
+
+// WithBatchHandler registers fn to be called after every batch of contacts
+// is created or updated, so callers can record exactly how far a long
+// operation got. fn runs on the calling goroutine before the next batch.
+// Handlers registered by several options are called in order.
+func WithBatchHandler(fn func(batch Batch)) Option {
+	return func(c *Client) {
+		previous := c.onBatch
+		if previous == nil {
+			c.onBatch = fn
+			return
+		}
+		c.onBatch = func(batch Batch) {
+			previous(batch)
+			fn(batch)
+		}
+	}
+}
+
+// WithoutPacing removes the delay between requests. It is intended for
+// in-process fakes of the API, never for Google itself.
+func WithoutPacing() Option {
+	return func(c *Client) {
+		c.limiter = unlimited{}
+	}
+}
+
+// WithPersonFields limits the person fields read when listing or fetching
+// contacts to the given People API names, which must be among
+// PersonFields. metadata is always read, as it identifies contacts and their
+// sources. An empty list reads every field.
+func WithPersonFields(fields []string) Option {
+	return func(c *Client) {
+		if len(fields) == 0 {
+			return
+		}
+		if !slices.Contains(fields, "metadata") {
+			fields = append(slices.Clip(fields), "metadata")
+		}
+		c.readMask = strings.Join(fields, ",")
+	}
+}
+
+// PersonFields returns the person fields the client reads by default: every
+// field a backup holds.
+func PersonFields() []string {
+	return strings.Split(personFields, ",")
 }
 
 // NewClient creates a new People API client.
-func NewClient(ctx context.Context, httpClient *http.Client) (*Client, error) {
-	service, err := people.NewService(ctx, option.WithHTTPClient(httpClient))
+func NewClient(ctx context.Context, httpClient *http.Client, opts ...Option) (*Client, error) {
+	c := &Client{
+		httpClient:      httpClient,
+		limiter:         NewAdaptiveLimiter(defaultRequestsPerSecond, 1),
+		createBatchSize: BatchCreateSize,
+		readMask:        personFields,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.readOnly {
+		c.httpClient = readOnlyHTTPClient(httpClient)
+	}
+
+	service, err := people.NewService(ctx, option.WithHTTPClient(c.httpClient))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create People API service: %w", err)
 	}
+	c.service = service
+
+	return c, nil
+}
+
+// CreateBatchSize returns the number of contacts CreateContacts sends per request.
+func (c *Client) CreateBatchSize() int {
+	return c.createBatchSize
+}
+
+// execute runs an API call within the client's request budget. Per-minute
+// rate limit errors are retried with exponential backoff, and slow down an
+// adaptive rate limiter; daily quota errors are returned immediately as a
+// *QuotaError since retrying cannot succeed before the quota resets.
+func execute[T any](ctx context.Context, c *Client, do func(...googleapi.CallOption) (T, error)) (T, error) {
+	adaptive, _ := c.limiter.(AdaptiveRateLimiter)
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			var zero T
+			return zero, err
+		}
+
+		result, err := do()
+		if err == nil {
+			if adaptive != nil {
+				adaptive.Succeeded()
+			}
+			return result, nil
+		}
+
+		qe := asQuotaError(err)
+		if qe == nil {
+			return result, err
+		}
+		if qe.Daily || attempt >= maxAttempts {
+			return result, qe
+		}
+		if adaptive != nil {
+			adaptive.Throttled()
+		}
+
+		delay := backoff
+		if qe.RetryAfter > delay {
+			delay = qe.RetryAfter
+		}
+		if c.onRetry != nil {
+			c.onRetry(attempt, delay, qe)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return result, err
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
 
-	return &Client{service: service}, nil
+// sleep pauses for d or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // ListContacts retrieves all contacts with pagination.
 // The progressFn callback is called with (current, total) after each page.
 func (c *Client) ListContacts(ctx context.Context, progressFn func(current, total int)) ([]*people.Person, error) {
 	var allContacts []*people.Person
-	var pageToken string
 	totalCount := 0
 
+	err := c.ListContactPages(ctx, "", func(page ContactPage) error {
+		// Update total count from first response
+		if totalCount == 0 && page.TotalPeople > 0 {
+			totalCount = page.TotalPeople
+		}
+
+		allContacts = append(allContacts, page.Contacts...)
+
+		if progressFn != nil {
+			progressFn(len(allContacts), totalCount)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allContacts, nil
+}
+
+// ContactPage is one page of contacts listed by ListContactPages.
+type ContactPage struct {
+	Contacts []*people.Person
+
+	// NextPageToken continues the listing after this page. It is empty on
+	// the last page.
+	NextPageToken string
+
+	// TotalPeople is the number of contacts in the account, as reported by
+	// the API
+	TotalPeople int
+}
+
+// ListContactPages lists contacts page by page, starting at pageToken, or
+// at the first page if it is empty, and passes each page to fn. Saving each
+// page with its NextPageToken lets an interrupted listing continue where it
+// stopped. Listing stops at the first error returned by fn.
+func (c *Client) ListContactPages(ctx context.Context, pageToken string, fn func(page ContactPage) error) error {
 	for {
 		call := c.service.People.Connections.List("people/me").
-			PersonFields(personFields).
+			PersonFields(c.readMask).
 			PageSize(maxPageSize).
 			Context(ctx)
 
@@ -61,33 +416,178 @@ func (c *Client) ListContacts(ctx context.Context, progressFn func(current, tota
 			call = call.PageToken(pageToken)
 		}
 
-		resp, err := call.Do()
+		resp, err := execute(ctx, c, call.Do)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list contacts: %w", err)
+			return fmt.Errorf("failed to list contacts: %w", err)
 		}
 
-		// Update total count from first response
-		if totalCount == 0 && resp.TotalPeople > 0 {
-			totalCount = int(resp.TotalPeople)
+		page := ContactPage{
+			Contacts:      resp.Connections,
+			NextPageToken: resp.NextPageToken,
+			TotalPeople:   int(resp.TotalPeople),
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+
+		pageToken = resp.NextPageToken
+		if pageToken == "" {
+			return nil
+		}
+	}
+}
+
+// ListOtherContacts retrieves all "Other contacts": people Gmail saved
+// automatically after you interacted with them, which are not part of your
+// contacts. They only carry names, email addresses, phone numbers and photos.
+// The progressFn callback is called with (current, total) after each page.
+func (c *Client) ListOtherContacts(ctx context.Context, progressFn func(current, total int)) ([]*people.Person, error) {
+	var allContacts []*people.Person
+	var pageToken string
+
+	for {
+		call := c.service.OtherContacts.List().
+			ReadMask(otherContactFields).
+			PageSize(maxPageSize).
+			Context(ctx)
+
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
 		}
 
-		allContacts = append(allContacts, resp.Connections...)
+		resp, err := execute(ctx, c, call.Do)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list other contacts: %w", err)
+		}
+
+		allContacts = append(allContacts, resp.OtherContacts...)
 
 		if progressFn != nil {
-			progressFn(len(allContacts), totalCount)
+			progressFn(len(allContacts), int(resp.TotalSize))
 		}
 
 		pageToken = resp.NextPageToken
 		if pageToken == "" {
 			break
 		}
-
-		time.Sleep(rateLimitDelay)
 	}
 
 	return allContacts, nil
 }
 
+// CountContacts returns the number of contacts in the account by requesting
+// a single, minimal page and reading the reported total.
+func (c *Client) CountContacts(ctx context.Context) (int, error) {
+	resp, err := execute(ctx, c, c.service.People.Connections.List("people/me").
+		PersonFields("metadata").
+		PageSize(1).
+		Fields("totalItems", "totalPeople").
+		Context(ctx).
+		Do)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count contacts: %w", err)
+	}
+
+	if resp.TotalItems > 0 {
+		return int(resp.TotalItems), nil
+	}
+	return int(resp.TotalPeople), nil
+}
+
+// Me returns the profile of the signed-in account. Its resource name,
+// people/ followed by the account's ID, is always set; names and email
+// addresses are only included when the granted scopes allow Google to share
+// them.
+func (c *Client) Me(ctx context.Context) (*people.Person, error) {
+	person, err := execute(ctx, c, c.service.People.Get("people/me").
+		PersonFields("metadata,names,emailAddresses").
+		Context(ctx).
+		Do)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the signed-in account: %w", err)
+	}
+	return person, nil
+}
+
+// searchPageSize is the most results people.searchContacts returns
+const searchPageSize = 30
+
+// SearchContacts returns the contacts whose names, nicknames, email
+// addresses, phone numbers or organizations start with words of query, as
+// Google's contact search matches them, up to 30 of them. Google caches the
+// index it searches; an empty search is sent first to refresh it, as the
+// API documentation asks.
+func (c *Client) SearchContacts(ctx context.Context, query string) ([]*people.Person, error) {
+	if _, err := execute(ctx, c, c.service.People.SearchContacts().
+		Query("").
+		ReadMask("metadata").
+		Context(ctx).
+		Do); err != nil {
+		return nil, fmt.Errorf("failed to search contacts: %w", err)
+	}
+
+	resp, err := execute(ctx, c, c.service.People.SearchContacts().
+		Query(query).
+		ReadMask(c.readMask).
+		PageSize(searchPageSize).
+		Context(ctx).
+		Do)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search contacts: %w", err)
+	}
+	found := make([]*people.Person, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		if result.Person != nil {
+			found = append(found, result.Person)
+		}
+	}
+	return found, nil
+}
+
+// GetContacts fetches the given contacts by resource name in batches.
+// Resource names that could not be fetched are returned in the missing slice
+// rather than failing the whole call.
+// The progressFn callback is called with (fetched, total) after each batch.
+func (c *Client) GetContacts(ctx context.Context, resourceNames []string, progressFn func(fetched, total int)) ([]*people.Person, []string, error) {
+	var found []*people.Person
+	var missing []string
+	totalContacts := len(resourceNames)
+	fetched := 0
+
+	for i := 0; i < len(resourceNames); i += batchGetSize {
+		end := i + batchGetSize
+		if end > len(resourceNames) {
+			end = len(resourceNames)
+		}
+
+		batch := resourceNames[i:end]
+
+		resp, err := execute(ctx, c, c.service.People.GetBatchGet().
+			ResourceNames(batch...).
+			PersonFields(c.readMask).
+			Context(ctx).
+			Do)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get contacts batch: %w", err)
+		}
+
+		for _, r := range resp.Responses {
+			if r.Person == nil || (r.Status != nil && r.Status.Code != 0) {
+				missing = append(missing, r.RequestedResourceName)
+				continue
+			}
+			found = append(found, r.Person)
+		}
+
+		fetched += len(batch)
+		if progressFn != nil {
+			progressFn(fetched, totalContacts)
+		}
+	}
+
+	return found, missing, nil
+}
+
 // ListGroups retrieves all contact groups.
 func (c *Client) ListGroups(ctx context.Context) ([]*people.ContactGroup, error) {
 	var allGroups []*people.ContactGroup
@@ -102,7 +602,7 @@ func (c *Client) ListGroups(ctx context.Context) ([]*people.ContactGroup, error)
 			call = call.PageToken(pageToken)
 		}
 
-		resp, err := call.Do()
+		resp, err := execute(ctx, c, call.Do)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list contact groups: %w", err)
 		}
@@ -113,16 +613,122 @@ func (c *Client) ListGroups(ctx context.Context) ([]*people.ContactGroup, error)
 		if pageToken == "" {
 			break
 		}
-
-		time.Sleep(rateLimitDelay)
 	}
 
 	return allGroups, nil
 }
 
+// GetGroupMembers fetches the member resource names of each user contact group.
+// Returns a map of group resource names to member resource names.
+// The progressFn callback is called with (fetched, total) after each group.
+func (c *Client) GetGroupMembers(ctx context.Context, groups []*people.ContactGroup, progressFn func(fetched, total int)) (map[string][]string, error) {
+	members := make(map[string][]string)
+
+	var userGroups []*people.ContactGroup
+	for _, group := range groups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			userGroups = append(userGroups, group)
+		}
+	}
+
+	for i, group := range userGroups {
+		if group.MemberCount > 0 {
+			full, err := execute(ctx, c, c.service.ContactGroups.Get(group.ResourceName).
+				MaxMembers(group.MemberCount).
+				Context(ctx).
+				Do)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get members of group %s: %w", group.Name, err)
+			}
+
+			members[group.ResourceName] = full.MemberResourceNames
+		} else {
+			members[group.ResourceName] = []string{}
+		}
+
+		if progressFn != nil {
+			progressFn(i+1, len(userGroups))
+		}
+	}
+
+	return members, nil
+}
+
+// DownloadPhoto fetches the image at a contact or profile photo URL.
+// Returns the image bytes and their content type.
+func (c *Client) DownloadPhoto(ctx context.Context, url string) ([]byte, string, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create photo request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download photo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download photo: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPhotoSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read photo: %w", err)
+	}
+	if len(data) > maxPhotoSize {
+		return nil, "", fmt.Errorf("photo is larger than %d bytes", maxPhotoSize)
+	}
+
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// RestorePhotos uploads photos as the contact photos of existing contacts,
+// one request per contact, in resource name order. photos maps resource
+// names of the contacts to update to the raw image bytes.
+// Photos that fail to upload are reported to the warning handler and
+// returned by resource name; only cancellation and daily quota errors stop the upload.
+// The progressFn callback is called with (done, total) after each photo.
+func (c *Client) RestorePhotos(ctx context.Context, photos map[string][]byte, progressFn func(done, total int)) ([]string, error) {
+	if err := c.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	resourceNames := slices.Sorted(maps.Keys(photos))
+	var skipped []string
+
+	for i, resourceName := range resourceNames {
+		_, err := execute(ctx, c, c.service.People.UpdateContactPhoto(resourceName, &people.UpdateContactPhotoRequest{
+			PhotoBytes: base64.StdEncoding.EncodeToString(photos[resourceName]),
+		}).Context(ctx).Do)
+
+		if qe := asQuotaError(err); (qe != nil && qe.Daily) || ctx.Err() != nil {
+			return skipped, fmt.Errorf("failed to restore photo of %s: %w", resourceName, err)
+		}
+		if err != nil {
+			c.warn("failed to restore photo of %s: %v", resourceName, err)
+			skipped = append(skipped, resourceName)
+		}
+
+		if progressFn != nil {
+			progressFn(i+1, len(resourceNames))
+		}
+	}
+
+	return skipped, nil
+}
+
 // DeleteAllContacts deletes all contacts in batches.
 // The progressFn callback is called with (deleted, total) after each batch.
 func (c *Client) DeleteAllContacts(ctx context.Context, progressFn func(deleted, total int)) error {
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+
 	// First, get all contact resource names
 	contacts, err := c.ListContacts(ctx, nil)
 	if err != nil {
@@ -133,8 +739,6 @@ func (c *Client) DeleteAllContacts(ctx context.Context, progressFn func(deleted,
 		return nil
 	}
 
-	totalContacts := len(contacts)
-
 	// Extract resource names
 	resourceNames := make([]string, 0, len(contacts))
 	for _, contact := range contacts {
@@ -143,6 +747,16 @@ func (c *Client) DeleteAllContacts(ctx context.Context, progressFn func(deleted,
 		}
 	}
 
+	return c.DeleteContacts(ctx, resourceNames, progressFn)
+}
+
+// DeleteContacts deletes the given contacts by resource name in batches.
+// The progressFn callback is called with (deleted, total) after each batch.
+func (c *Client) DeleteContacts(ctx context.Context, resourceNames []string, progressFn func(deleted, total int)) error {
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+
 	// Delete in batches
 	deleted := 0
 	for i := 0; i < len(resourceNames); i += batchDeleteSize {
@@ -157,28 +771,43 @@ func (c *Client) DeleteAllContacts(ctx context.Context, progressFn func(deleted,
 			ResourceNames: batch,
 		}
 
-		_, err := c.service.People.BatchDeleteContacts(req).Context(ctx).Do()
+		_, err := execute(ctx, c, c.service.People.BatchDeleteContacts(req).Context(ctx).Do)
 		if err != nil {
 			return fmt.Errorf("failed to delete contacts batch: %w", err)
 		}
 
 		deleted += len(batch)
 		if progressFn != nil {
-			progressFn(deleted, totalContacts)
+			progressFn(deleted, len(resourceNames))
 		}
-
-		time.Sleep(rateLimitDelay)
 	}
 
 	return nil
 }
 
 // DeleteUserGroups deletes all user-created contact groups.
+// Groups that fail to delete are skipped with a warning and returned by name.
 // The progressFn callback is called with (deleted, total) after each deletion.
-func (c *Client) DeleteUserGroups(ctx context.Context, progressFn func(deleted, total int)) error {
+func (c *Client) DeleteUserGroups(ctx context.Context, progressFn func(deleted, total int)) ([]string, error) {
+	if err := c.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	groups, err := c.ListGroups(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return c.DeleteGroups(ctx, groups, progressFn)
+}
+
+// DeleteGroups deletes the user-created groups among groups; system groups
+// are ignored. Contacts in the groups are kept. Groups that fail to delete
+// are reported to the warning handler and returned by name.
+// The progressFn callback is called with (deleted, total) after each deletion.
+func (c *Client) DeleteGroups(ctx context.Context, groups []*people.ContactGroup, progressFn func(deleted, total int)) ([]string, error) {
+	if err := c.checkWritable(); err != nil {
+		return nil, err
 	}
 
 	// Filter to only user-created groups
@@ -190,21 +819,26 @@ func (c *Client) DeleteUserGroups(ctx context.Context, progressFn func(deleted,
 	}
 
 	if len(userGroups) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	totalGroups := len(userGroups)
 	deleted := 0
+	var skipped []string
 
 	for _, group := range userGroups {
-		_, err := c.service.ContactGroups.Delete(group.ResourceName).
+		_, err := execute(ctx, c, c.service.ContactGroups.Delete(group.ResourceName).
 			DeleteContacts(false). // Don't delete contacts, just the group
 			Context(ctx).
-			Do()
+			Do)
 
+		if qe := asQuotaError(err); (qe != nil && qe.Daily) || ctx.Err() != nil {
+			return skipped, err
+		}
 		if err != nil {
-			// Log warning but continue with other groups
-			fmt.Printf("Warning: failed to delete group %s: %v\n", group.Name, err)
+			// Report it but continue with other groups
+			c.warn("failed to delete group %s: %v", group.Name, err)
+			skipped = append(skipped, group.Name)
 		} else {
 			deleted++
 		}
@@ -212,64 +846,191 @@ func (c *Client) DeleteUserGroups(ctx context.Context, progressFn func(deleted,
 		if progressFn != nil {
 			progressFn(deleted, totalGroups)
 		}
+	}
 
-		time.Sleep(rateLimitDelay)
+	return skipped, nil
+}
+
+// ModifyGroupMembers adds contacts to and removes contacts from a contact
+// group by resource name, in batches. Contacts are only ever added to or
+// removed from the group; the contacts themselves are not changed.
+// The progressFn callback is called with (modified, total) after each batch.
+func (c *Client) ModifyGroupMembers(ctx context.Context, group string, add, remove []string, progressFn func(modified, total int)) error {
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+
+	total := len(add) + len(remove)
+	modified := 0
+	for len(add) > 0 || len(remove) > 0 {
+		req := &people.ModifyContactGroupMembersRequest{}
+		n := min(len(add), batchMemberSize)
+		req.ResourceNamesToAdd, add = add[:n], add[n:]
+		n = min(len(remove), batchMemberSize-len(req.ResourceNamesToAdd))
+		req.ResourceNamesToRemove, remove = remove[:n], remove[n:]
+
+		resp, err := execute(ctx, c, c.service.ContactGroups.Members.Modify(group, req).Context(ctx).Do)
+		if err != nil {
+			return fmt.Errorf("failed to modify members of group %s: %w", group, err)
+		}
+		if len(resp.NotFoundResourceNames) > 0 {
+			return fmt.Errorf("failed to modify members of group %s: contacts not found: %s", group, strings.Join(resp.NotFoundResourceNames, ", "))
+		}
+
+		modified += len(req.ResourceNamesToAdd) + len(req.ResourceNamesToRemove)
+		if progressFn != nil {
+			progressFn(modified, total)
+		}
 	}
 
 	return nil
 }
 
-// CreateGroups creates contact groups from the backup.
+// CreateGroups creates the user contact groups among groups, up to
+// groupCreateConcurrency at a time, started in the order given. A group whose
+// name is already taken in the account, such as a leftover group a restore
+// could not delete, is looked up and reused instead of failing the restore,
+// and reported to the warning handler.
 // Returns a map of old resource names to new resource names.
 func (c *Client) CreateGroups(ctx context.Context, groups []*people.ContactGroup, progressFn func(created, total int)) (map[string]string, error) {
-	resourceNameMap := make(map[string]string)
-	totalGroups := len(groups)
-	created := 0
+	if err := c.checkWritable(); err != nil {
+		return nil, err
+	}
 
+	var userGroups []*people.ContactGroup
 	for _, group := range groups {
 		// Only create user contact groups
-		if group.GroupType != "USER_CONTACT_GROUP" {
-			continue
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			userGroups = append(userGroups, group)
 		}
+	}
+	resourceNameMap := make(map[string]string, len(userGroups))
+	if len(userGroups) == 0 {
+		return resourceNameMap, nil
+	}
 
-		req := &people.CreateContactGroupRequest{
-			ContactGroup: &people.ContactGroup{
-				Name: group.Name,
-			},
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		existing []*people.ContactGroup
+		created  int
+	)
+	slots := make(chan struct{}, groupCreateConcurrency)
+	for _, group := range userGroups {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
 		}
-
-		newGroup, err := c.service.ContactGroups.Create(req).Context(ctx).Do()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create group %s: %w", group.Name, err)
+		if ctx.Err() != nil {
+			break
 		}
 
-		// Map old resource name to new one
-		resourceNameMap[group.ResourceName] = newGroup.ResourceName
-		created++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			req := &people.CreateContactGroupRequest{
+				ContactGroup: &people.ContactGroup{
+					Name: group.Name,
+				},
+			}
+			newGroup, err := execute(ctx, c, c.service.ContactGroups.Create(req).Context(ctx).Do)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case isAlreadyExists(err):
+				existing = append(existing, group)
+				return
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to create group %s: %w", group.Name, err)
+					cancel()
+				}
+				return
+			}
 
+			// Map old resource name to new one
+			resourceNameMap[group.ResourceName] = newGroup.ResourceName
+			created++
+			if progressFn != nil {
+				progressFn(created, len(userGroups))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return resourceNameMap, nil
+	}
+
+	// Reuse the groups that already exist, matched by name
+	liveGroups, err := c.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]string)
+	for _, group := range liveGroups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			byName[group.Name] = group.ResourceName
+		}
+	}
+	for _, group := range existing {
+		live, ok := byName[group.Name]
+		if !ok {
+			return nil, fmt.Errorf("failed to create group %s: a group with that name exists but could not be found", group.Name)
+		}
+		c.warn("reusing existing contact group %s", group.Name)
+		resourceNameMap[group.ResourceName] = live
+		created++
 		if progressFn != nil {
-			progressFn(created, totalGroups)
+			progressFn(created, len(userGroups))
 		}
-
-		time.Sleep(rateLimitDelay)
 	}
 
 	return resourceNameMap, nil
 }
 
-// CreateContacts creates contacts from the backup in batches.
-// groupMap maps old group resource names to new ones for updating memberships.
-func (c *Client) CreateContacts(ctx context.Context, contacts []*people.Person, groupMap map[string]string, progressFn func(created, total int)) error {
+// CreateContacts creates contacts from the backup in batches of CreateBatchSize,
+// in the order given. groupMap maps old group resource names to new ones for
+// updating memberships. Errors report the zero-based batch index so failures
+// can be matched against the restore order.
+// Returns a map of original resource names to the resource names of the
+// created contacts.
+func (c *Client) CreateContacts(ctx context.Context, contacts []*people.Person, groupMap map[string]string, progressFn func(created, total int)) (map[string]string, error) {
+	if err := c.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	resourceNameMap := make(map[string]string)
 	if len(contacts) == 0 {
-		return nil
+		return resourceNameMap, nil
 	}
 
 	totalContacts := len(contacts)
 	created := 0
 
 	// Process in batches
-	for i := 0; i < len(contacts); i += batchCreateSize {
-		end := i + batchCreateSize
+	batchSize := c.createBatchSize
+	for i := 0; i < len(contacts); i += batchSize {
+		if c.createLimiter != nil {
+			if err := c.createLimiter.Wait(ctx); err != nil {
+				return resourceNameMap, err
+			}
+		}
+
+		end := i + batchSize
 		if end > len(contacts) {
 			end = len(contacts)
 		}
@@ -280,7 +1041,10 @@ func (c *Client) CreateContacts(ctx context.Context, contacts []*people.Person,
 		contactsToCreate := make([]*people.ContactToCreate, 0, len(batch))
 		for _, contact := range batch {
 			// Clean the contact for creation (remove server-assigned fields)
-			cleanContact := cleanContactForCreation(contact, groupMap)
+			cleanContact, err := cleanContactForCreation(contact, groupMap)
+			if err != nil {
+				return resourceNameMap, err
+			}
 			contactsToCreate = append(contactsToCreate, &people.ContactToCreate{
 				ContactPerson: cleanContact,
 			})
@@ -292,24 +1056,117 @@ func (c *Client) CreateContacts(ctx context.Context, contacts []*people.Person,
 			Sources:  []string{"READ_SOURCE_TYPE_CONTACT"},
 		}
 
-		_, err := c.service.People.BatchCreateContacts(req).Context(ctx).Do()
+		start := time.Now()
+		resp, err := execute(ctx, c, c.service.People.BatchCreateContacts(req).Context(ctx).Do)
 		if err != nil {
-			return fmt.Errorf("failed to create contacts batch: %w", err)
+			err = fmt.Errorf("failed to create contacts batch %d (contacts %d-%d): %w", i/batchSize, i+1, end, err)
+			c.reportBatch("create", i/batchSize, batch, nil, start, err)
+			return resourceNameMap, err
+		}
+
+		var createdNames []string
+		for j, createdPerson := range resp.CreatedPeople {
+			if createdPerson.Person == nil {
+				continue
+			}
+			createdNames = append(createdNames, createdPerson.Person.ResourceName)
+			if j < len(batch) && batch[j].ResourceName != "" {
+				resourceNameMap[batch[j].ResourceName] = createdPerson.Person.ResourceName
+			}
 		}
+		c.reportBatch("create", i/batchSize, batch, createdNames, start, nil)
 
 		created += len(batch)
 		if progressFn != nil {
 			progressFn(created, totalContacts)
 		}
+	}
 
-		time.Sleep(rateLimitDelay)
+	return resourceNameMap, nil
+}
+
+// UpdateContacts writes the given fields (People API names such as
+// "clientData") of existing contacts in batches. Each contact must carry the
+// resource name and etag it was read with; the update fails if the contact has
+// changed since. Returns the updated contacts, with their new etags.
+// The progressFn callback is called with (updated, total) after each batch.
+func (c *Client) UpdateContacts(ctx context.Context, contacts []*people.Person, fields []string, progressFn func(updated, total int)) ([]*people.Person, error) {
+	if err := c.checkWritable(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	updated := make([]*people.Person, 0, len(contacts))
+	updateMask := strings.Join(fields, ",")
+
+	for i := 0; i < len(contacts); i += batchUpdateSize {
+		end := i + batchUpdateSize
+		if end > len(contacts) {
+			end = len(contacts)
+		}
+
+		req := &people.BatchUpdateContactsRequest{
+			Contacts:   make(map[string]people.Person, end-i),
+			UpdateMask: updateMask,
+			ReadMask:   personFields,
+			Sources:    []string{"READ_SOURCE_TYPE_CONTACT"},
+		}
+		for _, contact := range contacts[i:end] {
+			clean, err := clonePerson(contact)
+			if err != nil {
+				return updated, err
+			}
+			clearFieldMetadata(clean)
+			clean.Metadata = nil
+			req.Contacts[contact.ResourceName] = *clean
+		}
+
+		start := time.Now()
+		resp, err := execute(ctx, c, c.service.People.BatchUpdateContacts(req).Context(ctx).Do)
+		if err != nil {
+			err = fmt.Errorf("failed to update contacts batch %d (contacts %d-%d): %w", i/batchUpdateSize, i+1, end, err)
+			c.reportBatch("update", i/batchUpdateSize, contacts[i:end], nil, start, err)
+			return updated, err
+		}
+		c.reportBatch("update", i/batchUpdateSize, contacts[i:end], nil, start, nil)
+
+		for _, contact := range contacts[i:end] {
+			if result, ok := resp.UpdateResult[contact.ResourceName]; ok && result.Person != nil {
+				updated = append(updated, result.Person)
+			}
+		}
+
+		if progressFn != nil {
+			progressFn(end, len(contacts))
+		}
+	}
+
+	return updated, nil
+}
+
+// reportBatch passes the outcome of a batch to the batch handler, if any.
+func (c *Client) reportBatch(operation string, index int, batch []*people.Person, created []string, start time.Time, err error) {
+	if c.onBatch == nil {
+		return
+	}
+	names := make([]string, len(batch))
+	for i, contact := range batch {
+		names[i] = contact.ResourceName
+	}
+	c.onBatch(Batch{
+		Operation: operation,
+		Index:     index,
+		Contacts:  names,
+		Created:   created,
+		Duration:  time.Since(start),
+		Err:       err,
+	})
 }
 
 // cleanContactForCreation removes server-assigned fields and updates group memberships.
-func cleanContactForCreation(contact *people.Person, groupMap map[string]string) *people.Person {
+// Field values such as Biography.ContentType are carried over unchanged so
+// HTML notes are restored as HTML. The result is a deep copy; contact itself
+// is not modified.
+func cleanContactForCreation(contact *people.Person, groupMap map[string]string) (*people.Person, error) {
 	// Create a new person with only the fields we can set
 	newPerson := &people.Person{
 		Names:          contact.Names,
@@ -370,10 +1227,30 @@ func cleanContactForCreation(contact *people.Person, groupMap map[string]string)
 		newPerson.Memberships = newMemberships
 	}
 
+	// Copy the fields before clearing their metadata, so the caller's contact
+	// (which other goroutines may be reading) is left untouched
+	newPerson, err := clonePerson(newPerson)
+	if err != nil {
+		return nil, err
+	}
+
 	// Clear metadata from nested objects
 	clearFieldMetadata(newPerson)
 
-	return newPerson
+	return newPerson, nil
+}
+
+// clonePerson returns a deep copy of a person.
+func clonePerson(person *people.Person) (*people.Person, error) {
+	data, err := json.Marshal(person)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy contact: %w", err)
+	}
+	var clone people.Person
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to copy contact: %w", err)
+	}
+	return &clone, nil
 }
 
 // clearFieldMetadata removes server-assigned metadata from all fields.
@@ -0,0 +1,174 @@
+package contacts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // quota reset times are computed in Pacific Time on every platform
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// QuotaError is returned when the People API rejects a request because a
+// quota or rate limit was exceeded.
+type QuotaError struct {
+	// Daily is true when a per-day quota is exhausted; retrying before
+	// ResetAt is pointless
+	Daily bool
+
+	// ResetAt is when a daily quota is replenished (midnight Pacific Time)
+	ResetAt time.Time
+
+	// RetryAfter is the delay suggested by the server for per-minute limits
+	RetryAfter time.Duration
+
+	// Err is the underlying API error
+	Err error
+}
+
+func (e *QuotaError) Error() string {
+	if e.Daily {
+		return fmt.Sprintf("daily People API quota exhausted (resets at %s): %v",
+			e.ResetAt.Local().Format(time.RFC1123), e.Err)
+	}
+	return fmt.Sprintf("People API rate limit exceeded: %v", e.Err)
+}
+
+func (e *QuotaError) Unwrap() error { return e.Err }
+
+// asQuotaError classifies err as a quota error, or returns nil if it is not one.
+func asQuotaError(err error) *QuotaError {
+	var qe *QuotaError
+	if errors.As(err, &qe) {
+		return qe
+	}
+
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+
+	isQuota := apiErr.Code == http.StatusTooManyRequests
+	if apiErr.Code == http.StatusForbidden {
+		for _, item := range apiErr.Errors {
+			switch item.Reason {
+			case "rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded", "dailyLimitExceeded":
+				isQuota = true
+			}
+		}
+	}
+	if !isQuota {
+		return nil
+	}
+
+	qe = &QuotaError{Err: err}
+	if isDailyQuota(apiErr) {
+		qe.Daily = true
+		qe.ResetAt = nextQuotaReset(time.Now())
+	} else if seconds, err := strconv.Atoi(apiErr.Header.Get("Retry-After")); err == nil {
+		qe.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return qe
+}
+
+// isDailyQuota reports whether an API error refers to a per-day quota rather
+// than a per-minute rate limit, using the ErrorInfo details and message.
+func isDailyQuota(apiErr *googleapi.Error) bool {
+	isDaily := func(s string) bool {
+		s = strings.ToLower(s)
+		return strings.Contains(s, "perday") || strings.Contains(s, "per_day") ||
+			strings.Contains(s, "per day") || strings.Contains(s, "daily")
+	}
+
+	for _, item := range apiErr.Errors {
+		if item.Reason == "dailyLimitExceeded" || isDaily(item.Message) {
+			return true
+		}
+	}
+
+	for _, detail := range apiErr.Details {
+		info, ok := detail.(map[string]any)
+		if !ok {
+			continue
+		}
+		metadata, _ := info["metadata"].(map[string]any)
+		for _, key := range []string{"quota_limit", "quota_metric", "quota_location"} {
+			if value, ok := metadata[key].(string); ok && isDaily(value) {
+				return true
+			}
+		}
+	}
+
+	return isDaily(apiErr.Message)
+}
+
+// nextQuotaReset returns the next midnight in Pacific Time, when Google
+// replenishes daily API quotas.
+func nextQuotaReset(now time.Time) time.Time {
+	local := now.In(pacificTime())
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, local.Location())
+}
+
+// NextQuotaReset returns when the daily People API quotas are next
+// replenished: the next midnight in Pacific Time.
+func NextQuotaReset() time.Time {
+	return nextQuotaReset(time.Now())
+}
+
+// QuotaDay returns the quota day t falls in, as the date in Pacific Time
+// (e.g. "2024-06-01"). Daily quotas are counted per quota day.
+func QuotaDay(t time.Time) string {
+	return t.In(pacificTime()).Format(time.DateOnly)
+}
+
+// pacificTime returns the time zone Google counts daily quotas in.
+func pacificTime() *time.Location {
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		return time.FixedZone("PST", -8*60*60)
+	}
+	return pacific
+}
+
+// IsQuotaError reports whether err is a People API rate limit or quota error.
+func IsQuotaError(err error) bool {
+	return asQuotaError(err) != nil
+}
+
+// IsAuthError reports whether err was caused by missing or rejected credentials.
+func IsAuthError(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return true
+	}
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized
+}
+
+// isAlreadyExists reports whether err means the resource to create, such as
+// a contact group with the same name, already exists.
+func isAlreadyExists(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
+
+// IsInsufficientScope reports whether err means the OAuth token was not
+// granted a scope the request needs, typically because it was issued before
+// the tool asked for that scope.
+func IsInsufficientScope(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "insufficientPermissions" {
+			return true
+		}
+	}
+	return strings.Contains(apiErr.Message, "insufficient authentication scopes")
+}
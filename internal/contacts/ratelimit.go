@@ -0,0 +1,130 @@
+package contacts
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// adaptiveRecoverAfter is the number of successful requests after which
+	// an adaptive limiter that slowed down raises its rate again
+	adaptiveRecoverAfter = 20
+
+	// adaptiveMinRate is the slowest an adaptive limiter gets, in requests
+	// per second
+	adaptiveMinRate = 0.2
+)
+
+// RateLimiter paces requests. Wait blocks until the caller may send one
+// request, or returns the context's error if it is cancelled first.
+// Implementations must be safe for concurrent use.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// AdaptiveRateLimiter is a RateLimiter that is told the outcome of each
+// request, so it can change its pace.
+type AdaptiveRateLimiter interface {
+	RateLimiter
+
+	// Succeeded reports a request that was accepted
+	Succeeded()
+
+	// Throttled reports a request rejected by a per-minute rate limit
+	Throttled()
+}
+
+// TokenBucket is a RateLimiter that allows bursts of up to burst requests and
+// refills at a steady rate.
+type TokenBucket struct {
+	limiter *rate.Limiter
+}
+
+// NewTokenBucket creates a token bucket allowing perSecond requests per second
+// on average and bursts of up to burst requests. It starts full.
+func NewTokenBucket(perSecond float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+}
+
+// Wait takes a token, sleeping until one is available. Concurrent callers
+// are served in order, and a cancelled Wait gives its token back.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return b.limiter.Wait(ctx)
+}
+
+// AdaptiveLimiter is a token bucket that halves its rate every time the API
+// reports a per-minute rate limit, and raises it again by a tenth of the
+// configured rate after every 20 successful requests, never above the
+// configured rate. Fast accounts run at full speed, while long restores settle
+// at a rate the API accepts instead of retrying in a loop.
+type AdaptiveLimiter struct {
+	limiter *rate.Limiter
+	max     rate.Limit
+
+	mu        sync.Mutex
+	successes int
+}
+
+// NewAdaptiveLimiter creates an adaptive limiter starting at, and never
+// exceeding, perSecond requests per second with bursts of up to burst
+// requests.
+func NewAdaptiveLimiter(perSecond float64, burst int) *AdaptiveLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &AdaptiveLimiter{
+		limiter: rate.NewLimiter(rate.Limit(perSecond), burst),
+		max:     rate.Limit(perSecond),
+	}
+}
+
+// Wait takes a token at the current rate.
+func (a *AdaptiveLimiter) Wait(ctx context.Context) error {
+	return a.limiter.Wait(ctx)
+}
+
+// Succeeded counts an accepted request, raising the rate after enough of
+// them if it was lowered.
+func (a *AdaptiveLimiter) Succeeded() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := a.limiter.Limit()
+	if current >= a.max {
+		a.successes = 0
+		return
+	}
+	a.successes++
+	if a.successes < adaptiveRecoverAfter {
+		return
+	}
+	a.successes = 0
+	a.limiter.SetLimit(min(current+a.max/10, a.max))
+}
+
+// Throttled halves the rate, down to one request every five seconds.
+func (a *AdaptiveLimiter) Throttled() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.successes = 0
+	floor := min(rate.Limit(adaptiveMinRate), a.max)
+	a.limiter.SetLimit(max(a.limiter.Limit()/2, floor))
+}
+
+// Rate returns the current rate in requests per second.
+func (a *AdaptiveLimiter) Rate() float64 {
+	return float64(a.limiter.Limit())
+}
+
+// unlimited is a RateLimiter that never waits
+type unlimited struct{}
+
+func (unlimited) Wait(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -0,0 +1,63 @@
+package contacts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrReadOnly is returned by every operation that would change contacts,
+// groups or photos on a read-only client.
+var ErrReadOnly = errors.New("read-only mode: contacts and groups cannot be changed")
+
+// WithReadOnly makes the client refuse every operation that changes the
+// account. Create, update and delete operations fail with ErrReadOnly before
+// sending anything, and as a second line of defence the client's HTTP
+// transport rejects every request that is not a GET, so no write can reach
+// the People API even through a code path that forgot to check.
+func WithReadOnly() Option {
+	return func(c *Client) {
+		c.readOnly = true
+	}
+}
+
+// ReadOnly reports whether the client refuses changes.
+func (c *Client) ReadOnly() bool {
+	return c.readOnly
+}
+
+// checkWritable returns ErrReadOnly if the client is read-only.
+func (c *Client) checkWritable() error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// readOnlyTransport lets GET and HEAD requests through to base and rejects
+// all others. Every People API call that only reads uses GET.
+type readOnlyTransport struct {
+	base http.RoundTripper
+}
+
+func (t readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w (refused %s %s)", ErrReadOnly, req.Method, req.URL.Path)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// readOnlyHTTPClient returns a copy of httpClient that only sends GET and
+// HEAD requests.
+func readOnlyHTTPClient(httpClient *http.Client) *http.Client {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	guarded := *httpClient
+	guarded.Transport = readOnlyTransport{base: base}
+	return &guarded
+}
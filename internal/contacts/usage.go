@@ -0,0 +1,57 @@
+package contacts
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Usage counts the People API requests sent by the clients it is given to,
+// so that consumption of daily quotas can be tracked across runs. Reads are
+// GET requests and writes all others, as for WithReadOnly. Retried requests
+// are counted every time they are sent; photo downloads, which do not go to
+// the People API, are not counted.
+type Usage struct {
+	reads, writes atomic.Int64
+}
+
+// Reads returns the number of read requests sent so far.
+func (u *Usage) Reads() int {
+	return int(u.reads.Load())
+}
+
+// Writes returns the number of write requests sent so far.
+func (u *Usage) Writes() int {
+	return int(u.writes.Load())
+}
+
+// WithUsage counts the client's People API requests in u. The same Usage may
+// be shared by several clients.
+func WithUsage(u *Usage) Option {
+	return func(c *Client) {
+		base := c.httpClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		counted := *c.httpClient
+		counted.Transport = usageTransport{base: base, usage: u}
+		c.httpClient = &counted
+	}
+}
+
+// usageTransport counts the People API requests it passes to base.
+type usageTransport struct {
+	base  http.RoundTripper
+	usage *Usage
+}
+
+func (t usageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.URL.Path, "/v1/") {
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			t.usage.reads.Add(1)
+		} else {
+			t.usage.writes.Add(1)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
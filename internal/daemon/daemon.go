@@ -0,0 +1,162 @@
+// Package daemon installs the operating system's scheduler entry for
+// unattended backups: a systemd user timer on Linux, a launchd agent on
+// macOS, or a Scheduled Task or service on Windows.
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Name identifies the installed unit, agent or task.
+const Name = "google-contacts-backup"
+
+// Schedules are the accepted values of Job.Schedule.
+var Schedules = []string{"hourly", "daily", "weekly"}
+
+// Job is a command run on a schedule.
+type Job struct {
+	// Command is the program and its arguments
+	Command []string
+
+	// Schedule is "hourly", "daily" (at 02:00) or "weekly" (Sundays at 02:00)
+	Schedule string
+}
+
+// File is a file written by Install.
+type File struct {
+	Path    string
+	Content string
+}
+
+// Scheduler installs jobs into one operating system's scheduler.
+type Scheduler interface {
+	// Kind names the scheduler, e.g. "systemd user timer"
+	Kind() string
+
+	// Files returns the files that Install writes for the job
+	Files(job Job) ([]File, error)
+
+	// Install writes the files and activates the job, replacing any
+	// previous installation
+	Install(job Job) error
+
+	// Uninstall deactivates the job and removes its files
+	Uninstall() error
+
+	// Status writes the scheduler's view of the job to w
+	Status(w io.Writer) error
+}
+
+// New returns the scheduler of the given operating system (runtime.GOOS).
+func New(goos string) (Scheduler, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	switch goos {
+	case "linux":
+		return &systemd{home: home}, nil
+	case "darwin":
+		return &launchd{home: home}, nil
+	case "windows":
+		return &schtasks{}, nil
+	default:
+		return nil, fmt.Errorf("scheduled backups are not supported on %s", goos)
+	}
+}
+
+// validate returns an error if the job cannot be scheduled
+func validate(job Job) error {
+	if len(job.Command) == 0 {
+		return fmt.Errorf("no command to schedule")
+	}
+	if !slices.Contains(Schedules, job.Schedule) {
+		return fmt.Errorf("invalid schedule %q: must be one of %s", job.Schedule, strings.Join(Schedules, ", "))
+	}
+	return nil
+}
+
+// writeFiles writes files with owner-only permissions
+func writeFiles(files []File) error {
+	for _, file := range files {
+		if err := os.MkdirAll(dirOf(file.Path), 0700); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+		}
+		if err := os.WriteFile(file.Path, []byte(file.Content), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// removeFiles removes files, ignoring those that do not exist
+func removeFiles(paths ...string) error {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// dirOf returns the directory of a path using either separator
+func dirOf(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[:i]
+	}
+	return "."
+}
+
+// run runs a scheduler command, including its output in any error
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w\n%s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// show runs a scheduler command, copying its output to w. A failing
+// command is reported in the output rather than as an error, since status
+// commands fail when the job is not installed.
+func show(w io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil
+		}
+		return fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	return nil
+}
+
+// Next returns the first time after t at which a job on the schedule runs,
+// in t's location, matching the times used by the installed schedulers.
+func Next(schedule string, t time.Time) time.Time {
+	switch schedule {
+	case "hourly":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+	case "weekly":
+		next := time.Date(t.Year(), t.Month(), t.Day(), 2, 0, 0, 0, t.Location())
+		next = next.AddDate(0, 0, -int(next.Weekday()))
+		for !next.After(t) {
+			next = next.AddDate(0, 0, 7)
+		}
+		return next
+	default:
+		next := time.Date(t.Year(), t.Month(), t.Day(), 2, 0, 0, 0, t.Location())
+		if !next.After(t) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+}
@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// launchdLabel is the label of the launchd agent
+const launchdLabel = "com.github.mheap." + Name
+
+// launchd installs a per-user agent under ~/Library/LaunchAgents
+type launchd struct {
+	home string
+}
+
+func (l *launchd) Kind() string {
+	return "launchd agent"
+}
+
+func (l *launchd) plistPath() string {
+	return filepath.Join(l.home, "Library", "LaunchAgents", launchdLabel+".plist")
+}
+
+func (l *launchd) logPath() string {
+	return filepath.Join(l.home, "Library", "Logs", Name+".log")
+}
+
+func (l *launchd) Files(job Job) ([]File, error) {
+	if err := validate(job); err != nil {
+		return nil, err
+	}
+
+	var args strings.Builder
+	for _, arg := range job.Command {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>StartCalendarInterval</key>
+	<dict>
+%s	</dict>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, launchdLabel, args.String(), launchdInterval(job.Schedule), xmlEscape(l.logPath()), xmlEscape(l.logPath()))
+
+	return []File{{Path: l.plistPath(), Content: plist}}, nil
+}
+
+func (l *launchd) Install(job Job) error {
+	files, err := l.Files(job)
+	if err != nil {
+		return err
+	}
+	// Unload any previous version so launchd picks up the new schedule
+	_ = run("launchctl", "unload", l.plistPath())
+	if err := writeFiles(files); err != nil {
+		return err
+	}
+	return run("launchctl", "load", "-w", l.plistPath())
+}
+
+func (l *launchd) Uninstall() error {
+	_ = run("launchctl", "unload", "-w", l.plistPath())
+	return removeFiles(l.plistPath())
+}
+
+func (l *launchd) Status(w io.Writer) error {
+	fmt.Fprintf(w, "Log file: %s\n", l.logPath())
+	return show(w, "launchctl", "list", launchdLabel)
+}
+
+// launchdInterval returns the StartCalendarInterval entries of a schedule
+func launchdInterval(schedule string) string {
+	entries := "\t\t<key>Minute</key>\n\t\t<integer>0</integer>\n"
+	switch schedule {
+	case "daily":
+		entries += "\t\t<key>Hour</key>\n\t\t<integer>2</integer>\n"
+	case "weekly":
+		entries += "\t\t<key>Hour</key>\n\t\t<integer>2</integer>\n\t\t<key>Weekday</key>\n\t\t<integer>0</integer>\n"
+	}
+	return entries
+}
+
+// xmlEscape escapes text for a plist string
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
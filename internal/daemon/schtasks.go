@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"io"
+	"strings"
+)
+
+// schtasks installs a Windows Scheduled Task for the current user
+type schtasks struct{}
+
+func (s *schtasks) Kind() string {
+	return "Windows Scheduled Task"
+}
+
+// Files returns nothing: Scheduled Tasks are created by schtasks.exe
+func (s *schtasks) Files(job Job) ([]File, error) {
+	return nil, validate(job)
+}
+
+// args returns the schtasks arguments that create the task
+func (s *schtasks) args(job Job) []string {
+	quoted := make([]string, len(job.Command))
+	for i, arg := range job.Command {
+		if strings.ContainsAny(arg, " \t") {
+			arg = `"` + arg + `"`
+		}
+		quoted[i] = arg
+	}
+
+	args := []string{"/Create", "/F", "/TN", Name, "/TR", strings.Join(quoted, " ")}
+	switch job.Schedule {
+	case "hourly":
+		args = append(args, "/SC", "HOURLY", "/ST", "00:00")
+	case "weekly":
+		args = append(args, "/SC", "WEEKLY", "/D", "SUN", "/ST", "02:00")
+	default:
+		args = append(args, "/SC", "DAILY", "/ST", "02:00")
+	}
+	return args
+}
+
+func (s *schtasks) Install(job Job) error {
+	if err := validate(job); err != nil {
+		return err
+	}
+	return run("schtasks", s.args(job)...)
+}
+
+func (s *schtasks) Uninstall() error {
+	return run("schtasks", "/Delete", "/F", "/TN", Name)
+}
+
+func (s *schtasks) Status(w io.Writer) error {
+	return show(w, "schtasks", "/Query", "/TN", Name, "/V", "/FO", "LIST")
+}
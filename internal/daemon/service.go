@@ -0,0 +1,23 @@
+package daemon
+
+// ServiceDisplayName is the name of the Windows service in the Services
+// console.
+const ServiceDisplayName = "Google Contacts Backup"
+
+// Event ids of the messages a service writes to the Windows event log, so
+// monitoring can filter on them.
+const (
+	EventStarted       = 1
+	EventStopped       = 2
+	EventBackupStarted = 10
+	EventBackupDone    = 11
+	EventBackupFailed  = 12
+	EventWarning       = 20
+)
+
+// Logger writes the messages of a service to the Windows event log.
+type Logger interface {
+	Info(id uint32, msg string)
+	Warning(id uint32, msg string)
+	Error(id uint32, msg string)
+}
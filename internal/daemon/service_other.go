@@ -0,0 +1,25 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+)
+
+// NewService returns the scheduler that installs the daemon as a Windows
+// service, which is only available on Windows.
+func NewService() (Scheduler, error) {
+	return nil, fmt.Errorf("--windows-service is only supported on Windows")
+}
+
+// InService reports whether the process was started by the Windows service
+// manager, which it never is outside Windows.
+func InService() (bool, error) {
+	return false, nil
+}
+
+// RunService is only supported on Windows.
+func RunService(run func(ctx context.Context, log Logger) error) error {
+	return fmt.Errorf("running as a Windows service is only supported on Windows")
+}
@@ -0,0 +1,367 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// stopTimeout is how long to wait for the service to stop, long enough for
+// a running backup to be interrupted
+const stopTimeout = 2 * time.Minute
+
+// winService installs a Windows service that runs 'daemon run'. It runs as
+// LocalSystem with the installing user's profile directory in its
+// environment, so it finds that user's config file, credentials and token.
+type winService struct {
+	home string
+}
+
+// NewService returns the scheduler that installs the daemon as a Windows
+// service.
+func NewService() (Scheduler, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return &winService{home: home}, nil
+}
+
+func (s *winService) Kind() string {
+	return "Windows service"
+}
+
+// Files returns nothing: services are registered with the service manager
+func (s *winService) Files(job Job) ([]File, error) {
+	return nil, validate(job)
+}
+
+// environment returns the variables the service runs with. APPDATA and
+// GOOGLE_APPLICATION_CREDENTIALS let uploads to gs:// destinations find the
+// installing user's Application Default Credentials.
+func (s *winService) environment() []string {
+	env := []string{"USERPROFILE=" + s.home}
+	for _, name := range []string{"XDG_CONFIG_HOME", "APPDATA", "GOOGLE_APPLICATION_CREDENTIALS"} {
+		if value := os.Getenv(name); value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+func (s *winService) Install(job Job) error {
+	if err := validate(job); err != nil {
+		return err
+	}
+
+	m, err := connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	config := mgr.Config{
+		DisplayName:      ServiceDisplayName,
+		Description:      "Backs up Google Contacts on a schedule.",
+		StartType:        mgr.StartAutomatic,
+		DelayedAutoStart: true,
+	}
+
+	service, err := m.OpenService(Name)
+	if err == nil {
+		// Replace the previous installation in place, since a deleted
+		// service cannot be created again until its handles are closed
+		if err := stopService(service); err != nil {
+			service.Close()
+			return err
+		}
+		current, err := service.Config()
+		if err != nil {
+			service.Close()
+			return fmt.Errorf("failed to read the service configuration: %w", err)
+		}
+		current.DisplayName = config.DisplayName
+		current.Description = config.Description
+		current.StartType = config.StartType
+		current.DelayedAutoStart = config.DelayedAutoStart
+		current.BinaryPathName = commandLine(job.Command)
+		if err := service.UpdateConfig(current); err != nil {
+			service.Close()
+			return fmt.Errorf("failed to update the service: %w", err)
+		}
+	} else {
+		service, err = m.CreateService(Name, job.Command[0], config, job.Command[1:]...)
+		if err != nil {
+			return fmt.Errorf("failed to create the service: %w", err)
+		}
+	}
+	defer service.Close()
+
+	// Restart after a crash, but give up on a service that keeps failing
+	err = service.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Minute},
+		{Type: mgr.NoAction},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to set the service's recovery actions: %w", err)
+	}
+	if err := s.setEnvironment(); err != nil {
+		return err
+	}
+
+	// Register the event source, replacing any previous registration
+	_ = eventlog.Remove(Name)
+	if err := eventlog.InstallAsEventCreate(Name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		return fmt.Errorf("failed to register the event log source: %w", err)
+	}
+
+	if err := service.Start(); err != nil {
+		return fmt.Errorf("failed to start the service: %w", err)
+	}
+	return nil
+}
+
+// setEnvironment stores the service's environment variables in its
+// registry key, where the service manager reads them from
+func (s *winService) setEnvironment() error {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Services\`+Name, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open the service's registry key: %w", err)
+	}
+	defer key.Close()
+	if err := key.SetStringsValue("Environment", s.environment()); err != nil {
+		return fmt.Errorf("failed to set the service's environment: %w", err)
+	}
+	return nil
+}
+
+func (s *winService) Uninstall() error {
+	m, err := connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	service, err := m.OpenService(Name)
+	if err != nil {
+		return fmt.Errorf("the service is not installed: %w", err)
+	}
+	defer service.Close()
+
+	if err := stopService(service); err != nil {
+		return err
+	}
+	if err := service.Delete(); err != nil {
+		return fmt.Errorf("failed to delete the service: %w", err)
+	}
+	if err := eventlog.Remove(Name); err != nil {
+		return fmt.Errorf("failed to remove the event log source: %w", err)
+	}
+	return nil
+}
+
+func (s *winService) Status(w io.Writer) error {
+	m, err := connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	service, err := m.OpenService(Name)
+	if err != nil {
+		fmt.Fprintf(w, "The %s service is not installed.\n", Name)
+		return nil
+	}
+	defer service.Close()
+
+	status, err := service.Query()
+	if err != nil {
+		return fmt.Errorf("failed to query the service: %w", err)
+	}
+	config, err := service.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read the service configuration: %w", err)
+	}
+
+	start := "manual"
+	switch {
+	case config.StartType == mgr.StartAutomatic && config.DelayedAutoStart:
+		start = "automatic (delayed)"
+	case config.StartType == mgr.StartAutomatic:
+		start = "automatic"
+	case config.StartType == mgr.StartDisabled:
+		start = "disabled"
+	}
+
+	fmt.Fprintf(w, "Service:  %s (%s)\n", Name, config.DisplayName)
+	fmt.Fprintf(w, "State:    %s\n", stateName(status.State))
+	fmt.Fprintf(w, "Start:    %s\n", start)
+	fmt.Fprintf(w, "Account:  %s\n", config.ServiceStartName)
+	fmt.Fprintf(w, "Command:  %s\n", config.BinaryPathName)
+	fmt.Fprintf(w, "Events:   Windows Logs > Application, source %s\n", Name)
+	return nil
+}
+
+// connect connects to the service manager, which needs an administrator
+// prompt
+func connect() (*mgr.Mgr, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return nil, fmt.Errorf("failed to connect to the service manager: run this from an administrator prompt: %w", err)
+		}
+		return nil, fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	return m, nil
+}
+
+// stopService stops a service and waits until it has stopped
+func stopService(service *mgr.Service) error {
+	status, err := service.Query()
+	if err != nil {
+		return fmt.Errorf("failed to query the service: %w", err)
+	}
+	if status.State == svc.Stopped {
+		return nil
+	}
+	if status.State != svc.StopPending {
+		if _, err := service.Control(svc.Stop); err != nil && !errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+			return fmt.Errorf("failed to stop the service: %w", err)
+		}
+	}
+
+	deadline := time.Now().Add(stopTimeout)
+	for time.Now().Before(deadline) {
+		status, err := service.Query()
+		if err != nil {
+			return fmt.Errorf("failed to query the service: %w", err)
+		}
+		if status.State == svc.Stopped {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("the service did not stop within %s", stopTimeout)
+}
+
+// commandLine quotes a command the way CreateService does
+func commandLine(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = syscall.EscapeArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// stateName describes a service state
+func stateName(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "stopped"
+	case svc.StartPending:
+		return "starting"
+	case svc.StopPending:
+		return "stopping"
+	case svc.Running:
+		return "running"
+	case svc.ContinuePending:
+		return "resuming"
+	case svc.PausePending:
+		return "pausing"
+	case svc.Paused:
+		return "paused"
+	default:
+		return fmt.Sprintf("unknown (%d)", state)
+	}
+}
+
+// InService reports whether the process was started by the Windows service
+// manager.
+func InService() (bool, error) {
+	return svc.IsWindowsService()
+}
+
+// RunService runs the process as the Windows service Name: run is called
+// with a Logger writing to the event log, and its context is cancelled when
+// the service manager stops the service or the machine shuts down.
+// RunService returns once run has returned.
+func RunService(run func(ctx context.Context, log Logger) error) error {
+	events, err := eventlog.Open(Name)
+	if err != nil {
+		return fmt.Errorf("failed to open the event log: %w", err)
+	}
+	defer events.Close()
+
+	return svc.Run(Name, &handler{run: run, log: &eventLogger{events}})
+}
+
+// handler answers the service manager's requests while run does the work
+type handler struct {
+	run func(ctx context.Context, log Logger) error
+	log Logger
+}
+
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- h.run(ctx, h.log)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				h.log.Error(EventStopped, fmt.Sprintf("Service stopped: %v", err))
+				return true, 1
+			}
+			h.log.Info(EventStopped, "Service stopped")
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopTimeout.Milliseconds())}
+				cancel()
+			default:
+				h.log.Warning(EventWarning, fmt.Sprintf("Unexpected service control request %d", request.Cmd))
+			}
+		}
+	}
+}
+
+// eventLogger writes to the event log, ignoring failures since there is
+// nowhere else to report them
+type eventLogger struct {
+	events *eventlog.Log
+}
+
+func (l *eventLogger) Info(id uint32, msg string) {
+	_ = l.events.Info(id, msg)
+}
+
+func (l *eventLogger) Warning(id uint32, msg string) {
+	_ = l.events.Warning(id, msg)
+}
+
+func (l *eventLogger) Error(id uint32, msg string) {
+	_ = l.events.Error(id, msg)
+}
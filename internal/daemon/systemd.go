@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// systemd installs a user service and timer under ~/.config/systemd/user
+type systemd struct {
+	home string
+}
+
+func (s *systemd) Kind() string {
+	return "systemd user timer"
+}
+
+func (s *systemd) dir() string {
+	return filepath.Join(s.home, ".config", "systemd", "user")
+}
+
+func (s *systemd) servicePath() string {
+	return filepath.Join(s.dir(), Name+".service")
+}
+
+func (s *systemd) timerPath() string {
+	return filepath.Join(s.dir(), Name+".timer")
+}
+
+func (s *systemd) Files(job Job) ([]File, error) {
+	if err := validate(job); err != nil {
+		return nil, err
+	}
+
+	quoted := make([]string, len(job.Command))
+	for i, arg := range job.Command {
+		quoted[i] = systemdQuote(arg)
+	}
+
+	service := fmt.Sprintf(`[Unit]
+Description=Back up Google Contacts
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=%s
+`, strings.Join(quoted, " "))
+
+	// Persistent catches up on runs missed while the machine was off
+	timer := fmt.Sprintf(`[Unit]
+Description=Back up Google Contacts %s
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+RandomizedDelaySec=5min
+
+[Install]
+WantedBy=timers.target
+`, job.Schedule, systemdCalendar(job.Schedule))
+
+	return []File{
+		{Path: s.servicePath(), Content: service},
+		{Path: s.timerPath(), Content: timer},
+	}, nil
+}
+
+func (s *systemd) Install(job Job) error {
+	files, err := s.Files(job)
+	if err != nil {
+		return err
+	}
+	if err := writeFiles(files); err != nil {
+		return err
+	}
+	if err := run("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return run("systemctl", "--user", "enable", "--now", Name+".timer")
+}
+
+func (s *systemd) Uninstall() error {
+	// Disabling fails if the timer was never installed, which is fine
+	_ = run("systemctl", "--user", "disable", "--now", Name+".timer")
+	if err := removeFiles(s.timerPath(), s.servicePath()); err != nil {
+		return err
+	}
+	return run("systemctl", "--user", "daemon-reload")
+}
+
+func (s *systemd) Status(w io.Writer) error {
+	return show(w, "systemctl", "--user", "list-timers", Name+".timer", "--all", "--no-pager")
+}
+
+// systemdCalendar returns the OnCalendar expression of a schedule
+func systemdCalendar(schedule string) string {
+	switch schedule {
+	case "hourly":
+		return "hourly"
+	case "weekly":
+		return "Sun *-*-* 02:00:00"
+	default:
+		return "*-*-* 02:00:00"
+	}
+}
+
+// systemdQuote quotes an ExecStart argument if it contains spaces, quotes,
+// backslashes or specifiers
+func systemdQuote(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
@@ -0,0 +1,128 @@
+// Package dedupe finds contacts that describe the same person. Contacts are
+// compared by match keys derived from their details, normalized so that
+// spellings of the same email address or phone number produce the same key.
+package dedupe
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// plusAddressDomains are the mail providers that deliver user+tag@domain to
+// user@domain.
+var plusAddressDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+	"outlook.com":    true,
+	"hotmail.com":    true,
+	"live.com":       true,
+	"icloud.com":     true,
+	"me.com":         true,
+	"fastmail.com":   true,
+	"protonmail.com": true,
+	"proton.me":      true,
+}
+
+// CanonicalEmail returns the inbox an email address delivers to: the address
+// in lower case, with the +tag removed for providers that support plus
+// addressing and, for Gmail, the dots removed from the local part and
+// googlemail.com replaced by gmail.com. Addresses without an @ are only
+// lower-cased.
+func CanonicalEmail(address string) string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return address
+	}
+	local, domain := address[:at], address[at+1:]
+
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if plusAddressDomains[domain] {
+		if plus := strings.Index(local, "+"); plus >= 0 {
+			local = local[:plus]
+		}
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
+
+// EmailKeys returns the match keys of a contact's email addresses: their
+// canonical forms, sorted and without duplicates.
+func EmailKeys(contact *people.Person) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, email := range contact.EmailAddresses {
+		if email.Value == "" {
+			continue
+		}
+		key := CanonicalEmail(email.Value)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Alias is an inbox that contacts reach through more than one spelling of
+// its address.
+type Alias struct {
+	// Canonical is the address the spellings deliver to
+	Canonical string
+
+	// Addresses are the distinct spellings, sorted
+	Addresses []string
+
+	// Contacts are the contacts with one of the spellings, in input order
+	Contacts []*people.Person
+}
+
+// Aliases finds the email addresses of contacts that look different but
+// deliver to the same inbox, e.g. jane.doe+news@gmail.com and
+// janedoe@gmail.com. Spellings that only differ in case are not aliases.
+// Aliases are sorted by canonical address.
+func Aliases(contacts []*people.Person) []Alias {
+	addresses := make(map[string]map[string]bool)
+	members := make(map[string][]*people.Person)
+	for _, contact := range contacts {
+		added := make(map[string]bool)
+		for _, email := range contact.EmailAddresses {
+			if email.Value == "" {
+				continue
+			}
+			key := CanonicalEmail(email.Value)
+			if addresses[key] == nil {
+				addresses[key] = make(map[string]bool)
+			}
+			addresses[key][strings.ToLower(strings.TrimSpace(email.Value))] = true
+			if !added[key] {
+				added[key] = true
+				members[key] = append(members[key], contact)
+			}
+		}
+	}
+
+	var aliases []Alias
+	for key, spellings := range addresses {
+		if len(spellings) < 2 {
+			continue
+		}
+		alias := Alias{Canonical: key, Contacts: members[key]}
+		for spelling := range spellings {
+			alias.Addresses = append(alias.Addresses, spelling)
+		}
+		sort.Strings(alias.Addresses)
+		aliases = append(aliases, alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Canonical < aliases[j].Canonical
+	})
+	return aliases
+}
@@ -0,0 +1,321 @@
+package dedupe
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"google.golang.org/api/people/v1"
+)
+
+// DefaultThreshold is the confidence from which two contacts are reported
+// as duplicates: a shared email address or phone number, or the same name,
+// is enough on its own; a nickname match needs a second signal.
+const DefaultThreshold = 0.8
+
+// Signal confidences. Signals are combined as independent evidence, so two
+// weak signals add up to more than either.
+const (
+	scoreEmail    = 0.95
+	scorePhone    = 0.9
+	scoreName     = 0.85
+	scoreNickname = 0.7
+	scoreInitial  = 0.4
+
+	scoreGivenOnly = 0.5
+)
+
+// Options tunes duplicate detection.
+type Options struct {
+	// Threshold is the minimum confidence, between 0 and 1, for two contacts
+	// to be duplicates. Zero means DefaultThreshold.
+	Threshold float64
+}
+
+// Pair is two contacts that look like the same person.
+type Pair struct {
+	A, B *people.Person
+
+	// Confidence is how likely the contacts are duplicates, between 0 and 1
+	Confidence float64
+
+	// Reasons describe the signals that matched, e.g. "same email"
+	Reasons []string
+}
+
+// Cluster is a set of contacts that are all duplicates of each other,
+// directly or through other members.
+type Cluster struct {
+	// Contacts are in input order
+	Contacts []*people.Person
+
+	// Pairs are the matches that joined the cluster, most confident first
+	Pairs []Pair
+}
+
+// Compare scores how likely two contacts are the same person. Email
+// addresses are compared by canonical inbox and phone numbers by their last
+// ten digits. Names are compared after normalization (see NormalizeName):
+// the same given and family name is a strong signal, a nickname of the
+// given name (Bob and Robert, Sasha and Alexander) with the same family name
+// a weaker one, and a matching initial weaker still; contacts with only the
+// same given name and no family name are a weak signal too. Contacts with
+// different family names only match through email or phone.
+func Compare(a, b *people.Person) (float64, []string) {
+	var scores []float64
+	var reasons []string
+	add := func(score float64, reason string) {
+		scores = append(scores, score)
+		reasons = append(reasons, reason)
+	}
+
+	if shared(EmailKeys(a), EmailKeys(b)) {
+		add(scoreEmail, "same email")
+	}
+	if shared(PhoneKeys(a), PhoneKeys(b)) {
+		add(scorePhone, "same phone")
+	}
+
+	givenA, familyA := nameParts(a)
+	givenB, familyB := nameParts(b)
+	if givenA != "" && givenB != "" && familyA == familyB {
+		switch {
+		case givenA == givenB && familyA == "":
+			add(scoreGivenOnly, "same given name, no family name")
+		case givenA == givenB:
+			reason := "same name"
+			if displayA, displayB := strings.ToLower(displayName(a)), strings.ToLower(displayName(b)); displayA != displayB {
+				reason = "same name when transliterated"
+			}
+			add(scoreName, reason)
+		case familyA != "" && sameGivenName(givenA, givenB):
+			add(scoreNickname, "nickname")
+		case familyA != "" && (isInitial(givenA, givenB) || isInitial(givenB, givenA)):
+			add(scoreInitial, "same initial")
+		}
+	}
+
+	miss := 1.0
+	for _, score := range scores {
+		miss *= 1 - score
+	}
+	return 1 - miss, reasons
+}
+
+// indexedPair is a Pair with the input positions of its contacts.
+type indexedPair struct {
+	i, j int
+	pair Pair
+}
+
+// Find groups contacts into clusters of duplicates: contacts whose Compare
+// confidence reaches the threshold, and the contacts they transitively
+// match. Only contacts sharing an email address, phone number, family name
+// or full name are compared, so large address books are scanned quickly.
+// Clusters are sorted by the position of their first contact.
+func Find(contacts []*people.Person, opts Options) []Cluster {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	blocks := make(map[string][]int)
+	for i, contact := range contacts {
+		for _, key := range blockKeys(contact) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	parent := make([]int, len(contacts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	compared := make(map[[2]int]bool)
+	paired := make(map[int]bool)
+	var pairs []indexedPair
+	for _, members := range blocks {
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				i, j := members[x], members[y]
+				if compared[[2]int{i, j}] {
+					continue
+				}
+				compared[[2]int{i, j}] = true
+
+				confidence, reasons := Compare(contacts[i], contacts[j])
+				if confidence < threshold {
+					continue
+				}
+				pairs = append(pairs, indexedPair{i, j, Pair{A: contacts[i], B: contacts[j], Confidence: confidence, Reasons: reasons}})
+				paired[i], paired[j] = true, true
+				if ri, rj := find(i), find(j); ri != rj {
+					if ri < rj {
+						parent[rj] = ri
+					} else {
+						parent[ri] = rj
+					}
+				}
+			}
+		}
+	}
+
+	byRoot := make(map[int]*Cluster)
+	var roots []int
+	for i, contact := range contacts {
+		if !paired[i] {
+			continue
+		}
+		root := find(i)
+		cluster, ok := byRoot[root]
+		if !ok {
+			cluster = &Cluster{}
+			byRoot[root] = cluster
+			roots = append(roots, root)
+		}
+		cluster.Contacts = append(cluster.Contacts, contact)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a].i != pairs[b].i {
+			return pairs[a].i < pairs[b].i
+		}
+		return pairs[a].j < pairs[b].j
+	})
+	for _, p := range pairs {
+		cluster := byRoot[find(p.i)]
+		cluster.Pairs = append(cluster.Pairs, p.pair)
+	}
+
+	sort.Ints(roots)
+	clusters := make([]Cluster, 0, len(roots))
+	for _, root := range roots {
+		cluster := byRoot[root]
+		sort.SliceStable(cluster.Pairs, func(i, j int) bool {
+			return cluster.Pairs[i].Confidence > cluster.Pairs[j].Confidence
+		})
+		clusters = append(clusters, *cluster)
+	}
+	return clusters
+}
+
+// PhoneKeys returns the match keys of a contact's phone numbers: their last
+// ten digits, so the same number with and without a country or trunk
+// prefix matches. Numbers with fewer than seven digits are ignored.
+func PhoneKeys(contact *people.Person) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, phone := range contact.PhoneNumbers {
+		key := phoneKey(phone.Value)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// phoneKey returns the match key of a phone number, its last ten digits, or
+// "" if it has fewer than seven digits.
+func phoneKey(number string) string {
+	var digits strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	key := digits.String()
+	if len(key) < 7 {
+		return ""
+	}
+	if len(key) > 10 {
+		key = key[len(key)-10:]
+	}
+	return key
+}
+
+// blockKeys returns the keys under which a contact is compared with others.
+func blockKeys(contact *people.Person) []string {
+	var keys []string
+	for _, key := range EmailKeys(contact) {
+		keys = append(keys, "email:"+key)
+	}
+	for _, key := range PhoneKeys(contact) {
+		keys = append(keys, "phone:"+key)
+	}
+	given, family := nameParts(contact)
+	switch {
+	case family != "":
+		keys = append(keys, "family:"+family)
+	case given != "":
+		keys = append(keys, "name:"+given)
+	}
+	return keys
+}
+
+// nameParts returns a contact's normalized given and family names, taken
+// from its structured name or, failing that, split from its display name.
+func nameParts(contact *people.Person) (given, family string) {
+	if len(contact.Names) == 0 {
+		return "", ""
+	}
+	name := contact.Names[0]
+	if name.GivenName != "" || name.FamilyName != "" {
+		given = NormalizeName(name.GivenName)
+		if fields := strings.Fields(given); len(fields) > 0 {
+			given = fields[0]
+		}
+		return given, NormalizeName(name.FamilyName)
+	}
+
+	fields := strings.Fields(NormalizeName(name.DisplayName))
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	default:
+		return fields[0], fields[len(fields)-1]
+	}
+}
+
+// displayName returns the name a contact is shown with, without falling
+// back to its email address or phone number.
+func displayName(contact *people.Person) string {
+	if len(contact.Names) == 0 {
+		return ""
+	}
+	name := contact.Names[0]
+	if name.DisplayName != "" {
+		return name.DisplayName
+	}
+	return strings.TrimSpace(name.GivenName + " " + name.FamilyName)
+}
+
+// isInitial reports whether a is a single letter that starts b.
+func isInitial(a, b string) bool {
+	runes := []rune(a)
+	return len(runes) == 1 && unicode.IsLetter(runes[0]) && strings.HasPrefix(b, a)
+}
+
+// shared reports whether two sorted key lists have a key in common.
+func shared(a, b []string) bool {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			return true
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return false
+}
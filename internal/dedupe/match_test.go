@@ -0,0 +1,135 @@
+package dedupe
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/people/v1"
+)
+
+// contact returns a contact with a structured name and the given emails or
+// phone numbers; values containing an @ are emails.
+func contact(given, family string, values ...string) *people.Person {
+	p := &people.Person{Names: []*people.Name{{GivenName: given, FamilyName: family}}}
+	for _, v := range values {
+		if strings.Contains(v, "@") {
+			p.EmailAddresses = append(p.EmailAddresses, &people.EmailAddress{Value: v})
+		} else {
+			p.PhoneNumbers = append(p.PhoneNumbers, &people.PhoneNumber{Value: v})
+		}
+	}
+	return p
+}
+
+func TestCompare(t *testing.T) {
+	displayOnly := func(name string) *people.Person {
+		return &people.Person{Names: []*people.Name{{DisplayName: name}}}
+	}
+
+	tests := []struct {
+		name    string
+		a, b    *people.Person
+		score   float64
+		reasons []string
+	}{
+		{"canonical email", contact("Ada", "Lovelace", "Ada.L+work@gmail.com"), contact("Augusta", "King", "adal@googlemail.com"), 0.95, []string{"same email"}},
+		{"phone with country code", contact("Ada", "Lovelace", "+44 20 7946 0000"), contact("Augusta", "King", "020 7946 0000"), 0.9, []string{"same phone"}},
+		{"short numbers are ignored", contact("Ada", "Lovelace", "112"), contact("Augusta", "King", "112"), 0, nil},
+		{"same name", contact("Ada", "Lovelace"), contact("ada", "LOVELACE"), 0.85, []string{"same name"}},
+		{"transliterated name", contact("Иван", "Петров"), contact("Ivan", "Petrov"), 0.85, []string{"same name when transliterated"}},
+		{"diacritics", contact("José", "García"), contact("Jose", "Garcia"), 0.85, []string{"same name when transliterated"}},
+		{"nickname", contact("Bob", "Smith"), contact("Robert", "Smith"), 0.7, []string{"nickname"}},
+		{"nickname and phone", contact("Bob", "Smith", "555-0100-123"), contact("Robert", "Smith", "5550100123"), 1 - 0.3*0.1, []string{"same phone", "nickname"}},
+		{"initial", contact("R", "Smith"), contact("Robert", "Smith"), 0.4, []string{"same initial"}},
+		{"given name only", contact("Ada", ""), contact("Ada", ""), 0.5, []string{"same given name, no family name"}},
+		{"nickname without family name", contact("Bob", ""), contact("Robert", ""), 0, nil},
+		{"different family names", contact("Robert", "Smith"), contact("Robert", "Jones"), 0, nil},
+		{"names from display names", displayOnly("Sasha Ivanova"), displayOnly("Alexandra Ivanova"), 0.7, []string{"nickname"}},
+		{"no names", &people.Person{}, &people.Person{}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := Compare(tt.a, tt.b)
+			if math.Abs(score-tt.score) > 1e-9 {
+				t.Errorf("score = %v, want %v", score, tt.score)
+			}
+			if !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("reasons = %v, want %v", reasons, tt.reasons)
+			}
+
+			// Compare is symmetric
+			if score2, _ := Compare(tt.b, tt.a); math.Abs(score2-score) > 1e-9 {
+				t.Errorf("reversed score = %v, want %v", score2, score)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	contacts := []*people.Person{
+		contact("Robert", "Smith", "+1 555 010 0123"),
+		contact("Grace", "Hopper"),
+		contact("Ada", "Lovelace", "ada@example.com"),
+		contact("Bob", "Smith", "555 010 0123"),
+		contact("Augusta", "King", "ADA@example.com", "+44 20 7946 0000"),
+		contact("Charles", "Babbage"),
+		contact("Ada", "King", "020 7946 0000"),
+		contact("Rob", "Smith"),
+	}
+
+	clusters := Find(contacts, Options{})
+	var got [][]*people.Person
+	for _, cluster := range clusters {
+		got = append(got, cluster.Contacts)
+	}
+	want := [][]*people.Person{
+		{contacts[0], contacts[3]},
+		{contacts[2], contacts[4], contacts[6]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("clusters = %v, want %v", names(got), names(want))
+	}
+
+	// Ada and Ada King only match through Augusta King; the pairs are
+	// sorted most confident first
+	pairs := clusters[1].Pairs
+	if len(pairs) != 2 || pairs[0].A != contacts[2] || pairs[0].B != contacts[4] || pairs[1].A != contacts[4] || pairs[1].B != contacts[6] {
+		t.Errorf("pairs = %+v", pairs)
+	}
+	if pairs[0].Confidence < pairs[1].Confidence {
+		t.Errorf("pairs are not sorted by confidence: %v then %v", pairs[0].Confidence, pairs[1].Confidence)
+	}
+
+	// A lower threshold lets a nickname alone match
+	clusters = Find(contacts, Options{Threshold: 0.6})
+	if len(clusters) != 2 || len(clusters[0].Contacts) != 3 || clusters[0].Contacts[2] != contacts[7] {
+		t.Errorf("with threshold 0.6: clusters = %v", clusters)
+	}
+
+	if clusters := Find(nil, Options{}); len(clusters) != 0 {
+		t.Errorf("Find(nil) = %v", clusters)
+	}
+}
+
+// names returns the given names of clustered contacts, for messages.
+func names(clusters [][]*people.Person) [][]string {
+	var result [][]string
+	for _, cluster := range clusters {
+		var n []string
+		for _, p := range cluster {
+			n = append(n, p.Names[0].GivenName+" "+p.Names[0].FamilyName)
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+func TestPhoneKeys(t *testing.T) {
+	p := contact("Ada", "Lovelace", "+44 (20) 7946-0000", "020 7946 0000", "0044 20 7946 0001", "911")
+	want := []string{"2079460000", "2079460001"}
+	if got := PhoneKeys(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("PhoneKeys = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package dedupe
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// Merge adds to base the details of the other contacts that it lacks, so
+// that the duplicates can be dropped without losing anything. Email
+// addresses and phone numbers are compared by their match keys and other
+// multi-valued fields by value, ignoring case; single-valued fields such as
+// the name, birthday and notes are only taken when base has none. The added
+// entries are shared with the other contacts, not copied.
+func Merge(base *people.Person, others ...*people.Person) {
+	for _, other := range others {
+		base.EmailAddresses = appendMissing(base.EmailAddresses, other.EmailAddresses, func(e *people.EmailAddress) string {
+			return CanonicalEmail(e.Value)
+		})
+		base.PhoneNumbers = appendMissing(base.PhoneNumbers, other.PhoneNumbers, func(p *people.PhoneNumber) string {
+			if key := phoneKey(p.Value); key != "" {
+				return key
+			}
+			return foldKey(p.Value)
+		})
+		base.Addresses = appendMissing(base.Addresses, other.Addresses, func(a *people.Address) string {
+			if a.FormattedValue != "" && a.StreetAddress == "" && a.City == "" {
+				return foldKey(a.FormattedValue)
+			}
+			return foldKey(a.PoBox, a.ExtendedAddress, a.StreetAddress, a.City, a.Region, a.PostalCode, a.Country)
+		})
+		base.Organizations = appendMissing(base.Organizations, other.Organizations, func(o *people.Organization) string {
+			return foldKey(o.Name, o.Department, o.Title)
+		})
+		base.Urls = appendMissing(base.Urls, other.Urls, func(u *people.Url) string {
+			return foldKey(u.Value)
+		})
+		base.Nicknames = appendMissing(base.Nicknames, other.Nicknames, func(n *people.Nickname) string {
+			return foldKey(n.Value)
+		})
+		base.Relations = appendMissing(base.Relations, other.Relations, func(r *people.Relation) string {
+			return foldKey(r.Type, r.Person)
+		})
+		base.Events = appendMissing(base.Events, other.Events, func(e *people.Event) string {
+			return foldKey(e.Type, dateKey(e.Date))
+		})
+		base.ImClients = appendMissing(base.ImClients, other.ImClients, func(im *people.ImClient) string {
+			return foldKey(im.Protocol, im.Username)
+		})
+		base.UserDefined = appendMissing(base.UserDefined, other.UserDefined, func(u *people.UserDefined) string {
+			return foldKey(u.Key, u.Value)
+		})
+		base.ExternalIds = appendMissing(base.ExternalIds, other.ExternalIds, func(id *people.ExternalId) string {
+			return foldKey(id.Type, id.Value)
+		})
+		base.Memberships = appendMissing(base.Memberships, other.Memberships, func(m *people.Membership) string {
+			if m.ContactGroupMembership == nil {
+				return ""
+			}
+			return m.ContactGroupMembership.ContactGroupResourceName
+		})
+		base.ClientData = appendMissing(base.ClientData, other.ClientData, func(d *people.ClientData) string {
+			return d.Key
+		})
+
+		if len(base.Names) == 0 {
+			base.Names = other.Names
+		}
+		if len(base.Birthdays) == 0 {
+			base.Birthdays = other.Birthdays
+		}
+		if len(base.Biographies) == 0 {
+			base.Biographies = other.Biographies
+		}
+		if len(base.Genders) == 0 {
+			base.Genders = other.Genders
+		}
+		if len(base.FileAses) == 0 {
+			base.FileAses = other.FileAses
+		}
+	}
+}
+
+// appendMissing appends the entries of src whose key no entry of dst has.
+// Entries with an empty key are never appended.
+func appendMissing[T any](dst, src []*T, key func(*T) string) []*T {
+	seen := make(map[string]bool, len(dst))
+	for _, entry := range dst {
+		seen[key(entry)] = true
+	}
+	for _, entry := range src {
+		k := key(entry)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		dst = append(dst, entry)
+	}
+	return dst
+}
+
+// foldKey joins values into a case-insensitive comparison key, or "" if all
+// of them are blank.
+func foldKey(values ...string) string {
+	for i, value := range values {
+		values[i] = strings.ToLower(strings.Join(strings.Fields(value), " "))
+	}
+	key := strings.Join(values, "\x00")
+	if strings.Trim(key, "\x00") == "" {
+		return ""
+	}
+	return key
+}
+
+// dateKey formats a date for comparison
+func dateKey(date *people.Date) string {
+	if date == nil {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", date.Year, date.Month, date.Day)
+}
@@ -0,0 +1,152 @@
+package dedupe
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// nicknames groups given names that are used for the same person. A name
+// may be in several groups (e.g. "alex").
+var nicknames = [][]string{
+	{"abigail", "abby", "abbie", "gail"},
+	{"albert", "al", "bert", "bertie"},
+	{"alexander", "alex", "alec", "sandy", "sasha", "alejandro", "alessandro", "aleksandr"},
+	{"alexandra", "alex", "alexa", "sandra", "sandy", "sasha", "lexi"},
+	{"andrew", "andy", "drew", "andreas", "andres", "andrea", "andrei"},
+	{"anthony", "tony", "antonio", "anton"},
+	{"benjamin", "ben", "benny", "benji"},
+	{"catherine", "katherine", "kathryn", "cathy", "kathy", "kate", "katie", "kat", "kitty", "caterina", "katarina", "ekaterina", "katya"},
+	{"charles", "charlie", "chuck", "chas", "carlos", "carl", "karl"},
+	{"christopher", "chris", "kit", "topher", "cristobal"},
+	{"christina", "christine", "chris", "tina", "chrissy", "kristina", "kristin"},
+	{"daniel", "dan", "danny"},
+	{"david", "dave", "davy"},
+	{"deborah", "debra", "deb", "debbie"},
+	{"dorothy", "dot", "dottie", "dora"},
+	{"edward", "ed", "eddie", "ted", "teddy", "ned", "eduardo"},
+	{"elizabeth", "liz", "lizzie", "beth", "betty", "betsy", "eliza", "libby", "elisabeth", "isabel", "elsa", "lisa"},
+	{"frances", "fran", "frankie"},
+	{"francis", "frank", "frankie", "francisco", "francesco", "franz", "paco"},
+	{"frederick", "fred", "freddie", "fritz"},
+	{"gregory", "greg"},
+	{"henry", "harry", "hank", "enrique", "heinrich"},
+	{"jacob", "jake", "jakob"},
+	{"james", "jim", "jimmy", "jamie", "diego", "jaime"},
+	{"jennifer", "jen", "jenny", "jenn"},
+	{"john", "jack", "johnny", "jon", "juan", "johann", "johannes", "hans", "giovanni", "jean", "ivan", "sean"},
+	{"jonathan", "jon", "jonny"},
+	{"joseph", "joe", "joey", "jose", "giuseppe", "josef", "pepe"},
+	{"joshua", "josh"},
+	{"kenneth", "ken", "kenny"},
+	{"lawrence", "laurence", "larry", "laurie"},
+	{"leonard", "leo", "len", "lenny"},
+	{"margaret", "maggie", "meg", "peggy", "marge", "margie", "greta", "margarita", "rita"},
+	{"matthew", "matt", "matty", "mateo", "matteo", "matthias"},
+	{"michael", "mike", "mikey", "mick", "mickey", "miguel", "michel", "mikhail", "misha"},
+	{"nicholas", "nick", "nicky", "nico", "nikolai", "nicolas", "klaus"},
+	{"patricia", "pat", "patty", "trish", "tricia"},
+	{"patrick", "pat", "paddy", "patricio"},
+	{"peter", "pete", "pedro", "pierre", "pietro", "pyotr"},
+	{"philip", "phillip", "phil", "felipe", "filippo"},
+	{"rebecca", "becky", "becca"},
+	{"richard", "rich", "rick", "ricky", "dick", "ricardo", "riccardo"},
+	{"robert", "rob", "bob", "bobby", "robbie", "bert", "roberto"},
+	{"ronald", "ron", "ronnie"},
+	{"samuel", "sam", "sammy"},
+	{"samantha", "sam", "sammy"},
+	{"stephen", "steven", "steve", "stevie", "stefan", "esteban", "stephan"},
+	{"susan", "sue", "susie", "suzanne", "susanna"},
+	{"theodore", "ted", "teddy", "theo"},
+	{"thomas", "tom", "tommy", "tomas", "tomasz"},
+	{"timothy", "tim", "timmy"},
+	{"victoria", "vicky", "tori"},
+	{"william", "will", "bill", "billy", "willy", "liam", "guillermo", "wilhelm"},
+	{"zachary", "zach", "zack"},
+}
+
+// nicknameGroups maps each name to the indexes of its groups in nicknames
+var nicknameGroups = func() map[string][]int {
+	groups := make(map[string][]int)
+	for i, names := range nicknames {
+		for _, name := range names {
+			groups[name] = append(groups[name], i)
+		}
+	}
+	return groups
+}()
+
+// transliterations spell letters that do not decompose into a Latin base
+// letter and diacritics with Latin letters.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+// stripMarks removes diacritics, e.g. é becomes e
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// NormalizeName returns a name in lower-case ASCII where possible: diacritics
+// are removed and Cyrillic, Greek and special Latin letters are
+// transliterated, so "José", "Jose" and "JOSE" are equal, as are "Иван" and
+// "Ivan". Punctuation becomes spaces and runs of spaces are collapsed.
+func NormalizeName(name string) string {
+	stripped, _, err := transform.String(stripMarks, strings.ToLower(name))
+	if err != nil {
+		stripped = strings.ToLower(name)
+	}
+
+	var b strings.Builder
+	space := false
+	for _, r := range stripped {
+		if latin, ok := transliterations[r]; ok {
+			b.WriteString(latin)
+			space = false
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if r == '\'' || r == '’' {
+			continue
+		}
+		if !space && b.Len() > 0 {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// sameGivenName reports whether two normalized given names name the same
+// person: they are equal or share a nickname group.
+func sameGivenName(a, b string) bool {
+	if a == b {
+		return true
+	}
+	for _, i := range nicknameGroups[a] {
+		for _, j := range nicknameGroups[b] {
+			if i == j {
+				return true
+			}
+		}
+	}
+	return false
+}
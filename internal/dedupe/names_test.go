@@ -0,0 +1,48 @@
+package dedupe
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"José":             "jose",
+		"JOSE":             "jose",
+		"Иван":             "ivan",
+		"Σωκράτης":         "sokratis",
+		"Straße":           "strasse",
+		"Ørsted":           "orsted",
+		"Łukasz":           "lukasz",
+		"O'Brien":          "obrien",
+		"García-López":     "garcia lopez",
+		"  Anne   Marie  ": "anne marie",
+		"Dr. J. R. R.":     "dr j r r",
+		"":                 "",
+	}
+	for name, want := range tests {
+		if got := NormalizeName(name); got != want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSameGivenName(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"robert", "robert", true},
+		{"robert", "bob", true},
+		{"bob", "robbie", true},
+		{"sasha", "alexander", true},
+		{"sasha", "alexandra", true},
+		{"alex", "alexandra", true},
+		// "alex" is in both groups, but the full names are not
+		{"alexander", "alexandra", false},
+		{"bob", "bill", false},
+		{"unlisted", "other", false},
+	}
+	for _, tt := range tests {
+		if got := sameGivenName(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameGivenName(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
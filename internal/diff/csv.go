@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// csvValueSeparator joins the values of a field within one cell
+const csvValueSeparator = "; "
+
+// csvRow is a contact in the CSV report with the fields it shows.
+type csvRow struct {
+	change   string
+	contact  Contact
+	old, new *people.Person
+	fields   []string
+}
+
+// WriteCSV writes the contact differences of result, as compared from the
+// from and to backups, as CSV for review in a spreadsheet: one row per
+// added, removed or changed contact, with the old and new values of each
+// field in adjacent columns. Changed contacts only fill in the fields that
+// differ; added and removed contacts fill in every field they have. Link
+// and group changes are not included.
+func WriteCSV(w io.Writer, result *Result, from, to *models.BackupFile) error {
+	fromByKey, toByKey := contactsByKey(from.Contacts), contactsByKey(to.Contacts)
+
+	var rows []csvRow
+	for _, contact := range result.Contacts.Added {
+		person := toByKey[contact.Key]
+		rows = append(rows, csvRow{change: "added", contact: contact, new: person, fields: shownFields(person)})
+	}
+	for _, contact := range result.Contacts.Removed {
+		person := fromByKey[contact.Key]
+		rows = append(rows, csvRow{change: "removed", contact: contact, old: person, fields: shownFields(person)})
+	}
+	for _, change := range result.Contacts.Changed {
+		rows = append(rows, csvRow{change: "changed", contact: change.Contact, old: change.Old, new: change.New, fields: change.Fields})
+	}
+
+	// Only fields that appear in some row get columns, in field name order
+	used := make(map[string]bool)
+	for _, row := range rows {
+		for _, field := range row.fields {
+			used[field] = true
+		}
+	}
+	var columns []string
+	for _, field := range models.PersonFieldNames() {
+		if used[field] {
+			columns = append(columns, field)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{"Change", "Name", "Key"}
+	for _, field := range columns {
+		label := models.FieldLabel(field)
+		header = append(header, label+" (old)", label+" (new)")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	fromGroups, toGroups := from.UserGroupNames(), to.UserGroupNames()
+	for _, row := range rows {
+		record := []string{row.change, row.contact.Name, row.contact.Key}
+		for _, field := range columns {
+			if !slices.Contains(row.fields, field) {
+				record = append(record, "", "")
+				continue
+			}
+			record = append(record,
+				strings.Join(models.FieldValues(row.old, field, fromGroups), csvValueSeparator),
+				strings.Join(models.FieldValues(row.new, field, toGroups), csvValueSeparator))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// shownFields returns the fields of an added or removed contact that hold a
+// value, including its labels.
+func shownFields(person *people.Person) []string {
+	if person == nil {
+		return nil
+	}
+	fields := models.PresentFields(person)
+	if len(person.Memberships) > 0 {
+		fields = append(fields, "memberships")
+	}
+	return fields
+}
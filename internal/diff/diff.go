@@ -0,0 +1,284 @@
+// Package diff compares two backups of the same account and reports which
+// contacts and contact groups were added, removed or changed between them.
+package diff
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/integrity"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Result lists the differences between an older and a newer backup.
+type Result struct {
+	From Summary `json:"from"`
+	To   Summary `json:"to"`
+
+	Contacts ContactChanges `json:"contacts"`
+	Groups   GroupChanges   `json:"groups"`
+}
+
+// Summary describes one of the compared backups.
+type Summary struct {
+	// File is the path the backup was loaded from, if set by the caller
+	File string `json:"file,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	Contacts  int       `json:"contacts"`
+	Groups    int       `json:"groups"`
+
+	// Linked counts the contacts linked to Google profiles or other people
+	Linked int `json:"linked"`
+}
+
+// ContactChanges lists contacts by how they differ, each sorted by key.
+type ContactChanges struct {
+	Added   []Contact       `json:"added"`
+	Removed []Contact       `json:"removed"`
+	Changed []ContactChange `json:"changed"`
+
+	// Relinked are contacts in both backups whose links to Google profiles
+	// or other people changed. Links are metadata, so these contacts are
+	// only listed under Changed if their content changed too.
+	Relinked []LinkChange `json:"relinked"`
+}
+
+// LinkChange is a contact whose linked people changed between the backups.
+type LinkChange struct {
+	Contact
+
+	// Linked and Unlinked are the resource names of the people the contact
+	// was linked to or unlinked from
+	Linked   []string `json:"linked"`
+	Unlinked []string `json:"unlinked"`
+}
+
+// Contact identifies a contact in a backup.
+type Contact struct {
+	// Key is the resource name, or the contact's position for contacts
+	// without one (see integrity.Key)
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// ContactChange is a contact present in both backups with different content.
+type ContactChange struct {
+	Contact
+
+	// Fields are the person fields (People API names) whose values differ
+	Fields []string `json:"fields"`
+
+	// Old and New are the contact in the older and newer backup
+	Old *people.Person `json:"-"`
+	New *people.Person `json:"-"`
+}
+
+// GroupChanges lists user contact groups by how they differ, sorted by name.
+type GroupChanges struct {
+	Added   []Group       `json:"added"`
+	Removed []Group       `json:"removed"`
+	Changed []GroupChange `json:"changed"`
+}
+
+// Group identifies a user contact group and counts its members.
+type Group struct {
+	ResourceName string `json:"resource_name"`
+	Name         string `json:"name"`
+	Members      int    `json:"members"`
+}
+
+// GroupChange is a group present in both backups that was renamed or
+// gained or lost members.
+type GroupChange struct {
+	ResourceName string `json:"resource_name"`
+	OldName      string `json:"old_name"`
+	NewName      string `json:"new_name"`
+	OldMembers   int    `json:"old_members"`
+	NewMembers   int    `json:"new_members"`
+}
+
+// Empty reports whether the backups hold the same contacts and groups.
+func (r *Result) Empty() bool {
+	return len(r.Contacts.Added) == 0 && len(r.Contacts.Removed) == 0 && len(r.Contacts.Changed) == 0 &&
+		len(r.Contacts.Relinked) == 0 && len(r.Groups.Added) == 0 && len(r.Groups.Removed) == 0 && len(r.Groups.Changed) == 0
+}
+
+// Backups compares an older backup with a newer one. Contacts are matched
+// by resource name and compared by content hash, ignoring etags and
+// metadata; group member lists are applied to both backups first, so
+// membership changes show up as changes to the "memberships" field.
+func Backups(from, to *models.BackupFile) (*Result, error) {
+	from.ApplyGroupMembers()
+	to.ApplyGroupMembers()
+
+	result := &Result{
+		From: summarize(from),
+		To:   summarize(to),
+		Contacts: ContactChanges{
+			Added:    []Contact{},
+			Removed:  []Contact{},
+			Changed:  []ContactChange{},
+			Relinked: []LinkChange{},
+		},
+		Groups: GroupChanges{
+			Added:   []Group{},
+			Removed: []Group{},
+			Changed: []GroupChange{},
+		},
+	}
+
+	fromHashes, err := integrity.Hashes(from.Contacts)
+	if err != nil {
+		return nil, err
+	}
+	toHashes, err := integrity.Hashes(to.Contacts)
+	if err != nil {
+		return nil, err
+	}
+	fromByKey := contactsByKey(from.Contacts)
+	toByKey := contactsByKey(to.Contacts)
+
+	d := integrity.Compare(fromHashes, toHashes)
+	for _, key := range d.Added {
+		result.Contacts.Added = append(result.Contacts.Added, Contact{Key: key, Name: models.DisplayName(toByKey[key])})
+	}
+	for _, key := range d.Removed {
+		result.Contacts.Removed = append(result.Contacts.Removed, Contact{Key: key, Name: models.DisplayName(fromByKey[key])})
+	}
+	for _, key := range d.Changed {
+		before, after := fromByKey[key], toByKey[key]
+		fields := models.DiffFields(before, after)
+		if fields == nil {
+			fields = []string{}
+		}
+		result.Contacts.Changed = append(result.Contacts.Changed, ContactChange{
+			Contact: Contact{Key: key, Name: models.DisplayName(after)},
+			Fields:  fields,
+			Old:     before,
+			New:     after,
+		})
+	}
+
+	keys := make([]string, 0, len(toByKey))
+	for key := range toByKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		before, ok := fromByKey[key]
+		if !ok {
+			continue
+		}
+		after := toByKey[key]
+		oldLinks, newLinks := models.LinkedProfiles(before), models.LinkedProfiles(after)
+		linked, unlinked := difference(newLinks, oldLinks), difference(oldLinks, newLinks)
+		if len(linked) > 0 || len(unlinked) > 0 {
+			result.Contacts.Relinked = append(result.Contacts.Relinked, LinkChange{
+				Contact:  Contact{Key: key, Name: models.DisplayName(after)},
+				Linked:   linked,
+				Unlinked: unlinked,
+			})
+		}
+	}
+
+	fromGroups, toGroups := userGroups(from), userGroups(to)
+	for name, group := range toGroups {
+		old, ok := fromGroups[name]
+		if !ok {
+			result.Groups.Added = append(result.Groups.Added, group)
+			continue
+		}
+		if old.Name != group.Name || old.Members != group.Members {
+			result.Groups.Changed = append(result.Groups.Changed, GroupChange{
+				ResourceName: name,
+				OldName:      old.Name,
+				NewName:      group.Name,
+				OldMembers:   old.Members,
+				NewMembers:   group.Members,
+			})
+		}
+	}
+	for name, group := range fromGroups {
+		if _, ok := toGroups[name]; !ok {
+			result.Groups.Removed = append(result.Groups.Removed, group)
+		}
+	}
+	sortGroups(result.Groups.Added)
+	sortGroups(result.Groups.Removed)
+	sort.Slice(result.Groups.Changed, func(i, j int) bool {
+		a, b := result.Groups.Changed[i], result.Groups.Changed[j]
+		if a.NewName != b.NewName {
+			return a.NewName < b.NewName
+		}
+		return a.ResourceName < b.ResourceName
+	})
+
+	return result, nil
+}
+
+// summarize describes a backup by its creation time and sizes.
+func summarize(backup *models.BackupFile) Summary {
+	return Summary{
+		CreatedAt: backup.CreatedAt,
+		Contacts:  len(backup.Contacts),
+		Groups:    len(backup.GetUserGroups()),
+		Linked:    len(models.LinkedContacts(backup.Contacts)),
+	}
+}
+
+// difference returns the names in a that are not in b, in the order of a.
+func difference(a, b []string) []string {
+	result := []string{}
+	for _, name := range a {
+		if !slices.Contains(b, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// contactsByKey maps the hash keys of contacts to the contacts.
+func contactsByKey(contacts []*people.Person) map[string]*people.Person {
+	byKey := make(map[string]*people.Person, len(contacts))
+	for i, contact := range contacts {
+		byKey[integrity.Key(contact, i)] = contact
+	}
+	return byKey
+}
+
+// userGroups maps the resource names of a backup's user groups to the
+// groups and their member counts, counted from the contacts' memberships.
+func userGroups(backup *models.BackupFile) map[string]Group {
+	members := make(map[string]int)
+	for _, contact := range backup.Contacts {
+		for _, membership := range contact.Memberships {
+			if membership.ContactGroupMembership != nil {
+				members[membership.ContactGroupMembership.ContactGroupResourceName]++
+			}
+		}
+	}
+
+	groups := make(map[string]Group)
+	for _, group := range backup.GetUserGroups() {
+		groups[group.ResourceName] = Group{
+			ResourceName: group.ResourceName,
+			Name:         group.Name,
+			Members:      members[group.ResourceName],
+		}
+	}
+	return groups
+}
+
+// sortGroups orders groups by name, then resource name.
+func sortGroups(groups []Group) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].ResourceName < groups[j].ResourceName
+	})
+}
@@ -0,0 +1,134 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+func person(resourceName, given string) *people.Person {
+	return &people.Person{
+		ResourceName: resourceName,
+		Etag:         "etag-" + resourceName,
+		Names:        []*people.Name{{GivenName: given, DisplayName: given}},
+	}
+}
+
+func member(group string) *people.Membership {
+	return &people.Membership{ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: group}}
+}
+
+func group(resourceName, name string) *people.ContactGroup {
+	return &people.ContactGroup{ResourceName: resourceName, Name: name, GroupType: "USER_CONTACT_GROUP"}
+}
+
+func TestBackups(t *testing.T) {
+	from := models.NewBackupFile()
+	from.AddGroup(group("contactGroups/work", "Work"))
+	from.AddGroup(group("contactGroups/old", "Old"))
+	from.AddGroup(group("contactGroups/friends", "Friends"))
+	from.AddContact(person("people/same", "Same"))
+	from.AddContact(person("people/removed", "Removed"))
+	edited := person("people/edited", "Edited")
+	edited.EmailAddresses = []*people.EmailAddress{{Value: "old@example.com"}}
+	from.AddContact(edited)
+	from.AddContact(person("people/joined", "Joined"))
+	from.AddContact(person("people/etag", "Etag"))
+	from.GroupMembers = map[string][]string{"contactGroups/work": {"people/same"}}
+
+	to := models.NewBackupFile()
+	to.AddGroup(group("contactGroups/work", "Work"))
+	to.AddGroup(group("contactGroups/friends", "Pals"))
+	to.AddGroup(group("contactGroups/new", "New"))
+	same := person("people/same", "Same")
+	same.Memberships = []*people.Membership{member("contactGroups/work")}
+	to.AddContact(same)
+	edited = person("people/edited", "Edited")
+	edited.EmailAddresses = []*people.EmailAddress{{Value: "new@example.com"}}
+	edited.Nicknames = []*people.Nickname{{Value: "Ed"}}
+	to.AddContact(edited)
+	joined := person("people/joined", "Joined")
+	to.AddContact(joined)
+	to.GroupMembers = map[string][]string{"contactGroups/work": {"people/joined"}}
+	etag := person("people/etag", "Etag")
+	etag.Etag = "changed"
+	etag.Metadata = &people.PersonMetadata{Sources: []*people.Source{{Type: "CONTACT", UpdateTime: "2024-06-01T00:00:00Z"}}}
+	to.AddContact(etag)
+	to.AddContact(person("people/added", "Added"))
+
+	result, err := Backups(from, to)
+	if err != nil {
+		t.Fatalf("Backups: %v", err)
+	}
+
+	if want := []Contact{{Key: "people/added", Name: "Added"}}; !reflect.DeepEqual(result.Contacts.Added, want) {
+		t.Errorf("added = %+v, want %+v", result.Contacts.Added, want)
+	}
+	if want := []Contact{{Key: "people/removed", Name: "Removed"}}; !reflect.DeepEqual(result.Contacts.Removed, want) {
+		t.Errorf("removed = %+v, want %+v", result.Contacts.Removed, want)
+	}
+
+	// Member lists are applied first, so joining a group changes the
+	// contact's memberships; etag and metadata changes are not changes
+	changed := make(map[string][]string)
+	for _, change := range result.Contacts.Changed {
+		changed[change.Key] = change.Fields
+		if change.Old == nil || change.New == nil {
+			t.Errorf("change of %s lacks the old or new contact", change.Key)
+		}
+	}
+	want := map[string][]string{
+		"people/edited": {"emailAddresses", "nicknames"},
+		"people/joined": {"memberships"},
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+
+	if want := []Group{{ResourceName: "contactGroups/new", Name: "New"}}; !reflect.DeepEqual(result.Groups.Added, want) {
+		t.Errorf("added groups = %+v, want %+v", result.Groups.Added, want)
+	}
+	if want := []Group{{ResourceName: "contactGroups/old", Name: "Old"}}; !reflect.DeepEqual(result.Groups.Removed, want) {
+		t.Errorf("removed groups = %+v, want %+v", result.Groups.Removed, want)
+	}
+	wantGroups := []GroupChange{
+		{ResourceName: "contactGroups/friends", OldName: "Friends", NewName: "Pals"},
+		{ResourceName: "contactGroups/work", OldName: "Work", NewName: "Work", OldMembers: 1, NewMembers: 2},
+	}
+	if !reflect.DeepEqual(result.Groups.Changed, wantGroups) {
+		t.Errorf("changed groups = %+v, want %+v", result.Groups.Changed, wantGroups)
+	}
+
+	if result.From.Contacts != 5 || result.To.Contacts != 5 || result.From.Groups != 3 || result.To.Groups != 3 {
+		t.Errorf("summaries = %+v and %+v", result.From, result.To)
+	}
+	if result.Empty() {
+		t.Error("Empty() = true")
+	}
+}
+
+func TestBackupsIdentical(t *testing.T) {
+	build := func() *models.BackupFile {
+		backup := models.NewBackupFile()
+		backup.AddGroup(group("contactGroups/work", "Work"))
+		p := person("people/a", "A")
+		p.Memberships = []*people.Membership{member("contactGroups/work")}
+		backup.AddContact(p)
+		backup.AddContact(person("", "No resource name"))
+		return backup
+	}
+
+	result, err := Backups(build(), build())
+	if err != nil {
+		t.Fatalf("Backups: %v", err)
+	}
+	if !result.Empty() {
+		t.Errorf("identical backups differ: %+v", result)
+	}
+	if result.Contacts.Added == nil || result.Groups.Changed == nil {
+		t.Error("empty lists are nil, so they would be written as null in JSON")
+	}
+}
@@ -0,0 +1,230 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/integrity"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Digest summarizes how an address book changed over a period, as recorded
+// by the backups taken during it.
+type Digest struct {
+	// Since is the start of the period
+	Since time.Time `json:"since"`
+
+	// Complete is false if no backup predates Since, so changes made before
+	// the oldest backup are missing
+	Complete bool `json:"complete"`
+
+	// From and To are the first and last backup compared, Backups the
+	// number of backups in between, both included
+	From    Summary `json:"from"`
+	To      Summary `json:"to"`
+	Backups int     `json:"backups"`
+
+	Contacts DigestContacts `json:"contacts"`
+
+	// Groups are the differences between the user groups of the first and
+	// the last backup
+	Groups GroupChanges `json:"groups"`
+}
+
+// DigestContacts lists the contacts that changed during the period, each
+// sorted by the time of the change, then by name.
+type DigestContacts struct {
+	// Added are contacts created during the period that still exist
+	Added []DigestContact `json:"added"`
+
+	// Removed are contacts that existed at the start of the period and were
+	// deleted during it
+	Removed []DigestContact `json:"removed"`
+
+	// Changed are contacts that existed throughout the period and whose
+	// content changed
+	Changed []DigestChange `json:"changed"`
+
+	// Transient are contacts both created and deleted during the period
+	Transient []DigestContact `json:"transient"`
+}
+
+// DigestContact is a contact added or removed during the period. At is the
+// creation time of the first backup that shows the change, so the contact
+// was added or removed between that backup and the one before it.
+type DigestContact struct {
+	Contact
+	At time.Time `json:"at"`
+}
+
+// DigestChange is a contact changed during the period.
+type DigestChange struct {
+	Contact
+
+	// Fields are the person fields (People API names) that changed at some
+	// point, sorted
+	Fields []string `json:"fields"`
+
+	// Changes counts the backups that show a change, and At is the creation
+	// time of the last of them
+	Changes int       `json:"changes"`
+	At      time.Time `json:"at"`
+}
+
+// Empty reports whether nothing changed during the period.
+func (d *Digest) Empty() bool {
+	return len(d.Contacts.Added) == 0 && len(d.Contacts.Removed) == 0 && len(d.Contacts.Changed) == 0 &&
+		len(d.Contacts.Transient) == 0 && len(d.Groups.Added) == 0 && len(d.Groups.Removed) == 0 && len(d.Groups.Changed) == 0
+}
+
+// history is what the backups of a period record about one contact
+type history struct {
+	name string
+
+	// first and last tell whether the first and the last backup hold it
+	first, last bool
+
+	// added, removed and changed are the creation times of the backups
+	// that first showed it added, last showed it removed and last showed
+	// it changed
+	added, removed, changed time.Time
+
+	fields  map[string]bool
+	changes int
+}
+
+// History compares the backups of a period, from the newest backup taken at
+// or before since (or the oldest backup, if none is that old) to the newest
+// one, and collects every contact added, removed or changed along the way.
+// Consecutive backups are compared as Backups does, so a contact created and
+// deleted between two backups is not seen. Backups need not be sorted.
+func History(backups []*models.BackupFile, since time.Time) (*Digest, error) {
+	sorted := slices.Clone(backups)
+	slices.SortStableFunc(sorted, func(a, b *models.BackupFile) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	first := 0
+	complete := false
+	for i, backup := range sorted {
+		if !backup.CreatedAt.After(since) {
+			first = i
+			complete = true
+		}
+	}
+	sorted = sorted[first:]
+	if len(sorted) < 2 {
+		return nil, fmt.Errorf("at least two backups are needed, found %d for the period", len(sorted))
+	}
+
+	contacts := make(map[string]*history)
+	entry := func(contact Contact) *history {
+		h := contacts[contact.Key]
+		if h == nil {
+			h = &history{fields: make(map[string]bool)}
+			contacts[contact.Key] = h
+		}
+		h.name = contact.Name
+		return h
+	}
+	for i, contact := range sorted[0].Contacts {
+		entry(Contact{Key: integrity.Key(contact, i), Name: models.DisplayName(contact)}).first = true
+	}
+
+	for i := 1; i < len(sorted); i++ {
+		step, err := Backups(sorted[i-1], sorted[i])
+		if err != nil {
+			return nil, err
+		}
+		at := sorted[i].CreatedAt
+		for _, contact := range step.Contacts.Added {
+			h := entry(contact)
+			if h.added.IsZero() {
+				h.added = at
+			}
+		}
+		for _, contact := range step.Contacts.Removed {
+			entry(contact).removed = at
+		}
+		for _, change := range step.Contacts.Changed {
+			h := entry(change.Contact)
+			for _, field := range change.Fields {
+				h.fields[field] = true
+			}
+			h.changes++
+			h.changed = at
+		}
+	}
+	last := sorted[len(sorted)-1]
+	for i, contact := range last.Contacts {
+		if h := contacts[integrity.Key(contact, i)]; h != nil {
+			h.last = true
+			h.name = models.DisplayName(contact)
+		}
+	}
+
+	net, err := Backups(sorted[0], last)
+	if err != nil {
+		return nil, err
+	}
+	digest := &Digest{
+		Since:    since,
+		Complete: complete,
+		From:     net.From,
+		To:       net.To,
+		Backups:  len(sorted),
+		Contacts: DigestContacts{
+			Added:     []DigestContact{},
+			Removed:   []DigestContact{},
+			Changed:   []DigestChange{},
+			Transient: []DigestContact{},
+		},
+		Groups: net.Groups,
+	}
+
+	for key, h := range contacts {
+		contact := Contact{Key: key, Name: h.name}
+		switch {
+		case !h.first && h.last:
+			digest.Contacts.Added = append(digest.Contacts.Added, DigestContact{Contact: contact, At: h.added})
+		case h.first && !h.last:
+			digest.Contacts.Removed = append(digest.Contacts.Removed, DigestContact{Contact: contact, At: h.removed})
+		case !h.first && !h.last:
+			digest.Contacts.Transient = append(digest.Contacts.Transient, DigestContact{Contact: contact, At: h.added})
+		case h.changes > 0:
+			fields := make([]string, 0, len(h.fields))
+			for field := range h.fields {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			digest.Contacts.Changed = append(digest.Contacts.Changed, DigestChange{
+				Contact: contact,
+				Fields:  fields,
+				Changes: h.changes,
+				At:      h.changed,
+			})
+		}
+	}
+	sortByTime(digest.Contacts.Added, func(c DigestContact) (time.Time, Contact) { return c.At, c.Contact })
+	sortByTime(digest.Contacts.Removed, func(c DigestContact) (time.Time, Contact) { return c.At, c.Contact })
+	sortByTime(digest.Contacts.Transient, func(c DigestContact) (time.Time, Contact) { return c.At, c.Contact })
+	sortByTime(digest.Contacts.Changed, func(c DigestChange) (time.Time, Contact) { return c.At, c.Contact })
+
+	return digest, nil
+}
+
+// sortByTime sorts entries by time, then name, then key.
+func sortByTime[T any](entries []T, of func(T) (time.Time, Contact)) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, ci := of(entries[i])
+		tj, cj := of(entries[j])
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		if ci.Name != cj.Name {
+			return ci.Name < cj.Name
+		}
+		return ci.Key < cj.Key
+	})
+}
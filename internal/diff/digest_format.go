@@ -0,0 +1,237 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// digestDate is how the digest shows the time of a change
+const digestDate = "2006-01-02"
+
+// digestHTMLTemplate renders a digest as a standalone HTML page, also used
+// as the HTML part of digest emails
+var digestHTMLTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Local().Format(digestDate) },
+	"join": func(values []string) string { return strings.Join(values, ", ") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { text-align: left; padding: 0.2em 1em 0.2em 0; vertical-align: top; }
+.date { color: #666; white-space: nowrap; }
+.note { color: #a60; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Period}}</p>
+{{if not .Digest.Complete}}<p class="note">No backup predates the start of the period, so changes before {{date .Digest.From.CreatedAt}} are missing.</p>
+{{end}}<p>{{.Summary}}</p>
+{{with .Digest.Contacts}}{{if .Added}}<h2>Added contacts ({{len .Added}})</h2>
+<table>
+{{range .Added}}<tr><td class="date">{{date .At}}</td><td>{{.Name}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Removed}}<h2>Removed contacts ({{len .Removed}})</h2>
+<table>
+{{range .Removed}}<tr><td class="date">{{date .At}}</td><td>{{.Name}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Changed}}<h2>Changed contacts ({{len .Changed}})</h2>
+<table>
+{{range .Changed}}<tr><td class="date">{{date .At}}</td><td>{{.Name}}</td><td>{{join .Fields}}{{if gt .Changes 1}} ({{.Changes}} changes){{end}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Transient}}<h2>Added and removed again ({{len .Transient}})</h2>
+<table>
+{{range .Transient}}<tr><td class="date">{{date .At}}</td><td>{{.Name}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}{{with .Digest.Groups}}{{if or .Added .Removed .Changed}}<h2>Labels</h2>
+<table>
+{{range .Added}}<tr><td>Added</td><td>{{.Name}}</td><td>{{.Members}} members</td></tr>
+{{end}}{{range .Removed}}<tr><td>Removed</td><td>{{.Name}}</td><td>{{.Members}} members</td></tr>
+{{end}}{{range .Changed}}<tr><td>Changed</td><td>{{.NewName}}</td><td>{{if ne .OldName .NewName}}renamed from {{.OldName}}, {{end}}{{.OldMembers}} &rarr; {{.NewMembers}} members</td></tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// digestPage is the data passed to digestHTMLTemplate
+type digestPage struct {
+	Title, Period, Summary string
+	Digest                 *Digest
+}
+
+// DigestEmailOptions addresses a digest email.
+type DigestEmailOptions struct {
+	From string
+	To   []string
+
+	// Subject defaults to the digest's title and summary
+	Subject string
+}
+
+// Title returns the heading of the digest.
+func (d *Digest) Title() string {
+	return "Contact changes since " + d.Since.Local().Format(digestDate)
+}
+
+// Summary returns a one-line count of the changes in the digest.
+func (d *Digest) Summary() string {
+	if d.Empty() {
+		return "No contacts or labels changed."
+	}
+	c, g := d.Contacts, d.Groups
+	summary := fmt.Sprintf("%d contacts added, %d removed, %d changed", len(c.Added), len(c.Removed), len(c.Changed))
+	if len(c.Transient) > 0 {
+		summary += fmt.Sprintf(", %d added and removed again", len(c.Transient))
+	}
+	if changed := len(g.Added) + len(g.Removed) + len(g.Changed); changed > 0 {
+		summary += fmt.Sprintf("; %d labels changed", changed)
+	}
+	return summary + "."
+}
+
+// period describes the backups the digest compares.
+func (d *Digest) period() string {
+	return fmt.Sprintf("Compared %d backups from %s (%d contacts) to %s (%d contacts).",
+		d.Backups, d.From.CreatedAt.Local().Format(digestDate), d.From.Contacts,
+		d.To.CreatedAt.Local().Format(digestDate), d.To.Contacts)
+}
+
+// WriteDigestText writes the digest as plain text to w.
+func WriteDigestText(w io.Writer, d *Digest) error {
+	var b strings.Builder
+	b.WriteString(d.Title() + "\n\n")
+	b.WriteString(d.period() + "\n")
+	if !d.Complete {
+		fmt.Fprintf(&b, "No backup predates the start of the period, so changes before %s are missing.\n",
+			d.From.CreatedAt.Local().Format(digestDate))
+	}
+	b.WriteString(d.Summary() + "\n")
+
+	list := func(title string, contacts []DigestContact) {
+		if len(contacts) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n", title, len(contacts))
+		for _, contact := range contacts {
+			fmt.Fprintf(&b, "  %s  %s\n", contact.At.Local().Format(digestDate), contact.Name)
+		}
+	}
+	list("Added contacts", d.Contacts.Added)
+	list("Removed contacts", d.Contacts.Removed)
+	if len(d.Contacts.Changed) > 0 {
+		fmt.Fprintf(&b, "\nChanged contacts (%d):\n", len(d.Contacts.Changed))
+		for _, change := range d.Contacts.Changed {
+			fmt.Fprintf(&b, "  %s  %s: %s", change.At.Local().Format(digestDate), change.Name, strings.Join(change.Fields, ", "))
+			if change.Changes > 1 {
+				fmt.Fprintf(&b, " (%d changes)", change.Changes)
+			}
+			b.WriteString("\n")
+		}
+	}
+	list("Added and removed again", d.Contacts.Transient)
+
+	g := d.Groups
+	if len(g.Added)+len(g.Removed)+len(g.Changed) > 0 {
+		b.WriteString("\nLabels:\n")
+		for _, group := range g.Added {
+			fmt.Fprintf(&b, "  Added %s (%d members)\n", group.Name, group.Members)
+		}
+		for _, group := range g.Removed {
+			fmt.Fprintf(&b, "  Removed %s (%d members)\n", group.Name, group.Members)
+		}
+		for _, change := range g.Changed {
+			fmt.Fprintf(&b, "  Changed %s: ", change.NewName)
+			if change.OldName != change.NewName {
+				fmt.Fprintf(&b, "renamed from %q, ", change.OldName)
+			}
+			fmt.Fprintf(&b, "%d -> %d members\n", change.OldMembers, change.NewMembers)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDigestHTML writes the digest as a standalone HTML page to w.
+func WriteDigestHTML(w io.Writer, d *Digest) error {
+	page := digestPage{Title: d.Title(), Period: d.period(), Summary: d.Summary(), Digest: d}
+	if err := digestHTMLTemplate.Execute(w, page); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+	return nil
+}
+
+// WriteDigestEmail writes the digest to w as an email message with a plain
+// text and an HTML part, ready to be handed to sendmail -t.
+func WriteDigestEmail(w io.Writer, d *Digest, opts DigestEmailOptions) error {
+	var text, html bytes.Buffer
+	if err := WriteDigestText(&text, d); err != nil {
+		return err
+	}
+	if err := WriteDigestHTML(&html, d); err != nil {
+		return err
+	}
+
+	subject := opts.Subject
+	if subject == "" {
+		subject = d.Title() + ": " + strings.TrimSuffix(d.Summary(), ".")
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		pw, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write(part.content); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return err
+	}
+
+	var header strings.Builder
+	if opts.From != "" {
+		fmt.Fprintf(&header, "From: %s\r\n", opts.From)
+	}
+	if len(opts.To) > 0 {
+		fmt.Fprintf(&header, "To: %s\r\n", strings.Join(opts.To, ", "))
+	}
+	fmt.Fprintf(&header, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&header, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	header.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&header, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+	_, err := w.Write(body.Bytes())
+	return err
+}
@@ -0,0 +1,225 @@
+// Package encryption encrypts backup files with age (https://age-encryption.org).
+//
+// Besides native X25519 keys, recipients and identities can be provided by
+// age plugins such as age-plugin-yubikey or age-plugin-tpm, so that
+// decrypting a backup requires a hardware token. Plugins are separate
+// programs found on $PATH as age-plugin-NAME.
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/plugin"
+)
+
+// header starts every binary age file
+const header = "age-encryption.org/v1\n"
+
+// scryptStanza starts the header line of a file encrypted with a passphrase
+const scryptStanza = "-> scrypt "
+
+// PassphraseEnv is the environment variable read for the passphrase of
+// passphrase-encrypted backups, so scheduled backups can run unattended
+const PassphraseEnv = "CONTACTS_BACKUP_PASSPHRASE"
+
+// IsEncrypted reports whether data is an age-encrypted file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(header))
+}
+
+// IsPassphraseEncrypted reports whether data is an age file encrypted with
+// a passphrase rather than to recipients.
+func IsPassphraseEncrypted(data []byte) bool {
+	if !IsEncrypted(data) {
+		return false
+	}
+	// The stanzas follow the version line; a passphrase-encrypted file has
+	// exactly one, of type scrypt
+	rest := data[len(header):]
+	return bytes.HasPrefix(rest, []byte(scryptStanza))
+}
+
+// PassphraseRecipient returns a recipient that encrypts with a key derived
+// from passphrase with scrypt. It cannot be combined with other recipients.
+func PassphraseRecipient(passphrase string) (age.Recipient, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("the passphrase must not be empty")
+	}
+	r, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key from passphrase: %w", err)
+	}
+	return r, nil
+}
+
+// DecryptPassphrase decrypts an age file encrypted with a passphrase.
+func DecryptPassphrase(data []byte, passphrase string) ([]byte, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key from passphrase: %w", err)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, fmt.Errorf("incorrect passphrase")
+		}
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// ParseRecipients parses recipients given on the command line or in the
+// config file. Each is a native public key (age1...), a plugin recipient
+// (age1yubikey1..., age1tpm1...) or the path of a file listing recipients one
+// per line, with # comments.
+func ParseRecipients(specs []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, spec := range specs {
+		if strings.HasPrefix(spec, "age1") {
+			r, err := parseRecipient(spec)
+			if err != nil {
+				return nil, err
+			}
+			recipients = append(recipients, r)
+			continue
+		}
+
+		lines, err := readKeyFile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipients file: %w", err)
+		}
+		for _, line := range lines {
+			r, err := parseRecipient(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", spec, err)
+			}
+			recipients = append(recipients, r)
+		}
+	}
+	return recipients, nil
+}
+
+// parseRecipient parses a single native or plugin recipient.
+func parseRecipient(s string) (age.Recipient, error) {
+	if r, err := age.ParseX25519Recipient(s); err == nil {
+		return r, nil
+	}
+	r, err := plugin.NewRecipient(s, terminalUI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", s, err)
+	}
+	return r, nil
+}
+
+// LoadIdentities reads identity files, as written by age-keygen or by a
+// plugin (for example age-plugin-yubikey --identity). Each line holds a
+// native key (AGE-SECRET-KEY-1...) or a plugin identity (AGE-PLUGIN-...).
+func LoadIdentities(paths []string) ([]age.Identity, error) {
+	var identities []age.Identity
+	for _, path := range paths {
+		lines, err := readKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file: %w", err)
+		}
+		for _, line := range lines {
+			var id age.Identity
+			if strings.HasPrefix(line, "AGE-PLUGIN-") {
+				id, err = plugin.NewIdentity(line, terminalUI)
+			} else {
+				id, err = age.ParseX25519Identity(line)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid identity: %w", path, err)
+			}
+			identities = append(identities, id)
+		}
+	}
+	return identities, nil
+}
+
+// IdentityRecipients returns the recipients of identities, which files
+// encrypted to them can be decrypted with.
+func IdentityRecipients(identities []age.Identity) []age.Recipient {
+	var recipients []age.Recipient
+	for _, id := range identities {
+		switch id := id.(type) {
+		case *age.X25519Identity:
+			recipients = append(recipients, id.Recipient())
+		case *plugin.Identity:
+			recipients = append(recipients, id.Recipient())
+		}
+	}
+	return recipients
+}
+
+// Encrypt returns a writer that encrypts to the recipients and writes the
+// result to w. The caller must close it to flush the final chunk.
+func Encrypt(w io.Writer, recipients []age.Recipient) (io.WriteCloser, error) {
+	enc, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return enc, nil
+}
+
+// Decrypt decrypts an age file with the first identity that matches.
+func Decrypt(data []byte, identities []age.Identity) ([]byte, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no identities to decrypt with")
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(data), identities...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, fmt.Errorf("none of the identities can decrypt this file")
+		}
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// readKeyFile returns the non-empty, non-comment lines of a key file.
+func readKeyFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s: no keys found", path)
+	}
+	return lines, nil
+}
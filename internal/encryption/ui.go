@@ -0,0 +1,98 @@
+package encryption
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age/plugin"
+	"golang.org/x/term"
+)
+
+// terminalUI lets plugins talk to the user on the terminal, for example to
+// ask for a PIN or to ask them to touch their hardware token.
+var terminalUI = &plugin.ClientUI{
+	DisplayMessage: func(name, message string) error {
+		fmt.Fprintf(os.Stderr, "age-plugin-%s: %s\n", name, message)
+		return nil
+	},
+	RequestValue: func(name, prompt string, secret bool) (string, error) {
+		fmt.Fprintf(os.Stderr, "age-plugin-%s: %s ", name, prompt)
+		if secret {
+			value, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return "", fmt.Errorf("failed to read value: %w", err)
+			}
+			return string(value), nil
+		}
+		return readLine()
+	},
+	Confirm: func(name, prompt, yes, no string) (bool, error) {
+		if no == "" {
+			fmt.Fprintf(os.Stderr, "age-plugin-%s: %s [%s] ", name, prompt, yes)
+		} else {
+			fmt.Fprintf(os.Stderr, "age-plugin-%s: %s [%s/%s] ", name, prompt, yes, no)
+		}
+		answer, err := readLine()
+		if err != nil {
+			return false, err
+		}
+		return no == "" || strings.EqualFold(answer, yes), nil
+	},
+	WaitTimer: func(name string) {
+		fmt.Fprintf(os.Stderr, "Waiting for age-plugin-%s (you may need to touch your token)...\n", name)
+	},
+}
+
+// readLine reads one line from standard input.
+func readLine() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// ReadPassphrase returns the passphrase from PassphraseEnv or, if it is not
+// set, asks for it on the terminal without echoing it. With confirm, the
+// passphrase is asked twice and must match.
+func ReadPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no terminal to ask for the passphrase: set %s", PassphraseEnv)
+	}
+
+	passphrase, err := readSecret(prompt)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("the passphrase must not be empty")
+	}
+	if confirm {
+		again, err := readSecret("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readSecret prints a prompt on stderr and reads a line from the terminal
+// without echoing it.
+func readSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	value, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(value), nil
+}
@@ -0,0 +1,252 @@
+// Package evidence packages an export with its provenance: checksums of
+// every file, an attestation of where the contacts came from, signed with an
+// ed25519 key and timestamped by an RFC 3161 timestamp authority, so a third
+// party can check the export has not changed since it was made.
+package evidence
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Names of the files the evidence adds to the archive
+const (
+	AttestationFile = "attestation.json"
+	SignatureFile   = "attestation.sig"
+	PublicKeyFile   = "signing-key.pem"
+	RequestFile     = "attestation.tsq"
+	ResponseFile    = "attestation.tsr"
+	ChecksumFile    = "SHA256SUMS"
+	ReadmeFile      = "README.txt"
+)
+
+// File is a file of the archive.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Attestation records what the archive holds and where it came from. It is
+// what gets signed and timestamped.
+type Attestation struct {
+	// Tool and Version name the program that made the archive
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+
+	// CreatedAt is the local clock's time when the archive was made; the
+	// timestamp token gives an independent one
+	CreatedAt time.Time `json:"created_at"`
+	Host      string    `json:"host,omitempty"`
+
+	// Account is the Google account the tool was signed in to
+	Account Account `json:"account"`
+
+	Source Source `json:"source"`
+
+	// Files are the checksums of the exported files
+	Files []FileDigest `json:"files"`
+
+	// PublicKey verifies the signature, in the form 'approve keygen' prints
+	PublicKey string `json:"public_key"`
+
+	// TSA is the timestamp authority asked to timestamp the attestation
+	TSA string `json:"tsa,omitempty"`
+}
+
+// Account identifies a Google account.
+type Account struct {
+	// ResourceName is people/ followed by the account's ID, which never
+	// changes
+	ResourceName string `json:"resource_name"`
+	Email        string `json:"email,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Source describes the backup the export was made from.
+type Source struct {
+	File      string    `json:"file"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+	Contacts  int       `json:"contacts"`
+	Groups    int       `json:"groups"`
+
+	// ManifestRoot is the Merkle root of the exported contacts' hashes, as
+	// in manifest.json
+	ManifestRoot string `json:"manifest_root"`
+}
+
+// FileDigest is the checksum of one file.
+type FileDigest struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Evidence is a sealed archive, ready to be written.
+type Evidence struct {
+	Files       []File
+	Attestation *Attestation
+
+	// attestation is the encoded attestation that was signed
+	attestation []byte
+	signature   []byte
+	publicKey   []byte
+
+	// Timestamp is nil if the attestation was not timestamped
+	Timestamp *Timestamp
+}
+
+// Seal records the checksums of files in a, signs it with key and, unless
+// tsa is empty, has the timestamp authority at tsa timestamp it.
+func Seal(ctx context.Context, files []File, a *Attestation, key ed25519.PrivateKey, publicKey, tsa string) (*Evidence, error) {
+	a.Files = make([]FileDigest, 0, len(files))
+	for _, file := range files {
+		a.Files = append(a.Files, FileDigest{Name: file.Name, Size: len(file.Data), SHA256: sha256Hex(file.Data)})
+	}
+	a.PublicKey = publicKey
+	a.TSA = tsa
+	a.CreatedAt = a.CreatedAt.UTC().Truncate(time.Second)
+
+	encoded, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attestation: %w", err)
+	}
+	encoded = append(encoded, '\n')
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	e := &Evidence{
+		Files:       files,
+		Attestation: a,
+		attestation: encoded,
+		signature:   ed25519.Sign(key, encoded),
+		publicKey:   pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+	}
+	if tsa != "" {
+		if e.Timestamp, err = RequestTimestamp(ctx, tsa, encoded); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// WriteZip writes the archive to w: the exported files, the attestation,
+// its signature, public key and timestamp, a SHA256SUMS file covering all of
+// them and a README explaining how to check them.
+func (e *Evidence) WriteZip(w io.Writer) error {
+	files := append([]File{}, e.Files...)
+	files = append(files,
+		File{AttestationFile, e.attestation},
+		File{SignatureFile, e.signature},
+		File{PublicKeyFile, e.publicKey},
+	)
+	if e.Timestamp != nil {
+		files = append(files,
+			File{RequestFile, e.Timestamp.Request},
+			File{ResponseFile, e.Timestamp.Response},
+		)
+	}
+
+	var sums strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&sums, "%s  %s\n", sha256Hex(file.Data), file.Name)
+	}
+	files = append(files,
+		File{ChecksumFile, []byte(sums.String())},
+		File{ReadmeFile, []byte(e.readme())},
+	)
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: e.Attestation.CreatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.Name, err)
+		}
+		if _, err := fw.Write(file.Data); err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.Name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// readme explains the archive to whoever receives it.
+func (e *Evidence) readme() string {
+	a := e.Attestation
+	var b strings.Builder
+	fmt.Fprintf(&b, "Contact export made by %s %s on %s.\n\n", a.Tool, a.Version, a.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Account: %s", a.Account.ResourceName)
+	if a.Account.Email != "" {
+		fmt.Fprintf(&b, " (%s)", a.Account.Email)
+	}
+	fmt.Fprintf(&b, "\nSource:  %s, backup of %s, %d contacts exported\n", a.Source.File,
+		a.Source.CreatedAt.UTC().Format(time.RFC3339), a.Source.Contacts)
+	if e.Timestamp != nil {
+		fmt.Fprintf(&b, "Timestamp: %s by %s (serial %s)\n", e.Timestamp.Time.Format(time.RFC3339), e.Timestamp.TSA, e.Timestamp.Serial)
+	} else {
+		b.WriteString("Timestamp: none; the attestation was not timestamped\n")
+	}
+
+	b.WriteString(`
+Files:
+`)
+	for _, file := range a.Files {
+		fmt.Fprintf(&b, "  %s\n", file.Name)
+	}
+	b.WriteString(`  ` + AttestationFile + `   what the export holds, where it came from and the checksums above
+  ` + SignatureFile + `    ed25519 signature of ` + AttestationFile + `
+  ` + PublicKeyFile + `    public key the signature verifies with
+`)
+	if e.Timestamp != nil {
+		b.WriteString(`  ` + RequestFile + `    RFC 3161 timestamp request for ` + AttestationFile + `
+  ` + ResponseFile + `    RFC 3161 timestamp token from the timestamp authority
+`)
+	}
+	b.WriteString(`  ` + ChecksumFile + `         SHA-256 of every file above
+
+To check the archive:
+
+  # The files are unchanged
+  sha256sum -c ` + ChecksumFile + `
+
+  # The attestation was signed with the key; compare the key with the
+  # public_key in ` + AttestationFile + ` and with a copy obtained separately
+  openssl pkeyutl -verify -pubin -inkey ` + PublicKeyFile + ` -rawin \
+    -in ` + AttestationFile + ` -sigfile ` + SignatureFile + `
+`)
+	if e.Timestamp != nil {
+		b.WriteString(`
+  # The attestation existed at the time of the timestamp; get the
+  # authority's CA certificate (and, if it has one, its signing
+  # certificate) from the authority
+  openssl ts -reply -in ` + ResponseFile + ` -text
+  openssl ts -verify -data ` + AttestationFile + ` -in ` + ResponseFile + ` -CAfile tsa-ca.pem
+`)
+	}
+	return b.String()
+}
+
+// sha256Hex returns the hex SHA-256 of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,211 @@
+package evidence
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTSA is the RFC 3161 timestamp authority used unless another is given
+const DefaultTSA = "https://freetsa.org/tsr"
+
+var (
+	oidSHA256     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+// Timestamp is a timestamp token from an RFC 3161 timestamp authority over
+// the SHA-256 of some data.
+type Timestamp struct {
+	// TSA is the URL of the authority
+	TSA string
+
+	// Request and Response are the DER-encoded TimeStampReq and
+	// TimeStampResp, as openssl ts reads them
+	Request  []byte
+	Response []byte
+
+	// Time is the time the authority put in the token, and Serial the
+	// token's serial number
+	Time   time.Time
+	Serial string
+}
+
+// The ASN.1 structures of RFC 3161 and RFC 5652, as far as they are read.
+// Trailing fields that are not needed are left out; encoding/asn1 ignores
+// them.
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapContentInfo
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"tag:0"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// pkiStatusText names the PKIStatus values of RFC 3161
+var pkiStatusText = []string{"granted", "grantedWithMods", "rejection", "waiting", "revocationWarning", "revocationNotification"}
+
+// RequestTimestamp asks the timestamp authority at tsa to timestamp data,
+// and checks that the token it returns covers data and answers this request.
+// The signature on the token is not checked here; openssl ts -verify does
+// that with the authority's certificate.
+func RequestTimestamp(ctx context.Context, tsa string, data []byte) (*Timestamp, error) {
+	digest := sha256.Sum256(data)
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a nonce: %w", err)
+	}
+	request, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: digest[:],
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the timestamp request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tsa, bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp authority %q: %w", tsa, err)
+	}
+	req.Header.Set("Content-Type", "application/timestamp-query")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the timestamp authority: %w", err)
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the timestamp response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the timestamp authority returned %s", resp.Status)
+	}
+
+	ts, err := parseTimestampResponse(response, digest[:], nonce)
+	if err != nil {
+		return nil, err
+	}
+	ts.TSA = tsa
+	ts.Request = request
+	return ts, nil
+}
+
+// parseTimestampResponse reads a TimeStampResp and checks that its token
+// covers digest and carries nonce.
+func parseTimestampResponse(response, digest []byte, nonce *big.Int) (*Timestamp, error) {
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(response, &resp); err != nil {
+		return nil, fmt.Errorf("invalid timestamp response: %w", err)
+	}
+	if status := resp.Status.Status; status != 0 && status != 1 {
+		text := fmt.Sprintf("status %d", status)
+		if status < len(pkiStatusText) {
+			text = pkiStatusText[status]
+		}
+		if len(resp.Status.StatusString) > 0 {
+			text += ": " + strings.Join(resp.Status.StatusString, "; ")
+		}
+		return nil, fmt.Errorf("the timestamp authority refused the request (%s)", text)
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, errors.New("the timestamp response has no token")
+	}
+
+	var token contentInfo
+	if _, err := asn1.Unmarshal(resp.TimeStampToken.FullBytes, &token); err != nil || !token.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("invalid timestamp token: not a signed data structure")
+	}
+	var signed signedData
+	if _, err := asn1.Unmarshal(token.Content.Bytes, &signed); err != nil {
+		return nil, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	if !signed.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, errors.New("invalid timestamp token: no timestamp info")
+	}
+	var content []byte
+	if _, err := asn1.Unmarshal(signed.EncapContentInfo.EContent.Bytes, &content); err != nil {
+		return nil, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(content, &info); err != nil {
+		return nil, fmt.Errorf("invalid timestamp info: %w", err)
+	}
+
+	switch {
+	case !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) || !bytes.Equal(info.MessageImprint.HashedMessage, digest):
+		return nil, errors.New("the timestamp token covers different data")
+	case info.Nonce == nil || info.Nonce.Cmp(nonce) != 0:
+		return nil, errors.New("the timestamp token does not answer this request")
+	}
+
+	return &Timestamp{
+		Response: response,
+		Time:     info.GenTime.UTC(),
+		Serial:   info.SerialNumber.Text(16),
+	}, nil
+}
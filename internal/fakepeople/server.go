@@ -0,0 +1,589 @@
+// Package fakepeople provides an in-memory fake of the Google People API.
+//
+// It serves the subset of endpoints used by this tool so that restore logic
+// can be exercised (self tests, simulations, benchmarks) without touching a
+// real Google account. Requests never leave the process: the fake is plugged
+// in as the transport of the HTTP client passed to contacts.NewClient.
+//
+// Like the real API, the fake refuses contacts that break the limits of
+// Google Contacts or hold invalid values, failing the whole batch they were
+// sent in.
+package fakepeople
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// systemGroups are the contact groups every account has.
+var systemGroups = []string{"myContacts", "starred", "friends", "family", "coworkers", "chatBuddies", "all", "blocked"}
+
+// Server is an in-memory People API. It is safe for concurrent use.
+type Server struct {
+	mu         sync.Mutex
+	people     map[string]*people.Person
+	order      []string
+	groups     map[string]*people.ContactGroup
+	groupOrder []string
+	nextID     int
+	requests   int
+
+	// createBatches and updateBatches count batch requests, to locate
+	// rejections
+	createBatches int
+	updateBatches int
+
+	// collect records invalid contacts in rejections instead of failing
+	// their request
+	collect    bool
+	rejections []Rejection
+}
+
+// New returns a fake People API with the system contact groups and no contacts.
+func New() *Server {
+	s := &Server{
+		people: make(map[string]*people.Person),
+		groups: make(map[string]*people.ContactGroup),
+	}
+	for _, id := range systemGroups {
+		s.putGroup(&people.ContactGroup{
+			ResourceName: "contactGroups/" + id,
+			Name:         id,
+			GroupType:    "SYSTEM_CONTACT_GROUP",
+		})
+	}
+	return s
+}
+
+// HTTPClient returns an HTTP client whose requests are served by the fake.
+func (s *Server) HTTPClient() *http.Client {
+	return &http.Client{Transport: s}
+}
+
+// RoundTrip implements http.RoundTripper by serving the request in-process.
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// Seed adds existing contacts and groups, keeping their resource names.
+func (s *Server) Seed(contacts []*people.Person, groups []*people.ContactGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, group := range groups {
+		if _, ok := s.groups[group.ResourceName]; ok {
+			continue
+		}
+		s.putGroup(clone(group))
+	}
+	for _, contact := range contacts {
+		p := clone(contact)
+		if p.ResourceName == "" {
+			p.ResourceName = s.newResourceName("people/c")
+		}
+		s.putPerson(p)
+	}
+}
+
+// Contacts returns copies of all stored contacts in creation order.
+func (s *Server) Contacts() []*people.Person {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*people.Person, 0, len(s.order))
+	for _, name := range s.order {
+		result = append(result, clone(s.people[name]))
+	}
+	return result
+}
+
+// RequestCount returns the number of API requests served so far.
+func (s *Server) RequestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// ServeHTTP routes a People API request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "people/me/connections":
+		s.listConnections(w, r)
+	case r.Method == http.MethodGet && path == "people:batchGet":
+		s.batchGet(w, r)
+	case r.Method == http.MethodPost && path == "people:batchCreateContacts":
+		s.batchCreate(w, r)
+	case r.Method == http.MethodPost && path == "people:batchDeleteContacts":
+		s.batchDelete(w, r)
+	case r.Method == http.MethodPost && path == "people:batchUpdateContacts":
+		s.batchUpdate(w, r)
+	case r.Method == http.MethodPatch && strings.HasSuffix(path, ":updateContactPhoto"):
+		s.updatePhoto(w, r, strings.TrimSuffix(path, ":updateContactPhoto"))
+	case r.Method == http.MethodGet && path == "contactGroups":
+		s.listGroups(w, r)
+	case r.Method == http.MethodPost && path == "contactGroups":
+		s.createGroup(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/members:modify"):
+		s.modifyMembers(w, r, strings.TrimSuffix(path, "/members:modify"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "contactGroups/"):
+		s.getGroup(w, r, path)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "contactGroups/"):
+		s.deleteGroup(w, path)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("fakepeople: unsupported request %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) listConnections(w http.ResponseWriter, r *http.Request) {
+	start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	end := start + pageSize
+	if end > len(s.order) {
+		end = len(s.order)
+	}
+
+	resp := &people.ListConnectionsResponse{
+		TotalItems:  int64(len(s.order)),
+		TotalPeople: int64(len(s.order)),
+	}
+	for _, name := range s.order[start:end] {
+		resp.Connections = append(resp.Connections, s.people[name])
+	}
+	if end < len(s.order) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+
+	writeJSON(w, resp)
+}
+
+func (s *Server) batchGet(w http.ResponseWriter, r *http.Request) {
+	resp := &people.GetPeopleResponse{}
+	for _, name := range r.URL.Query()["resourceNames"] {
+		pr := &people.PersonResponse{RequestedResourceName: name}
+		if p, ok := s.people[name]; ok {
+			pr.Person = p
+			pr.HttpStatusCode = http.StatusOK
+		} else {
+			pr.HttpStatusCode = http.StatusNotFound
+			pr.Status = &people.Status{Code: 5, Message: "Requested entity was not found."}
+		}
+		resp.Responses = append(resp.Responses, pr)
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) batchCreate(w http.ResponseWriter, r *http.Request) {
+	var req people.BatchCreateContactsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	batch := s.createBatches
+	s.createBatches++
+
+	// Validate every contact first: the real API applies a batch atomically
+	rejected := make(map[int]string)
+	accepted := 0
+	for i, c := range req.Contacts {
+		if c.ContactPerson == nil {
+			writeError(w, http.StatusBadRequest, "contactPerson is required")
+			return
+		}
+		reason := s.validatePerson(c.ContactPerson)
+		if reason == "" && len(s.order)+accepted >= MaxContacts {
+			reason = fmt.Sprintf("the account already holds the maximum of %d contacts", MaxContacts)
+		}
+		if reason == "" {
+			accepted++
+			continue
+		}
+		if !s.collect {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid contact at index %d: %s", i, reason))
+			return
+		}
+		rejected[i] = reason
+		s.rejections = append(s.rejections, Rejection{
+			Operation: "create",
+			Batch:     batch,
+			Index:     i,
+			Name:      models.DisplayName(c.ContactPerson),
+			Reason:    reason,
+		})
+	}
+
+	resp := &people.BatchCreateContactsResponse{}
+	for i, c := range req.Contacts {
+		if reason, ok := rejected[i]; ok {
+			// Keep the positions of the responses in line with the request
+			resp.CreatedPeople = append(resp.CreatedPeople, &people.PersonResponse{
+				HttpStatusCode: http.StatusBadRequest,
+				Status:         &people.Status{Code: 3, Message: reason},
+			})
+			continue
+		}
+		p := clone(c.ContactPerson)
+		p.ResourceName = s.newResourceName("people/c")
+		p.Etag = "%fake-" + p.ResourceName
+		s.putPerson(p)
+		resp.CreatedPeople = append(resp.CreatedPeople, &people.PersonResponse{
+			HttpStatusCode:        http.StatusOK,
+			Person:                p,
+			RequestedResourceName: p.ResourceName,
+		})
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) batchDelete(w http.ResponseWriter, r *http.Request) {
+	var req people.BatchDeleteContactsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, name := range req.ResourceNames {
+		s.deletePerson(name)
+	}
+	writeJSON(w, &people.Empty{})
+}
+
+func (s *Server) batchUpdate(w http.ResponseWriter, r *http.Request) {
+	var req people.BatchUpdateContactsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fields := strings.Split(req.UpdateMask, ",")
+	for _, field := range fields {
+		if !models.IsPersonField(field) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid updateMask field %q", field))
+			return
+		}
+	}
+
+	batch := s.updateBatches
+	s.updateBatches++
+
+	// Validate every contact first: the real API applies a batch atomically
+	names := make([]string, 0, len(req.Contacts))
+	for name := range req.Contacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	merged := make(map[string]*people.Person, len(names))
+	for i, name := range names {
+		p := req.Contacts[name]
+		existing, ok := s.people[name]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Requested entity was not found.")
+			return
+		}
+		if p.Etag != existing.Etag {
+			writeError(w, http.StatusBadRequest, "Request person.etag is different than the current person.etag.")
+			return
+		}
+
+		updated := clone(existing)
+		for _, field := range fields {
+			models.CopyPersonField(updated, &p, field)
+		}
+		reason := s.validatePerson(updated)
+		if reason == "" {
+			merged[name] = updated
+			continue
+		}
+		if !s.collect {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid contact %s: %s", name, reason))
+			return
+		}
+		s.rejections = append(s.rejections, Rejection{
+			Operation: "update",
+			Batch:     batch,
+			Index:     i,
+			Name:      models.DisplayName(updated),
+			Reason:    reason,
+		})
+	}
+
+	resp := &people.BatchUpdateContactsResponse{UpdateResult: make(map[string]people.PersonResponse)}
+	for _, name := range names {
+		updated, ok := merged[name]
+		if !ok {
+			continue
+		}
+		s.nextID++
+		updated.Etag = fmt.Sprintf("%%fake-%s-%d", name, s.nextID)
+		s.putPerson(updated)
+		resp.UpdateResult[name] = people.PersonResponse{
+			HttpStatusCode:        http.StatusOK,
+			Person:                clone(updated),
+			RequestedResourceName: name,
+		}
+	}
+	writeJSON(w, resp)
+}
+
+// updatePhoto replaces the contact photo with one served from a fake URL
+func (s *Server) updatePhoto(w http.ResponseWriter, r *http.Request, name string) {
+	var req people.UpdateContactPhotoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(req.PhotoBytes)
+	if err != nil || len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "Invalid photo bytes.")
+		return
+	}
+	existing, ok := s.people[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Requested entity was not found.")
+		return
+	}
+
+	updated := clone(existing)
+	s.nextID++
+	updated.Photos = []*people.Photo{{
+		Url:      fmt.Sprintf("https://fakepeople.invalid/photos/%d", s.nextID),
+		Metadata: &people.FieldMetadata{Source: &people.Source{Type: "CONTACT"}},
+	}}
+	updated.Etag = fmt.Sprintf("%%fake-%s-%d", name, s.nextID)
+	s.putPerson(updated)
+	writeJSON(w, &people.UpdateContactPhotoResponse{})
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request) {
+	resp := &people.ListContactGroupsResponse{TotalItems: int64(len(s.groupOrder))}
+	for _, name := range s.groupOrder {
+		group := clone(s.groups[name])
+		group.MemberCount = int64(len(s.members(name)))
+		resp.ContactGroups = append(resp.ContactGroups, group)
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request, path string) {
+	group, ok := s.groups[path]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Requested entity was not found.")
+		return
+	}
+
+	result := clone(group)
+	members := s.members(path)
+	result.MemberCount = int64(len(members))
+	if maxMembers, _ := strconv.Atoi(r.URL.Query().Get("maxMembers")); maxMembers > 0 {
+		if len(members) > maxMembers {
+			members = members[:maxMembers]
+		}
+		result.MemberResourceNames = members
+	}
+	writeJSON(w, result)
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	var req people.CreateContactGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ContactGroup == nil {
+		writeError(w, http.StatusBadRequest, "contactGroup is required")
+		return
+	}
+	for _, existing := range s.groups {
+		if existing.Name == req.ContactGroup.Name {
+			writeError(w, http.StatusConflict, "Contact group name already exists.")
+			return
+		}
+	}
+
+	group := &people.ContactGroup{
+		ResourceName: s.newResourceName("contactGroups/"),
+		Name:         req.ContactGroup.Name,
+		GroupType:    "USER_CONTACT_GROUP",
+	}
+	s.putGroup(group)
+	writeJSON(w, group)
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, path string) {
+	group, ok := s.groups[path]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Requested entity was not found.")
+		return
+	}
+	if group.GroupType != "USER_CONTACT_GROUP" {
+		writeError(w, http.StatusBadRequest, "Cannot delete a system contact group.")
+		return
+	}
+
+	delete(s.groups, path)
+	s.groupOrder = remove(s.groupOrder, path)
+	for _, p := range s.people {
+		kept := p.Memberships[:0]
+		for _, m := range p.Memberships {
+			if m.ContactGroupMembership == nil || m.ContactGroupMembership.ContactGroupResourceName != path {
+				kept = append(kept, m)
+			}
+		}
+		p.Memberships = kept
+	}
+	writeJSON(w, &people.Empty{})
+}
+
+func (s *Server) modifyMembers(w http.ResponseWriter, r *http.Request, path string) {
+	if _, ok := s.groups[path]; !ok {
+		writeError(w, http.StatusNotFound, "Requested entity was not found.")
+		return
+	}
+	var req people.ModifyContactGroupMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := &people.ModifyContactGroupMembersResponse{}
+	for _, name := range req.ResourceNamesToAdd {
+		p, ok := s.people[name]
+		if !ok {
+			resp.NotFoundResourceNames = append(resp.NotFoundResourceNames, name)
+			continue
+		}
+		if !slices.ContainsFunc(p.Memberships, isMembershipOf(path)) {
+			p.Memberships = append(p.Memberships, &people.Membership{
+				ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: path},
+			})
+		}
+	}
+	for _, name := range req.ResourceNamesToRemove {
+		p, ok := s.people[name]
+		if !ok {
+			resp.NotFoundResourceNames = append(resp.NotFoundResourceNames, name)
+			continue
+		}
+		p.Memberships = slices.DeleteFunc(p.Memberships, isMembershipOf(path))
+	}
+	writeJSON(w, resp)
+}
+
+// isMembershipOf returns a predicate matching memberships of a group.
+func isMembershipOf(groupResourceName string) func(*people.Membership) bool {
+	return func(m *people.Membership) bool {
+		return m.ContactGroupMembership != nil && m.ContactGroupMembership.ContactGroupResourceName == groupResourceName
+	}
+}
+
+// members returns the sorted resource names of contacts in a group.
+func (s *Server) members(groupResourceName string) []string {
+	var result []string
+	for name, p := range s.people {
+		for _, m := range p.Memberships {
+			if m.ContactGroupMembership != nil && m.ContactGroupMembership.ContactGroupResourceName == groupResourceName {
+				result = append(result, name)
+				break
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// newResourceName returns an unused resource name with the given prefix.
+func (s *Server) newResourceName(prefix string) string {
+	for {
+		s.nextID++
+		name := fmt.Sprintf("%s%d", prefix, s.nextID)
+		_, personExists := s.people[name]
+		_, groupExists := s.groups[name]
+		if !personExists && !groupExists {
+			return name
+		}
+	}
+}
+
+func (s *Server) putPerson(p *people.Person) {
+	if _, ok := s.people[p.ResourceName]; !ok {
+		s.order = append(s.order, p.ResourceName)
+	}
+	s.people[p.ResourceName] = p
+}
+
+func (s *Server) deletePerson(name string) {
+	if _, ok := s.people[name]; !ok {
+		return
+	}
+	delete(s.people, name)
+	s.order = remove(s.order, name)
+}
+
+func (s *Server) putGroup(group *people.ContactGroup) {
+	if _, ok := s.groups[group.ResourceName]; !ok {
+		s.groupOrder = append(s.groupOrder, group.ResourceName)
+	}
+	s.groups[group.ResourceName] = group
+}
+
+func remove(list []string, item string) []string {
+	for i, v := range list {
+		if v == item {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// clone deep-copies a value through its JSON representation, which is also
+// exactly what survives a trip to the real API.
+func clone[T any](v *T) *T {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("fakepeople: marshal: %v", err))
+	}
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic(fmt.Sprintf("fakepeople: unmarshal: %v", err))
+	}
+	return &out
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the Google API JSON error format.
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+			"status":  http.StatusText(code),
+		},
+	})
+}
@@ -0,0 +1,163 @@
+package fakepeople
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/people/v1"
+)
+
+func newService(t *testing.T, s *Server) *people.Service {
+	t.Helper()
+	svc, err := people.NewService(context.Background(), option.WithHTTPClient(s.HTTPClient()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func named(given string) *people.Person {
+	return &people.Person{Names: []*people.Name{{GivenName: given}}}
+}
+
+func createRequest(contacts ...*people.Person) *people.BatchCreateContactsRequest {
+	req := &people.BatchCreateContactsRequest{ReadMask: "names"}
+	for _, c := range contacts {
+		req.Contacts = append(req.Contacts, &people.ContactToCreate{ContactPerson: c})
+	}
+	return req
+}
+
+func statusCode(err error) int {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
+
+func TestCreateAndList(t *testing.T) {
+	s := New()
+	svc := newService(t, s)
+
+	s.Seed([]*people.Person{{ResourceName: "people/seeded", Names: []*people.Name{{GivenName: "Seeded"}}}}, nil)
+	resp, err := svc.People.BatchCreateContacts(createRequest(named("Ada"), named("Charles"), named("Grace"))).Do()
+	if err != nil {
+		t.Fatalf("BatchCreateContacts: %v", err)
+	}
+	if len(resp.CreatedPeople) != 3 {
+		t.Fatalf("created %d people, want 3", len(resp.CreatedPeople))
+	}
+	for _, created := range resp.CreatedPeople {
+		if created.Person.ResourceName == "" || created.Person.Etag == "" {
+			t.Errorf("created person lacks a resource name or etag: %+v", created.Person)
+		}
+	}
+
+	var names []string
+	pageToken := ""
+	pages := 0
+	for {
+		list, err := svc.People.Connections.List("people/me").PersonFields("names").PageSize(2).PageToken(pageToken).Do()
+		if err != nil {
+			t.Fatalf("Connections.List: %v", err)
+		}
+		pages++
+		if list.TotalPeople != 4 {
+			t.Errorf("TotalPeople = %d, want 4", list.TotalPeople)
+		}
+		for _, p := range list.Connections {
+			names = append(names, p.Names[0].GivenName)
+		}
+		if pageToken = list.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+	if got := strings.Join(names, ","); got != "Seeded,Ada,Charles,Grace" || pages != 2 {
+		t.Errorf("listed %s in %d pages, want Seeded,Ada,Charles,Grace in 2", got, pages)
+	}
+	if s.RequestCount() != 3 {
+		t.Errorf("RequestCount = %d, want 3", s.RequestCount())
+	}
+}
+
+func TestUpdateChecksEtag(t *testing.T) {
+	s := New()
+	svc := newService(t, s)
+	s.Seed([]*people.Person{{ResourceName: "people/c1", Etag: "v1", Names: []*people.Name{{GivenName: "Ada"}}}}, nil)
+
+	update := func(etag, given string) error {
+		_, err := svc.People.BatchUpdateContacts(&people.BatchUpdateContactsRequest{
+			Contacts:   map[string]people.Person{"people/c1": {Etag: etag, Names: []*people.Name{{GivenName: given}}}},
+			UpdateMask: "names",
+			ReadMask:   "names",
+		}).Do()
+		return err
+	}
+	if err := update("stale", "Augusta"); statusCode(err) != http.StatusBadRequest {
+		t.Errorf("update with a stale etag: %v, want a 400", err)
+	}
+	if err := update("v1", "Augusta"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got := s.Contacts()[0]
+	if got.Names[0].GivenName != "Augusta" || got.Etag == "v1" {
+		t.Errorf("after update: name %q, etag %q", got.Names[0].GivenName, got.Etag)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	s := New()
+	svc := newService(t, s)
+
+	group, err := svc.ContactGroups.Create(&people.CreateContactGroupRequest{ContactGroup: &people.ContactGroup{Name: "Work"}}).Do()
+	if err != nil {
+		t.Fatalf("ContactGroups.Create: %v", err)
+	}
+	if _, err := svc.ContactGroups.Create(&people.CreateContactGroupRequest{ContactGroup: &people.ContactGroup{Name: "Work"}}).Do(); statusCode(err) != http.StatusConflict {
+		t.Errorf("creating a duplicate group: %v, want a 409", err)
+	}
+
+	s.Seed([]*people.Person{{ResourceName: "people/a"}, {ResourceName: "people/b"}}, nil)
+	modify, err := svc.ContactGroups.Members.Modify(group.ResourceName, &people.ModifyContactGroupMembersRequest{
+		ResourceNamesToAdd: []string{"people/a", "people/b", "people/missing"},
+	}).Do()
+	if err != nil {
+		t.Fatalf("Members.Modify: %v", err)
+	}
+	if len(modify.NotFoundResourceNames) != 1 || modify.NotFoundResourceNames[0] != "people/missing" {
+		t.Errorf("NotFoundResourceNames = %v", modify.NotFoundResourceNames)
+	}
+
+	got, err := svc.ContactGroups.Get(group.ResourceName).MaxMembers(10).Do()
+	if err != nil {
+		t.Fatalf("ContactGroups.Get: %v", err)
+	}
+	if got.MemberCount != 2 || strings.Join(got.MemberResourceNames, ",") != "people/a,people/b" {
+		t.Errorf("group has %d members %v, want people/a and people/b", got.MemberCount, got.MemberResourceNames)
+	}
+
+	if _, err := svc.ContactGroups.Delete("contactGroups/myContacts").Do(); statusCode(err) != http.StatusBadRequest {
+		t.Errorf("deleting a system group: %v, want a 400", err)
+	}
+	if _, err := svc.ContactGroups.Delete(group.ResourceName).Do(); err != nil {
+		t.Fatalf("ContactGroups.Delete: %v", err)
+	}
+	for _, p := range s.Contacts() {
+		if len(p.Memberships) != 0 {
+			t.Errorf("%s is still a member of the deleted group", p.ResourceName)
+		}
+	}
+}
+
+func TestUnsupportedRequest(t *testing.T) {
+	svc := newService(t, New())
+	if _, err := svc.People.Get("people/c1").PersonFields("names").Do(); statusCode(err) != http.StatusNotFound || !strings.Contains(err.Error(), "unsupported request") {
+		t.Errorf("People.Get: %v, want an unsupported request error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package fakepeople
+
+import (
+	"fmt"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/validate"
+)
+
+// MaxContacts is the number of contacts an account can hold. The limits of
+// a single contact are those of the validate package.
+const MaxContacts = 25000
+
+// Rejection is a contact the fake refused to create or update, with the
+// reason the People API would give.
+type Rejection struct {
+	// Operation is "create" or "update"
+	Operation string
+
+	// Batch is the zero-based index of the create or update request among
+	// the requests of its operation, and Index the contact's position in it
+	Batch int
+	Index int
+
+	// Name is the contact's display name
+	Name string
+
+	Reason string
+}
+
+// CollectRejections makes the fake skip invalid contacts instead of failing
+// the request they are in, and record them, so that every invalid contact of
+// a restore is found in one run. The real API fails the whole batch.
+func (s *Server) CollectRejections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collect = true
+}
+
+// Rejections returns the contacts refused so far, in request order.
+func (s *Server) Rejections() []Rejection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Rejection(nil), s.rejections...)
+}
+
+// validatePerson returns why the People API would refuse p, or "" if it
+// would accept it.
+func (s *Server) validatePerson(p *people.Person) string {
+	if problems := validate.Contact(p); len(problems) > 0 {
+		return problems[0].String()
+	}
+
+	for _, membership := range p.Memberships {
+		if membership.ContactGroupMembership == nil {
+			continue
+		}
+		name := membership.ContactGroupMembership.ContactGroupResourceName
+		if _, ok := s.groups[name]; !ok {
+			return fmt.Sprintf("contact group %s does not exist", name)
+		}
+	}
+	return ""
+}
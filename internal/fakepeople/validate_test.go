@@ -0,0 +1,79 @@
+package fakepeople
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/people/v1"
+)
+
+// invalid returns a contact the People API refuses: it has two names
+func invalid(given string) *people.Person {
+	return &people.Person{Names: []*people.Name{{GivenName: given}, {GivenName: given + " again"}}}
+}
+
+func TestInvalidContactFailsBatch(t *testing.T) {
+	s := New()
+	svc := newService(t, s)
+
+	_, err := svc.People.BatchCreateContacts(createRequest(named("Ada"), invalid("Charles"))).Do()
+	if statusCode(err) != http.StatusBadRequest || !strings.Contains(err.Error(), "Invalid contact at index 1: names: can only hold one value") {
+		t.Errorf("BatchCreateContacts: %v, want a 400 for index 1", err)
+	}
+	if n := len(s.Contacts()); n != 0 {
+		t.Errorf("a failed batch stored %d contacts", n)
+	}
+
+	unknownGroup := named("Grace")
+	unknownGroup.Memberships = []*people.Membership{{ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: "contactGroups/missing"}}}
+	if _, err := svc.People.BatchCreateContacts(createRequest(unknownGroup)).Do(); err == nil || !strings.Contains(err.Error(), "contact group contactGroups/missing does not exist") {
+		t.Errorf("BatchCreateContacts with an unknown group: %v", err)
+	}
+}
+
+func TestCollectRejections(t *testing.T) {
+	s := New()
+	s.CollectRejections()
+	svc := newService(t, s)
+
+	if _, err := svc.People.BatchCreateContacts(createRequest(named("Ada"))).Do(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := svc.People.BatchCreateContacts(createRequest(named("Charles"), invalid("Grace"), named("Alan"))).Do()
+	if err != nil {
+		t.Fatalf("BatchCreateContacts: %v", err)
+	}
+	if len(resp.CreatedPeople) != 3 || resp.CreatedPeople[1].HttpStatusCode != http.StatusBadRequest || resp.CreatedPeople[2].Person.Names[0].GivenName != "Alan" {
+		t.Errorf("responses do not line up with the request: %+v", resp.CreatedPeople)
+	}
+	if n := len(s.Contacts()); n != 3 {
+		t.Errorf("stored %d contacts, want the 3 valid ones", n)
+	}
+
+	ada := s.Contacts()[0]
+	update := invalid("Ada")
+	update.Etag = ada.Etag
+	_, err = svc.People.BatchUpdateContacts(&people.BatchUpdateContactsRequest{
+		Contacts:   map[string]people.Person{ada.ResourceName: *update},
+		UpdateMask: "names",
+		ReadMask:   "names",
+	}).Do()
+	if err != nil {
+		t.Fatalf("BatchUpdateContacts: %v", err)
+	}
+
+	rejections := s.Rejections()
+	if len(rejections) != 2 {
+		t.Fatalf("got %d rejections, want 2: %+v", len(rejections), rejections)
+	}
+	if r := rejections[0]; r.Operation != "create" || r.Batch != 1 || r.Index != 1 || r.Name != "Grace" || !strings.Contains(r.Reason, "names") {
+		t.Errorf("create rejection = %+v", r)
+	}
+	if r := rejections[1]; r.Operation != "update" || r.Batch != 0 || r.Index != 0 {
+		t.Errorf("update rejection = %+v", r)
+	}
+	if got := s.Contacts()[0]; len(got.Names) != 1 || got.Etag != ada.Etag {
+		t.Errorf("a rejected update changed the contact: %+v", got)
+	}
+}
@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// node is a node of a parsed filter expression
+type node interface {
+	eval(s *subject) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(s *subject) bool { return n.left.eval(s) && n.right.eval(s) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(s *subject) bool { return n.left.eval(s) || n.right.eval(s) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(s *subject) bool { return !n.inner.eval(s) }
+
+// hasNode is true if the contact has a value for the field
+type hasNode struct{ field field }
+
+func (n hasNode) eval(s *subject) bool {
+	for _, v := range n.field.values(s) {
+		if v != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// comparisonNode compares a field's values against a constant
+type comparisonNode struct {
+	field field
+	op    string
+	value string
+
+	// for date fields: the parsed value, and whether it is a whole day
+	date     time.Time
+	wholeDay bool
+}
+
+// newComparison validates and prepares a comparison.
+func newComparison(f field, op, value string) (comparisonNode, error) {
+	if op == "==" {
+		op = "="
+	}
+	n := comparisonNode{field: f, op: op, value: strings.ToLower(value)}
+
+	if f.date {
+		if op == "~" {
+			return n, fmt.Errorf("operator ~ cannot be used with %s", f.name)
+		}
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			n.date, n.wholeDay = t, true
+		} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+			n.date = t
+		} else {
+			return n, fmt.Errorf("invalid date %q: use YYYY-MM-DD or RFC 3339", value)
+		}
+		return n, nil
+	}
+
+	switch op {
+	case ">", ">=", "<", "<=":
+		return n, fmt.Errorf("operator %s can only be used with dates", op)
+	}
+	return n, nil
+}
+
+func (n comparisonNode) eval(s *subject) bool {
+	values := n.field.values(s)
+	if n.op == "!=" {
+		for _, v := range values {
+			if n.matches(v, "=") {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if n.matches(v, n.op) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches compares a single value using op.
+func (n comparisonNode) matches(value, op string) bool {
+	if n.field.date {
+		return n.compareDate(value, op)
+	}
+
+	value = strings.ToLower(value)
+	switch op {
+	case "=":
+		if n.field.name == "domain" {
+			return value == n.value || strings.HasSuffix(value, "."+n.value)
+		}
+		return value == n.value
+	case "~":
+		return strings.Contains(value, n.value)
+	}
+	return false
+}
+
+// compareDate compares an RFC 3339 value with the comparison's date. When the
+// date has no time of day, the whole day is compared: updated>2024-01-01
+// means after the end of that day, and updated=2024-01-01 means during it.
+func (n comparisonNode) compareDate(value, op string) bool {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return false
+	}
+
+	start, end := n.date, n.date
+	if n.wholeDay {
+		end = start.Add(24 * time.Hour)
+	}
+
+	switch op {
+	case "=":
+		if n.wholeDay {
+			return !t.Before(start) && t.Before(end)
+		}
+		return t.Equal(start)
+	case ">":
+		if n.wholeDay {
+			return !t.Before(end)
+		}
+		return t.After(start)
+	case ">=":
+		return !t.Before(start)
+	case "<":
+		return t.Before(start)
+	case "<=":
+		if n.wholeDay {
+			return t.Before(end)
+		}
+		return !t.After(start)
+	}
+	return false
+}
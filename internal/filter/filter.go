@@ -0,0 +1,214 @@
+// Package filter implements the contact filter expressions accepted by the
+// --filter flag of every command that selects contacts, for example:
+//
+//	label="Work" && has(phone) && updated>2024-01-01
+//
+// Expressions combine comparisons and has(FIELD) checks with &&, || and !,
+// grouped with parentheses. Comparisons are FIELD OP VALUE, where OP is one of
+// = (or ==), !=, ~ (contains), >, >=, < and <=, and VALUE is a quoted string
+// or a bare word. Text comparisons ignore case. A field with several values
+// (such as email) matches if any of its values does; != matches if none does.
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Filter is a parsed filter expression.
+type Filter struct {
+	src  string
+	root node
+}
+
+// Parse parses a filter expression.
+func Parse(expr string) (*Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("invalid filter: %w", p.errorf(t, "unexpected %q", t.text))
+	}
+
+	return &Filter{src: expr, root: root}, nil
+}
+
+// String returns the expression the filter was parsed from.
+func (f *Filter) String() string {
+	return f.src
+}
+
+// Match reports whether a contact satisfies the filter. groupNames maps group
+// resource names to label names, as returned by BackupFile.UserGroupNames.
+func (f *Filter) Match(contact *people.Person, groupNames map[string]string) bool {
+	return f.root.eval(&subject{person: contact, groupNames: groupNames})
+}
+
+// Apply removes the contacts that do not match the filter from a backup and
+// returns how many were removed.
+func (f *Filter) Apply(backup *models.BackupFile) int {
+	groupNames := backup.UserGroupNames()
+	return len(backup.RemoveContacts(func(p *people.Person) bool {
+		return !f.Match(p, groupNames)
+	}))
+}
+
+// subject is the contact a filter is evaluated against
+type subject struct {
+	person     *people.Person
+	groupNames map[string]string
+}
+
+// field describes a filterable property of a contact
+type field struct {
+	name        string
+	description string
+	// values returns the field's values; a nil or empty result means the
+	// contact does not have the field
+	values func(s *subject) []string
+	// date marks fields whose values are RFC 3339 timestamps
+	date bool
+}
+
+// fields lists the named fields; "tag.KEY" and People API field names
+// (for has()) are resolved separately by lookupField
+var fields = []field{
+	{"name", "display name", func(s *subject) []string {
+		if len(s.person.Names) == 0 {
+			return nil
+		}
+		return []string{models.DisplayName(s.person)}
+	}, false},
+	{"email", "any email address", func(s *subject) []string {
+		var values []string
+		for _, e := range s.person.EmailAddresses {
+			values = append(values, e.Value)
+		}
+		return values
+	}, false},
+	{"domain", "domain of any email address (subdomains match with =)", func(s *subject) []string {
+		var values []string
+		for _, e := range s.person.EmailAddresses {
+			if _, domain, ok := strings.Cut(e.Value, "@"); ok {
+				values = append(values, domain)
+			}
+		}
+		return values
+	}, false},
+	{"phone", "any phone number", func(s *subject) []string {
+		var values []string
+		for _, ph := range s.person.PhoneNumbers {
+			values = append(values, ph.Value)
+		}
+		return values
+	}, false},
+	{"label", "name of any label (user contact group)", func(s *subject) []string {
+		var values []string
+		for _, m := range s.person.Memberships {
+			if m.ContactGroupMembership == nil {
+				continue
+			}
+			if name, ok := s.groupNames[m.ContactGroupMembership.ContactGroupResourceName]; ok {
+				values = append(values, name)
+			}
+		}
+		return values
+	}, false},
+	{"org", "organization name", func(s *subject) []string {
+		var values []string
+		for _, o := range s.person.Organizations {
+			values = append(values, o.Name)
+		}
+		return values
+	}, false},
+	{"address", "any formatted address", func(s *subject) []string {
+		var values []string
+		for _, a := range s.person.Addresses {
+			values = append(values, a.FormattedValue)
+		}
+		return values
+	}, false},
+	{"notes", "notes text", func(s *subject) []string {
+		if len(s.person.Biographies) == 0 {
+			return nil
+		}
+		return []string{models.NotesText(s.person.Biographies[0], true)}
+	}, false},
+	{"resource", "resource name, e.g. people/c123", func(s *subject) []string {
+		return []string{s.person.ResourceName}
+	}, false},
+	{"tag", "key of any tag (clientData entry)", func(s *subject) []string {
+		var values []string
+		for _, cd := range s.person.ClientData {
+			values = append(values, cd.Key)
+		}
+		return values
+	}, false},
+	{"updated", "last update time of the contact", func(s *subject) []string {
+		if t := models.UpdateTime(s.person); !t.IsZero() {
+			return []string{t.Format(time.RFC3339)}
+		}
+		return nil
+	}, true},
+}
+
+// FieldHelp returns one "name  description" line per field, for command help.
+func FieldHelp() []string {
+	lines := make([]string, 0, len(fields)+2)
+	for _, f := range fields {
+		lines = append(lines, fmt.Sprintf("%-10s %s", f.name, f.description))
+	}
+	lines = append(lines,
+		fmt.Sprintf("%-10s %s", "tag.KEY", "value of the tag KEY"),
+		fmt.Sprintf("%-10s %s", "has(F)", "true if field F (or any People API field, e.g. birthdays) is set"))
+	return lines
+}
+
+// lookupField resolves a field name.
+func lookupField(name string) (field, error) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, nil
+		}
+	}
+
+	if key, ok := strings.CutPrefix(name, "tag."); ok && key != "" {
+		return field{name: name, values: func(s *subject) []string {
+			if v, ok := models.ClientDataValue(s.person, key); ok {
+				return []string{v}
+			}
+			return nil
+		}}, nil
+	}
+
+	if models.IsPersonField(name) {
+		return field{name: name, values: func(s *subject) []string {
+			for _, present := range models.PresentFields(s.person) {
+				if present == name {
+					return []string{name}
+				}
+			}
+			return nil
+		}}, nil
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	return field{}, fmt.Errorf("unknown field %q (fields: %s, tag.KEY)", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,182 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// testBackup returns a backup with a few contacts and a "Work" and a
+// "Family" label.
+func testBackup() *models.BackupFile {
+	backup := models.NewBackupFile()
+	backup.AddGroup(&people.ContactGroup{ResourceName: "contactGroups/work", Name: "Work", GroupType: "USER_CONTACT_GROUP"})
+	backup.AddGroup(&people.ContactGroup{ResourceName: "contactGroups/family", Name: "Family", GroupType: "USER_CONTACT_GROUP"})
+
+	member := func(group string) *people.Membership {
+		return &people.Membership{ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: group}}
+	}
+	updated := func(t string) *people.PersonMetadata {
+		return &people.PersonMetadata{Sources: []*people.Source{{Type: "CONTACT", UpdateTime: t}}}
+	}
+
+	backup.AddContact(&people.Person{
+		ResourceName:   "people/ada",
+		Names:          []*people.Name{{DisplayName: "Ada Lovelace", GivenName: "Ada"}},
+		EmailAddresses: []*people.EmailAddress{{Value: "ada@mail.example.com"}, {Value: "ada@home.example.org"}},
+		PhoneNumbers:   []*people.PhoneNumber{{Value: "+44 20 7946 0000"}},
+		Memberships:    []*people.Membership{member("contactGroups/work")},
+		Organizations:  []*people.Organization{{Name: "Analytical Engines"}},
+		ClientData:     []*people.ClientData{{Key: "vip", Value: "yes"}},
+		Metadata:       updated("2024-03-01T10:00:00Z"),
+	})
+	backup.AddContact(&people.Person{
+		ResourceName:   "people/charles",
+		Names:          []*people.Name{{DisplayName: "Charles Babbage", GivenName: "Charles"}},
+		EmailAddresses: []*people.EmailAddress{{Value: "charles@example.com"}},
+		Memberships:    []*people.Membership{member("contactGroups/work"), member("contactGroups/family")},
+		Birthdays:      []*people.Birthday{{Date: &people.Date{Month: 12, Day: 26}}},
+		Metadata:       updated("2024-01-01T23:30:00Z"),
+	})
+	backup.AddContact(&people.Person{
+		ResourceName: "people/grace",
+		Names:        []*people.Name{{DisplayName: "Grace \"Amazing\" Hopper", GivenName: "Grace"}},
+		PhoneNumbers: []*people.PhoneNumber{{Value: "+1 555 0100"}},
+		Biographies:  []*people.Biography{{Value: "Invented the compiler", ContentType: "TEXT_PLAIN"}},
+		Memberships:  []*people.Membership{member("contactGroups/family")},
+		Metadata:     updated("2023-12-31T08:00:00Z"),
+	})
+	backup.AddContact(&people.Person{
+		ResourceName: "people/nameless",
+		PhoneNumbers: []*people.PhoneNumber{{Value: "+1 555 0199"}},
+	})
+	return backup
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		// Comparisons
+		{`label="Work"`, []string{"ada", "charles"}},
+		{`label == work`, []string{"ada", "charles"}},
+		{`label!=Work`, []string{"grace", "nameless"}},
+		{`name~love`, []string{"ada"}},
+		{`email=charles@example.com`, []string{"charles"}},
+		{`domain=example.com`, []string{"ada", "charles"}},
+		{`domain=home.example.org`, []string{"ada"}},
+		{`domain!=example.org`, []string{"charles", "grace", "nameless"}},
+		{`org~engines`, []string{"ada"}},
+		{`notes~compiler`, []string{"grace"}},
+		{`resource=people/nameless`, []string{"nameless"}},
+		{`tag=vip`, []string{"ada"}},
+		{`tag.vip=yes`, []string{"ada"}},
+		{`has(phone)`, []string{"ada", "grace", "nameless"}},
+		{`has(birthdays)`, []string{"charles"}},
+		{`has(name)`, []string{"ada", "charles", "grace"}},
+
+		// Dates: a bare date covers the whole day
+		{`updated>2024-01-01`, []string{"ada"}},
+		{`updated>=2024-01-01`, []string{"ada", "charles"}},
+		{`updated=2024-01-01`, []string{"charles"}},
+		{`updated<2024-01-01`, []string{"grace"}},
+		{`updated<=2024-01-01`, []string{"charles", "grace"}},
+		{`updated>2024-01-01T12:00:00Z`, []string{"ada", "charles"}},
+		{`updated=2024-03-01T10:00:00Z`, []string{"ada"}},
+
+		// Precedence: ! binds tighter than &&, which binds tighter than ||
+		{`label=Family || label=Work && has(phone)`, []string{"ada", "charles", "grace"}},
+		{`(label=Family || label=Work) && has(phone)`, []string{"ada", "grace"}},
+		{`!label=Work && has(phone)`, []string{"grace", "nameless"}},
+		{`!(label=Work && has(phone))`, []string{"charles", "grace", "nameless"}},
+		{`!!has(birthdays)`, []string{"charles"}},
+		{`has(phone) && has(email) || has(notes) && !has(email)`, []string{"ada", "grace"}},
+
+		// Quoting
+		{`name="Ada Lovelace"`, []string{"ada"}},
+		{`name='ada lovelace'`, []string{"ada"}},
+		{`name="Grace \"Amazing\" Hopper"`, []string{"grace"}},
+		{`name~'"amazing"'`, []string{"grace"}},
+		{`name="Ada && Charles" || name=' || '`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			backup := testBackup()
+			groupNames := backup.UserGroupNames()
+			var got []string
+			for _, contact := range backup.Contacts {
+				if f.Match(contact, groupNames) {
+					got = append(got, strings.TrimPrefix(contact.ResourceName, "people/"))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{``, "at position 1: unexpected end of expression"},
+		{`label=`, `at position 7: expected a value after "="`},
+		{`label Work`, `at position 7: expected an operator after "label"`},
+		{`colour=red`, `at position 1: unknown field "colour"`},
+		{`has(colour)`, `at position 5: unknown field "colour"`},
+		{`size(phone)`, `at position 1: unknown function "size"`},
+		{`has(phone`, `at position 10: expected ")"`},
+		{`(label=Work`, `at position 12: expected ")"`},
+		{`label=Work)`, `at position 11: unexpected ")"`},
+		{`label=Work &&`, "at position 14: unexpected end of expression"},
+		{`label=Work has(phone)`, `at position 12: unexpected "has"`},
+		{`name="Ada`, "at position 6: unterminated string"},
+		{`name=Ada $`, `at position 10: unexpected character '$'`},
+		{`name>Ada`, "at position 6: operator > can only be used with dates"},
+		{`updated~2024`, "at position 9: operator ~ cannot be used with updated"},
+		{`updated>yesterday`, `at position 9: invalid date "yesterday"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil {
+				t.Fatalf("Parse succeeded, want error containing %q", tt.wantErr)
+			}
+			if !strings.HasPrefix(err.Error(), "invalid filter: ") || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse: %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	f, err := Parse(`label=Family && !has(email)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.String(); got != `label=Family && !has(email)` {
+		t.Errorf("String() = %q", got)
+	}
+
+	backup := testBackup()
+	if removed := f.Apply(backup); removed != 3 {
+		t.Errorf("Apply removed %d contacts, want 3", removed)
+	}
+	if len(backup.Contacts) != 1 || backup.Contacts[0].ResourceName != "people/grace" {
+		t.Errorf("kept %d contacts, want only people/grace", len(backup.Contacts))
+	}
+	if len(backup.GetUserGroups()) != 2 {
+		t.Errorf("Apply changed the groups: %d left", len(backup.GetUserGroups()))
+	}
+}
@@ -0,0 +1,264 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the type of a lexical token
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+// token is a lexical token with its position in the expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators lists comparison operators, longest first so that ">=" is not
+// read as ">" followed by "="
+var operators = []string{"==", "!=", ">=", "<=", "=", "~", ">", "<"}
+
+// lex splits an expression into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.HasPrefix(src[i:], "&&"):
+			tokens = append(tokens, token{tokAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "||"):
+			tokens = append(tokens, token{tokOr, "||", i})
+			i += 2
+		case c == '!' && !strings.HasPrefix(src[i:], "!="):
+			tokens = append(tokens, token{tokNot, "!", i})
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '"' || c == '\'':
+			text, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("at position %d: %w", i+1, err)
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i += n
+		default:
+			if op := matchOperator(src[i:]); op != "" {
+				tokens = append(tokens, token{tokOp, op, i})
+				i += len(op)
+				continue
+			}
+			start := i
+			for i < len(src) && isWordChar(rune(src[i])) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("at position %d: unexpected character %q", i+1, src[i])
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], start})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+// lexString reads a quoted string starting at s[0], handling backslash
+// escapes. Returns the unquoted text and the number of bytes consumed.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				sb.WriteByte(s[i])
+			}
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// matchOperator returns the comparison operator at the start of s, if any.
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// isWordChar reports whether c can be part of a bare word such as a field
+// name, a date or an email address.
+func isWordChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("._-+@:/", c)
+}
+
+// parser is a recursive-descent parser over a token list
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("at position %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+// parseOr parses: and ("||" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary ("&&" unary)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary parses: "!" unary | primary
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses: "(" or ")" | IDENT "(" IDENT ")" | IDENT OP value
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected \")\"")
+		}
+		return inner, nil
+
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		return p.parseComparison(t)
+
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+// parseCall parses a function call such as has(phone) after its name.
+func (p *parser) parseCall(name token) (node, error) {
+	p.next() // "("
+	arg := p.next()
+	if arg.kind != tokIdent {
+		return nil, p.errorf(arg, "expected a field name")
+	}
+	if closing := p.next(); closing.kind != tokRParen {
+		return nil, p.errorf(closing, "expected \")\"")
+	}
+
+	switch name.text {
+	case "has":
+		field, err := lookupField(arg.text)
+		if err != nil {
+			return nil, p.errorf(arg, "%v", err)
+		}
+		return hasNode{field}, nil
+	default:
+		return nil, p.errorf(name, "unknown function %q", name.text)
+	}
+}
+
+// parseComparison parses "OP value" after a field name.
+func (p *parser) parseComparison(name token) (node, error) {
+	field, err := lookupField(name.text)
+	if err != nil {
+		return nil, p.errorf(name, "%v", err)
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, p.errorf(op, "expected an operator after %q", name.text)
+	}
+
+	value := p.next()
+	if value.kind != tokString && value.kind != tokIdent {
+		return nil, p.errorf(value, "expected a value after %q", op.text)
+	}
+
+	cmp, err := newComparison(field, op.text, value.text)
+	if err != nil {
+		return nil, p.errorf(value, "%v", err)
+	}
+	return cmp, nil
+}
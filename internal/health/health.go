@@ -0,0 +1,178 @@
+// Package health serves liveness, last-run and metrics endpoints for
+// monitoring the tool when it runs in a container, e.g. as a Kubernetes
+// CronJob or pod.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/state"
+	"github.com/mheap/google-contacts-backup/internal/trend"
+)
+
+// Server serves /healthz, /last-run and /metrics. It is safe for concurrent
+// use.
+type Server struct {
+	command   string
+	startedAt time.Time
+	srv       *http.Server
+
+	mu      sync.Mutex
+	running bool
+	lastRun *state.Run
+	nextRun time.Time
+	trend   *trend.Trend
+}
+
+// Status is the JSON body of /healthz.
+type Status struct {
+	Status        string     `json:"status"`
+	Command       string     `json:"command"`
+	Running       bool       `json:"running"`
+	UptimeSeconds float64    `json:"uptime_seconds"`
+	LastRunOK     *bool      `json:"last_run_ok,omitempty"`
+	NextRun       *time.Time `json:"next_run,omitempty"`
+}
+
+// New returns a server reporting on runs of command. lastRun is the most
+// recent run recorded before the server started, or nil.
+func New(command string, lastRun *state.Run) *Server {
+	s := &Server{
+		command:   command,
+		startedAt: time.Now(),
+		lastRun:   lastRun,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /last-run", s.handleLastRun)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	return s
+}
+
+// Start listens on addr (e.g. ":8080") and serves requests in the
+// background until Shutdown is called.
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to serve health endpoint: %w", err)
+	}
+	go func() {
+		if err := s.srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Warning: health endpoint stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the server, waiting for requests in flight.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// SetRunning records whether a run is in progress.
+func (s *Server) SetRunning(running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = running
+}
+
+// SetNextRun records when the next scheduled run starts.
+func (s *Server) SetNextRun(next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRun = next
+}
+
+// RecordRun records a finished run as the last run.
+func (s *Server) RecordRun(run *state.Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.lastRun = run
+}
+
+// SetTrend records the size of the address book over the recorded backups.
+func (s *Server) SetTrend(t *trend.Trend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trend = t
+}
+
+// handleHealth reports that the process is alive. It always answers 200,
+// so a failed backup does not get a pod restarted; check last_run_ok or
+// /last-run to alert on failures.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := Status{
+		Status:        "ok",
+		Command:       s.command,
+		Running:       s.running,
+		UptimeSeconds: time.Since(s.startedAt).Seconds(),
+	}
+	if s.lastRun != nil {
+		ok := s.lastRun.Succeeded()
+		status.LastRunOK = &ok
+	}
+	if !s.nextRun.IsZero() {
+		next := s.nextRun
+		status.NextRun = &next
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleLastRun returns the last recorded run, or 404 before the first one.
+func (s *Server) handleLastRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	run := s.lastRun
+	s.mu.Unlock()
+
+	if run == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no " + s.command + " run recorded yet"})
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// handleMetrics serves Prometheus gauges for the last run and the size of
+// the address book at the last backup.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	run, t := s.lastRun, s.trend
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if run != nil {
+		success := 0
+		if run.Succeeded() {
+			success = 1
+		}
+		fmt.Fprintf(w, "# HELP google_contacts_backup_last_run_success 1 if the last %s succeeded.\n", s.command)
+		fmt.Fprintf(w, "# TYPE google_contacts_backup_last_run_success gauge\n")
+		fmt.Fprintf(w, "google_contacts_backup_last_run_success %d\n", success)
+		fmt.Fprintf(w, "# HELP google_contacts_backup_last_run_timestamp_seconds When the last %s finished, in seconds since the epoch.\n", s.command)
+		fmt.Fprintf(w, "# TYPE google_contacts_backup_last_run_timestamp_seconds gauge\n")
+		fmt.Fprintf(w, "google_contacts_backup_last_run_timestamp_seconds %d\n", run.FinishedAt.Unix())
+	}
+	if t != nil {
+		trend.WritePrometheus(w, t)
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,93 @@
+// Package i18n translates user-facing CLI messages.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// fallback is the language used for messages missing from a catalog
+const fallback = "en"
+
+// current is the language selected with SetLanguage
+var current = fallback
+
+// Languages returns the supported language codes, sorted.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// SetLanguage selects the language of translated messages. It accepts a
+// language code such as "de" or a locale such as "de_DE.UTF-8".
+func SetLanguage(lang string) error {
+	code := normalize(lang)
+	if _, ok := catalogs[code]; !ok {
+		return fmt.Errorf("unsupported language %q: must be one of %s", lang, strings.Join(Languages(), ", "))
+	}
+	current = code
+	return nil
+}
+
+// Detect returns the supported language named by the LC_ALL, LC_MESSAGES or
+// LANG environment variables, in that order, or English.
+func Detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		// The first variable that is set wins, as with gettext
+		if _, ok := catalogs[normalize(value)]; ok {
+			return normalize(value)
+		}
+		return fallback
+	}
+	return fallback
+}
+
+// normalize reduces a locale such as "pt_BR.UTF-8" to its language code
+func normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// T returns the message with the given key in the current language, with
+// args formatted into it as by fmt.Sprintf. Messages missing from the
+// current language fall back to English.
+func T(key string, args ...any) string {
+	format, ok := catalogs[current][key]
+	if !ok {
+		format, ok = catalogs[fallback][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// IsYes reports whether an answer to a confirmation prompt agrees, in the
+// current language or in English, so scripts piping "yes" keep working.
+func IsYes(answer string) bool {
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	for _, lang := range []string{current, fallback} {
+		for _, yes := range yesAnswers[lang] {
+			if answer == yes {
+				return true
+			}
+		}
+	}
+	return false
+}
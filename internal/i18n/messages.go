@@ -0,0 +1,174 @@
+package i18n
+
+// yesAnswers are the answers accepted as agreement by confirmation prompts
+var yesAnswers = map[string][]string{
+	"en": {"yes", "y"},
+	"de": {"ja", "j"},
+	"es": {"sí", "si", "s"},
+	"fr": {"oui", "o"},
+}
+
+// catalogs maps language codes to message keys and their translations.
+// English is the reference catalog: every key must exist there.
+var catalogs = map[string]map[string]string{
+	"en": {
+		"confirm.continue": "Are you sure you want to continue? (yes/no): ",
+
+		"backup.completed":        "Backup completed successfully!",
+		"backup.summary.format":   "  Format:   %s",
+		"backup.summary.contacts": "  Contacts: %d",
+		"backup.summary.groups":   "  Groups:   %d",
+		"backup.summary.file":     "  File:     %s",
+
+		"restore.warning":               "WARNING: This will DELETE ALL existing contacts and groups!",
+		"restore.selection_warning":     "Contacts in your account that match the selection will be DELETED and recreated from the backup. Other contacts and labels are not changed.",
+		"restore.merge_warning":         "Contacts in the backup will be added to your account or will overwrite the matching contacts. Nothing is deleted.",
+		"restore.fields_warning":        "The selected fields (%s) of matching contacts will be overwritten with the values from the backup. Nothing else is changed, created or deleted.",
+		"restore.resume_warning":        "The interrupted restore continues where it stopped. Completed steps and contacts that were already created are not repeated.",
+		"restore.recommend_backup":      "It is recommended to create a backup first:",
+		"restore.safety_backup_note":    "A safety backup of the account is saved before anything is changed.",
+		"restore.cancelled":             "Restore cancelled.",
+		"restore.confirm_phrase":        "Type \"%s\" to continue: ",
+		"restore.completed":             "Restore completed successfully!",
+		"restore.summary.contacts":      "  Contacts restored: %d",
+		"restore.summary.groups":        "  Groups restored:   %d",
+		"restore.summary.created":       "  Contacts created:   %d",
+		"restore.summary.updated":       "  Contacts updated:   %d",
+		"restore.summary.unchanged":     "  Already up to date: %d",
+		"restore.summary.photos":        "  Photos restored:   %d",
+		"restore.summary.safety_backup": "  Safety backup:     %s",
+		"restore.photos_note":           "Note: Contact photos were not restored because the backup has no photo data.\nBack up with --photo-bytes to restore photos.",
+
+		"cleanup.confirm":        "Remove %d contacts from %s? (yes/no): ",
+		"cleanup.target_account": "your Google account",
+		"cleanup.delete_warning": "WARNING: Deleted contacts cannot be restored except from a backup.",
+		"cleanup.cancelled":      "Cleanup cancelled.",
+		"cleanup.completed":      "Cleanup completed successfully!",
+
+		"state.reset_warning": "This will delete the following state: %s",
+		"state.cancelled":     "Reset cancelled.",
+
+		"prune.confirm":   "Delete %d backups from %s? (yes/no): ",
+		"prune.cancelled": "Prune cancelled.",
+	},
+	"de": {
+		"confirm.continue": "Möchten Sie wirklich fortfahren? (ja/nein): ",
+
+		"backup.completed":        "Sicherung erfolgreich abgeschlossen!",
+		"backup.summary.format":   "  Format:   %s",
+		"backup.summary.contacts": "  Kontakte: %d",
+		"backup.summary.groups":   "  Gruppen:  %d",
+		"backup.summary.file":     "  Datei:    %s",
+
+		"restore.warning":               "WARNUNG: Dadurch werden ALLE vorhandenen Kontakte und Gruppen GELÖSCHT!",
+		"restore.selection_warning":     "Kontakte in Ihrem Konto, die der Auswahl entsprechen, werden GELÖSCHT und aus der Sicherung neu erstellt. Andere Kontakte und Labels bleiben unverändert.",
+		"restore.merge_warning":         "Kontakte aus der Sicherung werden Ihrem Konto hinzugefügt oder überschreiben die passenden Kontakte. Es wird nichts gelöscht.",
+		"restore.fields_warning":        "Die ausgewählten Felder (%s) passender Kontakte werden mit den Werten aus der Sicherung überschrieben. Sonst wird nichts geändert, erstellt oder gelöscht.",
+		"restore.resume_warning":        "Die unterbrochene Wiederherstellung wird dort fortgesetzt, wo sie angehalten hat. Abgeschlossene Schritte und bereits erstellte Kontakte werden nicht wiederholt.",
+		"restore.recommend_backup":      "Es wird empfohlen, zuerst eine Sicherung zu erstellen:",
+		"restore.safety_backup_note":    "Vor jeder Änderung wird eine Sicherheitskopie des Kontos gespeichert.",
+		"restore.cancelled":             "Wiederherstellung abgebrochen.",
+		"restore.confirm_phrase":        "Geben Sie \"%s\" ein, um fortzufahren: ",
+		"restore.completed":             "Wiederherstellung erfolgreich abgeschlossen!",
+		"restore.summary.contacts":      "  Wiederhergestellte Kontakte: %d",
+		"restore.summary.groups":        "  Wiederhergestellte Gruppen:  %d",
+		"restore.summary.created":       "  Erstellte Kontakte:      %d",
+		"restore.summary.updated":       "  Aktualisierte Kontakte:  %d",
+		"restore.summary.unchanged":     "  Bereits aktuell:         %d",
+		"restore.summary.photos":        "  Wiederhergestellte Fotos:    %d",
+		"restore.summary.safety_backup": "  Sicherheitskopie:            %s",
+		"restore.photos_note":           "Hinweis: Kontaktfotos wurden nicht wiederhergestellt, da die Sicherung keine Fotodaten enthält.\nSichern Sie mit --photo-bytes, um Fotos wiederherzustellen.",
+
+		"cleanup.confirm":        "%d Kontakte aus %s entfernen? (ja/nein): ",
+		"cleanup.target_account": "Ihrem Google-Konto",
+		"cleanup.delete_warning": "WARNUNG: Gelöschte Kontakte können nur aus einer Sicherung wiederhergestellt werden.",
+		"cleanup.cancelled":      "Bereinigung abgebrochen.",
+		"cleanup.completed":      "Bereinigung erfolgreich abgeschlossen!",
+
+		"state.reset_warning": "Dadurch wird der folgende Zustand gelöscht: %s",
+		"state.cancelled":     "Zurücksetzen abgebrochen.",
+
+		"prune.confirm":   "%d Sicherungen aus %s löschen? (ja/nein): ",
+		"prune.cancelled": "Aufräumen abgebrochen.",
+	},
+	"es": {
+		"confirm.continue": "¿Seguro que desea continuar? (sí/no): ",
+
+		"backup.completed":        "¡Copia de seguridad completada correctamente!",
+		"backup.summary.format":   "  Formato:   %s",
+		"backup.summary.contacts": "  Contactos: %d",
+		"backup.summary.groups":   "  Grupos:    %d",
+		"backup.summary.file":     "  Archivo:   %s",
+
+		"restore.warning":               "ADVERTENCIA: ¡Se ELIMINARÁN TODOS los contactos y grupos existentes!",
+		"restore.selection_warning":     "Los contactos de su cuenta que coincidan con la selección se ELIMINARÁN y se volverán a crear a partir de la copia. Los demás contactos y etiquetas no se modifican.",
+		"restore.merge_warning":         "Los contactos de la copia se añadirán a su cuenta o sobrescribirán los contactos coincidentes. No se elimina nada.",
+		"restore.fields_warning":        "Los campos seleccionados (%s) de los contactos coincidentes se sobrescribirán con los valores de la copia. No se modifica, crea ni elimina nada más.",
+		"restore.resume_warning":        "La restauración interrumpida continúa donde se detuvo. Los pasos completados y los contactos ya creados no se repiten.",
+		"restore.recommend_backup":      "Se recomienda crear primero una copia de seguridad:",
+		"restore.safety_backup_note":    "Se guarda una copia de seguridad de la cuenta antes de cambiar nada.",
+		"restore.cancelled":             "Restauración cancelada.",
+		"restore.confirm_phrase":        "Escriba \"%s\" para continuar: ",
+		"restore.completed":             "¡Restauración completada correctamente!",
+		"restore.summary.contacts":      "  Contactos restaurados: %d",
+		"restore.summary.groups":        "  Grupos restaurados:    %d",
+		"restore.summary.created":       "  Contactos creados:      %d",
+		"restore.summary.updated":       "  Contactos actualizados: %d",
+		"restore.summary.unchanged":     "  Ya actualizados:        %d",
+		"restore.summary.photos":        "  Fotos restauradas:     %d",
+		"restore.summary.safety_backup": "  Copia de seguridad:    %s",
+		"restore.photos_note":           "Nota: Las fotos de los contactos no se restauraron porque la copia no contiene los datos de las fotos.\nHaga la copia con --photo-bytes para restaurar las fotos.",
+
+		"cleanup.confirm":        "¿Eliminar %d contactos de %s? (sí/no): ",
+		"cleanup.target_account": "su cuenta de Google",
+		"cleanup.delete_warning": "ADVERTENCIA: Los contactos eliminados solo se pueden recuperar desde una copia de seguridad.",
+		"cleanup.cancelled":      "Limpieza cancelada.",
+		"cleanup.completed":      "¡Limpieza completada correctamente!",
+
+		"state.reset_warning": "Se eliminará el siguiente estado: %s",
+		"state.cancelled":     "Restablecimiento cancelado.",
+
+		"prune.confirm":   "¿Eliminar %d copias de seguridad de %s? (sí/no): ",
+		"prune.cancelled": "Limpieza cancelada.",
+	},
+	"fr": {
+		"confirm.continue": "Voulez-vous vraiment continuer ? (oui/non) : ",
+
+		"backup.completed":        "Sauvegarde terminée avec succès !",
+		"backup.summary.format":   "  Format :   %s",
+		"backup.summary.contacts": "  Contacts : %d",
+		"backup.summary.groups":   "  Groupes :  %d",
+		"backup.summary.file":     "  Fichier :  %s",
+
+		"restore.warning":               "ATTENTION : TOUS les contacts et groupes existants vont être SUPPRIMÉS !",
+		"restore.selection_warning":     "Les contacts de votre compte correspondant à la sélection seront SUPPRIMÉS puis recréés à partir de la sauvegarde. Les autres contacts et libellés ne sont pas modifiés.",
+		"restore.merge_warning":         "Les contacts de la sauvegarde seront ajoutés à votre compte ou remplaceront les contacts correspondants. Rien n'est supprimé.",
+		"restore.fields_warning":        "Les champs sélectionnés (%s) des contacts correspondants seront remplacés par les valeurs de la sauvegarde. Rien d'autre n'est modifié, créé ni supprimé.",
+		"restore.resume_warning":        "La restauration interrompue reprend là où elle s'est arrêtée. Les étapes terminées et les contacts déjà créés ne sont pas répétés.",
+		"restore.recommend_backup":      "Il est recommandé de créer d'abord une sauvegarde :",
+		"restore.safety_backup_note":    "Une sauvegarde de sécurité du compte est enregistrée avant toute modification.",
+		"restore.cancelled":             "Restauration annulée.",
+		"restore.confirm_phrase":        "Tapez \"%s\" pour continuer : ",
+		"restore.completed":             "Restauration terminée avec succès !",
+		"restore.summary.contacts":      "  Contacts restaurés : %d",
+		"restore.summary.groups":        "  Groupes restaurés :  %d",
+		"restore.summary.created":       "  Contacts créés :         %d",
+		"restore.summary.updated":       "  Contacts mis à jour :    %d",
+		"restore.summary.unchanged":     "  Déjà à jour :            %d",
+		"restore.summary.photos":        "  Photos restaurées :  %d",
+		"restore.summary.safety_backup": "  Sauvegarde de sécurité : %s",
+		"restore.photos_note":           "Remarque : les photos des contacts n'ont pas été restaurées car la sauvegarde ne contient pas les données des photos.\nSauvegardez avec --photo-bytes pour restaurer les photos.",
+
+		"cleanup.confirm":        "Supprimer %d contacts de %s ? (oui/non) : ",
+		"cleanup.target_account": "votre compte Google",
+		"cleanup.delete_warning": "ATTENTION : les contacts supprimés ne peuvent être récupérés qu'à partir d'une sauvegarde.",
+		"cleanup.cancelled":      "Nettoyage annulé.",
+		"cleanup.completed":      "Nettoyage terminé avec succès !",
+
+		"state.reset_warning": "L'état suivant va être supprimé : %s",
+		"state.cancelled":     "Réinitialisation annulée.",
+
+		"prune.confirm":   "Supprimer %d sauvegardes de %s ? (oui/non) : ",
+		"prune.cancelled": "Nettoyage annulé.",
+	},
+}
@@ -0,0 +1,96 @@
+// Package ignore implements the ignore list of the config file: contacts that
+// are never backed up, exported or restored, such as contacts synced from a
+// sensitive source.
+package ignore
+
+import (
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// List identifies the contacts to ignore. A contact is ignored if it matches
+// any entry.
+type List struct {
+	// Emails are email addresses, compared ignoring case. An entry starting
+	// with @ (e.g. "@clinic.example") matches every address at that domain.
+	Emails []string `json:"emails,omitempty"`
+
+	// ResourceNames are contact resource names such as "people/c123", or
+	// contact UUIDs (see backup --assign-uuids), which survive restores
+	ResourceNames []string `json:"resource_names,omitempty"`
+
+	// Labels are label names, compared ignoring case
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Empty reports whether the list ignores nothing.
+func (l List) Empty() bool {
+	return len(l.Emails) == 0 && len(l.ResourceNames) == 0 && len(l.Labels) == 0
+}
+
+// Match reports whether a contact is on the list. groupNames maps group
+// resource names to label names.
+func (l List) Match(contact *people.Person, groupNames map[string]string) bool {
+	for _, name := range l.ResourceNames {
+		if name == contact.ResourceName || (name != "" && name == models.ContactUUID(contact)) {
+			return true
+		}
+	}
+
+	for _, email := range contact.EmailAddresses {
+		address := strings.ToLower(strings.TrimSpace(email.Value))
+		for _, entry := range l.Emails {
+			entry = strings.ToLower(strings.TrimSpace(entry))
+			if entry == address || (strings.HasPrefix(entry, "@") && strings.HasSuffix(address, entry)) {
+				return true
+			}
+		}
+	}
+
+	for _, membership := range contact.Memberships {
+		if membership.ContactGroupMembership == nil {
+			continue
+		}
+		if l.HasLabel(groupNames[membership.ContactGroupMembership.ContactGroupResourceName]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasLabel reports whether contacts with the named label are ignored.
+func (l List) HasLabel(name string) bool {
+	for _, entry := range l.Labels {
+		if name != "" && strings.EqualFold(entry, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply removes the contacts on the list from a backup and returns them.
+func (l List) Apply(backup *models.BackupFile) []*people.Person {
+	if l.Empty() {
+		return nil
+	}
+	groupNames := backup.UserGroupNames()
+	return backup.RemoveContacts(func(p *people.Person) bool {
+		return l.Match(p, groupNames)
+	})
+}
+
+// Split divides contacts into those the list keeps and those it ignores.
+func (l List) Split(contacts []*people.Person, groupNames map[string]string) (kept, ignored []*people.Person) {
+	for _, contact := range contacts {
+		if l.Match(contact, groupNames) {
+			ignored = append(ignored, contact)
+		} else {
+			kept = append(kept, contact)
+		}
+	}
+	return kept, ignored
+}
@@ -0,0 +1,172 @@
+// Package integrity hashes contacts and arranges the hashes in a Merkle tree,
+// so that a backup can be checked for corruption and two contact lists can be
+// compared without a field-by-field diff.
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"google.golang.org/api/people/v1"
+)
+
+// Algorithm names the hash function used for contact hashes and tree nodes
+const Algorithm = "sha256"
+
+// volatileKeys are JSON keys that change without the contact's content
+// changing: etags, source metadata and the resource name, which is the key
+// the hash is stored under rather than part of it
+var volatileKeys = []string{"etag", "metadata", "resourceName"}
+
+// ContactHash returns the hex SHA-256 of a contact's content. Etags and
+// metadata are ignored, so a contact that was not edited hashes the same in
+// a backup and in the live account.
+func ContactHash(contact *people.Person) (string, error) {
+	data, err := json.Marshal(contact)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal contact: %w", err)
+	}
+
+	var content map[string]any
+	if err := json.Unmarshal(data, &content); err != nil {
+		return "", fmt.Errorf("failed to unmarshal contact: %w", err)
+	}
+	stripVolatile(content)
+
+	// encoding/json sorts map keys, making this encoding canonical
+	canonical, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal contact: %w", err)
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// stripVolatile removes volatile keys from v and every object nested in it.
+func stripVolatile(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, key := range volatileKeys {
+			delete(v, key)
+		}
+		for _, child := range v {
+			stripVolatile(child)
+		}
+	case []any:
+		for _, child := range v {
+			stripVolatile(child)
+		}
+	}
+}
+
+// Hashes returns the content hash of every contact, keyed by resource name.
+// Contacts without a resource name are keyed by their position instead.
+func Hashes(contacts []*people.Person) (map[string]string, error) {
+	hashes := make(map[string]string, len(contacts))
+	for i, contact := range contacts {
+		hash, err := ContactHash(contact)
+		if err != nil {
+			return nil, err
+		}
+		hashes[Key(contact, i)] = hash
+	}
+	return hashes, nil
+}
+
+// Key returns the key a contact's hash is stored under.
+func Key(contact *people.Person, index int) string {
+	if contact.ResourceName != "" {
+		return contact.ResourceName
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// Root returns the Merkle root of a set of contact hashes. Leaves are
+// H(key, hash) in key order and each node is H(left, right); an odd node is
+// carried up unchanged. The root of an empty set is the hash of nothing.
+func Root(hashes map[string]string) string {
+	keys := make([]string, 0, len(hashes))
+	for key := range hashes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	level := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		level = append(level, node([]byte(key), []byte(hashes[key])))
+	}
+	if len(level) == 0 {
+		return hex.EncodeToString(node())
+	}
+
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, node(level[i], level[i+1]))
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// node hashes its length-prefixed parts.
+func node(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+// Diff lists the keys that differ between two sets of contact hashes.
+type Diff struct {
+	// Changed are in both sets with different hashes
+	Changed []string
+	// Removed are only in the first set
+	Removed []string
+	// Added are only in the second set
+	Added []string
+}
+
+// Empty reports whether the two sets were identical.
+func (d Diff) Empty() bool {
+	return len(d.Changed) == 0 && len(d.Removed) == 0 && len(d.Added) == 0
+}
+
+// Compare returns the differences between two sets of contact hashes. When
+// their roots match it returns without comparing individual hashes.
+func Compare(from, to map[string]string) Diff {
+	var d Diff
+	if len(from) == len(to) && Root(from) == Root(to) {
+		return d
+	}
+
+	for key, hash := range from {
+		other, ok := to[key]
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, key)
+		case other != hash:
+			d.Changed = append(d.Changed, key)
+		}
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			d.Added = append(d.Added, key)
+		}
+	}
+
+	sort.Strings(d.Changed)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Added)
+	return d
+}
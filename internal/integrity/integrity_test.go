@@ -0,0 +1,130 @@
+package integrity
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/people/v1"
+)
+
+func contact(resourceName, email string) *people.Person {
+	return &people.Person{
+		ResourceName:   resourceName,
+		Names:          []*people.Name{{GivenName: "Ada", FamilyName: "Lovelace"}},
+		EmailAddresses: []*people.EmailAddress{{Value: email}},
+	}
+}
+
+func TestContactHash(t *testing.T) {
+	base, err := ContactHash(contact("people/c1", "ada@example.com"))
+	if err != nil {
+		t.Fatalf("ContactHash: %v", err)
+	}
+	if len(base) != 64 {
+		t.Errorf("hash %q is not hex SHA-256", base)
+	}
+
+	// Etags, metadata at any depth and the resource name are not content
+	volatile := contact("people/c2", "ada@example.com")
+	volatile.Etag = "%EgUBAi43PRoEAQIHCCIMRE5OUnA="
+	volatile.Metadata = &people.PersonMetadata{Sources: []*people.Source{{Type: "CONTACT", UpdateTime: "2024-06-01T02:00:00Z"}}}
+	volatile.EmailAddresses[0].Metadata = &people.FieldMetadata{Primary: true, Source: &people.Source{Type: "CONTACT", Id: "1"}}
+	if got, _ := ContactHash(volatile); got != base {
+		t.Error("etag, metadata or resource name changed the hash")
+	}
+
+	edited := contact("people/c1", "ada@example.org")
+	if got, _ := ContactHash(edited); got == base {
+		t.Error("a changed email did not change the hash")
+	}
+	extra := contact("people/c1", "ada@example.com")
+	extra.Nicknames = []*people.Nickname{{Value: "Ada"}}
+	if got, _ := ContactHash(extra); got == base {
+		t.Error("an added nickname did not change the hash")
+	}
+}
+
+func TestHashes(t *testing.T) {
+	contacts := []*people.Person{
+		contact("people/c1", "a@example.com"),
+		contact("", "b@example.com"),
+		contact("people/c3", "c@example.com"),
+	}
+	hashes, err := Hashes(contacts)
+	if err != nil {
+		t.Fatalf("Hashes: %v", err)
+	}
+
+	var keys []string
+	for i, c := range contacts {
+		key := Key(c, i)
+		keys = append(keys, key)
+		want, _ := ContactHash(c)
+		if hashes[key] != want {
+			t.Errorf("hash of %s = %s, want %s", key, hashes[key], want)
+		}
+	}
+	if want := []string{"people/c1", "#1", "people/c3"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+	if len(hashes) != 3 {
+		t.Errorf("got %d hashes, want 3", len(hashes))
+	}
+}
+
+func TestRoot(t *testing.T) {
+	if got, want := Root(nil), hex.EncodeToString(node()); got != want {
+		t.Errorf("Root(empty) = %s, want %s", got, want)
+	}
+
+	hashes := map[string]string{"people/c1": "aa", "people/c2": "bb", "people/c3": "cc"}
+	leaf := func(key string) []byte { return node([]byte(key), []byte(hashes[key])) }
+
+	// Leaves in key order, the odd one carried up unchanged
+	want := hex.EncodeToString(node(node(leaf("people/c1"), leaf("people/c2")), leaf("people/c3")))
+	if got := Root(hashes); got != want {
+		t.Errorf("Root = %s, want %s", got, want)
+	}
+
+	single := map[string]string{"people/c1": "aa"}
+	if got := Root(single); got != hex.EncodeToString(leaf("people/c1")) {
+		t.Errorf("Root of one hash = %s, want its leaf", got)
+	}
+
+	changed := map[string]string{"people/c1": "aa", "people/c2": "bb", "people/c3": "cd"}
+	renamed := map[string]string{"people/c1": "aa", "people/c2": "bb", "people/c4": "cc"}
+	if Root(changed) == want || Root(renamed) == want {
+		t.Error("changing a hash or key did not change the root")
+	}
+
+	// Length prefixes keep the key and hash boundary unambiguous
+	if Root(map[string]string{"ab": "c"}) == Root(map[string]string{"a": "bc"}) {
+		t.Error("shifting bytes between key and hash did not change the root")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := map[string]string{"people/a": "1", "people/b": "2", "people/c": "3", "people/d": "4"}
+	to := map[string]string{"people/a": "1", "people/b": "9", "people/d": "8", "people/e": "5", "#0": "6"}
+
+	d := Compare(from, to)
+	want := Diff{
+		Changed: []string{"people/b", "people/d"},
+		Removed: []string{"people/c"},
+		Added:   []string{"#0", "people/e"},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("Compare = %+v, want %+v", d, want)
+	}
+	if d.Empty() {
+		t.Error("Empty() = true for differing sets")
+	}
+
+	if d := Compare(from, map[string]string{"people/d": "4", "people/c": "3", "people/b": "2", "people/a": "1"}); !d.Empty() {
+		t.Errorf("Compare of equal sets = %+v", d)
+	}
+	if d := Compare(nil, nil); !d.Empty() {
+		t.Errorf("Compare of empty sets = %+v", d)
+	}
+}
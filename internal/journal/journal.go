@@ -0,0 +1,94 @@
+// Package journal appends an audit trail of long-running operations to a
+// JSON Lines file. Every record is flushed to disk as soon as it is written,
+// so a crash or kill leaves a precise record of how far the operation got.
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record event types.
+const (
+	EventStart  = "start"
+	EventStep   = "step"
+	EventBatch  = "batch"
+	EventFinish = "finish"
+)
+
+// Record is one line of the journal.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Event   string    `json:"event"`
+
+	// Step names the phase a step record completed, e.g. "delete_contacts"
+	Step string `json:"step,omitempty"`
+
+	// Operation, Batch, Contacts and Created describe a batch record: the
+	// operation ("create" or "update"), the zero-based batch index, the
+	// backup resource names sent and the resource names Google assigned
+	Operation string   `json:"operation,omitempty"`
+	Batch     *int     `json:"batch,omitempty"`
+	Contacts  []string `json:"contacts,omitempty"`
+	Created   []string `json:"created,omitempty"`
+
+	// DurationMS is how long the batch or the whole command took
+	DurationMS int64 `json:"duration_ms,omitempty"`
+
+	// Error is set for failed batches and commands
+	Error string `json:"error,omitempty"`
+
+	// Details holds free-form context, such as the backup file and counts
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Journal appends records to a file. It is safe for concurrent use.
+type Journal struct {
+	command string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens the journal at path for appending, creating it if needed.
+// Records are attributed to command.
+func Open(path, command string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	return &Journal{command: command, file: file}, nil
+}
+
+// Write appends record, filling in its time and command, and syncs the file.
+func (j *Journal) Write(record Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	record.Command = j.command
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
@@ -2,17 +2,31 @@
 package models
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"time"
 
 	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/encryption"
 )
 
 const (
 	// BackupVersion is the current version of the backup file format
 	BackupVersion = "1.0"
+
+	// TagPostRestore tags the backup restore takes of the account after a
+	// verified restore
+	TagPostRestore = "post-restore"
+
+	// TagPreRestore tags the safety backup restore takes of the account
+	// before changing anything
+	TagPreRestore = "pre-restore"
 )
 
 // BackupFile represents the complete backup data structure.
@@ -29,11 +43,65 @@ type BackupFile struct {
 	// GroupCount is the total number of contact groups in the backup
 	GroupCount int `json:"group_count"`
 
+	// Tag marks backups taken by the tool itself rather than by the user,
+	// e.g. TagPostRestore
+	Tag string `json:"tag,omitempty"`
+
+	// Transforms lists the filters and exclusions applied when the backup
+	// was created. Backups without any hold every contact of the account.
+	Transforms []Transform `json:"transforms,omitempty"`
+
 	// Contacts contains all backed up contact data
 	Contacts []*people.Person `json:"contacts"`
 
+	// OtherContacts holds the "Other contacts" Gmail saved automatically,
+	// for backups that include them. They are not restored.
+	OtherContacts []*people.Person `json:"other_contacts,omitempty"`
+
 	// Groups contains all backed up contact group data
 	Groups []*people.ContactGroup `json:"groups"`
+
+	// GroupMembers maps user group resource names to their member resource
+	// names, as reported by the group itself rather than by each contact
+	GroupMembers map[string][]string `json:"group_members,omitempty"`
+
+	// Photos maps contact resource names to the downloaded contact photo of
+	// each contact that has one, for backups taken with photo bytes
+	Photos map[string]*Photo `json:"photos,omitempty"`
+
+	// FallbackPhotos maps contact resource names to the linked Google profile
+	// photo of contacts that have no contact photo of their own
+	FallbackPhotos map[string]*FallbackPhoto `json:"fallback_photos,omitempty"`
+
+	// Manifest holds the contact hash tree, refreshed every time the
+	// backup is written. Backups from older versions have none.
+	Manifest *Manifest `json:"manifest,omitempty"`
+}
+
+// Photo is a contact photo downloaded at backup time, so it can be restored
+// after the photo URL has expired.
+type Photo struct {
+	// URL of the contact photo at backup time
+	URL string `json:"url"`
+
+	// ContentType and Data hold the downloaded image
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"data"`
+}
+
+// FallbackPhoto is a Google profile photo kept as a contact's avatar because
+// the contact has no photo of its own. It is not part of the contact itself.
+type FallbackPhoto struct {
+	// Source is always "PROFILE", marking the photo as coming from the
+	// contact's Google profile rather than the contact
+	Source string `json:"source"`
+
+	// URL of the profile photo at backup time
+	URL string `json:"url"`
+
+	// ContentType and Data hold the downloaded image, if requested
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"data,omitempty"`
 }
 
 // NewBackupFile creates a new backup file with the current timestamp.
@@ -58,29 +126,93 @@ func (b *BackupFile) AddGroup(group *people.ContactGroup) {
 	b.GroupCount = len(b.Groups)
 }
 
-// SaveToFile writes the backup to a JSON file.
+// ReplaceContact replaces the contact with the same resource name, or adds it
+// if the backup does not contain it yet. It reports whether a contact was replaced.
+func (b *BackupFile) ReplaceContact(contact *people.Person) bool {
+	for i, existing := range b.Contacts {
+		if existing.ResourceName == contact.ResourceName {
+			b.Contacts[i] = contact
+			return true
+		}
+	}
+	b.AddContact(contact)
+	return false
+}
+
+// SaveToFile writes the backup to a JSON file, compressed if the path ends
+// in .gz or .zst.
 func (b *BackupFile) SaveToFile(path string) error {
-	data, err := json.MarshalIndent(b, "", "  ")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+
+	if err := WriteCompressed(file, CompressionForPath(path), b.WriteJSON); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+
+	return nil
+}
+
+// WriteJSON refreshes the backup's manifest and writes the backup as
+// indented JSON to w. Contacts are encoded one at a time, so the JSON of the
+// whole backup is never held in memory.
+func (b *BackupFile) WriteJSON(w io.Writer) error {
+	manifest, err := b.BuildManifest()
 	if err != nil {
-		return fmt.Errorf("failed to marshal backup data: %w", err)
+		return err
 	}
+	b.Manifest = manifest
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := b.writeJSONStream(w); err != nil {
 		return fmt.Errorf("failed to write backup file: %w", err)
 	}
 
 	return nil
 }
 
-// LoadBackupFile loads a backup from a JSON file.
+// LoadBackupFile loads a backup from a JSON file, which may be gzip or zstd
+// compressed. Encrypted backups must be decrypted first and passed to
+// ParseBackupFile.
 func LoadBackupFile(path string) (*BackupFile, error) {
-	data, err := os.ReadFile(path)
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read backup file: %w", err)
 	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	// The age header is the first line of an encrypted file
+	if head, _ := r.Peek(64); encryption.IsEncrypted(head) {
+		return nil, fmt.Errorf("backup file %s is encrypted and must be decrypted first", path)
+	}
+
+	return ReadBackup(r)
+}
 
-	var backup BackupFile
-	if err := json.Unmarshal(data, &backup); err != nil {
+// ParseBackupFile parses a JSON backup held in memory, which may be gzip or
+// zstd compressed.
+func ParseBackupFile(data []byte) (*BackupFile, error) {
+	return ReadBackup(bytes.NewReader(data))
+}
+
+// ReadBackup decodes a JSON backup from r, decompressing it on the fly if it
+// is gzip or zstd compressed. Contacts are decoded one at a time as they are
+// read, so the file is never held in memory as a whole.
+func ReadBackup(r io.Reader) (*BackupFile, error) {
+	decompressed, release, err := Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	backup, err := decodeBackup(json.NewDecoder(decompressed))
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse backup file: %w", err)
 	}
 
@@ -89,7 +221,7 @@ func LoadBackupFile(path string) (*BackupFile, error) {
 		return nil, fmt.Errorf("invalid backup file: missing version")
 	}
 
-	return &backup, nil
+	return backup, nil
 }
 
 // GetUserGroups returns only user-created contact groups (excludes system groups).
@@ -102,3 +234,251 @@ func (b *BackupFile) GetUserGroups() []*people.ContactGroup {
 	}
 	return userGroups
 }
+
+// UserGroupNames maps the resource names of user-created groups to their names.
+func (b *BackupFile) UserGroupNames() map[string]string {
+	groupNames := make(map[string]string)
+	for _, group := range b.GetUserGroups() {
+		groupNames[group.ResourceName] = group.Name
+	}
+	return groupNames
+}
+
+// SortForRestore orders groups by name and contacts by resource name, so that
+// repeated restores from the same backup create the same batches in the same
+// order. The sort is stable, so contacts sharing a resource name (for example
+// imported contacts that have none) keep their relative order.
+func (b *BackupFile) SortForRestore() {
+	sort.SliceStable(b.Groups, func(i, j int) bool {
+		if b.Groups[i].Name != b.Groups[j].Name {
+			return b.Groups[i].Name < b.Groups[j].Name
+		}
+		return b.Groups[i].ResourceName < b.Groups[j].ResourceName
+	})
+	sort.SliceStable(b.Contacts, func(i, j int) bool {
+		return b.Contacts[i].ResourceName < b.Contacts[j].ResourceName
+	})
+}
+
+// Canonicalize puts the backup in a canonical order, so that two backups of
+// an unchanged account are written byte for byte the same: contacts, other
+// contacts and groups are sorted by resource name, and group member lists
+// and each contact's memberships by group. The API's order of everything
+// else, such as a contact's email addresses, is kept, as it is stable and
+// puts the primary value first.
+//
+// CreatedAt, which would differ on every run, is set to the most recent
+// update time of any contact or group in the backup, so it only changes
+// along with them. Backups without any update times keep their CreatedAt.
+func (b *BackupFile) Canonicalize() {
+	byResourceName := func(contacts []*people.Person) {
+		sort.SliceStable(contacts, func(i, j int) bool {
+			return contacts[i].ResourceName < contacts[j].ResourceName
+		})
+	}
+	byResourceName(b.Contacts)
+	byResourceName(b.OtherContacts)
+	sort.SliceStable(b.Groups, func(i, j int) bool {
+		return b.Groups[i].ResourceName < b.Groups[j].ResourceName
+	})
+	for _, members := range b.GroupMembers {
+		sort.Strings(members)
+	}
+
+	var latest time.Time
+	noteUpdate := func(value string) {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	for _, contact := range b.Contacts {
+		sort.SliceStable(contact.Memberships, func(i, j int) bool {
+			return membershipGroup(contact.Memberships[i]) < membershipGroup(contact.Memberships[j])
+		})
+		if contact.Metadata != nil {
+			for _, source := range contact.Metadata.Sources {
+				noteUpdate(source.UpdateTime)
+			}
+		}
+	}
+	for _, group := range b.Groups {
+		if group.Metadata != nil {
+			noteUpdate(group.Metadata.UpdateTime)
+		}
+	}
+	if !latest.IsZero() {
+		b.CreatedAt = latest.UTC()
+	}
+}
+
+// membershipGroup returns the resource name of a membership's contact group,
+// or "" for domain memberships.
+func membershipGroup(membership *people.Membership) string {
+	if membership.ContactGroupMembership == nil {
+		return ""
+	}
+	return membership.ContactGroupMembership.ContactGroupResourceName
+}
+
+// ApplyGroupMembers adds any group memberships recorded in GroupMembers that
+// are missing from the contacts themselves, so both sources of membership
+// information are honoured on restore. Returns the number of memberships added.
+func (b *BackupFile) ApplyGroupMembers() int {
+	if len(b.GroupMembers) == 0 {
+		return 0
+	}
+
+	byResourceName := make(map[string]*people.Person, len(b.Contacts))
+	for _, contact := range b.Contacts {
+		if contact.ResourceName != "" {
+			byResourceName[contact.ResourceName] = contact
+		}
+	}
+
+	groupNames := make([]string, 0, len(b.GroupMembers))
+	for groupName := range b.GroupMembers {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+
+	added := 0
+	for _, groupName := range groupNames {
+		for _, member := range b.GroupMembers[groupName] {
+			contact, ok := byResourceName[member]
+			if !ok || hasMembership(contact, groupName) {
+				continue
+			}
+			contact.Memberships = append(contact.Memberships, &people.Membership{
+				ContactGroupMembership: &people.ContactGroupMembership{
+					ContactGroupResourceName: groupName,
+				},
+			})
+			added++
+		}
+	}
+
+	return added
+}
+
+// hasMembership reports whether the contact is a member of the given group.
+func hasMembership(contact *people.Person, groupResourceName string) bool {
+	for _, membership := range contact.Memberships {
+		if membership.ContactGroupMembership != nil &&
+			membership.ContactGroupMembership.ContactGroupResourceName == groupResourceName {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns a new backup holding only the contacts that carry one of the
+// given labels (user group names) or have one of the given resource names,
+// along with the groups they belong to. Empty filters select nothing.
+func (b *BackupFile) Select(labels, resourceNames []string) *BackupFile {
+	wantGroups := make(map[string]bool)
+	for _, group := range b.GetUserGroups() {
+		for _, label := range labels {
+			if group.Name == label {
+				wantGroups[group.ResourceName] = true
+			}
+		}
+	}
+	wantContacts := make(map[string]bool, len(resourceNames))
+	for _, name := range resourceNames {
+		wantContacts[name] = true
+	}
+
+	selected := NewBackupFile()
+	usedGroups := make(map[string]bool)
+	for _, contact := range b.Contacts {
+		match := wantContacts[contact.ResourceName]
+		for groupName := range wantGroups {
+			if hasMembership(contact, groupName) {
+				match = true
+			}
+		}
+		if !match {
+			continue
+		}
+		selected.AddContact(contact)
+		for _, membership := range contact.Memberships {
+			if membership.ContactGroupMembership != nil {
+				usedGroups[membership.ContactGroupMembership.ContactGroupResourceName] = true
+			}
+		}
+	}
+
+	for _, group := range b.Groups {
+		if usedGroups[group.ResourceName] {
+			selected.AddGroup(group)
+		}
+	}
+
+	return selected
+}
+
+// RemoveUnusedGroups removes the user groups none of the backup's contacts
+// belongs to, along with their member lists, and returns how many were
+// removed. System groups are kept.
+func (b *BackupFile) RemoveUnusedGroups() int {
+	used := make(map[string]bool)
+	for _, contact := range b.Contacts {
+		for _, membership := range contact.Memberships {
+			if membership.ContactGroupMembership != nil {
+				used[membership.ContactGroupMembership.ContactGroupResourceName] = true
+			}
+		}
+	}
+
+	kept := b.Groups[:0:0]
+	for _, group := range b.Groups {
+		if group.GroupType == "USER_CONTACT_GROUP" && !used[group.ResourceName] {
+			delete(b.GroupMembers, group.ResourceName)
+			continue
+		}
+		kept = append(kept, group)
+	}
+	removed := len(b.Groups) - len(kept)
+	b.Groups = kept
+	b.GroupCount = len(kept)
+	return removed
+}
+
+// RemoveContacts removes the contacts for which match returns true, along
+// with their group member entries and photos. Returns the removed
+// contacts.
+func (b *BackupFile) RemoveContacts(match func(*people.Person) bool) []*people.Person {
+	kept := make([]*people.Person, 0, len(b.Contacts))
+	var removed []*people.Person
+	removedNames := make(map[string]bool)
+	for _, contact := range b.Contacts {
+		if match(contact) {
+			removed = append(removed, contact)
+			removedNames[contact.ResourceName] = true
+			continue
+		}
+		kept = append(kept, contact)
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	b.Contacts = kept
+	b.ContactCount = len(kept)
+
+	for groupName, members := range b.GroupMembers {
+		keptMembers := members[:0:0]
+		for _, member := range members {
+			if !removedNames[member] {
+				keptMembers = append(keptMembers, member)
+			}
+		}
+		b.GroupMembers[groupName] = keptMembers
+	}
+	for name := range removedNames {
+		delete(b.Photos, name)
+		delete(b.FallbackPhotos, name)
+	}
+
+	return removed
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// charsets maps the supported legacy character sets to their code pages.
+// UTF-8 is supported too and needs no conversion.
+var charsets = map[string]*charmap.Charmap{
+	"iso-8859-1":   charmap.ISO8859_1,
+	"iso-8859-15":  charmap.ISO8859_15,
+	"windows-1250": charmap.Windows1250,
+	"windows-1251": charmap.Windows1251,
+	"windows-1252": charmap.Windows1252,
+}
+
+// Charsets returns the character sets exports can be written in, for older
+// phones and car head units that do not understand UTF-8.
+func Charsets() []string {
+	return []string{"utf-8", "iso-8859-1", "iso-8859-15", "windows-1250", "windows-1251", "windows-1252"}
+}
+
+// IsCharset reports whether name is a supported character set.
+func IsCharset(name string) bool {
+	_, err := lookupCharset(name)
+	return err == nil
+}
+
+// lookupCharset returns the code page of a character set, or nil for UTF-8.
+func lookupCharset(name string) (*charmap.Charmap, error) {
+	name = strings.ToLower(name)
+	if name == "" || name == "utf-8" || name == "utf8" {
+		return nil, nil
+	}
+	if cm, ok := charsets[name]; ok {
+		return cm, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q: must be one of %s", name, strings.Join(Charsets(), ", "))
+}
+
+// encodeCharset converts a UTF-8 string to a code page, replacing characters
+// the code page cannot represent with "?".
+func encodeCharset(s string, cm *charmap.Charmap) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		b, ok := cm.EncodeRune(r)
+		if !ok {
+			b = '?'
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// charsetWriter converts UTF-8 written to it into a code page. A rune split
+// across two writes is held back until it is complete.
+type charsetWriter struct {
+	w       io.Writer
+	cm      *charmap.Charmap
+	pending []byte
+}
+
+// newCharsetWriter returns w itself for UTF-8, or a writer converting to
+// the named character set.
+func newCharsetWriter(w io.Writer, charset string) (io.Writer, error) {
+	cm, err := lookupCharset(charset)
+	if err != nil || cm == nil {
+		return w, err
+	}
+	return &charsetWriter{w: w, cm: cm}, nil
+}
+
+func (c *charsetWriter) Write(p []byte) (int, error) {
+	data := append(c.pending, p...)
+
+	// Only the last rune can be incomplete
+	complete := len(data)
+	start := complete - 1
+	for start > 0 && !utf8.RuneStart(data[start]) {
+		start--
+	}
+	if start >= 0 && !utf8.FullRune(data[start:]) {
+		complete = start
+	}
+
+	if _, err := c.w.Write(encodeCharset(string(data[:complete]), c.cm)); err != nil {
+		return 0, err
+	}
+	c.pending = append([]byte(nil), data[complete:]...)
+	return len(p), nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import "google.golang.org/api/people/v1"
+
+// ClientDataValue returns the value of the contact's client data entry with
+// the given key, and whether it exists.
+func ClientDataValue(contact *people.Person, key string) (string, bool) {
+	for _, data := range contact.ClientData {
+		if data.Key == key {
+			return data.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetClientData sets a client data entry on the contact, replacing the value
+// of an existing entry with the same key. It reports whether the contact changed.
+func SetClientData(contact *people.Person, key, value string) bool {
+	for _, data := range contact.ClientData {
+		if data.Key == key {
+			if data.Value == value {
+				return false
+			}
+			data.Value = value
+			return true
+		}
+	}
+	contact.ClientData = append(contact.ClientData, &people.ClientData{Key: key, Value: value})
+	return true
+}
+
+// RemoveClientData removes the contact's client data entry with the given
+// key. If value is not empty, the entry is only removed if it has that value.
+// It reports whether the contact changed.
+func RemoveClientData(contact *people.Person, key, value string) bool {
+	for i, data := range contact.ClientData {
+		if data.Key == key && (value == "" || data.Value == value) {
+			contact.ClientData = append(contact.ClientData[:i:i], contact.ClientData[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats for backup files.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Magic numbers that start compressed streams
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseCompression normalizes a compression format given on the command
+// line or in the config file. An empty value means no compression.
+func ParseCompression(compression string) (string, error) {
+	switch strings.ToLower(compression) {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, "gz":
+		return CompressionGzip, nil
+	case CompressionZstd, "zst":
+		return CompressionZstd, nil
+	default:
+		return "", fmt.Errorf("invalid compression %q: must be 'gzip', 'zstd' or 'none'", compression)
+	}
+}
+
+// CompressionExtension returns the file name suffix for a compression
+// format, e.g. ".gz", or "" for none.
+func CompressionExtension(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// CompressionForPath returns the compression format a file name's suffix
+// stands for.
+func CompressionForPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(path, ".zst"):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// DetectCompression returns the compression format of a stream from its
+// first bytes.
+func DetectCompression(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(head, zstdMagic):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// WriteCompressed calls write with a writer that compresses to w in the
+// given format, and flushes the compressed stream when write returns.
+func WriteCompressed(w io.Writer, compression string, write func(io.Writer) error) error {
+	var cw io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		cw = gzip.NewWriter(w)
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(w)
+		if err != nil {
+			return fmt.Errorf("failed to compress backup: %w", err)
+		}
+		cw = enc
+	default:
+		return write(w)
+	}
+
+	if err := write(cw); err != nil {
+		cw.Close()
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return fmt.Errorf("failed to compress backup: %w", err)
+	}
+	return nil
+}
+
+// Decompress returns a reader of the decompressed contents of r if r holds a
+// gzip or zstd stream, detected from its first bytes, or a reader of r's
+// contents as they are otherwise. The returned function releases the
+// decompressor and must be called when done.
+func Decompress(r io.Reader) (io.Reader, func(), error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress backup: %w", err)
+		}
+		return gz, func() { gz.Close() }, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		dec, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress backup: %w", err)
+		}
+		return dec, dec.Close, nil
+	default:
+		return br, func() {}, nil
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ConvertOptions controls how Convert writes contacts. Only the options of the
+// output format apply.
+type ConvertOptions struct {
+	// CSV is used when writing csv
+	CSV CSVOptions
+
+	// VCard is used when writing vcf and vcf21; the version is taken from
+	// the format
+	VCard VCardOptions
+
+	// HTML is used when writing html
+	HTML HTMLOptions
+
+	// Template is used when writing one of the CSVTemplates
+	Template TemplateCSVOptions
+}
+
+// convertReaders maps the formats Convert reads to their readers
+var convertReaders = map[string]func(r io.Reader) (*BackupFile, error){
+	"json": ReadBackup,
+	"csv":  ReadCSV,
+	"vcf":  ReadVCard,
+}
+
+// convertWriters maps the formats Convert writes, other than the CSV
+// templates, to their writers
+var convertWriters = map[string]func(b *BackupFile, w io.Writer, opts ConvertOptions) error{
+	"json": func(b *BackupFile, w io.Writer, opts ConvertOptions) error {
+		return b.WriteJSON(w)
+	},
+	"csv": func(b *BackupFile, w io.Writer, opts ConvertOptions) error {
+		return b.WriteCSV(w, opts.CSV)
+	},
+	"vcf": func(b *BackupFile, w io.Writer, opts ConvertOptions) error {
+		opts.VCard.Version = "3.0"
+		return b.WriteVCard(w, opts.VCard)
+	},
+	"vcf21": func(b *BackupFile, w io.Writer, opts ConvertOptions) error {
+		opts.VCard.Version = "2.1"
+		return b.WriteVCard(w, opts.VCard)
+	},
+	"html": func(b *BackupFile, w io.Writer, opts ConvertOptions) error {
+		return b.WriteHTML(w, opts.HTML)
+	},
+}
+
+// ConvertInputFormats returns the formats Convert reads, sorted.
+func ConvertInputFormats() []string {
+	formats := make([]string, 0, len(convertReaders))
+	for format := range convertReaders {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// ConvertOutputFormats returns the formats Convert writes, sorted: json, csv,
+// vcf, vcf21, html and the CSVTemplates.
+func ConvertOutputFormats() []string {
+	formats := CSVTemplates()
+	for format := range convertWriters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Convert reads contacts in fromFormat from r and writes them in toFormat to
+// w, without touching the file system or Google. See ConvertInputFormats and
+// ConvertOutputFormats for the formats. Compressed JSON is decompressed.
+func Convert(r io.Reader, fromFormat string, w io.Writer, toFormat string, opts ConvertOptions) error {
+	backup, err := ReadFormat(r, fromFormat)
+	if err != nil {
+		return err
+	}
+	return backup.WriteFormat(w, toFormat, opts)
+}
+
+// ReadFormat reads contacts in the given format (see ConvertInputFormats).
+func ReadFormat(r io.Reader, format string) (*BackupFile, error) {
+	read, ok := convertReaders[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported input format %q: must be one of %s", format, strings.Join(ConvertInputFormats(), ", "))
+	}
+	return read(r)
+}
+
+// WriteFormat writes the backup in the given format (see
+// ConvertOutputFormats).
+func (b *BackupFile) WriteFormat(w io.Writer, format string, opts ConvertOptions) error {
+	format = strings.ToLower(format)
+	if write, ok := convertWriters[format]; ok {
+		return write(b, w, opts)
+	}
+	if _, ok := csvTemplates[format]; ok {
+		return b.WriteTemplateCSV(w, format, opts.Template)
+	}
+	return fmt.Errorf("unsupported output format %q: must be one of %s", format, strings.Join(ConvertOutputFormats(), ", "))
+}
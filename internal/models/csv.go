@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -27,6 +28,9 @@ const (
 	colOrgName            = "Organization Name"
 	colOrgTitle           = "Organization Title"
 	colOrgDepartment      = "Organization Department"
+	colNameSource         = "Name Source"
+	colBirthdaySource     = "Birthday Source"
+	colOrgSource          = "Organization Source"
 )
 
 // labelSeparator is the separator used between labels in the Labels column
@@ -74,8 +78,9 @@ func countMaxFields(contacts []*people.Person) csvFieldCounts {
 	return counts
 }
 
-// buildCSVHeaders creates the header row based on field counts
-func buildCSVHeaders(counts csvFieldCounts) []string {
+// buildCSVHeaders creates the header row based on field counts, with a
+// source column for each field if sources is set
+func buildCSVHeaders(counts csvFieldCounts, sources bool) []string {
 	headers := []string{
 		colNamePrefix,
 		colFirstName,
@@ -92,17 +97,28 @@ func buildCSVHeaders(counts csvFieldCounts) []string {
 		colOrgTitle,
 		colOrgDepartment,
 	}
+	if sources {
+		headers = append(headers, colNameSource, colBirthdaySource, colOrgSource)
+	}
+	// source adds the source column of a numbered field
+	source := func(field string, i int) {
+		if sources {
+			headers = append(headers, fmt.Sprintf("%s %d - Source", field, i))
+		}
+	}
 
 	// Add email columns
 	for i := 1; i <= counts.Emails; i++ {
 		headers = append(headers, fmt.Sprintf("Email %d - Label", i))
 		headers = append(headers, fmt.Sprintf("Email %d - Value", i))
+		source("Email", i)
 	}
 
 	// Add phone columns
 	for i := 1; i <= counts.Phones; i++ {
 		headers = append(headers, fmt.Sprintf("Phone %d - Label", i))
 		headers = append(headers, fmt.Sprintf("Phone %d - Value", i))
+		source("Phone", i)
 	}
 
 	// Add address columns
@@ -115,30 +131,35 @@ func buildCSVHeaders(counts csvFieldCounts) []string {
 		headers = append(headers, fmt.Sprintf("Address %d - Postal Code", i))
 		headers = append(headers, fmt.Sprintf("Address %d - Country", i))
 		headers = append(headers, fmt.Sprintf("Address %d - PO Box", i))
+		source("Address", i)
 	}
 
 	// Add event columns
 	for i := 1; i <= counts.Events; i++ {
 		headers = append(headers, fmt.Sprintf("Event %d - Label", i))
 		headers = append(headers, fmt.Sprintf("Event %d - Value", i))
+		source("Event", i)
 	}
 
 	// Add relation columns
 	for i := 1; i <= counts.Relations; i++ {
 		headers = append(headers, fmt.Sprintf("Relation %d - Label", i))
 		headers = append(headers, fmt.Sprintf("Relation %d - Value", i))
+		source("Relation", i)
 	}
 
 	// Add website columns
 	for i := 1; i <= counts.Websites; i++ {
 		headers = append(headers, fmt.Sprintf("Website %d - Label", i))
 		headers = append(headers, fmt.Sprintf("Website %d - Value", i))
+		source("Website", i)
 	}
 
 	// Add custom field columns
 	for i := 1; i <= counts.CustomFields; i++ {
 		headers = append(headers, fmt.Sprintf("Custom Field %d - Label", i))
 		headers = append(headers, fmt.Sprintf("Custom Field %d - Value", i))
+		source("Custom Field", i)
 	}
 
 	// Add notes and labels at the end
@@ -148,14 +169,15 @@ func buildCSVHeaders(counts csvFieldCounts) []string {
 }
 
 // contactToCSVRow converts a contact to a CSV row
-func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap map[string]string) []string {
+func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap map[string]string, opts CSVOptions) []string {
 	row := make([]string, 0)
 
 	// Name fields
 	var namePrefix, firstName, middleName, lastName, nameSuffix string
-	var phoneticFirst, phoneticMiddle, phoneticLast string
+	var phoneticFirst, phoneticMiddle, phoneticLast, nameSource string
 	if len(contact.Names) > 0 {
 		name := contact.Names[0]
+		nameSource = FieldSource(name.Metadata)
 		namePrefix = name.HonorificPrefix
 		firstName = name.GivenName
 		middleName = name.MiddleName
@@ -179,9 +201,10 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 	}
 
 	// Birthday
-	var birthday string
+	var birthday, birthdaySource string
 	if len(contact.Birthdays) > 0 {
 		bday := contact.Birthdays[0]
+		birthdaySource = FieldSource(bday.Metadata)
 		if bday.Date != nil {
 			if bday.Date.Year > 0 {
 				birthday = fmt.Sprintf("%04d-%02d-%02d", bday.Date.Year, bday.Date.Month, bday.Date.Day)
@@ -192,9 +215,10 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 	}
 
 	// Organization
-	var orgName, orgTitle, orgDepartment string
+	var orgName, orgTitle, orgDepartment, orgSource string
 	if len(contact.Organizations) > 0 {
 		org := contact.Organizations[0]
+		orgSource = FieldSource(org.Metadata)
 		orgName = org.Name
 		orgTitle = org.Title
 		orgDepartment = org.Department
@@ -217,14 +241,26 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 		orgTitle,
 		orgDepartment,
 	)
+	if opts.Sources {
+		row = append(row, nameSource, birthdaySource, orgSource)
+	}
+	// source adds the source column of a numbered field, empty if the
+	// contact has no value there
+	source := func(metadata *people.FieldMetadata) {
+		if opts.Sources {
+			row = append(row, FieldSource(metadata))
+		}
+	}
 
 	// Add emails
 	for i := 0; i < counts.Emails; i++ {
 		if i < len(contact.EmailAddresses) {
 			email := contact.EmailAddresses[i]
 			row = append(row, normalizeLabel(email.Type), email.Value)
+			source(email.Metadata)
 		} else {
 			row = append(row, "", "")
+			source(nil)
 		}
 	}
 
@@ -233,8 +269,10 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 		if i < len(contact.PhoneNumbers) {
 			phone := contact.PhoneNumbers[i]
 			row = append(row, normalizeLabel(phone.Type), phone.Value)
+			source(phone.Metadata)
 		} else {
 			row = append(row, "", "")
+			source(nil)
 		}
 	}
 
@@ -252,8 +290,10 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 				addr.Country,
 				addr.PoBox,
 			)
+			source(addr.Metadata)
 		} else {
 			row = append(row, "", "", "", "", "", "", "", "")
+			source(nil)
 		}
 	}
 
@@ -270,8 +310,10 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 				}
 			}
 			row = append(row, normalizeLabel(event.Type), eventDate)
+			source(event.Metadata)
 		} else {
 			row = append(row, "", "")
+			source(nil)
 		}
 	}
 
@@ -280,8 +322,10 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 		if i < len(contact.Relations) {
 			rel := contact.Relations[i]
 			row = append(row, normalizeLabel(rel.Type), rel.Person)
+			source(rel.Metadata)
 		} else {
 			row = append(row, "", "")
+			source(nil)
 		}
 	}
 
@@ -290,8 +334,10 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 		if i < len(contact.Urls) {
 			url := contact.Urls[i]
 			row = append(row, normalizeLabel(url.Type), url.Value)
+			source(url.Metadata)
 		} else {
 			row = append(row, "", "")
+			source(nil)
 		}
 	}
 
@@ -300,15 +346,17 @@ func contactToCSVRow(contact *people.Person, counts csvFieldCounts, groupNameMap
 		if i < len(contact.UserDefined) {
 			ud := contact.UserDefined[i]
 			row = append(row, ud.Key, ud.Value)
+			source(ud.Metadata)
 		} else {
 			row = append(row, "", "")
+			source(nil)
 		}
 	}
 
 	// Notes
 	var notes string
 	if len(contact.Biographies) > 0 {
-		notes = contact.Biographies[0].Value
+		notes = NotesText(contact.Biographies[0], opts.PlainTextNotes)
 	}
 	row = append(row, notes)
 
@@ -402,8 +450,41 @@ func isSystemGroup(resourceName string) bool {
 	return false
 }
 
+// CSVOptions controls how a backup is written as CSV.
+type CSVOptions struct {
+	// Locale selects the language of the header row (see CSVLocales).
+	// Empty means English.
+	Locale string
+
+	// PlainTextNotes converts HTML notes (TEXT_HTML biographies) to plain text
+	PlainTextNotes bool
+
+	// Sources adds a column after each field naming the source its value
+	// came from (see FieldSource), such as CONTACT or DOMAIN_PROFILE
+	Sources bool
+}
+
 // SaveToCSV writes the backup to a Google-compatible CSV file.
-func (b *BackupFile) SaveToCSV(path string) error {
+func (b *BackupFile) SaveToCSV(path string, opts CSVOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+
+	if err := b.WriteCSV(file, opts); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+
+	return nil
+}
+
+// WriteCSV writes the backup as Google-compatible CSV to w.
+func (b *BackupFile) WriteCSV(w io.Writer, opts CSVOptions) error {
 	// Build group name lookup map
 	groupNameMap := make(map[string]string)
 	for _, group := range b.Groups {
@@ -424,18 +505,13 @@ func (b *BackupFile) SaveToCSV(path string) error {
 	}
 
 	// Build headers
-	headers := buildCSVHeaders(counts)
-
-	// Create file
-	file, err := os.Create(path)
+	headers, err := localizeCSVHeaders(buildCSVHeaders(counts, opts.Sources), opts.Locale)
 	if err != nil {
-		return fmt.Errorf("failed to create CSV file: %w", err)
+		return err
 	}
-	defer file.Close()
 
 	// Create CSV writer
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	writer := csv.NewWriter(w)
 
 	// Write header
 	if err := writer.Write(headers); err != nil {
@@ -444,11 +520,16 @@ func (b *BackupFile) SaveToCSV(path string) error {
 
 	// Write contacts
 	for _, contact := range b.Contacts {
-		row := contactToCSVRow(contact, counts, groupNameMap)
+		row := contactToCSVRow(contact, counts, groupNameMap, opts)
 		if err := writer.Write(row); err != nil {
 			return fmt.Errorf("failed to write CSV row: %w", err)
 		}
 	}
 
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+
 	return nil
 }
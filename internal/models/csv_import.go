@@ -0,0 +1,585 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// colFullName is the single name column of Google's older "Google CSV"
+// export, used when a contact has no structured name
+const colFullName = "Name"
+
+// csvHeaderAliases maps the headers of Google Contacts' own CSV exports,
+// current and older, to the headers WriteCSV writes
+var csvHeaderAliases = map[string]string{
+	"Given Name":                  colFirstName,
+	"Additional Name":             colMiddleName,
+	"Family Name":                 colLastName,
+	"Given Name Yomi":             colPhoneticFirstName,
+	"Additional Name Yomi":        colPhoneticMiddleName,
+	"Family Name Yomi":            colPhoneticLastName,
+	"Group Membership":            colLabels,
+	"Organization 1 - Name":       colOrgName,
+	"Organization 1 - Title":      colOrgTitle,
+	"Organization 1 - Department": colOrgDepartment,
+}
+
+// csvTokenAliases maps the tokens of numbered headers in Google's exports,
+// such as "E-mail 1 - Type", to the tokens WriteCSV writes
+var csvTokenAliases = map[string]string{
+	"E-mail": "Email",
+	"Type":   "Label",
+}
+
+// csvTypes are the People API types of emails, phone numbers, addresses,
+// events, relations and websites. Labels matching one of them, ignoring case
+// and spaces, are read as that type; other labels are kept as custom labels.
+var csvTypes = []string{
+	"home", "work", "other", "mobile", "main", "homeFax", "workFax", "otherFax",
+	"pager", "workMobile", "workPager", "googleVoice", "anniversary", "spouse",
+	"child", "mother", "father", "parent", "brother", "sister", "friend",
+	"relative", "domesticPartner", "manager", "assistant", "referredBy",
+	"partner", "homePage", "blog", "profile", "ftp", "appInstallPage",
+}
+
+// csvColumn is a column of a CSV file being read, by its English header.
+// Numbered columns such as "Email 2 - Value" are split into field, number
+// and attribute.
+type csvColumn struct {
+	header    string
+	field     string
+	number    int
+	attribute string
+}
+
+// LoadCSV reads a CSV file written by SaveToCSV or exported from Google
+// Contacts. See ReadCSV.
+func LoadCSV(path string) (*BackupFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadCSV(file)
+}
+
+// ReadCSV reads contacts from CSV in the format WriteCSV writes, in any of
+// the CSV locales, or in the format Google Contacts exports. Labels become
+// user contact groups and every contact is a member of myContacts. Contacts
+// and groups get placeholder resource names, as they have none in Google yet.
+// Columns ReadCSV does not understand are ignored; see ReadCSVMapped.
+func ReadCSV(r io.Reader) (*BackupFile, error) {
+	backup, _, err := ReadCSVMapped(r, nil)
+	return backup, err
+}
+
+// ReadCSVMapped reads contacts as ReadCSV does, with the columns named in
+// overrides read as the columns they map to. It also returns how every
+// column was read, so callers can show which columns are dropped.
+func ReadCSVMapped(r io.Reader, overrides CSVMap) (*BackupFile, *CSVMapping, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("invalid CSV file: missing header row")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV file: %w", err)
+	}
+	// Spreadsheet programs often start the file with a byte order mark
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+	}
+
+	columns := make([]csvColumn, len(headers))
+	mapping := &CSVMapping{Columns: make([]CSVColumnMapping, len(headers))}
+	used := make(map[string]bool)
+	known := 0
+	for i, header := range headers {
+		header = strings.TrimSpace(header)
+		target, override := overrides[header]
+		if override {
+			used[header] = true
+			if target != "" {
+				columns[i] = parseCSVHeader(target)
+				if columns[i].header == "" {
+					return nil, nil, fmt.Errorf("invalid column mapping for %q: %q is not a column that can be imported", header, target)
+				}
+			}
+		} else {
+			columns[i] = parseCSVHeader(header)
+		}
+		mapping.Columns[i] = CSVColumnMapping{Header: header, Field: columns[i].contactField(), Override: override}
+		if columns[i].header != "" {
+			known++
+		}
+	}
+	if known == 0 {
+		return nil, nil, fmt.Errorf("invalid CSV file: no contact columns found in the header row")
+	}
+	for header := range overrides {
+		if !used[header] {
+			mapping.UnusedOverrides = append(mapping.UnusedOverrides, header)
+		}
+	}
+	sort.Strings(mapping.UnusedOverrides)
+
+	im := newImporter("csv")
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse CSV file: %w", err)
+		}
+		for i, value := range record {
+			if i < len(mapping.Columns) && strings.TrimSpace(value) != "" {
+				mapping.Columns[i].Values++
+			}
+		}
+
+		contact, labels, err := csvRecordToContact(columns, record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if contact != nil {
+			im.add(contact, row, labels)
+		}
+	}
+
+	return im.backup, mapping, nil
+}
+
+// CSVMap maps the headers of a CSV file to the columns ReadCSVMapped should
+// read them as, given by their English headers as WriteCSV writes them,
+// e.g. "E-Mail privat" to "Email 1 - Value". An empty target skips the
+// column.
+type CSVMap map[string]string
+
+// LoadCSVMap reads a CSVMap from a JSON file holding an object of headers
+// and targets.
+func LoadCSVMap(path string) (CSVMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column mapping: %w", err)
+	}
+	var m CSVMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid column mapping %s: %w", path, err)
+	}
+	for header, target := range m {
+		if target != "" && parseCSVHeader(target).header == "" {
+			return nil, fmt.Errorf("invalid column mapping %s: %q is not a column that can be imported (mapped from %q)", path, target, header)
+		}
+	}
+	return m, nil
+}
+
+// CSVColumnMapping is how one column of a CSV file is read.
+type CSVColumnMapping struct {
+	// Header is the header of the column in the file
+	Header string
+
+	// Field is the contact field the column is read into, such as
+	// "names.givenName" or "emailAddresses.value #2", or "" if the column
+	// is not read
+	Field string
+
+	// Override reports whether the column was mapped by a CSVMap
+	Override bool
+
+	// Values is the number of rows with a value in the column
+	Values int
+}
+
+// CSVMapping is how the columns of a CSV file were read, in file order.
+type CSVMapping struct {
+	Columns []CSVColumnMapping
+
+	// UnusedOverrides are the headers of the CSVMap the file does not have
+	UnusedOverrides []string
+}
+
+// Dropped returns the columns that were not read although rows have values
+// in them, leaving out those a CSVMap skips on purpose.
+func (m *CSVMapping) Dropped() []CSVColumnMapping {
+	var dropped []CSVColumnMapping
+	for _, column := range m.Columns {
+		if column.Field == "" && !column.Override && column.Values > 0 {
+			dropped = append(dropped, column)
+		}
+	}
+	return dropped
+}
+
+// csvFields maps the headers of single columns to the contact fields they
+// are read into
+var csvFields = map[string]string{
+	colNamePrefix:         "names.honorificPrefix",
+	colFirstName:          "names.givenName",
+	colMiddleName:         "names.middleName",
+	colLastName:           "names.familyName",
+	colNameSuffix:         "names.honorificSuffix",
+	colPhoneticFirstName:  "names.phoneticGivenName",
+	colPhoneticMiddleName: "names.phoneticMiddleName",
+	colPhoneticLastName:   "names.phoneticFamilyName",
+	colFullName:           "names.unstructuredName",
+	colNickname:           "nicknames.value",
+	colFileAs:             "fileAses.value",
+	colBirthday:           "birthdays.date",
+	colNotes:              "biographies.value",
+	colLabels:             "memberships (labels)",
+	colOrgName:            "organizations.name",
+	colOrgTitle:           "organizations.title",
+	colOrgDepartment:      "organizations.department",
+	colNameSource:         "names.metadata.source",
+	colBirthdaySource:     "birthdays.metadata.source",
+	colOrgSource:          "organizations.metadata.source",
+}
+
+// csvNumberedFields maps the fields of numbered columns to People API fields
+var csvNumberedFields = map[string]string{
+	"Email":        "emailAddresses",
+	"Phone":        "phoneNumbers",
+	"Address":      "addresses",
+	"Event":        "events",
+	"Relation":     "relations",
+	"Website":      "urls",
+	"Custom Field": "userDefined",
+}
+
+// csvAttributes maps the attributes ReadCSV reads of each numbered field to
+// the People API attributes they are read into
+var csvAttributes = map[string]map[string]string{
+	"Email":    {"Label": "type", "Value": "value", "Source": "metadata.source"},
+	"Phone":    {"Label": "type", "Value": "value", "Source": "metadata.source"},
+	"Event":    {"Label": "type", "Value": "date", "Source": "metadata.source"},
+	"Relation": {"Label": "type", "Value": "person", "Source": "metadata.source"},
+	"Website":  {"Label": "type", "Value": "value", "Source": "metadata.source"},
+	"Address": {
+		"Label": "type", "Formatted": "formattedValue", "Street": "streetAddress",
+		"Extended Address": "extendedAddress", "City": "city", "Region": "region",
+		"Postal Code": "postalCode", "Country": "country", "PO Box": "poBox",
+		"Source": "metadata.source",
+	},
+	"Custom Field": {"Label": "key", "Value": "value", "Source": "metadata.source"},
+}
+
+// contactField returns the contact field the column is read into, or "" if
+// it is not read.
+func (c csvColumn) contactField() string {
+	if c.header == "" {
+		return ""
+	}
+	if c.field == "" {
+		return csvFields[c.header]
+	}
+	return fmt.Sprintf("%s.%s #%d", csvNumberedFields[c.field], csvAttributes[c.field][c.attribute], c.number)
+}
+
+// csvHeaderEnglish maps the localized headers and header tokens of every CSV
+// locale back to English
+var csvHeaderEnglish = func() map[string]string {
+	english := make(map[string]string)
+	for _, table := range csvHeaderTranslations {
+		for token, translated := range table {
+			english[translated] = token
+		}
+	}
+	return english
+}()
+
+// parseCSVHeader returns the column a header names, or a column with an
+// empty header if it is not one ReadCSV understands.
+func parseCSVHeader(header string) csvColumn {
+	header = strings.TrimSpace(header)
+	if alias, ok := csvHeaderAliases[header]; ok {
+		header = alias
+	}
+	if english, ok := csvHeaderEnglish[header]; ok {
+		header = english
+	}
+
+	switch header {
+	case colNamePrefix, colFirstName, colMiddleName, colLastName, colNameSuffix,
+		colPhoneticFirstName, colPhoneticMiddleName, colPhoneticLastName,
+		colNickname, colFileAs, colBirthday, colNotes, colLabels,
+		colOrgName, colOrgTitle, colOrgDepartment, colFullName,
+		colNameSource, colBirthdaySource, colOrgSource:
+		return csvColumn{header: header}
+	}
+
+	m := numberedHeaderPattern.FindStringSubmatch(header)
+	if m == nil {
+		return csvColumn{}
+	}
+	token := func(s string) string {
+		if english, ok := csvHeaderEnglish[s]; ok {
+			s = english
+		}
+		if alias, ok := csvTokenAliases[s]; ok {
+			s = alias
+		}
+		return s
+	}
+	number, err := strconv.Atoi(m[2])
+	if err != nil {
+		return csvColumn{}
+	}
+	column := csvColumn{field: token(m[1]), number: number, attribute: token(m[3])}
+	if _, ok := csvAttributes[column.field][column.attribute]; !ok {
+		return csvColumn{}
+	}
+	column.header = fmt.Sprintf("%s %d - %s", column.field, column.number, column.attribute)
+	return column
+}
+
+// csvRecordToContact converts a CSV row to a contact and the names of its
+// labels. Returns a nil contact for rows without any values.
+func csvRecordToContact(columns []csvColumn, record []string) (*people.Person, []string, error) {
+	values := make(map[string]string)
+	// numbered maps field and number to the attributes of that value
+	numbered := make(map[string]map[int]map[string]string)
+	for i, value := range record {
+		value = strings.TrimSpace(value)
+		if i >= len(columns) || columns[i].header == "" || value == "" {
+			continue
+		}
+		column := columns[i]
+		if column.field == "" {
+			values[column.header] = value
+			continue
+		}
+		if numbered[column.field] == nil {
+			numbered[column.field] = make(map[int]map[string]string)
+		}
+		if numbered[column.field][column.number] == nil {
+			numbered[column.field][column.number] = make(map[string]string)
+		}
+		numbered[column.field][column.number][column.attribute] = value
+	}
+	if len(values) == 0 && len(numbered) == 0 {
+		return nil, nil, nil
+	}
+
+	contact := &people.Person{}
+
+	name := &people.Name{
+		HonorificPrefix:    values[colNamePrefix],
+		GivenName:          values[colFirstName],
+		MiddleName:         values[colMiddleName],
+		FamilyName:         values[colLastName],
+		HonorificSuffix:    values[colNameSuffix],
+		PhoneticGivenName:  values[colPhoneticFirstName],
+		PhoneticMiddleName: values[colPhoneticMiddleName],
+		PhoneticFamilyName: values[colPhoneticLastName],
+	}
+	if name.HonorificPrefix+name.GivenName+name.MiddleName+name.FamilyName+name.HonorificSuffix+
+		name.PhoneticGivenName+name.PhoneticMiddleName+name.PhoneticFamilyName != "" {
+		name.Metadata = csvSource(values[colNameSource])
+		contact.Names = []*people.Name{name}
+	} else if full := values[colFullName]; full != "" {
+		contact.Names = []*people.Name{{DisplayName: full, UnstructuredName: full}}
+	}
+
+	if nickname := values[colNickname]; nickname != "" {
+		contact.Nicknames = []*people.Nickname{{Value: nickname}}
+	}
+	if fileAs := values[colFileAs]; fileAs != "" {
+		contact.FileAses = []*people.FileAs{{Value: fileAs}}
+	}
+	if birthday := values[colBirthday]; birthday != "" {
+		date, err := parseCSVDate(birthday)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid birthday: %w", err)
+		}
+		contact.Birthdays = []*people.Birthday{{Date: date, Metadata: csvSource(values[colBirthdaySource])}}
+	}
+	if values[colOrgName] != "" || values[colOrgTitle] != "" || values[colOrgDepartment] != "" {
+		contact.Organizations = []*people.Organization{{
+			Name:       values[colOrgName],
+			Title:      values[colOrgTitle],
+			Department: values[colOrgDepartment],
+			Metadata:   csvSource(values[colOrgSource]),
+		}}
+	}
+	if notes := values[colNotes]; notes != "" {
+		contact.Biographies = []*people.Biography{{Value: notes, ContentType: "TEXT_PLAIN"}}
+	}
+
+	for _, value := range csvValues(numbered["Email"]) {
+		contact.EmailAddresses = append(contact.EmailAddresses, &people.EmailAddress{Type: csvType(value["Label"]), Value: value["Value"], Metadata: csvSource(value["Source"])})
+	}
+	for _, value := range csvValues(numbered["Phone"]) {
+		contact.PhoneNumbers = append(contact.PhoneNumbers, &people.PhoneNumber{Type: csvType(value["Label"]), Value: value["Value"], Metadata: csvSource(value["Source"])})
+	}
+	for _, value := range csvValues(numbered["Address"]) {
+		contact.Addresses = append(contact.Addresses, &people.Address{
+			Type:            csvType(value["Label"]),
+			FormattedValue:  value["Formatted"],
+			StreetAddress:   value["Street"],
+			ExtendedAddress: value["Extended Address"],
+			City:            value["City"],
+			Region:          value["Region"],
+			PostalCode:      value["Postal Code"],
+			Country:         value["Country"],
+			PoBox:           value["PO Box"],
+			Metadata:        csvSource(value["Source"]),
+		})
+	}
+	for _, value := range csvValues(numbered["Event"]) {
+		date, err := parseCSVDate(value["Value"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid event date: %w", err)
+		}
+		contact.Events = append(contact.Events, &people.Event{Type: csvType(value["Label"]), Date: date, Metadata: csvSource(value["Source"])})
+	}
+	for _, value := range csvValues(numbered["Relation"]) {
+		contact.Relations = append(contact.Relations, &people.Relation{Type: csvType(value["Label"]), Person: value["Value"], Metadata: csvSource(value["Source"])})
+	}
+	for _, value := range csvValues(numbered["Website"]) {
+		contact.Urls = append(contact.Urls, &people.Url{Type: csvType(value["Label"]), Value: value["Value"], Metadata: csvSource(value["Source"])})
+	}
+	for _, value := range csvValues(numbered["Custom Field"]) {
+		contact.UserDefined = append(contact.UserDefined, &people.UserDefined{Key: value["Label"], Value: value["Value"], Metadata: csvSource(value["Source"])})
+	}
+
+	var labels []string
+	for _, label := range strings.Split(values[colLabels], labelSeparator) {
+		label = strings.TrimSpace(label)
+		// Google's export marks system groups such as myContacts with "* "
+		if label == "" || strings.HasPrefix(label, "* ") {
+			continue
+		}
+		labels = append(labels, label)
+	}
+
+	return contact, labels, nil
+}
+
+// csvSource returns the metadata of a field whose source column says
+// where its value came from, or nil if the column is empty or missing.
+func csvSource(source string) *people.FieldMetadata {
+	if source == "" {
+		return nil
+	}
+	return &people.FieldMetadata{Source: &people.Source{Type: strings.ToUpper(source)}}
+}
+
+// csvValues returns the values of a numbered field in column order. Google's
+// export puts several values with the same label into one column, separated
+// by " ::: "; they are split into values of their own.
+func csvValues(byNumber map[int]map[string]string) []map[string]string {
+	numbers := make([]int, 0, len(byNumber))
+	for number := range byNumber {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	var values []map[string]string
+	for _, number := range numbers {
+		attributes := byNumber[number]
+		count := 1
+		for attribute, value := range attributes {
+			if attribute != "Label" {
+				count = max(count, len(strings.Split(value, labelSeparator)))
+			}
+		}
+		for i := 0; i < count; i++ {
+			value := map[string]string{"Label": attributes["Label"]}
+			empty := true
+			for attribute, joined := range attributes {
+				if attribute == "Label" {
+					continue
+				}
+				if parts := strings.Split(joined, labelSeparator); i < len(parts) {
+					value[attribute] = strings.TrimSpace(parts[i])
+					empty = empty && (value[attribute] == "" || attribute == "Source")
+				}
+			}
+			if !empty {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// csvLocalizedTypes maps the lower-cased type labels of every CSV locale to
+// People API types
+var csvLocalizedTypes = func() map[string]string {
+	types := make(map[string]string)
+	for _, table := range csvTypeTranslations {
+		for label, apiType := range table {
+			types[strings.ToLower(label)] = apiType
+		}
+	}
+	return types
+}()
+
+// csvType returns the People API type a CSV label stands for, in English or
+// in one of the CSV locales, or the label itself if it is a custom label.
+// Google's export marks the primary value's label with "* ".
+func csvType(label string) string {
+	label = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(label), "* "))
+	key := strings.ToLower(strings.ReplaceAll(label, " ", ""))
+	for _, apiType := range csvTypes {
+		if strings.ToLower(apiType) == key {
+			return apiType
+		}
+	}
+	if apiType, ok := csvLocalizedTypes[strings.ToLower(label)]; ok {
+		return apiType
+	}
+	return label
+}
+
+// parseCSVDate parses a date as WriteCSV writes it, "2006-01-02" or
+// "--01-02" for dates without a year.
+func parseCSVDate(value string) (*people.Date, error) {
+	var year, month, day int64
+	parts := strings.Split(strings.TrimPrefix(value, "--"), "-")
+	var err error
+	switch {
+	case strings.HasPrefix(value, "--") && len(parts) == 2:
+		month, err = parseDatePart(parts[0])
+		if err == nil {
+			day, err = parseDatePart(parts[1])
+		}
+	case len(parts) == 3:
+		year, err = parseDatePart(parts[0])
+		if err == nil {
+			month, err = parseDatePart(parts[1])
+		}
+		if err == nil {
+			day, err = parseDatePart(parts[2])
+		}
+	default:
+		err = errors.New("expected YYYY-MM-DD or --MM-DD")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", value, err)
+	}
+	return &people.Date{Year: year, Month: month, Day: day}, nil
+}
+
+// parseDatePart parses the year, month or day of a date
+func parseDatePart(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/people/v1"
+)
+
+func TestReadCSVGoogleExport(t *testing.T) {
+	input := "\ufeffGiven Name,Family Name,Group Membership,E-mail 1 - Type,E-mail 1 - Value,Phone 1 - Type,Phone 1 - Value,Organization 1 - Name,Birthday\n" +
+		"Ada,Lovelace,* myContacts ::: Friends,* Home,ada@example.com,Mobile,+44 20 7946 0000,Analytical Engines,1815-12-10\n" +
+		"Charles,Babbage,Friends ::: Work,,,Work,+44 20 7946 0001,,\n"
+
+	backup, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	if len(backup.Contacts) != 2 {
+		t.Fatalf("got %d contacts, want 2", len(backup.Contacts))
+	}
+
+	ada := backup.Contacts[0]
+	if got := ada.Names[0].GivenName + " " + ada.Names[0].FamilyName; got != "Ada Lovelace" {
+		t.Errorf("name = %q", got)
+	}
+	if len(ada.EmailAddresses) != 1 || ada.EmailAddresses[0].Value != "ada@example.com" || ada.EmailAddresses[0].Type != "home" {
+		t.Errorf("emails = %+v", ada.EmailAddresses)
+	}
+	if len(ada.PhoneNumbers) != 1 || ada.PhoneNumbers[0].Type != "mobile" {
+		t.Errorf("phones = %+v", ada.PhoneNumbers)
+	}
+	if len(ada.Organizations) != 1 || ada.Organizations[0].Name != "Analytical Engines" {
+		t.Errorf("organizations = %+v", ada.Organizations)
+	}
+	if len(ada.Birthdays) != 1 || !reflect.DeepEqual(ada.Birthdays[0].Date, &people.Date{Year: 1815, Month: 12, Day: 10}) {
+		t.Errorf("birthdays = %+v", ada.Birthdays)
+	}
+
+	var labels []string
+	for _, group := range backup.GetUserGroups() {
+		labels = append(labels, group.Name)
+	}
+	if !reflect.DeepEqual(labels, []string{"Friends", "Work"}) {
+		t.Errorf("user groups = %v, want [Friends Work]", labels)
+	}
+}
+
+func TestReadCSVRoundTrip(t *testing.T) {
+	original := NewBackupFile()
+	original.AddContact(&people.Person{
+		ResourceName:   "people/c1",
+		Names:          []*people.Name{{GivenName: "Grace", FamilyName: "Hopper"}},
+		EmailAddresses: []*people.EmailAddress{{Value: "grace@example.com", Type: "work"}, {Value: "g@example.org", Type: "home"}},
+		Biographies:    []*people.Biography{{Value: "Rear admiral", ContentType: "TEXT_PLAIN"}},
+	})
+
+	for _, locale := range append([]string{""}, CSVLocales()...) {
+		var buf bytes.Buffer
+		if err := original.WriteCSV(&buf, CSVOptions{Locale: locale}); err != nil {
+			t.Fatalf("WriteCSV(%q): %v", locale, err)
+		}
+		backup, mapping, err := ReadCSVMapped(&buf, nil)
+		if err != nil {
+			t.Fatalf("ReadCSVMapped(%q): %v", locale, err)
+		}
+		if dropped := mapping.Dropped(); len(dropped) > 0 {
+			t.Errorf("locale %q: dropped columns %+v", locale, dropped)
+		}
+		if len(backup.Contacts) != 1 {
+			t.Fatalf("locale %q: got %d contacts, want 1", locale, len(backup.Contacts))
+		}
+		contact := backup.Contacts[0]
+		if contact.Names[0].GivenName != "Grace" || len(contact.EmailAddresses) != 2 || contact.EmailAddresses[1].Value != "g@example.org" {
+			t.Errorf("locale %q: contact = %+v", locale, contact)
+		}
+		if len(contact.Biographies) != 1 || contact.Biographies[0].Value != "Rear admiral" {
+			t.Errorf("locale %q: notes = %+v", locale, contact.Biographies)
+		}
+	}
+}
+
+func TestReadCSVMapped(t *testing.T) {
+	input := "Vorname,E-Mail privat,Lieblingsfarbe,Intern,Leer\n" +
+		"Ada,ada@example.com,blue,x,\n" +
+		"Charles,,green,y,\n"
+	overrides := CSVMap{
+		"E-Mail privat": "Email 1 - Value",
+		"Intern":        "",
+		"Fax":           "Phone 2 - Value",
+	}
+
+	backup, mapping, err := ReadCSVMapped(strings.NewReader(input), overrides)
+	if err != nil {
+		t.Fatalf("ReadCSVMapped: %v", err)
+	}
+	if len(backup.Contacts) != 2 {
+		t.Fatalf("got %d contacts, want 2", len(backup.Contacts))
+	}
+	if emails := backup.Contacts[0].EmailAddresses; len(emails) != 1 || emails[0].Value != "ada@example.com" {
+		t.Errorf("emails = %+v", emails)
+	}
+
+	want := []CSVColumnMapping{
+		{Header: "Vorname", Field: "names.givenName", Values: 2},
+		{Header: "E-Mail privat", Field: "emailAddresses.value #1", Override: true, Values: 1},
+		{Header: "Lieblingsfarbe", Values: 2},
+		{Header: "Intern", Override: true, Values: 2},
+		{Header: "Leer"},
+	}
+	if !reflect.DeepEqual(mapping.Columns, want) {
+		t.Errorf("columns = %+v, want %+v", mapping.Columns, want)
+	}
+	if !reflect.DeepEqual(mapping.Dropped(), want[2:3]) {
+		t.Errorf("Dropped() = %+v, want only Lieblingsfarbe", mapping.Dropped())
+	}
+	if !reflect.DeepEqual(mapping.UnusedOverrides, []string{"Fax"}) {
+		t.Errorf("UnusedOverrides = %v, want [Fax]", mapping.UnusedOverrides)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		overrides CSVMap
+		wantErr   string
+	}{
+		{"empty", "", nil, "missing header row"},
+		{"no contact columns", "Foo,Bar\n1,2\n", nil, "no contact columns"},
+		{"invalid override", "Mail\nada@example.com\n", CSVMap{"Mail": "Email 1 - Colour"}, "not a column that can be imported"},
+		{"invalid birthday", "First Name,Birthday\nAda,tomorrow\n", nil, "row 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadCSVMapped(strings.NewReader(tt.input), tt.overrides)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadCSVMapped: %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadCSVMap(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	m, err := LoadCSVMap(write("valid.json", `{"E-Mail privat": "Email 1 - Value", "Intern": "", "Firma": "Organization Name"}`))
+	if err != nil {
+		t.Fatalf("LoadCSVMap: %v", err)
+	}
+	if want := (CSVMap{"E-Mail privat": "Email 1 - Value", "Intern": "", "Firma": "Organization Name"}); !reflect.DeepEqual(m, want) {
+		t.Errorf("map = %v, want %v", m, want)
+	}
+
+	if _, err := LoadCSVMap(write("target.json", `{"Mail": "Electronic Mail"}`)); err == nil {
+		t.Error("LoadCSVMap accepted an unknown target")
+	}
+	if _, err := LoadCSVMap(write("syntax.json", `["Mail"]`)); err == nil {
+		t.Error("LoadCSVMap accepted a JSON array")
+	}
+	if _, err := LoadCSVMap(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadCSVMap accepted a missing file")
+	}
+}
@@ -0,0 +1,275 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// csvHeaderTranslations maps English CSV header tokens to the names Google's
+// web importer expects for accounts in other languages. Numbered headers such
+// as "Email 1 - Label" are translated token by token.
+var csvHeaderTranslations = map[string]map[string]string{
+	"fr": {
+		colNamePrefix:         "Préfixe du nom",
+		colFirstName:          "Prénom",
+		colMiddleName:         "Deuxième prénom",
+		colLastName:           "Nom de famille",
+		colNameSuffix:         "Suffixe du nom",
+		colPhoneticFirstName:  "Prénom phonétique",
+		colPhoneticMiddleName: "Deuxième prénom phonétique",
+		colPhoneticLastName:   "Nom de famille phonétique",
+		colNickname:           "Surnom",
+		colFileAs:             "Classer sous",
+		colBirthday:           "Anniversaire",
+		colNotes:              "Notes",
+		colLabels:             "Libellés",
+		colOrgName:            "Nom de l'organisation",
+		colOrgTitle:           "Titre dans l'organisation",
+		colOrgDepartment:      "Service de l'organisation",
+		"Email":               "E-mail",
+		"Phone":               "Téléphone",
+		"Address":             "Adresse",
+		"Event":               "Événement",
+		"Relation":            "Relation",
+		"Website":             "Site Web",
+		"Custom Field":        "Champ personnalisé",
+		"Label":               "Libellé",
+		"Value":               "Valeur",
+		"Street":              "Rue",
+		"Extended Address":    "Adresse complémentaire",
+		"City":                "Ville",
+		"Region":              "Région",
+		"Postal Code":         "Code postal",
+		"Country":             "Pays",
+		"PO Box":              "Boîte postale",
+		"Source":              "Source",
+		colNameSource:         "Source du nom",
+		colBirthdaySource:     "Source de l'anniversaire",
+		colOrgSource:          "Source de l'organisation",
+	},
+	"de": {
+		colNamePrefix:         "Namenspräfix",
+		colFirstName:          "Vorname",
+		colMiddleName:         "Zweiter Vorname",
+		colLastName:           "Nachname",
+		colNameSuffix:         "Namenssuffix",
+		colPhoneticFirstName:  "Vorname (phonetisch)",
+		colPhoneticMiddleName: "Zweiter Vorname (phonetisch)",
+		colPhoneticLastName:   "Nachname (phonetisch)",
+		colNickname:           "Spitzname",
+		colFileAs:             "Speichern unter",
+		colBirthday:           "Geburtstag",
+		colNotes:              "Notizen",
+		colLabels:             "Labels",
+		colOrgName:            "Organisation",
+		colOrgTitle:           "Position",
+		colOrgDepartment:      "Abteilung",
+		"Email":               "E-Mail",
+		"Phone":               "Telefon",
+		"Address":             "Adresse",
+		"Event":               "Ereignis",
+		"Relation":            "Beziehung",
+		"Website":             "Website",
+		"Custom Field":        "Benutzerdefiniertes Feld",
+		"Label":               "Label",
+		"Value":               "Wert",
+		"Street":              "Straße",
+		"Extended Address":    "Adresszusatz",
+		"City":                "Stadt",
+		"Region":              "Bundesland",
+		"Postal Code":         "Postleitzahl",
+		"Country":             "Land",
+		"PO Box":              "Postfach",
+		"Source":              "Quelle",
+		colNameSource:         "Quelle des Namens",
+		colBirthdaySource:     "Quelle des Geburtstags",
+		colOrgSource:          "Quelle der Organisation",
+	},
+	"es": {
+		colNamePrefix:         "Prefijo del nombre",
+		colFirstName:          "Nombre",
+		colMiddleName:         "Segundo nombre",
+		colLastName:           "Apellidos",
+		colNameSuffix:         "Sufijo del nombre",
+		colPhoneticFirstName:  "Nombre fonético",
+		colPhoneticMiddleName: "Segundo nombre fonético",
+		colPhoneticLastName:   "Apellidos fonéticos",
+		colNickname:           "Alias",
+		colFileAs:             "Archivar como",
+		colBirthday:           "Cumpleaños",
+		colNotes:              "Notas",
+		colLabels:             "Etiquetas",
+		colOrgName:            "Nombre de la organización",
+		colOrgTitle:           "Cargo en la organización",
+		colOrgDepartment:      "Departamento de la organización",
+		"Email":               "Correo electrónico",
+		"Phone":               "Teléfono",
+		"Address":             "Dirección",
+		"Event":               "Evento",
+		"Relation":            "Relación",
+		"Website":             "Sitio web",
+		"Custom Field":        "Campo personalizado",
+		"Label":               "Etiqueta",
+		"Value":               "Valor",
+		"Street":              "Calle",
+		"Extended Address":    "Dirección ampliada",
+		"City":                "Ciudad",
+		"Region":              "Región",
+		"Postal Code":         "Código postal",
+		"Country":             "País",
+		"PO Box":              "Apartado postal",
+		"Source":              "Origen",
+		colNameSource:         "Origen del nombre",
+		colBirthdaySource:     "Origen del cumpleaños",
+		colOrgSource:          "Origen de la organización",
+	},
+}
+
+// csvTypeTranslations maps the type labels Google Contacts writes in CSV
+// exports of accounts in other languages, such as "Domicile" for home, to
+// People API types. Labels are matched ignoring case.
+var csvTypeTranslations = map[string]map[string]string{
+	"fr": {
+		"Domicile":                "home",
+		"Travail":                 "work",
+		"Mobile":                  "mobile",
+		"Principal":               "main",
+		"Autre":                   "other",
+		"Fax domicile":            "homeFax",
+		"Fax travail":             "workFax",
+		"Autre fax":               "otherFax",
+		"Bipeur":                  "pager",
+		"Mobile professionnel":    "workMobile",
+		"Bipeur professionnel":    "workPager",
+		"Anniversaire de mariage": "anniversary",
+		"Conjoint":                "spouse",
+		"Enfant":                  "child",
+		"Mère":                    "mother",
+		"Père":                    "father",
+		"Parent":                  "parent",
+		"Frère":                   "brother",
+		"Sœur":                    "sister",
+		"Ami":                     "friend",
+		"Membre de la famille":    "relative",
+		"Partenaire domestique":   "domesticPartner",
+		"Responsable":             "manager",
+		"Assistant":               "assistant",
+		"Recommandé par":          "referredBy",
+		"Partenaire":              "partner",
+		"Page d'accueil":          "homePage",
+		"Profil":                  "profile",
+	},
+	"de": {
+		"Privat":             "home",
+		"Geschäftlich":       "work",
+		"Mobil":              "mobile",
+		"Hauptnummer":        "main",
+		"Sonstige":           "other",
+		"Fax privat":         "homeFax",
+		"Fax geschäftlich":   "workFax",
+		"Sonstiges Fax":      "otherFax",
+		"Pager":              "pager",
+		"Mobil geschäftlich": "workMobile",
+		"Pager geschäftlich": "workPager",
+		"Jahrestag":          "anniversary",
+		"Ehepartner":         "spouse",
+		"Kind":               "child",
+		"Mutter":             "mother",
+		"Vater":              "father",
+		"Elternteil":         "parent",
+		"Bruder":             "brother",
+		"Schwester":          "sister",
+		"Freund":             "friend",
+		"Verwandter":         "relative",
+		"Lebenspartner":      "domesticPartner",
+		"Vorgesetzter":       "manager",
+		"Assistent":          "assistant",
+		"Empfohlen von":      "referredBy",
+		"Partner":            "partner",
+		"Startseite":         "homePage",
+		"Profil":             "profile",
+	},
+	"es": {
+		"Casa":                      "home",
+		"Trabajo":                   "work",
+		"Móvil":                     "mobile",
+		"Principal":                 "main",
+		"Otro":                      "other",
+		"Fax de casa":               "homeFax",
+		"Fax del trabajo":           "workFax",
+		"Otro fax":                  "otherFax",
+		"Buscapersonas":             "pager",
+		"Móvil del trabajo":         "workMobile",
+		"Buscapersonas del trabajo": "workPager",
+		"Aniversario":               "anniversary",
+		"Cónyuge":                   "spouse",
+		"Hijo":                      "child",
+		"Madre":                     "mother",
+		"Padre":                     "father",
+		"Padre o madre":             "parent",
+		"Hermano":                   "brother",
+		"Hermana":                   "sister",
+		"Amigo":                     "friend",
+		"Familiar":                  "relative",
+		"Pareja de hecho":           "domesticPartner",
+		"Responsable":               "manager",
+		"Asistente":                 "assistant",
+		"Recomendado por":           "referredBy",
+		"Pareja":                    "partner",
+		"Página principal":          "homePage",
+		"Perfil":                    "profile",
+	},
+}
+
+// numberedHeaderPattern matches headers like "Address 2 - Postal Code"
+var numberedHeaderPattern = regexp.MustCompile(`^(.+) (\d+) - (.+)$`)
+
+// CSVLocales returns the supported CSV header locales, including "en".
+func CSVLocales() []string {
+	locales := []string{"en"}
+	for locale := range csvHeaderTranslations {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// IsCSVLocale reports whether locale is a supported CSV header locale.
+func IsCSVLocale(locale string) bool {
+	locale = strings.ToLower(locale)
+	_, ok := csvHeaderTranslations[locale]
+	return ok || locale == "" || locale == "en"
+}
+
+// localizeCSVHeaders translates English headers into the given locale.
+func localizeCSVHeaders(headers []string, locale string) ([]string, error) {
+	locale = strings.ToLower(locale)
+	if locale == "" || locale == "en" {
+		return headers, nil
+	}
+
+	table, ok := csvHeaderTranslations[locale]
+	if !ok {
+		return nil, fmt.Errorf("unsupported CSV locale %q (supported: %s)", locale, strings.Join(CSVLocales(), ", "))
+	}
+
+	translate := func(token string) string {
+		if translated, ok := table[token]; ok {
+			return translated
+		}
+		return token
+	}
+
+	localized := make([]string, len(headers))
+	for i, header := range headers {
+		if m := numberedHeaderPattern.FindStringSubmatch(header); m != nil {
+			localized[i] = fmt.Sprintf("%s %s - %s", translate(m[1]), m[2], translate(m[3]))
+		} else {
+			localized[i] = translate(header)
+		}
+	}
+
+	return localized, nil
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// templateColumn is one column of a CSV import template
+type templateColumn struct {
+	header string
+	value  func(contact *people.Person) string
+}
+
+// blankColumn is a column the importer expects but which has no Google
+// equivalent, such as a CRM record owner; the importer fills in its default
+func blankColumn(header string) templateColumn {
+	return templateColumn{header, func(*people.Person) string { return "" }}
+}
+
+// csvTemplates maps template names to the columns of the contact-import CSV
+// of a CRM or a phone vendor's desktop suite
+var csvTemplates = map[string][]templateColumn{
+	// HubSpot's default contact properties, as named in its import tool
+	"hubspot": {
+		{"First Name", givenName},
+		{"Last Name", familyName},
+		{"Email", primaryEmail},
+		{"Phone Number", func(p *people.Person) string { return phoneNumber(p, false) }},
+		{"Mobile Phone Number", func(p *people.Person) string { return phoneNumber(p, true) }},
+		{"Company Name", func(p *people.Person) string { return primaryOrganization(p).Name }},
+		{"Job Title", func(p *people.Person) string { return primaryOrganization(p).Title }},
+		{"Street Address", func(p *people.Person) string { return primaryAddress(p).StreetAddress }},
+		{"City", func(p *people.Person) string { return primaryAddress(p).City }},
+		{"State/Region", func(p *people.Person) string { return primaryAddress(p).Region }},
+		{"Postal Code", func(p *people.Person) string { return primaryAddress(p).PostalCode }},
+		{"Country/Region", func(p *people.Person) string { return primaryAddress(p).Country }},
+		{"Website URL", primaryURL},
+		blankColumn("Contact owner"),
+	},
+	// Salesforce's Data Import Wizard contact template. Last Name is
+	// required there, so contacts without one use their display name.
+	"salesforce": {
+		{"First Name", givenName},
+		{"Last Name", func(p *people.Person) string {
+			if name := familyName(p); name != "" {
+				return name
+			}
+			return DisplayName(p)
+		}},
+		{"Title", func(p *people.Person) string { return primaryOrganization(p).Title }},
+		{"Account Name", func(p *people.Person) string { return primaryOrganization(p).Name }},
+		{"Department", func(p *people.Person) string { return primaryOrganization(p).Department }},
+		{"Email", primaryEmail},
+		{"Phone", func(p *people.Person) string { return phoneNumber(p, false) }},
+		{"Mobile", func(p *people.Person) string { return phoneNumber(p, true) }},
+		{"Mailing Street", func(p *people.Person) string { return primaryAddress(p).StreetAddress }},
+		{"Mailing City", func(p *people.Person) string { return primaryAddress(p).City }},
+		{"Mailing State/Province", func(p *people.Person) string { return primaryAddress(p).Region }},
+		{"Mailing Zip/Postal Code", func(p *people.Person) string { return primaryAddress(p).PostalCode }},
+		{"Mailing Country", func(p *people.Person) string { return primaryAddress(p).Country }},
+		{"Birthdate", birthdate},
+		{"Description", func(p *people.Person) string {
+			if len(p.Biographies) == 0 {
+				return ""
+			}
+			return NotesText(p.Biographies[0], true)
+		}},
+		blankColumn("Contact Owner"),
+	},
+	// Nokia PC Suite / Nokia Suite contacts CSV, for Series 30/40 phones
+	"nokia": {
+		{"Title", func(p *people.Person) string { return firstName(p).HonorificPrefix }},
+		{"First name", givenName},
+		{"Middle name", func(p *people.Person) string { return firstName(p).MiddleName }},
+		{"Last name", familyName},
+		{"Suffix", func(p *people.Person) string { return firstName(p).HonorificSuffix }},
+		{"Mobile", func(p *people.Person) string { return phoneOfType(p, "mobile") }},
+		{"Home phone", func(p *people.Person) string { return phoneOfType(p, "home") }},
+		{"Business phone", func(p *people.Person) string { return phoneOfType(p, "work") }},
+		{"General phone", func(p *people.Person) string { return phoneOfType(p, "main", "other", "") }},
+		{"Fax", func(p *people.Person) string { return phoneOfType(p, "homeFax", "workFax", "otherFax") }},
+		{"E-mail", primaryEmail},
+		{"Company", func(p *people.Person) string { return primaryOrganization(p).Name }},
+		{"Job title", func(p *people.Person) string { return primaryOrganization(p).Title }},
+		{"Street", func(p *people.Person) string { return primaryAddress(p).StreetAddress }},
+		{"City", func(p *people.Person) string { return primaryAddress(p).City }},
+		{"Postal code", func(p *people.Person) string { return primaryAddress(p).PostalCode }},
+		{"Country", func(p *people.Person) string { return primaryAddress(p).Country }},
+		{"Birthday", birthdate},
+	},
+	// Samsung Kies / Samsung PC Studio contacts CSV, for older Samsung phones
+	"samsung": {
+		{"Name", DisplayName},
+		{"Mobile", func(p *people.Person) string { return phoneOfType(p, "mobile") }},
+		{"Home", func(p *people.Person) string { return phoneOfType(p, "home") }},
+		{"Office", func(p *people.Person) string { return phoneOfType(p, "work") }},
+		{"Other", func(p *people.Person) string { return phoneOfType(p, "main", "other", "") }},
+		{"Fax", func(p *people.Person) string { return phoneOfType(p, "homeFax", "workFax", "otherFax") }},
+		{"Email", primaryEmail},
+		{"Company", func(p *people.Person) string { return primaryOrganization(p).Name }},
+		{"Birthday", birthdate},
+	},
+	// Name and phone number only, for SIM managers and basic devices
+	"minimal": {
+		{"Name", DisplayName},
+		{"Phone", primaryPhone},
+	},
+}
+
+// CSVTemplates returns the names of the supported CSV import templates.
+func CSVTemplates() []string {
+	names := make([]string, 0, len(csvTemplates))
+	for name := range csvTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TemplateCSVOptions controls how a backup is written as a template CSV.
+type TemplateCSVOptions struct {
+	// Charset is the output character set (see Charsets). Empty means UTF-8.
+	Charset string
+}
+
+// WriteTemplateCSV writes the backup as a CSV matching a contact-import
+// template (see CSVTemplates). Each contact is one row holding its primary
+// email, phone, organization and address.
+func (b *BackupFile) WriteTemplateCSV(w io.Writer, template string, opts TemplateCSVOptions) error {
+	columns, ok := csvTemplates[template]
+	if !ok {
+		return fmt.Errorf("unknown CSV template %q: must be one of %s", template, strings.Join(CSVTemplates(), ", "))
+	}
+
+	w, err := newCharsetWriter(w, opts.Charset)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.header
+	}
+	if err := writer.Write(headers); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, contact := range b.Contacts {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = column.value(contact)
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	return nil
+}
+
+// givenName returns the contact's first name
+func givenName(p *people.Person) string {
+	if len(p.Names) == 0 {
+		return ""
+	}
+	return p.Names[0].GivenName
+}
+
+// familyName returns the contact's last name
+func familyName(p *people.Person) string {
+	if len(p.Names) == 0 {
+		return ""
+	}
+	return p.Names[0].FamilyName
+}
+
+// firstName returns the contact's first name entry, or an empty name
+func firstName(p *people.Person) *people.Name {
+	if len(p.Names) == 0 {
+		return &people.Name{}
+	}
+	return p.Names[0]
+}
+
+// phoneOfType returns the first phone number with one of the given API types
+// (compared case-insensitively; "" matches numbers without a type)
+func phoneOfType(p *people.Person, types ...string) string {
+	for _, phone := range p.PhoneNumbers {
+		for _, t := range types {
+			if strings.EqualFold(phone.Type, t) {
+				return phone.Value
+			}
+		}
+	}
+	return ""
+}
+
+// isPrimary reports whether field metadata marks a value as primary
+func isPrimary(metadata *people.FieldMetadata) bool {
+	return metadata != nil && metadata.Primary
+}
+
+// primaryEmail returns the primary email address, or the first one
+func primaryEmail(p *people.Person) string {
+	for _, email := range p.EmailAddresses {
+		if isPrimary(email.Metadata) {
+			return email.Value
+		}
+	}
+	if len(p.EmailAddresses) > 0 {
+		return p.EmailAddresses[0].Value
+	}
+	return ""
+}
+
+// phoneNumber returns the first mobile number, or with mobile false the
+// primary (or first) number that is not a mobile number
+func phoneNumber(p *people.Person, mobile bool) string {
+	var first string
+	for _, phone := range p.PhoneNumbers {
+		if strings.EqualFold(phone.Type, "mobile") != mobile {
+			continue
+		}
+		if mobile || isPrimary(phone.Metadata) {
+			return phone.Value
+		}
+		if first == "" {
+			first = phone.Value
+		}
+	}
+	return first
+}
+
+// primaryOrganization returns the primary organization, or the first one,
+// or an empty organization
+func primaryOrganization(p *people.Person) *people.Organization {
+	for _, org := range p.Organizations {
+		if isPrimary(org.Metadata) {
+			return org
+		}
+	}
+	if len(p.Organizations) > 0 {
+		return p.Organizations[0]
+	}
+	return &people.Organization{}
+}
+
+// primaryAddress returns the primary address, or the first one, or an
+// empty address
+func primaryAddress(p *people.Person) *people.Address {
+	for _, addr := range p.Addresses {
+		if isPrimary(addr.Metadata) {
+			return addr
+		}
+	}
+	if len(p.Addresses) > 0 {
+		return p.Addresses[0]
+	}
+	return &people.Address{}
+}
+
+// primaryURL returns the primary website, or the first one
+func primaryURL(p *people.Person) string {
+	for _, url := range p.Urls {
+		if isPrimary(url.Metadata) {
+			return url.Value
+		}
+	}
+	if len(p.Urls) > 0 {
+		return p.Urls[0].Value
+	}
+	return ""
+}
+
+// birthdate returns the birthday as YYYY-MM-DD, or nothing if it is
+// missing or has no year
+func birthdate(p *people.Person) string {
+	if len(p.Birthdays) == 0 || p.Birthdays[0].Date == nil || p.Birthdays[0].Date.Year == 0 {
+		return ""
+	}
+	date := p.Birthdays[0].Date
+	return fmt.Sprintf("%04d-%02d-%02d", date.Year, date.Month, date.Day)
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/people/v1"
+)
+
+// Files of a backup directory, as written by SaveToDir
+const (
+	dirManifestFile       = "manifest.json"
+	dirGroupsFile         = "groups.json"
+	dirContactsDir        = "contacts"
+	dirOtherContactsFile  = "other_contacts.json"
+	dirPhotosFile         = "photos.json"
+	dirFallbackPhotosFile = "fallback_photos.json"
+)
+
+// dirManifest is the manifest.json of a backup directory: everything about
+// the backup but its contacts, groups and photos
+type dirManifest struct {
+	Version      string      `json:"version"`
+	CreatedAt    time.Time   `json:"created_at"`
+	ContactCount int         `json:"contact_count"`
+	GroupCount   int         `json:"group_count"`
+	Tag          string      `json:"tag,omitempty"`
+	Transforms   []Transform `json:"transforms,omitempty"`
+	Manifest     *Manifest   `json:"manifest"`
+}
+
+// dirGroups is the groups.json of a backup directory
+type dirGroups struct {
+	Groups       []*people.ContactGroup `json:"groups"`
+	GroupMembers map[string][]string    `json:"group_members,omitempty"`
+}
+
+// IsBackupDir reports whether path is a directory holding a backup written
+// by SaveToDir.
+func IsBackupDir(path string) bool {
+	info, err := os.Stat(filepath.Join(path, dirManifestFile))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// SaveToDir writes the backup as a directory meant to be kept in git: one
+// JSON file per contact in contacts/, named after its resource name, plus
+// groups.json, manifest.json (the backup's version, date, counts and
+// content hashes) and, if the backup has any, other_contacts.json,
+// photos.json and fallback_photos.json. Every file is written the same way
+// for the same content, groups and member lists are sorted, and files of
+// contacts that are no longer in the backup are removed, so a commit after
+// each backup shows exactly which contacts changed.
+func (b *BackupFile) SaveToDir(dir string) error {
+	manifest, err := b.BuildManifest()
+	if err != nil {
+		return err
+	}
+	b.Manifest = manifest
+
+	contactsDir := filepath.Join(dir, dirContactsDir)
+	if err := os.MkdirAll(contactsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	written := make(map[string]bool, len(b.Contacts))
+	for i, contact := range b.Contacts {
+		name := contactFileName(contact, i)
+		if written[name] {
+			return fmt.Errorf("two contacts would be written to %s", filepath.Join(dirContactsDir, name))
+		}
+		written[name] = true
+		if err := writeDirFile(filepath.Join(contactsDir, name), contact); err != nil {
+			return err
+		}
+	}
+	entries, err := os.ReadDir(contactsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".json") && !written[entry.Name()] {
+			if err := os.Remove(filepath.Join(contactsDir, entry.Name())); err != nil {
+				return fmt.Errorf("failed to remove deleted contact: %w", err)
+			}
+		}
+	}
+
+	groups := dirGroups{Groups: append([]*people.ContactGroup{}, b.Groups...)}
+	sort.SliceStable(groups.Groups, func(i, j int) bool {
+		return groups.Groups[i].ResourceName < groups.Groups[j].ResourceName
+	})
+	if len(b.GroupMembers) > 0 {
+		groups.GroupMembers = make(map[string][]string, len(b.GroupMembers))
+		for group, members := range b.GroupMembers {
+			members = append([]string{}, members...)
+			sort.Strings(members)
+			groups.GroupMembers[group] = members
+		}
+	}
+	if err := writeDirFile(filepath.Join(dir, dirGroupsFile), groups); err != nil {
+		return err
+	}
+
+	otherContacts := append([]*people.Person{}, b.OtherContacts...)
+	sort.SliceStable(otherContacts, func(i, j int) bool {
+		return otherContacts[i].ResourceName < otherContacts[j].ResourceName
+	})
+	optional := []struct {
+		name  string
+		empty bool
+		value any
+	}{
+		{dirOtherContactsFile, len(otherContacts) == 0, otherContacts},
+		{dirPhotosFile, len(b.Photos) == 0, b.Photos},
+		{dirFallbackPhotosFile, len(b.FallbackPhotos) == 0, b.FallbackPhotos},
+	}
+	for _, file := range optional {
+		path := filepath.Join(dir, file.name)
+		if file.empty {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", file.name, err)
+			}
+			continue
+		}
+		if err := writeDirFile(path, file.value); err != nil {
+			return err
+		}
+	}
+
+	// The manifest is written last, so a directory with one is complete
+	return writeDirFile(filepath.Join(dir, dirManifestFile), dirManifest{
+		Version:      b.Version,
+		CreatedAt:    b.CreatedAt,
+		ContactCount: len(b.Contacts),
+		GroupCount:   len(b.Groups),
+		Tag:          b.Tag,
+		Transforms:   b.Transforms,
+		Manifest:     manifest,
+	})
+}
+
+// LoadDir loads a backup directory written by SaveToDir. Contacts are in the
+// order of their file names.
+func LoadDir(dir string) (*BackupFile, error) {
+	var manifest dirManifest
+	if err := readDirFile(filepath.Join(dir, dirManifestFile), &manifest); err != nil {
+		return nil, err
+	}
+	backup := &BackupFile{
+		Version:      manifest.Version,
+		CreatedAt:    manifest.CreatedAt,
+		ContactCount: manifest.ContactCount,
+		GroupCount:   manifest.GroupCount,
+		Tag:          manifest.Tag,
+		Transforms:   manifest.Transforms,
+		Manifest:     manifest.Manifest,
+		Contacts:     make([]*people.Person, 0, manifest.ContactCount),
+	}
+
+	var groups dirGroups
+	if err := readDirFile(filepath.Join(dir, dirGroupsFile), &groups); err != nil {
+		return nil, err
+	}
+	backup.Groups = groups.Groups
+	backup.GroupMembers = groups.GroupMembers
+	if backup.Groups == nil {
+		backup.Groups = make([]*people.ContactGroup, 0)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, dirContactsDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		var contact people.Person
+		if err := readDirFile(filepath.Join(dir, dirContactsDir, entry.Name()), &contact); err != nil {
+			return nil, err
+		}
+		backup.Contacts = append(backup.Contacts, &contact)
+	}
+
+	optional := map[string]any{
+		dirOtherContactsFile:  &backup.OtherContacts,
+		dirPhotosFile:         &backup.Photos,
+		dirFallbackPhotosFile: &backup.FallbackPhotos,
+	}
+	for name, value := range optional {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := readDirFile(path, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return backup, nil
+}
+
+// contactFileName returns the name of a contact's file in contacts/: its
+// resource name without "people/", or its position for contacts without
+// one.
+func contactFileName(contact *people.Person, index int) string {
+	id := strings.TrimPrefix(contact.ResourceName, "people/")
+	if id == "" {
+		return fmt.Sprintf("unnamed-%d.json", index+1)
+	}
+	id = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, id)
+	return id + ".json"
+}
+
+// writeDirFile writes v to path as indented JSON with a final newline.
+func writeDirFile(path string, v any) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return nil
+}
+
+// readDirFile decodes the JSON file at path into v.
+func readDirFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// fieldLabels are readable names of person fields, for people who do not
+// know the People API names
+var fieldLabels = map[string]string{
+	"addresses":      "Addresses",
+	"biographies":    "Notes",
+	"birthdays":      "Birthday",
+	"emailAddresses": "Emails",
+	"events":         "Events",
+	"imClients":      "Chat",
+	"memberships":    "Labels",
+	"names":          "Name",
+	"nicknames":      "Nickname",
+	"occupations":    "Occupation",
+	"organizations":  "Organization",
+	"phoneNumbers":   "Phones",
+	"relations":      "Relations",
+	"urls":           "Websites",
+	"userDefined":    "Custom fields",
+}
+
+// FieldLabel returns a readable name for a person field, or its API name if
+// it has none.
+func FieldLabel(name string) string {
+	if label, ok := fieldLabels[name]; ok {
+		return label
+	}
+	return name
+}
+
+// FieldValues returns the values of the named person field as text, one
+// string per value, with the value's type in parentheses where it has one.
+// groupNames maps group resource names to names for memberships; groups
+// missing from it are shown by their ID. Fields without a readable form are
+// shown as JSON, without metadata.
+func FieldValues(p *people.Person, name string, groupNames map[string]string) []string {
+	i, ok := personFieldIndex[name]
+	if !ok || p == nil {
+		return nil
+	}
+
+	var values []string
+	add := func(value, apiType string) {
+		if value == "" {
+			return
+		}
+		if apiType != "" {
+			value += " (" + normalizeLabel(apiType) + ")"
+		}
+		values = append(values, value)
+	}
+
+	switch name {
+	case "names":
+		for _, n := range p.Names {
+			add(strings.Join(nonEmpty(n.HonorificPrefix, n.GivenName, n.MiddleName, n.FamilyName, n.HonorificSuffix), " "), "")
+		}
+	case "nicknames":
+		for _, n := range p.Nicknames {
+			add(n.Value, "")
+		}
+	case "emailAddresses":
+		for _, email := range p.EmailAddresses {
+			add(email.Value, email.Type)
+		}
+	case "phoneNumbers":
+		for _, phone := range p.PhoneNumbers {
+			add(phone.Value, phone.Type)
+		}
+	case "addresses":
+		for _, addr := range p.Addresses {
+			value := addr.FormattedValue
+			if value == "" {
+				value = strings.Join(nonEmpty(addr.StreetAddress, addr.ExtendedAddress, addr.City,
+					addr.Region, addr.PostalCode, addr.Country), ", ")
+			}
+			add(value, addr.Type)
+		}
+	case "organizations":
+		for _, org := range p.Organizations {
+			add(strings.Join(nonEmpty(org.Name, org.Title, org.Department), ", "), "")
+		}
+	case "birthdays":
+		for _, bday := range p.Birthdays {
+			if bday.Date != nil {
+				add(formatDate(bday.Date), "")
+			} else {
+				add(bday.Text, "")
+			}
+		}
+	case "events":
+		for _, event := range p.Events {
+			if event.Date != nil {
+				add(formatDate(event.Date), event.Type)
+			}
+		}
+	case "biographies":
+		for _, bio := range p.Biographies {
+			add(NotesText(bio, true), "")
+		}
+	case "urls":
+		for _, url := range p.Urls {
+			add(url.Value, url.Type)
+		}
+	case "relations":
+		for _, rel := range p.Relations {
+			add(rel.Person, rel.Type)
+		}
+	case "imClients":
+		for _, im := range p.ImClients {
+			add(im.Username, im.Protocol)
+		}
+	case "userDefined":
+		for _, ud := range p.UserDefined {
+			add(ud.Key+": "+ud.Value, "")
+		}
+	case "memberships":
+		for _, membership := range p.Memberships {
+			if membership.ContactGroupMembership == nil {
+				continue
+			}
+			resourceName := membership.ContactGroupMembership.ContactGroupResourceName
+			if groupName, ok := groupNames[resourceName]; ok {
+				add(groupName, "")
+			} else {
+				add(strings.TrimPrefix(resourceName, "contactGroups/"), "")
+			}
+		}
+	default:
+		// Most other fields hold a Value (or Url) and an optional Type
+		field := reflect.ValueOf(p).Elem().Field(i)
+		for j := 0; j < field.Len(); j++ {
+			item := field.Index(j)
+			if item.Kind() == reflect.Pointer {
+				item = item.Elem()
+			}
+			value, apiType := stringField(item, "Value"), stringField(item, "Type")
+			if value == "" {
+				value = stringField(item, "Url")
+			}
+			if value == "" {
+				// canonicalField encodes a list; show the one value without brackets
+				value = string(canonicalField(field.Slice(j, j+1)))
+				value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+			}
+			add(value, apiType)
+		}
+	}
+	return values
+}
+
+// stringField returns the string field of a struct value by name, or "".
+func stringField(v reflect.Value, name string) string {
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// formatDate formats a date as YYYY-MM-DD, or --MM-DD without a year.
+func formatDate(date *people.Date) string {
+	if date.Year > 0 {
+		return fmt.Sprintf("%04d-%02d-%02d", date.Year, date.Month, date.Day)
+	}
+	return fmt.Sprintf("--%02d-%02d", date.Month, date.Day)
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// personFieldIndex maps People API field names (e.g. "biographies") to the
+// index of the corresponding multi-value field in people.Person.
+var personFieldIndex = buildPersonFieldIndex()
+
+func buildPersonFieldIndex() map[string]int {
+	index := make(map[string]int)
+	t := reflect.TypeOf(people.Person{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || field.Type.Kind() != reflect.Slice {
+			continue
+		}
+		index[name] = i
+	}
+	return index
+}
+
+// fieldAliases are short names accepted for person fields on the command line
+var fieldAliases = map[string]string{
+	"phones":        "phoneNumbers",
+	"phone":         "phoneNumbers",
+	"emails":        "emailAddresses",
+	"email":         "emailAddresses",
+	"address":       "addresses",
+	"birthday":      "birthdays",
+	"notes":         "biographies",
+	"note":          "biographies",
+	"orgs":          "organizations",
+	"organization":  "organizations",
+	"websites":      "urls",
+	"im":            "imClients",
+	"labels":        "memberships",
+	"groups":        "memberships",
+	"custom":        "userDefined",
+	"relationships": "relations",
+}
+
+// ResolveFieldName returns the API name of a person field given by its API
+// name or a short alias such as "phones" or "emails". It reports false if
+// name is neither.
+func ResolveFieldName(name string) (string, bool) {
+	if alias, ok := fieldAliases[strings.ToLower(name)]; ok {
+		return alias, true
+	}
+	if IsPersonField(name) {
+		return name, true
+	}
+	return "", false
+}
+
+// PersonFieldNames returns the API names of all multi-value person fields, sorted.
+func PersonFieldNames() []string {
+	names := make([]string, 0, len(personFieldIndex))
+	for name := range personFieldIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsPersonField reports whether name is a multi-value person field.
+func IsPersonField(name string) bool {
+	_, ok := personFieldIndex[name]
+	return ok
+}
+
+// PresentFields returns the sorted names of the person fields that hold at
+// least one value. Memberships are not included.
+func PresentFields(p *people.Person) []string {
+	v := reflect.ValueOf(p).Elem()
+
+	var present []string
+	for _, name := range PersonFieldNames() {
+		if name != "memberships" && v.Field(personFieldIndex[name]).Len() > 0 {
+			present = append(present, name)
+		}
+	}
+	return present
+}
+
+// FieldsBySource returns, for each source type other than CONTACT that
+// values of the contact came from (see FieldSource), the sorted names of
+// the person fields holding such values. It tells data synced from a Google
+// profile or a Workspace directory apart from data entered in the contact.
+// Memberships are not included.
+func FieldsBySource(p *people.Person) map[string][]string {
+	v := reflect.ValueOf(p).Elem()
+
+	bySource := make(map[string][]string)
+	for _, name := range PresentFields(p) {
+		values := v.Field(personFieldIndex[name])
+		seen := make(map[string]bool)
+		for i := 0; i < values.Len(); i++ {
+			value := values.Index(i)
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			field := value.FieldByName("Metadata")
+			if !field.IsValid() {
+				continue
+			}
+			metadata, _ := field.Interface().(*people.FieldMetadata)
+			source := FieldSource(metadata)
+			if source == "" || source == "CONTACT" || seen[source] {
+				continue
+			}
+			seen[source] = true
+			bySource[source] = append(bySource[source], name)
+		}
+	}
+	return bySource
+}
+
+// CopyPersonField copies the named field from src to dst. It reports false if
+// name is not a person field.
+func CopyPersonField(dst, src *people.Person, name string) bool {
+	i, ok := personFieldIndex[name]
+	if !ok {
+		return false
+	}
+	reflect.ValueOf(dst).Elem().Field(i).Set(reflect.ValueOf(src).Elem().Field(i))
+	return true
+}
+
+// DiffFields returns the sorted names of person fields whose values differ
+// between a and b. Server-assigned metadata is ignored, as are the fields
+// listed in ignore.
+func DiffFields(a, b *people.Person, ignore ...string) []string {
+	skip := make(map[string]bool, len(ignore))
+	for _, name := range ignore {
+		skip[name] = true
+	}
+
+	va := reflect.ValueOf(a).Elem()
+	vb := reflect.ValueOf(b).Elem()
+
+	var diffs []string
+	for _, name := range PersonFieldNames() {
+		if skip[name] {
+			continue
+		}
+		i := personFieldIndex[name]
+		if !bytes.Equal(canonicalField(va.Field(i)), canonicalField(vb.Field(i))) {
+			diffs = append(diffs, name)
+		}
+	}
+	return diffs
+}
+
+// canonicalField returns the JSON encoding of a field value with all
+// "metadata" keys removed, so values can be compared independently of
+// server-assigned sources and identifiers.
+func canonicalField(v reflect.Value) []byte {
+	if v.Len() == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil
+	}
+	stripMetadata(generic)
+
+	// encoding/json sorts map keys, so the result is canonical
+	canonical, _ := json.Marshal(generic)
+	return canonical
+}
+
+// stripMetadata removes "metadata" keys from a decoded JSON value in place.
+func stripMetadata(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		delete(t, "metadata")
+		for _, child := range t {
+			stripMetadata(child)
+		}
+	case []any:
+		for _, child := range t {
+			stripMetadata(child)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// htmlTemplate renders contacts as a standalone, printable HTML page
+var htmlTemplate = template.Must(template.New("contacts").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.contact { border-bottom: 1px solid #ddd; padding: 1em 0; }
+.contact h2 { margin: 0 0 0.3em; font-size: 1.2em; }
+.labels { color: #666; font-size: 0.9em; }
+dt { font-weight: bold; float: left; clear: left; width: 8em; }
+dd { margin-left: 9em; }
+.notes { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Contacts}}<div class="contact">
+<h2>{{.Name}}</h2>
+{{if .Labels}}<div class="labels">{{range $i, $l := .Labels}}{{if $i}}, {{end}}{{$l}}{{end}}</div>
+{{end}}<dl>
+{{range .Fields}}<dt>{{.Label}}</dt><dd>{{.Value}}</dd>
+{{end}}</dl>
+{{if .Notes}}<div class="notes">{{.Notes}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+// htmlPage is the data passed to htmlTemplate
+type htmlPage struct {
+	Title    string
+	Contacts []htmlContact
+}
+
+// htmlContact is one contact as shown on the HTML page
+type htmlContact struct {
+	Name   string
+	Labels []string
+	Fields []htmlField
+	Notes  string
+}
+
+// htmlField is a labelled value of a contact
+type htmlField struct {
+	Label string
+	Value string
+}
+
+// HTMLOptions controls how a backup is written as HTML.
+type HTMLOptions struct {
+	// Title is shown as the page heading. Empty means "Contacts".
+	Title string
+}
+
+// WriteHTML writes the backup's contacts as a standalone HTML page to w.
+// Notes are always converted to plain text.
+func (b *BackupFile) WriteHTML(w io.Writer, opts HTMLOptions) error {
+	page := htmlPage{Title: opts.Title}
+	if page.Title == "" {
+		page.Title = "Contacts"
+	}
+
+	groupNameMap := b.UserGroupNames()
+	for _, contact := range b.Contacts {
+		page.Contacts = append(page.Contacts, contactToHTML(contact, groupNameMap))
+	}
+
+	if err := htmlTemplate.Execute(w, page); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+
+	return nil
+}
+
+// contactToHTML converts a contact to its HTML page representation
+func contactToHTML(contact *people.Person, groupNameMap map[string]string) htmlContact {
+	c := htmlContact{
+		Name:   DisplayName(contact),
+		Labels: extractLabels(contact, groupNameMap),
+	}
+
+	add := func(kind, apiType, value string) {
+		if value == "" {
+			return
+		}
+		label := kind
+		if apiType != "" {
+			label = kind + " (" + normalizeLabel(apiType) + ")"
+		}
+		c.Fields = append(c.Fields, htmlField{Label: label, Value: value})
+	}
+
+	if len(contact.Organizations) > 0 {
+		org := contact.Organizations[0]
+		add("Organization", "", strings.TrimSpace(strings.Join([]string{org.Title, org.Name}, " ")))
+	}
+	for _, email := range contact.EmailAddresses {
+		add("Email", email.Type, email.Value)
+	}
+	for _, phone := range contact.PhoneNumbers {
+		add("Phone", phone.Type, phone.Value)
+	}
+	for _, addr := range contact.Addresses {
+		value := addr.FormattedValue
+		if value == "" {
+			value = strings.Join(nonEmpty(addr.StreetAddress, addr.ExtendedAddress, addr.City,
+				addr.Region, addr.PostalCode, addr.Country), ", ")
+		}
+		add("Address", addr.Type, value)
+	}
+	if len(contact.Birthdays) > 0 && contact.Birthdays[0].Date != nil {
+		date := contact.Birthdays[0].Date
+		if date.Year > 0 {
+			add("Birthday", "", fmt.Sprintf("%04d-%02d-%02d", date.Year, date.Month, date.Day))
+		} else {
+			add("Birthday", "", fmt.Sprintf("--%02d-%02d", date.Month, date.Day))
+		}
+	}
+	for _, url := range contact.Urls {
+		add("Website", url.Type, url.Value)
+	}
+
+	if len(contact.Biographies) > 0 {
+		c.Notes = NotesText(contact.Biographies[0], true)
+	}
+
+	return c
+}
+
+// nonEmpty returns the values that are not empty
+func nonEmpty(values ...string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
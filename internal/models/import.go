@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+
+	"google.golang.org/api/people/v1"
+)
+
+// importer collects the contacts read from a CSV or vCard file into a
+// backup. Labels become user contact groups and every contact is a member of
+// myContacts. Contacts and groups get placeholder resource names, as they
+// have none in Google yet.
+type importer struct {
+	backup *BackupFile
+
+	// prefix starts the placeholder resource names, e.g. "csv"
+	prefix string
+
+	// groups maps label names to group resource names
+	groups map[string]string
+}
+
+// newImporter returns an importer whose placeholder resource names start
+// with prefix.
+func newImporter(prefix string) *importer {
+	backup := NewBackupFile()
+	backup.AddGroup(&people.ContactGroup{ResourceName: "contactGroups/myContacts", Name: "myContacts", GroupType: "SYSTEM_CONTACT_GROUP"})
+	return &importer{backup: backup, prefix: prefix, groups: make(map[string]string)}
+}
+
+// add adds a contact with the given labels. Contacts without a resource name
+// are named after id, such as their row or card number.
+func (im *importer) add(contact *people.Person, id int, labels []string) {
+	if contact.ResourceName == "" {
+		contact.ResourceName = fmt.Sprintf("people/%s%d", im.prefix, id)
+	}
+	contact.Memberships = []*people.Membership{groupMembership("contactGroups/myContacts")}
+	for _, label := range labels {
+		resourceName, ok := im.groups[label]
+		if !ok {
+			resourceName = fmt.Sprintf("contactGroups/%s%d", im.prefix, len(im.groups)+1)
+			im.groups[label] = resourceName
+			im.backup.AddGroup(&people.ContactGroup{ResourceName: resourceName, Name: label, GroupType: "USER_CONTACT_GROUP"})
+		}
+		contact.Memberships = append(contact.Memberships, groupMembership(resourceName))
+	}
+	im.backup.AddContact(contact)
+}
+
+// groupMembership returns a membership of the contact group
+func groupMembership(resourceName string) *people.Membership {
+	return &people.Membership{
+		ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: resourceName},
+	}
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mheap/google-contacts-backup/internal/integrity"
+)
+
+// GroupSummary describes a contact group of a backup.
+type GroupSummary struct {
+	ResourceName string `json:"resource_name"`
+	Name         string `json:"name"`
+	GroupType    string `json:"group_type"`
+
+	// Members is the number of the backup's contacts in the group, by their
+	// memberships or the group's member list
+	Members int `json:"members"`
+}
+
+// GroupSummaries returns the backup's groups with their member counts, user
+// groups first, each kind sorted by name.
+func (b *BackupFile) GroupSummaries() []GroupSummary {
+	contacts := make(map[string]bool, len(b.Contacts))
+	for _, contact := range b.Contacts {
+		contacts[contact.ResourceName] = true
+	}
+
+	summaries := make([]GroupSummary, 0, len(b.Groups))
+	for _, group := range b.Groups {
+		members := make(map[string]bool)
+		for _, contact := range b.Contacts {
+			if hasMembership(contact, group.ResourceName) {
+				members[contact.ResourceName] = true
+			}
+		}
+		for _, resourceName := range b.GroupMembers[group.ResourceName] {
+			if contacts[resourceName] {
+				members[resourceName] = true
+			}
+		}
+		summaries = append(summaries, GroupSummary{
+			ResourceName: group.ResourceName,
+			Name:         group.Name,
+			GroupType:    group.GroupType,
+			Members:      len(members),
+		})
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		userI := summaries[i].GroupType == "USER_CONTACT_GROUP"
+		userJ := summaries[j].GroupType == "USER_CONTACT_GROUP"
+		if userI != userJ {
+			return userI
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+// FieldCoverage returns the number of contacts holding at least one value of
+// each person field, for the fields some contact has (see PresentFields).
+func (b *BackupFile) FieldCoverage() map[string]int {
+	coverage := make(map[string]int)
+	for _, contact := range b.Contacts {
+		for _, field := range PresentFields(contact) {
+			coverage[field]++
+		}
+	}
+	return coverage
+}
+
+// StructureProblems checks that the backup is consistent with itself and
+// returns a description of each problem found: counts that disagree with
+// the contents, missing or repeated resource names, memberships and member
+// lists of groups the backup does not have, photos of contacts it does not
+// have, and contacts that no longer match the manifest.
+func (b *BackupFile) StructureProblems() []string {
+	var problems []string
+	addf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if b.Version == "" {
+		addf("the backup has no format version")
+	}
+	if b.ContactCount != len(b.Contacts) {
+		addf("contact_count is %d but the backup holds %d contacts", b.ContactCount, len(b.Contacts))
+	}
+	if b.GroupCount != len(b.Groups) {
+		addf("group_count is %d but the backup holds %d groups", b.GroupCount, len(b.Groups))
+	}
+
+	groups := make(map[string]bool, len(b.Groups))
+	for i, group := range b.Groups {
+		switch {
+		case group.ResourceName == "":
+			addf("group #%d (%s) has no resource name", i+1, group.Name)
+		case groups[group.ResourceName]:
+			addf("group %s appears more than once", group.ResourceName)
+		}
+		groups[group.ResourceName] = true
+	}
+
+	contacts := make(map[string]bool, len(b.Contacts))
+	unknownGroups := make(map[string]int)
+	for i, contact := range b.Contacts {
+		switch {
+		case contact.ResourceName == "":
+			addf("contact #%d (%s) has no resource name", i+1, DisplayName(contact))
+		case contacts[contact.ResourceName]:
+			addf("contact %s appears more than once", contact.ResourceName)
+		}
+		contacts[contact.ResourceName] = true
+
+		for _, membership := range contact.Memberships {
+			if m := membership.ContactGroupMembership; m != nil && !groups[m.ContactGroupResourceName] {
+				unknownGroups[m.ContactGroupResourceName]++
+			}
+		}
+	}
+	for _, group := range sortedKeys(unknownGroups) {
+		addf("%d contacts are members of group %s, which is not in the backup", unknownGroups[group], group)
+	}
+
+	for _, group := range sortedKeys(b.GroupMembers) {
+		if !groups[group] {
+			addf("group_members lists group %s, which is not in the backup", group)
+		}
+		unknown := 0
+		for _, member := range b.GroupMembers[group] {
+			if !contacts[member] {
+				unknown++
+			}
+		}
+		if unknown > 0 {
+			addf("group_members of %s lists %d contacts that are not in the backup", group, unknown)
+		}
+	}
+	for _, resourceName := range sortedKeys(b.Photos) {
+		if !contacts[resourceName] {
+			addf("photos holds a photo of %s, which is not in the backup", resourceName)
+		}
+	}
+	for _, resourceName := range sortedKeys(b.FallbackPhotos) {
+		if !contacts[resourceName] {
+			addf("fallback_photos holds a photo of %s, which is not in the backup", resourceName)
+		}
+	}
+
+	if b.Manifest != nil {
+		hashes, err := integrity.Hashes(b.Contacts)
+		if err != nil {
+			addf("failed to hash contacts: %v", err)
+		} else if diff := integrity.Compare(b.Manifest.Contacts, hashes); !diff.Empty() || integrity.Root(hashes) != b.Manifest.Root {
+			addf("the manifest does not match the contents: %d changed, %d missing and %d unlisted contacts",
+				len(diff.Changed), len(diff.Removed), len(diff.Added))
+		}
+	}
+
+	return problems
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
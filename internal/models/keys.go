@@ -0,0 +1,158 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// Types of encryption keys, as written in the TYPE parameter of vCard KEY
+// properties.
+const (
+	KeyTypePGP  = "PGP"
+	KeyTypeX509 = "X509"
+)
+
+// pgpLabels and x509Labels are words in the names of custom fields and
+// client data keys that mail clients store encryption keys under
+var (
+	pgpLabels  = []string{"pgp", "gpg", "autocrypt"}
+	x509Labels = []string{"smime", "s/mime", "s-mime", "x509", "x.509", "certificate"}
+)
+
+// EncryptionKey is a public encryption key stored on a contact by a mail
+// client, in a custom field (userDefined) or in client data.
+type EncryptionKey struct {
+	// Type is KeyTypePGP or KeyTypeX509 (S/MIME)
+	Type string
+
+	// Label is the name of the custom field or client data key
+	Label string
+
+	// Data is the binary key, or nil if the key is given by URI
+	Data []byte
+
+	// URI locates the key, for keys published on a key server
+	URI string
+}
+
+// EncryptionKeys returns the PGP keys and S/MIME certificates stored in a
+// contact's custom fields and client data. A value is taken as a key if it
+// is ASCII-armored (BEGIN PGP PUBLIC KEY BLOCK, BEGIN CERTIFICATE), or if
+// its field is named like a key (e.g. "PGP Key", "S/MIME certificate") and
+// it is a base64-encoded key or an http(s) URL. Fingerprints and other
+// values that are not key material are ignored.
+func EncryptionKeys(contact *people.Person) []EncryptionKey {
+	var keys []EncryptionKey
+	add := func(label, value string) {
+		if key, ok := parseEncryptionKey(label, value); ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, field := range contact.UserDefined {
+		add(field.Key, field.Value)
+	}
+	for _, data := range contact.ClientData {
+		add(data.Key, data.Value)
+	}
+	return keys
+}
+
+// parseEncryptionKey decodes the value of a field holding a key.
+func parseEncryptionKey(label, value string) (EncryptionKey, bool) {
+	value = strings.TrimSpace(value)
+	key := EncryptionKey{Label: label, Type: keyTypeForLabel(label)}
+
+	switch {
+	case strings.Contains(value, "-----BEGIN PGP PUBLIC KEY BLOCK-----"):
+		data, ok := dearmorPGP(value)
+		key.Type, key.Data = KeyTypePGP, data
+		return key, ok
+	case strings.Contains(value, "-----BEGIN CERTIFICATE-----"):
+		block, _ := pem.Decode([]byte(value))
+		if block == nil {
+			return key, false
+		}
+		key.Type, key.Data = KeyTypeX509, block.Bytes
+		return key, true
+	case key.Type == "":
+		return key, false
+	case strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://"):
+		key.URI = value
+		return key, true
+	}
+
+	// Bare base64; anything shorter than a key (such as a fingerprint,
+	// which is hex and would decode too) is not key material
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil || len(data) < 64 {
+		return key, false
+	}
+	key.Data = data
+	return key, true
+}
+
+// keyTypeForLabel returns the key type a field name suggests, or "".
+func keyTypeForLabel(label string) string {
+	label = strings.ToLower(label)
+	for _, word := range pgpLabels {
+		if strings.Contains(label, word) {
+			return KeyTypePGP
+		}
+	}
+	for _, word := range x509Labels {
+		if strings.Contains(label, word) {
+			return KeyTypeX509
+		}
+	}
+	return ""
+}
+
+// dearmorPGP decodes an ASCII-armored OpenPGP block: the base64 lines
+// between the armor headers and the checksum line.
+func dearmorPGP(armored string) ([]byte, bool) {
+	lines := strings.Split(strings.ReplaceAll(armored, "\r\n", "\n"), "\n")
+	var body strings.Builder
+	inBlock, inHeaders := false, false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "-----BEGIN PGP"):
+			inBlock, inHeaders = true, true
+		case strings.HasPrefix(line, "-----END PGP"):
+			inBlock = false
+		case !inBlock:
+		case inHeaders && strings.Contains(line, ": "):
+			// Armor header such as "Version: GnuPG v2"
+		case inHeaders && line == "":
+			inHeaders = false
+		case strings.HasPrefix(line, "=") && len(line) == 5:
+			// CRC-24 checksum
+		default:
+			inHeaders = false
+			body.WriteString(line)
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+// vCardKeyLines returns the vCard 3.0 KEY properties of a contact's
+// encryption keys.
+func vCardKeyLines(contact *people.Person) []string {
+	var lines []string
+	for _, key := range EncryptionKeys(contact) {
+		if key.URI != "" {
+			lines = append(lines, "KEY;VALUE=uri;TYPE="+key.Type+":"+key.URI)
+		} else {
+			lines = append(lines, "KEY;ENCODING=b;TYPE="+key.Type+":"+base64.StdEncoding.EncodeToString(key.Data))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/mheap/google-contacts-backup/internal/integrity"
+)
+
+// Manifest records a content hash of every contact in a backup, arranged as
+// a Merkle tree, so the backup can be checked for corruption and compared
+// with the live account without a field-by-field diff.
+type Manifest struct {
+	// Algorithm is the hash function, always "sha256"
+	Algorithm string `json:"algorithm"`
+
+	// Root is the Merkle root over all contact hashes
+	Root string `json:"root"`
+
+	// Contacts maps resource names to content hashes
+	Contacts map[string]string `json:"contacts"`
+}
+
+// BuildManifest hashes the backup's contacts into a new manifest.
+func (b *BackupFile) BuildManifest() (*Manifest, error) {
+	hashes, err := integrity.Hashes(b.Contacts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash contacts: %w", err)
+	}
+	return &Manifest{
+		Algorithm: integrity.Algorithm,
+		Root:      integrity.Root(hashes),
+		Contacts:  hashes,
+	}, nil
+}
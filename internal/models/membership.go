@@ -0,0 +1,182 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Membership matrix column headers
+const (
+	colMatrixResourceName = "Resource Name"
+	colMatrixName         = "Name"
+)
+
+// memberMark marks a membership in a matrix written by WriteCSV
+const memberMark = "x"
+
+// MembershipMatrix is a contacts × labels table of label memberships, one
+// row per contact and one column per user label, meant to be edited in a
+// spreadsheet.
+type MembershipMatrix struct {
+	// Labels are the label names, in column order
+	Labels []string
+
+	// Rows holds one row per contact
+	Rows []MembershipRow
+}
+
+// MembershipRow is a contact's row of a membership matrix.
+type MembershipRow struct {
+	// ResourceName identifies the contact
+	ResourceName string
+
+	// Name is the contact's display name, for the reader's benefit only
+	Name string
+
+	// Labels holds the names of the labels the contact carries
+	Labels map[string]bool
+}
+
+// MembershipMatrix returns the label memberships of the backup's contacts,
+// with the labels sorted by name and the contacts in backup order.
+// Memberships recorded in GroupMembers are included.
+func (b *BackupFile) MembershipMatrix() *MembershipMatrix {
+	groupNames := b.UserGroupNames()
+	matrix := &MembershipMatrix{}
+	seen := make(map[string]bool)
+	for _, name := range groupNames {
+		if !seen[name] {
+			seen[name] = true
+			matrix.Labels = append(matrix.Labels, name)
+		}
+	}
+	sort.Strings(matrix.Labels)
+
+	members := make(map[string]map[string]bool)
+	for group, resourceNames := range b.GroupMembers {
+		for _, resourceName := range resourceNames {
+			if members[resourceName] == nil {
+				members[resourceName] = make(map[string]bool)
+			}
+			members[resourceName][group] = true
+		}
+	}
+
+	for _, contact := range b.Contacts {
+		row := MembershipRow{
+			ResourceName: contact.ResourceName,
+			Name:         DisplayName(contact),
+			Labels:       make(map[string]bool),
+		}
+		for group, name := range groupNames {
+			if hasMembership(contact, group) || members[contact.ResourceName][group] {
+				row.Labels[name] = true
+			}
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+
+	return matrix
+}
+
+// WriteCSV writes the matrix as CSV: a resource name and a name column, then
+// one column per label holding "x" where the contact carries the label.
+func (m *MembershipMatrix) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	headers := append([]string{colMatrixResourceName, colMatrixName}, m.Labels...)
+	if err := writer.Write(headers); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, row := range m.Rows {
+		record := []string{row.ResourceName, row.Name}
+		for _, label := range m.Labels {
+			if row.Labels[label] {
+				record = append(record, memberMark)
+			} else {
+				record = append(record, "")
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadMembershipMatrix reads a matrix in the format MembershipMatrix.WriteCSV
+// writes. A cell holding x, 1, yes or true (in any case) marks a membership;
+// an empty cell or 0, no or false marks none. Any other value is an error, so
+// that a typo does not silently remove a label.
+func ReadMembershipMatrix(r io.Reader) (*MembershipMatrix, error) {
+	reader := csv.NewReader(r)
+
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("invalid membership matrix: missing header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse membership matrix: %w", err)
+	}
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+	}
+	if len(headers) < 2 || headers[0] != colMatrixResourceName || headers[1] != colMatrixName {
+		return nil, fmt.Errorf("invalid membership matrix: the first columns must be %q and %q", colMatrixResourceName, colMatrixName)
+	}
+
+	matrix := &MembershipMatrix{}
+	seen := make(map[string]bool)
+	for _, label := range headers[2:] {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			return nil, fmt.Errorf("invalid membership matrix: a label column has no name")
+		}
+		if seen[label] {
+			return nil, fmt.Errorf("invalid membership matrix: label %q has more than one column", label)
+		}
+		seen[label] = true
+		matrix.Labels = append(matrix.Labels, label)
+	}
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse membership matrix: %w", err)
+		}
+
+		entry := MembershipRow{
+			ResourceName: strings.TrimSpace(record[0]),
+			Name:         record[1],
+			Labels:       make(map[string]bool),
+		}
+		if entry.ResourceName == "" {
+			return nil, fmt.Errorf("row %d: missing resource name", row)
+		}
+		for i, label := range matrix.Labels {
+			switch strings.ToLower(strings.TrimSpace(record[i+2])) {
+			case "x", "1", "yes", "true":
+				entry.Labels[label] = true
+			case "", "0", "no", "false":
+			default:
+				return nil, fmt.Errorf("row %d, label %q: %q is not x or empty", row, label, record[i+2])
+			}
+		}
+		matrix.Rows = append(matrix.Rows, entry)
+	}
+
+	return matrix, nil
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// MinimalOptions controls how contacts are reduced to SIM-style entries.
+type MinimalOptions struct {
+	// MaxNameLength caps names, in characters. Zero means no limit.
+	MaxNameLength int
+
+	// MaxPhoneLength caps phone numbers, in characters after removing
+	// spaces and punctuation. Zero means no limit.
+	MaxPhoneLength int
+}
+
+// Minimal returns a copy of the backup holding only a name and the primary
+// phone number of each contact, the most that SIM cards and basic devices
+// can store. Contacts without a phone number are left out. Groups are not
+// copied.
+func (b *BackupFile) Minimal(opts MinimalOptions) *BackupFile {
+	minimal := NewBackupFile()
+	minimal.CreatedAt = b.CreatedAt
+
+	for _, contact := range b.Contacts {
+		phone := primaryPhone(contact)
+		if phone == "" {
+			continue
+		}
+		name := truncateRunes(DisplayName(contact), opts.MaxNameLength)
+		minimal.AddContact(&people.Person{
+			Names:        []*people.Name{{DisplayName: name, GivenName: name}},
+			PhoneNumbers: []*people.PhoneNumber{{Value: truncateRunes(dialString(phone), opts.MaxPhoneLength)}},
+		})
+	}
+
+	return minimal
+}
+
+// primaryPhone returns the primary phone number, or the first one
+func primaryPhone(p *people.Person) string {
+	for _, phone := range p.PhoneNumbers {
+		if isPrimary(phone.Metadata) {
+			return phone.Value
+		}
+	}
+	if len(p.PhoneNumbers) > 0 {
+		return p.PhoneNumbers[0].Value
+	}
+	return ""
+}
+
+// dialString keeps only the characters a phone can dial: digits, a leading
+// +, and the * # , ; control characters
+func dialString(phone string) string {
+	var sb strings.Builder
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9', r == '*', r == '#', r == ',', r == ';':
+			sb.WriteRune(r)
+		case r == '+' && i == 0:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// truncateRunes shortens s to at most limit characters; a limit of zero
+// leaves it unchanged
+func truncateRunes(s string, limit int) string {
+	if limit <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:limit]))
+}
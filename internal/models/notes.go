@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"google.golang.org/api/people/v1"
+)
+
+const (
+	// NotesContentTypeHTML marks a biography whose value is HTML
+	NotesContentTypeHTML = "TEXT_HTML"
+	// NotesContentTypePlain marks a biography whose value is plain text
+	NotesContentTypePlain = "TEXT_PLAIN"
+)
+
+// NotesText returns the value of a contact's notes. If plain is true and the
+// notes are HTML, the markup is converted to plain text.
+func NotesText(bio *people.Biography, plain bool) string {
+	if bio == nil {
+		return ""
+	}
+	if plain && bio.ContentType == NotesContentTypeHTML {
+		return HTMLToPlainText(bio.Value)
+	}
+	return bio.Value
+}
+
+// HTMLToPlainText strips markup from an HTML fragment, turning block-level
+// elements and <br> into line breaks and decoding entities.
+func HTMLToPlainText(fragment string) string {
+	var sb strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(collapseBlankLines(sb.String()))
+		case html.TextToken:
+			sb.Write(tokenizer.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "br", "p", "div", "li", "tr", "h1", "h2", "h3", "h4", "h5", "h6":
+				sb.WriteString("\n")
+			}
+		}
+	}
+}
+
+// collapseBlankLines reduces runs of blank lines to a single blank line.
+func collapseBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
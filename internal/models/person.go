@@ -0,0 +1,179 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/people/v1"
+)
+
+// DisplayName returns a human-readable name for a contact, falling back to
+// its primary email, phone number or resource name when it has no name.
+func DisplayName(contact *people.Person) string {
+	if len(contact.Names) > 0 {
+		name := contact.Names[0]
+		if name.DisplayName != "" {
+			return name.DisplayName
+		}
+		full := strings.TrimSpace(strings.Join([]string{name.GivenName, name.FamilyName}, " "))
+		if full != "" {
+			return full
+		}
+	}
+	if len(contact.EmailAddresses) > 0 && contact.EmailAddresses[0].Value != "" {
+		return contact.EmailAddresses[0].Value
+	}
+	if len(contact.PhoneNumbers) > 0 && contact.PhoneNumbers[0].Value != "" {
+		return contact.PhoneNumbers[0].Value
+	}
+	if contact.ResourceName != "" {
+		return contact.ResourceName
+	}
+	return "(unnamed contact)"
+}
+
+// ProfilePhotoFallback returns the contact's Google profile photo if the
+// contact has no non-default photo of its own, or nil otherwise.
+func ProfilePhotoFallback(contact *people.Person) *people.Photo {
+	var profilePhoto *people.Photo
+	for _, photo := range contact.Photos {
+		if photo.Default || photo.Url == "" {
+			continue
+		}
+		if photo.Metadata == nil || photo.Metadata.Source == nil {
+			continue
+		}
+		switch photo.Metadata.Source.Type {
+		case "CONTACT":
+			return nil
+		case "PROFILE":
+			if profilePhoto == nil {
+				profilePhoto = photo
+			}
+		}
+	}
+	return profilePhoto
+}
+
+// ContactPhoto returns the contact's own photo, as opposed to a default
+// placeholder or a Google profile photo, or nil if it has none.
+func ContactPhoto(contact *people.Person) *people.Photo {
+	for _, photo := range contact.Photos {
+		if photo.Default || photo.Url == "" || photo.Metadata == nil || photo.Metadata.Source == nil {
+			continue
+		}
+		if photo.Metadata.Source.Type == "CONTACT" {
+			return photo
+		}
+	}
+	return nil
+}
+
+// IsEmptyContact reports whether a contact has no name, no email address and
+// no phone number. Such contacts are typically left behind by phone syncs.
+func IsEmptyContact(contact *people.Person) bool {
+	for _, name := range contact.Names {
+		if strings.TrimSpace(name.DisplayName+name.GivenName+name.MiddleName+name.FamilyName) != "" {
+			return false
+		}
+	}
+	for _, email := range contact.EmailAddresses {
+		if strings.TrimSpace(email.Value) != "" {
+			return false
+		}
+	}
+	for _, phone := range contact.PhoneNumbers {
+		if strings.TrimSpace(phone.Value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HasEmailDomain reports whether any of the contact's email addresses is in
+// one of the given domains or their subdomains. Domains are matched
+// case-insensitively and may be given with a leading "@".
+func HasEmailDomain(contact *people.Person, domains []string) bool {
+	for _, email := range contact.EmailAddresses {
+		_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email.Value)), "@")
+		if !ok {
+			continue
+		}
+		for _, want := range domains {
+			want = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(want)), "@")
+			if domain == want || strings.HasSuffix(domain, "."+want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FieldSource returns the type of the source a field's value came from:
+// CONTACT for data entered in the contact itself, PROFILE for the person's
+// Google profile, DOMAIN_PROFILE for their profile in a Workspace directory,
+// and so on. It returns "" if the backup does not record the source.
+func FieldSource(metadata *people.FieldMetadata) string {
+	if metadata == nil || metadata.Source == nil {
+		return ""
+	}
+	return metadata.Source.Type
+}
+
+// UpdateTime returns when the contact was last updated, according to its
+// CONTACT source metadata, or the zero time if that is unknown.
+func UpdateTime(contact *people.Person) time.Time {
+	if contact.Metadata == nil {
+		return time.Time{}
+	}
+	for _, source := range contact.Metadata.Sources {
+		if source.Type != "CONTACT" || source.UpdateTime == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, source.UpdateTime); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// LinkedProfiles returns the sorted resource names of the Google profiles and
+// other people the contact is linked to, from its metadata: sources of type
+// PROFILE or DOMAIN_PROFILE and linkedPeopleResourceNames. A restored contact
+// is created without these links, since the People API cannot set them.
+func LinkedProfiles(contact *people.Person) []string {
+	if contact.Metadata == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var linked []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			linked = append(linked, name)
+		}
+	}
+	for _, source := range contact.Metadata.Sources {
+		if (source.Type == "PROFILE" || source.Type == "DOMAIN_PROFILE") && source.Id != "" {
+			add("people/" + source.Id)
+		}
+	}
+	for _, name := range contact.Metadata.LinkedPeopleResourceNames {
+		add(name)
+	}
+	sort.Strings(linked)
+	return linked
+}
+
+// LinkedContacts returns the contacts that are linked to Google profiles or
+// other people (see LinkedProfiles).
+func LinkedContacts(contacts []*people.Person) []*people.Person {
+	var linked []*people.Person
+	for _, contact := range contacts {
+		if len(LinkedProfiles(contact)) > 0 {
+			linked = append(linked, contact)
+		}
+	}
+	return linked
+}
@@ -0,0 +1,255 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"google.golang.org/api/people/v1"
+)
+
+// Backups are written and read one contact at a time, so that a backup of a
+// large account is never held in memory twice, once decoded and once as
+// JSON. The output is byte for byte what json.MarshalIndent with two-space
+// indentation produces.
+
+// jsonStream writes a JSON object field by field.
+type jsonStream struct {
+	w      *bufio.Writer
+	fields int
+	err    error
+}
+
+// write writes s unless an earlier write failed.
+func (s *jsonStream) write(data ...[]byte) {
+	for _, d := range data {
+		if s.err != nil {
+			return
+		}
+		_, s.err = s.w.Write(d)
+	}
+}
+
+// key starts the next field of the object.
+func (s *jsonStream) key(name string) {
+	if s.fields == 0 {
+		s.write([]byte("{\n  "))
+	} else {
+		s.write([]byte(",\n  "))
+	}
+	s.fields++
+	s.write(marshalKey(name), []byte(": "))
+}
+
+// field writes a field with a value that is small enough to marshal at once.
+func (s *jsonStream) field(name string, value any) {
+	if s.err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(value, "  ", "  ")
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.key(name)
+	s.write(data)
+}
+
+// close ends the object and flushes the output.
+func (s *jsonStream) close() error {
+	s.write([]byte("\n}"))
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
+
+// streamArray writes a field holding a list, marshaling one item at a time.
+func streamArray[T any](s *jsonStream, name string, items []T) {
+	if len(items) == 0 {
+		// nil marshals as null, an empty list as []
+		s.field(name, items)
+		return
+	}
+
+	s.key(name)
+	s.write([]byte("[\n"))
+	for i, item := range items {
+		if s.err != nil {
+			return
+		}
+		data, err := json.MarshalIndent(item, "    ", "  ")
+		if err != nil {
+			s.err = err
+			return
+		}
+		if i > 0 {
+			s.write([]byte(",\n"))
+		}
+		s.write([]byte("    "), data)
+	}
+	s.write([]byte("\n  ]"))
+}
+
+// streamMap writes a field holding a map, marshaling one entry at a time in
+// key order, as encoding/json does.
+func streamMap[V any](s *jsonStream, name string, entries map[string]V) {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	s.key(name)
+	s.write([]byte("{\n"))
+	for i, key := range keys {
+		if s.err != nil {
+			return
+		}
+		data, err := json.MarshalIndent(entries[key], "    ", "  ")
+		if err != nil {
+			s.err = err
+			return
+		}
+		if i > 0 {
+			s.write([]byte(",\n"))
+		}
+		s.write([]byte("    "), marshalKey(key), []byte(": "), data)
+	}
+	s.write([]byte("\n  }"))
+}
+
+// marshalKey returns an object key as a JSON string.
+func marshalKey(key string) []byte {
+	data, _ := json.Marshal(key)
+	return data
+}
+
+// writeJSONStream writes the backup to w one contact at a time. Fields are
+// in the order of the BackupFile struct, and empty fields tagged omitempty
+// are left out.
+func (b *BackupFile) writeJSONStream(w io.Writer) error {
+	s := &jsonStream{w: bufio.NewWriterSize(w, 64*1024)}
+	s.field("version", b.Version)
+	s.field("created_at", b.CreatedAt)
+	s.field("contact_count", b.ContactCount)
+	s.field("group_count", b.GroupCount)
+	if b.Tag != "" {
+		s.field("tag", b.Tag)
+	}
+	if len(b.Transforms) > 0 {
+		s.field("transforms", b.Transforms)
+	}
+	streamArray(s, "contacts", b.Contacts)
+	if len(b.OtherContacts) > 0 {
+		streamArray(s, "other_contacts", b.OtherContacts)
+	}
+	streamArray(s, "groups", b.Groups)
+	if len(b.GroupMembers) > 0 {
+		streamMap(s, "group_members", b.GroupMembers)
+	}
+	if len(b.Photos) > 0 {
+		streamMap(s, "photos", b.Photos)
+	}
+	if len(b.FallbackPhotos) > 0 {
+		streamMap(s, "fallback_photos", b.FallbackPhotos)
+	}
+	if b.Manifest != nil {
+		s.field("manifest", b.Manifest)
+	}
+	return s.close()
+}
+
+// decodeBackup reads a backup object from dec, decoding contacts one at a
+// time instead of buffering the whole document first. Unknown fields are
+// skipped.
+func decodeBackup(dec *json.Decoder) (*BackupFile, error) {
+	var backup BackupFile
+	fields := map[string]any{
+		"version":         &backup.Version,
+		"created_at":      &backup.CreatedAt,
+		"contact_count":   &backup.ContactCount,
+		"group_count":     &backup.GroupCount,
+		"tag":             &backup.Tag,
+		"transforms":      &backup.Transforms,
+		"groups":          &backup.Groups,
+		"group_members":   &backup.GroupMembers,
+		"photos":          &backup.Photos,
+		"fallback_photos": &backup.FallbackPhotos,
+		"manifest":        &backup.Manifest,
+	}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		switch key {
+		case "contacts":
+			backup.Contacts, err = decodeContacts(dec)
+		case "other_contacts":
+			backup.OtherContacts, err = decodeContacts(dec)
+		default:
+			if field, ok := fields[key]; ok {
+				err = dec.Decode(field)
+			} else {
+				var skip json.RawMessage
+				err = dec.Decode(&skip)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", key, err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	return &backup, nil
+}
+
+// decodeContacts reads a list of contacts, or null, from dec.
+func decodeContacts(dec *json.Decoder) ([]*people.Person, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected a list of contacts")
+	}
+
+	contacts := make([]*people.Person, 0)
+	for dec.More() {
+		var contact *people.Person
+		if err := dec.Decode(&contact); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, contact)
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// expectDelim reads the next token from dec and fails unless it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if got, ok := token.(json.Delim); !ok || got != delim {
+		return fmt.Errorf("expected %q, found %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import "fmt"
+
+// Types of transforms recorded in backups.
+const (
+	TransformFilter      = "filter"
+	TransformDomain      = "domain"
+	TransformIgnoreList  = "ignore_list"
+	TransformSinceBackup = "since_backup"
+
+	// TransformFields leaves fields out of every contact rather than
+	// leaving contacts out
+	TransformFields = "fields"
+)
+
+// Transform records a filter or exclusion applied to a backup's contacts when
+// it was created, so that a partial backup is not mistaken for a complete
+// one when it is restored.
+type Transform struct {
+	// Type is what was applied, e.g. TransformFilter
+	Type string `json:"type"`
+
+	// Description gives the details, such as the filter expression
+	Description string `json:"description,omitempty"`
+
+	// Removed is the number of contacts the transform left out
+	Removed int `json:"removed"`
+}
+
+// String describes the transform on one line.
+func (t Transform) String() string {
+	if t.Type == TransformFields {
+		return fmt.Sprintf("%s %s (left out of every contact)", t.Type, t.Description)
+	}
+	if t.Description == "" {
+		return fmt.Sprintf("%s (%d contacts left out)", t.Type, t.Removed)
+	}
+	return fmt.Sprintf("%s %s (%d contacts left out)", t.Type, t.Description, t.Removed)
+}
+
+// AddTransform records a transform applied to the backup.
+func (b *BackupFile) AddTransform(kind, description string, removed int) {
+	b.Transforms = append(b.Transforms, Transform{Type: kind, Description: description, Removed: removed})
+}
+
+// IsPartial reports whether the backup was filtered when it was created and
+// therefore may not hold every contact of the account.
+func (b *BackupFile) IsPartial() bool {
+	return len(b.Transforms) > 0
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"google.golang.org/api/people/v1"
+)
+
+// UUIDKey is the client data key holding a contact's stable UUID. Unlike the
+// resource name, the UUID survives restores, which recreate contacts under
+// new resource names but keep their client data.
+const UUIDKey = "google-contacts-backup.uuid"
+
+// ContactUUID returns the contact's stable UUID, or "" if it has none.
+func ContactUUID(contact *people.Person) string {
+	value, _ := ClientDataValue(contact, UUIDKey)
+	return value
+}
+
+// AssignUUIDs gives each contact without a UUID a new random one and returns
+// the contacts that were changed.
+func AssignUUIDs(contacts []*people.Person) []*people.Person {
+	var assigned []*people.Person
+	for _, contact := range contacts {
+		if ContactUUID(contact) != "" {
+			continue
+		}
+		SetClientData(contact, UUIDKey, uuid.NewString())
+		assigned = append(assigned, contact)
+	}
+	return assigned
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/api/people/v1"
+)
+
+// vCardLineLimit is the maximum line length in octets before folding (RFC 2425)
+const vCardLineLimit = 75
+
+// VCardOptions controls how a backup is written as vCard.
+type VCardOptions struct {
+	// PlainTextNotes converts HTML notes (TEXT_HTML biographies) to plain text
+	PlainTextNotes bool
+
+	// Version is "3.0" (the default) or "2.1", for older phones
+	Version string
+
+	// Charset is the character set of vCard 2.1 values (see Charsets).
+	// Empty means UTF-8. vCard 3.0 is always UTF-8.
+	Charset string
+
+	// Keys writes the PGP keys and S/MIME certificates stored in custom
+	// fields and client data (see EncryptionKeys) as KEY properties. Only
+	// vCard 3.0 supports it.
+	Keys bool
+}
+
+// WriteVCard writes the backup's contacts as vCard 3.0 (or 2.1) cards to w.
+func (b *BackupFile) WriteVCard(w io.Writer, opts VCardOptions) error {
+	groupNameMap := b.UserGroupNames()
+	bw := bufio.NewWriter(w)
+
+	cards := func(contact *people.Person) []string {
+		return contactToVCard(contact, groupNameMap, opts)
+	}
+	switch opts.Version {
+	case "", "3.0":
+	case "2.1":
+		cm, err := lookupCharset(opts.Charset)
+		if err != nil {
+			return err
+		}
+		enc := vCard21Encoder{cm: cm, charset: "UTF-8"}
+		if cm != nil {
+			enc.charset = strings.ToUpper(opts.Charset)
+		}
+		// Many phones cannot parse folded lines, so vCard 2.1 lines are not
+		// folded; quoted-printable values use soft line breaks instead
+		cards = func(contact *people.Person) []string {
+			return contactToVCard21(contact, opts, enc)
+		}
+	default:
+		return fmt.Errorf("unsupported vCard version %q: must be 3.0 or 2.1", opts.Version)
+	}
+
+	for _, contact := range b.Contacts {
+		for _, line := range cards(contact) {
+			if opts.Version != "2.1" {
+				line = foldVCardLine(line)
+			}
+			if _, err := bw.WriteString(line + "\r\n"); err != nil {
+				return fmt.Errorf("failed to write vCard: %w", err)
+			}
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write vCard: %w", err)
+	}
+
+	return nil
+}
+
+// ContactVCard returns a single contact as a vCard 3.0 card. groupNameMap maps
+// group resource names to the label names written as CATEGORIES.
+func ContactVCard(contact *people.Person, groupNameMap map[string]string, opts VCardOptions) string {
+	var sb strings.Builder
+	for _, line := range contactToVCard(contact, groupNameMap, opts) {
+		sb.WriteString(foldVCardLine(line))
+		sb.WriteString("\r\n")
+	}
+	return sb.String()
+}
+
+// contactToVCard converts a contact to the unfolded lines of one vCard
+func contactToVCard(contact *people.Person, groupNameMap map[string]string, opts VCardOptions) []string {
+	lines := []string{"BEGIN:VCARD", "VERSION:3.0"}
+
+	// FN is required by vCard 3.0, N is required too but may be empty
+	lines = append(lines, "FN:"+escapeVCard(DisplayName(contact)))
+	if len(contact.Names) > 0 {
+		name := contact.Names[0]
+		lines = append(lines, "N:"+strings.Join([]string{
+			escapeVCard(name.FamilyName),
+			escapeVCard(name.GivenName),
+			escapeVCard(name.MiddleName),
+			escapeVCard(name.HonorificPrefix),
+			escapeVCard(name.HonorificSuffix),
+		}, ";"))
+	} else {
+		lines = append(lines, "N:;;;;")
+	}
+
+	for _, nickname := range contact.Nicknames {
+		lines = append(lines, "NICKNAME:"+escapeVCard(nickname.Value))
+	}
+
+	for _, email := range contact.EmailAddresses {
+		lines = append(lines, "EMAIL"+vCardType("INTERNET", email.Type)+":"+escapeVCard(email.Value))
+	}
+
+	for _, phone := range contact.PhoneNumbers {
+		lines = append(lines, "TEL"+vCardType("", phone.Type)+":"+escapeVCard(phone.Value))
+	}
+
+	for _, addr := range contact.Addresses {
+		lines = append(lines, "ADR"+vCardType("", addr.Type)+":"+strings.Join([]string{
+			escapeVCard(addr.PoBox),
+			escapeVCard(addr.ExtendedAddress),
+			escapeVCard(addr.StreetAddress),
+			escapeVCard(addr.City),
+			escapeVCard(addr.Region),
+			escapeVCard(addr.PostalCode),
+			escapeVCard(addr.Country),
+		}, ";"))
+	}
+
+	if len(contact.Organizations) > 0 {
+		org := contact.Organizations[0]
+		if org.Name != "" || org.Department != "" {
+			lines = append(lines, "ORG:"+escapeVCard(org.Name)+";"+escapeVCard(org.Department))
+		}
+		if org.Title != "" {
+			lines = append(lines, "TITLE:"+escapeVCard(org.Title))
+		}
+	}
+
+	if len(contact.Birthdays) > 0 && contact.Birthdays[0].Date != nil {
+		date := contact.Birthdays[0].Date
+		if date.Year > 0 {
+			lines = append(lines, fmt.Sprintf("BDAY:%04d-%02d-%02d", date.Year, date.Month, date.Day))
+		} else {
+			lines = append(lines, fmt.Sprintf("BDAY:--%02d-%02d", date.Month, date.Day))
+		}
+	}
+
+	for _, url := range contact.Urls {
+		lines = append(lines, "URL:"+escapeVCard(url.Value))
+	}
+
+	if len(contact.Biographies) > 0 {
+		if notes := NotesText(contact.Biographies[0], opts.PlainTextNotes); notes != "" {
+			lines = append(lines, "NOTE:"+escapeVCard(notes))
+		}
+	}
+
+	if labels := extractLabels(contact, groupNameMap); len(labels) > 0 {
+		escaped := make([]string, len(labels))
+		for i, label := range labels {
+			escaped[i] = escapeVCard(label)
+		}
+		lines = append(lines, "CATEGORIES:"+strings.Join(escaped, ","))
+	}
+
+	for _, photo := range contact.Photos {
+		if !photo.Default && photo.Url != "" {
+			lines = append(lines, "PHOTO;VALUE=URI:"+photo.Url)
+			break
+		}
+	}
+
+	if opts.Keys {
+		lines = append(lines, vCardKeyLines(contact)...)
+	}
+
+	if id := ContactUUID(contact); id != "" {
+		lines = append(lines, "UID:urn:uuid:"+id)
+	} else if contact.ResourceName != "" {
+		lines = append(lines, "UID:"+escapeVCard(contact.ResourceName))
+	}
+
+	return append(lines, "END:VCARD")
+}
+
+// vCardType builds the TYPE parameter from a fixed type and an API type value
+func vCardType(fixed, apiType string) string {
+	types := make([]string, 0, 2)
+	if fixed != "" {
+		types = append(types, fixed)
+	}
+	switch strings.ToLower(apiType) {
+	case "home", "work", "pager":
+		types = append(types, strings.ToUpper(apiType))
+	case "mobile":
+		types = append(types, "CELL")
+	case "homefax":
+		types = append(types, "HOME", "FAX")
+	case "workfax":
+		types = append(types, "WORK", "FAX")
+	case "main":
+		types = append(types, "PREF")
+	}
+	if len(types) == 0 {
+		return ""
+	}
+	return ";TYPE=" + strings.Join(types, ",")
+}
+
+// vCardEscaper escapes text values as required by RFC 2426
+var vCardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	",", `\,`,
+	";", `\;`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// escapeVCard escapes a text value for use in a vCard property
+func escapeVCard(value string) string {
+	return vCardEscaper.Replace(value)
+}
+
+// foldVCardLine splits a line into 75-octet chunks joined by CRLF and a space,
+// never splitting a UTF-8 sequence
+func foldVCardLine(line string) string {
+	if len(line) <= vCardLineLimit {
+		return line
+	}
+
+	var sb strings.Builder
+	limit := vCardLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = vCardLineLimit - 1
+	}
+	sb.WriteString(line)
+
+	return sb.String()
+}
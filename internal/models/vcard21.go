@@ -0,0 +1,168 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"google.golang.org/api/people/v1"
+)
+
+// qpLineLimit is the longest quoted-printable line, excluding the soft
+// line break (RFC 2045)
+const qpLineLimit = 75
+
+// vCard21Encoder writes vCard 2.1 properties in a character set
+type vCard21Encoder struct {
+	// cm is the code page values are converted to, or nil for UTF-8
+	cm      *charmap.Charmap
+	charset string
+}
+
+// contactToVCard21 converts a contact to the lines of one vCard 2.1 card.
+// vCard 2.1 is the version older phones and car head units understand: types
+// are bare parameters (TEL;CELL), and values containing line breaks or
+// non-ASCII characters are quoted-printable encoded with a CHARSET parameter.
+func contactToVCard21(contact *people.Person, opts VCardOptions, enc vCard21Encoder) []string {
+	lines := []string{"BEGIN:VCARD", "VERSION:2.1"}
+
+	if len(contact.Names) > 0 {
+		name := contact.Names[0]
+		lines = append(lines, enc.property("N", name.FamilyName, name.GivenName, name.MiddleName,
+			name.HonorificPrefix, name.HonorificSuffix))
+	} else {
+		lines = append(lines, "N:;;;;")
+	}
+	lines = append(lines, enc.property("FN", DisplayName(contact)))
+
+	for i, phone := range contact.PhoneNumbers {
+		params := vCard21PhoneType(phone.Type)
+		if i == 0 {
+			params = append(params, "PREF")
+		}
+		lines = append(lines, enc.property(strings.Join(append([]string{"TEL"}, params...), ";"), phone.Value))
+	}
+
+	for _, email := range contact.EmailAddresses {
+		lines = append(lines, enc.property("EMAIL;INTERNET", email.Value))
+	}
+
+	for _, addr := range contact.Addresses {
+		name := "ADR"
+		switch strings.ToLower(addr.Type) {
+		case "home":
+			name = "ADR;HOME"
+		case "work":
+			name = "ADR;WORK"
+		}
+		lines = append(lines, enc.property(name, addr.PoBox, addr.ExtendedAddress, addr.StreetAddress,
+			addr.City, addr.Region, addr.PostalCode, addr.Country))
+	}
+
+	if len(contact.Organizations) > 0 {
+		org := contact.Organizations[0]
+		if org.Name != "" || org.Department != "" {
+			lines = append(lines, enc.property("ORG", org.Name, org.Department))
+		}
+		if org.Title != "" {
+			lines = append(lines, enc.property("TITLE", org.Title))
+		}
+	}
+
+	if len(contact.Birthdays) > 0 && contact.Birthdays[0].Date != nil && contact.Birthdays[0].Date.Year > 0 {
+		date := contact.Birthdays[0].Date
+		lines = append(lines, fmt.Sprintf("BDAY:%04d%02d%02d", date.Year, date.Month, date.Day))
+	}
+
+	for _, url := range contact.Urls {
+		lines = append(lines, enc.property("URL", url.Value))
+	}
+
+	if len(contact.Biographies) > 0 {
+		if notes := NotesText(contact.Biographies[0], opts.PlainTextNotes); notes != "" {
+			lines = append(lines, enc.property("NOTE", notes))
+		}
+	}
+
+	if id := ContactUUID(contact); id != "" {
+		lines = append(lines, "UID:urn:uuid:"+id)
+	}
+
+	return append(lines, "END:VCARD")
+}
+
+// vCard21PhoneType maps an API phone type to vCard 2.1 TEL parameters
+func vCard21PhoneType(apiType string) []string {
+	switch strings.ToLower(apiType) {
+	case "mobile":
+		return []string{"CELL"}
+	case "home":
+		return []string{"HOME", "VOICE"}
+	case "work":
+		return []string{"WORK", "VOICE"}
+	case "homefax":
+		return []string{"HOME", "FAX"}
+	case "workfax":
+		return []string{"WORK", "FAX"}
+	case "pager":
+		return []string{"PAGER"}
+	default:
+		return []string{"VOICE"}
+	}
+}
+
+// property formats a property with one or more components, which are joined
+// with semicolons. Semicolons inside components are escaped.
+func (e vCard21Encoder) property(name string, components ...string) string {
+	escaped := make([]string, len(components))
+	for i, component := range components {
+		escaped[i] = strings.ReplaceAll(component, ";", `\;`)
+	}
+	value := strings.Join(escaped, ";")
+	value = strings.ReplaceAll(strings.ReplaceAll(value, "\r\n", "\n"), "\n", "\r\n")
+
+	if isPlainASCII(value) {
+		return name + ":" + value
+	}
+
+	data := []byte(value)
+	if e.cm != nil {
+		data = encodeCharset(value, e.cm)
+	}
+	prefix := name + ";CHARSET=" + e.charset + ";ENCODING=QUOTED-PRINTABLE:"
+	return prefix + quotedPrintable(data, len(prefix))
+}
+
+// isPlainASCII reports whether a value can be written without encoding
+func isPlainASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < ' ' || value[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// quotedPrintable encodes data, inserting soft line breaks so that no line is
+// longer than qpLineLimit. used is the length already taken on the first line.
+func quotedPrintable(data []byte, used int) string {
+	var sb strings.Builder
+	lineLen := used
+	for i, b := range data {
+		token := string(b)
+		last := i == len(data)-1
+		if b < '!' || b > '~' || b == '=' {
+			// Spaces only need encoding at the end of a line
+			if b != ' ' || last {
+				token = fmt.Sprintf("=%02X", b)
+			}
+		}
+		if lineLen+len(token) > qpLineLimit {
+			sb.WriteString("=\r\n")
+			lineLen = 0
+		}
+		sb.WriteString(token)
+		lineLen += len(token)
+	}
+	return sb.String()
+}
@@ -0,0 +1,366 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+)
+
+// maxVCardLineLength is the longest line ReadVCard accepts, large enough for
+// an embedded photo
+const maxVCardLineLength = 16 << 20
+
+// vCardProperty is one property of a vCard, unfolded and decoded
+type vCardProperty struct {
+	name  string
+	types map[string]bool
+	value string
+}
+
+// ReadVCard reads the cards of a vCard 2.1, 3.0 or 4.0 file, such as one
+// written by WriteVCard or exported by a phone, into a backup. CATEGORIES
+// become labels as in ReadCSV; photos and properties without a People API
+// equivalent are skipped. A UID written by WriteVCard restores the contact's
+// resource name or UUID.
+func ReadVCard(r io.Reader) (*BackupFile, error) {
+	lines, err := unfoldVCard(r)
+	if err != nil {
+		return nil, err
+	}
+
+	im := newImporter("vcf")
+	var card []vCardProperty
+	inCard := false
+	cards := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		property, err := parseVCardLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		switch {
+		case property.name == "BEGIN" && strings.EqualFold(property.value, "VCARD"):
+			inCard, card = true, nil
+		case property.name == "END" && strings.EqualFold(property.value, "VCARD"):
+			if !inCard {
+				return nil, fmt.Errorf("line %d: END:VCARD without BEGIN:VCARD", i+1)
+			}
+			cards++
+			contact, labels, err := vCardToContact(card)
+			if err != nil {
+				return nil, fmt.Errorf("card %d: %w", cards, err)
+			}
+			im.add(contact, cards, labels)
+			inCard = false
+		case inCard:
+			card = append(card, property)
+		}
+	}
+	if inCard {
+		return nil, fmt.Errorf("invalid vCard file: the last card has no END:VCARD")
+	}
+	if cards == 0 {
+		return nil, fmt.Errorf("invalid vCard file: no cards found")
+	}
+
+	return im.backup, nil
+}
+
+// unfoldVCard returns the logical lines of a vCard file: lines folded with a
+// leading space or tab are joined, and so are quoted-printable values
+// continued with a soft line break.
+func unfoldVCard(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxVCardLineLength)
+
+	var lines []string
+	softBreak := false
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		last := len(lines) - 1
+		switch {
+		case softBreak:
+			lines[last] = strings.TrimSuffix(lines[last], "=") + line
+		case last >= 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			lines[last] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+		last = len(lines) - 1
+		softBreak = strings.HasSuffix(lines[last], "=") && isQuotedPrintable(lines[last])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vCard file: %w", err)
+	}
+	return lines, nil
+}
+
+// isQuotedPrintable reports whether a property line's parameters mark its
+// value as quoted-printable
+func isQuotedPrintable(line string) bool {
+	params, _, _ := strings.Cut(line, ":")
+	return strings.Contains(strings.ToUpper(params), "QUOTED-PRINTABLE")
+}
+
+// parseVCardLine splits a logical line into its property name, TYPE
+// parameters and decoded value.
+func parseVCardLine(line string) (vCardProperty, error) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return vCardProperty{}, fmt.Errorf("missing ':' in %q", line)
+	}
+
+	params := strings.Split(head, ";")
+	name := strings.ToUpper(params[0])
+	// Properties may be grouped, as in item1.EMAIL
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	property := vCardProperty{name: name, types: make(map[string]bool)}
+	var encoding, charset string
+	for _, param := range params[1:] {
+		key, val, hasValue := strings.Cut(param, "=")
+		key = strings.ToUpper(key)
+		switch {
+		case !hasValue:
+			// vCard 2.1 gives types and the encoding as bare parameters
+			if key == "QUOTED-PRINTABLE" || key == "BASE64" || key == "B" {
+				encoding = key
+			} else {
+				property.types[key] = true
+			}
+		case key == "TYPE":
+			for _, t := range strings.Split(strings.Trim(val, `"`), ",") {
+				property.types[strings.ToUpper(t)] = true
+			}
+		case key == "ENCODING":
+			encoding = strings.ToUpper(val)
+		case key == "CHARSET":
+			charset = val
+		case key == "PREF":
+			property.types["PREF"] = true
+		}
+	}
+
+	switch encoding {
+	case "QUOTED-PRINTABLE":
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(value)))
+		if err != nil {
+			return vCardProperty{}, fmt.Errorf("invalid quoted-printable value of %s: %w", name, err)
+		}
+		value = string(decoded)
+	case "BASE64", "B":
+		// Embedded binary values such as photos are not read
+		value = ""
+	}
+	if charset != "" {
+		cm, err := lookupCharset(charset)
+		if err != nil {
+			return vCardProperty{}, fmt.Errorf("%s: %w", name, err)
+		}
+		if cm != nil {
+			decoded, err := cm.NewDecoder().String(value)
+			if err != nil {
+				return vCardProperty{}, fmt.Errorf("%s: invalid %s value: %w", name, charset, err)
+			}
+			value = decoded
+		}
+	}
+	property.value = value
+	return property, nil
+}
+
+// vCardToContact converts the properties of a card to a contact and the
+// names of its labels.
+func vCardToContact(card []vCardProperty) (*people.Person, []string, error) {
+	contact := &people.Person{}
+	var formattedName string
+	var labels []string
+
+	organization := func() *people.Organization {
+		if len(contact.Organizations) == 0 {
+			contact.Organizations = []*people.Organization{{}}
+		}
+		return contact.Organizations[0]
+	}
+
+	for _, property := range card {
+		switch property.name {
+		case "FN":
+			formattedName = unescapeVCard(property.value)
+		case "N":
+			parts := splitVCardValue(property.value, ';', 5)
+			name := &people.Name{
+				FamilyName:      parts[0],
+				GivenName:       parts[1],
+				MiddleName:      parts[2],
+				HonorificPrefix: parts[3],
+				HonorificSuffix: parts[4],
+			}
+			if strings.Join(parts, "") != "" {
+				contact.Names = []*people.Name{name}
+			}
+		case "NICKNAME":
+			for _, nickname := range splitVCardValue(property.value, ',', 0) {
+				if nickname != "" {
+					contact.Nicknames = append(contact.Nicknames, &people.Nickname{Value: nickname})
+				}
+			}
+		case "EMAIL":
+			if value := unescapeVCard(property.value); value != "" {
+				contact.EmailAddresses = append(contact.EmailAddresses, &people.EmailAddress{Type: vCardAPIType(property.types), Value: value})
+			}
+		case "TEL":
+			if value := unescapeVCard(strings.TrimPrefix(property.value, "tel:")); value != "" {
+				contact.PhoneNumbers = append(contact.PhoneNumbers, &people.PhoneNumber{Type: vCardAPIType(property.types), Value: value})
+			}
+		case "ADR":
+			parts := splitVCardValue(property.value, ';', 7)
+			if strings.Join(parts, "") != "" {
+				contact.Addresses = append(contact.Addresses, &people.Address{
+					Type:            vCardAPIType(property.types),
+					PoBox:           parts[0],
+					ExtendedAddress: parts[1],
+					StreetAddress:   parts[2],
+					City:            parts[3],
+					Region:          parts[4],
+					PostalCode:      parts[5],
+					Country:         parts[6],
+				})
+			}
+		case "ORG":
+			parts := splitVCardValue(property.value, ';', 2)
+			organization().Name = parts[0]
+			organization().Department = parts[1]
+		case "TITLE":
+			organization().Title = unescapeVCard(property.value)
+		case "BDAY", "ANNIVERSARY", "X-ANNIVERSARY":
+			date, err := parseVCardDate(property.value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %w", property.name, err)
+			}
+			if property.name == "BDAY" {
+				contact.Birthdays = []*people.Birthday{{Date: date}}
+			} else {
+				contact.Events = append(contact.Events, &people.Event{Type: "anniversary", Date: date})
+			}
+		case "URL":
+			if value := unescapeVCard(property.value); value != "" {
+				contact.Urls = append(contact.Urls, &people.Url{Type: vCardAPIType(property.types), Value: value})
+			}
+		case "NOTE":
+			if value := unescapeVCard(property.value); value != "" {
+				contact.Biographies = []*people.Biography{{Value: value, ContentType: "TEXT_PLAIN"}}
+			}
+		case "CATEGORIES":
+			for _, label := range splitVCardValue(property.value, ',', 0) {
+				if label = strings.TrimSpace(label); label != "" {
+					labels = append(labels, label)
+				}
+			}
+		case "UID":
+			uid := unescapeVCard(property.value)
+			if id, ok := strings.CutPrefix(uid, "urn:uuid:"); ok {
+				SetClientData(contact, UUIDKey, id)
+			} else if strings.HasPrefix(uid, "people/") {
+				contact.ResourceName = uid
+			}
+		}
+	}
+
+	if len(contact.Names) == 0 && formattedName != "" {
+		contact.Names = []*people.Name{{DisplayName: formattedName, UnstructuredName: formattedName}}
+	}
+	return contact, labels, nil
+}
+
+// vCardAPIType returns the People API type for the TYPE parameters of an
+// EMAIL, TEL, ADR or URL property, as WriteVCard writes them
+func vCardAPIType(types map[string]bool) string {
+	switch {
+	case types["FAX"] && types["HOME"]:
+		return "homeFax"
+	case types["FAX"] && types["WORK"]:
+		return "workFax"
+	case types["FAX"]:
+		return "otherFax"
+	case types["CELL"]:
+		return "mobile"
+	case types["PAGER"]:
+		return "pager"
+	case types["HOME"]:
+		return "home"
+	case types["WORK"]:
+		return "work"
+	case types["OTHER"]:
+		return "other"
+	case types["PREF"]:
+		return "main"
+	}
+	return ""
+}
+
+// splitVCardValue splits a structured value at unescaped separators and
+// unescapes the parts. With n > 0, the result has exactly n parts.
+func splitVCardValue(value string, sep byte, n int) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			current.WriteByte(value[i])
+			current.WriteByte(value[i+1])
+			i++
+		case value[i] == sep:
+			parts = append(parts, unescapeVCard(current.String()))
+			current.Reset()
+		default:
+			current.WriteByte(value[i])
+		}
+	}
+	parts = append(parts, unescapeVCard(current.String()))
+
+	if n > 0 {
+		for len(parts) < n {
+			parts = append(parts, "")
+		}
+		parts = parts[:n]
+	}
+	return parts
+}
+
+// vCardUnescaper reverses escapeVCard
+var vCardUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	`\,`, ",",
+	`\;`, ";",
+	`\n`, "\n",
+	`\N`, "\n",
+)
+
+// unescapeVCard unescapes a text value of a vCard property
+func unescapeVCard(value string) string {
+	return vCardUnescaper.Replace(value)
+}
+
+// parseVCardDate parses a vCard date: "2006-01-02", "20060102", or
+// "--01-02" and "--0102" without a year. A time after "T" is ignored.
+func parseVCardDate(value string) (*people.Date, error) {
+	value, _, _ = strings.Cut(strings.TrimSpace(value), "T")
+	if len(value) == 8 && !strings.Contains(value, "-") {
+		value = value[:4] + "-" + value[4:6] + "-" + value[6:]
+	} else if len(value) == 6 && strings.HasPrefix(value, "--") {
+		value = "--" + value[2:4] + "-" + value[4:]
+	}
+	return parseCSVDate(value)
+}
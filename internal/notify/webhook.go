@@ -0,0 +1,136 @@
+// Package notify delivers event notifications to configured webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/config"
+)
+
+const (
+	// SchemaVersion is the version of the event payload format. It changes
+	// whenever fields are removed or change meaning.
+	SchemaVersion = "1"
+
+	// Header names sent with every delivery
+	HeaderEvent         = "X-Contacts-Backup-Event"
+	HeaderSchemaVersion = "X-Contacts-Backup-Schema-Version"
+	HeaderTimestamp     = "X-Contacts-Backup-Timestamp"
+	HeaderSignature     = "X-Contacts-Backup-Signature"
+
+	// deliveryTimeout bounds each webhook request
+	deliveryTimeout = 10 * time.Second
+)
+
+// Event is the JSON payload posted to webhooks.
+type Event struct {
+	SchemaVersion string         `json:"schema_version"`
+	ID            string         `json:"id"`
+	Type          string         `json:"type"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Data          map[string]any `json:"data,omitempty"`
+}
+
+// NewEvent creates an event of the given type with a random ID.
+func NewEvent(eventType string, data map[string]any) Event {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return Event{
+		SchemaVersion: SchemaVersion,
+		ID:            hex.EncodeToString(id),
+		Type:          eventType,
+		Timestamp:     time.Now().UTC(),
+		Data:          data,
+	}
+}
+
+// Sign returns the signature header value for a payload: the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with secret, prefixed with "sha256=".
+// Including the timestamp lets receivers reject replayed deliveries.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for the payload. It is provided
+// for receivers written in Go.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Send posts the event to every webhook subscribed to its type. Delivery
+// failures are returned rather than aborting, so one broken endpoint does
+// not prevent the others from being notified.
+func Send(ctx context.Context, hooks []config.Webhook, event Event) []error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return []error{fmt.Errorf("failed to marshal event: %w", err)}
+	}
+
+	var errs []error
+	for _, hook := range hooks {
+		if !subscribed(hook, event.Type) {
+			continue
+		}
+		if err := deliver(ctx, hook, event, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", hook.URL, err))
+		}
+	}
+	return errs
+}
+
+func subscribed(hook config.Webhook, eventType string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, e := range hook.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func deliver(ctx context.Context, hook config.Webhook, event Event, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(event.Timestamp.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderSchemaVersion, SchemaVersion)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if secret := hook.SigningSecret(); secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
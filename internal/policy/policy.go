@@ -0,0 +1,132 @@
+// Package policy checks the contents of a backup against data quality rules.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"google.golang.org/api/people/v1"
+)
+
+// Rule limits how many contacts may fail a check.
+type Rule struct {
+	// Check is the name of the check (see Checks)
+	Check string `json:"check"`
+
+	// Max is the number of contacts allowed to fail the check. Zero means
+	// every contact must pass.
+	Max int `json:"max,omitempty"`
+}
+
+// Result is the outcome of one rule.
+type Result struct {
+	Rule
+
+	// Description explains the check
+	Description string
+
+	// Violations are the contacts that failed the check
+	Violations []*people.Person
+}
+
+// Passed reports whether no more contacts failed than the rule allows.
+func (r Result) Passed() bool {
+	return len(r.Violations) <= r.Max
+}
+
+// check is a test that a single contact fails when it returns true
+type check struct {
+	description string
+	fails       func(*people.Person) bool
+}
+
+// checks are the available checks, by name
+var checks = map[string]check{
+	"missing_name": {"contact has no name", func(p *people.Person) bool {
+		for _, name := range p.Names {
+			if strings.TrimSpace(name.DisplayName+name.GivenName+name.FamilyName) != "" {
+				return false
+			}
+		}
+		return true
+	}},
+	"missing_contact_info": {"contact has no email address or phone number", func(p *people.Person) bool {
+		return len(p.EmailAddresses) == 0 && len(p.PhoneNumbers) == 0
+	}},
+	"email_whitespace": {"an email address contains whitespace", func(p *people.Person) bool {
+		for _, email := range p.EmailAddresses {
+			if strings.IndexFunc(email.Value, unicode.IsSpace) >= 0 {
+				return true
+			}
+		}
+		return false
+	}},
+	"invalid_email": {"an email address is not of the form name@domain", func(p *people.Person) bool {
+		for _, email := range p.EmailAddresses {
+			local, domain, ok := strings.Cut(strings.TrimSpace(email.Value), "@")
+			if !ok || local == "" || domain == "" || strings.Contains(domain, "@") {
+				return true
+			}
+		}
+		return false
+	}},
+	"invalid_phone": {"a phone number contains no digits", func(p *people.Person) bool {
+		for _, phone := range p.PhoneNumbers {
+			if strings.IndexFunc(phone.Value, unicode.IsDigit) < 0 {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+// Checks returns the names of the available checks, sorted.
+func Checks() []string {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describe returns a one-line description of a check.
+func Describe(name string) string {
+	return checks[name].description
+}
+
+// Validate returns an error if a rule names an unknown check or allows a
+// negative number of failures.
+func Validate(rules []Rule) error {
+	for _, rule := range rules {
+		if _, ok := checks[rule.Check]; !ok {
+			return fmt.Errorf("unknown policy check %q: must be one of %s", rule.Check, strings.Join(Checks(), ", "))
+		}
+		if rule.Max < 0 {
+			return fmt.Errorf("policy check %q: max must not be negative", rule.Check)
+		}
+	}
+	return nil
+}
+
+// Evaluate runs each rule against the contacts, in order.
+func Evaluate(contacts []*people.Person, rules []Rule) ([]Result, error) {
+	if err := Validate(rules); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(rules))
+	for _, rule := range rules {
+		c := checks[rule.Check]
+		result := Result{Rule: rule, Description: c.description}
+		for _, contact := range contacts {
+			if c.fails(contact) {
+				result.Violations = append(result.Violations, contact)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
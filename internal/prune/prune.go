@@ -0,0 +1,170 @@
+// Package prune decides which timestamped backups in a directory to keep
+// under a retention policy and which to delete.
+package prune
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// backupName matches the default names of the backup command, e.g.
+// contacts-20240601-020000.json or contacts-20240601-020000.json.zst.age,
+// and contacts-20240601-020000 for the dir format
+var backupName = regexp.MustCompile(`^contacts-(\d{8}-\d{6})(\.(json|csv)(\.gz|\.zst)?(\.age)?)?$`)
+
+// timestampLayout is the layout of the timestamp in backup file names
+const timestampLayout = "20060102-150405"
+
+// Policy says how many backups to keep. Each rule keeps the newest backup of
+// each of that many most recent periods that have a backup; a backup is kept
+// if any rule keeps it.
+type Policy struct {
+	// Last keeps the newest backups regardless of when they were taken
+	Last int
+
+	// Daily, Weekly and Monthly keep one backup per day, ISO week and
+	// calendar month
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// Empty reports whether the policy keeps nothing.
+func (p Policy) Empty() bool {
+	return p.Last <= 0 && p.Daily <= 0 && p.Weekly <= 0 && p.Monthly <= 0
+}
+
+// Backup is a backup file, or dir-format backup directory, found in a
+// directory.
+type Backup struct {
+	// Path is the file's path
+	Path string
+
+	// Dir is true for a backup written in the dir format, which is deleted
+	// with everything in it
+	Dir bool
+
+	// Time is when the backup was taken, from its file name
+	Time time.Time
+
+	// Reasons are the rules that keep the backup, e.g. "daily"
+	Reasons []string
+}
+
+// ParseTime returns the time a backup name says it was taken, in the local
+// time zone, or false for names that are not backup names.
+func ParseTime(name string) (time.Time, bool) {
+	match := backupName.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(timestampLayout, match[1], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Scan lists the backups in dir, newest first: files with backup names, and
+// directories named like contacts-20240601-020000 that hold a dir-format
+// backup. Other files and subdirectories are ignored.
+func Scan(dir string) ([]Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		t, ok := ParseTime(entry.Name())
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		isDir := filepath.Ext(entry.Name()) == ""
+		switch {
+		case isDir && entry.IsDir() && models.IsBackupDir(path):
+		case !isDir && entry.Type().IsRegular():
+		default:
+			continue
+		}
+		backups = append(backups, Backup{Path: path, Dir: isDir, Time: t})
+	}
+	sortNewestFirst(backups)
+	return backups, nil
+}
+
+// Plan splits backups into those the policy keeps and those to delete, both
+// newest first. Kept backups carry the rules that keep them. Backups taken at
+// the same time, such as the .json and .csv files of one run, count as one
+// backup: they are kept or deleted together.
+func Plan(backups []Backup, policy Policy) (keep, remove []Backup) {
+	backups = append([]Backup(nil), backups...)
+	sortNewestFirst(backups)
+
+	var times []time.Time
+	for i, backup := range backups {
+		if i == 0 || !backup.Time.Equal(backups[i-1].Time) {
+			times = append(times, backup.Time)
+		}
+	}
+
+	rules := []struct {
+		name   string
+		count  int
+		period func(time.Time) string
+	}{
+		{"last", policy.Last, func(t time.Time) string { return t.String() }},
+		{"daily", policy.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", policy.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{"monthly", policy.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	reasons := make([][]string, len(times))
+	for _, rule := range rules {
+		seen := make(map[string]bool)
+		for i, t := range times {
+			if len(seen) >= rule.count {
+				break
+			}
+			period := rule.period(t)
+			if seen[period] {
+				continue
+			}
+			seen[period] = true
+			reasons[i] = append(reasons[i], rule.name)
+		}
+	}
+
+	i := 0
+	for _, backup := range backups {
+		if !backup.Time.Equal(times[i]) {
+			i++
+		}
+		backup.Reasons = reasons[i]
+		if len(backup.Reasons) > 0 {
+			keep = append(keep, backup)
+		} else {
+			remove = append(remove, backup)
+		}
+	}
+	return keep, remove
+}
+
+// sortNewestFirst orders backups by time, newest first, then by path.
+func sortNewestFirst(backups []Backup) {
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.After(backups[j].Time)
+		}
+		return backups[i].Path < backups[j].Path
+	})
+}
@@ -0,0 +1,203 @@
+package prune
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// backups returns a Backup for each file name, with the time it names.
+func backups(t *testing.T, names ...string) []Backup {
+	t.Helper()
+	var result []Backup
+	for _, name := range names {
+		taken, ok := ParseTime(name)
+		if !ok {
+			t.Fatalf("%s is not a backup file name", name)
+		}
+		result = append(result, Backup{Path: name, Time: taken})
+	}
+	return result
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		backups []string
+		policy  Policy
+		// keep maps the kept backups to their rules; all others are removed
+		keep map[string]string
+	}{
+		{
+			name: "overlapping rules",
+			backups: []string{
+				"contacts-20240301-020000.json",
+				"contacts-20240415-020000.json",
+				"contacts-20240520-020000.json",
+				"contacts-20240531-020000.json",
+				"contacts-20240601-020000.json",
+				"contacts-20240602-020000.json",
+				"contacts-20240603-020000.json",
+				"contacts-20240603-140000.json",
+			},
+			policy: Policy{Last: 2, Daily: 3, Weekly: 3, Monthly: 3},
+			keep: map[string]string{
+				"contacts-20240603-140000.json": "last, daily, weekly, monthly",
+				"contacts-20240603-020000.json": "last",
+				"contacts-20240602-020000.json": "daily, weekly",
+				"contacts-20240601-020000.json": "daily",
+				"contacts-20240531-020000.json": "monthly",
+				"contacts-20240520-020000.json": "weekly",
+				"contacts-20240415-020000.json": "monthly",
+			},
+		},
+		{
+			name: "ISO week 53 spans the new year",
+			backups: []string{
+				"contacts-20201227-020000.json", // Sunday of 2020-W52
+				"contacts-20201231-020000.json", // Thursday of 2020-W53
+				"contacts-20210103-020000.json", // Sunday of 2020-W53
+			},
+			policy: Policy{Weekly: 2},
+			keep: map[string]string{
+				"contacts-20210103-020000.json": "weekly",
+				"contacts-20201227-020000.json": "weekly",
+			},
+		},
+		{
+			name: "ISO week 1 starts in December",
+			backups: []string{
+				"contacts-20241228-020000.json", // Saturday of 2024-W52
+				"contacts-20241229-020000.json", // Sunday of 2024-W52
+				"contacts-20241230-020000.json", // Monday of 2025-W01
+				"contacts-20250102-020000.json", // Thursday of 2025-W01
+			},
+			policy: Policy{Weekly: 2, Monthly: 1},
+			keep: map[string]string{
+				"contacts-20250102-020000.json": "weekly, monthly",
+				"contacts-20241229-020000.json": "weekly",
+			},
+		},
+		{
+			name: "files of one run are kept together",
+			backups: []string{
+				"contacts-20240602-020000.json",
+				"contacts-20240603-020000.csv",
+				"contacts-20240603-020000.json",
+				"contacts-20240603-020000.json.zst.age",
+			},
+			policy: Policy{Last: 1},
+			keep: map[string]string{
+				"contacts-20240603-020000.csv":          "last",
+				"contacts-20240603-020000.json":         "last",
+				"contacts-20240603-020000.json.zst.age": "last",
+			},
+		},
+		{
+			name: "files of one run are removed together",
+			backups: []string{
+				"contacts-20240603-020000.csv",
+				"contacts-20240603-020000.json.gz",
+				"contacts-20240603-140000.json",
+			},
+			policy: Policy{Daily: 1},
+			keep: map[string]string{
+				"contacts-20240603-140000.json": "daily",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, remove := Plan(backups(t, tt.backups...), tt.policy)
+
+			got := make(map[string]string)
+			for _, backup := range keep {
+				got[backup.Path] = strings.Join(backup.Reasons, ", ")
+			}
+			if !reflect.DeepEqual(got, tt.keep) {
+				t.Errorf("kept %v, want %v", got, tt.keep)
+			}
+			if len(keep)+len(remove) != len(tt.backups) {
+				t.Errorf("kept %d and removed %d of %d backups", len(keep), len(remove), len(tt.backups))
+			}
+			for _, backup := range remove {
+				if _, ok := tt.keep[backup.Path]; ok || len(backup.Reasons) > 0 {
+					t.Errorf("removed %s (%v)", backup.Path, backup.Reasons)
+				}
+			}
+			for i := 1; i < len(remove); i++ {
+				if remove[i].Time.After(remove[i-1].Time) {
+					t.Errorf("removed backups are not newest first: %s after %s", remove[i].Path, remove[i-1].Path)
+				}
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"contacts-20240601-020000.json",
+		"contacts-20240602-020000.csv",
+		"contacts-20240603-020000.json.gz",
+		"contacts-20240604-020000.json.zst.age",
+		// Not backups, or not named by the backup command
+		"notes.txt",
+		"contacts.json",
+		"contacts-20240605-020000.json.bak",
+		"contacts-20240605-020000-post-restore.json",
+		"pre-restore-20240605-020000.json",
+		"contacts-20241345-020000.json",
+	}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{
+		"contacts-20240606-020000.json",
+		"contacts-20240607-020000",
+		"contacts-20240608-020000",
+	} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only a directory holding a dir-format backup is a backup
+	if err := os.WriteFile(filepath.Join(dir, "contacts-20240608-020000", "manifest.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A file with a dir-format name is not
+	if err := os.WriteFile(filepath.Join(dir, "contacts-20240609-020000"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	var got []string
+	for _, backup := range found {
+		name := filepath.Base(backup.Path)
+		if backup.Dir {
+			name += "/"
+		}
+		got = append(got, name)
+	}
+	want := []string{
+		"contacts-20240608-020000/",
+		"contacts-20240604-020000.json.zst.age",
+		"contacts-20240603-020000.json.gz",
+		"contacts-20240602-020000.csv",
+		"contacts-20240601-020000.json",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan = %v, want %v", got, want)
+	}
+
+	if _, err := Scan(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Scan of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,20 @@
+// Package reconcile decides how backup data is merged into existing contacts.
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// ValidateFields returns an error if any name is not a multi-value person
+// field. Frozen fields are checked with it when the config file is loaded;
+// Merge is what keeps them unchanged.
+func ValidateFields(fields []string) error {
+	for _, field := range fields {
+		if !models.IsPersonField(field) {
+			return fmt.Errorf("unknown person field %q", field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package reconcile
+
+import (
+	"slices"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Match pairs backup contacts with the live contacts they were restored
+// from or to, first by resource name, then by stable UUID (see
+// models.ContactUUID) and then by external ID (type and value). Each live
+// contact is matched at most once. Backup contacts without a match are
+// absent from the result.
+func Match(backup, live []*people.Person) map[*people.Person]*people.Person {
+	byResourceName := make(map[string]*people.Person, len(live))
+	byUUID := make(map[string]*people.Person)
+	byExternalID := make(map[string]*people.Person)
+	for _, contact := range live {
+		byResourceName[contact.ResourceName] = contact
+		if id := models.ContactUUID(contact); id != "" {
+			if _, ok := byUUID[id]; !ok {
+				byUUID[id] = contact
+			}
+		}
+		for _, id := range contact.ExternalIds {
+			key := externalIDKey(id)
+			if _, ok := byExternalID[key]; !ok {
+				byExternalID[key] = contact
+			}
+		}
+	}
+
+	matches := make(map[*people.Person]*people.Person)
+	used := make(map[*people.Person]bool)
+	for _, contact := range backup {
+		if existing, ok := byResourceName[contact.ResourceName]; ok && contact.ResourceName != "" && !used[existing] {
+			matches[contact] = existing
+			used[existing] = true
+		}
+	}
+	for _, contact := range backup {
+		if _, ok := matches[contact]; ok {
+			continue
+		}
+		if existing, ok := byUUID[models.ContactUUID(contact)]; ok && !used[existing] {
+			matches[contact] = existing
+			used[existing] = true
+		}
+	}
+	for _, contact := range backup {
+		if _, ok := matches[contact]; ok {
+			continue
+		}
+		for _, id := range contact.ExternalIds {
+			if existing, ok := byExternalID[externalIDKey(id)]; ok && !used[existing] {
+				matches[contact] = existing
+				used[existing] = true
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// externalIDKey identifies an external ID by type and value only
+func externalIDKey(id *people.ExternalId) string {
+	return id.Type + "\x00" + id.Value
+}
+
+// Merge returns the contact to write when restoring incoming (from a backup)
+// over existing (live). Writable fields present in incoming replace those of
+// existing, fields incoming lacks keep their existing values, and frozen
+// fields are never changed. Memberships are combined: existing ones are kept
+// and incoming user groups are added through groupMap, which maps backup
+// group resource names to live ones. The result carries the existing
+// resource name and etag; neither argument is modified.
+func Merge(existing, incoming *people.Person, groupMap map[string]string, frozen []string) *people.Person {
+	merged := &people.Person{
+		ResourceName: existing.ResourceName,
+		Etag:         existing.Etag,
+	}
+
+	for _, field := range contacts.WritableFields {
+		if field == "memberships" {
+			continue
+		}
+		source := incoming
+		if slices.Contains(frozen, field) || !slices.Contains(models.PresentFields(incoming), field) {
+			source = existing
+		}
+		models.CopyPersonField(merged, source, field)
+	}
+
+	merged.Memberships = append([]*people.Membership(nil), existing.Memberships...)
+	if !slices.Contains(frozen, "memberships") {
+		for _, membership := range incoming.Memberships {
+			if membership.ContactGroupMembership == nil {
+				continue
+			}
+			group, ok := groupMap[membership.ContactGroupMembership.ContactGroupResourceName]
+			if !ok || hasGroup(merged, group) {
+				continue
+			}
+			merged.Memberships = append(merged.Memberships, &people.Membership{
+				ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: group},
+			})
+		}
+	}
+
+	return merged
+}
+
+// MergeFields returns the existing contact with only the given fields taken
+// from incoming, for a restore that writes nothing else. Fields are copied as
+// they are in the backup, so a field the backup has no values for is
+// cleared.
+func MergeFields(existing, incoming *people.Person, fields []string) *people.Person {
+	merged := &people.Person{
+		ResourceName: existing.ResourceName,
+		Etag:         existing.Etag,
+	}
+	for _, field := range contacts.WritableFields {
+		source := existing
+		if slices.Contains(fields, field) {
+			source = incoming
+		}
+		models.CopyPersonField(merged, source, field)
+	}
+	return merged
+}
+
+// Changed reports whether writing merged would change any writable field of
+// existing. Server-assigned metadata is ignored.
+func Changed(existing, merged *people.Person) bool {
+	for _, field := range models.DiffFields(existing, merged) {
+		if slices.Contains(contacts.WritableFields, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGroup reports whether the contact is a member of the group
+func hasGroup(contact *people.Person, group string) bool {
+	for _, membership := range contact.Memberships {
+		if membership.ContactGroupMembership != nil && membership.ContactGroupMembership.ContactGroupResourceName == group {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package reconcile
+
+import (
+	"slices"
+	"time"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Recovery is the result of comparing older backups with the live account.
+type Recovery struct {
+	// Backup holds the contacts found in the backups but missing from the
+	// live account, the user groups they belong to and their photos
+	Backup *models.BackupFile
+
+	// Backups are the backups that were compared, oldest first
+	Backups []*models.BackupFile
+
+	// LastSeen maps the resource name of each recoverable contact to the
+	// creation time of the newest backup that still contained it
+	LastSeen map[string]time.Time
+
+	// Complete is false if no backup predates the cutoff, so contacts
+	// deleted before the oldest backup could not be found
+	Complete bool
+}
+
+// Deleted finds contacts deleted from the live account since cutoff. It
+// compares the newest backup taken at or before cutoff and every later
+// backup with the live contacts; contacts in any of them that match no live
+// contact (see Match) are recoverable. A contact in several backups is taken
+// from the newest one. Group member lists are applied to each backup first.
+func Deleted(backups []*models.BackupFile, live []*people.Person, cutoff time.Time) *Recovery {
+	sorted := slices.Clone(backups)
+	slices.SortStableFunc(sorted, func(a, b *models.BackupFile) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	// Backups before the newest one at or before the cutoff only hold
+	// contacts deleted earlier than that
+	first := 0
+	complete := false
+	for i, backup := range sorted {
+		if !backup.CreatedAt.After(cutoff) {
+			first = i
+			complete = true
+		}
+	}
+	sorted = sorted[first:]
+
+	recovery := &Recovery{
+		Backup:   models.NewBackupFile(),
+		Backups:  sorted,
+		LastSeen: make(map[string]time.Time),
+		Complete: complete,
+	}
+
+	// Newest backups first, so each contact is taken from the newest backup
+	// that contains it
+	seen := make(map[string]bool)
+	groups := make(map[string]*people.ContactGroup)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		backup := sorted[i]
+		backup.ApplyGroupMembers()
+
+		var candidates []*people.Person
+		for _, contact := range backup.Contacts {
+			if contact.ResourceName == "" || seen[contact.ResourceName] {
+				continue
+			}
+			seen[contact.ResourceName] = true
+			candidates = append(candidates, contact)
+		}
+
+		matches := Match(candidates, live)
+		for _, contact := range candidates {
+			if _, ok := matches[contact]; ok {
+				continue
+			}
+			recovery.Backup.AddContact(contact)
+			recovery.LastSeen[contact.ResourceName] = backup.CreatedAt
+			if photo := backup.Photos[contact.ResourceName]; photo != nil {
+				if recovery.Backup.Photos == nil {
+					recovery.Backup.Photos = make(map[string]*models.Photo)
+				}
+				recovery.Backup.Photos[contact.ResourceName] = photo
+			}
+		}
+
+		for _, group := range backup.GetUserGroups() {
+			if _, ok := groups[group.ResourceName]; !ok {
+				groups[group.ResourceName] = group
+			}
+		}
+	}
+
+	// Keep only the groups the recovered contacts belong to
+	for _, contact := range recovery.Backup.Contacts {
+		for _, membership := range contact.Memberships {
+			if membership.ContactGroupMembership == nil {
+				continue
+			}
+			name := membership.ContactGroupMembership.ContactGroupResourceName
+			if group, ok := groups[name]; ok {
+				recovery.Backup.AddGroup(group)
+				delete(groups, name)
+			}
+		}
+	}
+	recovery.Backup.SortForRestore()
+
+	return recovery
+}
@@ -0,0 +1,190 @@
+package reconcile
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Comparison is the field-by-field comparison of a backup with the live
+// account.
+type Comparison struct {
+	// Matched is the number of backup contacts found in the live account
+	Matched int
+
+	// Mismatches are matched contacts whose writable fields differ
+	Mismatches []Mismatch
+
+	// Missing are backup contacts with no live counterpart
+	Missing []*people.Person
+
+	// Extra are live contacts that match no backup contact
+	Extra []*people.Person
+}
+
+// Mismatch is a backup contact and the live contact it matched, with the
+// writable person fields whose values differ.
+type Mismatch struct {
+	Backup *people.Person
+	Live   *people.Person
+	Fields []string
+}
+
+// Compare matches the backup's contacts with the live ones and compares
+// each pair field by field. Contacts are matched as in Match and then, for
+// contacts that were restored under a new resource name, by name, email
+// addresses and phone numbers. Only writable fields are compared, since a
+// restore cannot bring back read-only ones; memberships are compared by
+// user group name, as restored groups get new resource names. liveGroups
+// are the live account's contact groups.
+func Compare(backup *models.BackupFile, live []*people.Person, liveGroups []*people.ContactGroup) *Comparison {
+	matches := Match(backup.Contacts, live)
+	matchByIdentity(backup.Contacts, live, matches)
+
+	ignore := []string{"memberships"}
+	for _, name := range models.PersonFieldNames() {
+		if !slices.Contains(contacts.WritableFields, name) {
+			ignore = append(ignore, name)
+		}
+	}
+	backupGroups := backup.UserGroupNames()
+	liveGroupNames := make(map[string]string)
+	for _, group := range liveGroups {
+		if group.GroupType == "USER_CONTACT_GROUP" {
+			liveGroupNames[group.ResourceName] = group.Name
+		}
+	}
+
+	comparison := &Comparison{}
+	matchedLive := make(map[*people.Person]bool, len(matches))
+	for _, contact := range backup.Contacts {
+		existing, ok := matches[contact]
+		if !ok {
+			comparison.Missing = append(comparison.Missing, contact)
+			continue
+		}
+		comparison.Matched++
+		matchedLive[existing] = true
+
+		fields := models.DiffFields(contact, existing, ignore...)
+		if !slices.Equal(groupNames(contact, backupGroups), groupNames(existing, liveGroupNames)) {
+			fields = append(fields, "memberships")
+			sort.Strings(fields)
+		}
+		if len(fields) > 0 {
+			comparison.Mismatches = append(comparison.Mismatches, Mismatch{Backup: contact, Live: existing, Fields: fields})
+		}
+	}
+	for _, contact := range live {
+		if !matchedLive[contact] {
+			comparison.Extra = append(comparison.Extra, contact)
+		}
+	}
+
+	return comparison
+}
+
+// matchByIdentity adds matches for the backup contacts Match left unmatched,
+// pairing them with unmatched live contacts that have the same identity key,
+// and then with the only unmatched live contact that has the same name, if
+// no other unmatched backup contact has it, so contacts whose email address
+// or phone number changed are reported as mismatched rather than missing.
+func matchByIdentity(backup, live []*people.Person, matches map[*people.Person]*people.Person) {
+	passes := []struct {
+		key func(*people.Person) string
+		// unique requires a single contact with the key on both sides
+		unique bool
+	}{
+		{identityKey, false},
+		{nameKey, true},
+	}
+	for _, pass := range passes {
+		key := pass.key
+		used := make(map[*people.Person]bool, len(matches))
+		for _, existing := range matches {
+			used[existing] = true
+		}
+		byKey := make(map[string][]*people.Person)
+		for _, contact := range live {
+			if !used[contact] {
+				k := key(contact)
+				byKey[k] = append(byKey[k], contact)
+			}
+		}
+		unmatched := make(map[string][]*people.Person)
+		for _, contact := range backup {
+			if _, ok := matches[contact]; !ok {
+				k := key(contact)
+				unmatched[k] = append(unmatched[k], contact)
+			}
+		}
+
+		for _, contact := range backup {
+			if _, ok := matches[contact]; ok {
+				continue
+			}
+			k := key(contact)
+			candidates := byKey[k]
+			if k == "" || len(candidates) == 0 {
+				continue
+			}
+			if pass.unique && (len(candidates) > 1 || len(unmatched[k]) > 1) {
+				continue
+			}
+			matches[contact] = candidates[0]
+			byKey[k] = candidates[1:]
+		}
+	}
+}
+
+// nameKey identifies a contact by its display name, or is empty for
+// contacts without a name.
+func nameKey(contact *people.Person) string {
+	if len(contact.Names) == 0 {
+		return ""
+	}
+	return models.DisplayName(contact)
+}
+
+// identityKey identifies a contact by its display name, email addresses and
+// phone numbers, which survive a restore unchanged. Contacts with none of
+// them have an empty key.
+func identityKey(contact *people.Person) string {
+	var parts []string
+	for _, email := range contact.EmailAddresses {
+		parts = append(parts, strings.ToLower(email.Value))
+	}
+	for _, phone := range contact.PhoneNumbers {
+		parts = append(parts, phone.Value)
+	}
+	sort.Strings(parts)
+	name := ""
+	if len(contact.Names) > 0 {
+		name = models.DisplayName(contact)
+	}
+	if name == "" && len(parts) == 0 {
+		return ""
+	}
+	return name + "\x00" + strings.Join(parts, "\x00")
+}
+
+// groupNames returns the sorted names of the user groups the contact belongs
+// to, looked up in names.
+func groupNames(contact *people.Person, names map[string]string) []string {
+	var result []string
+	for _, membership := range contact.Memberships {
+		if membership.ContactGroupMembership == nil {
+			continue
+		}
+		if name, ok := names[membership.ContactGroupMembership.ContactGroupResourceName]; ok {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,164 @@
+// Package search finds contacts by a piece of their name, email address,
+// phone number or notes.
+package search
+
+import (
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/dedupe"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Fields searched, by their People API names
+const (
+	FieldNames  = "names"
+	FieldEmails = "emailAddresses"
+	FieldPhones = "phoneNumbers"
+	FieldNotes  = "biographies"
+)
+
+// minPhoneDigits is how many digits a query needs to be compared with phone
+// numbers digit by digit
+const minPhoneDigits = 3
+
+// Match is a contact found by a search.
+type Match struct {
+	Contact *people.Person
+
+	// Fields are the fields the query was found in, in the order names,
+	// emails, phones, notes
+	Fields []string
+}
+
+// Query is a parsed search query.
+type Query struct {
+	// text is the query normalized like the values it is compared with
+	text string
+
+	// digits are the digits of a query that looks like a phone number
+	digits string
+}
+
+// Parse prepares a query. Text is matched without regard to case, accents
+// or punctuation, so "jose" finds "José" and "smith" finds
+// "jane.smith@example.com". A query made of digits and the characters of
+// phone numbers is also matched against the digits of phone numbers, so
+// "555 0123" finds "+1 (555) 012-3456".
+func Parse(s string) Query {
+	q := Query{text: dedupe.NormalizeName(s)}
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" +-().", r):
+		default:
+			return q
+		}
+	}
+	if digits.Len() >= minPhoneDigits {
+		q.digits = digits.String()
+	}
+	return q
+}
+
+// Empty reports whether the query matches nothing, having no letters or
+// digits.
+func (q Query) Empty() bool {
+	return q.text == "" && q.digits == ""
+}
+
+// Fields returns the fields of contact the query is found in.
+func (q Query) Fields(contact *people.Person) []string {
+	if q.Empty() {
+		return nil
+	}
+	var fields []string
+	if q.matchesText(nameValues(contact)) {
+		fields = append(fields, FieldNames)
+	}
+	var emails []string
+	for _, email := range contact.EmailAddresses {
+		emails = append(emails, email.Value)
+	}
+	if q.matchesText(emails) {
+		fields = append(fields, FieldEmails)
+	}
+	if q.matchesPhone(contact) {
+		fields = append(fields, FieldPhones)
+	}
+	var notes []string
+	for _, bio := range contact.Biographies {
+		notes = append(notes, models.NotesText(bio, true))
+	}
+	if q.matchesText(notes) {
+		fields = append(fields, FieldNotes)
+	}
+	return fields
+}
+
+// Contacts returns the contacts the query is found in, in their order.
+func Contacts(contacts []*people.Person, q Query) []Match {
+	var matches []Match
+	for _, contact := range contacts {
+		if fields := q.Fields(contact); len(fields) > 0 {
+			matches = append(matches, Match{Contact: contact, Fields: fields})
+		}
+	}
+	return matches
+}
+
+// matchesText reports whether the query's text is part of any of values.
+func (q Query) matchesText(values []string) bool {
+	if q.text == "" {
+		return false
+	}
+	for _, value := range values {
+		if strings.Contains(dedupe.NormalizeName(value), q.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPhone reports whether the query's digits are part of the digits of
+// any of the contact's phone numbers.
+func (q Query) matchesPhone(contact *people.Person) bool {
+	if q.digits == "" {
+		return false
+	}
+	for _, phone := range contact.PhoneNumbers {
+		for _, value := range []string{phone.Value, phone.CanonicalForm} {
+			if strings.Contains(digitsOf(value), q.digits) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nameValues returns the names and nicknames of a contact.
+func nameValues(contact *people.Person) []string {
+	values := []string{models.DisplayName(contact)}
+	for _, name := range contact.Names {
+		values = append(values, name.DisplayName, name.GivenName+" "+name.MiddleName+" "+name.FamilyName,
+			name.PhoneticFullName)
+	}
+	for _, nickname := range contact.Nicknames {
+		values = append(values, nickname.Value)
+	}
+	return values
+}
+
+// digitsOf returns the digits of s.
+func digitsOf(s string) string {
+	var digits strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
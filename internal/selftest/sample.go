@@ -0,0 +1,66 @@
+package selftest
+
+import (
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// SampleBackup returns a small backup that uses every writable person field,
+// for running the self test without real data.
+func SampleBackup() *models.BackupFile {
+	backup := models.NewBackupFile()
+
+	backup.AddGroup(&people.ContactGroup{ResourceName: "contactGroups/myContacts", Name: "myContacts", GroupType: "SYSTEM_CONTACT_GROUP"})
+	backup.AddGroup(&people.ContactGroup{ResourceName: "contactGroups/sample1", Name: "Family", GroupType: "USER_CONTACT_GROUP"})
+	backup.AddGroup(&people.ContactGroup{ResourceName: "contactGroups/sample2", Name: "Work", GroupType: "USER_CONTACT_GROUP"})
+
+	member := func(group string) *people.Membership {
+		return &people.Membership{ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: group}}
+	}
+
+	backup.AddContact(&people.Person{
+		ResourceName:   "people/sample1",
+		Names:          []*people.Name{{GivenName: "Ada", MiddleName: "King", FamilyName: "Lovelace", HonorificPrefix: "Countess", PhoneticGivenName: "AY-duh"}},
+		Nicknames:      []*people.Nickname{{Value: "Ada"}},
+		EmailAddresses: []*people.EmailAddress{{Value: "ada@example.com", Type: "home"}, {Value: "ada@work.example.com", Type: "work"}},
+		PhoneNumbers:   []*people.PhoneNumber{{Value: "+44 20 7946 0000", Type: "mobile"}},
+		Addresses:      []*people.Address{{StreetAddress: "12 St James's Square", City: "London", PostalCode: "SW1Y 4JH", Country: "United Kingdom", Type: "home"}},
+		Organizations:  []*people.Organization{{Name: "Analytical Engines Ltd", Title: "Programmer", Department: "Research"}},
+		Birthdays:      []*people.Birthday{{Date: &people.Date{Year: 1815, Month: 12, Day: 10}}},
+		Biographies:    []*people.Biography{{Value: "<p>First <b>programmer</b></p>", ContentType: models.NotesContentTypeHTML}},
+		Urls:           []*people.Url{{Value: "https://example.com/ada", Type: "homePage"}},
+		UserDefined:    []*people.UserDefined{{Key: "Favourite engine", Value: "Analytical"}},
+		Events:         []*people.Event{{Date: &people.Date{Year: 1835, Month: 7, Day: 8}, Type: "anniversary"}},
+		Relations:      []*people.Relation{{Person: "William King", Type: "spouse"}},
+		Occupations:    []*people.Occupation{{Value: "Mathematician"}},
+		Genders:        []*people.Gender{{Value: "female"}},
+		ImClients:      []*people.ImClient{{Username: "ada", Protocol: "xmpp", Type: "home"}},
+		Interests:      []*people.Interest{{Value: "Poetical science"}},
+		SipAddresses:   []*people.SipAddress{{Value: "sip:ada@example.com", Type: "home"}},
+		CalendarUrls:   []*people.CalendarUrl{{Url: "https://example.com/ada.ics", Type: "home"}},
+		ExternalIds:    []*people.ExternalId{{Value: "AL-1815", Type: "account"}},
+		Locales:        []*people.Locale{{Value: "en-GB"}},
+		Locations:      []*people.Location{{Value: "Study", Type: "desk"}},
+		MiscKeywords:   []*people.MiscKeyword{{Value: "pioneer", Type: "OUTLOOK_KEYWORD"}},
+		ClientData:     []*people.ClientData{{Key: "source", Value: "selftest"}},
+		Memberships:    []*people.Membership{member("contactGroups/myContacts"), member("contactGroups/sample1")},
+	})
+
+	backup.AddContact(&people.Person{
+		ResourceName:   "people/sample2",
+		Names:          []*people.Name{{GivenName: "Grace", FamilyName: "Hopper"}},
+		EmailAddresses: []*people.EmailAddress{{Value: "grace@example.com"}},
+		Biographies:    []*people.Biography{{Value: "Plain text notes\nwith two lines", ContentType: models.NotesContentTypePlain}},
+		Birthdays:      []*people.Birthday{{Date: &people.Date{Month: 12, Day: 9}}},
+		Memberships:    []*people.Membership{member("contactGroups/myContacts"), member("contactGroups/sample2")},
+	})
+
+	backup.AddContact(&people.Person{
+		ResourceName: "people/sample3",
+		PhoneNumbers: []*people.PhoneNumber{{Value: "+1 555 0100", Type: "work"}},
+		Memberships:  []*people.Membership{member("contactGroups/sample1"), member("contactGroups/sample2")},
+	})
+
+	return backup
+}
@@ -0,0 +1,168 @@
+// Package selftest checks that backup data survives a restore round trip.
+//
+// A backup is restored into an in-memory fake of the People API using the
+// same client code as a real restore, read back, and compared field by field
+// with the original.
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/contacts"
+	"github.com/mheap/google-contacts-backup/internal/fakepeople"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// maxExamples is the number of example contacts recorded per field
+const maxExamples = 5
+
+// FieldLoss records a field that did not survive the round trip.
+type FieldLoss struct {
+	// Field is the People API field name
+	Field string
+	// ReadOnly is true if the API does not accept the field on create, so
+	// losing it is expected
+	ReadOnly bool
+	// Contacts is the number of contacts that lost the field
+	Contacts int
+	// Examples holds display names of some affected contacts
+	Examples []string
+}
+
+// Report is the result of a round trip.
+type Report struct {
+	Contacts int
+	Groups   int
+	Losses   []*FieldLoss
+}
+
+// Failed reports whether any writable field was lost.
+func (r *Report) Failed() bool {
+	for _, loss := range r.Losses {
+		if !loss.ReadOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// Run restores the backup into a fake People API, reads it back, and reports
+// every field that does not survive.
+func Run(ctx context.Context, backup *models.BackupFile) (*Report, error) {
+	server := fakepeople.New()
+	client, err := contacts.NewClient(ctx, server.HTTPClient(), contacts.WithoutPacing())
+	if err != nil {
+		return nil, err
+	}
+
+	groupMap, err := client.CreateGroups(ctx, backup.GetUserGroups(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create groups: %w", err)
+	}
+	contactMap, err := client.CreateContacts(ctx, backup.Contacts, groupMap, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contacts: %w", err)
+	}
+
+	restored, err := client.ListContacts(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read back contacts: %w", err)
+	}
+	byResourceName := make(map[string]*people.Person, len(restored))
+	for _, p := range restored {
+		byResourceName[p.ResourceName] = p
+	}
+
+	writable := make(map[string]bool)
+	for _, field := range contacts.WritableFields {
+		writable[field] = true
+	}
+
+	losses := make(map[string]*FieldLoss)
+	record := func(field string, contact *people.Person) {
+		loss, ok := losses[field]
+		if !ok {
+			loss = &FieldLoss{Field: field, ReadOnly: !writable[field]}
+			losses[field] = loss
+		}
+		loss.Contacts++
+		if len(loss.Examples) < maxExamples {
+			loss.Examples = append(loss.Examples, models.DisplayName(contact))
+		}
+	}
+
+	groupNames := make(map[string]string)
+	for _, group := range backup.GetUserGroups() {
+		groupNames[group.ResourceName] = group.Name
+	}
+	restoredGroupNames := make(map[string]string)
+	for oldName, newName := range groupMap {
+		restoredGroupNames[newName] = groupNames[oldName]
+	}
+
+	for i, original := range backup.Contacts {
+		var roundTripped *people.Person
+		if newName, ok := contactMap[original.ResourceName]; ok {
+			roundTripped = byResourceName[newName]
+		} else if original.ResourceName == "" && i < len(restored) {
+			roundTripped = restored[i]
+		}
+		if roundTripped == nil {
+			record("(entire contact)", original)
+			continue
+		}
+
+		for _, field := range models.DiffFields(original, roundTripped, "memberships") {
+			record(field, original)
+		}
+		if !sameLabels(original, roundTripped, groupNames, restoredGroupNames) {
+			record("memberships", original)
+		}
+	}
+
+	report := &Report{
+		Contacts: len(backup.Contacts),
+		Groups:   len(groupMap),
+	}
+	for _, loss := range losses {
+		report.Losses = append(report.Losses, loss)
+	}
+	sort.Slice(report.Losses, func(i, j int) bool {
+		return report.Losses[i].Field < report.Losses[j].Field
+	})
+
+	return report, nil
+}
+
+// sameLabels compares user group memberships by group name, since groups get
+// new resource names when they are recreated.
+func sameLabels(a, b *people.Person, aGroups, bGroups map[string]string) bool {
+	labels := func(p *people.Person, names map[string]string) []string {
+		var result []string
+		for _, m := range p.Memberships {
+			if m.ContactGroupMembership == nil {
+				continue
+			}
+			if name, ok := names[m.ContactGroupMembership.ContactGroupResourceName]; ok {
+				result = append(result, name)
+			}
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	la, lb := labels(a, aGroups), labels(b, bGroups)
+	if len(la) != len(lb) {
+		return false
+	}
+	for i := range la {
+		if la[i] != lb[i] {
+			return false
+		}
+	}
+	return true
+}
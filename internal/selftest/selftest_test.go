@@ -0,0 +1,52 @@
+package selftest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mheap/google-contacts-backup/internal/synthetic"
+)
+
+func TestRunSyntheticBackup(t *testing.T) {
+	backup := synthetic.Generate(synthetic.Options{Contacts: 200, Groups: 8, Seed: 42})
+
+	report, err := Run(context.Background(), backup)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if report.Contacts != len(backup.Contacts) {
+		t.Errorf("Contacts = %d, want %d", report.Contacts, len(backup.Contacts))
+	}
+	if want := len(backup.GetUserGroups()); report.Groups != want {
+		t.Errorf("Groups = %d, want %d", report.Groups, want)
+	}
+	for _, loss := range report.Losses {
+		if !loss.ReadOnly {
+			t.Errorf("writable field %s lost on %d contacts, e.g. %v", loss.Field, loss.Contacts, loss.Examples)
+		}
+	}
+	if report.Failed() {
+		t.Error("Failed() = true, want false")
+	}
+}
+
+func TestReportFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		losses []*FieldLoss
+		want   bool
+	}{
+		{"no losses", nil, false},
+		{"read-only loss", []*FieldLoss{{Field: "photos", ReadOnly: true}}, false},
+		{"writable loss", []*FieldLoss{{Field: "photos", ReadOnly: true}, {Field: "nicknames"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &Report{Losses: tt.losses}
+			if got := report.Failed(); got != tt.want {
+				t.Errorf("Failed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
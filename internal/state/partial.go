@@ -0,0 +1,152 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/people/v1"
+)
+
+// partialListingFile is the filename of the partial listing in the temp directory
+const partialListingFile = "google-contacts-backup-partial.jsonl"
+
+// DefaultPartialListingPath returns where backups record the pages of
+// contacts they have received so far.
+func DefaultPartialListingPath() string {
+	return filepath.Join(os.TempDir(), partialListingFile)
+}
+
+// PartialListing is a file that a contact listing appends each page to, so
+// that an interrupted backup can continue from the last page it received
+// instead of starting over. It is a JSON Lines file: a header, then one
+// line per page with its contacts and the token of the next page. Every
+// line is synced to disk as soon as it is written.
+type PartialListing struct {
+	file *os.File
+}
+
+// partialHeader is the first line of a partial listing
+type partialHeader struct {
+	StartedAt time.Time `json:"started_at"`
+}
+
+// partialPage is a page line of a partial listing
+type partialPage struct {
+	Contacts      []*people.Person `json:"contacts"`
+	NextPageToken string           `json:"next_page_token,omitempty"`
+	Total         int              `json:"total,omitempty"`
+}
+
+// ListingProgress is what a partial listing holds.
+type ListingProgress struct {
+	// StartedAt is when the interrupted listing started
+	StartedAt time.Time
+
+	// Contacts are the contacts received so far
+	Contacts []*people.Person
+
+	// NextPageToken continues the listing; Complete is set instead once
+	// the last page was received
+	NextPageToken string
+	Complete      bool
+
+	// Total is the number of contacts the API reported
+	Total int
+}
+
+// CreatePartialListing starts a partial listing at path, replacing any
+// earlier one. The file is only readable by the user, as it holds contacts.
+func CreatePartialListing(path string, startedAt time.Time) (*PartialListing, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create partial listing: %w", err)
+	}
+	l := &PartialListing{file: file}
+	if err := l.writeLine(partialHeader{StartedAt: startedAt.UTC()}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// AddPage records a page of contacts and the token of the page after it.
+func (l *PartialListing) AddPage(contacts []*people.Person, nextPageToken string, total int) error {
+	return l.writeLine(partialPage{Contacts: contacts, NextPageToken: nextPageToken, Total: total})
+}
+
+// writeLine appends v as one JSON line and syncs the file.
+func (l *PartialListing) writeLine(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal partial listing: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write partial listing: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write partial listing: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file.
+func (l *PartialListing) Close() error {
+	return l.file.Close()
+}
+
+// ReadPartialListing reads the partial listing at path, or returns nil if
+// there is none. A last line cut short by a crash is ignored, so the page
+// it held is fetched again.
+func ReadPartialListing(path string) (*ListingProgress, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partial listing: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		// Not even the header was written completely
+		return nil, nil
+	}
+	var header partialHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse partial listing %s: %w", path, err)
+	}
+
+	progress := &ListingProgress{StartedAt: header.StartedAt}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var page partialPage
+		if err := json.Unmarshal(line, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse partial listing %s: %w", path, err)
+		}
+		progress.Contacts = append(progress.Contacts, page.Contacts...)
+		progress.NextPageToken = page.NextPageToken
+		progress.Complete = page.NextPageToken == ""
+		if page.Total > 0 {
+			progress.Total = page.Total
+		}
+	}
+	return progress, nil
+}
+
+// RemovePartialListing deletes the partial listing at path, if any.
+func RemovePartialListing(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete partial listing: %w", err)
+	}
+	return nil
+}
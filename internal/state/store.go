@@ -0,0 +1,481 @@
+// Package state persists data that must survive between runs: sync tokens,
+// checkpoints of interrupted operations, tables mapping resource names across
+// accounts, metadata about the last run of each command, the People API
+// requests sent on the current quota day, and the size of the address book
+// at each backup.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/mheap/google-contacts-backup/internal/config"
+)
+
+const (
+	// stateFile is the filename of the state database in the config directory
+	stateFile = "state.db"
+
+	// openTimeout is how long Open waits for another process to release the database
+	openTimeout = 5 * time.Second
+
+	// maxSizes is how many address book sizes are kept; older ones are dropped
+	maxSizes = 5000
+
+	// sizeKey formats the time of a size as its key, so keys sort by time
+	sizeKey = "2006-01-02T15:04:05.000000000Z"
+)
+
+// Sections of the store. Mapping tables live in buckets prefixed with
+// SectionMappings and the table name.
+const (
+	SectionSyncTokens  = "sync_tokens"
+	SectionCheckpoints = "checkpoints"
+	SectionMappings    = "mappings"
+	SectionRuns        = "runs"
+	SectionUsage       = "usage"
+	SectionSizes       = "sizes"
+)
+
+// mappingPrefix separates the mappings section from the table name in bucket names
+const mappingPrefix = SectionMappings + "/"
+
+// Sections lists every section that can be shown or reset.
+func Sections() []string {
+	return []string{SectionSyncTokens, SectionCheckpoints, SectionMappings, SectionRuns, SectionUsage, SectionSizes}
+}
+
+// IsSection reports whether name is a known section.
+func IsSection(name string) bool {
+	for _, s := range Sections() {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultPath returns the default path of the state database.
+func DefaultPath() string {
+	return filepath.Join(config.Dir(), stateFile)
+}
+
+// Store is a persistent key-value store backed by a bbolt database.
+// Only one process can hold it open at a time.
+type Store struct {
+	db *bolt.DB
+}
+
+// Run records the outcome of one command invocation.
+type Run struct {
+	Command    string         `json:"command"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Error      string         `json:"error,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+// Succeeded reports whether the run finished without an error.
+func (r *Run) Succeeded() bool {
+	return r.Error == ""
+}
+
+// Open opens the state database at path, creating it and its directory if needed.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("state database %s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// put stores value under key in bucket, creating the bucket if needed.
+func (s *Store) put(bucket, key string, value []byte) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write state %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// get returns a copy of the value stored under key in bucket, or nil.
+func (s *Store) get(bucket, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state %s/%s: %w", bucket, key, err)
+	}
+	return value, nil
+}
+
+// remove deletes key from bucket. Missing keys are not an error.
+func (s *Store) remove(bucket, key string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete state %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// SetSyncToken stores the People API sync token for a named sync.
+func (s *Store) SetSyncToken(name, token string) error {
+	return s.put(SectionSyncTokens, name, []byte(token))
+}
+
+// SyncToken returns the sync token for a named sync, or "" if there is none.
+func (s *Store) SyncToken(name string) (string, error) {
+	value, err := s.get(SectionSyncTokens, name)
+	return string(value), err
+}
+
+// SaveCheckpoint stores v as JSON under name, replacing any earlier checkpoint.
+func (s *Store) SaveCheckpoint(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint %s: %w", name, err)
+	}
+	return s.put(SectionCheckpoints, name, data)
+}
+
+// LoadCheckpoint decodes the checkpoint stored under name into v and reports
+// whether one was found.
+func (s *Store) LoadCheckpoint(name string, v any) (bool, error) {
+	data, err := s.get(SectionCheckpoints, name)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse checkpoint %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// DeleteCheckpoint removes the checkpoint stored under name.
+func (s *Store) DeleteCheckpoint(name string) error {
+	return s.remove(SectionCheckpoints, name)
+}
+
+// SetMappings adds entries to a mapping table, e.g. from the resource names
+// in a backup to the resource names they were restored as.
+func (s *Store) SetMappings(table string, entries map[string]string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(mappingPrefix + table))
+		if err != nil {
+			return err
+		}
+		for from, to := range entries {
+			if err := b.Put([]byte(from), []byte(to)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write mapping table %s: %w", table, err)
+	}
+	return nil
+}
+
+// Mappings returns every entry of a mapping table.
+func (s *Store) Mappings(table string) (map[string]string, error) {
+	entries := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(mappingPrefix + table))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			entries[string(k)] = string(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping table %s: %w", table, err)
+	}
+	return entries, nil
+}
+
+// RecordRun stores a run as the last run of its command.
+func (s *Store) RecordRun(run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	return s.put(SectionRuns, run.Command, data)
+}
+
+// LastRun returns the last recorded run of a command, or nil if it never ran.
+func (s *Store) LastRun(command string) (*Run, error) {
+	data, err := s.get(SectionRuns, command)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", command, err)
+	}
+	return &run, nil
+}
+
+// Usage counts the People API requests sent on one quota day.
+type Usage struct {
+	Reads  int `json:"reads"`
+	Writes int `json:"writes"`
+}
+
+// AddUsage adds requests to the usage of a quota day (see
+// contacts.QuotaDay). The usage of earlier days is dropped, as only the
+// current day counts against daily quotas.
+func (s *Store) AddUsage(day string, reads, writes int) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(SectionUsage))
+		if err != nil {
+			return err
+		}
+		var usage Usage
+		if data := b.Get([]byte(day)); data != nil {
+			if err := json.Unmarshal(data, &usage); err != nil {
+				return err
+			}
+		}
+		usage.Reads += reads
+		usage.Writes += writes
+		data, err := json.Marshal(usage)
+		if err != nil {
+			return err
+		}
+
+		var old [][]byte
+		err = b.ForEach(func(k, v []byte) error {
+			if string(k) != day {
+				old = append(old, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range old {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return b.Put([]byte(day), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record API usage: %w", err)
+	}
+	return nil
+}
+
+// DailyUsage returns the requests recorded for a quota day, zero if none.
+func (s *Store) DailyUsage(day string) (Usage, error) {
+	var usage Usage
+	data, err := s.get(SectionUsage, day)
+	if err != nil || data == nil {
+		return usage, err
+	}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return usage, fmt.Errorf("failed to parse API usage: %w", err)
+	}
+	return usage, nil
+}
+
+// Size records how big the address book was at one backup.
+type Size struct {
+	At            time.Time `json:"at"`
+	Contacts      int       `json:"contacts"`
+	OtherContacts int       `json:"other_contacts,omitempty"`
+	Groups        int       `json:"groups"`
+}
+
+// RecordSize adds the size of the address book at a backup. Only the
+// newest maxSizes sizes are kept.
+func (s *Store) RecordSize(size Size) error {
+	size.At = size.At.UTC()
+	data, err := json.Marshal(size)
+	if err != nil {
+		return fmt.Errorf("failed to marshal size: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(SectionSizes))
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(size.At.Format(sizeKey)), data); err != nil {
+			return err
+		}
+		count := 0
+		if err := b.ForEach(func(k, v []byte) error { count++; return nil }); err != nil {
+			return err
+		}
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && count > maxSizes; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			count--
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record address book size: %w", err)
+	}
+	return nil
+}
+
+// Sizes returns the recorded sizes of the address book, oldest first.
+func (s *Store) Sizes() ([]Size, error) {
+	var sizes []Size
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(SectionSizes))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var size Size
+			if err := json.Unmarshal(v, &size); err != nil {
+				return fmt.Errorf("failed to parse size %s: %w", k, err)
+			}
+			sizes = append(sizes, size)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read address book sizes: %w", err)
+	}
+	return sizes, nil
+}
+
+// Summary describes the contents of one section.
+type Summary struct {
+	Section string
+	// Keys lists the entries of the section; for mappings these are table
+	// names, with Counts holding the number of entries in each
+	Keys   []string
+	Counts map[string]int
+}
+
+// Summarize returns a summary of every section, in the order of Sections.
+func (s *Store) Summarize() ([]Summary, error) {
+	summaries := make(map[string]*Summary)
+	for _, section := range Sections() {
+		summaries[section] = &Summary{Section: section, Counts: make(map[string]int)}
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			section, table := splitBucket(string(name))
+			summary, ok := summaries[section]
+			if !ok {
+				return nil
+			}
+			if section == SectionMappings {
+				summary.Keys = append(summary.Keys, table)
+				summary.Counts[table] = b.Stats().KeyN
+				return nil
+			}
+			return b.ForEach(func(k, v []byte) error {
+				summary.Keys = append(summary.Keys, string(k))
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	result := make([]Summary, 0, len(summaries))
+	for _, section := range Sections() {
+		sort.Strings(summaries[section].Keys)
+		result = append(result, *summaries[section])
+	}
+	return result, nil
+}
+
+// Reset deletes the given sections, or everything when none are given.
+func (s *Store) Reset(sections ...string) error {
+	if len(sections) == 0 {
+		sections = Sections()
+	}
+	reset := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		if !IsSection(section) {
+			return fmt.Errorf("unknown state section %q", section)
+		}
+		reset[section] = true
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var names [][]byte
+		err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if section, _ := splitBucket(string(name)); reset[section] {
+				names = append(names, append([]byte{}, name...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset state: %w", err)
+	}
+	return nil
+}
+
+// splitBucket splits a bucket name into its section and mapping table name.
+func splitBucket(name string) (section, table string) {
+	if table, ok := strings.CutPrefix(name, mappingPrefix); ok {
+		return SectionMappings, table
+	}
+	return name, ""
+}
@@ -0,0 +1,345 @@
+// Package stats measures how complete the contacts of a backup are: how many
+// have an email address, a phone number, a birthday and other details,
+// overall, per group and per email domain.
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// DefaultTopDomains is the number of email domains listed by default
+const DefaultTopDomains = 20
+
+// NoEmail is the domain listed for contacts without an email address
+const NoEmail = "(no email)"
+
+// Detail is a piece of contact data whose coverage is measured.
+type Detail struct {
+	// Key identifies the detail in JSON output, e.g. "email"
+	Key string
+
+	// Label names the detail for people, e.g. "Email address"
+	Label string
+
+	// Has reports whether a contact has the detail; photo is the photo a
+	// backup saved for the contact, if any
+	Has func(contact *people.Person, photo *models.Photo) bool
+}
+
+// Details are the details measured, in the order they are listed.
+var Details = []Detail{
+	{"name", "Name", func(c *people.Person, _ *models.Photo) bool { return hasName(c) }},
+	{"email", "Email address", func(c *people.Person, _ *models.Photo) bool {
+		return hasValue(c.EmailAddresses, func(e *people.EmailAddress) string { return e.Value })
+	}},
+	{"phone", "Phone number", func(c *people.Person, _ *models.Photo) bool {
+		return hasValue(c.PhoneNumbers, func(p *people.PhoneNumber) string { return p.Value })
+	}},
+	{"photo", "Photo", func(c *people.Person, photo *models.Photo) bool { return photo != nil || models.ContactPhoto(c) != nil }},
+	{"birthday", "Birthday", func(c *people.Person, _ *models.Photo) bool { return len(c.Birthdays) > 0 }},
+	{"organization", "Organization", func(c *people.Person, _ *models.Photo) bool { return len(c.Organizations) > 0 }},
+	{"address", "Postal address", func(c *people.Person, _ *models.Photo) bool { return len(c.Addresses) > 0 }},
+	{"notes", "Notes", func(c *people.Person, _ *models.Photo) bool { return len(c.Biographies) > 0 }},
+	{"nickname", "Nickname", func(c *people.Person, _ *models.Photo) bool { return len(c.Nicknames) > 0 }},
+	{"website", "Website", func(c *people.Person, _ *models.Photo) bool { return len(c.Urls) > 0 }},
+	{"event", "Other dates", func(c *people.Person, _ *models.Photo) bool { return len(c.Events) > 0 }},
+	{"relation", "Relations", func(c *people.Person, _ *models.Photo) bool { return len(c.Relations) > 0 }},
+	{"custom", "Custom fields", func(c *people.Person, _ *models.Photo) bool { return len(c.UserDefined) > 0 }},
+}
+
+// Coverage is the number of contacts with a detail.
+type Coverage struct {
+	Key      string  `json:"key"`
+	Label    string  `json:"label"`
+	Contacts int     `json:"contacts"`
+	Percent  float64 `json:"percent"`
+}
+
+// Group is the coverage of the contacts of one group.
+type Group struct {
+	ResourceName string `json:"resource_name"`
+	Name         string `json:"name"`
+	System       bool   `json:"system"`
+	Contacts     int    `json:"contacts"`
+
+	// Coverage maps the keys of Details to the number of the group's
+	// contacts with them
+	Coverage map[string]int `json:"coverage"`
+}
+
+// Domain is the number of contacts with an email address at a domain.
+type Domain struct {
+	Domain   string  `json:"domain"`
+	Contacts int     `json:"contacts"`
+	Percent  float64 `json:"percent"`
+}
+
+// Stats is the data quality report of a backup.
+type Stats struct {
+	Contacts int        `json:"contacts"`
+	Coverage []Coverage `json:"coverage"`
+
+	// Unreachable counts the contacts without an email address or phone
+	// number, and Unlabeled those in no user group
+	Unreachable int `json:"unreachable"`
+	Unlabeled   int `json:"unlabeled"`
+
+	Groups []Group `json:"groups"`
+
+	// Domains are the email domains with the most contacts, most first,
+	// followed by NoEmail; OtherDomains counts the domains left out
+	Domains      []Domain `json:"domains"`
+	OtherDomains int      `json:"other_domains"`
+}
+
+// Compute measures the contacts of a backup, listing up to topDomains email
+// domains (all of them if topDomains is 0 or less).
+func Compute(backup *models.BackupFile, topDomains int) *Stats {
+	s := &Stats{Contacts: len(backup.Contacts)}
+	total := make(map[string]int)
+	groupCoverage := make(map[string]map[string]int)
+	groupContacts := make(map[string]int)
+	domains := make(map[string]int)
+
+	// system maps the backup's groups to whether they are system groups
+	system := make(map[string]bool, len(backup.Groups))
+	for _, group := range backup.Groups {
+		system[group.ResourceName] = isSystem(group)
+	}
+	listed := make(map[string]map[string]bool)
+	for group, members := range backup.GroupMembers {
+		listed[group] = make(map[string]bool, len(members))
+		for _, member := range members {
+			listed[group][member] = true
+		}
+	}
+
+	for _, contact := range backup.Contacts {
+		photo := backup.Photos[contact.ResourceName]
+		has := make(map[string]bool, len(Details))
+		for _, detail := range Details {
+			if detail.Has(contact, photo) {
+				has[detail.Key] = true
+				total[detail.Key]++
+			}
+		}
+		if !has["email"] && !has["phone"] {
+			s.Unreachable++
+		}
+
+		labeled := false
+		for _, group := range contactGroups(contact, listed) {
+			systemGroup, ok := system[group]
+			if !ok {
+				continue
+			}
+			if !systemGroup {
+				labeled = true
+			}
+			groupContacts[group]++
+			if groupCoverage[group] == nil {
+				groupCoverage[group] = make(map[string]int)
+			}
+			for key := range has {
+				groupCoverage[group][key]++
+			}
+		}
+		if !labeled {
+			s.Unlabeled++
+		}
+
+		seen := make(map[string]bool)
+		for _, email := range contact.EmailAddresses {
+			_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email.Value)), "@")
+			if ok && domain != "" && !seen[domain] {
+				seen[domain] = true
+				domains[domain]++
+			}
+		}
+		if len(seen) == 0 {
+			domains[NoEmail]++
+		}
+	}
+
+	for _, detail := range Details {
+		s.Coverage = append(s.Coverage, Coverage{
+			Key:      detail.Key,
+			Label:    detail.Label,
+			Contacts: total[detail.Key],
+			Percent:  percent(total[detail.Key], s.Contacts),
+		})
+	}
+
+	for _, group := range backup.Groups {
+		if groupContacts[group.ResourceName] == 0 {
+			continue
+		}
+		s.Groups = append(s.Groups, Group{
+			ResourceName: group.ResourceName,
+			Name:         group.Name,
+			System:       isSystem(group),
+			Contacts:     groupContacts[group.ResourceName],
+			Coverage:     groupCoverage[group.ResourceName],
+		})
+	}
+	sort.SliceStable(s.Groups, func(i, j int) bool {
+		if s.Groups[i].System != s.Groups[j].System {
+			return !s.Groups[i].System
+		}
+		if s.Groups[i].Contacts != s.Groups[j].Contacts {
+			return s.Groups[i].Contacts > s.Groups[j].Contacts
+		}
+		return s.Groups[i].Name < s.Groups[j].Name
+	})
+
+	noEmail := domains[NoEmail]
+	delete(domains, NoEmail)
+	for domain, contacts := range domains {
+		s.Domains = append(s.Domains, Domain{Domain: domain, Contacts: contacts, Percent: percent(contacts, s.Contacts)})
+	}
+	sort.Slice(s.Domains, func(i, j int) bool {
+		if s.Domains[i].Contacts != s.Domains[j].Contacts {
+			return s.Domains[i].Contacts > s.Domains[j].Contacts
+		}
+		return s.Domains[i].Domain < s.Domains[j].Domain
+	})
+	if topDomains > 0 && len(s.Domains) > topDomains {
+		s.OtherDomains = len(s.Domains) - topDomains
+		s.Domains = s.Domains[:topDomains]
+	}
+	if noEmail > 0 {
+		s.Domains = append(s.Domains, Domain{Domain: NoEmail, Contacts: noEmail, Percent: percent(noEmail, s.Contacts)})
+	}
+	return s
+}
+
+// WriteText writes the report as tables: the coverage of each detail, the
+// groups with their coverage of the main details, and the email domains.
+func WriteText(w io.Writer, s *Stats) error {
+	var b strings.Builder
+	if s.Contacts == 0 {
+		b.WriteString("The backup has no contacts.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	fmt.Fprintf(&b, "Field coverage of %d contacts:\n\n", s.Contacts)
+	for _, c := range s.Coverage {
+		line := fmt.Sprintf("  %-16s %6d  %5.1f%%  %s", c.Label, c.Contacts, c.Percent, bar(c.Percent))
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	fmt.Fprintf(&b, "\n  No email or phone: %d\n", s.Unreachable)
+	fmt.Fprintf(&b, "  In no label:       %d\n", s.Unlabeled)
+
+	if len(s.Groups) > 0 {
+		fmt.Fprintf(&b, "\nBy group:\n\n")
+		fmt.Fprintf(&b, "  %-30s %8s  %6s  %6s  %6s  %8s\n", "Group", "Contacts", "Email", "Phone", "Photo", "Birthday")
+		for _, group := range s.Groups {
+			name := group.Name
+			if group.System {
+				name += " (system)"
+			}
+			fmt.Fprintf(&b, "  %-30s %8d", truncate(name, 30), group.Contacts)
+			for _, key := range []string{"email", "phone", "photo", "birthday"} {
+				width := 6
+				if key == "birthday" {
+					width = 8
+				}
+				fmt.Fprintf(&b, "  %*s", width, fmt.Sprintf("%.0f%%", percent(group.Coverage[key], group.Contacts)))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(s.Domains) > 0 {
+		fmt.Fprintf(&b, "\nBy email domain:\n\n")
+		for _, domain := range s.Domains {
+			fmt.Fprintf(&b, "  %-40s %6d  %5.1f%%\n", truncate(domain.Domain, 40), domain.Contacts, domain.Percent)
+		}
+		if s.OtherDomains > 0 {
+			fmt.Fprintf(&b, "  (%d more domains; raise --top to list them)\n", s.OtherDomains)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// contactGroups returns the groups a contact belongs to, by its memberships
+// or the groups' member lists, each once.
+func contactGroups(contact *people.Person, listed map[string]map[string]bool) []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, membership := range contact.Memberships {
+		if membership.ContactGroupMembership == nil {
+			continue
+		}
+		group := membership.ContactGroupMembership.ContactGroupResourceName
+		if !seen[group] {
+			seen[group] = true
+			groups = append(groups, group)
+		}
+	}
+	for group, members := range listed {
+		if members[contact.ResourceName] && !seen[group] {
+			seen[group] = true
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// isSystem reports whether a group is a system group such as "My Contacts"
+// rather than a label created by the user.
+func isSystem(group *people.ContactGroup) bool {
+	return group.GroupType != "USER_CONTACT_GROUP"
+}
+
+// hasName reports whether a contact has a non-blank name.
+func hasName(contact *people.Person) bool {
+	for _, name := range contact.Names {
+		if strings.TrimSpace(name.DisplayName+name.GivenName+name.MiddleName+name.FamilyName) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValue reports whether any of values has a non-blank value.
+func hasValue[T any](values []T, value func(T) string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(value(v)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// percent returns n as a percentage of total.
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+// bar draws a percentage as a bar of up to 20 characters.
+func bar(percent float64) string {
+	return strings.Repeat("#", int(percent/5+0.5))
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// gcsBackend stores objects in a Google Cloud Storage bucket
+type gcsBackend struct {
+	client *storage.Client
+	bucket *storage.BucketHandle
+	name   string
+}
+
+// newGCSBackend authenticates with Application Default Credentials: a key
+// file in GOOGLE_APPLICATION_CREDENTIALS, the credentials saved by 'gcloud
+// auth application-default login', or the service account of the machine
+// when running on Google Cloud.
+func newGCSBackend(ctx context.Context, bucket string) (*gcsBackend, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: run 'gcloud auth application-default login' or set GOOGLE_APPLICATION_CREDENTIALS to a service account key file: %w", err)
+	}
+
+	return &gcsBackend{
+		client: client,
+		bucket: client.Bucket(bucket),
+		name:   bucket,
+	}, nil
+}
+
+func (g *gcsBackend) Upload(ctx context.Context, key string, r io.Reader, contentType string) error {
+	w := g.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to upload gs://%s/%s: %w", g.name, key, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to upload gs://%s/%s: %w", g.name, key, err)
+	}
+	return nil
+}
+
+// PresignGet signs a V4 URL. Signing needs a service account: either a key
+// file in GOOGLE_APPLICATION_CREDENTIALS or the IAM signBlob permission.
+func (g *gcsBackend) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	signed, err := g.bucket.SignedURL(key, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(expiry),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign gs://%s/%s: %w", g.name, key, err)
+	}
+	return signed, nil
+}
+
+func (g *gcsBackend) Close() error {
+	return g.client.Close()
+}
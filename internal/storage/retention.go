@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Retention modes. Governance locks can be lifted by users with a special
+// permission; compliance locks cannot be shortened or removed by anyone,
+// including the account root, until they expire.
+const (
+	RetentionGovernance = "GOVERNANCE"
+	RetentionCompliance = "COMPLIANCE"
+)
+
+// Retention is a write-once lock that keeps an object from being deleted or
+// overwritten until a point in time.
+type Retention struct {
+	// Mode is RetentionGovernance or RetentionCompliance
+	Mode string
+	// Until is when the lock expires
+	Until time.Time
+}
+
+// ParseRetentionMode normalizes a retention mode given on the command line.
+func ParseRetentionMode(mode string) (string, error) {
+	switch strings.ToUpper(mode) {
+	case RetentionGovernance:
+		return RetentionGovernance, nil
+	case RetentionCompliance:
+		return RetentionCompliance, nil
+	default:
+		return "", fmt.Errorf("invalid retention mode %q: must be 'governance' or 'compliance'", mode)
+	}
+}
+
+// Covers reports whether the retention r is at least as strong as want: the
+// same mode, lasting at least as long. Stores round the expiry to whole
+// seconds, so up to a second less is accepted.
+func (r Retention) Covers(want Retention) bool {
+	return r.Mode == want.Mode && !r.Until.Before(want.Until.Add(-time.Second))
+}
+
+// Retainer is implemented by backends that support object lock (S3 and
+// S3-compatible stores such as Backblaze B2). The bucket must have object
+// lock enabled.
+type Retainer interface {
+	// UploadRetained stores the contents of r under key and locks it with
+	// the given retention in the same request
+	UploadRetained(ctx context.Context, key string, r io.Reader, contentType string, retention Retention) error
+
+	// Retention returns the lock currently applied to key
+	Retention(ctx context.Context, key string) (Retention, error)
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Backend stores objects in an Amazon S3 (or S3-compatible) bucket. Other
+// S3-compatible services such as Backblaze B2 are reached by setting
+// AWS_ENDPOINT_URL.
+type s3Backend struct {
+	bucket  string
+	client  *s3.Client
+	presign *s3.PresignClient
+}
+
+func newS3Backend(ctx context.Context, bucket string) (*s3Backend, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg)
+	return &s3Backend{
+		bucket:  bucket,
+		client:  client,
+		presign: s3.NewPresignClient(client),
+	}, nil
+}
+
+func (s *s3Backend) Upload(ctx context.Context, key string, r io.Reader, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        r,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return nil
+}
+
+func (s *s3Backend) UploadRetained(ctx context.Context, key string, r io.Reader, contentType string, retention Retention) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:                    aws.String(s.bucket),
+		Key:                       aws.String(key),
+		Body:                      r,
+		ContentType:               aws.String(contentType),
+		ObjectLockMode:            types.ObjectLockMode(retention.Mode),
+		ObjectLockRetainUntilDate: aws.Time(retention.Until),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload s3://%s/%s with object lock: %w", s.bucket, key, err)
+	}
+	return nil
+}
+
+func (s *s3Backend) Retention(ctx context.Context, key string) (Retention, error) {
+	out, err := s.client.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return Retention{}, fmt.Errorf("failed to read retention of s3://%s/%s: %w", s.bucket, key, err)
+	}
+
+	var retention Retention
+	if out.Retention != nil {
+		retention.Mode = string(out.Retention.Mode)
+		if out.Retention.RetainUntilDate != nil {
+			retention.Until = *out.Retention.RetainUntilDate
+		}
+	}
+	return retention, nil
+}
+
+func (s *s3Backend) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return req.URL, nil
+}
+
+func (s *s3Backend) Close() error {
+	return nil
+}
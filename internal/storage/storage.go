@@ -0,0 +1,87 @@
+// Package storage uploads exports to remote object stores.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Backend is an object store that can hold exported files.
+type Backend interface {
+	// Upload stores the contents of r under key
+	Upload(ctx context.Context, key string, r io.Reader, contentType string) error
+
+	// PresignGet returns a URL that allows anyone holding it to download
+	// key until expiry has passed
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+
+	// Close releases any resources held by the backend
+	Close() error
+}
+
+// Location is a parsed destination URL such as s3://bucket/prefix.
+type Location struct {
+	// Scheme is "s3" or "gs"
+	Scheme string
+	// Bucket is the bucket name
+	Bucket string
+	// Prefix is prepended to every key, without leading or trailing slashes
+	Prefix string
+}
+
+// Key joins the location's prefix and name into an object key.
+func (l Location) Key(name string) string {
+	if l.Prefix == "" {
+		return name
+	}
+	return l.Prefix + "/" + name
+}
+
+// String returns the location as a URL.
+func (l Location) String() string {
+	if l.Prefix == "" {
+		return l.Scheme + "://" + l.Bucket
+	}
+	return l.Scheme + "://" + l.Bucket + "/" + l.Prefix
+}
+
+// ParseLocation parses an s3://bucket/prefix or gs://bucket/prefix URL.
+func ParseLocation(destination string) (Location, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid destination %q: %w", destination, err)
+	}
+
+	switch u.Scheme {
+	case "s3", "gs":
+	default:
+		return Location{}, fmt.Errorf("unsupported destination %q: must start with s3:// or gs://", destination)
+	}
+	if u.Host == "" {
+		return Location{}, fmt.Errorf("invalid destination %q: missing bucket name", destination)
+	}
+
+	return Location{
+		Scheme: u.Scheme,
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// Open returns the backend for a location, using the ambient credentials of
+// the provider (AWS_* environment and shared config for S3, Application
+// Default Credentials for GCS).
+func Open(ctx context.Context, loc Location) (Backend, error) {
+	switch loc.Scheme {
+	case "s3":
+		return newS3Backend(ctx, loc.Bucket)
+	case "gs":
+		return newGCSBackend(ctx, loc.Bucket)
+	default:
+		return nil, fmt.Errorf("unsupported storage scheme: %s", loc.Scheme)
+	}
+}
@@ -0,0 +1,55 @@
+package storage
+
+import "testing"
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		destination string
+		want        Location
+	}{
+		{"s3://bucket", Location{Scheme: "s3", Bucket: "bucket"}},
+		{"s3://bucket/", Location{Scheme: "s3", Bucket: "bucket"}},
+		{"s3://bucket/backups/contacts/", Location{Scheme: "s3", Bucket: "bucket", Prefix: "backups/contacts"}},
+		{"gs://my-bucket/exports", Location{Scheme: "gs", Bucket: "my-bucket", Prefix: "exports"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocation(tt.destination)
+		if err != nil {
+			t.Errorf("ParseLocation(%q): %v", tt.destination, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLocation(%q) = %+v, want %+v", tt.destination, got, tt.want)
+		}
+	}
+
+	for _, destination := range []string{"", "bucket/prefix", "file:///tmp/backups", "https://bucket/prefix", "s3:///prefix", "gs://%zz"} {
+		if _, err := ParseLocation(destination); err == nil {
+			t.Errorf("ParseLocation(%q) succeeded", destination)
+		}
+	}
+}
+
+func TestLocationKey(t *testing.T) {
+	loc := Location{Scheme: "s3", Bucket: "bucket"}
+	if got := loc.Key("backup.json"); got != "backup.json" {
+		t.Errorf("Key without prefix = %q", got)
+	}
+
+	loc.Prefix = "backups/contacts"
+	if got := loc.Key("backup.json"); got != "backups/contacts/backup.json" {
+		t.Errorf("Key with prefix = %q", got)
+	}
+}
+
+func TestLocationString(t *testing.T) {
+	for _, destination := range []string{"s3://bucket", "gs://bucket/backups/contacts"} {
+		loc, err := ParseLocation(destination)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := loc.String(); got != destination {
+			t.Errorf("String() = %q, want %q", got, destination)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package synthetic
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/dedupe"
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// AnonymizeOptions controls how a backup is anonymized.
+type AnonymizeOptions struct {
+	// Seed makes the fake values reproducible
+	Seed uint64
+
+	// Labels renames user groups to "Label 1", "Label 2" and so on; by
+	// default their names are kept
+	Labels bool
+}
+
+// AnonymizeReport counts what Anonymize replaced.
+type AnonymizeReport struct {
+	Contacts int
+	Emails   int
+	Phones   int
+	Photos   int
+	Labels   int
+}
+
+// anonymizer hands out fake values, the same fake value for the same real
+// one throughout a backup, so duplicates and shared addresses survive.
+type anonymizer struct {
+	rng *rand.Rand
+
+	emails    map[string]string
+	domains   map[string]string
+	phones    map[string]string
+	profiles  map[string]string
+	names     map[string][2]string
+	usedNames map[[2]string]bool
+	nextPhone int
+	photo     []byte
+
+	report AnonymizeReport
+}
+
+// Anonymize replaces the personal data of every contact and other contact
+// of a backup with fake values, in place: names, nicknames, email addresses,
+// phone numbers, postal addresses, organizations, dates, notes, websites,
+// relations, custom field values and every other free-text field, the ids of
+// linked Google profiles, the details of recorded filters, and photos, which
+// become a plain grey image. The structure is kept: the same fields and
+// number of values, their types, resource names, group memberships and,
+// unless opts.Labels is set, label names. The same real name, email
+// address or phone number always gets the same fake one, so duplicates stay
+// duplicates. Fake email addresses use the reserved .example domains and
+// phone numbers the ranges reserved for fiction, as Generate does.
+func Anonymize(backup *models.BackupFile, opts AnonymizeOptions) AnonymizeReport {
+	a := &anonymizer{
+		rng:       rand.New(rand.NewPCG(opts.Seed, opts.Seed^0xa11a5)),
+		emails:    make(map[string]string),
+		domains:   make(map[string]string),
+		phones:    make(map[string]string),
+		profiles:  make(map[string]string),
+		names:     make(map[string][2]string),
+		usedNames: make(map[[2]string]bool),
+	}
+
+	for i, contact := range backup.Contacts {
+		a.contact(contact, i)
+	}
+	for i, contact := range backup.OtherContacts {
+		a.contact(contact, len(backup.Contacts)+i)
+	}
+
+	for resourceName, photo := range backup.Photos {
+		photo.URL = fakePhotoURL(resourceName)
+		photo.ContentType = "image/jpeg"
+		photo.Data = a.grey()
+		a.report.Photos++
+	}
+	for resourceName, photo := range backup.FallbackPhotos {
+		photo.URL = fakePhotoURL(resourceName)
+		if len(photo.Data) > 0 {
+			photo.ContentType = "image/jpeg"
+			photo.Data = a.grey()
+		}
+		a.report.Photos++
+	}
+
+	// Filters, domains and ignore lists may name the people left out
+	for i, transform := range backup.Transforms {
+		if transform.Type != models.TransformFields {
+			backup.Transforms[i].Description = keep(transform.Description, "")
+		}
+	}
+
+	if opts.Labels {
+		n := 0
+		for _, group := range backup.Groups {
+			if group.GroupType != "USER_CONTACT_GROUP" {
+				continue
+			}
+			n++
+			group.Name = fmt.Sprintf("Label %d", n)
+			group.FormattedName = group.Name
+			a.report.Labels++
+		}
+	}
+	return a.report
+}
+
+// contact anonymizes one contact; index numbers its fake email addresses.
+func (a *anonymizer) contact(c *people.Person, index int) {
+	a.report.Contacts++
+	given, family := a.name(c)
+	localPart := strings.ToLower(asciiOnly(given) + "." + asciiOnly(family))
+
+	for _, name := range c.Names {
+		full := given + " " + family
+		name.DisplayName = keep(name.DisplayName, full)
+		name.DisplayNameLastFirst = keep(name.DisplayNameLastFirst, family+", "+given)
+		name.UnstructuredName = keep(name.UnstructuredName, full)
+		name.GivenName = keep(name.GivenName, given)
+		name.FamilyName = keep(name.FamilyName, family)
+		name.MiddleName = keep(name.MiddleName, pick(a.rng, givenNames)[:1])
+		name.HonorificPrefix = keep(name.HonorificPrefix, "Dr.")
+		name.HonorificSuffix = keep(name.HonorificSuffix, "Jr.")
+		name.PhoneticFullName = keep(name.PhoneticFullName, full)
+		name.PhoneticGivenName = keep(name.PhoneticGivenName, given)
+		name.PhoneticFamilyName = keep(name.PhoneticFamilyName, family)
+		name.PhoneticMiddleName = keep(name.PhoneticMiddleName, "")
+		name.PhoneticHonorificPrefix = keep(name.PhoneticHonorificPrefix, "")
+		name.PhoneticHonorificSuffix = keep(name.PhoneticHonorificSuffix, "")
+	}
+	for _, nickname := range c.Nicknames {
+		nickname.Value = keep(nickname.Value, pick(a.rng, nicknames))
+	}
+	for _, email := range c.EmailAddresses {
+		email.Value = a.email(email.Value, localPart, index)
+		email.DisplayName = keep(email.DisplayName, given+" "+family)
+	}
+	for _, phone := range c.PhoneNumbers {
+		phone.Value = a.phone(phone.Value)
+		if phone.CanonicalForm != "" {
+			phone.CanonicalForm = canonicalPhone(phone.Value)
+		}
+	}
+	for _, address := range c.Addresses {
+		street := fmt.Sprintf("%d %s", 1+a.rng.IntN(250), pick(a.rng, streets))
+		city, country := pick(a.rng, cities), pick(a.rng, countries)
+		postalCode := fmt.Sprintf("%05d", a.rng.IntN(100000))
+		address.StreetAddress = keep(address.StreetAddress, street)
+		address.ExtendedAddress = keep(address.ExtendedAddress, fmt.Sprintf("Flat %d", 1+a.rng.IntN(20)))
+		address.PoBox = keep(address.PoBox, fmt.Sprintf("PO Box %d", 1+a.rng.IntN(999)))
+		address.City = keep(address.City, city)
+		address.Region = keep(address.Region, "")
+		address.PostalCode = keep(address.PostalCode, postalCode)
+		address.Country = keep(address.Country, country)
+		address.FormattedValue = keep(address.FormattedValue, strings.Join([]string{street, city, postalCode, country}, "\n"))
+	}
+	for _, org := range c.Organizations {
+		org.Name = keep(org.Name, pick(a.rng, companies))
+		org.PhoneticName = keep(org.PhoneticName, "")
+		org.Title = keep(org.Title, pick(a.rng, titles))
+		org.Department = keep(org.Department, pick(a.rng, departments))
+		org.JobDescription = keep(org.JobDescription, pick(a.rng, titles))
+		org.Location = keep(org.Location, pick(a.rng, cities))
+		org.Symbol = keep(org.Symbol, "")
+		org.Domain = keep(org.Domain, pick(a.rng, workDomains))
+		org.CostCenter = keep(org.CostCenter, "")
+	}
+	for _, birthday := range c.Birthdays {
+		a.date(birthday.Date)
+		birthday.Text = keep(birthday.Text, "")
+	}
+	for _, event := range c.Events {
+		a.date(event.Date)
+	}
+	for _, bio := range c.Biographies {
+		note := pick(a.rng, notes)
+		if bio.ContentType == models.NotesContentTypeHTML {
+			note = "<p>" + strings.ReplaceAll(note, "\n", "</p><p>") + "</p>"
+		}
+		bio.Value = keep(bio.Value, note)
+	}
+	for i, url := range c.Urls {
+		url.Value = keep(url.Value, fmt.Sprintf("https://%s/%s/%d", pick(a.rng, domains), localPart, i+1))
+	}
+	for _, relation := range c.Relations {
+		relation.Person = keep(relation.Person, pick(a.rng, givenNames)+" "+family)
+	}
+	for _, im := range c.ImClients {
+		im.Username = keep(im.Username, fmt.Sprintf("%s%d", localPart, index))
+	}
+	for i, field := range c.UserDefined {
+		field.Value = keep(field.Value, fmt.Sprintf("value %d", i+1))
+	}
+	for _, photo := range c.Photos {
+		photo.Url = keep(photo.Url, fakePhotoURL(c.ResourceName))
+	}
+	for _, photo := range c.CoverPhotos {
+		photo.Url = keep(photo.Url, fakePhotoURL(c.ResourceName))
+	}
+	for i, data := range c.ClientData {
+		// The tool's own keys, such as the stable UUID, hold no personal data
+		if !strings.HasPrefix(data.Key, "google-contacts-backup.") {
+			data.Value = keep(data.Value, fmt.Sprintf("value %d", i+1))
+		}
+	}
+
+	// Whatever free text is left, such as occupations, interests, skills
+	// and external IDs
+	handled := map[string]bool{
+		"names": true, "nicknames": true, "emailAddresses": true, "phoneNumbers": true,
+		"addresses": true, "organizations": true, "birthdays": true, "events": true,
+		"biographies": true, "urls": true, "relations": true, "imClients": true,
+		"userDefined": true, "photos": true, "coverPhotos": true, "clientData": true,
+		"memberships": true,
+	}
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		list := v.Field(i)
+		if handled[field] || list.Kind() != reflect.Slice {
+			continue
+		}
+		for j := 0; j < list.Len(); j++ {
+			redactStrings(list.Index(j), fmt.Sprintf("%s %d", field, j+1))
+		}
+	}
+
+	a.profileIDs(c)
+}
+
+// name returns the fake given and family name for a contact. Contacts with
+// the same real name get the same fake one, and different real names get
+// different fake ones while the combinations last, so duplicates found by
+// name stay duplicates and no new ones appear.
+func (a *anonymizer) name(c *people.Person) (string, string) {
+	key := ""
+	if len(c.Names) > 0 {
+		key = dedupe.NormalizeName(models.DisplayName(c))
+	}
+	if fake, ok := a.names[key]; ok && key != "" {
+		return fake[0], fake[1]
+	}
+	var fake [2]string
+	for attempt := 0; attempt < 100; attempt++ {
+		fake = [2]string{pick(a.rng, givenNames), pick(a.rng, familyNames)}
+		if !a.usedNames[fake] {
+			break
+		}
+	}
+	a.usedNames[fake] = true
+	if key != "" {
+		a.names[key] = fake
+	}
+	return fake[0], fake[1]
+}
+
+// email returns the fake address for a real one: a local part made from the
+// contact's fake name at a fake domain standing for the real domain.
+func (a *anonymizer) email(value, localPart string, index int) string {
+	if value == "" {
+		return ""
+	}
+	key := strings.ToLower(strings.TrimSpace(value))
+	if fake, ok := a.emails[key]; ok {
+		return fake
+	}
+	_, domain, _ := strings.Cut(key, "@")
+	fakeDomain, ok := a.domains[domain]
+	if !ok {
+		fakeDomain = fmt.Sprintf("domain%d.example", len(a.domains)+1)
+		a.domains[domain] = fakeDomain
+	}
+	fake := fmt.Sprintf("%s%d@%s", localPart, index, fakeDomain)
+	for n := 2; a.taken(fake); n++ {
+		fake = fmt.Sprintf("%s%d.%d@%s", localPart, index, n, fakeDomain)
+	}
+	a.emails[key] = fake
+	a.report.Emails++
+	return fake
+}
+
+// taken reports whether a fake email address is already in use.
+func (a *anonymizer) taken(fake string) bool {
+	for _, used := range a.emails {
+		if used == fake {
+			return true
+		}
+	}
+	return false
+}
+
+// phone returns the fake number for a real one, the same for every way of
+// writing the same digits.
+func (a *anonymizer) phone(value string) string {
+	if value == "" {
+		return ""
+	}
+	var key strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			key.WriteRune(r)
+		}
+	}
+	if fake, ok := a.phones[key.String()]; ok {
+		return fake
+	}
+	fake := sequentialPhone(a.nextPhone)
+	a.nextPhone++
+	a.phones[key.String()] = fake
+	a.report.Phones++
+	return fake
+}
+
+// sequentialPhone returns the nth number from the ranges reserved for
+// fiction: 100 in the US, then 1,000 UK mobile and 1,000 London numbers.
+// Past those, numbers repeat.
+func sequentialPhone(n int) string {
+	n %= 2100
+	switch {
+	case n < 100:
+		return fmt.Sprintf("+1 555-01%02d", n)
+	case n < 1100:
+		return fmt.Sprintf("+44 7700 900%03d", n-100)
+	}
+	return fmt.Sprintf("+44 20 7946 0%03d", n-1100)
+}
+
+// canonicalPhone returns a phone number in E.164 form.
+func canonicalPhone(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r == '+' || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// date moves a date to a random day, keeping whether it has a year.
+func (a *anonymizer) date(date *people.Date) {
+	if date == nil {
+		return
+	}
+	if date.Year != 0 {
+		date.Year = 1940 + a.rng.Int64N(70)
+	}
+	if date.Month != 0 {
+		date.Month = 1 + a.rng.Int64N(12)
+	}
+	if date.Day != 0 {
+		date.Day = 1 + a.rng.Int64N(28)
+	}
+}
+
+// profileIDs replaces the ids of the Google profiles a contact is linked to,
+// in its metadata and in the metadata of each field, which identify real
+// people. The same profile gets the same fake id everywhere.
+func (a *anonymizer) profileIDs(c *people.Person) {
+	fake := func(id string) string {
+		if id == "" {
+			return ""
+		}
+		if f, ok := a.profiles[id]; ok {
+			return f
+		}
+		f := fmt.Sprintf("1%019d%d", a.rng.Uint64()%10000000000000000000, a.rng.IntN(10))
+		a.profiles[id] = f
+		return f
+	}
+	source := func(s *people.Source) {
+		if s != nil && (s.Type == "PROFILE" || s.Type == "DOMAIN_PROFILE") {
+			s.Id = fake(s.Id)
+		}
+	}
+
+	if c.Metadata != nil {
+		for _, s := range c.Metadata.Sources {
+			source(s)
+		}
+		for i, linked := range c.Metadata.LinkedPeopleResourceNames {
+			c.Metadata.LinkedPeopleResourceNames[i] = "people/" + fake(strings.TrimPrefix(linked, "people/"))
+		}
+	}
+
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		list := v.Field(i)
+		if list.Kind() != reflect.Slice {
+			continue
+		}
+		for j := 0; j < list.Len(); j++ {
+			item := reflect.Indirect(list.Index(j))
+			if item.Kind() != reflect.Struct {
+				continue
+			}
+			if metadata, ok := item.FieldByName("Metadata").Interface().(*people.FieldMetadata); ok && metadata != nil {
+				source(metadata.Source)
+			}
+		}
+	}
+}
+
+// grey returns a small grey JPEG standing in for every photo.
+func (a *anonymizer) grey() []byte {
+	if a.photo == nil {
+		img := image.NewGray(image.Rect(0, 0, 16, 16))
+		for i := range img.Pix {
+			img.Pix[i] = 0x99
+		}
+		var buf bytes.Buffer
+		jpeg.Encode(&buf, img, nil)
+		a.photo = buf.Bytes()
+	}
+	return a.photo
+}
+
+// fakePhotoURL returns a placeholder photo URL for a contact.
+func fakePhotoURL(resourceName string) string {
+	return "https://photos.example/" + strings.TrimPrefix(resourceName, "people/") + ".jpg"
+}
+
+// keep returns fake if value is not empty, so fields stay empty or set as
+// they were.
+func keep(value, fake string) string {
+	if value == "" {
+		return ""
+	}
+	if fake == "" {
+		return "redacted"
+	}
+	return fake
+}
+
+// redactStrings replaces the non-empty free-text string fields of a field
+// value, leaving its type and metadata alone.
+func redactStrings(v reflect.Value, fake string) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		f := v.Field(i)
+		if f.Kind() != reflect.String || !f.CanSet() || f.String() == "" {
+			continue
+		}
+		switch name {
+		case "Key", "Type", "FormattedType", "ContentType", "Protocol", "FormattedProtocol":
+			continue
+		}
+		f.SetString(fake)
+	}
+}
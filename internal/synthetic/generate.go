@@ -0,0 +1,260 @@
+// Package synthetic generates realistic fake backups for load testing and
+// benchmarking without touching real personal data.
+//
+// All generated email addresses use the reserved example.* domains and all
+// phone numbers use ranges reserved for fiction, so output can never reach a
+// real person.
+package synthetic
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"google.golang.org/api/people/v1"
+
+	"github.com/mheap/google-contacts-backup/internal/models"
+)
+
+// Options controls the size and shape of a generated backup.
+type Options struct {
+	// Contacts is the number of contacts to generate
+	Contacts int
+	// Groups is the number of user contact groups to generate
+	Groups int
+	// Seed makes the output reproducible; the same seed yields the same backup
+	Seed uint64
+}
+
+var (
+	givenNames = []string{
+		"Olivia", "Liam", "Emma", "Noah", "Amelia", "Oliver", "Ava", "Elijah", "Sophia", "Lucas",
+		"Isabella", "Mateo", "Mia", "Levi", "Charlotte", "Hiroshi", "Aiko", "Priya", "Arjun", "Fatima",
+		"Omar", "Chloé", "Léa", "Jürgen", "Søren", "Ngozi", "Chinedu", "Mei", "Wei", "Sofía",
+		"Diego", "Valentina", "Ivan", "Olga", "Kofi", "Ama", "Zara", "Yusuf", "Ingrid", "Tomás",
+	}
+	familyNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+		"Nguyen", "Kim", "Patel", "Singh", "Tanaka", "Suzuki", "Müller", "Schmidt", "Dubois", "Moreau",
+		"Rossi", "Bianchi", "Kowalski", "Nowak", "Okafor", "Mensah", "Andersen", "Nielsen", "Ivanova", "O'Brien",
+		"García-López", "van der Berg", "Haddad", "Cohen", "Silva", "Santos", "Chen", "Wang", "Hughes", "Murphy",
+	}
+	nicknames   = []string{"Ace", "Bean", "Sunny", "Doc", "Kit", "Max", "Bee", "Jay"}
+	domains     = []string{"example.com", "example.org", "example.net"}
+	workDomains = []string{"corp.example.com", "mail.example.org", "team.example.net"}
+	companies   = []string{"Acme Corp", "Globex", "Initech", "Umbrella Labs", "Hooli", "Stark Industries", "Wayne Enterprises", "Vandelay Industries"}
+	titles      = []string{"Engineer", "Designer", "Manager", "Director", "Analyst", "Consultant", "Nurse", "Teacher", "Chef"}
+	departments = []string{"Engineering", "Sales", "Marketing", "Finance", "Operations", "Research"}
+	streets     = []string{"High Street", "Main Street", "Station Road", "Elm Avenue", "Rue de la Paix", "Hauptstraße", "Calle Mayor"}
+	cities      = []string{"Springfield", "Riverton", "Lakeside", "Hill Valley", "Sunnydale", "Twin Peaks", "Gotham"}
+	countries   = []string{"United States", "United Kingdom", "France", "Germany", "Spain", "Japan", "Nigeria"}
+	groupNames  = []string{"Family", "Friends", "Work", "Book Club", "Neighbours", "School", "Football", "Doctors", "Clients", "Suppliers"}
+	notes       = []string{
+		"Met at the conference last spring.",
+		"Prefers to be contacted by email.",
+		"<p>Allergies: <b>peanuts</b></p><p>Birthday gift ideas: books</p>",
+		"Owes me a coffee.",
+		"Lives next to the park.\nHas two dogs.",
+	}
+	phoneTypes   = []string{"mobile", "home", "work", "main"}
+	emailTypes   = []string{"home", "work", "other"}
+	relationType = []string{"spouse", "child", "mother", "father", "sister", "brother", "friend", "assistant"}
+)
+
+// Generate returns a backup filled with fake contacts and groups.
+func Generate(opts Options) *models.BackupFile {
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x5eed))
+	backup := models.NewBackupFile()
+	backup.GroupMembers = make(map[string][]string)
+
+	backup.AddGroup(&people.ContactGroup{
+		ResourceName:  "contactGroups/myContacts",
+		Name:          "myContacts",
+		FormattedName: "My Contacts",
+		GroupType:     "SYSTEM_CONTACT_GROUP",
+	})
+
+	userGroups := make([]*people.ContactGroup, 0, opts.Groups)
+	for i := 0; i < opts.Groups; i++ {
+		name := groupNames[i%len(groupNames)]
+		if i >= len(groupNames) {
+			name = fmt.Sprintf("%s %d", name, i/len(groupNames)+1)
+		}
+		group := &people.ContactGroup{
+			ResourceName:  fmt.Sprintf("contactGroups/%012x", rng.Uint64()&0xffffffffffff),
+			Name:          name,
+			FormattedName: name,
+			GroupType:     "USER_CONTACT_GROUP",
+		}
+		userGroups = append(userGroups, group)
+		backup.AddGroup(group)
+		backup.GroupMembers[group.ResourceName] = []string{}
+	}
+
+	for i := 0; i < opts.Contacts; i++ {
+		contact := generateContact(rng, i)
+
+		contact.Memberships = []*people.Membership{membership("contactGroups/myContacts")}
+		if len(userGroups) > 0 {
+			for n := rng.IntN(3); n > 0; n-- {
+				group := userGroups[rng.IntN(len(userGroups))]
+				if hasMembership(contact, group.ResourceName) {
+					continue
+				}
+				contact.Memberships = append(contact.Memberships, membership(group.ResourceName))
+				backup.GroupMembers[group.ResourceName] = append(backup.GroupMembers[group.ResourceName], contact.ResourceName)
+				group.MemberCount++
+			}
+		}
+
+		backup.AddContact(contact)
+	}
+
+	return backup
+}
+
+func generateContact(rng *rand.Rand, index int) *people.Person {
+	given := pick(rng, givenNames)
+	family := pick(rng, familyNames)
+	localPart := strings.ToLower(asciiOnly(given) + "." + asciiOnly(family))
+
+	contact := &people.Person{
+		ResourceName: fmt.Sprintf("people/c%d", 1000000000000000000+rng.Int64N(8000000000000000000)),
+		Etag:         fmt.Sprintf("%%synthetic%d", index),
+		Names: []*people.Name{{
+			GivenName:   given,
+			FamilyName:  family,
+			DisplayName: given + " " + family,
+		}},
+	}
+
+	for n := 1 + rng.IntN(3); n > 0; n-- {
+		domain := pick(rng, domains)
+		emailType := pick(rng, emailTypes)
+		if emailType == "work" {
+			domain = pick(rng, workDomains)
+		}
+		contact.EmailAddresses = append(contact.EmailAddresses, &people.EmailAddress{
+			Value: fmt.Sprintf("%s%d@%s", localPart, index, domain),
+			Type:  emailType,
+		})
+	}
+
+	for n := rng.IntN(4); n > 0; n-- {
+		contact.PhoneNumbers = append(contact.PhoneNumbers, &people.PhoneNumber{
+			Value: fictionalPhone(rng),
+			Type:  pick(rng, phoneTypes),
+		})
+	}
+
+	if chance(rng, 40) {
+		contact.Addresses = append(contact.Addresses, &people.Address{
+			StreetAddress: fmt.Sprintf("%d %s", 1+rng.IntN(250), pick(rng, streets)),
+			City:          pick(rng, cities),
+			PostalCode:    fmt.Sprintf("%05d", rng.IntN(100000)),
+			Country:       pick(rng, countries),
+			Type:          pick(rng, []string{"home", "work"}),
+		})
+	}
+
+	if chance(rng, 50) {
+		contact.Organizations = append(contact.Organizations, &people.Organization{
+			Name:       pick(rng, companies),
+			Title:      pick(rng, titles),
+			Department: pick(rng, departments),
+		})
+	}
+
+	if chance(rng, 30) {
+		date := &people.Date{Month: 1 + rng.Int64N(12), Day: 1 + rng.Int64N(28)}
+		if chance(rng, 70) {
+			date.Year = 1940 + rng.Int64N(70)
+		}
+		contact.Birthdays = append(contact.Birthdays, &people.Birthday{Date: date})
+	}
+
+	if chance(rng, 20) {
+		note := pick(rng, notes)
+		contentType := models.NotesContentTypePlain
+		if strings.HasPrefix(note, "<") {
+			contentType = models.NotesContentTypeHTML
+		}
+		contact.Biographies = append(contact.Biographies, &people.Biography{Value: note, ContentType: contentType})
+	}
+
+	if chance(rng, 10) {
+		contact.Nicknames = append(contact.Nicknames, &people.Nickname{Value: pick(rng, nicknames)})
+	}
+
+	if chance(rng, 10) {
+		contact.Urls = append(contact.Urls, &people.Url{
+			Value: fmt.Sprintf("https://%s/%s", pick(rng, domains), localPart),
+			Type:  "homePage",
+		})
+	}
+
+	if chance(rng, 10) {
+		contact.Relations = append(contact.Relations, &people.Relation{
+			Person: pick(rng, givenNames) + " " + family,
+			Type:   pick(rng, relationType),
+		})
+	}
+
+	if chance(rng, 5) {
+		contact.Events = append(contact.Events, &people.Event{
+			Date: &people.Date{Year: 1990 + rng.Int64N(35), Month: 1 + rng.Int64N(12), Day: 1 + rng.Int64N(28)},
+			Type: "anniversary",
+		})
+	}
+
+	if chance(rng, 5) {
+		contact.UserDefined = append(contact.UserDefined, &people.UserDefined{
+			Key:   "Customer ID",
+			Value: fmt.Sprintf("CUST-%06d", rng.IntN(1000000)),
+		})
+	}
+
+	return contact
+}
+
+// fictionalPhone returns a number from ranges reserved for fiction.
+func fictionalPhone(rng *rand.Rand) string {
+	if chance(rng, 50) {
+		return fmt.Sprintf("+1 555-01%02d", rng.IntN(100))
+	}
+	return fmt.Sprintf("+44 7700 900%03d", rng.IntN(1000))
+}
+
+func membership(group string) *people.Membership {
+	return &people.Membership{
+		ContactGroupMembership: &people.ContactGroupMembership{ContactGroupResourceName: group},
+	}
+}
+
+func hasMembership(contact *people.Person, group string) bool {
+	for _, m := range contact.Memberships {
+		if m.ContactGroupMembership != nil && m.ContactGroupMembership.ContactGroupResourceName == group {
+			return true
+		}
+	}
+	return false
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.IntN(len(values))]
+}
+
+func chance(rng *rand.Rand, percent int) bool {
+	return rng.IntN(100) < percent
+}
+
+// asciiOnly drops characters that are awkward in email local parts.
+func asciiOnly(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
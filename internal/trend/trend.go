@@ -0,0 +1,224 @@
+// Package trend follows the size of the address book from backup to backup
+// and flags sudden drops or explosions, such as a sync client deleting
+// contacts or a sync loop creating duplicates.
+package trend
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mheap/google-contacts-backup/internal/state"
+)
+
+// Default thresholds of Options
+const (
+	DefaultMaxDrop   = 0.10
+	DefaultMaxGrowth = 0.25
+	DefaultMinChange = 10
+)
+
+// Alerts of a Point
+const (
+	AlertDrop   = "drop"
+	AlertGrowth = "growth"
+)
+
+// Options decide which changes are unusual.
+type Options struct {
+	// MaxDrop and MaxGrowth are the largest share of its contacts (0.1 is
+	// 10%) the address book may lose or gain from one backup to the next
+	MaxDrop   float64
+	MaxGrowth float64
+
+	// MinChange is the smallest change in contacts that is flagged, so a
+	// small address book is not flagged for a few edits
+	MinChange int
+}
+
+// withDefaults fills in the zero fields of o.
+func (o Options) withDefaults() Options {
+	if o.MaxDrop <= 0 {
+		o.MaxDrop = DefaultMaxDrop
+	}
+	if o.MaxGrowth <= 0 {
+		o.MaxGrowth = DefaultMaxGrowth
+	}
+	if o.MinChange <= 0 {
+		o.MinChange = DefaultMinChange
+	}
+	return o
+}
+
+// Point is the size of the address book at one backup, compared with the
+// backup before it.
+type Point struct {
+	state.Size
+
+	// Change and GroupChange are the contacts and groups gained (or, if
+	// negative, lost) since the previous backup, and Percent the change in
+	// contacts as a share of the previous count
+	Change      int     `json:"change"`
+	GroupChange int     `json:"group_change"`
+	Percent     float64 `json:"percent"`
+
+	// Alert is AlertDrop or AlertGrowth if the change is unusual
+	Alert string `json:"alert,omitempty"`
+}
+
+// Trend is the size of the address book over a series of backups.
+type Trend struct {
+	Points []Point `json:"points"`
+
+	// Alerts counts the points with an alert
+	Alerts int `json:"alerts"`
+}
+
+// Analyze compares each size with the one before it. Sizes must be sorted
+// oldest first. The first size is only compared with previous, the size
+// before the period shown, if that is not nil.
+func Analyze(sizes []state.Size, previous *state.Size, opts Options) *Trend {
+	opts = opts.withDefaults()
+	t := &Trend{Points: make([]Point, 0, len(sizes))}
+	for i, size := range sizes {
+		point := Point{Size: size}
+		before := previous
+		if i > 0 {
+			before = &sizes[i-1]
+		}
+		if before != nil {
+			point.Change = size.Contacts - before.Contacts
+			point.GroupChange = size.Groups - before.Groups
+			if before.Contacts > 0 {
+				point.Percent = 100 * float64(point.Change) / float64(before.Contacts)
+			}
+			point.Alert = alert(before.Contacts, point.Change, opts)
+			if point.Alert != "" {
+				t.Alerts++
+			}
+		}
+		t.Points = append(t.Points, point)
+	}
+	return t
+}
+
+// alert returns the alert for a change of contacts from a count of before.
+func alert(before, change int, opts Options) string {
+	if abs(change) < opts.MinChange {
+		return ""
+	}
+	share := math.Inf(1)
+	if before > 0 {
+		share = float64(abs(change)) / float64(before)
+	}
+	switch {
+	case change < 0 && share > opts.MaxDrop:
+		return AlertDrop
+	case change > 0 && share > opts.MaxGrowth:
+		return AlertGrowth
+	}
+	return ""
+}
+
+// Last returns the newest point, or nil if there are none.
+func (t *Trend) Last() *Point {
+	if len(t.Points) == 0 {
+		return nil
+	}
+	return &t.Points[len(t.Points)-1]
+}
+
+// Describe explains the alert of a point, e.g. "lost 612 contacts (51%)".
+func (p *Point) Describe() string {
+	switch p.Alert {
+	case AlertDrop:
+		return fmt.Sprintf("lost %d contacts (%.0f%%)", -p.Change, -p.Percent)
+	case AlertGrowth:
+		if p.Change == p.Contacts {
+			return fmt.Sprintf("gained %d contacts", p.Change)
+		}
+		return fmt.Sprintf("gained %d contacts (+%.0f%%)", p.Change, p.Percent)
+	}
+	return ""
+}
+
+// WriteText writes the trend as a table, one backup per line, followed by
+// the net change and the unusual changes.
+func WriteText(w io.Writer, t *Trend) error {
+	var b strings.Builder
+	if len(t.Points) == 0 {
+		b.WriteString("No backup sizes recorded for the period.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	first, last := t.Points[0], t.Last()
+	fmt.Fprintf(&b, "Address book size over %d backups, %s to %s:\n\n", len(t.Points),
+		first.At.Local().Format(time.DateOnly), last.At.Local().Format(time.DateOnly))
+	fmt.Fprintf(&b, "  %-16s  %8s  %8s  %6s\n", "Backup", "Contacts", "Change", "Groups")
+	for _, point := range t.Points {
+		change := ""
+		if point.Change != 0 {
+			change = fmt.Sprintf("%+d", point.Change)
+		}
+		fmt.Fprintf(&b, "  %-16s  %8d  %8s  %6d", point.At.Local().Format("2006-01-02 15:04"), point.Contacts, change, point.Groups)
+		if point.Alert != "" {
+			fmt.Fprintf(&b, "  ! %s", point.Describe())
+		}
+		b.WriteString("\n")
+	}
+
+	// The first point's change reaches back to the backup before the period
+	before := first.Contacts - first.Change
+	net := last.Contacts - before
+	fmt.Fprintf(&b, "\nNet change: %+d contacts", net)
+	if before > 0 {
+		fmt.Fprintf(&b, " (%+.1f%%)", 100*float64(net)/float64(before))
+	}
+	fmt.Fprintf(&b, ", %+d groups\n", last.Groups-first.Groups+first.GroupChange)
+	if t.Alerts > 0 {
+		fmt.Fprintf(&b, "%d unusual changes; check the backups before and after them.\n", t.Alerts)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WritePrometheus writes gauges for the newest point in the Prometheus text
+// format, for the node_exporter textfile collector or a /metrics endpoint.
+// Nothing is written if there are no points.
+func WritePrometheus(w io.Writer, t *Trend) error {
+	last := t.Last()
+	if last == nil {
+		return nil
+	}
+	alert := 0
+	if last.Alert != "" {
+		alert = 1
+	}
+
+	var b strings.Builder
+	gauge := func(name, help string, value any) {
+		fmt.Fprintf(&b, "# HELP google_contacts_backup_%s %s\n", name, help)
+		fmt.Fprintf(&b, "# TYPE google_contacts_backup_%s gauge\n", name)
+		fmt.Fprintf(&b, "google_contacts_backup_%s %v\n", name, value)
+	}
+	gauge("contacts", "Contacts in the address book at the last backup.", last.Contacts)
+	gauge("other_contacts", "Other contacts at the last backup, if backed up.", last.OtherContacts)
+	gauge("groups", "Contact groups at the last backup.", last.Groups)
+	gauge("contacts_change", "Contacts gained (negative: lost) since the backup before the last.", last.Change)
+	gauge("size_alert", "1 if the last backup shows an unusual drop or growth in contacts.", alert)
+	gauge("size_timestamp_seconds", "Time of the last backup, in seconds since the epoch.", last.At.Unix())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,373 @@
+// Package update finds the latest release of the tool on GitHub, checks the
+// release's signed checksums and replaces the running binary with it.
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release files, as published by GoReleaser
+const (
+	// Project is the name of the project, its binary and its archives
+	Project = "google-contacts-backup"
+
+	// ChecksumsFile lists the SHA-256 of every archive of a release
+	ChecksumsFile = "checksums.txt"
+
+	// SignatureFile is the raw Ed25519 signature of ChecksumsFile
+	SignatureFile = ChecksumsFile + ".sig"
+)
+
+// DefaultRepository is the GitHub repository releases are published in
+const DefaultRepository = "mheap/google-contacts-backup"
+
+// maxDownload bounds the size of any file downloaded from a release
+const maxDownload = 200 << 20
+
+// ErrUnsigned is returned for a release without a signature of its
+// checksums.
+var ErrUnsigned = errors.New("the release has no signed checksums")
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Release is a GitHub release.
+type Release struct {
+	Tag         string    `json:"tag_name"`
+	URL         string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Version returns the release's version without the leading "v".
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Asset returns the asset with the given name.
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Client talks to GitHub.
+type Client struct {
+	// HTTPClient sends the requests; http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// Repository is the owner/name of the repository; DefaultRepository if
+	// empty
+	Repository string
+
+	// APIURL is the base URL of the GitHub API; https://api.github.com if
+	// empty
+	APIURL string
+
+	// Token authenticates API requests, raising GitHub's rate limit for
+	// shared addresses, e.g. from GITHUB_TOKEN; optional
+	Token string
+}
+
+// Latest returns the newest release that is not a draft or prerelease.
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	repository := c.Repository
+	if repository == "" {
+		repository = DefaultRepository
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(apiURL, "/")+"/repos/"+repository+"/releases/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	data, err := c.get(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the latest release: %w", err)
+	}
+
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("invalid release from GitHub: %w", err)
+	}
+	if release.Tag == "" {
+		return nil, fmt.Errorf("invalid release from GitHub: no tag")
+	}
+	return &release, nil
+}
+
+// Download returns the contents of a release asset.
+func (c *Client) Download(ctx context.Context, asset Asset) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.get(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	return data, nil
+}
+
+// get sends a GET request and returns the body of a 200 response.
+func (c *Client) get(req *http.Request) ([]byte, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownload {
+		return nil, fmt.Errorf("%s is larger than %d MB", path.Base(req.URL.Path), maxDownload>>20)
+	}
+	return data, nil
+}
+
+// ArchiveName returns the name of the release archive for a platform, as
+// named by GoReleaser, e.g. google-contacts-backup_1.4.0_Linux_x86_64.tar.gz.
+// Windows builds are taken from the zip archives.
+func ArchiveName(version, goos, goarch string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("%s_%s_%s_%s%s", Project, version, strings.ToUpper(goos[:1])+goos[1:], arch, ext)
+}
+
+// ParsePublicKey decodes the base64 Ed25519 key release checksums are
+// signed with.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key: must be a base64 Ed25519 public key")
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// VerifyChecksums checks the signature of a release's checksums file and
+// returns the hex SHA-256 it lists for name.
+func VerifyChecksums(checksums, signature []byte, key ed25519.PublicKey, name string) (string, error) {
+	if !ed25519.Verify(key, checksums, signature) {
+		return "", fmt.Errorf("the signature of %s does not match the release signing key; the release may have been tampered with", ChecksumsFile)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s does not list %s", ChecksumsFile, name)
+}
+
+// CheckDigest checks that data has the hex SHA-256 want.
+func CheckDigest(data []byte, name, want string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("the SHA-256 of %s is %s, but %s lists %s; the download is corrupt or was tampered with", name, got, ChecksumsFile, want)
+	}
+	return nil
+}
+
+// ExtractBinary returns the executable named binary from a .tar.gz or .zip
+// release archive.
+func ExtractBinary(archive []byte, archiveName, binary string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive %s: %w", archiveName, err)
+		}
+		for _, file := range zr.File {
+			if path.Base(file.Name) != binary || file.FileInfo().IsDir() {
+				continue
+			}
+			r, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("invalid archive %s: %w", archiveName, err)
+			}
+			defer r.Close()
+			return readBinary(r, archiveName)
+		}
+		return nil, fmt.Errorf("%s does not contain %s", archiveName, binary)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive %s: %w", archiveName, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s does not contain %s", archiveName, binary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive %s: %w", archiveName, err)
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == binary {
+			return readBinary(tr, archiveName)
+		}
+	}
+}
+
+// readBinary reads a binary from an archive, up to maxDownload bytes.
+func readBinary(r io.Reader, archiveName string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxDownload+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive %s: %w", archiveName, err)
+	}
+	if len(data) > maxDownload {
+		return nil, fmt.Errorf("the binary in %s is larger than %d MB", archiveName, maxDownload>>20)
+	}
+	return data, nil
+}
+
+// BinaryName returns the name of the tool's executable on this platform.
+func BinaryName() string {
+	if runtime.GOOS == "windows" {
+		return Project + ".exe"
+	}
+	return Project
+}
+
+// Replace swaps the executable at exe for binary. The new binary is written
+// next to exe and renamed over it, so exe is never left half written. The old
+// executable is first moved aside to exe.old, as Windows cannot overwrite a
+// running program; it is removed where the platform allows.
+func Replace(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return fmt.Errorf("failed to read the current executable: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+".new-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", filepath.Dir(exe), err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the new executable: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the new executable: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("failed to make the new executable runnable: %w", err)
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return fmt.Errorf("failed to move the current executable aside: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		// Put the old executable back
+		if restoreErr := os.Rename(old, exe); restoreErr != nil {
+			return fmt.Errorf("failed to install the new executable: %w; the previous one is at %s", err, old)
+		}
+		return fmt.Errorf("failed to install the new executable: %w", err)
+	}
+	os.Remove(old)
+	return nil
+}
+
+// Newer reports whether version latest is newer than current. Versions are
+// compared as major.minor.patch; a prerelease or snapshot such as 1.4.0-next
+// is older than 1.4.0.
+func Newer(latest, current string) bool {
+	return compareVersions(latest, current) > 0
+}
+
+// compareVersions compares two versions, with or without a leading "v".
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	for i := range aCore {
+		if aCore[i] != bCore[i] {
+			if aCore[i] < bCore[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	}
+	return 1
+}
+
+// splitVersion splits a version into its numbers and prerelease suffix.
+// Missing or invalid numbers count as 0.
+func splitVersion(version string) ([3]int, string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	core, pre, _ := strings.Cut(version, "-")
+	core, _, _ = strings.Cut(core, "+")
+	var numbers [3]int
+	for i, part := range strings.SplitN(core, ".", 3) {
+		numbers[i], _ = strconv.Atoi(part)
+	}
+	return numbers, pre
+}
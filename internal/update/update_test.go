@@ -0,0 +1,121 @@
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := []byte("release archive")
+	sum := sha256.Sum256(archive)
+	digest := hex.EncodeToString(sum[:])
+	checksums := []byte(strings.ToUpper(digest) + "  google-contacts-backup_1.2.0_Linux_x86_64.tar.gz\n" +
+		"0000000000000000000000000000000000000000000000000000000000000000 *google-contacts-backup_1.2.0_Windows_x86_64.zip\n")
+	signature := ed25519.Sign(priv, checksums)
+
+	got, err := VerifyChecksums(checksums, signature, pub, "google-contacts-backup_1.2.0_Linux_x86_64.tar.gz")
+	if err != nil {
+		t.Fatalf("VerifyChecksums: %v", err)
+	}
+	if got != digest {
+		t.Errorf("digest = %s, want %s", got, digest)
+	}
+	if err := CheckDigest(archive, "archive", got); err != nil {
+		t.Errorf("CheckDigest: %v", err)
+	}
+	if err := CheckDigest([]byte("tampered archive"), "archive", got); err == nil {
+		t.Error("CheckDigest accepted a different archive")
+	}
+
+	got, err = VerifyChecksums(checksums, signature, pub, "google-contacts-backup_1.2.0_Windows_x86_64.zip")
+	if err != nil || got != strings.Repeat("0", 64) {
+		t.Errorf("binary-mode entry = %q, %v", got, err)
+	}
+
+	if _, err := VerifyChecksums(checksums, signature, otherPub, "google-contacts-backup_1.2.0_Linux_x86_64.tar.gz"); err == nil {
+		t.Error("VerifyChecksums accepted a signature from another key")
+	}
+	tampered := append([]byte(nil), checksums...)
+	tampered[0] ^= 1
+	if _, err := VerifyChecksums(tampered, signature, pub, "google-contacts-backup_1.2.0_Linux_x86_64.tar.gz"); err == nil {
+		t.Error("VerifyChecksums accepted a tampered checksums file")
+	}
+	if _, err := VerifyChecksums(checksums, signature, pub, "google-contacts-backup_1.2.0_Darwin_arm64.tar.gz"); err == nil {
+		t.Error("VerifyChecksums found an archive the checksums file does not list")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ParsePublicKey(" " + base64.StdEncoding.EncodeToString(pub) + "\n")
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if !key.Equal(pub) {
+		t.Error("ParsePublicKey returned a different key")
+	}
+
+	for _, encoded := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(pub[:16])} {
+		if _, err := ParsePublicKey(encoded); err == nil {
+			t.Errorf("ParsePublicKey(%q) succeeded", encoded)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3+build", "1.2.3", 0},
+		{"1.2.4", "1.2.3", 1},
+		{"1.3.0", "1.2.9", 1},
+		{"2.0.0", "1.10.0", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.4.0", "1.4.0-next", 1},
+		{"1.4.0-next", "1.3.9", 1},
+		{"1.4.0-beta", "1.4.0-alpha", 1},
+		{"dev", "0.0.0", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := compareVersions(tt.b, tt.a); got != -tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestNewer(t *testing.T) {
+	if !Newer("v1.5.0", "1.4.2") {
+		t.Error("Newer(v1.5.0, 1.4.2) = false")
+	}
+	if Newer("1.4.2", "1.4.2") {
+		t.Error("Newer(1.4.2, 1.4.2) = true")
+	}
+	if Newer("1.4.0-next", "1.4.0") {
+		t.Error("Newer(1.4.0-next, 1.4.0) = true")
+	}
+}
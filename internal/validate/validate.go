@@ -0,0 +1,250 @@
+// Package validate checks contacts against the constraints the People API
+// enforces when contacts are created or updated, so that a contact Google
+// would refuse is found before it fails a whole batch of a restore.
+//
+// Each problem is located by a path into the contact in the People API's
+// JSON form, such as emailAddresses[1].value.
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/api/people/v1"
+)
+
+// Limits Google Contacts enforces on a contact.
+const (
+	// MaxContactSize is the size of a contact in bytes, as JSON
+	MaxContactSize = 128 * 1024
+
+	// MaxContactValues is the number of values (emails, phone numbers, ...)
+	// a contact can hold across all fields
+	MaxContactValues = 500
+
+	// MaxValueLength is the length of a single text value in characters.
+	// Notes may be as long as the contact size allows.
+	MaxValueLength = 2048
+)
+
+// contentTypes are the values biographies[].contentType accepts
+var contentTypes = []string{"", "CONTENT_TYPE_UNSPECIFIED", "TEXT_PLAIN", "TEXT_HTML"}
+
+// Problem is a reason the People API would refuse a contact.
+type Problem struct {
+	// Path locates the offending value, e.g. "emailAddresses[1].value", or
+	// is the field name for problems with a field as a whole
+	Path string
+
+	// Message describes the problem
+	Message string
+}
+
+// String returns the problem as "path: message".
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// Contact returns the problems that would make the People API refuse p, in
+// field order, or nil if it would accept it.
+func Contact(p *people.Person) []Problem {
+	var problems []Problem
+	add := func(path, format string, args ...any) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, singleton := range []struct {
+		field string
+		count int
+	}{
+		{"names", len(p.Names)},
+		{"birthdays", len(p.Birthdays)},
+		{"biographies", len(p.Biographies)},
+		{"genders", len(p.Genders)},
+	} {
+		if singleton.count > 1 {
+			add(singleton.field, "can only hold one value, has %d", singleton.count)
+		}
+	}
+
+	// Every field holding values is a list of pointers
+	v := reflect.ValueOf(p).Elem()
+	values := 0
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Pointer {
+			values += field.Len()
+		}
+	}
+	if values > MaxContactValues {
+		add("", "the contact has %d values, more than the limit of %d", values, MaxContactValues)
+	}
+
+	if data, err := json.Marshal(p); err == nil && len(data) > MaxContactSize {
+		add("", "the contact is %d bytes, more than the limit of %d", len(data), MaxContactSize)
+	}
+
+	for _, value := range textValues(p) {
+		if n := utf8.RuneCountInString(value.text); n > MaxValueLength {
+			add(value.path, "%d characters long, more than the limit of %d", n, MaxValueLength)
+		}
+	}
+
+	for i, email := range p.EmailAddresses {
+		if email != nil && email.Value != "" && !validEmail(email.Value) {
+			add(fmt.Sprintf("emailAddresses[%d].value", i), "%q is not a valid email address", email.Value)
+		}
+	}
+
+	for i, biography := range p.Biographies {
+		if biography != nil && !slices.Contains(contentTypes, biography.ContentType) {
+			add(fmt.Sprintf("biographies[%d].contentType", i), "%q is not one of %s", biography.ContentType, strings.Join(contentTypes[1:], ", "))
+		}
+	}
+
+	for i, birthday := range p.Birthdays {
+		if birthday == nil {
+			continue
+		}
+		if message := checkDate(birthday.Date); message != "" {
+			add(fmt.Sprintf("birthdays[%d].date", i), "%s", message)
+		}
+	}
+	for i, event := range p.Events {
+		if event == nil {
+			continue
+		}
+		if message := checkDate(event.Date); message != "" {
+			add(fmt.Sprintf("events[%d].date", i), "%s", message)
+		}
+	}
+
+	return problems
+}
+
+// validEmail reports whether value looks like an email address: a local
+// part and a domain around a single @, without whitespace.
+func validEmail(value string) bool {
+	if strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+		return false
+	}
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return false
+	}
+	return !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".") && !strings.Contains(domain, "..")
+}
+
+// checkDate returns why a date is invalid, or "" if it is valid or unset.
+// Year, month and day may each be zero where unknown.
+func checkDate(date *people.Date) string {
+	switch {
+	case date == nil:
+		return ""
+	case date.Year < 0 || date.Year > 9999:
+		return fmt.Sprintf("year %d is out of range", date.Year)
+	case date.Month < 0 || date.Month > 12:
+		return fmt.Sprintf("month %d is out of range", date.Month)
+	case date.Day < 0 || date.Day > daysIn(date.Month, date.Year):
+		return fmt.Sprintf("day %d is out of range", date.Day)
+	}
+	return ""
+}
+
+// daysIn returns the number of days in a month, allowing February 29 when the
+// year is unknown or a leap year. An unknown month allows 31 days.
+func daysIn(month, year int64) int64 {
+	switch month {
+	case 4, 6, 9, 11:
+		return 30
+	case 2:
+		if year == 0 || (year%4 == 0 && (year%100 != 0 || year%400 == 0)) {
+			return 29
+		}
+		return 28
+	}
+	return 31
+}
+
+// textValue is a text value of a contact with its path
+type textValue struct {
+	path string
+	text string
+}
+
+// textValues returns the text values of the fields the length limit applies
+// to, with their paths.
+func textValues(p *people.Person) []textValue {
+	var result []textValue
+	add := func(field string, i int, values map[string]string) {
+		// Sorted keys keep the problems in a stable order
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			result = append(result, textValue{fmt.Sprintf("%s[%d].%s", field, i, key), values[key]})
+		}
+	}
+
+	for i, n := range p.Names {
+		if n != nil {
+			add("names", i, map[string]string{
+				"givenName": n.GivenName, "middleName": n.MiddleName, "familyName": n.FamilyName,
+				"honorificPrefix": n.HonorificPrefix, "honorificSuffix": n.HonorificSuffix, "unstructuredName": n.UnstructuredName,
+			})
+		}
+	}
+	for i, n := range p.Nicknames {
+		if n != nil {
+			add("nicknames", i, map[string]string{"value": n.Value})
+		}
+	}
+	for i, e := range p.EmailAddresses {
+		if e != nil {
+			add("emailAddresses", i, map[string]string{"value": e.Value, "displayName": e.DisplayName})
+		}
+	}
+	for i, n := range p.PhoneNumbers {
+		if n != nil {
+			add("phoneNumbers", i, map[string]string{"value": n.Value})
+		}
+	}
+	for i, a := range p.Addresses {
+		if a != nil {
+			add("addresses", i, map[string]string{
+				"streetAddress": a.StreetAddress, "extendedAddress": a.ExtendedAddress, "city": a.City, "region": a.Region,
+				"postalCode": a.PostalCode, "country": a.Country, "poBox": a.PoBox, "formattedValue": a.FormattedValue,
+			})
+		}
+	}
+	for i, o := range p.Organizations {
+		if o != nil {
+			add("organizations", i, map[string]string{"name": o.Name, "title": o.Title, "department": o.Department})
+		}
+	}
+	for i, u := range p.Urls {
+		if u != nil {
+			add("urls", i, map[string]string{"value": u.Value})
+		}
+	}
+	for i, u := range p.UserDefined {
+		if u != nil {
+			add("userDefined", i, map[string]string{"key": u.Key, "value": u.Value})
+		}
+	}
+	for i, r := range p.Relations {
+		if r != nil {
+			add("relations", i, map[string]string{"person": r.Person})
+		}
+	}
+	return result
+}